@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// 统一错误响应：所有HTTP接口的错误响应为 {"code", "message", "details", "requestId"}，code 为稳定的机器可读错误码，
+// requestId 与响应头 X-Request-ID 及服务端日志对应，编码与正常响应一样按请求协商。
+
+// 错误码
+const (
+	ErrCodeMethodNotAllowed = "method_not_allowed"  // 接口不支持该请求方法
+	ErrCodeInvalidRequest   = "invalid_request"     // 请求体或参数无法解析、取值无效
+	ErrCodeMissingStreamID  = "missing_stream_id"   // 缺少 streamId
+	ErrCodeInvalidStreamID  = "invalid_stream_id"   // streamId 格式不合法
+	ErrCodeMissingParameter = "missing_parameter"   // 缺少其他必需参数
+	ErrCodeStreamNotFound   = "stream_not_found"    // 会话不存在
+	ErrCodeStreamPaused     = "stream_paused"       // 会话已暂停，恢复前不接收音频
+	ErrCodeNotFound         = "not_found"           // 请求的任务等资源不存在
+	ErrCodePayloadTooLarge  = "payload_too_large"   // 上传的录音过大或时长超过限制
+	ErrCodeUnsupportedMedia = "unsupported_media"   // 远程录音的内容类型不是音频
+	ErrCodeRemoteFetch      = "remote_fetch_failed" // 下载远程录音失败
+	ErrCodeFeatureDisabled  = "feature_disabled"    // 服务端未启用该功能
+	ErrCodeNotSupported     = "not_supported"       // 当前处理器不支持该操作
+	ErrCodeUnauthorized     = "unauthorized"        // 管理接口令牌无效
+	ErrCodeConflict         = "conflict"            // 与当前状态冲突，如样本库正在重建
+	ErrCodeUnprocessable    = "unprocessable"       // 请求格式正确但内容无法使用，如配置或样本无效
+	ErrCodeUnavailable      = "unavailable"         // 服务暂时无法接受请求，如任务队列已满
+	ErrCodeInternal         = "internal_error"      // 服务端内部错误
+)
+
+// APIError HTTP接口的错误响应
+type APIError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"requestId"`
+}
+
+// writeError 写出统一格式的错误响应，details 为空时省略
+// 请求ID沿用处理函数已设置的响应头，未设置时按请求头生成并写入响应头
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message, details string) {
+	requestID := w.Header().Get(RequestIDHeader)
+	if requestID == "" {
+		requestID = requestIDFrom(r)
+		w.Header().Set(RequestIDHeader, requestID)
+	}
+	writeResponse(w, r, status, APIError{Code: code, Message: message, Details: details, RequestID: requestID})
+}
+
+// writeMethodNotAllowed 写出请求方法不被支持的错误响应
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "方法不允许", "")
+}
+
+// remoteFetchCode 远程下载错误对应的错误码，与 remoteFetchStatus 的状态码对应
+func remoteFetchCode(err error) string {
+	switch {
+	case errors.Is(err, ErrAudioTooLong):
+		return ErrCodePayloadTooLarge
+	case errors.Is(err, ErrRemoteContentType):
+		return ErrCodeUnsupportedMedia
+	case errors.Is(err, ErrRemoteFetch):
+		return ErrCodeRemoteFetch
+	default:
+		return ErrCodeInvalidRequest
+	}
+}
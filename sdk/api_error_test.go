@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAPIErrorEnvelope 测试统一错误响应
+// 测试内容：
+// 1. 方法不允许、缺少 streamId、请求体无效、会话暂停时返回对应的错误码与状态码
+// 2. requestId 与响应头 X-Request-ID 一致，沿用请求头中的请求ID
+// 3. 底层错误放在 details 中，没有时省略
+// 4. 请求 MessagePack 时错误响应同样按 MessagePack 编码
+func TestAPIErrorEnvelope(t *testing.T) {
+	server := NewAudioServer(NewMockAudioProcessor())
+	call := func(handler http.HandlerFunc, method, target, body string, header map[string]string) (*httptest.ResponseRecorder, APIError) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		var apiErr APIError
+		if rec.Header().Get("Content-Type") != MsgpackContentType {
+			if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+				t.Fatalf("%s %s body = %q, want an error envelope", method, target, rec.Body)
+			}
+		}
+		return rec, apiErr
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		body    string
+		status  int
+		code    string
+		details bool
+	}{
+		{"method", server.handleStart, http.MethodGet, "", http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, false},
+		{"missing stream", server.handleStart, http.MethodPost, `{}`, http.StatusBadRequest, ErrCodeMissingStreamID, false},
+		{"invalid body", server.handleSend, http.MethodPost, `{"streamId":`, http.StatusBadRequest, ErrCodeInvalidRequest, true},
+		{"bad settings", server.handleStart, http.MethodPost, `{"streamId":"s1","strategy":"nope"}`, http.StatusBadRequest, ErrCodeInvalidRequest, true},
+	}
+	for _, tt := range tests {
+		rec, apiErr := call(tt.handler, tt.method, "/", tt.body, map[string]string{RequestIDHeader: "req-" + tt.code})
+		if rec.Code != tt.status || apiErr.Code != tt.code || apiErr.Message == "" {
+			t.Errorf("%s: %d %+v, want %d %s", tt.name, rec.Code, apiErr, tt.status, tt.code)
+		}
+		if apiErr.RequestID != "req-"+tt.code || rec.Header().Get(RequestIDHeader) != apiErr.RequestID {
+			t.Errorf("%s: requestId = %q, header %q", tt.name, apiErr.RequestID, rec.Header().Get(RequestIDHeader))
+		}
+		if (apiErr.Details != "") != tt.details {
+			t.Errorf("%s: details = %q, want present=%v", tt.name, apiErr.Details, tt.details)
+		}
+	}
+
+	server.setPaused("paused", true)
+	if rec, apiErr := call(server.handleSend, http.MethodPost, "/", `{"streamId":"paused","data":[0.1]}`, nil); rec.Code != http.StatusConflict || apiErr.Code != ErrCodeStreamPaused || apiErr.RequestID == "" {
+		t.Errorf("paused send = %d %+v", rec.Code, apiErr)
+	}
+
+	rec, _ := call(server.handleStart, http.MethodGet, "/?format=msgpack", "", nil)
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Content-Type") != MsgpackContentType {
+		t.Fatalf("msgpack error = %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
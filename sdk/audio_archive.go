@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 处理音频归档
+//
+// 用户质疑某个识别结果时，需要知道SDK当时到底听到了什么。开启归档后，每个识别结果对应的
+// 音频片段保存为 <resultId>.wav，特征与识别结果保存为同名的 .json，结果中携带 resultId
+// 即可找到对应的文件。归档按条数与保存时长限制，超出后删除最旧的条目。
+// 引擎归档每个非中间结果对应的段（或窗口）音频。
+
+// ArchiveRetention 归档保留限制，为0的项不限制
+type ArchiveRetention struct {
+	MaxEntries int           // 最多保留的条目数
+	MaxAge     time.Duration // 条目最长保留时间
+}
+
+// ArchivedResult 归档条目的附带数据
+type ArchivedResult struct {
+	ResultID   string        `json:"resultId"`
+	RequestID  string        `json:"requestId,omitempty"` // 产生该结果的音频块的请求ID
+	StreamID   string        `json:"streamId"`
+	Timestamp  int64         `json:"timestamp"`  // 结果产生的时间（毫秒时间戳）
+	SampleRate int           `json:"sampleRate"` // 归档音频的采样率
+	Duration   float64       `json:"duration"`   // 音频时长（秒）
+	Emotion    string        `json:"emotion"`
+	Confidence float64       `json:"confidence"`
+	Features   AudioFeatures `json:"features"`
+	Audio      string        `json:"audio"`           // 音频文件名，与附带数据在同一目录
+	Label      *ClipLabel    `json:"label,omitempty"` // 人工标注，见 labeling.go
+}
+
+// AudioArchiver 支持处理音频归档的处理器
+type AudioArchiver interface {
+	Archive() *AudioArchive
+	SetArchive(archive *AudioArchive)
+}
+
+// AudioArchive 处理音频归档目录
+type AudioArchive struct {
+	dir       string
+	retention ArchiveRetention
+	mu        sync.Mutex
+}
+
+// NewAudioArchive 创建归档，目录不存在时自动创建
+func NewAudioArchive(dir string, retention ArchiveRetention) (*AudioArchive, error) {
+	if retention.MaxEntries < 0 || retention.MaxAge < 0 {
+		return nil, fmt.Errorf("archive retention must not be negative")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create archive dir: %v", err)
+	}
+	return &AudioArchive{dir: dir, retention: retention}, nil
+}
+
+// newResultID 生成结果ID：流ID（仅保留文件名安全字符）加纳秒时间戳
+func newResultID(streamID string, now time.Time) string {
+	return fmt.Sprintf("%s_%d", unsafeFileChars.ReplaceAllString(streamID, "_"), now.UnixNano())
+}
+
+// Save 保存一条归档并按保留限制清理旧条目，record.Audio 由归档填写
+func (a *AudioArchive) Save(record ArchivedResult, samples []float64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	record.Audio = record.ResultID + ".wav"
+	if err := writeWAVFile(filepath.Join(a.dir, record.Audio), samples, record.SampleRate); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(a.dir, record.ResultID+".json"), data, 0644); err != nil {
+		os.Remove(filepath.Join(a.dir, record.Audio))
+		return err
+	}
+
+	a.prune(time.Now())
+	return nil
+}
+
+// Load 读取指定结果的归档附带数据
+func (a *AudioArchive) Load(resultID string) (*ArchivedResult, error) {
+	if resultID == "" || unsafeFileChars.MatchString(resultID) {
+		return nil, fmt.Errorf("invalid result id %q", resultID)
+	}
+	data, err := os.ReadFile(filepath.Join(a.dir, resultID+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var record ArchivedResult
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("parse archive entry: %v", err)
+	}
+	return &record, nil
+}
+
+// List 返回全部归档条目的附带数据，按结果产生时间排序
+func (a *AudioArchive) List() ([]ArchivedResult, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(a.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	records := make([]ArchivedResult, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // 读取时可能恰好被清理
+		}
+		var record ArchivedResult
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Printf("跳过无法解析的归档条目 %s: %v", path, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Timestamp != records[j].Timestamp {
+			return records[i].Timestamp < records[j].Timestamp
+		}
+		return records[i].ResultID < records[j].ResultID
+	})
+	return records, nil
+}
+
+// AudioPath 返回指定结果的归档音频路径
+func (a *AudioArchive) AudioPath(resultID string) (string, error) {
+	record, err := a.Load(resultID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(a.dir, filepath.Base(record.Audio)), nil
+}
+
+// SetLabel 记录条目的人工标注
+func (a *AudioArchive) SetLabel(resultID string, label ClipLabel) (*ArchivedResult, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	record, err := a.Load(resultID)
+	if err != nil {
+		return nil, err
+	}
+	record.Label = &label
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(a.dir, resultID+".json"), data, 0644); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// prune 删除超过保存时长的条目，再按条数限制删除最旧的条目（调用方需持有a.mu）
+func (a *AudioArchive) prune(now time.Time) {
+	if a.retention.MaxEntries == 0 && a.retention.MaxAge == 0 {
+		return
+	}
+
+	files, err := os.ReadDir(a.dir)
+	if err != nil {
+		log.Printf("读取归档目录失败: %v", err)
+		return
+	}
+
+	type entry struct {
+		id      string
+		modTime time.Time
+	}
+	var entries []entry
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{id: strings.TrimSuffix(f.Name(), ".json"), modTime: info.ModTime()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].modTime.Equal(entries[j].modTime) {
+			return entries[i].modTime.Before(entries[j].modTime)
+		}
+		return entries[i].id < entries[j].id
+	})
+
+	remove := 0
+	if a.retention.MaxAge > 0 {
+		for remove < len(entries) && now.Sub(entries[remove].modTime) > a.retention.MaxAge {
+			remove++
+		}
+	}
+	if a.retention.MaxEntries > 0 && len(entries)-remove > a.retention.MaxEntries {
+		remove = len(entries) - a.retention.MaxEntries
+	}
+	for _, e := range entries[:remove] {
+		os.Remove(filepath.Join(a.dir, e.id+".wav"))
+		os.Remove(filepath.Join(a.dir, e.id+".json"))
+	}
+	if remove > 0 {
+		log.Printf("归档清理: 删除 %d 个旧条目", remove)
+	}
+}
+
+// writeWAVFile 将 [-1, 1] 范围的样本保存为16位单声道PCM WAV文件
+func writeWAVFile(path string, samples []float64, sampleRate int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	if err := writeWAV(w, samples, sampleRate); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeWAV 写出16位单声道PCM WAV数据，超出 [-1, 1] 的样本被截断
+func writeWAV(w io.Writer, samples []float64, sampleRate int) error {
+	if sampleRate <= 0 {
+		return fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	dataSize := uint32(len(samples) * 2)
+	header := []interface{}{
+		[]byte("RIFF"), 36 + dataSize, []byte("WAVEfmt "),
+		uint32(16), uint16(1), uint16(1), // PCM，单声道
+		uint32(sampleRate), uint32(sampleRate * 2), uint16(2), uint16(16),
+		[]byte("data"), dataSize,
+	}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	pcm := make([]int16, len(samples))
+	for i, v := range samples {
+		pcm[i] = int16(math.Round(math.Max(-1, math.Min(1, v)) * math.MaxInt16))
+	}
+	return binary.Write(w, binary.LittleEndian, pcm)
+}
+
+// Archive 返回引擎的处理音频归档，未开启时为nil
+func (e *Engine) Archive() *AudioArchive {
+	e.settingsMu.RLock()
+	defer e.settingsMu.RUnlock()
+	return e.archive
+}
+
+// SetArchive 设置引擎的处理音频归档，每个非中间结果对应的音频与特征写入归档目录，传入nil关闭归档
+func (e *Engine) SetArchive(archive *AudioArchive) {
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+	e.archive = archive
+}
+
+// collectAudio 开启归档时记录会话当前段已分析的音频，start 为 samples 第一个样本的序号；
+// 相邻窗口重叠的部分只记录一次，段内被跳过的窗口不记录
+func (e *Engine) collectAudio(session *AudioStreamSession, samples []float64, start int64) {
+	if e.Archive() == nil {
+		return
+	}
+	skip := 0
+	if len(session.segmentAudio) > 0 && session.segmentAudioEnd > start {
+		skip = int(session.segmentAudioEnd - start)
+	}
+	if skip < len(samples) {
+		session.segmentAudio = append(session.segmentAudio, samples[skip:]...)
+	}
+	session.segmentAudioEnd = start + int64(len(samples))
+}
+
+// archiveSegment 将结果对应的段音频与特征以结果ID写入归档，并开始记录下一段；未开启归档或没有音频（频域）时不处理
+func (e *Engine) archiveSegment(session *AudioStreamSession, result *AudioStreamResult) {
+	samples := session.segmentAudio
+	session.segmentAudio = nil
+	archive := e.Archive()
+	if archive == nil || len(samples) == 0 {
+		return
+	}
+
+	rate := e.Config.SampleRate
+	record := ArchivedResult{
+		ResultID:   result.ResultID,
+		RequestID:  result.RequestID,
+		StreamID:   session.ID,
+		Timestamp:  e.now(session).UnixMilli(),
+		SampleRate: rate,
+		Duration:   float64(len(samples)) / float64(rate),
+		Emotion:    result.Emotion,
+		Confidence: result.Confidence,
+		Features:   archivedFeatures(result.Metadata.Features),
+	}
+	if err := archive.Save(record, samples); err != nil {
+		log.Printf("[%s] 归档音频片段失败: %v", session.ID, err)
+		return
+	}
+	log.Printf("音频片段[%s]已归档: 长度=%.2f秒, 情感=%s, 置信度=%.2f", record.ResultID, record.Duration, record.Emotion, record.Confidence)
+}
+
+// archivedFeatures 将引擎提取的特征转换为归档附带数据中的特征
+func archivedFeatures(features map[string]float64) AudioFeatures {
+	return AudioFeatures{
+		Energy:           features["Energy"],
+		Pitch:            features["Pitch"],
+		Duration:         features["Duration"],
+		ZeroCrossRate:    features["ZeroCrossRate"],
+		RootMeanSquare:   features["RootMeanSquare"],
+		PeakFreq:         features["PeakFreq"],
+		SpectralCentroid: features["SpectralCentroid"],
+		SpectralRolloff:  features["SpectralRolloff"],
+		FundamentalFreq:  features["FundamentalFreq"],
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestAudioArchive 测试处理音频归档
+// 测试内容：
+// 1. 保存的条目可以按 resultId 读回附带数据与WAV
+// 2. 归档的WAV可以解码，采样率与样本和保存时一致
+// 3. 超过条数限制时删除最旧的条目，超过保存时长的条目被删除
+func TestAudioArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := NewAudioArchive(dir, ArchiveRetention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tone := generateTestAudio(440, 1.5, 8000)
+	for i := range tone {
+		tone[i] *= 0.5
+	}
+	saved := ArchivedResult{ResultID: "cat_1_1", StreamID: "cat/1", SampleRate: 8000, Duration: 1.5, Emotion: "for_food", Confidence: 0.8}
+	if err := archive.Save(saved, tone); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := archive.Load(saved.ResultID)
+	if err != nil {
+		t.Fatalf("Load(%s) error = %v", saved.ResultID, err)
+	}
+	if record.StreamID != "cat/1" || record.Emotion != saved.Emotion || record.Confidence != saved.Confidence {
+		t.Errorf("record = %+v, want %+v", record, saved)
+	}
+	if record.SampleRate != 8000 || math.Abs(record.Duration-1.5) > 1e-9 {
+		t.Errorf("record rate/duration = %d/%.3f, want 8000/1.5", record.SampleRate, record.Duration)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, record.Audio))
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := decodeAudioFile(data)
+	if err != nil {
+		t.Fatalf("decodeAudioFile(archived) error = %v", err)
+	}
+	if audio.SampleRate != 8000 || len(audio.Samples) != len(tone) {
+		t.Errorf("archived audio = %d Hz, %d samples; want 8000 Hz, %d samples", audio.SampleRate, len(audio.Samples), len(tone))
+	}
+	if math.Abs(audio.Samples[100]-tone[100]) > 1e-3 {
+		t.Errorf("archived sample = %.4f, want %.4f", audio.Samples[100], tone[100])
+	}
+
+	// 条数与保存时长限制
+	limited, err := NewAudioArchive(t.TempDir(), ArchiveRetention{MaxEntries: 2, MaxAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		if err := limited.Save(ArchivedResult{ResultID: id, SampleRate: 8000}, tone[:800]); err != nil {
+			t.Fatal(err)
+		}
+		// 按保存顺序拉开修改时间，a 最旧
+		saved := time.Now().Add(time.Duration(i-3) * time.Minute)
+		os.Chtimes(filepath.Join(limited.dir, id+".json"), saved, saved)
+	}
+	if _, err := limited.Load("a"); err == nil {
+		t.Error("oldest entry should be removed when over MaxEntries")
+	}
+	if _, err := os.Stat(filepath.Join(limited.dir, "a.wav")); !os.IsNotExist(err) {
+		t.Error("audio of removed entry should be deleted")
+	}
+
+	stale := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(limited.dir, "b.json"), stale, stale)
+	if err := limited.Save(ArchivedResult{ResultID: "d", SampleRate: 8000}, tone[:800]); err != nil {
+		t.Fatal(err)
+	}
+	for id, kept := range map[string]bool{"b": false, "c": true, "d": true} {
+		if _, err := limited.Load(id); (err == nil) != kept {
+			t.Errorf("entry %s kept = %v, want %v", id, err == nil, kept)
+		}
+	}
+
+	if _, err := archive.Load("../x"); err == nil {
+		t.Error("unsafe result id should be rejected")
+	}
+	if _, err := NewAudioArchive(t.TempDir(), ArchiveRetention{MaxEntries: -1}); err == nil {
+		t.Error("negative retention should fail")
+	}
+}
+
+// TestEngineAudioArchive 测试处理引擎写入处理音频归档
+// 测试内容：
+// 1. 每个非中间结果的音频与特征以 resultId 写入归档，WAV可以解码，时长与附带数据一致
+// 2. 未设置触发条件时每个窗口单独归档，重叠的部分不丢失
+// 3. 设置触发条件时中间结果不归档，段结束时归档整段已分析的音频
+func TestEngineAudioArchive(t *testing.T) {
+	tone := generateTestAudio(440, 1, 8000)
+	for i := range tone {
+		tone[i] *= 0.5
+	}
+	run := func(config AudioStreamConfig, audio []float64) (*AudioArchive, []AudioStreamResult, int) {
+		t.Helper()
+		engine := newTestEngine(t, config)
+		archive, err := NewAudioArchive(t.TempDir(), ArchiveRetention{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var archiver AudioArchiver = engine
+		archiver.SetArchive(archive)
+
+		session := engine.NewSession("cat/1")
+		window, _ := session.Strategy.frames(config.BufferSize)
+		engine.Append(session, audio)
+		var results []AudioStreamResult
+		emit := func(data []byte) {
+			var result AudioStreamResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, result)
+		}
+		if err := engine.Drain(session, emit); err != nil {
+			t.Fatal(err)
+		}
+		if err := engine.Flush(session, emit); err != nil {
+			t.Fatal(err)
+		}
+		return archive, results, window
+	}
+	check := func(archive *AudioArchive, results []AudioStreamResult) []ArchivedResult {
+		t.Helper()
+		records, err := archive.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		final := 0
+		for _, result := range results {
+			record, err := archive.Load(result.ResultID)
+			if result.Partial {
+				if err == nil {
+					t.Errorf("partial result %s should not be archived", result.ResultID)
+				}
+				continue
+			}
+			final++
+			if err != nil {
+				t.Fatalf("Load(%s) error = %v", result.ResultID, err)
+			}
+			if record.StreamID != "cat/1" || record.Emotion != result.Emotion || record.Confidence != result.Confidence || record.SampleRate != 8000 {
+				t.Errorf("record = %+v, want linked to result %+v", record, result)
+			}
+			path, _ := archive.AudioPath(result.ResultID)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			audio, err := decodeAudioFile(data)
+			if err != nil {
+				t.Fatalf("decodeAudioFile(archived) error = %v", err)
+			}
+			if math.Abs(float64(len(audio.Samples))/8000-record.Duration) > 1e-9 {
+				t.Errorf("archived %d samples, record duration %.3f", len(audio.Samples), record.Duration)
+			}
+		}
+		if final == 0 || len(records) != final {
+			t.Errorf("archived %d entries, want one per final result (%d)", len(records), final)
+		}
+		return records
+	}
+
+	archive, results, window := run(AudioStreamConfig{SampleRate: 8000, BufferSize: 1024, Deterministic: true}, tone)
+	check(archive, results)
+	for _, result := range results {
+		// 除结束流时的剩余样本外，每个窗口都完整归档
+		record, err := archive.Load(result.ResultID)
+		if err == nil && !result.Final && (record.Duration != float64(window)/8000 || record.Features.Energy == 0) {
+			t.Errorf("window record = %+v, want one full window (%d samples) with features", record, window)
+		}
+	}
+
+	policy := DefaultTriggerPolicy()
+	archive, results, window = run(AudioStreamConfig{SampleRate: 8000, BufferSize: 1024, Deterministic: true, Trigger: &policy}, append(tone, make([]float64, 8000)...))
+	for _, record := range check(archive, results) {
+		if record.Duration <= float64(window)/8000 {
+			t.Errorf("segment duration = %.3f, want more than one window", record.Duration)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"soundsdk/dsp"
+)
+
+// 音频文件解码：decodeAudioFile 把WAV/MP3解码为样本，DecodeAudio/LoadAudio 可选目标采样率（降采样前做抗混叠滤波）
+// 与多声道的合并方式（平均，或只取左/右声道）。
+
+// 多声道合并方式
+const (
+	ChannelMix   = "mix"   // 各声道平均（默认）
+	ChannelLeft  = "left"  // 只取第一个声道
+	ChannelRight = "right" // 只取第二个声道，单声道文件取唯一的声道
+)
+
+// DecodeOptions 音频文件解码选项
+type DecodeOptions struct {
+	SampleRate int    `json:"sampleRate"` // 目标采样率，为0时保持文件的采样率
+	Channel    string `json:"channel"`    // 多声道合并方式：mix/left/right，为空时为 mix
+}
+
+// Validate 校验解码选项
+func (o DecodeOptions) Validate() error {
+	if o.SampleRate < 0 {
+		return fmt.Errorf("decode: sampleRate %d must not be negative", o.SampleRate)
+	}
+	switch o.Channel {
+	case "", ChannelMix, ChannelLeft, ChannelRight:
+	default:
+		return fmt.Errorf("decode: unknown channel %q (available: %s, %s, %s)", o.Channel, ChannelMix, ChannelLeft, ChannelRight)
+	}
+	return nil
+}
+
+// channelWeights 按合并方式给出 channels 个声道交织样本合并为单声道时各声道的权重
+func (o DecodeOptions) channelWeights(channels int) []float64 {
+	weights := make([]float64, channels)
+	switch {
+	case o.Channel == ChannelLeft:
+		weights[0] = 1
+	case o.Channel == ChannelRight && channels > 1:
+		weights[1] = 1
+	case o.Channel == ChannelRight:
+		weights[0] = 1
+	default:
+		for i := range weights {
+			weights[i] = 1 / float64(channels)
+		}
+	}
+	return weights
+}
+
+// DecodeAudio 按文件头识别WAV/MP3，按选项解码为单声道样本
+func DecodeAudio(data []byte, options DecodeOptions) (*AudioData, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	var audio *AudioData
+	var err error
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		audio, err = decodeWAVAudio(bytes.NewReader(data), options)
+	case isMP3(data):
+		audio, err = decodeMP3(bytes.NewReader(data), options)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format (want WAV or MP3)", ErrInvalidAudioFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if options.SampleRate > 0 && options.SampleRate != audio.SampleRate {
+		audio = &AudioData{
+			Samples:    dsp.Resample(audio.Samples, audio.SampleRate, options.SampleRate),
+			SampleRate: options.SampleRate,
+		}
+	}
+	return audio, nil
+}
+
+// LoadAudio 读取并按选项解码音频文件，文件大小不超过 MaxAnalyzeFileBytes
+func LoadAudio(filePath string, options DecodeOptions) (*AudioData, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxAnalyzeFileBytes {
+		return nil, ErrAudioTooLong
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAudio(data, options)
+}
+
+// decodeAudioFile 按文件头识别WAV/MP3并解码为单声道样本（各声道平均，保持文件的采样率）
+func decodeAudioFile(data []byte) (*AudioData, error) {
+	return DecodeAudio(data, DecodeOptions{})
+}
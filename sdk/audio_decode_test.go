@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildStereoWAV 构造16位双声道PCM的WAV数据
+func buildStereoWAV(left, right []int16, sampleRate int) []byte {
+	interleaved := make([]int16, 0, 2*len(left))
+	for i := range left {
+		interleaved = append(interleaved, left[i], right[i])
+	}
+	data := buildWAV(interleaved, sampleRate)
+	binary.LittleEndian.PutUint16(data[22:], 2)                    // 声道数
+	binary.LittleEndian.PutUint32(data[28:], uint32(sampleRate*4)) // 每秒字节数
+	binary.LittleEndian.PutUint16(data[32:], 4)                    // 每帧字节数
+	return data
+}
+
+// TestDecodeAudioOptions 测试解码选项
+// 测试内容：
+// 1. 双声道文件的样本数等于帧数，不会因交织而翻倍
+// 2. mix 取两个声道的平均，left/right 只取对应声道
+// 3. 设置目标采样率时重采样，样本数按比例变化
+// 4. 非法选项报错；LoadAudio 从文件读取
+func TestDecodeAudioOptions(t *testing.T) {
+	left := []int16{16384, 16384, 16384, 16384}
+	right := []int16{-16384, 0, 0, 0}
+	wav := buildStereoWAV(left, right, 16000)
+
+	cases := []struct {
+		channel string
+		want    float64
+	}{
+		{"", 0},
+		{ChannelMix, 0},
+		{ChannelLeft, 0.5},
+		{ChannelRight, -0.5},
+	}
+	for _, c := range cases {
+		audio, err := DecodeAudio(wav, DecodeOptions{Channel: c.channel})
+		if err != nil {
+			t.Fatalf("DecodeAudio(%q) error = %v", c.channel, err)
+		}
+		if len(audio.Samples) != len(left) {
+			t.Fatalf("DecodeAudio(%q) 样本数 = %d, want %d", c.channel, len(audio.Samples), len(left))
+		}
+		if audio.Samples[0] != c.want {
+			t.Errorf("DecodeAudio(%q) 第一个样本 = %v, want %v", c.channel, audio.Samples[0], c.want)
+		}
+	}
+
+	tone := make([]int16, 16000)
+	for i, v := range generateTestAudio(440, 1, 16000) {
+		tone[i] = int16(v * 16000)
+	}
+	audio, err := DecodeAudio(buildStereoWAV(tone, tone, 16000), DecodeOptions{SampleRate: 8000})
+	if err != nil {
+		t.Fatalf("DecodeAudio(sampleRate) error = %v", err)
+	}
+	if audio.SampleRate != 8000 || len(audio.Samples) != 8000 {
+		t.Errorf("重采样后 = %dHz, %d 个样本, want 8000Hz, 8000 个样本", audio.SampleRate, len(audio.Samples))
+	}
+
+	for _, options := range []DecodeOptions{{SampleRate: -1}, {Channel: "center"}} {
+		if _, err := DecodeAudio(wav, options); err == nil {
+			t.Errorf("DecodeAudio(%+v) 应报错", options)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "stereo.wav")
+	if err := os.WriteFile(path, wav, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err := LoadAudio(path, DecodeOptions{Channel: ChannelLeft}); err != nil || audio.Samples[1] != 0.5 {
+		t.Errorf("LoadAudio() = %v, %v", audio, err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"log"
+	"math"
+)
+
+// 特征提取与样本库共用的特征结构与基础计算（能量、过零率、汉明窗、余弦相似度）
+
+// AudioFeature 详细的音频特征
+type AudioFeature struct {
+	WindowIndex      int     // 窗口索引
+	StartTime        float64 // 窗口开始时间（秒）
+	EndTime          float64 // 窗口结束时间（秒）
+	Energy           float64 // 音频能量
+	ZeroCrossRate    float64 // 过零率
+	RootMeanSquare   float64 // 均方根值
+	PeakFreq         float64 // 峰值频率
+	SpectralCentroid float64 // 频谱质心
+	SpectralRolloff  float64 // 频谱滚降点
+	FundamentalFreq  float64 // 基频
+	Pitch            float64 // 音高
+	Duration         float64 // 持续时间
+
+	Custom map[string]float64 `json:",omitempty"` // RegisterFeature 注册的自定义特征，没有注册时为空
+}
+
+// AudioFeatures 简化的音频特征，归档条目记录识别所用的特征
+type AudioFeatures struct {
+	Energy           float64
+	Pitch            float64
+	Duration         float64
+	ZeroCrossRate    float64
+	RootMeanSquare   float64
+	PeakFreq         float64
+	SpectralCentroid float64
+	SpectralRolloff  float64
+	FundamentalFreq  float64
+}
+
+// applyHammingWindow 应用汉明窗函数，系数见 hammingWindow
+func applyHammingWindow(data []float64) []float64 {
+	windowedData := make([]float64, len(data))
+	multiplyWindow(windowedData, data, hammingWindow(len(data)))
+	return windowedData
+}
+
+// calculateEnergy 计算音频能量
+func calculateEnergy(data []float64) float64 {
+	if len(data) == 0 {
+		return 0.0
+	}
+
+	energy := 0.0
+	for _, sample := range data {
+		energy += sample * sample
+	}
+
+	return energy
+}
+
+// calculateZeroCrossRate 计算过零率
+func calculateZeroCrossRate(data []float64) float64 {
+	if len(data) <= 1 {
+		return 0.0
+	}
+
+	// 预处理数据，移除直流分量
+	mean := 0.0
+	for _, sample := range data {
+		mean += sample
+	}
+	mean /= float64(len(data))
+
+	centeredData := make([]float64, len(data))
+	for i, sample := range data {
+		centeredData[i] = sample - mean
+	}
+
+	crossCount := 0.0
+	for i := 1; i < len(centeredData); i++ {
+		if (centeredData[i-1] >= 0 && centeredData[i] < 0) || (centeredData[i-1] < 0 && centeredData[i] >= 0) {
+			crossCount++
+		}
+	}
+
+	// 如果没有找到过零点，尝试使用原始数据
+	if crossCount == 0 {
+		for i := 1; i < len(data); i++ {
+			if (data[i-1] >= 0 && data[i] < 0) || (data[i-1] < 0 && data[i] >= 0) {
+				crossCount++
+			}
+		}
+	}
+
+	zcr := crossCount / float64(len(data)-1)
+	log.Printf("过零率计算: 找到 %.1f 个过零点, 过零率=%.6f", crossCount, zcr)
+
+	return zcr
+}
+
+// cosineSimilarity 计算余弦相似度
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dotProduct, magnitudeA, magnitudeB float64
+
+	for i := 0; i < len(a); i++ {
+		dotProduct += a[i] * b[i]
+		magnitudeA += a[i] * a[i]
+		magnitudeB += b[i] * b[i]
+	}
+
+	magnitudeA = math.Sqrt(magnitudeA)
+	magnitudeB = math.Sqrt(magnitudeB)
+
+	if magnitudeA == 0 || magnitudeB == 0 {
+		return 0
+	}
+
+	return dotProduct / (magnitudeA * magnitudeB)
+}
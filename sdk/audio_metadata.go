@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// 样本的音频元数据
+//
+// 样本库里只有特征，看不出样本来自什么样的音频：8kHz 的电话录音与 48kHz 的手机录音混在一起时，
+// 高频相关的特征（峰值频率、频谱衰减点）系统性偏低，排查跨设备的特征漂移只能回头去翻原文件。
+// 构建样本库时为每个样本记录源音频的元数据：
+//   duration    源音频时长（秒），标注片段的时长见 Quality.Duration
+//   sampleRate  源音频的采样率（重采样前），录制会话为抽取后的有效采样率
+//   channels    声道数
+//   bitDepth    PCM 位深，MP3 等有损编码为0
+//   codec       pcm / float / mp3 / stream（录制会话）
+//   recordedAt  录制时间：WAV 的 LIST/INFO ICRD、MP3 的 ID3 年份或日期，都没有时取文件修改时间
+//   dateSource  录制时间的来源 riff / id3 / mtime
+// library build 与 library prune 的 -min-sample-rate 可以剔除采样率低于阈值的样本（如电话质量的8kHz样本）。
+
+// 编码与录制时间来源
+const (
+	CodecPCM    = "pcm"
+	CodecFloat  = "float"
+	CodecMP3    = "mp3"
+	CodecStream = "stream"
+
+	DateSourceRIFF  = "riff"
+	DateSourceID3   = "id3"
+	DateSourceMtime = "mtime"
+)
+
+// AudioMetadata 样本源音频的元数据
+type AudioMetadata struct {
+	Duration   float64 `json:"duration"`
+	SampleRate int     `json:"sampleRate"`
+	Channels   int     `json:"channels"`
+	BitDepth   int     `json:"bitDepth,omitempty"`
+	Codec      string  `json:"codec"`
+	RecordedAt string  `json:"recordedAt,omitempty"`
+	DateSource string  `json:"dateSource,omitempty"`
+}
+
+// probeAudioMetadata 从文件头读取编码、声道数、位深与标签中的录制时间，时长与采样率由调用方按解码结果补全
+func probeAudioMetadata(data []byte) AudioMetadata {
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return probeWAV(data)
+	case isMP3(data):
+		return probeMP3(data)
+	}
+	return AudioMetadata{}
+}
+
+// probeWAV 读取 fmt 块与 LIST/INFO 中的 ICRD（创建日期）
+func probeWAV(data []byte) AudioMetadata {
+	metadata := AudioMetadata{Codec: CodecPCM}
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		body := data[offset+8:]
+		if size < 0 || size > len(body) {
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch {
+		case id == "fmt " && len(body) >= 16:
+			format := binary.LittleEndian.Uint16(body[0:])
+			if format == 0xFFFE && len(body) >= 26 { // WAVE_FORMAT_EXTENSIBLE，实际格式在子格式GUID的前两个字节
+				format = binary.LittleEndian.Uint16(body[24:])
+			}
+			if format == 3 {
+				metadata.Codec = CodecFloat
+			}
+			metadata.Channels = int(binary.LittleEndian.Uint16(body[2:]))
+			metadata.SampleRate = int(binary.LittleEndian.Uint32(body[4:]))
+			metadata.BitDepth = int(binary.LittleEndian.Uint16(body[14:]))
+		case id == "LIST" && len(body) >= 4 && string(body[:4]) == "INFO":
+			for sub := 4; sub+8 <= len(body); {
+				subSize := int(binary.LittleEndian.Uint32(body[sub+4:]))
+				if subSize < 0 || sub+8+subSize > len(body) {
+					break
+				}
+				if string(body[sub:sub+4]) == "ICRD" {
+					if date := cleanTagText(body[sub+8 : sub+8+subSize]); date != "" {
+						metadata.RecordedAt, metadata.DateSource = date, DateSourceRIFF
+					}
+				}
+				sub += 8 + subSize + subSize%2
+			}
+		}
+		offset += 8 + size + size%2 // 块按偶数字节对齐
+	}
+	return metadata
+}
+
+// probeMP3 读取第一个 MPEG 帧头的声道模式与 ID3 标签中的录制时间（ID3v2 的 TDRC/TYER，其次 ID3v1 的年份）
+func probeMP3(data []byte) AudioMetadata {
+	metadata := AudioMetadata{Codec: CodecMP3}
+
+	offset := 0
+	if len(data) >= 10 && string(data[:3]) == "ID3" {
+		size := syncsafe(data[6:10])
+		if data[5]&0x10 != 0 { // 带页脚
+			size += 10
+		}
+		if end := 10 + size; end <= len(data) {
+			metadata.RecordedAt = id3v2Date(data[10:end], data[3])
+		}
+		offset = 10 + size
+	}
+	if metadata.RecordedAt == "" && len(data) >= 128 && string(data[len(data)-128:len(data)-125]) == "TAG" {
+		metadata.RecordedAt = cleanTagText(data[len(data)-128+93 : len(data)-128+97])
+	}
+	if metadata.RecordedAt != "" {
+		metadata.DateSource = DateSourceID3
+	}
+
+	// 声道模式：0 立体声，1 联合立体声，2 双声道，3 单声道
+	for i := offset; i+4 <= len(data); i++ {
+		if data[i] == 0xFF && data[i+1]&0xE0 == 0xE0 {
+			metadata.Channels = 2
+			if data[i+3]>>6 == 3 {
+				metadata.Channels = 1
+			}
+			break
+		}
+	}
+	return metadata
+}
+
+// id3v2Date 从 ID3v2.3/2.4 的标签帧中读取录制时间
+func id3v2Date(tag []byte, version byte) string {
+	dates := map[string]string{}
+	for offset := 0; offset+10 <= len(tag); {
+		id := string(tag[offset : offset+4])
+		if tag[offset] == 0 { // 填充
+			break
+		}
+		var size int
+		if version >= 4 {
+			size = syncsafe(tag[offset+4 : offset+8])
+		} else {
+			size = int(binary.BigEndian.Uint32(tag[offset+4:]))
+		}
+		if size <= 0 || offset+10+size > len(tag) {
+			break
+		}
+		if id == "TDRC" || id == "TYER" {
+			dates[id] = decodeTextFrame(tag[offset+10 : offset+10+size])
+		}
+		offset += 10 + size
+	}
+	if date := dates["TDRC"]; date != "" {
+		return date
+	}
+	return dates["TYER"]
+}
+
+// decodeTextFrame 解码 ID3v2 文本帧，日期只含 ASCII 字符，UTF-16 编码时去掉字节序标记与零字节即可
+func decodeTextFrame(frame []byte) string {
+	if len(frame) == 0 {
+		return ""
+	}
+	text := frame[1:]
+	switch frame[0] {
+	case 1, 2: // UTF-16（带字节序标记）/ UTF-16BE
+		var ascii []byte
+		for _, b := range text {
+			if b != 0 && b != 0xFF && b != 0xFE {
+				ascii = append(ascii, b)
+			}
+		}
+		text = ascii
+	}
+	return cleanTagText(text)
+}
+
+// cleanTagText 去掉标签文本末尾的零字节与空白
+func cleanTagText(text []byte) string {
+	return strings.TrimSpace(strings.TrimRight(string(text), "\x00"))
+}
+
+// syncsafe 解码 ID3v2 的同步安全整数（每字节7位）
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// loadAudioWithMetadata 读取并解码音频文件，同时返回其元数据；标签中没有录制时间时取文件修改时间
+func loadAudioWithMetadata(filePath string) (*AudioData, *AudioMetadata, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() > MaxAnalyzeFileBytes {
+		return nil, nil, ErrAudioTooLong
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	audio, err := DecodeAudio(data, DecodeOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata := probeAudioMetadata(data)
+	metadata.SampleRate = audio.SampleRate
+	metadata.Duration = float64(len(audio.Samples)) / float64(audio.SampleRate)
+	if metadata.RecordedAt == "" {
+		metadata.RecordedAt, metadata.DateSource = info.ModTime().UTC().Format(time.RFC3339), DateSourceMtime
+	}
+	return audio, &metadata, nil
+}
+
+// recordingMetadata 录制会话的元数据，录制时间取录制文件的修改时间
+func recordingMetadata(recordingPath string, audio *AudioData) *AudioMetadata {
+	metadata := &AudioMetadata{
+		Duration:   float64(len(audio.Samples)) / float64(audio.SampleRate),
+		SampleRate: audio.SampleRate,
+		Channels:   1,
+		Codec:      CodecStream,
+	}
+	if info, err := os.Stat(recordingPath); err == nil {
+		metadata.RecordedAt, metadata.DateSource = info.ModTime().UTC().Format(time.RFC3339), DateSourceMtime
+	}
+	return metadata
+}
+
+// ExcludeLowSampleRate 剔除源音频采样率低于 minRate 的样本并重新计算统计信息，返回剔除的样本数
+// 没有元数据的样本（旧样本库）保留；样本被全部剔除的情感从样本库中移除
+func (sl *SampleLibrary) ExcludeLowSampleRate(minRate int) int {
+	removed := 0
+	for emotion, samples := range sl.Samples {
+		kept := samples[:0]
+		for _, sample := range samples {
+			if sample.Metadata != nil && sample.Metadata.SampleRate < minRate {
+				log.Printf("剔除低采样率样本: %s (情感=%s, 采样率=%d Hz)", sample.FilePath, emotion, sample.Metadata.SampleRate)
+				removed++
+				continue
+			}
+			kept = append(kept, sample)
+		}
+		if len(kept) == 0 {
+			delete(sl.Samples, emotion)
+			delete(sl.Statistics, emotion)
+			continue
+		}
+		sl.Samples[emotion] = kept
+	}
+
+	if removed > 0 {
+		sl.NeedUpdate = true
+		sl.updateStatistics()
+	}
+	return removed
+}
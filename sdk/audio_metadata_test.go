@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestAudioMetadata 测试样本源音频元数据的读取
+// 测试内容：
+// 1. WAV 的编码、声道数、位深与 LIST/INFO 中的 ICRD 日期
+// 2. MP3 的声道模式，ID3v2.3 TYER、ID3v2.4 TDRC（UTF-16）与 ID3v1 年份
+// 3. 标签中没有日期时取文件修改时间，时长与采样率按解码结果
+// 4. 样本目录构建的样本带元数据，ExcludeLowSampleRate 剔除低采样率样本，没有元数据的样本保留
+func TestAudioMetadata(t *testing.T) {
+	tone := func(rate int) []byte {
+		samples := make([]int16, rate/2)
+		for i := range samples {
+			samples[i] = int16(8000 * math.Sin(2*math.Pi*500*float64(i)/float64(rate)))
+		}
+		return buildWAV(samples, rate)
+	}
+
+	// 在 data 块后追加 LIST/INFO 块
+	var info bytes.Buffer
+	info.WriteString("INFOICRD")
+	binary.Write(&info, binary.LittleEndian, uint32(11))
+	info.WriteString("2024-03-01\x00\x00") // 奇数长度补齐一个字节
+	wav := append(tone(16000), []byte("LIST")...)
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(info.Len()))
+	wav = append(wav, info.Bytes()...)
+	got := probeAudioMetadata(wav)
+	if got.Codec != CodecPCM || got.Channels != 1 || got.BitDepth != 16 || got.RecordedAt != "2024-03-01" || got.DateSource != DateSourceRIFF {
+		t.Errorf("probe WAV = %+v", got)
+	}
+
+	// ID3v2.3 TYER，单声道 MPEG 帧
+	frame := []byte{0xFF, 0xFB, 0x90, 0xC4}
+	tyer := append([]byte("TYER\x00\x00\x00\x05\x00\x00"), []byte("\x002019")...)
+	mp3 := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(tyer))}, tyer...)
+	mp3 = append(mp3, frame...)
+	if got := probeAudioMetadata(mp3); got.Codec != CodecMP3 || got.Channels != 1 || got.RecordedAt != "2019" || got.DateSource != DateSourceID3 {
+		t.Errorf("probe ID3v2.3 = %+v", got)
+	}
+
+	// ID3v2.4 TDRC，UTF-16 编码，立体声帧
+	text := []byte{1, 0xFF, 0xFE, '2', 0, '0', 0, '2', 0, '2', 0, '-', 0, '0', 0, '5', 0}
+	tdrc := append([]byte{'T', 'D', 'R', 'C', 0, 0, 0, byte(len(text)), 0, 0}, text...)
+	mp3 = append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, byte(len(tdrc))}, tdrc...)
+	mp3 = append(mp3, 0xFF, 0xFB, 0x90, 0x04)
+	if got := probeAudioMetadata(mp3); got.Channels != 2 || got.RecordedAt != "2022-05" {
+		t.Errorf("probe ID3v2.4 = %+v", got)
+	}
+
+	// ID3v1 年份
+	v1 := make([]byte, 128)
+	copy(v1, "TAG")
+	copy(v1[93:], "2017")
+	if got := probeAudioMetadata(append(append([]byte(nil), frame...), v1...)); got.RecordedAt != "2017" {
+		t.Errorf("probe ID3v1 = %+v", got)
+	}
+
+	// 没有日期标签时取文件修改时间
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "hungry"), 0755)
+	phone := filepath.Join(dir, "hungry", "phone.wav")
+	os.WriteFile(phone, tone(8000), 0644)
+	os.WriteFile(filepath.Join(dir, "hungry", "mic.wav"), tone(44100), 0644)
+	mtime := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(phone, mtime, mtime)
+	_, metadata, err := loadAudioWithMetadata(phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.SampleRate != 8000 || math.Abs(metadata.Duration-0.5) > 0.01 ||
+		metadata.RecordedAt != "2023-07-01T12:00:00Z" || metadata.DateSource != DateSourceMtime {
+		t.Errorf("loadAudioWithMetadata = %+v", metadata)
+	}
+
+	processor := NewSampleProcessor(AudioStreamConfig{})
+	if err := processor.ProcessDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, sample := range processor.Library.Samples["hungry"] {
+		if sample.Metadata == nil || sample.Metadata.Codec != CodecPCM {
+			t.Errorf("%s metadata = %+v", sample.FilePath, sample.Metadata)
+		}
+	}
+	processor.Library.AddSample(AudioSample{FilePath: "old.wav", Emotion: "hungry"})
+	if removed := processor.Library.ExcludeLowSampleRate(16000); removed != 1 {
+		t.Errorf("ExcludeLowSampleRate removed %d, want 1", removed)
+	}
+	if n := len(processor.Library.Samples["hungry"]); n != 2 {
+		t.Errorf("kept %d samples, want 2 (44.1kHz and one without metadata)", n)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// 缓冲区落盘
+//
+// 移动系统在内存紧张时会直接杀掉后台的应用，正在录的一声叫声还在流的缓冲区里，重启后就丢了。
+// SDK配置设置 spillDir 后，每个流的未处理样本同时追加写入该目录下的一个小文件；窗口处理完后
+// 已消耗的样本超过缓冲区剩余样本时重写文件，文件大小与缓冲区相当。应用重启后以相同的流ID和
+// "resume": true 调用 StartStreamEx，缓冲区从文件恢复，接着送入的音频与之前的样本连成一段。
+//
+// 只恢复采集到的音频，段内累积评分、情感平滑等状态从头开始。文件只在进程被杀时保留：写入依赖
+// 系统页缓存，不逐次 fsync（进程被杀不丢页缓存，整机断电则可能丢失最后的数据）；StopStream 与
+// ReleaseSDK 正常结束流时删除文件。
+//
+// 文件格式：8字节标识 MTSPILL1，8字节小端 int64 为文件中第一个样本在流中的序号，之后为小端 float32 样本。
+
+// spillMagic 落盘文件的标识
+var spillMagic = []byte("MTSPILL1")
+
+// spillHeaderSize 落盘文件头的字节数
+const spillHeaderSize = 16
+
+// bufferSpill 流的缓冲区落盘文件，方法可在nil上调用（未开启落盘）
+type bufferSpill struct {
+	path string
+	file *os.File
+	base int64 // 文件中第一个样本在流中的序号
+	err  error // 第一次写入失败的错误，失败后不再写入，识别照常进行
+}
+
+// spillPath 流的落盘文件路径，流ID编码后作为文件名
+func spillPath(dir, streamID string) string {
+	return filepath.Join(dir, base64.RawURLEncoding.EncodeToString([]byte(streamID))+".spill")
+}
+
+// openBufferSpill 打开流的落盘文件；resume 为 true 时返回文件中保存的样本及第一个样本的序号，
+// 否则丢弃之前留下的文件。文件不存在或已损坏时从空文件开始
+func openBufferSpill(dir, streamID string, resume bool) (*bufferSpill, []float64, int64, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, 0, fmt.Errorf("spill dir: %v", err)
+	}
+	path := spillPath(dir, streamID)
+
+	var samples []float64
+	var base int64
+	if resume {
+		var err error
+		samples, base, err = readSpill(path)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Discarding spill file of stream %s: %v\n", streamID, err)
+			samples, base = nil, 0
+		}
+	}
+
+	spill := &bufferSpill{path: path}
+	if err := spill.rewrite(base, samples); err != nil {
+		return nil, nil, 0, err
+	}
+	return spill, samples, base, nil
+}
+
+// readSpill 读取落盘文件中的样本与第一个样本的序号
+func readSpill(path string) ([]float64, int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) < spillHeaderSize || !bytes.Equal(data[:len(spillMagic)], spillMagic) {
+		return nil, 0, fmt.Errorf("invalid spill header")
+	}
+	base := int64(binary.LittleEndian.Uint64(data[len(spillMagic):spillHeaderSize]))
+	body := data[spillHeaderSize:]
+	// 进程在写入一个样本的中途被杀时丢弃不完整的尾部
+	samples := make([]float64, len(body)/4)
+	for i := range samples {
+		samples[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(body[i*4:])))
+	}
+	return samples, base, nil
+}
+
+// rewrite 以给定的样本重写落盘文件，先写临时文件再替换，中途被杀时保留原文件
+func (s *bufferSpill) rewrite(base int64, samples []float64) error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	tmp := s.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("spill: %v", err)
+	}
+	header := make([]byte, spillHeaderSize)
+	copy(header, spillMagic)
+	binary.LittleEndian.PutUint64(header[len(spillMagic):], uint64(base))
+	if _, err := file.Write(append(header, encodeSpillSamples(samples)...)); err != nil {
+		file.Close()
+		return fmt.Errorf("spill: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("spill: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("spill: %v", err)
+	}
+	if s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0); err != nil {
+		return fmt.Errorf("spill: %v", err)
+	}
+	s.base = base
+	return nil
+}
+
+// encodeSpillSamples 将样本编码为小端 float32
+func encodeSpillSamples(samples []float64) []byte {
+	data := make([]byte, len(samples)*4)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(v)))
+	}
+	return data
+}
+
+// fail 记录第一次写入失败并停止落盘
+func (s *bufferSpill) fail(err error) {
+	if s.err == nil {
+		s.err = err
+		fmt.Printf("Buffer spill disabled for %s: %v\n", s.path, err)
+	}
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
+
+// append 追加刚加入缓冲区的样本（调用方需持有会话的 bufferMu）
+func (s *bufferSpill) append(samples []float64) {
+	if s == nil || s.file == nil {
+		return
+	}
+	if _, err := s.file.Write(encodeSpillSamples(samples)); err != nil {
+		s.fail(err)
+	}
+}
+
+// compact 已处理的样本多于缓冲区剩余样本时，以缓冲区重写文件（调用方需持有会话的 bufferMu）
+func (s *bufferSpill) compact(session *AudioStreamSession) {
+	if s == nil || s.file == nil {
+		return
+	}
+	start := session.SamplesReceived - int64(len(session.Buffer))
+	if consumed := start - s.base; consumed <= 0 || consumed < int64(len(session.Buffer)) {
+		return
+	}
+	if err := s.rewrite(start, session.Buffer); err != nil {
+		s.fail(err)
+	}
+}
+
+// removeSpill 流正常结束后关闭并删除落盘文件；持有 bufferMu，之后仍在排队的异步处理不会重新写出文件
+func removeSpill(session *AudioStreamSession) {
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+
+	s := session.spill
+	if s == nil {
+		return
+	}
+	session.spill = nil
+	if s.file != nil {
+		s.file.Close()
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Failed to remove spill file %s: %v\n", s.path, err)
+	}
+}
+
+// closeSpill 关闭落盘文件但保留内容，同一流ID重新开始时用于旧会话
+func closeSpill(session *AudioStreamSession) {
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+
+	if s := session.spill; s != nil && s.file != nil {
+		s.file.Close()
+	}
+	session.spill = nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestBufferSpill 测试缓冲区落盘与恢复
+// 测试内容：
+// 1. 送入的样本追加写入落盘文件
+// 2. 以 resume 重新开始同一流时从文件恢复缓冲区与样本计数，不带 resume 时丢弃文件
+// 3. 处理后文件被压缩，大小与缓冲区相当
+// 4. 损坏的文件被丢弃；StopStream 删除文件
+func TestBufferSpill(t *testing.T) {
+	testDir := t.TempDir()
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatal(err)
+	}
+	spillDir := t.TempDir()
+	if !InitializeSDK(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, SampleLibraryPath: testDir + "/sample_library.json", Deterministic: true, SpillDir: spillDir}) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	defer ReleaseSDK()
+
+	sessionOf := func(id string) *AudioStreamSession {
+		mu.RLock()
+		defer mu.RUnlock()
+		return sdk.Sessions[id]
+	}
+
+	const id = "cat/1"
+	path := spillPath(spillDir, id)
+	if err := StartAudioStreamWithOptions(id, StreamOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 不足一个窗口，样本全部留在缓冲区
+	if err := SendAudioChunk(id, generateTestPCMData(0.05, 44100)); err != nil {
+		t.Fatal(err)
+	}
+	sent := len(sessionOf(id).Buffer)
+	samples, base, err := readSpill(path)
+	if err != nil || base != 0 || len(samples) != sent {
+		t.Fatalf("spill = %d samples from %d, %v; want %d from 0", len(samples), base, err, sent)
+	}
+	for i, v := range samples {
+		if float32(v) != float32(sessionOf(id).Buffer[i]) {
+			t.Fatalf("spill sample %d = %v, want %v", i, v, sessionOf(id).Buffer[i])
+		}
+	}
+
+	// 模拟应用被杀后重启：以 resume 重新开始同一流
+	if err := StartAudioStreamWithOptions(id, StreamOptions{Resume: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	session := sessionOf(id)
+	if len(session.Buffer) != sent || session.SamplesReceived != int64(sent) {
+		t.Fatalf("resumed buffer = %d samples, received %d; want %d", len(session.Buffer), session.SamplesReceived, sent)
+	}
+
+	// 继续送入音频，超过窗口后处理并压缩文件
+	for i := 0; i < 10; i++ {
+		if err := SendAudioChunk(id, generateTestPCMData(0.1, 44100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	session.bufferMu.Lock()
+	received, buffered := session.SamplesReceived, len(session.Buffer)
+	session.bufferMu.Unlock()
+	samples, base, err = readSpill(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base+int64(len(samples)) != received {
+		t.Errorf("spill covers %d..%d, want up to %d", base, base+int64(len(samples)), received)
+	}
+	if base > received-int64(buffered) || len(samples) > 2*buffered+4410 {
+		t.Errorf("spill not compacted: %d samples from %d, buffer %d of %d", len(samples), base, buffered, received)
+	}
+
+	// 不带 resume 时丢弃之前的文件
+	if err := StartAudioStreamWithOptions(id, StreamOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if session := sessionOf(id); len(session.Buffer) != 0 || session.SamplesReceived != 0 {
+		t.Errorf("fresh stream has %d buffered samples", len(session.Buffer))
+	}
+	if samples, _, err := readSpill(path); err != nil || len(samples) != 0 {
+		t.Errorf("fresh spill = %d samples, %v", len(samples), err)
+	}
+
+	// 损坏的文件被丢弃
+	if err := os.WriteFile(path, []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := StartAudioStreamWithOptions(id, StreamOptions{Resume: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if session := sessionOf(id); len(session.Buffer) != 0 {
+		t.Errorf("corrupt spill restored %d samples", len(session.Buffer))
+	}
+
+	if err := StopAudioStream(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("spill file still exists after stop: %v", err)
+	}
+}
@@ -9,7 +9,7 @@ if not exist ".\output" mkdir .\output
 
 REM 编译 Windows 版本
 echo 编译 Windows 版本...
-go build -buildmode=c-shared -o .\output\meowlib.dll mock_stream.go
+go build -buildmode=c-shared -o .\output\meowlib.dll .
 
 REM 复制到 Android 项目
 echo 复制到 Android 项目...
@@ -21,7 +21,7 @@ REM 如果有需要，可以添加 Android 和 iOS 的交叉编译
 echo 编译 Android arm64 版本...
 set GOOS=android
 set GOARCH=arm64
-go build -buildmode=c-shared -o .\output\meowlib_arm64.so mock_stream.go
+go build -buildmode=c-shared -o .\output\meowlib_arm64.so .
 copy /Y .\output\meowlib_arm64.so ..\android\app\src\main\jniLibs\arm64-v8a\libmeowlib.so
 
 REM 恢复环境变量
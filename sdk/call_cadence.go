@@ -0,0 +1,98 @@
+package main
+
+import "math"
+
+// 叫声节奏
+//
+// 同样是 for_food，偶尔叫一声和十秒内连叫五声是两回事，但每个结果只看到一声叫声。现在每个流
+// 记录最近一分钟内各声叫声的起止时间（距流开始的秒数），结果中附带节奏特征：
+// 处理引擎以叫声段（设置触发条件时按静默划分，见 trigger_policy.go）为一声，首尾相接的窗口与段合并为同一声。
+//   callsPerMinute     最近一分钟内第一声到最后一声的叫声频率（次/分钟），不足两声时为0
+//   interCallInterval  相邻两声起点的平均间隔（秒），不足两声时为0
+//   repetitions        当前连续叫声的次数：相邻两声的静默不超过 RepeatGap 时视为同一串
+// 节奏进入意图层：连续叫声达到 ContinuousRepetitions 次时视为连续模式，提示短语的强度提高一级
+// （如 for_food 从"饿了"变为"碗空了"），短语模板中也可以用 {{.Repetitions}}。
+
+// 叫声节奏参数
+const (
+	CadenceWindow         = 60.0 // 统计叫声频率的时间范围（秒）
+	RepeatGap             = 2.0  // 相邻两声之间的静默不超过该时长（秒）时视为同一串连续叫声
+	ContinuousRepetitions = 3    // 连续叫声达到该次数时视为连续模式
+	cadenceSameCall       = 0.05 // 起点相差不超过该时长（秒）的片段视为同一声（处理后保留的重叠样本会再次切出同一声）
+)
+
+// CallCadence 流最近的叫声节奏，附在结果中
+type CallCadence struct {
+	CallsPerMinute    float64 `json:"callsPerMinute"`
+	InterCallInterval float64 `json:"interCallInterval"`
+	Repetitions       int     `json:"repetitions"`
+}
+
+// Continuous 是否为连续叫声
+func (c CallCadence) Continuous() bool {
+	return c.Repetitions >= ContinuousRepetitions
+}
+
+// callSpan 一声叫声的起止时间（距流开始的秒数）
+type callSpan struct {
+	start float64
+	end   float64
+}
+
+// callCadence 流最近的叫声（调用方需保证同一流的叫声按时间顺序串行输入）
+type callCadence struct {
+	calls       []callSpan
+	repetitions int
+}
+
+// observe 输入一声叫声，返回包含该声在内的节奏
+func (c *callCadence) observe(start, end float64) CallCadence {
+	if n := len(c.calls); n > 0 && (math.Abs(c.calls[n-1].start-start) <= cadenceSameCall || start <= c.calls[n-1].end) {
+		// 同一声再次出现或与上一声首尾相接时只更新结束时间
+		c.calls[n-1].end = math.Max(c.calls[n-1].end, end)
+		return c.current()
+	}
+
+	if n := len(c.calls); n > 0 && start-c.calls[n-1].end <= RepeatGap {
+		c.repetitions++
+	} else {
+		c.repetitions = 1
+	}
+	c.calls = append(c.calls, callSpan{start: start, end: end})
+
+	// 只保留最近一分钟内的叫声
+	kept := c.calls[:0]
+	for _, call := range c.calls {
+		if start-call.start <= CadenceWindow {
+			kept = append(kept, call)
+		}
+	}
+	c.calls = kept
+	return c.current()
+}
+
+// current 返回当前的节奏，不输入新叫声
+func (c *callCadence) current() CallCadence {
+	cadence := CallCadence{Repetitions: c.repetitions}
+	if len(c.calls) < 2 {
+		return cadence
+	}
+	span := c.calls[len(c.calls)-1].start - c.calls[0].start
+	if span <= 0 {
+		return cadence
+	}
+	intervals := float64(len(c.calls) - 1)
+	cadence.InterCallInterval = span / intervals
+	cadence.CallsPerMinute = intervals / span * 60
+	return cadence
+}
+
+// escalateIntensity 连续叫声时提高一级叫声强度
+func escalateIntensity(intensity string) string {
+	switch intensity {
+	case IntensityLow:
+		return IntensityMedium
+	default:
+		return IntensityHigh
+	}
+}
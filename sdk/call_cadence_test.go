@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// TestCallCadence 测试叫声节奏统计
+// 测试内容：
+// 1. 叫声频率、平均间隔与连续次数
+// 2. 静默超过 RepeatGap 后连续次数重新计数，同一声重复出现不计数
+// 3. 一分钟之前的叫声不参与频率统计
+func TestCallCadence(t *testing.T) {
+	var c callCadence
+	if got := c.observe(0, 0.5); got != (CallCadence{Repetitions: 1}) {
+		t.Errorf("first call = %+v", got)
+	}
+	c.observe(1, 1.5)
+	got := c.observe(2, 2.5)
+	if got.Repetitions != 3 || !got.Continuous() || got.InterCallInterval != 1 || got.CallsPerMinute != 60 {
+		t.Errorf("three calls = %+v, want 3 repetitions one second apart", got)
+	}
+	if again := c.observe(2.01, 2.6); again.Repetitions != 3 {
+		t.Errorf("same call counted again: %+v", again)
+	}
+	if got := c.observe(10, 10.5); got.Repetitions != 1 || got.Continuous() {
+		t.Errorf("call after a long pause = %+v, want a new run", got)
+	}
+	if got := c.observe(100, 100.5); len(c.calls) != 1 || got.CallsPerMinute != 0 {
+		t.Errorf("old calls kept: %d calls, %+v", len(c.calls), got)
+	}
+
+}
+
+// TestEngineCallCadence 测试处理引擎按流统计叫声节奏
+// 测试内容：
+// 1. 设置触发条件时以静默划分的叫声段为一声，三声间隔0.8秒时连续次数为3，提示短语强度提高
+// 2. 未设置触发条件时首尾相接的窗口合并为同一声
+func TestEngineCallCadence(t *testing.T) {
+	const rate = 44100
+	policy := DefaultTriggerPolicy()
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 4096, Deterministic: true, Trigger: &policy})
+	last := func(engine *Engine, session *AudioStreamSession) AudioStreamResult {
+		t.Helper()
+		var result AudioStreamResult
+		err := engine.Drain(session, func(data []byte) {
+			result = AudioStreamResult{}
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatalf("bad result %s: %v", data, err)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	session := engine.NewSession("calls")
+	for i := 0; i < 3; i++ {
+		engine.Append(session, make([]float64, int(0.4*rate)))
+		engine.Append(session, generateTestAudio(440, 0.4, rate))
+	}
+	engine.Append(session, make([]float64, int(0.5*rate)))
+	result := last(engine, session)
+	if result.Partial || result.Cadence == nil || result.Cadence.Repetitions != 3 || math.Abs(result.Cadence.InterCallInterval-0.8) > 0.06 {
+		t.Fatalf("cadence = %+v (partial %v), want 3 calls 0.8s apart", result.Cadence, result.Partial)
+	}
+	vars := phraseVarsFor(CatProfile{}, "", result.Emotion, result.Confidence, math.Sqrt(result.Metadata.Features["Energy"]))
+	vars.Intensity = escalateIntensity(vars.Intensity)
+	vars.Repetitions = 3
+	if want := ComposeLocalizedMessage(session.Lang, vars); result.Message != want {
+		t.Errorf("continuous calls message = %q, want escalated %q", result.Message, want)
+	}
+
+	plain := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 4096, Deterministic: true})
+	session = plain.NewSession("tone")
+	plain.Append(session, generateTestAudio(440, 1, rate))
+	if result := last(plain, session); result.Cadence == nil || result.Cadence.Repetitions != 1 {
+		t.Errorf("cadence = %+v, want adjacent windows counted as one call", result.Cadence)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import "fmt"
+
+// 客户端采集时间
+//
+// 结果中的时间都是服务端时钟（到达、处理时间），与客户端同时录制的视频对齐时，网络延迟、缓冲与重连
+// 都会让两者错开。客户端可以为音频块带上其第一个样本的采集时间 captureTime（客户端时钟的毫秒时间戳，
+// /api/send 请求体与 WebSocket 的 {"data": [...]} 消息），引擎按样本序号把每个结果覆盖的样本
+// 映射回客户端时间，结果中附带 clientTime {"start", "end", "driftMs"}：
+//   - 每个带时间戳的块是一个锚点，样本的采集时间按最近的锚点加上样本数推算，不带时间戳的块沿用之前的锚点
+//   - driftMs 为所用锚点的时间戳与按第一个锚点和样本数推算的时间之差，持续增大说明客户端时钟与音频采样
+//     时钟存在漂移（或丢失了样本），每个新锚点都会校正映射
+// 流没有带时间戳的块时结果不含 clientTime；频域会话不支持。
+
+// CaptureTimestamper 支持客户端采集时间戳的处理器（Engine）
+type CaptureTimestamper interface {
+	ProcessAudioCaptured(streamID, requestID string, data []float64, captureTime int64) ([]byte, error)
+}
+
+// clockAnchor 序号为 Sample 的样本在客户端时钟 Capture 毫秒时采集
+type clockAnchor struct {
+	Sample  int64
+	Capture int64
+}
+
+// ClientTime 结果覆盖的样本在客户端时钟上的采集时间（毫秒时间戳）
+type ClientTime struct {
+	Start   int64 `json:"start"`   // 段内第一个样本的采集时间
+	End     int64 `json:"end"`     // 最后一个样本之后的采集时间
+	DriftMs int64 `json:"driftMs"` // 客户端时钟相对样本数推算时间的累计偏差
+}
+
+// processChunk 处理音频块，带采集时间且处理器支持时记录客户端时钟
+func (s *AudioServer) processChunk(streamID, requestID string, data []float64, captureTime *int64) ([]byte, error) {
+	if captureTime != nil {
+		if timestamper, ok := s.processor.(CaptureTimestamper); ok {
+			return timestamper.ProcessAudioCaptured(streamID, requestID, data, *captureTime)
+		}
+	}
+	return s.processor.ProcessAudioRequest(streamID, requestID, data)
+}
+
+// validateCaptureTime 采集时间须为正的毫秒时间戳
+func validateCaptureTime(captureTime int64) error {
+	if captureTime <= 0 {
+		return fmt.Errorf("captureTime must be a positive millisecond timestamp, got %d", captureTime)
+	}
+	return nil
+}
+
+// recordCapture 记录从序号 start 开始的样本的采集时间，并丢弃之后不再需要的锚点
+func (e *Engine) recordCapture(session *AudioStreamSession, start, captureTime int64) {
+	anchor := clockAnchor{Sample: start, Capture: captureTime}
+	if len(session.clientClock) == 0 {
+		session.clockOrigin = anchor
+	}
+	session.clientClock = append(session.clientClock, anchor)
+
+	// 保留未处理样本与当前段开始处之前的最后一个锚点
+	keep := session.SamplesReceived - int64(len(session.Buffer))
+	if session.segmentWindows > 0 && session.segmentStart < keep {
+		keep = session.segmentStart
+	}
+	for len(session.clientClock) > 1 && session.clientClock[1].Sample <= keep {
+		session.clientClock = session.clientClock[1:]
+	}
+}
+
+// clientTimeAt 按最近的锚点推算序号为 sample 的样本的采集时间，返回所用的锚点
+func (e *Engine) clientTimeAt(session *AudioStreamSession, sample int64) (int64, clockAnchor) {
+	anchor := session.clientClock[0]
+	for _, a := range session.clientClock[1:] {
+		if a.Sample > sample {
+			break
+		}
+		anchor = a
+	}
+	return anchor.Capture + samplesToMillis(sample-anchor.Sample, e.Config.SampleRate), anchor
+}
+
+// clientTime 结果覆盖序号 [start, end) 的样本时的客户端采集时间，流没有时间戳时返回nil
+func (e *Engine) clientTime(session *AudioStreamSession, start, end int64) *ClientTime {
+	if len(session.clientClock) == 0 || session.Format.Domain == DomainFrequency {
+		return nil
+	}
+	startMs, _ := e.clientTimeAt(session, start)
+	endMs, anchor := e.clientTimeAt(session, end)
+	origin := session.clockOrigin
+	expected := origin.Capture + samplesToMillis(anchor.Sample-origin.Sample, e.Config.SampleRate)
+	return &ClientTime{Start: startMs, End: endMs, DriftMs: anchor.Capture - expected}
+}
+
+// samplesToMillis 样本数对应的毫秒数
+func samplesToMillis(samples int64, sampleRate int) int64 {
+	return samples * 1000 / int64(sampleRate)
+}
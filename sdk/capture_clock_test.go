@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCaptureClock 测试客户端采集时间到结果的映射
+// 测试内容：
+// 1. 样本的采集时间按最近的锚点与样本数推算，driftMs 为锚点相对第一个锚点推算时间的偏差
+// 2. 已处理的样本之前的锚点被丢弃，只保留一个，漂移仍相对第一个锚点计算
+// 3. 引擎结果附带 clientTime，没有时间戳的流不附带
+// 4. /api/send 带 captureTime 时结果附带 clientTime，非正的时间戳返回 400
+func TestCaptureClock(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	rate := engine.Config.SampleRate
+	const t0 = int64(1700000000000)
+
+	session := engine.NewSession("u")
+	if engine.clientTime(session, 0, 100) != nil {
+		t.Error("stream without capture times should have no client time")
+	}
+	engine.Append(session, make([]float64, 1000))
+	engine.recordCapture(session, 0, t0)
+	engine.Append(session, make([]float64, 1000))
+	engine.Append(session, make([]float64, 1000))
+	engine.recordCapture(session, 2000, t0+samplesToMillis(2000, rate)+30)
+
+	got := engine.clientTime(session, 1500, 2500)
+	want := ClientTime{
+		Start:   t0 + samplesToMillis(1500, rate),
+		End:     t0 + samplesToMillis(2000, rate) + 30 + samplesToMillis(500, rate),
+		DriftMs: 30,
+	}
+	if got == nil || *got != want {
+		t.Errorf("clientTime() = %+v, want %+v", got, want)
+	}
+
+	session.Buffer = session.Buffer[2500:]
+	engine.Append(session, make([]float64, 1000))
+	engine.recordCapture(session, 3000, t0+samplesToMillis(3000, rate)+50)
+	if len(session.clientClock) != 2 || session.clientClock[0].Sample != 2000 {
+		t.Errorf("anchors after pruning = %+v, want from sample 2000", session.clientClock)
+	}
+	if got := engine.clientTime(session, 2500, 3500); got.DriftMs != 50 {
+		t.Errorf("drift = %d, want 50 relative to the first anchor", got.DriftMs)
+	}
+
+	// 引擎结果
+	window, _ := engine.NewSession("x").Strategy.frames(engine.Config.BufferSize)
+	resultClientTime := func(data []byte) *ClientTime {
+		t.Helper()
+		var result AudioStreamResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatal(err)
+		}
+		return result.ClientTime
+	}
+	data, err := engine.ProcessAudioCaptured("e", "", make([]float64, window), t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultClientTime(data); got == nil || got.Start != t0 || got.End != t0+samplesToMillis(int64(window), rate) {
+		t.Errorf("result clientTime = %+v, want start %d", got, t0)
+	}
+	data, _ = engine.ProcessAudioRequest("plain", "", make([]float64, window))
+	if got := resultClientTime(data); got != nil {
+		t.Errorf("result without capture time clientTime = %+v, want nil", got)
+	}
+
+	// /api/send
+	server := NewAudioServer(engine)
+	chunk := strings.TrimSuffix(strings.Repeat("0.01,", window), ",")
+	send := func(captureTime string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"streamId": "h", "data": [%s], "captureTime": %s}`, chunk, captureTime)
+		rec := httptest.NewRecorder()
+		server.handleSend(rec, httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader([]byte(body))))
+		return rec
+	}
+	rec := send(fmt.Sprint(t0))
+	if rec.Code != http.StatusOK || resultClientTime(rec.Body.Bytes()) == nil {
+		t.Errorf("send with captureTime = %d %s, want clientTime", rec.Code, rec.Body)
+	}
+	if rec := send("0"); rec.Code != http.StatusBadRequest {
+		t.Errorf("send with captureTime 0 status = %d, want 400", rec.Code)
+	}
+}
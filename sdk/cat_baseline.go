@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 猫咪长期基线
+//
+// 同样是一小时叫二十声，对话痨的暹罗猫是平常，对平时很安静的猫可能意味着不舒服。启用基线目录后，
+// 服务端按猫咪ID（/start、WebSocket 配置消息或 /send 中的 catId）长期累积叫声统计：基频的均值与离散度、
+// 相邻叫声的间隔、每天的叫声数，保存为基线目录下的 <catId>.json，重启后继续累积。
+// 关联了猫咪的流的每个最终结果附带 baseline 字段，给出本次叫声与基线的偏离；/baseline 返回猫咪的
+// 基线汇总。多个设备同时听到的同一声叫（时间线上合并为一条）只计一次。
+
+// 基线参数
+const (
+	MinBaselineCalls    = 30               // 累计叫声少于该数量时仍处于学习阶段
+	MaxBaselineDays     = 30               // 每日叫声数保留的天数
+	MaxCallInterval     = 10 * time.Minute // 相邻叫声间隔超过该值时不计入叫声频率（如主人外出、设备关闭）
+	recentCallSmoothing = 0.3              // 最近叫声间隔的指数平滑系数
+	baselineDateLayout  = "2006-01-02"
+)
+
+// CatBaseline 猫咪长期累积的叫声统计，按猫咪ID保存
+type CatBaseline struct {
+	CatID         string         `json:"catId"`
+	Calls         int64          `json:"calls"`         // 累计叫声数
+	PitchCount    int64          `json:"pitchCount"`    // 参与基频统计的叫声数
+	PitchMean     float64        `json:"pitchMean"`     // 基频均值（Hz）
+	PitchM2       float64        `json:"pitchM2"`       // 基频离差平方和，用于计算标准差
+	IntervalCount int64          `json:"intervalCount"` // 参与间隔统计的叫声数
+	IntervalMean  float64        `json:"intervalMean"`  // 相邻叫声的平均间隔（秒）
+	Daily         map[string]int `json:"daily"`         // 日期（本地时间 2006-01-02）-> 叫声数
+	UpdatedAt     int64          `json:"updatedAt"`     // 最近一次更新的时间（毫秒时间戳）
+}
+
+// BaselineDeviation 与基线的偏离
+type BaselineDeviation struct {
+	Learning          bool    `json:"learning"`                // 累计叫声不足 MinBaselineCalls，偏离仅供参考
+	Pitch             float64 `json:"pitch,omitempty"`         // 本次叫声的基频（Hz），结果中没有基频时为0
+	PitchZ            float64 `json:"pitchZ,omitempty"`        // 本次基频相对基线的标准分
+	PitchLow          float64 `json:"pitchLow"`                // 基线的典型基频下限（均值-2倍标准差）
+	PitchHigh         float64 `json:"pitchHigh"`               // 基线的典型基频上限（均值+2倍标准差）
+	CallRate          float64 `json:"callRate"`                // 最近的叫声频率（次/分钟）
+	TypicalCallRate   float64 `json:"typicalCallRate"`         // 基线的叫声频率（次/分钟）
+	TodayCount        int     `json:"todayCount"`              // 今天的叫声数
+	TypicalDailyCount float64 `json:"typicalDailyCount"`       // 之前各天的平均叫声数，没有历史时为0
+	Unusual           bool    `json:"unusual,omitempty"`       // 学习完成后，基频超出典型范围或叫声频率达到基线的2倍
+	UnusualReason     string  `json:"unusualReason,omitempty"` // pitch / call_rate
+}
+
+// DailyCount 一天的叫声数
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// CatBaselineSummary /baseline 的响应
+type CatBaselineSummary struct {
+	CatID     string            `json:"catId"`
+	Calls     int64             `json:"calls"`     // 累计叫声数
+	UpdatedAt int64             `json:"updatedAt"` // 最近一次更新的时间（毫秒时间戳）
+	Deviation BaselineDeviation `json:"deviation"` // 当前的叫声频率与今天的叫声数相对基线的偏离
+	Daily     []DailyCount      `json:"daily"`     // 保留的各天叫声数，从旧到新
+}
+
+// catBaselineState 猫咪基线及不需要保存的近期状态
+type catBaselineState struct {
+	baseline       CatBaseline
+	dirty          bool      // 有尚未保存的更新
+	lastCall       time.Time // 上一声叫的时间
+	recentInterval float64   // 最近叫声间隔的平滑值（秒）
+}
+
+// CatBaselineStore 按猫咪保存长期基线的目录
+type CatBaselineStore struct {
+	dir  string
+	mu   sync.Mutex
+	cats map[string]*catBaselineState
+}
+
+// NewCatBaselineStore 创建基线目录，目录不存在时自动创建
+func NewCatBaselineStore(dir string) (*CatBaselineStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create baseline dir: %v", err)
+	}
+	return &CatBaselineStore{dir: dir, cats: make(map[string]*catBaselineState)}, nil
+}
+
+// path 猫咪基线文件路径
+func (s *CatBaselineStore) path(catID string) string {
+	return filepath.Join(s.dir, catID+".json")
+}
+
+// state 返回猫咪的基线，首次访问时从文件加载（调用方需持有s.mu）
+func (s *CatBaselineStore) state(catID string) (*catBaselineState, error) {
+	if !validRequestID.MatchString(catID) {
+		return nil, fmt.Errorf("invalid cat id %q", catID)
+	}
+	if state, ok := s.cats[catID]; ok {
+		return state, nil
+	}
+
+	state := &catBaselineState{baseline: CatBaseline{CatID: catID}}
+	data, err := os.ReadFile(s.path(catID))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &state.baseline); err != nil {
+			return nil, fmt.Errorf("parse baseline %s: %v", catID, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("read baseline %s: %v", catID, err)
+	}
+	if state.baseline.Daily == nil {
+		state.baseline.Daily = make(map[string]int)
+	}
+	s.cats[catID] = state
+	return state, nil
+}
+
+// Observe 记录猫咪的一声叫并返回本次叫声与基线的偏离，pitch 为0表示结果中没有基频
+func (s *CatBaselineStore) Observe(catID string, pitch float64, now time.Time) (BaselineDeviation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.state(catID)
+	if err != nil {
+		return BaselineDeviation{}, err
+	}
+	b := &state.baseline
+
+	// 基频与加入本次之前的基线比较
+	deviation := state.deviation(pitch, now)
+
+	b.Calls++
+	if pitch > 0 {
+		b.PitchCount++
+		delta := pitch - b.PitchMean
+		b.PitchMean += delta / float64(b.PitchCount)
+		b.PitchM2 += delta * (pitch - b.PitchMean)
+	}
+	if !state.lastCall.IsZero() {
+		if interval := now.Sub(state.lastCall); interval > 0 && interval <= MaxCallInterval {
+			seconds := interval.Seconds()
+			b.IntervalCount++
+			b.IntervalMean += (seconds - b.IntervalMean) / float64(b.IntervalCount)
+			if state.recentInterval == 0 {
+				state.recentInterval = seconds
+			} else {
+				state.recentInterval += recentCallSmoothing * (seconds - state.recentInterval)
+			}
+		}
+	}
+	state.lastCall = now
+	b.Daily[now.Format(baselineDateLayout)]++
+	pruneBaselineDays(b.Daily)
+	b.UpdatedAt = now.UnixMilli()
+	state.dirty = true
+
+	// 叫声频率与今天的叫声数包含本次
+	counts := state.deviation(0, now)
+	deviation.CallRate, deviation.TypicalCallRate = counts.CallRate, counts.TypicalCallRate
+	deviation.TodayCount, deviation.TypicalDailyCount = counts.TodayCount, counts.TypicalDailyCount
+	deviation.markUnusual()
+	return deviation, nil
+}
+
+// Deviation 返回本次叫声与基线的偏离，不记录叫声（多个设备听到的同一声叫）
+func (s *CatBaselineStore) Deviation(catID string, pitch float64, now time.Time) (BaselineDeviation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.state(catID)
+	if err != nil {
+		return BaselineDeviation{}, err
+	}
+	deviation := state.deviation(pitch, now)
+	deviation.markUnusual()
+	return deviation, nil
+}
+
+// Summary 返回猫咪的基线汇总
+func (s *CatBaselineStore) Summary(catID string, now time.Time) (CatBaselineSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.state(catID)
+	if err != nil {
+		return CatBaselineSummary{}, err
+	}
+
+	summary := CatBaselineSummary{
+		CatID:     catID,
+		Calls:     state.baseline.Calls,
+		UpdatedAt: state.baseline.UpdatedAt,
+		Deviation: state.deviation(0, now),
+		Daily:     []DailyCount{},
+	}
+	summary.Deviation.markUnusual()
+	for date, count := range state.baseline.Daily {
+		summary.Daily = append(summary.Daily, DailyCount{Date: date, Count: count})
+	}
+	sort.Slice(summary.Daily, func(i, j int) bool { return summary.Daily[i].Date < summary.Daily[j].Date })
+	return summary, nil
+}
+
+// Save 将有更新的基线写入文件
+func (s *CatBaselineStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for catID, state := range s.cats {
+		if !state.dirty {
+			continue
+		}
+		data, err := json.MarshalIndent(state.baseline, "", "  ")
+		if err != nil {
+			return err
+		}
+		// 先写临时文件再改名，避免写到一半时进程退出留下损坏的基线
+		tmp := s.path(catID) + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return fmt.Errorf("write baseline %s: %v", catID, err)
+		}
+		if err := os.Rename(tmp, s.path(catID)); err != nil {
+			return fmt.Errorf("write baseline %s: %v", catID, err)
+		}
+		state.dirty = false
+	}
+	return nil
+}
+
+// Close 保存全部基线
+func (s *CatBaselineStore) Close() error {
+	return s.Save()
+}
+
+// deviation 按当前基线计算偏离，pitch 为0时不计算基频偏离
+func (state *catBaselineState) deviation(pitch float64, now time.Time) BaselineDeviation {
+	b := &state.baseline
+	deviation := BaselineDeviation{
+		Learning:   b.Calls < MinBaselineCalls,
+		Pitch:      pitch,
+		TodayCount: b.Daily[now.Format(baselineDateLayout)],
+	}
+
+	if b.PitchCount >= 2 {
+		std := math.Sqrt(b.PitchM2 / float64(b.PitchCount-1))
+		deviation.PitchLow = b.PitchMean - 2*std
+		deviation.PitchHigh = b.PitchMean + 2*std
+		if pitch > 0 && std > 0 {
+			deviation.PitchZ = (pitch - b.PitchMean) / std
+		}
+	}
+
+	// 距上一声叫太久时近期频率已无意义
+	if state.recentInterval > 0 && now.Sub(state.lastCall) <= MaxCallInterval {
+		deviation.CallRate = 60 / state.recentInterval
+	}
+	if b.IntervalMean > 0 {
+		deviation.TypicalCallRate = 60 / b.IntervalMean
+	}
+
+	today := now.Format(baselineDateLayout)
+	days, total := 0, 0
+	for date, count := range b.Daily {
+		if date != today {
+			days++
+			total += count
+		}
+	}
+	if days > 0 {
+		deviation.TypicalDailyCount = float64(total) / float64(days)
+	}
+	return deviation
+}
+
+// markUnusual 学习完成后，基频超出典型范围或叫声频率达到基线的2倍时标记为异常
+func (d *BaselineDeviation) markUnusual() {
+	if d.Learning {
+		return
+	}
+	switch {
+	case math.Abs(d.PitchZ) > 2:
+		d.Unusual, d.UnusualReason = true, "pitch"
+	case d.TypicalCallRate > 0 && d.CallRate >= 2*d.TypicalCallRate:
+		d.Unusual, d.UnusualReason = true, "call_rate"
+	}
+}
+
+// pruneBaselineDays 只保留最近 MaxBaselineDays 天的叫声数
+func pruneBaselineDays(daily map[string]int) {
+	if len(daily) <= MaxBaselineDays {
+		return
+	}
+	dates := make([]string, 0, len(daily))
+	for date := range daily {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	for _, date := range dates[:len(dates)-MaxBaselineDays] {
+		delete(daily, date)
+	}
+}
+
+// SetBaselineStore 设置猫咪长期基线目录，为nil时不累积基线
+func (s *AudioServer) SetBaselineStore(store *CatBaselineStore) {
+	s.baselines = store
+}
+
+// saveBaselines 流结束时保存有更新的基线
+func (s *AudioServer) saveBaselines() {
+	if s.baselines == nil {
+		return
+	}
+	if err := s.baselines.Save(); err != nil {
+		log.Printf("保存猫咪基线失败: %v", err)
+	}
+}
+
+// applyBaseline 记录关联猫咪的一声叫，并在结果JSON中附带与基线的偏离
+// counted 为 false 时（其他设备已记录同一声叫）只计算偏离
+func (s *AudioServer) applyBaseline(catID string, counted bool, result []byte, now time.Time) []byte {
+	if s.baselines == nil || catID == "" {
+		return result
+	}
+
+	var parsed struct {
+		Metadata struct {
+			Features map[string]float64 `json:"features"`
+		} `json:"metadata"`
+	}
+	json.Unmarshal(result, &parsed)
+	pitch := parsed.Metadata.Features["Pitch"]
+
+	var deviation BaselineDeviation
+	var err error
+	if counted {
+		deviation, err = s.baselines.Observe(catID, pitch, now)
+	} else {
+		deviation, err = s.baselines.Deviation(catID, pitch, now)
+	}
+	if err != nil {
+		log.Printf("更新猫咪基线失败: CatID=%s, %v", catID, err)
+		return result
+	}
+	return appendJSONField(result, "baseline", deviation)
+}
+
+// appendJSONField 在JSON对象末尾追加一个字段，保留原有字段的顺序；result 不是JSON对象时原样返回
+func appendJSONField(result []byte, key string, value interface{}) []byte {
+	trimmed := bytes.TrimSpace(result)
+	if len(trimmed) < 2 || trimmed[0] != '{' || trimmed[len(trimmed)-1] != '}' {
+		return result
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return result
+	}
+
+	body := bytes.TrimSpace(trimmed[:len(trimmed)-1])
+	out := make([]byte, 0, len(body)+len(key)+len(data)+4)
+	out = append(out, body...)
+	if len(body) > 1 {
+		out = append(out, ',')
+	}
+	out = append(out, fmt.Sprintf("%q:", key)...)
+	out = append(out, data...)
+	return append(out, '}')
+}
+
+// handleBaseline 返回猫咪的长期基线汇总：GET /baseline?catId=...
+func (s *AudioServer) handleBaseline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	if s.baselines == nil {
+		writeError(w, r, http.StatusNotFound, ErrCodeFeatureDisabled, "未启用猫咪基线", "")
+		return
+	}
+
+	catID := r.URL.Query().Get("catId")
+	if catID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingParameter, "catId参数缺失", "")
+		return
+	}
+	summary, err := s.baselines.Summary(catID, time.Now())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "读取猫咪基线失败", err.Error())
+		return
+	}
+	writeResponse(w, r, http.StatusOK, summary)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCatBaseline 测试猫咪长期基线
+// 测试内容：
+// 1. 累积基频范围、叫声频率与每天的叫声数，累计不足时处于学习阶段
+// 2. 学习完成后基频超出典型范围或叫声频率翻倍时标记异常
+// 3. 基线保存到文件，重新打开目录后继续累积；非法猫咪ID返回错误
+// 4. 结果JSON附带 baseline 字段，多个设备听到的同一声叫只计一次，/baseline 返回汇总
+func TestCatBaseline(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewCatBaselineStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 前一天每分钟叫一声，基频在 480~520Hz 之间
+	day := time.Date(2023, 11, 13, 8, 0, 0, 0, time.Local)
+	var deviation BaselineDeviation
+	for i := 0; i < MinBaselineCalls; i++ {
+		deviation, err = store.Observe("mimi", 480+float64(i%5)*10, day.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < MinBaselineCalls-1 && !deviation.Learning {
+			t.Fatalf("call %d should still be learning", i)
+		}
+	}
+	if deviation.TodayCount != MinBaselineCalls || deviation.TypicalDailyCount != 0 || deviation.TypicalCallRate != 1 {
+		t.Errorf("first day deviation = %+v", deviation)
+	}
+	if deviation.PitchLow >= 480 || deviation.PitchHigh <= 520 {
+		t.Errorf("typical pitch range = [%.0f, %.0f], want around 480~520", deviation.PitchLow, deviation.PitchHigh)
+	}
+
+	next := day.Add(24 * time.Hour)
+	if deviation, _ = store.Observe("mimi", 900, next); deviation.Learning || !deviation.Unusual || deviation.UnusualReason != "pitch" {
+		t.Errorf("900Hz call = %+v, want an unusual pitch", deviation)
+	}
+	if deviation.TodayCount != 1 || deviation.TypicalDailyCount != MinBaselineCalls {
+		t.Errorf("next day counts = %d/%.0f", deviation.TodayCount, deviation.TypicalDailyCount)
+	}
+	for i := 1; i <= 5; i++ {
+		deviation, _ = store.Observe("mimi", 500, next.Add(time.Duration(i)*15*time.Second))
+	}
+	if deviation.CallRate < 2*deviation.TypicalCallRate || deviation.UnusualReason != "call_rate" {
+		t.Errorf("calls every 15s = %+v, want an unusual call rate", deviation)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, _ := NewCatBaselineStore(dir)
+	summary, err := reopened.Summary("mimi", next)
+	if err != nil || summary.Calls != MinBaselineCalls+6 || len(summary.Daily) != 2 || summary.Daily[0].Date != "2023-11-13" {
+		t.Errorf("reopened summary = %+v, %v", summary, err)
+	}
+	if _, err := reopened.Observe("../mimi", 500, next); err == nil {
+		t.Error("cat id with path separators should be rejected")
+	}
+
+	if got := string(appendJSONField([]byte(`{"emotion":"hello"}`), "baseline", 1)); got != `{"emotion":"hello","baseline":1}` {
+		t.Errorf("appendJSONField = %s", got)
+	}
+	if got := string(appendJSONField([]byte(`{ }`), "baseline", 1)); got != `{"baseline":1}` {
+		t.Errorf("appendJSONField on empty object = %s", got)
+	}
+	if got := string(appendJSONField([]byte(`[1]`), "baseline", 1)); got != `[1]` {
+		t.Errorf("appendJSONField on array = %s", got)
+	}
+
+	server := NewAudioServer(NewMockAudioProcessor())
+	server.SetBaselineStore(reopened)
+	server.bindCat("kitchen", "mimi")
+	server.bindCat("bedroom", "mimi")
+	now := time.Now()
+	result := []byte(`{"status":"processed","emotion":"hello","confidence":0.8,"metadata":{"features":{"Pitch":500}}}`)
+	for _, streamID := range []string{"kitchen", "bedroom"} {
+		catID, counted := server.recordDetection(streamID, result, now)
+		var parsed struct {
+			Baseline *BaselineDeviation `json:"baseline"`
+		}
+		json.Unmarshal(server.applyBaseline(catID, counted, result, now), &parsed)
+		if parsed.Baseline == nil || parsed.Baseline.Pitch != 500 {
+			t.Errorf("%s result should carry the baseline deviation, got %+v", streamID, parsed.Baseline)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.handleBaseline(rec, httptest.NewRequest(http.MethodGet, "/baseline?catId=mimi", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil || summary.Calls != MinBaselineCalls+7 {
+		t.Errorf("/baseline = %s, want the overlapping detection counted once", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	server.handleBaseline(rec, httptest.NewRequest(http.MethodGet, "/baseline", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "catId") {
+		t.Errorf("/baseline without catId = %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+// CatProfile 猫咪档案，用于个性化识别结果
+type CatProfile struct {
+	ID       string         `json:"id"`                 // 猫咪ID
+	Name     string         `json:"name"`               // 猫咪名字，用于短语模板中的 {{.Name}}
+	Priors   *ContextPriors `json:"priors,omitempty"`   // 时间与上下文先验，为空时不调整识别结果
+	Baseline *VocalBaseline `json:"baseline,omitempty"` // 平时的声音特征，为空时健康提示以会话最初的叫声为基线
+}
+
+// streamPersona 每个流关联的猫咪档案、上下文、语言与情境先验
+type streamPersona struct {
+	Cat        CatProfile
+	Context    string
+	Lang       string
+	HintPriors map[string]float64 // 情境提示与直接注入的情感先验（见 context_hints.go）
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 多设备结果汇总
+//
+// 同一只猫常被多个设备同时监听（厨房与卧室），每个设备是独立的流，同一声叫会被各自识别一次。
+// 开始会话时（/start、WebSocket 配置消息或 /send 中的 catId）声明猫咪ID后，各流的最终结果按猫咪
+// 汇总为一条时间线：不同流在时间上重叠的检测视为同一声叫，合并为一条，情感取置信度最高的来源，
+// 所有来源一并列出。中间结果（partial）不进入时间线。
+
+const (
+	DefaultTimelineOverlap   = 500 * time.Millisecond // 两条检测的时间间隔在该范围内视为重叠
+	DefaultTimelineRetention = time.Hour              // 时间线保留的时长
+	MaxTimelineDetections    = 1000                   // 每只猫最多保留的检测条数
+)
+
+// DetectionSource 合并进一条检测的某个流的结果
+type DetectionSource struct {
+	StreamID   string  `json:"streamId"`
+	ResultID   string  `json:"resultId,omitempty"`
+	Emotion    string  `json:"emotion"`
+	Confidence float64 `json:"confidence"`
+}
+
+// TimelineDetection 时间线上的一条检测（一声叫）
+type TimelineDetection struct {
+	Start      int64             `json:"start"` // 第一个来源的音频到达时间（毫秒时间戳）
+	End        int64             `json:"end"`   // 最后一个来源的结果产生时间（毫秒时间戳）
+	Emotion    string            `json:"emotion"`
+	Label      string            `json:"label,omitempty"`
+	Confidence float64           `json:"confidence"`
+	Sources    []DetectionSource `json:"sources"`
+}
+
+// CatTimeline /timeline 的响应
+type CatTimeline struct {
+	CatID      string              `json:"catId"`
+	Streams    []string            `json:"streams"` // 当前关联该猫咪的流
+	Detections []TimelineDetection `json:"detections"`
+}
+
+// catTimelines 流与猫咪的关联及各猫咪的时间线
+type catTimelines struct {
+	mu         sync.Mutex
+	streams    map[string]string              // streamID -> catID
+	detections map[string][]TimelineDetection // catID -> 按开始时间排列的检测
+}
+
+// bindCat 将流关联到猫咪，catID 为空时解除关联
+func (s *AudioServer) bindCat(streamID, catID string) {
+	t := &s.timelines
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.streams == nil {
+		t.streams = make(map[string]string)
+		t.detections = make(map[string][]TimelineDetection)
+	}
+	if catID == "" {
+		delete(t.streams, streamID)
+		return
+	}
+	t.streams[streamID] = catID
+}
+
+// streamCat 流关联的猫咪ID，未关联时为空
+func (s *AudioServer) streamCat(streamID string) string {
+	t := &s.timelines
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.streams[streamID]
+}
+
+// recordDetection 将流的最终结果加入所关联猫咪的时间线，result 为处理器返回的JSON
+// 返回关联的猫咪ID（不是最终结果或流未关联猫咪时为空），以及该结果是否为新的一声叫（未与其他流的检测合并）
+func (s *AudioServer) recordDetection(streamID string, result []byte, now time.Time) (string, bool) {
+	var parsed struct {
+		Status     string  `json:"status"`
+		ResultID   string  `json:"resultId"`
+		Emotion    string  `json:"emotion"`
+		Label      string  `json:"label"`
+		Confidence float64 `json:"confidence"`
+		Partial    bool    `json:"partial"`
+		Metadata   struct {
+			Timing ResultTiming `json:"timing"`
+		} `json:"metadata"`
+	}
+	if len(result) == 0 || json.Unmarshal(result, &parsed) != nil {
+		return "", false
+	}
+	if parsed.Emotion == "" || parsed.Partial || parsed.Status == "waiting" {
+		return "", false
+	}
+
+	t := &s.timelines
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	catID, ok := t.streams[streamID]
+	if !ok {
+		return "", false
+	}
+
+	// 结果没有时间信息时以服务端收到结果的时间为准
+	start, end := parsed.Metadata.Timing.ReceivedAt, parsed.Metadata.Timing.ProcessEnd
+	if end == 0 {
+		end = now.UnixMilli()
+	}
+	if start == 0 || start > end {
+		start = end
+	}
+	source := DetectionSource{StreamID: streamID, ResultID: parsed.ResultID, Emotion: parsed.Emotion, Confidence: parsed.Confidence}
+	detection := TimelineDetection{
+		Start:      start,
+		End:        end,
+		Emotion:    parsed.Emotion,
+		Label:      parsed.Label,
+		Confidence: parsed.Confidence,
+		Sources:    []DetectionSource{source},
+	}
+
+	previous := len(t.detections[catID])
+	detections := mergeDetection(t.detections[catID], detection, DefaultTimelineOverlap.Milliseconds())
+	t.detections[catID] = pruneDetections(detections, now.Add(-DefaultTimelineRetention).UnixMilli())
+	return catID, len(detections) > previous
+}
+
+// mergeDetection 将检测并入时间线：与其他流的重叠检测合并，否则按开始时间插入
+func mergeDetection(detections []TimelineDetection, detection TimelineDetection, overlap int64) []TimelineDetection {
+	source := detection.Sources[0]
+	for i := len(detections) - 1; i >= 0; i-- {
+		existing := &detections[i]
+		overlapping := detection.Start <= existing.End+overlap && existing.Start <= detection.End+overlap
+		if !overlapping || existing.hasStream(source.StreamID) {
+			continue
+		}
+
+		existing.Sources = append(existing.Sources, source)
+		if detection.Start < existing.Start {
+			existing.Start = detection.Start
+		}
+		if detection.End > existing.End {
+			existing.End = detection.End
+		}
+		if detection.Confidence > existing.Confidence {
+			existing.Emotion = detection.Emotion
+			existing.Label = detection.Label
+			existing.Confidence = detection.Confidence
+		}
+		return detections
+	}
+
+	i := len(detections)
+	for i > 0 && detections[i-1].Start > detection.Start {
+		i--
+	}
+	detections = append(detections, TimelineDetection{})
+	copy(detections[i+1:], detections[i:])
+	detections[i] = detection
+	return detections
+}
+
+// hasStream 检测是否已包含该流的结果，同一个流的相邻结果是不同的叫声，不合并
+func (d *TimelineDetection) hasStream(streamID string) bool {
+	for _, source := range d.Sources {
+		if source.StreamID == streamID {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneDetections 删除早于 cutoff 的检测并限制条数
+func pruneDetections(detections []TimelineDetection, cutoff int64) []TimelineDetection {
+	first := 0
+	for first < len(detections) && detections[first].End < cutoff {
+		first++
+	}
+	if len(detections)-first > MaxTimelineDetections {
+		first = len(detections) - MaxTimelineDetections
+	}
+	return detections[first:]
+}
+
+// timeline 返回猫咪在 since（毫秒时间戳）之后的检测
+func (s *AudioServer) timeline(catID string, since int64) CatTimeline {
+	t := &s.timelines
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timeline := CatTimeline{CatID: catID, Streams: []string{}, Detections: []TimelineDetection{}}
+	for streamID, cat := range t.streams {
+		if cat == catID {
+			timeline.Streams = append(timeline.Streams, streamID)
+		}
+	}
+	sort.Strings(timeline.Streams)
+	for _, detection := range t.detections[catID] {
+		if detection.End >= since {
+			detection.Sources = append([]DetectionSource(nil), detection.Sources...)
+			timeline.Detections = append(timeline.Detections, detection)
+		}
+	}
+	return timeline
+}
+
+// handleTimeline 返回猫咪的汇总时间线：GET /timeline?catId=mimi&since=1700000000000
+func (s *AudioServer) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	catID := r.URL.Query().Get("catId")
+	if catID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingParameter, "catId参数缺失", "")
+		return
+	}
+	var since int64
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "since参数无效", "")
+			return
+		}
+		since = parsed
+	}
+	writeResponse(w, r, http.StatusOK, s.timeline(catID, since))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestCatTimeline 测试多设备结果按猫咪汇总
+// 测试内容：
+// 1. 关联同一只猫的两个流在时间上重叠的结果合并为一条检测，情感取置信度最高的来源
+// 2. 同一个流的相邻结果、中间结果与未关联猫咪的流不合并或不记录
+// 3. since 过滤与 /timeline 参数校验
+func TestCatTimeline(t *testing.T) {
+	server := NewAudioServer(NewMockAudioProcessor())
+	server.bindCat("kitchen", "mimi")
+	server.bindCat("bedroom", "mimi")
+
+	base := time.UnixMilli(1700000000000)
+	result := func(id, emotion string, confidence float64, at int64, partial bool) []byte {
+		data, _ := json.Marshal(map[string]interface{}{
+			"resultId":   id,
+			"emotion":    emotion,
+			"confidence": confidence,
+			"partial":    partial,
+			"metadata":   map[string]interface{}{"timing": ResultTiming{ReceivedAt: at, ProcessEnd: at + 300}},
+		})
+		return data
+	}
+	at := base.UnixMilli()
+	server.recordDetection("kitchen", result("k1", "hello", 0.55, at, false), base)
+	server.recordDetection("bedroom", result("b1", "for_food", 0.81, at+200, false), base)
+	server.recordDetection("kitchen", result("k2", "hello", 0.6, at+400, false), base)
+	server.recordDetection("bedroom", result("b2", "angry", 0.9, at+5000, true), base)
+	server.recordDetection("hallway", result("h1", "angry", 0.9, at, false), base)
+
+	timeline := server.timeline("mimi", 0)
+	if fmt.Sprint(timeline.Streams) != "[bedroom kitchen]" {
+		t.Errorf("streams = %v", timeline.Streams)
+	}
+	if len(timeline.Detections) != 2 {
+		t.Fatalf("detections = %+v, want 2", timeline.Detections)
+	}
+	merged := timeline.Detections[0]
+	if len(merged.Sources) != 2 || merged.Emotion != "for_food" || merged.Confidence != 0.81 || merged.Start != at || merged.End != at+500 {
+		t.Errorf("merged detection = %+v", merged)
+	}
+	if second := timeline.Detections[1]; len(second.Sources) != 1 || second.Sources[0].ResultID != "k2" {
+		t.Errorf("same-stream result should stay separate, got %+v", second)
+	}
+
+	if got := server.timeline("mimi", at+600).Detections; len(got) != 1 {
+		t.Errorf("since filter kept %d detections, want 1", len(got))
+	}
+
+	server.bindCat("bedroom", "")
+	if got := server.timeline("mimi", 0).Streams; len(got) != 1 {
+		t.Errorf("streams after unbind = %v", got)
+	}
+
+	for query, want := range map[string]int{
+		"":                      http.StatusBadRequest,
+		"?catId=mimi&since=abc": http.StatusBadRequest,
+		"?catId=mimi":           http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		server.handleTimeline(rec, httptest.NewRequest(http.MethodGet, "/timeline"+query, nil))
+		if rec.Code != want {
+			t.Errorf("GET /timeline%s = %d, want %d", query, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// 音频块序号
+//
+// 移动网络下 /api/send 超时后客户端会重发同一个音频块，而服务端可能已经处理过第一次的请求，
+// 重发的样本再次追加进缓冲区，之后每个分析窗口都错位。客户端可以为每个音频块带上递增的序号
+// （/api/send 请求体与 WebSocket 的 {"data": [...]} 消息中的 seq 字段）：
+//   - 序号不大于该流已接收的最大序号时视为重复（包括迟到的块），不再追加，HTTP 返回
+//     {"status": "duplicate"}，WebSocket 回复 {"type": "duplicate"}，重试因此是幂等的
+//   - 序号跳过了若干块时照常处理，并在响应中用 gap 报告缺失的序号范围 {"from": 5, "to": 7, "missing": 3}，
+//     WebSocket 另外回复 {"type": "gap"}，客户端可以据此判断结果是否可信
+//   - 流的第一个带序号的块确定起始序号；不带序号的块不参与检查
+// 序号状态保存在本副本中，/start 与 /stop 时清除，WebSocket 恢复会话时保留；多副本部署时同一个流的
+// 请求需由同一副本处理才能检测重复。同一个流带序号的块依次登记、处理并确认或撤销（见 serializeChunks），
+// 并发的重试不会在处理失败时撤销其他块的登记。
+
+// ChunkGap 缺失的音频块序号范围（含两端）
+type ChunkGap struct {
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+	Missing uint64 `json:"missing"`
+}
+
+// ChunkStats 流的音频块序号统计，/stop 响应与调试面板中提供
+type ChunkStats struct {
+	LastSeq    uint64 `json:"lastSeq"`    // 已接收的最大序号
+	Duplicates int    `json:"duplicates"` // 丢弃的重复块数
+	Missing    uint64 `json:"missing"`    // 缺失的块数
+}
+
+// chunkSequence 单个流的序号状态
+type chunkSequence struct {
+	ChunkStats
+	started   bool   // 是否已收到带序号的块
+	undoLast  uint64 // 接收当前块之前的 LastSeq 与 started，处理失败时回退
+	undoStart bool
+	busy      sync.Mutex // 从登记到处理完成期间持有，同一时间只有一个块在处理，撤销记录因此只需一份
+}
+
+// chunkSequences HTTP/WebSocket 服务中各流的音频块序号
+type chunkSequences struct {
+	mu      sync.Mutex
+	streams map[string]*chunkSequence
+}
+
+// stream 返回流的序号状态，不存在时创建（调用方需持有c.mu）
+func (c *chunkSequences) stream(streamID string) *chunkSequence {
+	if c.streams == nil {
+		c.streams = make(map[string]*chunkSequence)
+	}
+	state, ok := c.streams[streamID]
+	if !ok {
+		state = &chunkSequence{}
+		c.streams[streamID] = state
+	}
+	return state
+}
+
+// serializeChunks 等待流上一个带序号的块处理完成，返回的函数在本块处理完成（确认或撤销登记）后调用
+func (s *AudioServer) serializeChunks(streamID string) (done func()) {
+	s.chunks.mu.Lock()
+	state := s.chunks.stream(streamID)
+	s.chunks.mu.Unlock()
+
+	state.busy.Lock()
+	return state.busy.Unlock
+}
+
+// acceptChunk 登记音频块的序号：重复时返回 duplicate，跳号时返回缺失的范围
+// 调用方需通过 serializeChunks 持有流的处理锁直到处理完成，处理失败时调用 rejectChunk
+func (s *AudioServer) acceptChunk(streamID string, seq uint64) (duplicate bool, gap *ChunkGap) {
+	c := &s.chunks
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.stream(streamID)
+
+	switch {
+	case !state.started:
+	case seq <= state.LastSeq:
+		state.Duplicates++
+		return true, nil
+	case seq > state.LastSeq+1:
+		gap = &ChunkGap{From: state.LastSeq + 1, To: seq - 1, Missing: seq - 1 - state.LastSeq}
+		state.Missing += gap.Missing
+	}
+	state.undoLast, state.undoStart = state.LastSeq, state.started
+	state.LastSeq, state.started = seq, true
+	return false, gap
+}
+
+// rejectChunk 音频块处理失败时撤销登记，客户端重发同一序号时重新处理
+func (s *AudioServer) rejectChunk(streamID string, seq uint64, gap *ChunkGap) {
+	c := &s.chunks
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.streams[streamID]
+	if !ok || state.LastSeq != seq {
+		return
+	}
+	state.LastSeq, state.started = state.undoLast, state.undoStart
+	if gap != nil {
+		state.Missing -= gap.Missing
+	}
+}
+
+// resetChunks 清除流的序号状态
+func (s *AudioServer) resetChunks(streamID string) {
+	s.chunks.mu.Lock()
+	delete(s.chunks.streams, streamID)
+	s.chunks.mu.Unlock()
+}
+
+// chunkStats 流的序号统计，流没有带序号的块时返回nil
+func (s *AudioServer) chunkStats(streamID string) *ChunkStats {
+	s.chunks.mu.Lock()
+	defer s.chunks.mu.Unlock()
+	if state, ok := s.chunks.streams[streamID]; ok && state.started {
+		stats := state.ChunkStats
+		return &stats
+	}
+	return nil
+}
+
+// chunkMeta 音频块的可选字段：序号与客户端采集时间（见 capture_clock.go）
+type chunkMeta struct {
+	Seq         *uint64 `json:"seq"`
+	CaptureTime *int64  `json:"captureTime"`
+}
+
+// decodeWebSocketChunkMeta 读取WebSocket音频消息 {"data": [...], "seq": 3, "captureTime": ...} 中的可选字段，纯数组消息没有这些字段
+func decodeWebSocketChunkMeta(message []byte) (chunkMeta, error) {
+	var meta chunkMeta
+	trimmed := bytes.TrimSpace(message)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return meta, nil
+	}
+	if err := json.Unmarshal(trimmed, &meta); err != nil {
+		return meta, fmt.Errorf("seq and captureTime must be integers: %v", err)
+	}
+	if meta.CaptureTime != nil {
+		return meta, validateCaptureTime(*meta.CaptureTime)
+	}
+	return meta, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestChunkSequence 测试音频块序号的重复检测与缺失报告
+// 测试内容：
+// 1. /api/send 重发相同序号的块返回 duplicate，不再追加进缓冲区
+// 2. 跳号的块照常处理，响应中报告缺失的序号范围
+// 3. /api/stop 响应汇总重复与缺失块数，/api/start 重新开始序号
+// 4. WebSocket 音频消息的 seq：重复回复 duplicate，跳号回复 gap，序号无效时回复 error
+// 5. 处理失败的块撤销登记，可以用相同序号重发
+func TestChunkSequence(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	server := NewAudioServer(engine)
+	chunk := strings.TrimSuffix(strings.Repeat("0.01,", 100), ",")
+
+	call := func(handler http.HandlerFunc, body string) map[string]interface{} {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/x", bytes.NewReader([]byte(body))))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s status = %d, body %s", body, rec.Code, rec.Body)
+		}
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return response
+	}
+	send := func(seq int) map[string]interface{} {
+		return call(server.handleSend, fmt.Sprintf(`{"streamId": "s1", "data": [%s], "seq": %d}`, chunk, seq))
+	}
+	received := func(streamID string) int64 {
+		buffer, _ := engine.StreamBuffer(streamID)
+		return buffer.Received
+	}
+
+	call(server.handleStart, `{"streamId": "s1"}`)
+	if response := send(1); response["status"] != "waiting" || response["seq"] != 1.0 || response["gap"] != nil {
+		t.Errorf("first chunk response = %v", response)
+	}
+	if response := send(1); response["status"] != "duplicate" {
+		t.Errorf("resent chunk response = %v, want duplicate", response)
+	}
+	if response := send(4); !strings.Contains(fmt.Sprint(response["gap"]), "from:2 missing:2 to:3") {
+		t.Errorf("gap = %v, want from 2 to 3", response["gap"])
+	}
+	if response := send(3); response["status"] != "duplicate" {
+		t.Errorf("late chunk response = %v, want duplicate", response)
+	}
+	if got := received("s1"); got != 200 {
+		t.Errorf("samples received = %d, want 200", got)
+	}
+	if stats := server.chunkStats("s1"); stats == nil || *stats != (ChunkStats{LastSeq: 4, Duplicates: 2, Missing: 2}) {
+		t.Errorf("chunk stats = %+v", stats)
+	}
+	stop := call(server.handleStop, `{"streamId": "s1"}`)
+	if chunks, _ := stop["chunks"].(map[string]interface{}); chunks["duplicates"] != 2.0 || chunks["missing"] != 2.0 {
+		t.Errorf("stop chunks = %v", stop["chunks"])
+	}
+	call(server.handleStart, `{"streamId": "s1"}`)
+	if response := send(1); response["status"] == "duplicate" {
+		t.Error("sequence should restart after /start")
+	}
+
+	// 处理失败时撤销
+	server.acceptChunk("s2", 7)
+	duplicate, gap := server.acceptChunk("s2", 9)
+	server.rejectChunk("s2", 9, gap)
+	if duplicate, _ = server.acceptChunk("s2", 9); duplicate {
+		t.Error("rejected chunk should be accepted again")
+	}
+	if stats := server.chunkStats("s2"); stats.Missing != 1 {
+		t.Errorf("missing after retry = %d, want 1", stats.Missing)
+	}
+
+	// WebSocket
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer ts.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var init struct {
+		StreamID string `json:"streamId"`
+	}
+	if err := conn.ReadJSON(&init); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	write := func(seq string) {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"data": [%s], "seq": %s}`, chunk, seq))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() map[string]interface{} {
+		t.Helper()
+		var reply map[string]interface{}
+		if err := conn.ReadJSON(&reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+	write("5")
+	if reply := read(); reply["type"] != "result" || reply["seq"] != 5.0 {
+		t.Errorf("ws first chunk reply = %v, want result with seq", reply)
+	}
+	write("5")
+	if reply := read(); reply["type"] != "duplicate" || reply["seq"] != 5.0 {
+		t.Errorf("ws resent chunk reply = %v, want duplicate", reply)
+	}
+	write("8")
+	if reply := read(); reply["type"] != "gap" || fmt.Sprint(reply["gap"]) != "map[from:6 missing:2 to:7]" {
+		t.Errorf("ws gap reply = %v", reply)
+	}
+	if reply := read(); reply["type"] != "result" {
+		t.Errorf("chunk after gap reply = %v, want result", reply)
+	}
+	write("-1")
+	if reply := read(); reply["type"] != "error" {
+		t.Errorf("ws negative seq reply = %v, want error", reply)
+	}
+	if got := received(init.StreamID); got != 200 {
+		t.Errorf("ws samples received = %d, want 200", got)
+	}
+}
+
+// slowProcessor 记录同时处理的块数，fail 时处理失败
+type slowProcessor struct {
+	*MockAudioProcessor
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	fail        bool
+}
+
+func (p *slowProcessor) ProcessAudioRequest(streamID, requestID string, data []float64) ([]byte, error) {
+	p.mu.Lock()
+	p.inFlight++
+	p.maxInFlight = max(p.maxInFlight, p.inFlight)
+	fail := p.fail
+	p.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	if fail {
+		return nil, fmt.Errorf("processing failed")
+	}
+	return nil, nil
+}
+
+// TestChunkSequenceConcurrent 测试同一个流并发的带序号块
+// 测试内容：
+// 1. 同一个流带序号的块依次处理，不会同时处理
+// 2. 并发的块都处理失败时各自撤销登记，之后可以用相同序号重发
+func TestChunkSequenceConcurrent(t *testing.T) {
+	processor := &slowProcessor{MockAudioProcessor: NewMockAudioProcessor()}
+	server := NewAudioServer(processor)
+	sendAll := func(seqs ...int) {
+		t.Helper()
+		var wg sync.WaitGroup
+		for _, seq := range seqs {
+			wg.Add(1)
+			go func(seq int) {
+				defer wg.Done()
+				body := fmt.Sprintf(`{"streamId": "s", "data": [0.1, 0.2], "seq": %d}`, seq)
+				server.handleSend(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(body)))
+			}(seq)
+		}
+		wg.Wait()
+	}
+
+	processor.fail = true
+	sendAll(1, 2, 3)
+	if stats := server.chunkStats("s"); stats != nil {
+		t.Errorf("chunk stats after failed chunks = %+v, want none accepted", stats)
+	}
+	if duplicate, _ := server.acceptChunk("s", 1); duplicate {
+		t.Error("failed chunk should be accepted again")
+	}
+	server.resetChunks("s")
+
+	processor.fail = false
+	sendAll(1, 2, 3, 4, 5, 6)
+	if processor.maxInFlight != 1 {
+		t.Errorf("max chunks processed at once = %d, want 1", processor.maxInFlight)
+	}
+	if stats := server.chunkStats("s"); stats == nil || stats.LastSeq != 6 {
+		t.Errorf("chunk stats = %+v, want last seq 6", stats)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import "math"
+
+// 情感分类器
+//
+// 引擎按处理策略中的匹配方式选择分类器，所有分类器都只依赖样本库，
+// 替换样本库后立即生效。除原有的综合评分与最近样本评分外，模板匹配为每种情感
+// 取样本均值作为模板，按余弦相似度比较标准化后特征向量的方向：
+// 只看输入偏离全库平均的方向与哪种情感一致，不像最近样本匹配那样受个别离群样本左右。
+// 集成匹配（accurate 策略）对综合评分与模板匹配做软投票：两者的评分尺度不同，各自换算为
+// 各情感所占的比例后取平均，距离与方向两种判据一致时得分最高。
+
+// Classifier 根据特征给各情感评分，分数越高越匹配
+type Classifier interface {
+	Scores(feature AudioFeature) map[string]float64
+}
+
+// classifierFor 按匹配方式创建分类器，未知方式使用样本库的综合评分
+func classifierFor(library *SampleLibrary, matcher string) Classifier {
+	switch matcher {
+	case MatcherFast:
+		return fastClassifier{library: library}
+	case MatcherTemplate:
+		return TemplateClassifier{Library: library}
+	case MatcherEnsemble:
+		return EnsembleClassifier{Members: []Classifier{library, TemplateClassifier{Library: library}}}
+	}
+	return library
+}
+
+// EnsembleClassifier 多个分类器的软投票
+type EnsembleClassifier struct {
+	Members []Classifier
+}
+
+// Scores 实现 Classifier，各成员的评分换算为所占比例后取平均，结果在 [0, 1] 内
+func (c EnsembleClassifier) Scores(feature AudioFeature) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, member := range c.Members {
+		memberScores := member.Scores(feature)
+		total := 0.0
+		for _, score := range memberScores {
+			total += score
+		}
+		if total <= 0 {
+			continue
+		}
+		for emotion, score := range memberScores {
+			scores[emotion] += score / total / float64(len(c.Members))
+		}
+	}
+	return scores
+}
+
+// fastClassifier 仅按最近样本的欧氏距离评分
+type fastClassifier struct {
+	library *SampleLibrary
+}
+
+// Scores 实现 Classifier
+func (c fastClassifier) Scores(feature AudioFeature) map[string]float64 {
+	return fastScores(c.library, feature)
+}
+
+// TemplateClassifier 模板余弦相似度匹配
+// 各特征先按全库样本的均值与标准差标准化，避免音高、频率等数值大的特征主导相似度
+type TemplateClassifier struct {
+	Library *SampleLibrary
+}
+
+// Scores 实现 Classifier，余弦相似度 [-1, 1] 映射为 [0, 1]
+func (c TemplateClassifier) Scores(feature AudioFeature) map[string]float64 {
+	dimensions := len(featureNames())
+	var all [][]float64
+	templates := make(map[string][]float64, len(c.Library.Samples))
+	for emotion, samples := range c.Library.Samples {
+		if len(samples) == 0 {
+			continue
+		}
+		template := make([]float64, dimensions)
+		for _, sample := range samples {
+			values := featureValues(sample.Features)
+			all = append(all, values)
+			for j, v := range values {
+				template[j] += v / float64(len(samples))
+			}
+		}
+		templates[emotion] = template
+	}
+	if len(all) == 0 {
+		return map[string]float64{}
+	}
+
+	// 全库标准化参数，无离散度的特征不参与比较
+	means := make([]float64, dimensions)
+	stdDevs := make([]float64, dimensions)
+	for _, values := range all {
+		for j, v := range values {
+			means[j] += v / float64(len(all))
+		}
+	}
+	for _, values := range all {
+		for j, v := range values {
+			stdDevs[j] += (v - means[j]) * (v - means[j]) / float64(len(all))
+		}
+	}
+	standardize := func(values []float64) []float64 {
+		out := make([]float64, len(values))
+		for j, v := range values {
+			if stdDevs[j] > 0 {
+				out[j] = (v - means[j]) / math.Sqrt(stdDevs[j])
+			}
+		}
+		return out
+	}
+
+	query := standardize(featureValues(feature))
+	scores := make(map[string]float64, len(templates))
+	for emotion, template := range templates {
+		scores[emotion] = (1 + cosineSimilarity(query, standardize(template))) / 2
+	}
+	return scores
+}
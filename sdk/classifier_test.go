@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestTemplateClassifier 测试模板余弦相似度分类器
+// 测试内容：
+// 1. 输入与所属情感模板方向一致时得分最高，分数在 [0, 1] 内
+// 2. 数值大的特征（音高）不主导相似度
+// 3. template 策略通过 classifierFor 选择模板分类器，空样本库返回空评分
+func TestTemplateClassifier(t *testing.T) {
+	library := NewSampleLibrary()
+	for i := 0; i < 4; i++ {
+		d := float64(i) * 0.01
+		library.AddSample(AudioSample{Emotion: "happy", Features: AudioFeature{Energy: 0.8 + d, ZeroCrossRate: 0.1 + d, Pitch: 500 + d}})
+		library.AddSample(AudioSample{Emotion: "angry", Features: AudioFeature{Energy: 0.2 + d, ZeroCrossRate: 0.4 + d, Pitch: 510 + d}})
+	}
+
+	classifier := classifierFor(library, MatcherTemplate)
+	if _, ok := classifier.(TemplateClassifier); !ok {
+		t.Fatalf("classifierFor(template) = %T, want TemplateClassifier", classifier)
+	}
+
+	// 音高更接近 angry，但能量与过零率都指向 happy
+	scores := classifier.Scores(AudioFeature{Energy: 0.9, ZeroCrossRate: 0.05, Pitch: 509})
+	if scores["happy"] <= scores["angry"] {
+		t.Errorf("scores = %v, want happy > angry", scores)
+	}
+	for emotion, score := range scores {
+		if score < 0 || score > 1 {
+			t.Errorf("score[%s] = %v out of [0, 1]", emotion, score)
+		}
+	}
+	if emotion, _ := selectEmotion(scores, nil); emotion != "happy" {
+		t.Errorf("selectEmotion = %s, want happy", emotion)
+	}
+
+	strategy, err := LookupProcessingStrategy(StrategyTemplate)
+	if err != nil || strategy.Matcher != MatcherTemplate {
+		t.Errorf("LookupProcessingStrategy(template) = %+v, %v", strategy, err)
+	}
+	if _, ok := classifierFor(library, MatcherStandard).(*SampleLibrary); !ok {
+		t.Error("standard matcher should use the sample library scores")
+	}
+	if scores := (TemplateClassifier{Library: NewSampleLibrary()}).Scores(AudioFeature{}); len(scores) != 0 {
+		t.Errorf("empty library scores = %v", scores)
+	}
+}
+
+// TestEnsembleClassifier 测试集成匹配
+// 测试内容：
+// 1. accurate 策略通过 classifierFor 选择集成分类器，而不是直接使用综合评分
+// 2. 各成员评分换算为比例后取平均，分数在 [0, 1] 内且总和为1
+// 3. 综合评分与模板匹配一致时选出同一情感
+func TestEnsembleClassifier(t *testing.T) {
+	library := NewSampleLibrary()
+	for i := 0; i < 4; i++ {
+		d := float64(i) * 0.01
+		library.AddSample(AudioSample{Emotion: "happy", Features: AudioFeature{Energy: 0.8 + d, ZeroCrossRate: 0.1 + d, Pitch: 500 + d}})
+		library.AddSample(AudioSample{Emotion: "angry", Features: AudioFeature{Energy: 0.2 + d, ZeroCrossRate: 0.4 + d, Pitch: 300 + d}})
+	}
+
+	strategy, err := LookupProcessingStrategy(StrategyAccurate)
+	if err != nil || strategy.Matcher != MatcherEnsemble {
+		t.Fatalf("LookupProcessingStrategy(accurate) = %+v, %v", strategy, err)
+	}
+	classifier, ok := classifierFor(library, strategy.Matcher).(EnsembleClassifier)
+	if !ok || len(classifier.Members) != 2 {
+		t.Fatalf("classifierFor(ensemble) = %T, want EnsembleClassifier with 2 members", classifierFor(library, strategy.Matcher))
+	}
+
+	feature := AudioFeature{Energy: 0.82, ZeroCrossRate: 0.11, Pitch: 501}
+	scores := classifier.Scores(feature)
+	total := 0.0
+	for emotion, score := range scores {
+		if score < 0 || score > 1 {
+			t.Errorf("score[%s] = %v out of [0, 1]", emotion, score)
+		}
+		total += score
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("score total = %v, want 1", total)
+	}
+	if scores["happy"] <= scores["angry"] {
+		t.Errorf("scores = %v, want happy > angry", scores)
+	}
+	if standard := library.Scores(feature); reflect.DeepEqual(standard, scores) {
+		t.Error("ensemble scores should differ from the standard scores")
+	}
+}
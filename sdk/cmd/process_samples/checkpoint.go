@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 进度与检查点：每处理完一个文件输出进度与剩余时间，并把已处理的文件与当前的样本库写入输出路径旁的
+// xxx.checkpoint.json；-resume（默认开启）时跳过其中已处理的文件，全部完成后删除检查点。
+
+// checkpoint 处理进度检查点
+type checkpoint struct {
+	AudioDir  string        `json:"audioDir"`  // 音频目录
+	Processed []string      `json:"processed"` // 已处理的文件名（含处理失败的）
+	Library   SampleLibrary `json:"library"`   // 已处理文件得到的样本库
+}
+
+// checkpointPath 输出路径对应的检查点路径
+func checkpointPath(outputPath string) string {
+	ext := filepath.Ext(outputPath)
+	return outputPath[:len(outputPath)-len(ext)] + ".checkpoint.json"
+}
+
+// loadCheckpoint 读取检查点，不存在或音频目录不同时返回 nil，特征提取版本不同时返回错误
+func loadCheckpoint(path, audioDir string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("parse checkpoint %s: %v", path, err)
+	}
+	if saved.AudioDir != audioDir {
+		return nil, nil
+	}
+	if saved.Library.ExtractorVersion != extractorVersion {
+		return nil, fmt.Errorf("checkpoint %s was written by extractor version %q, current version is %q",
+			path, saved.Library.ExtractorVersion, extractorVersion)
+	}
+	if saved.Library.Samples == nil {
+		saved.Library.Samples = make(map[string][]Sample)
+	}
+	return &saved, nil
+}
+
+// save 写入检查点，先写临时文件再重命名，中途被打断也不会留下损坏的检查点
+func (c *checkpoint) save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// progress 处理进度，剩余时间按本次运行已处理文件的平均耗时估算
+type progress struct {
+	total   int       // 文件总数
+	done    int       // 已处理的文件数（含从检查点恢复的）
+	resumed int       // 从检查点恢复的文件数
+	start   time.Time // 本次运行开始处理的时间
+}
+
+// step 记录处理完一个文件，返回进度描述
+func (p *progress) step() string {
+	p.done++
+	remaining := p.total - p.done
+	finished := p.done - p.resumed
+	if remaining <= 0 || finished <= 0 {
+		return fmt.Sprintf("%d/%d", p.done, p.total)
+	}
+	eta := time.Since(p.start) / time.Duration(finished) * time.Duration(remaining)
+	return fmt.Sprintf("%d/%d, 预计剩余 %s", p.done, p.total, eta.Round(time.Second))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadCheckpointExtractorVersion 测试检查点的特征提取版本检查
+// 测试内容：
+// 1. 当前版本写入的检查点可以继续
+// 2. 其他版本写入的检查点返回错误，不复用其中的样本
+// 3. 音频目录不同时忽略检查点
+func TestLoadCheckpointExtractorVersion(t *testing.T) {
+	path := checkpointPath(filepath.Join(t.TempDir(), "library.json"))
+	state := &checkpoint{
+		AudioDir:  "audios",
+		Processed: []string{"happy_1.mp3"},
+		Library:   SampleLibrary{ExtractorVersion: extractorVersion, TotalSamples: 1},
+	}
+	if err := state.save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loadCheckpoint(path, "audios")
+	if err != nil || saved == nil || len(saved.Processed) != 1 {
+		t.Fatalf("loadCheckpoint = %+v, %v", saved, err)
+	}
+
+	state.Library.ExtractorVersion = ""
+	if err := state.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := loadCheckpoint(path, "audios"); err == nil || saved != nil {
+		t.Errorf("unversioned checkpoint: loadCheckpoint = %+v, %v, want error", saved, err)
+	}
+
+	if saved, err := loadCheckpoint(path, "other"); err != nil || saved != nil {
+		t.Errorf("other audio dir: loadCheckpoint = %+v, %v, want nil", saved, err)
+	}
+}
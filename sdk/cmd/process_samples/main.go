@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/binary"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -10,23 +12,32 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/youpy/go-wav"
 	"github.com/hajimehoshi/go-mp3"
+	"soundsdk/dsp"
 )
 
+// decimationFactor 提取特征前的抽取倍数
+const decimationFactor = 10
+
+// extractorVersion 本程序的特征提取版本，写入样本库与检查点。特征在抽取后的整段音频上计算，
+// 与 SDK 的 FeatureExtractorVersion 不同，SDK 加载本程序生成的样本库时会提示版本不一致
+const extractorVersion = "process_samples-1"
+
 // 样本库结构
 type SampleLibrary struct {
-	TotalSamples int                 `json:"totalSamples"`
-	Emotions     []string            `json:"emotions"`
-	Samples      map[string][]Sample `json:"samples"`
+	ExtractorVersion string              `json:"extractorVersion"`
+	TotalSamples     int                 `json:"totalSamples"`
+	Emotions         []string            `json:"emotions"`
+	Samples          map[string][]Sample `json:"samples"`
 }
 
 // 样本结构
 type Sample struct {
-	FilePath string           `json:"FilePath"`
-	Emotion  string           `json:"Emotion"`
-	Features AudioFeature     `json:"Features"`
+	FilePath string       `json:"FilePath"`
+	Emotion  string       `json:"Emotion"`
+	Features AudioFeature `json:"Features"`
 }
 
 // AudioFeature 详细的音频特征
@@ -43,31 +54,87 @@ type AudioFeature struct {
 }
 
 func main() {
+	audioDir := flag.String("audio-dir", "audios", "音频目录，文件名以情感开头（如 for_food_1.mp3）")
+	outputPath := flag.String("o", "", "输出样本库路径，为空时写入数据目录下的 new_sample_library.json")
+	dataDir := flag.String("data-dir", "", "数据目录，为空时使用平台约定的目录（Linux 为 ~/.local/share/meowtalk）")
+	resume := flag.Bool("resume", true, "存在检查点时跳过上次已处理的文件，设为 false 时从头处理")
+	flag.Parse()
+
 	// 创建新的样本库
 	library := SampleLibrary{
-		Emotions: []string{},
-		Samples:  make(map[string][]Sample),
+		ExtractorVersion: extractorVersion,
+		Emotions:         []string{},
+		Samples:          make(map[string][]Sample),
 	}
 
-	// 设置目录路径
-	audioDir := "d:\\uso_dev\\MeowTalk\\audios"
-	outputPath := "d:\\uso_dev\\MeowTalk\\sdk\\new_sample_library.json"
+	// 未指定输出路径时写入数据目录
+	if *outputPath == "" {
+		if *dataDir == "" {
+			dir, err := defaultDataDir()
+			if err != nil {
+				log.Fatalf("无法确定数据目录: %v", err)
+			}
+			*dataDir = dir
+		}
+		*outputPath = filepath.Join(*dataDir, "new_sample_library.json")
+	}
+	if err := os.MkdirAll(filepath.Dir(*outputPath), 0755); err != nil {
+		log.Fatalf("无法创建输出目录: %v", err)
+	}
 
 	// 获取所有MP3文件
-	files, err := filepath.Glob(filepath.Join(audioDir, "*.mp3"))
+	files, err := filepath.Glob(filepath.Join(*audioDir, "*.mp3"))
 	if err != nil {
 		log.Fatalf("无法获取音频文件: %v", err)
 	}
 
 	log.Printf("找到 %d 个音频文件", len(files))
 
+	// 从检查点继续上次中断的处理
+	checkpointFile := checkpointPath(*outputPath)
+	state := &checkpoint{AudioDir: *audioDir}
+	if *resume {
+		saved, err := loadCheckpoint(checkpointFile, *audioDir)
+		if err != nil {
+			log.Printf("忽略无法读取的检查点: %v", err)
+		} else if saved != nil {
+			state = saved
+			library = saved.Library
+			log.Printf("从检查点继续: 已处理 %d 个文件", len(saved.Processed))
+		}
+	}
+	processed := make(map[string]bool, len(state.Processed))
+	for _, name := range state.Processed {
+		processed[name] = true
+	}
+	bar := &progress{total: len(files), start: time.Now()}
+	for _, file := range files {
+		if processed[filepath.Base(file)] {
+			bar.done++
+			bar.resumed++
+		}
+	}
+
+	// markDone 记录处理完一个文件并更新检查点
+	markDone := func(file string) {
+		state.Processed = append(state.Processed, filepath.Base(file))
+		state.Library = library
+		if err := state.save(checkpointFile); err != nil {
+			log.Printf("保存检查点失败: %v", err)
+		}
+		log.Printf("进度: %s", bar.step())
+	}
+
 	// 处理每个MP3文件
 	for _, file := range files {
 		// 从文件名中提取情感标签
 		basename := filepath.Base(file)
+		if processed[basename] {
+			continue
+		}
 		emotion := strings.Split(basename, "_")[0]
-		emotion = strings.Split(emotion, ".")[0]  // 处理没有序号的文件
-		emotion = strings.Replace(emotion, "-", "_", -1)  // 标准化emotion名称
+		emotion = strings.Split(emotion, ".")[0]         // 处理没有序号的文件
+		emotion = strings.Replace(emotion, "-", "_", -1) // 标准化emotion名称
 
 		// 添加到情感列表（如果不存在）
 		found := false
@@ -87,12 +154,13 @@ func main() {
 		features, err := extractFeaturesFromMP3(file)
 		if err != nil {
 			log.Printf("处理文件 %s 时出错: %v", file, err)
+			markDone(file)
 			continue
 		}
 
-		// 创建样本
+		// 创建样本，路径相对样本库所在目录，样本库可以与音频一起拷贝到其他平台
 		sample := Sample{
-			FilePath: file,
+			FilePath: relativeSamplePath(file, filepath.Dir(*outputPath)),
 			Emotion:  emotion,
 			Features: features,
 		}
@@ -100,6 +168,7 @@ func main() {
 		// 添加到样本库
 		library.Samples[emotion] = append(library.Samples[emotion], sample)
 		library.TotalSamples++
+		markDone(file)
 	}
 
 	// 保存样本库到JSON文件
@@ -108,13 +177,14 @@ func main() {
 		log.Fatalf("无法将样本库转换为JSON: %v", err)
 	}
 
-	err = ioutil.WriteFile(outputPath, jsonData, 0644)
+	err = ioutil.WriteFile(*outputPath, jsonData, 0644)
 	if err != nil {
 		log.Fatalf("无法保存样本库到文件: %v", err)
 	}
 
-	log.Printf("样本库已保存到 %s，包含 %d 个样本，%d 种情感", 
-		outputPath, library.TotalSamples, len(library.Emotions))
+	os.Remove(checkpointFile)
+	log.Printf("样本库已保存到 %s，包含 %d 个样本，%d 种情感",
+		*outputPath, library.TotalSamples, len(library.Emotions))
 }
 
 // 从MP3文件中提取音频特征
@@ -147,33 +217,27 @@ func extractFeaturesFromMP3(filepath string) (AudioFeature, error) {
 		all = append(all, buffer[:n]...)
 	}
 
-	// 将字节数据转换为浮点数
-	sampleCount := len(all) / 2 // 16位音频
+	// go-mp3 输出16位小端双声道交织PCM（单声道文件两个声道相同），每帧4字节，
+	// 两个声道取平均合并为单声道，与 sdk/file_analysis.go 的 decodeMP3 一致
+	const channels = 2
+	sampleCount := len(all) / (2 * channels)
 	samples := make([]float64, sampleCount)
 
 	for i := 0; i < sampleCount; i++ {
-		// 将两个字节转换为16位整数
-		sample := int16(all[i*2]) | (int16(all[i*2+1]) << 8)
+		frame := all[i*2*channels:]
+		left := int16(binary.LittleEndian.Uint16(frame[0:]))
+		right := int16(binary.LittleEndian.Uint16(frame[2:]))
 		// 转换为float64并归一化到[-1, 1]范围
-		samples[i] = float64(sample) / 32768.0
+		samples[i] = (float64(left) + float64(right)) / 2 / 32768.0
 	}
 
-	// 采样降频 (以10为因子)
-	downsampledData := downsample(samples, 10)
+	// 抗混叠抽取，降到 1/decimationFactor 的采样率
+	downsampledData := dsp.Decimate(samples, decimationFactor)
 
 	// 提取音频特征
 	return calculateFeatures(downsampledData, sampleRate), nil
 }
 
-// 降采样
-func downsample(data []float64, factor int) []float64 {
-	result := make([]float64, len(data)/factor)
-	for i := 0; i < len(result); i++ {
-		result[i] = data[i*factor]
-	}
-	return result
-}
-
 // 计算音频特征
 func calculateFeatures(data []float64, sampleRate int) AudioFeature {
 	var features AudioFeature
@@ -182,7 +246,7 @@ func calculateFeatures(data []float64, sampleRate int) AudioFeature {
 	windowedData := applyHammingWindow(data)
 
 	// 计算持续时间（秒），考虑降采样因子
-	features.Duration = float64(len(data)*10) / float64(sampleRate)
+	features.Duration = float64(len(data)*decimationFactor) / float64(sampleRate)
 
 	// 计算能量
 	features.Energy = calculateEnergy(data)
@@ -203,7 +267,7 @@ func calculateFeatures(data []float64, sampleRate int) AudioFeature {
 	features.Pitch = features.FundamentalFreq
 
 	// 计算频谱
-	spectrum := performFFT(windowedData)
+	spectrum := dsp.RealFFT(windowedData)
 
 	// 计算频谱质心和滚降点
 	features.SpectralCentroid = calculateSpectralCentroid(spectrum, sampleRate/10)
@@ -213,7 +277,7 @@ func calculateFeatures(data []float64, sampleRate int) AudioFeature {
 	if features.Pitch < 70 || features.Pitch > 1500 {
 		features.Pitch = 0
 	}
-	
+
 	return features
 }
 
@@ -265,63 +329,6 @@ func calculateEnergy(data []float64) float64 {
 	return energy
 }
 
-// performFFT 执行快速傅里叶变换
-func performFFT(data []float64) []complex128 {
-	// 确保数据长度是2的幂
-	n := nextPowerOf2(len(data))
-	padded := make([]float64, n)
-	copy(padded, data)
-
-	// 创建复数数组
-	complex := make([]complex128, n)
-	for i, v := range padded {
-		complex[i] = complex(v, 0)
-	}
-
-	// 执行FFT
-	fft := recursiveFFT(complex)
-	return fft
-}
-
-// nextPowerOf2 计算大于等于n的最小2的幂
-func nextPowerOf2(n int) int {
-	p := 1
-	for p < n {
-		p *= 2
-	}
-	return p
-}
-
-// recursiveFFT 递归实现FFT
-func recursiveFFT(x []complex128) []complex128 {
-	n := len(x)
-	if n <= 1 {
-		return x
-	}
-
-	// 分为偶数和奇数部分
-	even := make([]complex128, n/2)
-	odd := make([]complex128, n/2)
-	for i := 0; i < n/2; i++ {
-		even[i] = x[2*i]
-		odd[i] = x[2*i+1]
-	}
-
-	// 递归计算
-	even = recursiveFFT(even)
-	odd = recursiveFFT(odd)
-
-	// 合并结果
-	y := make([]complex128, n)
-	for k := 0; k < n/2; k++ {
-		omega := cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n))
-		y[k] = even[k] + omega*odd[k]
-		y[k+n/2] = even[k] - omega*odd[k]
-	}
-
-	return y
-}
-
 // calculatePeakFrequency 计算峰值频率
 func calculatePeakFrequency(data []float64, sampleRate int) float64 {
 	if len(data) == 0 {
@@ -329,7 +336,7 @@ func calculatePeakFrequency(data []float64, sampleRate int) float64 {
 	}
 
 	// 执行FFT
-	fft := performFFT(data)
+	fft := dsp.RealFFT(data)
 
 	// 考虑降采样因子，使用有效采样率
 	effectiveSampleRate := sampleRate / 10
@@ -339,12 +346,12 @@ func calculatePeakFrequency(data []float64, sampleRate int) float64 {
 	// 查找峰值
 	maxMagnitude := 0.0
 	peakBin := 0
-	
+
 	// 从FFT结果中查找，忽略过低频率
 	for i := max(1, minBin); i < len(fft)/2; i++ {
 		// 计算当前bin对应的频率
 		freq := float64(i) * float64(effectiveSampleRate) / float64(len(fft))
-		
+
 		magnitude := cmplx.Abs(fft[i])
 		// 只考虑特定频率范围内的峰值，猫咪声音主要在70Hz-2000Hz之间
 		if freq >= 70.0 && freq <= 2000.0 && magnitude > maxMagnitude {
@@ -367,7 +374,7 @@ func calculatePeakFrequency(data []float64, sampleRate int) float64 {
 func estimateFundamentalFrequency(data []float64) float64 {
 	// 使用自相关法
 	effectiveSampleRate := 44100 / 10 // 采用实际降采样率 4410Hz
-	
+
 	// 定义频率范围：70Hz-1000Hz (猫咪主要声音范围)
 	minLag := effectiveSampleRate / 1000 // 最高频率限制
 	maxLag := effectiveSampleRate / 70   // 最低频率限制
@@ -386,7 +393,7 @@ func estimateFundamentalFrequency(data []float64) float64 {
 		for i := 0; i < len(data)-lag; i++ {
 			corr += data[i] * data[i+lag]
 		}
-		
+
 		// 归一化相关系数
 		corr = corr / float64(len(data)-lag)
 
@@ -417,7 +424,7 @@ func calculateSpectralCentroid(spectrum []complex128, sampleRate int) float64 {
 	for i := 1; i < len(spectrum)/2; i++ {
 		freq := float64(i) * float64(sampleRate) / float64(len(spectrum))
 		magnitude := cmplx.Abs(spectrum[i])
-		
+
 		weightedSum += freq * magnitude
 		magnitudeSum += magnitude
 	}
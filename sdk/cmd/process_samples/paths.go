@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// 输出目录与样本路径：音频目录与输出路径由命令行参数指定，输出默认写到平台约定的数据目录，
+// FilePath 写为相对样本库所在目录、以 / 分隔的路径，规则与 sdk/data_dir.go、sdk/library_paths.go 相同。
+
+// defaultDataDir 返回平台约定的数据目录：Linux 等为 $XDG_DATA_HOME/meowtalk 或 ~/.local/share/meowtalk，
+// Windows 与 macOS 为用户配置目录下的 meowtalk
+func defaultDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("data dir: %v", err)
+		}
+		return filepath.Join(dir, "meowtalk"), nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "meowtalk"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("data dir: %v", err)
+	}
+	return filepath.Join(home, ".local", "share", "meowtalk"), nil
+}
+
+// relativeSamplePath 返回样本路径相对样本库目录、以 / 分隔的形式，无法表示为相对路径时只统一分隔符
+func relativeSamplePath(path, libraryDir string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		if dir, err := filepath.Abs(libraryDir); err == nil {
+			if rel, err := filepath.Rel(dir, abs); err == nil {
+				path = rel
+			}
+		}
+	}
+	return strings.ReplaceAll(filepath.ToSlash(path), `\`, "/")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 配置热加载：SIGHUP 或 POST /admin/reload 重新读取 -profile 与 -trigger 文件，全部校验通过后才替换，会话不中断。
+// 结果钩子、样本库与其他命令行参数不重新加载，修改后需重启服务。
+
+// TriggerPolicySetter 支持替换缓冲处理触发条件的处理器（Engine）
+type TriggerPolicySetter interface {
+	SetTriggerPolicy(policy TriggerPolicy) error
+}
+
+// ConfigReloader 按启动时的文件路径重新加载领域配置与触发条件
+type ConfigReloader struct {
+	ProfilePath string         // 领域配置文件，为空时保持内置配置
+	TriggerPath string         // 触发条件文件，为空时不重新加载
+	Processor   AudioProcessor // 触发条件应用到的处理器
+
+	mu sync.Mutex // 同一时间只执行一次重新加载
+}
+
+// ReloadResult 一次重新加载的结果
+type ReloadResult struct {
+	Profile    string         `json:"profile,omitempty"` // 重新加载后的领域配置名称
+	Phrases    int            `json:"phrases,omitempty"` // 领域配置中的短语数
+	Trigger    *TriggerPolicy `json:"trigger,omitempty"` // 重新加载后的触发条件
+	ReloadedAt int64          `json:"reloadedAt"`        // 完成时间（毫秒时间戳）
+}
+
+// Reload 重新加载领域配置与触发条件文件，校验全部通过后才替换，替换失败时恢复原配置
+func (c *ConfigReloader) Reload() (*ReloadResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var profile *DomainProfile
+	if c.ProfilePath != "" {
+		loaded, err := LoadDomainProfile(c.ProfilePath)
+		if err != nil {
+			return nil, err
+		}
+		profile = loaded
+	}
+
+	var policy *TriggerPolicy
+	var setter TriggerPolicySetter
+	if c.TriggerPath != "" {
+		var ok bool
+		if setter, ok = c.Processor.(TriggerPolicySetter); !ok {
+			return nil, fmt.Errorf("reload: processor does not support trigger policies")
+		}
+		loaded, err := LoadTriggerPolicy(c.TriggerPath)
+		if err != nil {
+			return nil, err
+		}
+		policy = &loaded
+	}
+
+	// 触发条件设置失败时恢复原领域配置，两者要么都替换，要么都保持不变
+	profileMu.RLock()
+	previous := activeProfile
+	profileMu.RUnlock()
+
+	result := &ReloadResult{Trigger: policy}
+	if profile != nil {
+		if err := SetDomainProfile(profile); err != nil {
+			return nil, err
+		}
+		result.Profile = profile.Name
+		result.Phrases = len(profile.Phrases)
+	}
+	if policy != nil {
+		if err := setter.SetTriggerPolicy(*policy); err != nil {
+			if profile != nil {
+				if restoreErr := SetDomainProfile(previous); restoreErr != nil {
+					log.Printf("恢复原领域配置失败: %v", restoreErr)
+				}
+			}
+			return nil, err
+		}
+	}
+	result.ReloadedAt = time.Now().UnixMilli()
+	log.Printf("配置已重新加载: 领域配置=%q, 触发条件=%q", c.ProfilePath, c.TriggerPath)
+	return result, nil
+}
+
+// ReloadOnSIGHUP 收到 SIGHUP 时重新加载配置，返回的函数停止监听
+func (c *ConfigReloader) ReloadOnSIGHUP() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if _, err := c.Reload(); err != nil {
+					log.Printf("重新加载配置失败，继续使用原配置: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// SetReloader 设置 /admin/reload 使用的配置加载器
+func (s *AudioServer) SetReloader(reloader *ConfigReloader) {
+	s.reloader = reloader
+}
+
+// handleReload 重新加载配置文件：POST /admin/reload，需要管理令牌
+func (s *AudioServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	if !s.checkAdmin(w, r) {
+		return
+	}
+
+	if s.reloader == nil {
+		writeError(w, r, http.StatusNotImplemented, ErrCodeFeatureDisabled, "未启用配置重新加载", "")
+		return
+	}
+
+	result, err := s.reloader.Reload()
+	if err != nil {
+		log.Printf("重新加载配置失败，继续使用原配置: %v", err)
+		writeError(w, r, http.StatusUnprocessableEntity, ErrCodeUnprocessable, "重新加载配置失败", err.Error())
+		return
+	}
+	writeResponse(w, r, http.StatusOK, result)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestConfigReload 测试配置热加载
+// 测试内容：
+// 1. Reload 重新读取领域配置与触发条件文件并替换当前配置
+// 2. 任一文件无效，或触发条件设置失败时保持原配置
+// 3. /admin/reload 需要管理令牌，成功时返回加载结果
+func TestConfigReload(t *testing.T) {
+	t.Cleanup(func() { SetDomainProfile(nil) })
+	dir := t.TempDir()
+	profilePath := filepath.Join(dir, "profile.json")
+	triggerPath := filepath.Join(dir, "trigger.json")
+	dog, err := os.ReadFile(filepath.Join("profiles", "dog.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(profilePath, dog, 0644)
+	os.WriteFile(triggerPath, []byte(`{"minWindows":5,"silenceDuration":0.4,"maxBufferTime":4}`), 0644)
+
+	processor := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	trigger := func() TriggerPolicy {
+		policy, _ := processor.triggerPolicy()
+		if policy == nil {
+			return TriggerPolicy{}
+		}
+		return *policy
+	}
+	reloader := &ConfigReloader{ProfilePath: profilePath, TriggerPath: triggerPath, Processor: processor}
+	result, err := reloader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "dog" || CurrentDomainProfile().Name != "dog" {
+		t.Errorf("profile after reload = %q, current = %q", result.Profile, CurrentDomainProfile().Name)
+	}
+	if trigger().MinWindows != 5 || result.Trigger == nil || result.Trigger.MinWindows != 5 {
+		t.Errorf("trigger after reload = %+v", trigger())
+	}
+
+	os.WriteFile(triggerPath, []byte(`{"minWindows":-1}`), 0644)
+	os.WriteFile(profilePath, []byte(`{"name":"bird","defaultPreset":"x","frequencyPresets":{}}`), 0644)
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("invalid files should fail to reload")
+	}
+	if CurrentDomainProfile().Name != "dog" || trigger().MinWindows != 5 {
+		t.Errorf("config after failed reload = %q, %+v", CurrentDomainProfile().Name, trigger())
+	}
+
+	os.WriteFile(triggerPath, []byte(`{"minWindows":5,"silenceDuration":0.4,"maxBufferTime":4}`), 0644)
+	os.WriteFile(profilePath, dog, 0644)
+	SetDomainProfile(nil)
+	rejecting := &ConfigReloader{ProfilePath: profilePath, TriggerPath: triggerPath, Processor: rejectingTriggerProcessor{processor}}
+	if _, err := rejecting.Reload(); err == nil {
+		t.Error("reload should fail when the trigger policy is rejected")
+	}
+	if name := CurrentDomainProfile().Name; name == "dog" {
+		t.Errorf("profile after rejected trigger = %q, want the previous profile", name)
+	}
+	os.WriteFile(triggerPath, []byte(`{"minWindows":-1}`), 0644)
+	os.WriteFile(profilePath, []byte(`{"name":"bird","defaultPreset":"x","frequencyPresets":{}}`), 0644)
+
+	server := NewAudioServer(processor)
+	post := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		server.handleReload(rec, req)
+		return rec
+	}
+	if rec := post("secret"); rec.Code != http.StatusForbidden {
+		t.Errorf("reload without admin token configured = %d, want 403", rec.Code)
+	}
+	server.SetAdminToken("secret")
+	if rec := post("secret"); rec.Code != http.StatusNotImplemented {
+		t.Errorf("reload without reloader = %d, want 501", rec.Code)
+	}
+	server.SetReloader(reloader)
+	if rec := post("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reload with wrong token = %d, want 401", rec.Code)
+	}
+	if rec := post("secret"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reload with invalid files = %d, want 422", rec.Code)
+	}
+
+	os.WriteFile(profilePath, dog, 0644)
+	os.WriteFile(triggerPath, []byte(`{"minWindows":2,"silenceDuration":0.3,"maxBufferTime":5}`), 0644)
+	rec := post("secret")
+	var got ReloadResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("reload = %d %s", rec.Code, rec.Body.String())
+	}
+	if got.Profile != "dog" || got.Trigger == nil || got.Trigger.MinWindows != 2 || trigger().MinWindows != 2 {
+		t.Errorf("reload result = %+v, trigger = %+v", got, trigger())
+	}
+}
+
+// rejectingTriggerProcessor 拒绝任何触发条件的处理器
+type rejectingTriggerProcessor struct {
+	AudioProcessor
+}
+
+func (rejectingTriggerProcessor) SetTriggerPolicy(TriggerPolicy) error {
+	return errors.New("trigger policy rejected")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 流的情境提示
+//
+// 猫咪档案中的先验（见 priors.go）需要事先为每只猫配置喂食时间与上下文，而很多应用在开始录音时就知道
+// 当时的情境：用户刚点了"准备喂食"，或者日程里是"看兽医"。开始流时可以直接传入：
+//   hints          情境提示列表，每个提示对应一组情感先验倍数，多个提示的倍数相乘
+//   emotionPriors  直接注入的情感先验倍数，如 {"for_food": 1.5}
+// 两者与猫咪档案的时间与上下文先验相乘后参与情感选择，对该流的所有结果生效。内置提示见 builtinHints，
+// 领域配置的 hints 可以覆盖同名提示或增加新的提示（如狗的配置）。未知提示在开始流时报错。
+
+// builtinHints 内置的情境提示 -> 情感 -> 先验倍数
+var builtinHints = map[string]map[string]float64{
+	"pre-feeding": {"for_food": 2.0, "yummy": 1.3, "call": 1.2},
+	"vet-visit":   {"anxious": 2.0, "discomfort": 1.5, "warning": 1.4, "for_fight": 1.3},
+	"playtime":    {"ask_for_play": 1.8, "ask_for_hunting": 1.5, "curious": 1.3},
+	"bedtime":     {"comfortable": 1.5, "satisfy": 1.3},
+	"cuddling":    {"flighty": 1.8, "comfortable": 1.5, "satisfy": 1.3},
+	"stranger":    {"alert": 1.6, "warning": 1.4, "curious": 1.2},
+}
+
+// lookupHint 返回情境提示的先验，领域配置中的同名提示优先
+func lookupHint(profile *DomainProfile, hint string) (map[string]float64, bool) {
+	if weights, ok := profile.Hints[hint]; ok {
+		return weights, true
+	}
+	weights, ok := builtinHints[hint]
+	return weights, ok
+}
+
+// AvailableHints 返回当前领域配置下可用的情境提示
+func AvailableHints() []string {
+	profile := CurrentDomainProfile()
+	hints := make([]string, 0, len(builtinHints)+len(profile.Hints))
+	for hint := range builtinHints {
+		hints = append(hints, hint)
+	}
+	for hint := range profile.Hints {
+		if _, ok := builtinHints[hint]; !ok {
+			hints = append(hints, hint)
+		}
+	}
+	sort.Strings(hints)
+	return hints
+}
+
+// resolveHintPriors 将情境提示与直接注入的先验合并为情感先验倍数，都没有时返回nil
+func resolveHintPriors(hints []string, emotionPriors map[string]float64) (map[string]float64, error) {
+	if len(hints) == 0 && len(emotionPriors) == 0 {
+		return nil, nil
+	}
+	if err := validatePriorWeights(emotionPriors); err != nil {
+		return nil, fmt.Errorf("emotionPriors: %v", err)
+	}
+
+	profile := CurrentDomainProfile()
+	priors := make(map[string]float64)
+	multiply := func(weights map[string]float64) {
+		for emotion, w := range weights {
+			emotion = NormalizeEmotionID(emotion)
+			if cur, ok := priors[emotion]; ok {
+				priors[emotion] = cur * w
+			} else {
+				priors[emotion] = w
+			}
+		}
+	}
+	for _, hint := range hints {
+		weights, ok := lookupHint(profile, hint)
+		if !ok {
+			return nil, fmt.Errorf("unknown hint %q (available: %s)", hint, strings.Join(AvailableHints(), ", "))
+		}
+		multiply(weights)
+	}
+	multiply(emotionPriors)
+	return priors, nil
+}
+
+// streamPriors 猫咪档案的时间与上下文先验与流的情境先验相乘
+func streamPriors(cat CatProfile, context string, hintPriors map[string]float64, now time.Time) map[string]float64 {
+	priors := cat.Priors.Evaluate(now, context)
+	for emotion, w := range hintPriors {
+		if cur, ok := priors[emotion]; ok {
+			priors[emotion] = cur * w
+		} else {
+			priors[emotion] = w
+		}
+	}
+	return priors
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestStreamHints 测试开始流时传入的情境提示与情感先验
+// 测试内容：
+// 1. 多个提示与 emotionPriors 的倍数相乘，情感ID按别名规范化
+// 2. 未知提示与非正的倍数返回错误
+// 3. 领域配置的 hints 覆盖同名内置提示并可增加新提示
+// 4. 流的情境先验与猫咪档案的先验相乘，并改变选出的情感
+// 5. StartStreamEx 选项解析 hints/emotionPriors
+// 6. /api/start 带未知提示时返回 400，有效提示保存到流上
+func TestStreamHints(t *testing.T) {
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	now := time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)
+
+	priors, err := resolveHintPriors([]string{"pre-feeding", "playtime"}, map[string]float64{"for_food": 1.5, "curious": 2})
+	if err != nil {
+		t.Fatalf("resolveHintPriors() error = %v", err)
+	}
+	if !near(priors["for_food"], 3.0) || !near(priors["curious"], 2.6) || !near(priors["ask_for_play"], 1.8) {
+		t.Errorf("priors = %v, want for_food 3.0, curious 2.6, ask_for_play 1.8", priors)
+	}
+	if priors, err := resolveHintPriors(nil, nil); priors != nil || err != nil {
+		t.Errorf("resolveHintPriors(nil, nil) = %v, %v; want nil, nil", priors, err)
+	}
+	if _, err := resolveHintPriors([]string{"bath-time"}, nil); err == nil {
+		t.Error("unknown hint should fail")
+	}
+	if _, err := resolveHintPriors(nil, map[string]float64{"for_food": 0}); err == nil {
+		t.Error("non-positive emotion prior should fail")
+	}
+
+	profile := *CurrentDomainProfile()
+	profile.Hints = map[string]map[string]float64{
+		"pre-feeding": {"call": 3},
+		"bath-time":   {"warning": 2},
+	}
+	if err := SetDomainProfile(&profile); err != nil {
+		t.Fatalf("SetDomainProfile() error = %v", err)
+	}
+	priors, err = resolveHintPriors([]string{"pre-feeding", "bath-time"}, nil)
+	SetDomainProfile(nil)
+	if err != nil {
+		t.Fatalf("resolveHintPriors() with profile hints error = %v", err)
+	}
+	if _, ok := priors["for_food"]; ok || priors["call"] != 3 || priors["warning"] != 2 {
+		t.Errorf("priors with profile hints = %v, want call 3, warning 2", priors)
+	}
+	profile.Hints = map[string]map[string]float64{"bath-time": {"warning": -1}}
+	if err := profile.Validate(); err == nil {
+		t.Error("profile hint with negative weight should fail validation")
+	}
+
+	// 与猫咪档案的先验相乘
+	cat := CatProfile{Priors: &ContextPriors{Contexts: map[string]map[string]float64{"feeding": {"for_food": 2}}}}
+	combined := streamPriors(cat, "feeding", map[string]float64{"for_food": 1.5, "anxious": 2}, now)
+	if !near(combined["for_food"], 3) || !near(combined["anxious"], 2) {
+		t.Errorf("streamPriors() = %v, want for_food 3, anxious 2", combined)
+	}
+	scores := map[string]float64{"for_food": 0.5, "anxious": 0.45}
+	if emotion, _ := selectEmotion(scores, streamPriors(CatProfile{}, "", nil, now)); emotion != "for_food" {
+		t.Errorf("without hints emotion = %s, want for_food", emotion)
+	}
+	hinted, _ := resolveHintPriors([]string{"vet-visit"}, nil)
+	if emotion, _ := selectEmotion(scores, streamPriors(CatProfile{}, "", hinted, now)); emotion != "anxious" {
+		t.Errorf("with vet-visit hint emotion = %s, want anxious", emotion)
+	}
+
+	// StartStreamEx 的选项
+	options, err := ParseStreamOptions([]byte(`{"context": "feeding", "hints": ["pre-feeding"], "emotionPriors": {"yummy": 2}}`))
+	if err != nil || options.StreamHints == nil || len(options.Hints) != 1 || options.EmotionPriors["yummy"] != 2 {
+		t.Errorf("ParseStreamOptions() with hints = %+v, %v", options, err)
+	}
+
+	// /api/start
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	server := NewAudioServer(engine)
+	start := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/start", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+		server.handleStart(rec, req)
+		return rec.Code
+	}
+	if code := start(`{"streamId": "s1", "hints": ["bath-time"]}`); code != http.StatusBadRequest {
+		t.Errorf("start with unknown hint status = %d, want 400", code)
+	}
+	if code := start(`{"streamId": "s1", "hints": ["vet-visit"], "emotionPriors": {"anxious": 1.5}}`); code != http.StatusOK {
+		t.Fatalf("start with hints status = %d, want 200", code)
+	}
+	if got := engine.sessions["s1"].HintPriors["anxious"]; !near(got, 3) {
+		t.Errorf("stream anxious prior = %v, want 3", got)
+	}
+}
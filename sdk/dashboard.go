@@ -0,0 +1,169 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 实时调试面板
+//
+// 首页只是一份静态接口文档，测试识别效果还得自己写客户端。/dashboard 是随服务一起编译的
+// 调试面板：列出当前活跃的会话与样本库统计，可以直接用浏览器麦克风通过 /ws 推流，实时显示
+// 波形、频谱图和情感时间线，也可以选中其他会话订阅其情感变化事件。
+
+//go:embed dashboard/index.html
+var dashboardFS embed.FS
+
+// DashboardIdleTimeout 超过该时长没有数据的会话不再显示在面板中
+const DashboardIdleTimeout = 10 * time.Minute
+
+// StreamActivity 面板展示的会话活动
+type StreamActivity struct {
+	StreamID   string      `json:"streamId"`
+	Transport  string      `json:"transport"`            // http/ws
+	StartedAt  int64       `json:"startedAt"`            // 会话开始时间（毫秒时间戳）
+	LastSeen   int64       `json:"lastSeen"`             // 最近一次收到数据的时间（毫秒时间戳）
+	Samples    int64       `json:"samples"`              // 已收到的样本数
+	Results    int         `json:"results"`              // 已产生的识别结果数（不含等待状态）
+	Emotion    string      `json:"emotion,omitempty"`    // 最近一次识别的情感
+	Confidence float64     `json:"confidence,omitempty"` // 最近一次识别的置信度
+	Chunks     *ChunkStats `json:"chunks,omitempty"`     // 音频块序号统计，客户端带序号时提供
+}
+
+// LibrarySummary 样本库统计
+type LibrarySummary struct {
+	TotalSamples int                     `json:"totalSamples"`
+	Emotions     []LibraryEmotionSummary `json:"emotions"`
+}
+
+// LibraryEmotionSummary 单个情感的样本统计
+type LibraryEmotionSummary struct {
+	ID        string  `json:"id"`
+	Label     string  `json:"label"`
+	Samples   int     `json:"samples"`
+	MeanPitch float64 `json:"meanPitch"` // 平均音高（Hz）
+}
+
+// LibrarySummarizer 能够汇报样本库统计的处理器
+type LibrarySummarizer interface {
+	LibrarySummary(lang string) LibrarySummary
+}
+
+// DashboardState /dashboard/state 的响应
+type DashboardState struct {
+	Sessions []StreamActivity `json:"sessions"`
+	Library  *LibrarySummary  `json:"library,omitempty"`
+	Emotions []string         `json:"emotions"`
+}
+
+// trackActivity 记录会话收到的数据与产生的结果，result 为处理器返回的JSON
+func (s *AudioServer) trackActivity(streamID, transport string, samples int, result []byte) {
+	now := time.Now()
+	s.activityMu.Lock()
+	defer s.activityMu.Unlock()
+
+	if s.activity == nil {
+		s.activity = make(map[string]*StreamActivity)
+	}
+	activity, ok := s.activity[streamID]
+	if !ok {
+		activity = &StreamActivity{StreamID: streamID, Transport: transport, StartedAt: now.UnixMilli()}
+		s.activity[streamID] = activity
+	}
+	activity.LastSeen = now.UnixMilli()
+	activity.Samples += int64(samples)
+
+	var parsed struct {
+		Status     string  `json:"status"`
+		Emotion    string  `json:"emotion"`
+		Confidence float64 `json:"confidence"`
+	}
+	if len(result) > 0 && json.Unmarshal(result, &parsed) == nil && parsed.Emotion != "" && parsed.Status != "waiting" {
+		activity.Results++
+		activity.Emotion = parsed.Emotion
+		activity.Confidence = parsed.Confidence
+	}
+}
+
+// forgetActivity 会话结束后从面板移除
+func (s *AudioServer) forgetActivity(streamID string) {
+	s.activityMu.Lock()
+	defer s.activityMu.Unlock()
+	delete(s.activity, streamID)
+}
+
+// activeSessions 返回近期有数据的会话，按开始时间排序，同时清理长时间无数据的会话
+func (s *AudioServer) activeSessions(now time.Time) []StreamActivity {
+	s.activityMu.Lock()
+	defer s.activityMu.Unlock()
+
+	sessions := make([]StreamActivity, 0, len(s.activity))
+	for id, activity := range s.activity {
+		if now.Sub(time.UnixMilli(activity.LastSeen)) > DashboardIdleTimeout {
+			delete(s.activity, id)
+			continue
+		}
+		snapshot := *activity
+		snapshot.Chunks = s.chunkStats(id)
+		sessions = append(sessions, snapshot)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].StartedAt != sessions[j].StartedAt {
+			return sessions[i].StartedAt < sessions[j].StartedAt
+		}
+		return sessions[i].StreamID < sessions[j].StreamID
+	})
+	return sessions
+}
+
+// handleDashboard 调试面板：/dashboard 返回页面，/dashboard/state 返回会话与样本库统计，
+// /dashboard/events 同 /events，页面据此订阅选中会话的情感变化事件
+func (s *AudioServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/dashboard"), "/") {
+	case "":
+		page, err := dashboardFS.ReadFile("dashboard/index.html")
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "面板页面缺失", "")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	case "/state":
+		state := DashboardState{
+			Sessions: s.activeSessions(time.Now()),
+			Emotions: s.processor.Emotions(),
+		}
+		if summarizer, ok := s.processor.(LibrarySummarizer); ok {
+			summary := summarizer.LibrarySummary(r.URL.Query().Get("lang"))
+			state.Library = &summary
+		}
+		writeResponse(w, r, http.StatusOK, state)
+	case "/events":
+		s.handleEvents(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// LibrarySummary 当前样本库中各情感的样本数与平均音高，情感名称使用 lang 语言
+func (e *Engine) LibrarySummary(lang string) LibrarySummary {
+	summary := LibrarySummary{Emotions: []LibraryEmotionSummary{}}
+	library := e.library()
+	if library == nil {
+		return summary
+	}
+	for _, emotion := range e.Emotions() {
+		samples := library.Samples[emotion]
+		item := LibraryEmotionSummary{ID: emotion, Label: EmotionLabel(lang, emotion), Samples: len(samples)}
+		for _, sample := range samples {
+			item.MeanPitch += sample.Features.Pitch / float64(len(samples))
+		}
+		summary.TotalSamples += len(samples)
+		summary.Emotions = append(summary.Emotions, item)
+	}
+	return summary
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDashboard 测试调试面板
+// 测试内容：
+// 1. /dashboard 返回内嵌的页面
+// 2. /dashboard/state 列出发送过数据的会话、最近结果与样本库统计
+// 3. /stop 后会话从面板移除，未知路径返回404
+func TestDashboard(t *testing.T) {
+	server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.handleDashboard(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	state := func() DashboardState {
+		t.Helper()
+		var state DashboardState
+		if err := json.Unmarshal(get("/dashboard/state?lang=zh").Body.Bytes(), &state); err != nil {
+			t.Fatal(err)
+		}
+		return state
+	}
+
+	if rec := get("/dashboard"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/dashboard/state") {
+		t.Errorf("/dashboard = %d, want the embedded page", rec.Code)
+	}
+	if rec := get("/dashboard/unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("/dashboard/unknown = %d, want 404", rec.Code)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"streamId": "cat1", "data": generateTestAudio(440, 0.1, 44100)})
+	server.handleSend(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/send", bytes.NewReader(body)))
+
+	got := state()
+	if len(got.Sessions) != 1 {
+		t.Fatalf("sessions = %+v, want cat1", got.Sessions)
+	}
+	session := got.Sessions[0]
+	if session.StreamID != "cat1" || session.Transport != "http" || session.Samples != 4410 || session.Results != 1 || session.Emotion == "" {
+		t.Errorf("session = %+v", session)
+	}
+	if got.Library == nil || got.Library.TotalSamples == 0 || len(got.Library.Emotions) != len(got.Emotions) {
+		t.Fatalf("library = %+v, emotions = %v", got.Library, got.Emotions)
+	}
+	if first := got.Library.Emotions[0]; first.Label != EmotionLabel("zh", first.ID) {
+		t.Errorf("label = %q, want zh label", first.Label)
+	}
+
+	server.handleStop(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/stop", strings.NewReader(`{"streamId":"cat1"}`)))
+	if got := state(); len(got.Sessions) != 0 {
+		t.Errorf("sessions after stop = %+v", got.Sessions)
+	}
+}
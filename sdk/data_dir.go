@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// 工具的数据目录：默认输出放在平台约定的数据目录（$XDG_DATA_HOME/meowtalk、~/Library/Application Support/meowtalk、
+// %AppData%\meowtalk）下，-data-dir 可指定其他目录，命令行显式给出的输出路径按原样使用。
+
+// dataDirName 数据目录在平台目录下的子目录名
+const dataDirName = "meowtalk"
+
+// DefaultDataDir 返回平台约定的数据目录
+func DefaultDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("data dir: %v", err)
+		}
+		return filepath.Join(dir, dataDirName), nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, dataDirName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("data dir: %v", err)
+	}
+	return filepath.Join(home, ".local", "share", dataDirName), nil
+}
+
+// addDataDirFlag 注册 -data-dir 参数
+func addDataDirFlag(fs *flag.FlagSet) *string {
+	return fs.String("data-dir", "", "输出文件的数据目录，为空时使用平台约定的目录（Linux 为 ~/.local/share/meowtalk）")
+}
+
+// dataPath 返回数据目录下的路径，dir 为空时使用平台约定的数据目录，数据目录不存在时创建
+// name 为绝对路径时原样返回
+func dataPath(dir, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	if dir == "" {
+		var err error
+		if dir, err = DefaultDataDir(); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("data dir: %v", err)
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import "time"
+
+// 确定性处理模式
+//
+// 默认模式下是否触发处理、事件去抖、时间先验都依赖墙上时钟，同一段输入在不同时刻、
+// 不同网络抖动下可能得到不同结果。确定性模式下所有时间都由已接收的样本数推算，
+// 相同的输入字节总是产生相同的输出，是基于录制回放做回归测试的前提。
+
+// deterministicEpoch 确定性模式下流时钟的起点（UTC正午，避开夜间等时间段先验的边界）
+var deterministicEpoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// streamClock 根据已接收的样本数推算流时钟
+func streamClock(samples int64, sampleRate int) time.Time {
+	if sampleRate <= 0 {
+		return deterministicEpoch
+	}
+	return deterministicEpoch.Add(time.Duration(samples) * time.Second / time.Duration(sampleRate))
+}
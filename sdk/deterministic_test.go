@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestStreamClock 测试按样本数推算的流时钟
+func TestStreamClock(t *testing.T) {
+	if got := streamClock(0, 441); !got.Equal(deterministicEpoch) {
+		t.Errorf("streamClock(0) = %v, want epoch", got)
+	}
+	if got := streamClock(882, 441).Sub(deterministicEpoch); got != 2*time.Second {
+		t.Errorf("streamClock(882, 441) offset = %v, want 2s", got)
+	}
+}
+
+// TestDeterministicReplay 测试确定性模式下相同输入产生相同输出
+// 测试内容：
+// 1. 同一份录制在两个独立处理器上快速回放
+// 2. 输出逐字节一致
+func TestDeterministicReplay(t *testing.T) {
+	var records []RecordedRequest
+	for i := 0; i < 8; i++ {
+		data := make([]float64, 5000)
+		for j := range data {
+			// 叫声与静默交替
+			if i%4 < 2 {
+				data[j] = 0.4 * math.Sin(float64(i*5000+j)*0.7)
+			}
+		}
+		payload, _ := json.Marshal(SendAudioRequest{StreamID: "replay", Data: data})
+		records = append(records, RecordedRequest{
+			OffsetMs: int64(i * 450),
+			Kind:     RecordKindHTTP,
+			StreamID: "replay",
+			Payload:  payload,
+		})
+	}
+
+	run := func() []byte {
+		processor := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Deterministic: true})
+		var out bytes.Buffer
+		if err := ReplayRecording(processor, records, 0, &out); err != nil {
+			t.Fatalf("ReplayRecording() error = %v", err)
+		}
+		return out.Bytes()
+	}
+
+	first, second := run(), run()
+	if !bytes.Contains(first, []byte(`"emotion"`)) {
+		t.Fatalf("replay produced no processed results:\n%s", first)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("deterministic replay outputs differ:\n%s\n---\n%s", first, second)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 运行时诊断
+//
+// FFT 密集的负载下排查CPU与内存分配需要从运行中的服务采集 profile。启用后 pprof 与 expvar
+// 在单独的端口上提供，不经过对外的API端口，便于只绑定到本机或内网地址：
+//   go tool pprof http://127.0.0.1:6060/debug/pprof/profile?seconds=30
+//   go tool pprof http://127.0.0.1:6060/debug/pprof/allocs
+//   curl http://127.0.0.1:6060/debug/vars
+// net/http/pprof 与 expvar 导入时会向 http.DefaultServeMux 注册 /debug/ 路径，
+// Start 使用默认路由，因此API端口上屏蔽这些路径。
+
+var (
+	diagnosticsOnce   sync.Once
+	diagnosticsMu     sync.Mutex
+	diagnosticsServer *AudioServer // expvar 统计的服务，最近一次 StartDiagnostics 传入
+)
+
+// publishDiagnostics 注册服务相关的 expvar 变量（进程内只注册一次）
+func publishDiagnostics() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("activeSessions", expvar.Func(func() interface{} {
+		diagnosticsMu.Lock()
+		server := diagnosticsServer
+		diagnosticsMu.Unlock()
+		if server == nil {
+			return 0
+		}
+		return len(server.activeSessions(time.Now()))
+	}))
+}
+
+// diagnosticsHandler 诊断接口路由：/debug/pprof/ 与 /debug/vars
+func diagnosticsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// StartDiagnostics 在 addr 上启动诊断接口，监听失败时返回错误；返回的服务用于关闭
+func StartDiagnostics(addr string, server *AudioServer) (*http.Server, error) {
+	diagnosticsOnce.Do(publishDiagnostics)
+	diagnosticsMu.Lock()
+	diagnosticsServer = server
+	diagnosticsMu.Unlock()
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("diagnostics: %v", err)
+	}
+	httpServer := &http.Server{Handler: diagnosticsHandler()}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("诊断接口停止: %v", err)
+		}
+	}()
+	log.Printf("诊断接口（pprof、expvar）监听在 http://%s/debug/", listener.Addr())
+	return httpServer, nil
+}
+
+// hideDiagnostics 屏蔽默认路由上的 /debug/ 路径，诊断接口只在单独端口提供
+func hideDiagnostics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/debug/") {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDiagnostics 测试运行时诊断接口
+// 测试内容：
+// 1. 单独端口提供 pprof 与 expvar，expvar 含服务的会话数
+// 2. API端口的默认路由上屏蔽 /debug/ 路径
+func TestDiagnostics(t *testing.T) {
+	server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+	server.trackActivity("cat1", "http", 4410, nil)
+	diagnostics, err := StartDiagnostics("127.0.0.1:0", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer diagnostics.Close()
+	if _, err := StartDiagnostics("256.0.0.1:0", server); err == nil {
+		t.Error("invalid address should fail")
+	}
+
+	handler := diagnosticsHandler()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/heap?debug=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/debug/pprof/heap = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	var vars map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars["activeSessions"] != float64(1) || vars["goroutines"] == nil || vars["memstats"] == nil {
+		t.Errorf("expvar activeSessions = %v, goroutines = %v", vars["activeSessions"], vars["goroutines"])
+	}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/vars"} {
+		rec := httptest.NewRecorder()
+		hideDiagnostics(http.DefaultServeMux).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("API port %s = %d, want 404", path, rec.Code)
+		}
+	}
+}
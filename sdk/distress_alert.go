@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// 持续不适告警
+//
+// 主人外出时用设备监听独处的猫，关心的不是每一声叫的情感，而是猫是否长时间处于不适状态。
+// 启用告警规则后，服务端按流统计规则所列情感在最近一段时间内的累计时长（每个结果代表自上一个结果
+// 以来的音频），超过阈值时通过情感变化事件的同一通道（SSE /events、WebSocket）推送一条 type 为
+// alert 的事件；同一流在冷却时间内不重复告警。中间结果（partial）不参与统计。
+
+// EventTypeAlert 持续不适告警事件类型
+const EventTypeAlert = "alert"
+
+// DistressRule 持续不适告警规则
+type DistressRule struct {
+	Name          string   `json:"name"`          // 规则名称，随告警事件推送
+	Emotions      []string `json:"emotions"`      // 视为不适的情感
+	MinConfidence float64  `json:"minConfidence"` // 结果置信度低于该值时不计入
+	SustainedMs   int      `json:"sustainedMs"`   // 统计窗口内不适情感累计达到该时长（毫秒）时告警
+	WindowMs      int      `json:"windowMs"`      // 统计窗口（毫秒）
+	MaxGapMs      int      `json:"maxGapMs"`      // 相邻结果间隔超过该时长（毫秒）时不计入，避免断流期间被算作不适
+	CooldownMs    int      `json:"cooldownMs"`    // 同一流两次告警的最短间隔（毫秒）
+}
+
+// DefaultDistressRule 默认规则：2分钟内不适叫声累计超过30秒，5分钟内不重复告警
+func DefaultDistressRule() DistressRule {
+	return DistressRule{
+		Name:        "sustained-distress",
+		Emotions:    []string{"anxious", "discomfort", "find_mom", "warning", "for_fight"},
+		SustainedMs: 30000,
+		WindowMs:    120000,
+		MaxGapMs:    5000,
+		CooldownMs:  300000,
+	}
+}
+
+// Validate 校验告警规则
+func (r DistressRule) Validate() error {
+	if len(r.Emotions) == 0 {
+		return fmt.Errorf("distress rule: at least one emotion is required")
+	}
+	if r.MinConfidence < 0 || r.MinConfidence > 1 {
+		return fmt.Errorf("distress rule: minConfidence %.2f out of range [0, 1]", r.MinConfidence)
+	}
+	if r.SustainedMs <= 0 || r.WindowMs < r.SustainedMs {
+		return fmt.Errorf("distress rule: sustainedMs must be positive and not exceed windowMs")
+	}
+	if r.MaxGapMs <= 0 || r.CooldownMs < 0 {
+		return fmt.Errorf("distress rule: maxGapMs must be positive and cooldownMs non-negative")
+	}
+	return nil
+}
+
+// LoadDistressRule 从JSON文件加载告警规则，文件中未出现的字段使用默认值
+func LoadDistressRule(path string) (DistressRule, error) {
+	rule := DefaultDistressRule()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rule, fmt.Errorf("read distress rule: %v", err)
+	}
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return rule, fmt.Errorf("parse distress rule: %v", err)
+	}
+	return rule, rule.Validate()
+}
+
+// matches 结果是否计入不适时长
+func (r DistressRule) matches(emotion string, confidence float64) bool {
+	if confidence < r.MinConfidence {
+		return false
+	}
+	emotion = NormalizeEmotionID(emotion)
+	for _, e := range r.Emotions {
+		if NormalizeEmotionID(e) == emotion {
+			return true
+		}
+	}
+	return false
+}
+
+// DistressAlert 持续不适告警事件
+type DistressAlert struct {
+	Type       string `json:"type"`       // 事件类型，固定为 alert
+	StreamID   string `json:"streamId"`   // 流ID
+	Rule       string `json:"rule"`       // 触发的规则名称
+	Emotion    string `json:"emotion"`    // 统计窗口内累计时长最长的不适情感
+	DistressMs int64  `json:"distressMs"` // 统计窗口内不适情感的累计时长（毫秒）
+	WindowMs   int64  `json:"windowMs"`   // 统计窗口（毫秒）
+	Since      int64  `json:"since"`      // 统计窗口内第一段不适叫声的开始时间（毫秒时间戳）
+	Timestamp  int64  `json:"timestamp"`  // 告警发出的时间（毫秒时间戳）
+}
+
+// distressSpan 一段计入不适时长的音频
+type distressSpan struct {
+	start, end time.Time
+	emotion    string
+}
+
+// distressState 单个流的不适统计
+type distressState struct {
+	last      time.Time      // 上一个结果的时间
+	spans     []distressSpan // 统计窗口内的不适时段
+	lastAlert time.Time      // 上一次告警的时间
+}
+
+// distressMonitor 按流统计不适时长
+type distressMonitor struct {
+	mu      sync.Mutex
+	rule    *DistressRule // 为nil时不启用告警
+	streams map[string]*distressState
+}
+
+// SetDistressRule 设置持续不适告警规则，传入nil关闭告警
+func (s *AudioServer) SetDistressRule(rule *DistressRule) error {
+	if rule != nil {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	m := &s.distress
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rule = rule
+	m.streams = make(map[string]*distressState)
+	return nil
+}
+
+// observe 输入流的一个结果，达到告警条件时返回告警
+func (m *distressMonitor) observe(streamID, emotion string, confidence float64, now time.Time) (DistressAlert, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rule == nil {
+		return DistressAlert{}, false
+	}
+	rule := *m.rule
+
+	state, ok := m.streams[streamID]
+	if !ok {
+		state = &distressState{}
+		m.streams[streamID] = state
+	}
+
+	// 结果代表自上一个结果以来的音频
+	gap := time.Duration(rule.MaxGapMs) * time.Millisecond
+	if !state.last.IsZero() && now.Sub(state.last) <= gap && rule.matches(emotion, confidence) {
+		state.spans = append(state.spans, distressSpan{start: state.last, end: now, emotion: NormalizeEmotionID(emotion)})
+	}
+	state.last = now
+
+	// 只保留统计窗口内的部分
+	windowStart := now.Add(-time.Duration(rule.WindowMs) * time.Millisecond)
+	kept := state.spans[:0]
+	for _, span := range state.spans {
+		if span.end.After(windowStart) {
+			kept = append(kept, span)
+		}
+	}
+	state.spans = kept
+
+	var total time.Duration
+	durations := make(map[string]time.Duration)
+	for _, span := range state.spans {
+		start := span.start
+		if start.Before(windowStart) {
+			start = windowStart
+		}
+		total += span.end.Sub(start)
+		durations[span.emotion] += span.end.Sub(start)
+	}
+
+	cooldown := time.Duration(rule.CooldownMs) * time.Millisecond
+	if total < time.Duration(rule.SustainedMs)*time.Millisecond {
+		return DistressAlert{}, false
+	}
+	if !state.lastAlert.IsZero() && now.Sub(state.lastAlert) < cooldown {
+		return DistressAlert{}, false
+	}
+	state.lastAlert = now
+
+	dominant := ""
+	for e, d := range durations {
+		if dominant == "" || d > durations[dominant] || (d == durations[dominant] && e < dominant) {
+			dominant = e
+		}
+	}
+	since := state.spans[0].start
+	if since.Before(windowStart) {
+		since = windowStart
+	}
+	return DistressAlert{
+		Type:       EventTypeAlert,
+		StreamID:   streamID,
+		Rule:       rule.Name,
+		Emotion:    dominant,
+		DistressMs: total.Milliseconds(),
+		WindowMs:   int64(rule.WindowMs),
+		Since:      since.UnixMilli(),
+		Timestamp:  now.UnixMilli(),
+	}, true
+}
+
+// forget 清除流的不适统计
+func (m *distressMonitor) forget(streamID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.streams, streamID)
+}
+
+// checkDistress 将流的最终结果输入不适统计，达到告警条件时向该流的事件订阅者推送告警并记入结果历史
+// catID 为流关联的猫咪，没有时为空
+func (s *AudioServer) checkDistress(streamID, catID string, result []byte, now time.Time) {
+	var parsed struct {
+		Status     string  `json:"status"`
+		Emotion    string  `json:"emotion"`
+		Confidence float64 `json:"confidence"`
+		Partial    bool    `json:"partial"`
+	}
+	if len(result) == 0 || json.Unmarshal(result, &parsed) != nil {
+		return
+	}
+	if parsed.Emotion == "" || parsed.Partial || parsed.Status == "waiting" {
+		return
+	}
+
+	alert, ok := s.distress.observe(streamID, parsed.Emotion, parsed.Confidence, now)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+	log.Printf("[%s] 持续不适告警: 规则=%s, 情感=%s, 累计=%dms", streamID, alert.Rule, alert.Emotion, alert.DistressMs)
+	s.processor.Events().PublishData(streamID, data)
+	if s.history != nil {
+		s.history.AddAlert(alert, catID, now)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDistressAlert 测试持续不适告警
+// 测试内容：
+// 1. 不适情感累计时长达到阈值时告警，间隔过长或非不适的结果不计入
+// 2. 冷却时间内不重复告警，超出统计窗口的时段不再计入
+// 3. 告警以 alert 类型推送给流的事件订阅者，未启用规则时不告警
+// 4. 规则文件缺失字段使用默认值，无效规则返回错误
+func TestDistressAlert(t *testing.T) {
+	rule := DistressRule{Name: "test", Emotions: []string{"anxious"}, SustainedMs: 3500, WindowMs: 10000, MaxGapMs: 2000, CooldownMs: 20000}
+	server := NewAudioServer(NewMockAudioProcessor())
+	if err := server.SetDistressRule(&rule); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1700000000, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	observe := func(ms int, emotion string) (DistressAlert, bool) {
+		return server.distress.observe("cat1", emotion, 0.8, at(ms))
+	}
+
+	// 0~2000 不适，2000~3000 平静，3000~8000 间隔过长，8000~10000 不适
+	for _, step := range []struct {
+		ms      int
+		emotion string
+	}{{0, "anxious"}, {1000, "anxious"}, {2000, "anxious"}, {3000, "hello"}, {8000, "anxious"}, {9000, "anxious"}} {
+		if _, ok := observe(step.ms, step.emotion); ok {
+			t.Fatalf("unexpected alert at %dms before distress reaches the threshold", step.ms)
+		}
+	}
+	alert, ok := observe(10000, "anxious")
+	if !ok || alert.Type != EventTypeAlert || alert.DistressMs != 4000 || alert.Emotion != "anxious" || alert.Since != at(0).UnixMilli() {
+		t.Fatalf("alert = %+v, %t; want 4000ms since the first span", alert, ok)
+	}
+	if _, ok := observe(11000, "anxious"); ok {
+		t.Error("should not alert again within the cooldown")
+	}
+
+	// 冷却结束后，只有统计窗口内的时段计入
+	if _, ok := observe(31000, "anxious"); ok {
+		t.Error("spans outside the window should not count after a long pause")
+	}
+	for ms := 32000; ms <= 34000; ms += 1000 {
+		observe(ms, "anxious")
+	}
+	if alert, ok := observe(35000, "anxious"); !ok || alert.DistressMs != 4000 || alert.Since != at(31000).UnixMilli() {
+		t.Errorf("alert after cooldown = %+v, %t", alert, ok)
+	}
+
+	events, unsubscribe := server.processor.Events().Subscribe("cat2")
+	defer unsubscribe()
+	for ms := 0; ms <= 4000; ms += 1000 {
+		result, _ := json.Marshal(map[string]interface{}{"status": "processed", "emotion": "anxious", "confidence": 0.9})
+		server.checkDistress("cat2", "", result, at(ms))
+	}
+	select {
+	case event := <-events:
+		if eventType(event) != EventTypeAlert {
+			t.Errorf("event type = %s, want alert: %s", eventType(event), event)
+		}
+	default:
+		t.Error("alert should be published to the stream's subscribers")
+	}
+
+	server.SetDistressRule(nil)
+	for ms := 0; ms <= 5000; ms += 1000 {
+		if _, ok := server.distress.observe("cat3", "anxious", 1, at(ms)); ok {
+			t.Fatal("disabled rule should not alert")
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "rule.json")
+	os.WriteFile(path, []byte(`{"emotions": ["warning"], "sustainedMs": 10000}`), 0644)
+	loaded, err := LoadDistressRule(path)
+	if err != nil || loaded.WindowMs != DefaultDistressRule().WindowMs || loaded.Emotions[0] != "warning" {
+		t.Errorf("LoadDistressRule = %+v, %v", loaded, err)
+	}
+	for _, bad := range []string{`{"emotions": []}`, `{"sustainedMs": 200000}`, `{"minConfidence": 2}`} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if _, err := LoadDistressRule(path); err == nil {
+			t.Errorf("rule %s should be invalid", bad)
+		}
+	}
+	if eventType([]byte(fmt.Sprintf(`{"streamId":"%s"}`, "cat1"))) != EventTypeEmotionChange {
+		t.Error("events without a type should default to emotion_change")
+	}
+}
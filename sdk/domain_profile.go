@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DomainProfile 物种/领域配置：频率范围、叫声时长、情感集合、短语目录等可从JSON加载，
+// 同一套引擎更换配置即可识别狗叫、鸟鸣等其他声音
+type DomainProfile struct {
+	Name             string                        `json:"name"`             // 配置名称，如 cat / dog
+	FrequencyPresets map[string]FrequencyRange     `json:"frequencyPresets"` // 可选的频率范围预设
+	DefaultPreset    string                        `json:"defaultPreset"`    // 默认频率预设
+	MinDuration      float64                       `json:"minDuration"`      // 切分后叫声片段的最短时长（秒），更短的视为咔哒声等瞬态噪声
+	MaxDuration      float64                       `json:"maxDuration"`      // 切分后叫声片段的最长时长（秒），更长的视为持续的背景声，0表示不限制
+	Emotions         []string                      `json:"emotions"`         // 情感集合，为空时使用样本库中的全部情感
+	EmotionAliases   map[string]string             `json:"emotionAliases"`   // 情感别名 -> 规范情感ID，如旧样本目录名
+	Phrases          []PhraseRule                  `json:"phrases"`          // 短语目录，情感(+强度+上下文) -> 提示短语模板
+	Thresholds       map[string]float64            `json:"thresholds"`       // 情感 -> 接受阈值，置信度低于阈值时结果为 unknown
+	DefaultThreshold float64                       `json:"defaultThreshold"` // 未单独配置的情感使用的接受阈值，0表示 DefaultEmotionThreshold
+	Hints            map[string]map[string]float64 `json:"hints"`            // 情境提示 -> 情感 -> 先验倍数，覆盖同名的内置提示（见 context_hints.go）
+}
+
+// DefaultEmotionThreshold 默认的情感接受阈值
+const DefaultEmotionThreshold = 0.5
+
+var (
+	activeProfile *DomainProfile
+	profileMu     sync.RWMutex
+)
+
+// DefaultDomainProfile 内置的猫咪配置
+func DefaultDomainProfile() *DomainProfile {
+	presets := make(map[string]FrequencyRange, len(frequencyPresets))
+	for name, preset := range frequencyPresets {
+		presets[name] = preset
+	}
+
+	return &DomainProfile{
+		Name:             "cat",
+		FrequencyPresets: presets,
+		DefaultPreset:    FrequencyPresetAdult,
+		MinDuration:      0.1,
+		MaxDuration:      0,
+		Emotions:         nil,
+		EmotionAliases:   copyAliases(defaultCatEmotionAliases),
+		Phrases:          append([]PhraseRule(nil), defaultCatPhrases...),
+	}
+}
+
+// LoadDomainProfile 从JSON文件加载领域配置
+func LoadDomainProfile(path string) (*DomainProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read domain profile: %v", err)
+	}
+
+	var profile DomainProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("parse domain profile: %v", err)
+	}
+
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Validate 校验配置的完整性
+func (p *DomainProfile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("domain profile: name is required")
+	}
+	if len(p.FrequencyPresets) == 0 {
+		return fmt.Errorf("domain profile %s: at least one frequency preset is required", p.Name)
+	}
+	for name, preset := range p.FrequencyPresets {
+		if preset.PitchMin <= 0 || preset.PitchMin >= preset.PitchMax {
+			return fmt.Errorf("domain profile %s: preset %s has invalid pitch range", p.Name, name)
+		}
+		if preset.PeakMin < 0 || preset.PeakMin >= preset.PeakMax {
+			return fmt.Errorf("domain profile %s: preset %s has invalid peak range", p.Name, name)
+		}
+		if preset.ValidPitchMax < preset.PitchMax {
+			return fmt.Errorf("domain profile %s: preset %s validPitchMax below pitchMax", p.Name, name)
+		}
+	}
+	if _, ok := p.FrequencyPresets[p.DefaultPreset]; !ok {
+		return fmt.Errorf("domain profile %s: default preset %q not defined", p.Name, p.DefaultPreset)
+	}
+	if p.MinDuration < 0 || (p.MaxDuration > 0 && p.MaxDuration <= p.MinDuration) {
+		return fmt.Errorf("domain profile %s: invalid duration limits", p.Name)
+	}
+	for alias, canonical := range p.EmotionAliases {
+		if canonicalEmotionID(canonical) == "" {
+			return fmt.Errorf("domain profile %s: alias %q has empty target", p.Name, alias)
+		}
+		if _, ok := p.aliasTarget(canonicalEmotionID(canonical)); ok {
+			return fmt.Errorf("domain profile %s: alias target %q is itself an alias", p.Name, canonical)
+		}
+	}
+	if p.DefaultThreshold < 0 || p.DefaultThreshold > 1 {
+		return fmt.Errorf("domain profile %s: defaultThreshold %.2f out of range [0, 1]", p.Name, p.DefaultThreshold)
+	}
+	for emotion, threshold := range p.Thresholds {
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("domain profile %s: threshold for %q %.2f out of range [0, 1]", p.Name, emotion, threshold)
+		}
+	}
+	for hint, weights := range p.Hints {
+		if err := validatePriorWeights(weights); err != nil {
+			return fmt.Errorf("domain profile %s: hint %s: %v", p.Name, hint, err)
+		}
+	}
+	for i, rule := range p.Phrases {
+		if rule.Emotion == "" || rule.Template == "" {
+			return fmt.Errorf("domain profile %s: phrase #%d requires emotion and template", p.Name, i)
+		}
+		if _, err := parsePhraseTemplate(rule.Template); err != nil {
+			return fmt.Errorf("domain profile %s: phrase #%d: %v", p.Name, i, err)
+		}
+	}
+	return nil
+}
+
+// SetDomainProfile 设置当前生效的领域配置，传入nil恢复内置猫咪配置
+func SetDomainProfile(profile *DomainProfile) error {
+	if profile != nil {
+		if err := profile.Validate(); err != nil {
+			return err
+		}
+		// 预设名称以键为准
+		for name, preset := range profile.FrequencyPresets {
+			preset.Name = name
+			profile.FrequencyPresets[name] = preset
+		}
+	}
+
+	profileMu.Lock()
+	defer profileMu.Unlock()
+	activeProfile = profile
+	return nil
+}
+
+// CurrentDomainProfile 返回当前生效的领域配置
+func CurrentDomainProfile() *DomainProfile {
+	profileMu.RLock()
+	defer profileMu.RUnlock()
+	if activeProfile == nil {
+		return defaultProfile
+	}
+	return activeProfile
+}
+
+var defaultProfile = DefaultDomainProfile()
+
+// HasEmotion 判断情感是否属于该配置的情感集合（集合为空时全部接受），按规范ID比较
+func (p *DomainProfile) HasEmotion(emotion string) bool {
+	if len(p.Emotions) == 0 {
+		return true
+	}
+	emotion = p.NormalizeEmotion(emotion)
+	for _, e := range p.Emotions {
+		if p.NormalizeEmotion(e) == emotion {
+			return true
+		}
+	}
+	return false
+}
+
+// Threshold 返回情感的接受阈值，按规范ID查找，未配置时使用默认阈值
+func (p *DomainProfile) Threshold(emotion string) float64 {
+	emotion = p.NormalizeEmotion(emotion)
+	for name, threshold := range p.Thresholds {
+		if p.NormalizeEmotion(name) == emotion {
+			return threshold
+		}
+	}
+	if p.DefaultThreshold > 0 {
+		return p.DefaultThreshold
+	}
+	return DefaultEmotionThreshold
+}
+
+// DurationInRange 判断叫声时长是否在配置的有效范围内
+func (p *DomainProfile) DurationInRange(duration float64) bool {
+	if duration < p.MinDuration {
+		return false
+	}
+	return p.MaxDuration <= 0 || duration <= p.MaxDuration
+}
+
+// FilterSegments 丢弃时长不在有效范围内的叫声片段，在匹配之前调用
+func (p *DomainProfile) FilterSegments(segments []AudioSegment, sampleRate int) []AudioSegment {
+	kept := segments[:0]
+	for _, segment := range segments {
+		if p.DurationInRange(float64(segment.End-segment.Start) / float64(sampleRate)) {
+			kept = append(kept, segment)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadDomainProfile 测试领域配置加载
+// 测试内容：
+// 1. 内置示例配置均可加载
+// 2. 配置切换后频率预设随之变化
+// 3. 无效配置返回错误
+func TestLoadDomainProfile(t *testing.T) {
+	for _, name := range []string{"cat.json", "dog.json"} {
+		if _, err := LoadDomainProfile(filepath.Join("profiles", name)); err != nil {
+			t.Errorf("LoadDomainProfile(%s) error = %v", name, err)
+		}
+	}
+
+	dog, err := LoadDomainProfile(filepath.Join("profiles", "dog.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetDomainProfile(dog); err != nil {
+		t.Fatal(err)
+	}
+	defer SetDomainProfile(nil)
+
+	if _, err := LookupFrequencyPreset(FrequencyPresetKitten); err == nil {
+		t.Error("kitten preset should not exist in dog profile")
+	}
+	if preset, err := LookupFrequencyPreset(""); err != nil || preset.Name != "medium" {
+		t.Errorf("default preset = %q, %v; want medium", preset.Name, err)
+	}
+	if CurrentDomainProfile().HasEmotion("contented") {
+		t.Error("dog profile should not accept cat emotions")
+	}
+
+	testDir := t.TempDir()
+	invalid := filepath.Join(testDir, "invalid.json")
+	os.WriteFile(invalid, []byte(`{"name":"bird","defaultPreset":"x","frequencyPresets":{}}`), 0644)
+	if _, err := LoadDomainProfile(invalid); err == nil {
+		t.Error("expected error for profile without presets")
+	}
+}
+
+// TestEmotionThresholds 测试按情感配置的接受阈值
+// 测试内容：
+// 1. 未配置阈值时使用默认阈值 0.5
+// 2. 单独配置的阈值按规范ID查找，别名与原名共用同一阈值
+// 3. 超出 [0, 1] 的阈值校验失败
+func TestEmotionThresholds(t *testing.T) {
+	if threshold := DefaultDomainProfile().Threshold("warning"); threshold != DefaultEmotionThreshold {
+		t.Errorf("default threshold = %.2f, want %.2f", threshold, DefaultEmotionThreshold)
+	}
+
+	cat, err := LoadDomainProfile(filepath.Join("profiles", "cat.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if threshold := cat.Threshold("Warning"); threshold != 0.35 {
+		t.Errorf("warning threshold = %.2f, want 0.35", threshold)
+	}
+	if threshold := cat.Threshold("contented"); threshold != cat.Threshold("satisfy") || threshold != 0.6 {
+		t.Errorf("alias threshold = %.2f, want the satisfy threshold 0.6", threshold)
+	}
+	cat.DefaultThreshold = 0.7
+	if threshold := cat.Threshold("hello"); threshold != 0.7 {
+		t.Errorf("unconfigured threshold = %.2f, want defaultThreshold 0.7", threshold)
+	}
+
+	for _, bad := range []func(p *DomainProfile){
+		func(p *DomainProfile) { p.DefaultThreshold = 1.5 },
+		func(p *DomainProfile) { p.Thresholds = map[string]float64{"warning": -0.1} },
+	} {
+		profile := DefaultDomainProfile()
+		bad(profile)
+		if err := profile.Validate(); err == nil {
+			t.Errorf("thresholds %v / default %.2f should be invalid", profile.Thresholds, profile.DefaultThreshold)
+		}
+	}
+}
+
+// TestSegmentDurationBounds 测试切分后按叫声时长过滤片段
+// 测试内容：
+// 1. 默认配置下0.4秒与0.6秒的叫声都保留
+// 2. 配置 0.5~3.0 秒后0.4秒的叫声被丢弃
+// 3. 配置最长0.5秒后0.6秒的叫声被丢弃；splitSegments 本身不按时长过滤
+func TestSegmentDurationBounds(t *testing.T) {
+	pcm := buildCallRecording() // 0.4秒与0.6秒两声叫声
+	samples := make([]float64, len(pcm))
+	for i, v := range pcm {
+		samples[i] = float64(v) / 32768
+	}
+
+	if got := vocalizationSegments(samples, 16000, 0.3); len(got) != 2 {
+		t.Fatalf("默认配置 got %d segments, want 2", len(got))
+	}
+
+	profile := DefaultDomainProfile()
+	profile.MinDuration, profile.MaxDuration = 0.5, 3.0
+	if err := SetDomainProfile(profile); err != nil {
+		t.Fatalf("SetDomainProfile() error = %v", err)
+	}
+	defer SetDomainProfile(nil)
+	got := vocalizationSegments(samples, 16000, 0.3)
+	if len(got) != 1 || got[0].Start < 16000 {
+		t.Errorf("最短0.5秒时应只保留第二声叫声, got %+v", got)
+	}
+
+	profile = DefaultDomainProfile()
+	profile.MinDuration, profile.MaxDuration = 0.1, 0.5
+	if err := SetDomainProfile(profile); err != nil {
+		t.Fatalf("SetDomainProfile() error = %v", err)
+	}
+	if got := vocalizationSegments(samples, 16000, 0.3); len(got) != 1 || got[0].Start > 16000 {
+		t.Errorf("最长0.5秒时应只保留第一声叫声, got %+v", got)
+	}
+	if got := splitSegments(samples, 16000, 0.3); len(got) != 2 {
+		t.Errorf("splitSegments 不应按时长过滤, got %d segments", len(got))
+	}
+}
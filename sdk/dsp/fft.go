@@ -0,0 +1,80 @@
+// Package dsp 是 SDK 与 cmd/process_samples 共用的信号处理：FFT、抗混叠低通滤波与重采样。
+package dsp
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// 快速傅里叶变换：原地计算的迭代基2实现，旋转因子按长度缓存，非2的幂的输入补零到下一个2的幂。
+
+// fftTwiddles 各长度的旋转因子表：twiddles[k] = e^(-2πik/n)，k < n/2
+var fftTwiddles sync.Map // int -> []complex128
+
+// twiddlesFor 返回长度 n 的旋转因子表
+func twiddlesFor(n int) []complex128 {
+	if t, ok := fftTwiddles.Load(n); ok {
+		return t.([]complex128)
+	}
+	t := make([]complex128, n/2)
+	for k := range t {
+		s, c := math.Sincos(-2 * math.Pi * float64(k) / float64(n))
+		t[k] = complex(c, s)
+	}
+	fftTwiddles.Store(n, t)
+	return t
+}
+
+// FFT 原地计算 x 的离散傅里叶变换，len(x) 必须是2的幂
+func FFT(x []complex128) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+	if n&(n-1) != 0 {
+		panic("fft: length is not a power of two")
+	}
+
+	// 位反转排序
+	shift := 64 - uint(bits.TrailingZeros(uint(n)))
+	for i := range x {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	// 蝶形运算，长度为 size 的子变换使用步长 n/size 的旋转因子
+	twiddles := twiddlesFor(n)
+	for size := 2; size <= n; size <<= 1 {
+		half, stride := size/2, n/size
+		for start := 0; start < n; start += size {
+			for j := 0; j < half; j++ {
+				k, l := start+j, start+j+half
+				t := x[l] * twiddles[j*stride]
+				x[l] = x[k] - t
+				x[k] += t
+			}
+		}
+	}
+}
+
+// RealFFT 计算实数样本的频谱，长度不是2的幂时补零，返回长度为下一个2的幂的频谱
+func RealFFT(samples []float64) []complex128 {
+	spectrum := make([]complex128, NextPowerOfTwo(len(samples)))
+	for i, v := range samples {
+		spectrum[i] = complex(v, 0)
+	}
+	FFT(spectrum)
+	return spectrum
+}
+
+// NextPowerOfTwo 大于等于 n 的最小的2的幂
+func NextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
@@ -0,0 +1,118 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// naiveDFT 直接按定义计算的DFT，作为正确性参照
+func naiveDFT(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	for k := range out {
+		for t, v := range x {
+			out[k] += v * cmplx.Rect(1, -2*math.Pi*float64(k*t)/float64(n))
+		}
+	}
+	return out
+}
+
+// recursiveFFTReference 原特征提取器使用的递归FFT，仅用于性能对比
+func recursiveFFTReference(x []complex128) []complex128 {
+	n := len(x)
+	if n <= 1 {
+		return x
+	}
+	even := make([]complex128, n/2)
+	odd := make([]complex128, n/2)
+	for i := 0; i < n/2; i++ {
+		even[i] = x[2*i]
+		odd[i] = x[2*i+1]
+	}
+	even = recursiveFFTReference(even)
+	odd = recursiveFFTReference(odd)
+	result := make([]complex128, n)
+	for k := 0; k < n/2; k++ {
+		t := cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n)) * odd[k]
+		result[k] = even[k] + t
+		result[k+n/2] = even[k] - t
+	}
+	return result
+}
+
+// randomSignal 生成固定种子的随机复数信号
+func randomSignal(n int) []complex128 {
+	rng := rand.New(rand.NewSource(int64(n)))
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(rng.Float64()*2-1, rng.Float64()*2-1)
+	}
+	return x
+}
+
+// TestFFTMatchesDFT 测试FFT的正确性
+// 测试内容：
+// 1. 各个2的幂长度的结果与直接DFT一致
+// 2. 与原递归实现的结果一致
+// 3. 非2的幂的实数输入补零到下一个2的幂，纯音的峰值落在对应频点
+func TestFFTMatchesDFT(t *testing.T) {
+	for _, n := range []int{1, 2, 4, 8, 64, 512} {
+		x := randomSignal(n)
+		want := naiveDFT(x)
+		reference := recursiveFFTReference(x)
+		got := append([]complex128(nil), x...)
+		FFT(got)
+		for k := range got {
+			if cmplx.Abs(got[k]-want[k]) > 1e-9*float64(n) {
+				t.Fatalf("n=%d bin %d = %v, DFT = %v", n, k, got[k], want[k])
+			}
+			if cmplx.Abs(got[k]-reference[k]) > 1e-9*float64(n) {
+				t.Fatalf("n=%d bin %d = %v, 递归实现 = %v", n, k, got[k], reference[k])
+			}
+		}
+	}
+
+	const rate = 44100
+	spectrum := RealFFT(sine(1000, 1102, rate))
+	if len(spectrum) != 2048 {
+		t.Fatalf("len(spectrum) = %d, want 2048", len(spectrum))
+	}
+	peak := 0
+	for k := 1; k < len(spectrum)/2; k++ {
+		if cmplx.Abs(spectrum[k]) > cmplx.Abs(spectrum[peak]) {
+			peak = k
+		}
+	}
+	if freq := float64(peak) * rate / 2048; math.Abs(freq-1000) > rate/2048.0 {
+		t.Errorf("峰值频率 = %.1fHz, want 1000Hz", freq)
+	}
+}
+
+// BenchmarkFFT FFT性能基准测试
+// 测试内容：
+// 1. 迭代实现与原递归实现在 1024、2048（25ms帧补零后）、4096 点上的耗时与内存分配
+func BenchmarkFFT(b *testing.B) {
+	implementations := []struct {
+		name string
+		fn   func([]complex128)
+	}{
+		{"iterative", FFT},
+		{"recursive", func(x []complex128) { recursiveFFTReference(x) }},
+	}
+	for _, n := range []int{1024, 2048, 4096} {
+		x := randomSignal(n)
+		buf := make([]complex128, n)
+		for _, impl := range implementations {
+			b.Run(fmt.Sprintf("%s/%d", impl.name, n), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					copy(buf, x)
+					impl.fn(buf)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package dsp
+
+import (
+	"math"
+	"sync"
+)
+
+// 抗混叠重采样
+//
+// 降低采样率前先用加汉明窗的 sinc FIR 低通滤掉新奈奎斯特频率以上的成分，否则这些成分会折叠进分析频带
+// （例如44.1kHz下的15kHz噪声降到16kHz后出现在1kHz附近）。截止频率为新奈奎斯特频率的 antiAliasCutoff 倍，
+// 阶数随降采样倍数增加；信号两端按端点值延拓。
+// 整段重采样（Resample）、整数倍抽取（Decimate）与按块送入的流式重采样都由 Resampler 完成，
+// 逐块输出与整段一次重采样一致。
+
+// antiAliasCutoff 低通截止频率相对新奈奎斯特频率的比例，留出过渡带
+const antiAliasCutoff = 0.9
+
+// firKey 低通滤波器系数的缓存键
+type firKey struct {
+	cutoff float64 // 截止频率（相对采样率，0~0.5）
+	taps   int
+}
+
+// firFilters 各截止频率与阶数的滤波器系数
+var firFilters sync.Map // firKey -> []float64
+
+// lowPassFIR 返回截止频率为 cutoff（相对采样率）、长度为 taps（奇数）的低通滤波器系数，系数之和为1
+func lowPassFIR(cutoff float64, taps int) []float64 {
+	key := firKey{cutoff, taps}
+	if h, ok := firFilters.Load(key); ok {
+		return h.([]float64)
+	}
+	h := make([]float64, taps)
+	center := taps / 2
+	sum := 0.0
+	for i := range h {
+		x := float64(i - center)
+		if x == 0 {
+			h[i] = 2 * cutoff
+		} else {
+			h[i] = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		h[i] *= 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(taps-1)) // 汉明窗
+		sum += h[i]
+	}
+	for i := range h {
+		h[i] /= sum
+	}
+	firFilters.Store(key, h)
+	return h
+}
+
+// antiAliasTaps 降采样倍数为 ratio 时的滤波器长度
+func antiAliasTaps(ratio float64) int {
+	return 2*int(math.Ceil(8*ratio)) + 1
+}
+
+// Resample 把整段样本从 from 重采样到 to，降采样时先做抗混叠低通滤波，输出 len(samples)*to/from 个样本
+func Resample(samples []float64, from, to int) []float64 {
+	if from == to || from <= 0 || to <= 0 || len(samples) == 0 {
+		return samples
+	}
+	r := NewResampler(from, to)
+	return append(r.Process(samples), r.Flush()...)
+}
+
+// Decimate 低通滤波后每 factor 个样本保留一个
+func Decimate(samples []float64, factor int) []float64 {
+	if factor <= 1 {
+		return samples
+	}
+	return Resample(samples, factor, 1)
+}
+
+// Resampler 按块重采样的流式重采样器，跨块保留低通滤波的输入历史与线性插值的位置。
+// 滤波需要后续 Delay() 个样本，Process 的输出比输入晚这么多，输入结束时调用 Flush 取出剩余样本。
+type Resampler struct {
+	from, to int
+	h        []float64 // 低通滤波器系数，升采样时为 nil
+
+	input    []float64 // 尚需参与滤波的输入样本，input[0] 为第 inputStart 个
+	filtered []float64 // 尚需参与插值的滤波后样本，filtered[0] 为第 filteredStart 个
+
+	inputStart    int64
+	filteredStart int64
+	received      int64   // 已送入的输入样本数
+	last          float64 // 最后一个输入样本，Flush 时用于延拓信号末尾
+	next          int64   // 下一个输出样本的序号，对应输入位置 next*from/to
+}
+
+// NewResampler 创建从 from 重采样到 to 的流式重采样器
+func NewResampler(from, to int) *Resampler {
+	r := &Resampler{from: from, to: to}
+	if to < from {
+		ratio := float64(from) / float64(to)
+		r.h = lowPassFIR(antiAliasCutoff*0.5/ratio, antiAliasTaps(ratio))
+	}
+	return r
+}
+
+// Delay 输出相对输入的延迟（输入样本数），即低通滤波器长度的一半
+func (r *Resampler) Delay() int {
+	return len(r.h) / 2
+}
+
+// OutputLen 估计送入 n 个输入样本后新增的输出样本数
+func (r *Resampler) OutputLen(n int) int {
+	return n * r.to / r.from
+}
+
+// Process 送入一块输入样本，返回已能确定的输出样本
+func (r *Resampler) Process(samples []float64) []float64 {
+	if len(samples) == 0 {
+		return nil
+	}
+	center := r.Delay()
+	if r.received == 0 {
+		// 信号开头按第一个样本延拓
+		for i := 0; i < center; i++ {
+			r.input = append(r.input, samples[0])
+		}
+		r.inputStart = -int64(center)
+	}
+	r.input = append(r.input, samples...)
+	r.received += int64(len(samples))
+	r.last = samples[len(samples)-1]
+
+	// 第 n 个样本的滤波需要 n+center 之前的输入
+	r.filter(r.received - int64(center))
+	return r.interpolate(-1)
+}
+
+// Flush 输入结束时按最后一个样本延拓信号末尾，返回剩余的输出样本，使总输出数为 输入样本数*to/from。
+// Flush 之后不能再送入样本
+func (r *Resampler) Flush() []float64 {
+	if r.received == 0 {
+		return nil
+	}
+	for i := 0; i < r.Delay(); i++ {
+		r.input = append(r.input, r.last)
+	}
+	r.filter(r.received)
+	return r.interpolate(r.received * int64(r.to) / int64(r.from))
+}
+
+// filter 对序号小于 limit 的输入样本做低通滤波
+func (r *Resampler) filter(limit int64) {
+	center := int64(r.Delay())
+	for n := r.filteredStart + int64(len(r.filtered)); n < limit; n++ {
+		if r.h == nil {
+			r.filtered = append(r.filtered, r.input[n-r.inputStart])
+			continue
+		}
+		window := r.input[n-center-r.inputStart:]
+		sum := 0.0
+		for j, c := range r.h {
+			sum += c * window[j]
+		}
+		r.filtered = append(r.filtered, sum)
+	}
+	end := r.filteredStart + int64(len(r.filtered))
+	r.input = trimHead(r.input, &r.inputStart, end-center)
+}
+
+// interpolate 线性插值输出样本：第 i 个输出位于输入位置 i*from/to。total 小于0时只输出其后一个滤波后样本
+// 已确定的输出；否则输出到第 total 个为止，末尾缺少的后一个样本取最后一个滤波后样本
+func (r *Resampler) interpolate(total int64) []float64 {
+	end := r.filteredStart + int64(len(r.filtered))
+	var out []float64
+loop:
+	for total < 0 || r.next < total {
+		pos := r.next * int64(r.from)
+		j, frac := pos/int64(r.to), float64(pos%int64(r.to))/float64(r.to)
+		k := j - r.filteredStart
+		switch {
+		case j+1 < end:
+			out = append(out, r.filtered[k]*(1-frac)+r.filtered[k+1]*frac)
+		case total >= 0:
+			out = append(out, r.filtered[len(r.filtered)-1])
+		default:
+			break loop
+		}
+		r.next++
+	}
+	r.filtered = trimHead(r.filtered, &r.filteredStart, r.next*int64(r.from)/int64(r.to))
+	return out
+}
+
+// trimHead 丢弃序号小于 keep 的样本，start 为 samples[0] 的序号
+func trimHead(samples []float64, start *int64, keep int64) []float64 {
+	drop := keep - *start
+	if drop <= 0 {
+		return samples
+	}
+	if drop > int64(len(samples)) {
+		drop = int64(len(samples))
+	}
+	*start += drop
+	return append(samples[:0], samples[drop:]...)
+}
@@ -0,0 +1,125 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// sine 生成 n 个采样率为 rate 的 freq 赫兹纯音样本
+func sine(freq float64, n, rate int) []float64 {
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = math.Sin(2 * math.Pi * freq * float64(i) / float64(rate))
+	}
+	return samples
+}
+
+// rms 样本的均方根
+func rms(samples []float64) float64 {
+	sum := 0.0
+	for _, v := range samples {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// TestDecimate 测试抗混叠抽取
+// 测试内容：
+// 1. 通带内的纯音幅度基本不变
+// 2. 新奈奎斯特频率以上的纯音被滤除，不会折叠进分析频带，抽取后长度为 len/factor
+// 3. 直接抽取（无滤波）时同一纯音会混叠，作为对照
+func TestDecimate(t *testing.T) {
+	const rate, factor = 44100, 10 // 降到4410Hz，新奈奎斯特频率2205Hz
+
+	passband := Decimate(sine(500, rate/2, rate), factor)
+	if got := rms(passband[50 : len(passband)-50]); math.Abs(got-rms(sine(500, rate/2, rate))) > 0.02 {
+		t.Errorf("500Hz 纯音抽取后的均方根 = %.3f, 应基本不变", got)
+	}
+
+	alias := sine(4000, rate/2, rate) // 直接抽取会折叠到410Hz
+	if got := rms(Decimate(alias, factor)); got > 0.01 {
+		t.Errorf("4000Hz 纯音抽取后的均方根 = %.3f, 应被滤除", got)
+	}
+	if got := len(Decimate(alias, factor)); got != len(alias)/factor {
+		t.Errorf("抽取后长度 = %d, want %d", got, len(alias)/factor)
+	}
+	naive := make([]float64, len(alias)/factor)
+	for i := range naive {
+		naive[i] = alias[i*factor]
+	}
+	if rms(naive) < 0.1 {
+		t.Errorf("直接抽取应产生混叠, 均方根 = %.3f", rms(naive))
+	}
+}
+
+// TestResampleAntiAlias 测试降采样重采样的抗混叠
+// 测试内容：
+// 1. 44.1kHz 降到 16kHz 时 12kHz 的纯音被滤除，1kHz 的纯音保留
+// 2. 升采样不滤波，长度与原来一致
+func TestResampleAntiAlias(t *testing.T) {
+	high := Resample(sine(12000, 44100/2, 44100), 44100, 16000)
+	if got := rms(high); got > 0.02 {
+		t.Errorf("12kHz 纯音降到16kHz后的均方根 = %.3f, 应被滤除", got)
+	}
+	low := Resample(sine(1000, 44100/2, 44100), 44100, 16000)
+	if got := rms(low[20 : len(low)-20]); got < 0.6 {
+		t.Errorf("1kHz 纯音降到16kHz后的均方根 = %.3f, 应保留", got)
+	}
+
+	samples := sine(1000, 16000/10, 16000)
+	if up := Resample(samples, 16000, 44100); len(up) != len(samples)*44100/16000 {
+		t.Errorf("升采样长度 = %d", len(up))
+	}
+}
+
+// TestStreamResampler 测试按块重采样
+// 测试内容：
+// 1. 降采样与升采样时按不规则大小的块送入，Flush 之后输出与整段一次重采样逐样本一致，长度为 输入*to/from
+// 2. Flush 之前只缺末尾 Delay 对应的输出
+// 3. 逐块调用 Resample 作为对照，块边界处与整段重采样不一致
+func TestStreamResampler(t *testing.T) {
+	for _, rates := range [][2]int{{44100, 16000}, {48000, 44100}, {16000, 44100}} {
+		from, to := rates[0], rates[1]
+		signal := sine(700, from/2, from)
+		for i := range signal {
+			signal[i] += 0.3 * math.Sin(float64(i)*0.37)
+		}
+		whole := Resample(signal, from, to)
+
+		resampler := NewResampler(from, to)
+		var streamed, chunked []float64
+		for pos, size := 0, 1; pos < len(signal); size = size*3%997 + 17 {
+			end := pos + size
+			if end > len(signal) {
+				end = len(signal)
+			}
+			streamed = append(streamed, resampler.Process(signal[pos:end])...)
+			chunked = append(chunked, Resample(signal[pos:end], from, to)...)
+			pos = end
+		}
+
+		if missing := len(whole) - len(streamed); missing < 0 || missing > (resampler.Delay()+1)*to/from+1 {
+			t.Errorf("%d->%d: streamed %d samples before Flush, whole %d", from, to, len(streamed), len(whole))
+		}
+		streamed = append(streamed, resampler.Flush()...)
+		if len(whole) != len(signal)*to/from || len(streamed) != len(whole) {
+			t.Errorf("%d->%d: streamed %d samples, whole %d, want %d", from, to, len(streamed), len(whole), len(signal)*to/from)
+			continue
+		}
+		worst := 0.0
+		for i := range streamed {
+			worst = math.Max(worst, math.Abs(streamed[i]-whole[i]))
+		}
+		if worst > 1e-9 {
+			t.Errorf("%d->%d: streamed output differs from whole-signal resampling by %g", from, to, worst)
+		}
+
+		naive := 0.0
+		for i := 0; i < len(streamed) && i < len(chunked); i++ {
+			naive = math.Max(naive, math.Abs(chunked[i]-whole[i]))
+		}
+		if naive < 1e-3 {
+			t.Errorf("%d->%d: per-chunk resampling should differ at chunk boundaries (max diff %g)", from, to, naive)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build arm64
+
+package main
+
+// convertPCM16 将16位小端PCM转换为 [-1, 1) 的浮点样本，len(src) 至少为 2*len(dst)
+// arm64 上按4个样本一组展开，减少边界检查与循环开销
+func convertPCM16(dst []float64, src []byte) {
+	convertPCM16Unrolled(dst, src)
+}
+
+// multiplyWindow dst[i] = src[i] * window[i]，三个切片等长
+func multiplyWindow(dst, src, window []float64) {
+	multiplyWindowUnrolled(dst, src, window)
+}
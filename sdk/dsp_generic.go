@@ -0,0 +1,13 @@
+//go:build !arm64
+
+package main
+
+// convertPCM16 将16位小端PCM转换为 [-1, 1) 的浮点样本，len(src) 至少为 2*len(dst)
+func convertPCM16(dst []float64, src []byte) {
+	convertPCM16Scalar(dst, src)
+}
+
+// multiplyWindow dst[i] = src[i] * window[i]，三个切片等长
+func multiplyWindow(dst, src, window []float64) {
+	multiplyWindowScalar(dst, src, window)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+// 移动端DSP路径：窗函数按长度预先计算成表并缓存；PCM 转换与加窗在 arm64 上使用按4个样本一组展开的循环
+// （dsp_arm64.go），其余架构使用逐样本循环（dsp_generic.go），两种实现的结果逐位一致。
+
+// windowKey 窗函数表的缓存键
+type windowKey struct {
+	a0, a1 float64 // 窗函数 a0 - a1 * cos(2πi/(n-1)) 的系数
+	n      int
+}
+
+// windowTables 各窗函数、各长度的系数表，表在首次使用时计算，之后只读
+var windowTables sync.Map // windowKey -> []float64
+
+// cosineWindow 返回长度 n 的余弦窗系数：a0 - a1 * cos(2πi/(n-1))
+func cosineWindow(a0, a1 float64, n int) []float64 {
+	key := windowKey{a0, a1, n}
+	if t, ok := windowTables.Load(key); ok {
+		return t.([]float64)
+	}
+	t := make([]float64, n)
+	for i := range t {
+		t[i] = a0 - a1*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+	}
+	windowTables.Store(key, t)
+	return t
+}
+
+// hammingWindow 返回长度 n 的汉明窗系数：0.54 - 0.46 * cos(2πi/(n-1))
+func hammingWindow(n int) []float64 {
+	return cosineWindow(0.54, 0.46, n)
+}
+
+// hannWindow 返回长度 n 的汉宁窗系数：0.5 * (1 - cos(2πi/(n-1)))
+func hannWindow(n int) []float64 {
+	return cosineWindow(0.5, 0.5, n)
+}
+
+// pcm16Scale 16位PCM到 [-1, 1) 的缩放系数
+const pcm16Scale = 1.0 / 32768
+
+// convertPCM16Scalar 逐样本转换16位小端PCM，len(src) 至少为 2*len(dst)
+func convertPCM16Scalar(dst []float64, src []byte) {
+	for i := range dst {
+		dst[i] = float64(int16(binary.LittleEndian.Uint16(src[2*i:]))) * pcm16Scale
+	}
+}
+
+// multiplyWindowScalar 逐样本加窗，三个切片等长
+func multiplyWindowScalar(dst, src, window []float64) {
+	for i := range dst {
+		dst[i] = src[i] * window[i]
+	}
+}
+
+// convertPCM16Unrolled 每次读取8字节（4个样本）转换16位小端PCM，不足一组的尾部逐样本处理
+func convertPCM16Unrolled(dst []float64, src []byte) {
+	n := len(dst) &^ 3
+	src = src[:2*len(dst)]
+	for i := 0; i < n; i += 4 {
+		v := binary.LittleEndian.Uint64(src[2*i:])
+		d := dst[i : i+4 : i+4]
+		d[0] = float64(int16(v)) * pcm16Scale
+		d[1] = float64(int16(v>>16)) * pcm16Scale
+		d[2] = float64(int16(v>>32)) * pcm16Scale
+		d[3] = float64(int16(v>>48)) * pcm16Scale
+	}
+	convertPCM16Scalar(dst[n:], src[2*n:])
+}
+
+// multiplyWindowUnrolled 按4个样本一组加窗，组内切片定长以消除边界检查，不足一组的尾部逐样本处理
+func multiplyWindowUnrolled(dst, src, window []float64) {
+	n := len(dst) &^ 3
+	for i := 0; i < n; i += 4 {
+		d, s, w := dst[i:i+4:i+4], src[i:i+4:i+4], window[i:i+4:i+4]
+		d[0] = s[0] * w[0]
+		d[1] = s[1] * w[1]
+		d[2] = s[2] * w[2]
+		d[3] = s[3] * w[3]
+	}
+	multiplyWindowScalar(dst[n:], src[n:len(dst)], window[n:len(dst)])
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+
+	"soundsdk/dsp"
+)
+
+// TestConvertPCM16 测试PCM转换
+// 测试内容：
+// 1. 各种长度（包括不足一组的尾部样本）的结果与逐样本除以32768逐位一致
+// 2. 边界值 -32768 与 32767 的转换结果
+func TestConvertPCM16(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		src := make([]byte, 2*n)
+		rng.Read(src)
+		dst := make([]float64, n)
+		convertPCM16(dst, src)
+		for i := range dst {
+			want := float64(int16(binary.LittleEndian.Uint16(src[2*i:]))) / 32768.0
+			if dst[i] != want {
+				t.Fatalf("n=%d sample %d = %v, want %v", n, i, dst[i], want)
+			}
+		}
+	}
+
+	samples, err := decodePCM16([]byte{0x00, 0x80, 0xff, 0x7f})
+	if err != nil {
+		t.Fatalf("decodePCM16() error = %v", err)
+	}
+	if samples[0] != -1 || samples[1] != 32767.0/32768 {
+		t.Errorf("边界值转换结果 = %v", samples)
+	}
+}
+
+// TestHammingWindowTable 测试汉明窗系数表
+// 测试内容：
+// 1. 系数与逐样本计算的余弦公式一致，两端为0.08
+// 2. 加窗结果与逐样本相乘逐位一致
+func TestHammingWindowTable(t *testing.T) {
+	for _, n := range []int{2, 7, 1102, 4096} {
+		window := hammingWindow(n)
+		for i, w := range window {
+			if want := 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(n-1)); w != want {
+				t.Fatalf("n=%d 系数 %d = %v, want %v", n, i, w, want)
+			}
+		}
+		if math.Abs(window[0]-0.08) > 1e-12 || math.Abs(window[n-1]-0.08) > 1e-12 {
+			t.Errorf("n=%d 两端系数 = %v, %v, want 0.08", n, window[0], window[n-1])
+		}
+
+		data := generateTestAudio(440, float64(n)/44100, 44100)[:n]
+		windowed := applyHammingWindow(data)
+		for i := range windowed {
+			if windowed[i] != data[i]*window[i] {
+				t.Fatalf("n=%d 加窗结果 %d = %v, want %v", n, i, windowed[i], data[i]*window[i])
+			}
+		}
+	}
+}
+
+// TestHannWindowTable 测试汉宁窗系数表
+// 测试内容：
+// 1. 系数与 0.5 * (1 - cos(2πi/(n-1))) 一致，两端为0、中点为1
+// 2. 同一长度返回同一张表，不同窗函数的表互不影响
+func TestHannWindowTable(t *testing.T) {
+	const n = 1025
+	window := hannWindow(n)
+	for i, w := range window {
+		if want := 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(n-1))); math.Abs(w-want) > 1e-15 {
+			t.Fatalf("系数 %d = %v, want %v", i, w, want)
+		}
+	}
+	if window[0] != 0 || window[n-1] > 1e-15 || math.Abs(window[n/2]-1) > 1e-15 {
+		t.Errorf("两端与中点系数 = %v, %v, %v", window[0], window[n-1], window[n/2])
+	}
+	if &hannWindow(n)[0] != &window[0] {
+		t.Error("同一长度应复用缓存的表")
+	}
+	if hammingWindow(n)[0] == window[0] {
+		t.Error("汉明窗与汉宁窗不应共用表")
+	}
+}
+
+// TestDSPUnrolledMatchesScalar 测试 arm64 使用的展开实现与其余架构使用的逐样本实现
+// 测试内容：
+// 1. 各种长度（包括不足一组的尾部样本）的随机PCM，两种实现的转换结果逐位一致
+// 2. 随机样本与汉明窗，两种实现的加窗结果逐位一致
+func TestDSPUnrolledMatchesScalar(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for n := 0; n < 67; n++ {
+		src := make([]byte, 2*n)
+		rng.Read(src)
+		scalar, unrolled := make([]float64, n), make([]float64, n)
+		convertPCM16Scalar(scalar, src)
+		convertPCM16Unrolled(unrolled, src)
+		for i := range scalar {
+			if math.Float64bits(scalar[i]) != math.Float64bits(unrolled[i]) {
+				t.Fatalf("convertPCM16 n=%d sample %d: unrolled %v, scalar %v", n, i, unrolled[i], scalar[i])
+			}
+		}
+
+		data := make([]float64, n)
+		for i := range data {
+			data[i] = rng.Float64()*2 - 1
+		}
+		window := hammingWindow(n + 2)[:n]
+		multiplyWindowScalar(scalar, data, window)
+		multiplyWindowUnrolled(unrolled, data, window)
+		for i := range scalar {
+			if math.Float64bits(scalar[i]) != math.Float64bits(unrolled[i]) {
+				t.Fatalf("multiplyWindow n=%d sample %d: unrolled %v, scalar %v", n, i, unrolled[i], scalar[i])
+			}
+		}
+	}
+}
+
+// BenchmarkWindow 加窗性能基准测试
+// 测试内容：
+// 1. 4096样本窗口使用缓存系数表（当前架构的实现、展开实现、逐样本实现）与逐样本计算余弦的耗时对比
+func BenchmarkWindow(b *testing.B) {
+	data := generateTestAudio(440, 4096.0/44100, 44100)[:4096]
+	out := make([]float64, len(data))
+	b.Run("table", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			multiplyWindow(out, data, hammingWindow(len(data)))
+		}
+	})
+	b.Run("unrolled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			multiplyWindowUnrolled(out, data, hammingWindow(len(data)))
+		}
+	})
+	b.Run("scalar", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			multiplyWindowScalar(out, data, hammingWindow(len(data)))
+		}
+	})
+	b.Run("cos", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := range data {
+				out[j] = data[j] * (0.54 - 0.46*math.Cos(2*math.Pi*float64(j)/float64(len(data)-1)))
+			}
+		}
+	})
+}
+
+// BenchmarkDSPPath 移动端DSP路径性能基准测试
+// 测试内容：
+// 1. 一个4096样本块的PCM转换、加窗与FFT的耗时，可在目标设备上以 GOARCH=arm64 运行
+func BenchmarkDSPPath(b *testing.B) {
+	chunk := make([]byte, 2*4096)
+	rand.New(rand.NewSource(1)).Read(chunk)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		samples, _ := decodePCM16(chunk)
+		dsp.RealFFT(applyHammingWindow(samples))
+	}
+}
@@ -0,0 +1,13 @@
+//go:build embedlib
+
+package main
+
+import _ "embed"
+
+// 以 -tags embedlib 构建时将默认样本库编入二进制，容器中无需挂载样本库文件
+
+//go:embed sample_library.json
+var embeddedEngineLibrary []byte
+
+//go:embed new_sample_library.json
+var embeddedMockLibrary []byte
@@ -0,0 +1,9 @@
+//go:build !embedlib
+
+package main
+
+// 默认构建不包含内置样本库，样本库从 -library 指定的文件加载
+
+var embeddedEngineLibrary []byte
+
+var embeddedMockLibrary []byte
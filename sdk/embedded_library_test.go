@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestEmbeddedLibrary 测试内置样本库与 serve 子命令的默认值
+// 测试内容：
+// 1. 样本库路径为空时使用内置样本库，未以 -tags embedlib 构建时报错
+// 2. LoadFromBytes 与 LoadFromFile 加载结果一致
+// 3. serve 默认值：任务目录在临时目录，内置样本库可用时不再依赖工作目录中的样本库文件
+func TestEmbeddedLibrary(t *testing.T) {
+	engine, err := LoadEngine(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	if len(embeddedEngineLibrary) == 0 {
+		if err == nil {
+			t.Error("empty library path without embedded library should fail")
+		}
+	} else if err != nil || len(engine.Library.Samples) == 0 {
+		t.Errorf("embedded library: %v", err)
+	}
+
+	data, err := os.ReadFile("sample_library.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBytes, fromFile := NewSampleLibrary(), NewSampleLibrary()
+	if err := fromBytes.LoadFromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromFile.LoadFromFile("sample_library.json"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fromBytes.Samples) == 0 || len(fromBytes.Samples) != len(fromFile.Samples) {
+		t.Errorf("LoadFromBytes emotions = %d, LoadFromFile = %d", len(fromBytes.Samples), len(fromFile.Samples))
+	}
+
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	engineOpts := addEngineFlags(fs)
+	jobsDir := fs.String("jobs-dir", "jobs", "")
+	fs.Duration("debounce", time.Second, "")
+	applyServeDefaults(fs)
+	if err := fs.Parse([]string{"-sample-rate", "16000"}); err != nil {
+		t.Fatal(err)
+	}
+	if *jobsDir != filepath.Join(os.TempDir(), "meowtalk-jobs") {
+		t.Errorf("serve jobs-dir = %q, want temp dir", *jobsDir)
+	}
+	wantLibrary := "sample_library.json"
+	if len(embeddedEngineLibrary) > 0 {
+		wantLibrary = ""
+	}
+	if *engineOpts.library != wantLibrary || *engineOpts.sampleRate != 16000 {
+		t.Errorf("serve library = %q, sample rate = %d", *engineOpts.library, *engineOpts.sampleRate)
+	}
+}
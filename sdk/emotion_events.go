@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// 情感变化事件
+//
+// 逐窗口的识别结果在两种情感之间来回跳动时，直接推送会让通知接收方被刷屏。
+// EmotionTracker 先对识别结果做指数平滑，只有平滑后的情感发生变化并持续超过去抖时长才发出事件。
+
+// 事件默认参数
+const (
+	DefaultEventDebounce   = 2 * time.Second // 默认去抖时长
+	emotionSmoothing       = 0.5             // 指数平滑系数，越大越偏向最新结果
+	EventTypeEmotionChange = "emotion_change"
+)
+
+// EmotionEvent 情感变化事件
+type EmotionEvent struct {
+	Type       string  `json:"type"`       // 事件类型，固定为 emotion_change
+	StreamID   string  `json:"streamId"`   // 流ID
+	Previous   string  `json:"previous"`   // 变化前的情感，首次确定时为空
+	Emotion    string  `json:"emotion"`    // 变化后的情感
+	Confidence float64 `json:"confidence"` // 平滑后的置信度
+	Since      int64   `json:"since"`      // 新情感开始出现的时间（毫秒时间戳）
+	Timestamp  int64   `json:"timestamp"`  // 事件发出的时间（毫秒时间戳）
+}
+
+// EmotionTracker 单个流的情感平滑与去抖
+type EmotionTracker struct {
+	mu        sync.Mutex
+	debounce  time.Duration
+	scores    map[string]float64 // 各情感的平滑分数
+	current   string             // 当前已确认的情感
+	candidate string             // 等待确认的情感
+	since     time.Time          // 候选情感开始出现的时间
+}
+
+// NewEmotionTracker 创建情感跟踪器，debounce 为0时使用默认去抖时长
+func NewEmotionTracker(debounce time.Duration) *EmotionTracker {
+	if debounce <= 0 {
+		debounce = DefaultEventDebounce
+	}
+	return &EmotionTracker{
+		debounce: debounce,
+		scores:   make(map[string]float64),
+	}
+}
+
+// Observe 输入一次识别结果，平滑后的情感变化并持续超过去抖时长时返回事件
+// unknown 或空情感只参与衰减，不会成为候选情感
+func (t *EmotionTracker) Observe(emotion string, confidence float64, now time.Time) (EmotionEvent, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for e := range t.scores {
+		t.scores[e] *= 1 - emotionSmoothing
+	}
+	if emotion != "" && emotion != "unknown" {
+		t.scores[emotion] += emotionSmoothing * confidence
+	}
+
+	smoothed, score := "", 0.0
+	for e, s := range t.scores {
+		if s > score || (s == score && e < smoothed) {
+			smoothed, score = e, s
+		}
+	}
+
+	if smoothed == "" || smoothed == t.current {
+		t.candidate = ""
+		return EmotionEvent{}, false
+	}
+
+	if smoothed != t.candidate {
+		t.candidate = smoothed
+		t.since = now
+	}
+	if now.Sub(t.since) < t.debounce {
+		return EmotionEvent{}, false
+	}
+
+	event := EmotionEvent{
+		Type:       EventTypeEmotionChange,
+		Previous:   t.current,
+		Emotion:    smoothed,
+		Confidence: score,
+		Since:      t.since.UnixMilli(),
+		Timestamp:  now.UnixMilli(),
+	}
+	t.current = smoothed
+	t.candidate = ""
+	return event, true
+}
+
+// EmotionEventHub 按流分发情感变化事件
+type EmotionEventHub struct {
+	mu          sync.Mutex
+	subscribers map[string][]chan []byte
+}
+
+// NewEmotionEventHub 创建事件分发器
+func NewEmotionEventHub() *EmotionEventHub {
+	return &EmotionEventHub{subscribers: make(map[string][]chan []byte)}
+}
+
+// Subscribe 订阅指定流的事件，返回事件通道和取消订阅函数
+func (h *EmotionEventHub) Subscribe(streamID string) (<-chan []byte, func()) {
+	ch := make(chan []byte, 16)
+
+	h.mu.Lock()
+	h.subscribers[streamID] = append(h.subscribers[streamID], ch)
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		subs := h.subscribers[streamID]
+		for i, sub := range subs {
+			if sub == ch {
+				h.subscribers[streamID] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+		if len(h.subscribers[streamID]) == 0 {
+			delete(h.subscribers, streamID)
+		}
+	}
+	return ch, cancel
+}
+
+// Publish 向指定流的所有订阅者发送事件，订阅者通道已满时丢弃
+func (h *EmotionEventHub) Publish(event EmotionEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("序列化情感事件失败: %v", err)
+		return
+	}
+	h.PublishData(event.StreamID, data)
+}
+
+// PublishData 向指定流的所有订阅者发送已序列化的事件（如告警），事件JSON需包含 type 字段
+func (h *EmotionEventHub) PublishData(streamID string, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.subscribers[streamID] {
+		select {
+		case ch <- data:
+		default:
+			log.Printf("情感事件通道已满，丢弃事件: StreamID=%s", streamID)
+		}
+	}
+}
+
+// eventType 事件JSON中的 type 字段，缺失时为 emotion_change
+func eventType(data []byte) string {
+	var event struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(data, &event) != nil || event.Type == "" {
+		return EventTypeEmotionChange
+	}
+	return event.Type
+}
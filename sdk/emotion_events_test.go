@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestEmotionTrackerDebounce 测试情感变化去抖
+// 测试内容：
+// 1. 持续超过去抖时长后才发出首个事件
+// 2. 两种情感来回跳动不会产生事件
+// 3. 新情感持续后发出变化事件并携带变化前的情感
+func TestEmotionTrackerDebounce(t *testing.T) {
+	tracker := NewEmotionTracker(2 * time.Second)
+	start := time.Unix(1700000000, 0)
+	var events []EmotionEvent
+
+	observe := func(offset time.Duration, emotion string) {
+		if event, ok := tracker.Observe(emotion, 0.8, start.Add(offset)); ok {
+			events = append(events, event)
+		}
+	}
+
+	// 稳定的 hello，2秒后确认
+	for i := 0; i <= 4; i++ {
+		observe(time.Duration(i)*500*time.Millisecond, "hello")
+	}
+	if len(events) != 1 || events[0].Emotion != "hello" || events[0].Previous != "" {
+		t.Fatalf("initial events = %+v, want one hello event", events)
+	}
+
+	// 单个窗口跳到 for_food 又回到 hello，不应产生事件
+	observe(2500*time.Millisecond, "for_food")
+	observe(3000*time.Millisecond, "hello")
+	observe(3500*time.Millisecond, "for_food")
+	observe(4000*time.Millisecond, "hello")
+	if len(events) != 1 {
+		t.Fatalf("flapping produced events: %+v", events[1:])
+	}
+
+	// for_food 持续出现，超过去抖时长后发出变化事件
+	for i := 0; i <= 8; i++ {
+		observe(4500*time.Millisecond+time.Duration(i)*500*time.Millisecond, "for_food")
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %+v, want change to for_food", events)
+	}
+	if events[1].Previous != "hello" || events[1].Emotion != "for_food" {
+		t.Errorf("change event = %+v, want hello -> for_food", events[1])
+	}
+	if events[1].Timestamp-events[1].Since < 2000 {
+		t.Errorf("change event emitted before debounce: since=%d timestamp=%d", events[1].Since, events[1].Timestamp)
+	}
+}
+
+// TestEmotionEventHub 测试事件按流分发
+func TestEmotionEventHub(t *testing.T) {
+	hub := NewEmotionEventHub()
+	events, unsubscribe := hub.Subscribe("cat1")
+	defer unsubscribe()
+
+	hub.Publish(EmotionEvent{Type: EventTypeEmotionChange, StreamID: "other", Emotion: "hello"})
+	hub.Publish(EmotionEvent{Type: EventTypeEmotionChange, StreamID: "cat1", Emotion: "for_food"})
+
+	select {
+	case data := <-events:
+		var event EmotionEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		if event.Emotion != "for_food" {
+			t.Errorf("event emotion = %s, want for_food", event.Emotion)
+		}
+	default:
+		t.Fatal("no event received")
+	}
+
+	select {
+	case data := <-events:
+		t.Errorf("unexpected event for other stream: %s", data)
+	default:
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// 情感集合
+//
+// 可识别的情感由样本库决定（样本库里有样本的情感才可能被识别出来），领域配置的 emotions
+// 可以进一步限定范围。文档页、模拟AI分析和 /emotions 接口都从这里取情感列表，
+// 更换样本库或领域配置后无需再同步修改各处的硬编码列表。
+//
+// 情感ID统一使用前端 emotions.ts 的写法（小写、下划线），旧样本库中的连字符目录名（for-food）
+// 和按英文描述命名的目录（feels very tasty）由领域配置的别名表映射到规范ID。
+
+// EmotionInfo 一种可识别的情感
+type EmotionInfo struct {
+	ID    string `json:"id"`    // 返回给前端的情感ID
+	Label string `json:"label"` // 指定语言下的显示名称
+}
+
+// resolveEmotionSet 由样本库中的情感与领域配置确定情感集合，返回规范化的情感ID
+// 领域配置指定了情感集合时按配置顺序取样本库中存在的情感；样本库为空时直接使用配置的集合；
+// 否则返回样本库中的全部情感（按ID排序）
+func resolveEmotionSet(available []string, profile *DomainProfile) []string {
+	present := make(map[string]bool, len(available))
+	var emotions []string
+	for _, emotion := range available {
+		emotion = profile.NormalizeEmotion(emotion)
+		if !present[emotion] {
+			present[emotion] = true
+			emotions = append(emotions, emotion)
+		}
+	}
+
+	if len(profile.Emotions) > 0 {
+		var restricted []string
+		for _, emotion := range profile.Emotions {
+			emotion = profile.NormalizeEmotion(emotion)
+			if len(available) == 0 || present[emotion] {
+				restricted = append(restricted, emotion)
+			}
+		}
+		return restricted
+	}
+
+	sort.Strings(emotions)
+	return emotions
+}
+
+// libraryEmotions 样本库中有样本的情感
+func libraryEmotions(library *SampleLibrary) []string {
+	if library == nil {
+		return nil
+	}
+	emotions := make([]string, 0, len(library.Samples))
+	for emotion, samples := range library.Samples {
+		if len(samples) > 0 {
+			emotions = append(emotions, emotion)
+		}
+	}
+	return emotions
+}
+
+// defaultCatEmotionAliases 内置猫咪配置的情感别名：早期样本库按英文描述命名的目录 -> 前端情感ID
+var defaultCatEmotionAliases = map[string]string{
+	"affectionate":     "flighty",
+	"contented":        "satisfy",
+	"feels_very_tasty": "yummy",
+	"find":             "find_mom",
+}
+
+// copyAliases 复制别名表，避免配置之间共享同一个map
+func copyAliases(aliases map[string]string) map[string]string {
+	out := make(map[string]string, len(aliases))
+	for alias, canonical := range aliases {
+		out[alias] = canonical
+	}
+	return out
+}
+
+// canonicalEmotionID 情感ID的规范写法：小写，空格与连字符统一为下划线（前端 emotions.ts 的写法）
+func canonicalEmotionID(emotion string) string {
+	emotion = strings.ToLower(strings.TrimSpace(emotion))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(emotion)
+}
+
+// aliasTarget 查找规范写法的情感ID对应的别名目标
+func (p *DomainProfile) aliasTarget(emotion string) (string, bool) {
+	for alias, canonical := range p.EmotionAliases {
+		if canonicalEmotionID(alias) == emotion {
+			return canonicalEmotionID(canonical), true
+		}
+	}
+	return "", false
+}
+
+// NormalizeEmotion 将情感ID规范化并解析别名
+func (p *DomainProfile) NormalizeEmotion(emotion string) string {
+	emotion = canonicalEmotionID(emotion)
+	if canonical, ok := p.aliasTarget(emotion); ok {
+		return canonical
+	}
+	return emotion
+}
+
+// NormalizeEmotionID 按当前领域配置规范化情感ID，样本库加载与每个识别结果都经过这一步，
+// 保证样本库、服务端与客户端使用同一套ID
+func NormalizeEmotionID(emotion string) string {
+	return CurrentDomainProfile().NormalizeEmotion(emotion)
+}
+
+// normalizeScores 将评分的情感ID规范化，多个别名归并到同一情感时取最高分
+func normalizeScores(scores map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(scores))
+	for emotion, score := range scores {
+		id := NormalizeEmotionID(emotion)
+		if prev, ok := out[id]; !ok || score > prev {
+			out[id] = score
+		}
+	}
+	return out
+}
+
+// DescribeEmotions 返回情感集合在指定语言下的ID与显示名称
+func DescribeEmotions(emotions []string, lang string) []EmotionInfo {
+	infos := make([]EmotionInfo, 0, len(emotions))
+	for _, emotion := range emotions {
+		infos = append(infos, EmotionInfo{ID: emotion, Label: EmotionLabel(lang, emotion)})
+	}
+	return infos
+}
+
+// emotionListHTML 文档页中的情感列表项
+func emotionListHTML(emotions []string, lang string) string {
+	var b strings.Builder
+	for _, info := range DescribeEmotions(emotions, lang) {
+		fmt.Fprintf(&b, "\t\t\t\t<li>%s - %s</li>\n", html.EscapeString(info.ID), html.EscapeString(info.Label))
+	}
+	return b.String()
+}
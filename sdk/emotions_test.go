@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestEmotionSet 测试情感集合的确定与 /emotions 接口
+// 测试内容：
+// 1. 集合来自样本库中有样本的情感，使用规范ID并按ID排序
+// 2. 领域配置限定集合时按配置顺序取样本库中存在的情感，样本库为空时使用配置的集合
+// 3. /emotions 返回前端情感ID与指定语言的显示名称
+func TestEmotionSet(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	library := NewSampleLibrary()
+	for _, emotion := range []string{"happy", "for-food", "angry"} {
+		library.Samples[emotion] = []AudioSample{{Emotion: emotion, Features: AudioFeature{Energy: 0.5}}}
+	}
+	library.Samples["sad"] = nil
+	if err := engine.SetLibrary(library); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := engine.Emotions(), []string{"angry", "for_food", "happy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Emotions() = %v, want %v", got, want)
+	}
+
+	profile := &DomainProfile{Emotions: []string{"happy", "sad", "angry"}}
+	if got, want := resolveEmotionSet(libraryEmotions(library), profile), []string{"happy", "angry"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("restricted set = %v, want %v", got, want)
+	}
+	if got := resolveEmotionSet(nil, profile); !reflect.DeepEqual(got, profile.Emotions) {
+		t.Errorf("set without library = %v, want %v", got, profile.Emotions)
+	}
+
+	server := NewAudioServer(engine)
+	rec := httptest.NewRecorder()
+	server.handleEmotions(rec, httptest.NewRequest(http.MethodGet, "/emotions?lang=zh-CN", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp struct {
+		Lang     string        `json:"lang"`
+		Emotions []EmotionInfo `json:"emotions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Lang != "zh" || len(resp.Emotions) != 3 {
+		t.Fatalf("response = %+v, want 3 zh emotions", resp)
+	}
+	if food := resp.Emotions[1]; food.ID != "for_food" || food.Label != EmotionLabel("zh", "for_food") {
+		t.Errorf("for-food = %+v, want frontend ID and zh label", food)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleEmotions(rec, httptest.NewRequest(http.MethodPost, "/emotions", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
+
+// TestNormalizeEmotionID 测试情感ID规范化
+// 测试内容：
+// 1. 大小写、空格与连字符统一为下划线，内置别名映射到前端情感ID
+// 2. 样本库加载时别名与规范ID的样本合并，统计信息重新计算
+// 3. 引擎评分与识别结果使用规范ID
+// 4. 别名目标本身是别名的配置校验失败
+func TestNormalizeEmotionID(t *testing.T) {
+	tests := map[string]string{
+		"for-food":         "for_food",
+		"Ask-For-Play":     "ask_for_play",
+		"feels very tasty": "yummy",
+		"affectionate":     "flighty",
+		"yummy":            "yummy",
+		"unknown":          "unknown",
+		"":                 "",
+	}
+	for input, want := range tests {
+		if got := NormalizeEmotionID(input); got != want {
+			t.Errorf("NormalizeEmotionID(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	library := NewSampleLibrary()
+	library.Samples["feels very tasty"] = []AudioSample{{Emotion: "feels very tasty", Features: AudioFeature{Energy: 0.2, Pitch: 300}}}
+	library.Samples["yummy"] = []AudioSample{{Emotion: "yummy", Features: AudioFeature{Energy: 0.4, Pitch: 500}}}
+	library.Samples["for-food"] = []AudioSample{{Emotion: "for-food", Features: AudioFeature{Energy: 0.9, Pitch: 900}}}
+	path := filepath.Join(t.TempDir(), "library.json")
+	if err := library.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewSampleLibrary()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resolveEmotionSet(libraryEmotions(loaded), &DomainProfile{}), []string{"for_food", "yummy"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("loaded emotions = %v, want %v", got, want)
+	}
+	if stats := loaded.Statistics["yummy"]; stats.SampleCount != 2 || stats.MeanFeature.Pitch != 400 {
+		t.Errorf("yummy statistics = %+v, want 2 merged samples", stats)
+	}
+	for _, sample := range loaded.Samples["yummy"] {
+		if sample.Emotion != "yummy" {
+			t.Errorf("sample emotion = %q, want yummy", sample.Emotion)
+		}
+	}
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	if err := engine.SetLibrary(library); err != nil {
+		t.Fatal(err)
+	}
+	session := engine.NewSession("normalize")
+	scores := engine.score(session, map[string]float64{"Energy": 0.9, "Pitch": 900})
+	for emotion := range scores {
+		if emotion != NormalizeEmotionID(emotion) {
+			t.Errorf("score key %q is not canonical", emotion)
+		}
+	}
+	if _, ok := scores["for_food"]; !ok {
+		t.Errorf("scores = %v, want for_food", scores)
+	}
+
+	profile := DefaultDomainProfile()
+	profile.EmotionAliases["flighty"] = "cuddly"
+	if err := profile.Validate(); err == nil {
+		t.Error("alias chain should fail validation")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import "math"
+
+// 叫声端点检测
+//
+// 按静默切分的片段以20ms帧为单位，边界最多带一帧静音；整段样本文件与手工标注的区间更是录了多少
+// 静音就带多少。Duration 直接等于片段长度，Energy 是各帧能量的平均，两者都随片段周围的静音多少
+// 而变化，同一声叫声录得松一点紧一点，特征就不同。提取特征前先用短时能量的双门限把片段收缩到
+// 叫声实际的起止点：
+//   1. 以5ms帧计算短时能量，高门限取片段内最大帧能量的 endpointHighRatio，低门限取 endpointLowRatio；
+//   2. 第一帧与最后一帧超过高门限处为叫声的起止，再分别向外扩展到能量降到低门限以下为止，
+//      保留起音与衰减的部分，门限之间的起伏不会把一声叫声截断。
+// 片段内没有帧超过高门限（整段都很安静）时不收缩。
+
+// 端点检测参数
+const (
+	endpointFrameDuration = 0.005 // 短时能量的帧长（秒）
+	endpointHighRatio     = 0.05  // 高门限：最大帧能量的5%（约-13dB），超过时确认为叫声
+	endpointLowRatio      = 0.005 // 低门限：最大帧能量的0.5%（约-23dB），低于时视为叫声已结束
+	endpointMinEnergy     = 1e-6  // 高门限的下限（RMS 0.001，约-60dBFS），避免把底噪当作叫声
+)
+
+// trimSilence 返回样本中叫声的起止区间 [start, end)，没有可确认的叫声时返回整段
+func trimSilence(samples []float64, sampleRate int) (int, int) {
+	frameSize := max(1, int(endpointFrameDuration*float64(sampleRate)))
+	frameCount := (len(samples) + frameSize - 1) / frameSize
+	if frameCount == 0 {
+		return 0, len(samples)
+	}
+
+	energies := make([]float64, frameCount)
+	peak := 0.0
+	for i := range energies {
+		end := (i + 1) * frameSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+		frame := samples[i*frameSize : end]
+		energies[i] = calculateEnergy(frame) / float64(len(frame))
+		peak = math.Max(peak, energies[i])
+	}
+	high := math.Max(endpointMinEnergy, endpointHighRatio*peak)
+	low := endpointLowRatio * peak
+
+	first, last := -1, -1
+	for i, energy := range energies {
+		if energy >= high {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return 0, len(samples)
+	}
+	for first > 0 && energies[first-1] >= low {
+		first--
+	}
+	for last < frameCount-1 && energies[last+1] >= low {
+		last++
+	}
+
+	end := (last + 1) * frameSize
+	if end > len(samples) {
+		end = len(samples)
+	}
+	return first * frameSize, end
+}
+
+// trimSegment 将片段收缩到其中叫声的起止点
+func trimSegment(samples []float64, segment AudioSegment, sampleRate int) AudioSegment {
+	start, end := trimSilence(samples[segment.Start:segment.End], sampleRate)
+	return AudioSegment{Start: segment.Start + start, End: segment.Start + end}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+// TestTrimSilence 测试短时能量双门限的端点检测
+// 测试内容：
+// 1. 叫声前后的静音被去掉，边界误差不超过一帧
+// 2. 低于高门限但高于低门限的衰减尾音保留在叫声内
+// 3. 整段安静或为空时不收缩
+// 4. 标注区间与按静默切分的片段都收缩到叫声的起止点
+func TestTrimSilence(t *testing.T) {
+	const rate = 16000
+	const frame = rate / 200
+	scaled := func(samples []float64, gain float64) []float64 {
+		for i := range samples {
+			samples[i] *= gain
+		}
+		return samples
+	}
+	var samples []float64
+	samples = append(samples, make([]float64, 4800)...)                          // 0.3秒静音
+	samples = append(samples, scaled(generateTestAudio(600, 0.2, rate), 0.5)...) // 0.2秒叫声
+	samples = append(samples, scaled(generateTestAudio(600, 0.1, rate), 0.1)...) // 0.1秒尾音，能量为叫声的4%
+	samples = append(samples, make([]float64, 4800)...)                          // 0.3秒静音
+
+	start, end := trimSilence(samples, rate)
+	if abs(start-4800) > frame || abs(end-9600) > frame {
+		t.Errorf("trimSilence = [%d, %d), want about [4800, 9600)", start, end)
+	}
+
+	quiet := scaled(generateTestAudio(600, 0.2, rate), 0.0005)
+	if start, end := trimSilence(quiet, rate); start != 0 || end != len(quiet) {
+		t.Errorf("quiet audio trimmed to [%d, %d)", start, end)
+	}
+	if start, end := trimSilence(nil, rate); start != 0 || end != 0 {
+		t.Errorf("empty audio trimmed to [%d, %d)", start, end)
+	}
+
+	segments := labelSegments(RecordingLabel{Start: 0.1, End: 0.7}, samples, rate)
+	if len(segments) != 1 || abs(segments[0].Start-4800) > frame || abs(segments[0].End-9600) > frame {
+		t.Errorf("labelSegments = %+v, want about [4800, 9600)", segments)
+	}
+	segments = vocalizationSegments(samples, rate, 0.3)
+	if len(segments) != 1 || abs(segments[0].Start-4800) > frame || abs(segments[0].End-9600) > frame {
+		t.Errorf("vocalizationSegments = %+v, want about [4800, 9600)", segments)
+	}
+}
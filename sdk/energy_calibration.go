@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 能量直方图与阈值校准
+//
+// 静默阈值默认固定为 0.02（20ms 窗口的RMS），不同的麦克风增益、环境噪声下只能反复试错调整：
+// 阈值低于环境噪声时永远检测不到静默，高于轻声叫声时叫声被切碎。以 -energy-calibration 10m 启动后，
+// 处理器在收到的前 10 分钟音频（所有流累计，按声明的格式换算时长）中按与静默检测相同的 20ms 窗口
+// 统计 RMS 的 dBFS 直方图，收集完成后给出建议阈值：
+//   - 噪声底取 RMS 的第 20 百分位（数字静音的窗口单独计数，不参与百分位）
+//   - 静默阈值为噪声底 +6dB，最小能量为噪声底 +12dB
+//   - 两者限制在 [0.001, 0.1] 内
+// 静默阈值用于触发条件的静默检测与叫声分段（引擎在设置了触发条件时使用，见 trigger_policy.go），
+// RMS 低于最小能量的窗口不参与匹配。
+// 同时设置 -energy-calibration-apply 时收集完成后直接替换当前阈值，否则通过 GET /api/admin/energy 查看
+// 报告，确认后 POST /api/admin/energy {"apply": true} 应用。阈值只在本副本内存中生效，重启后需写入
+// 启动参数；文件分析按峰值推算静默阈值（见 file_analysis.go），不使用这里的阈值。
+
+const (
+	energyHistogramMinDB  = -90.0 // 直方图的最低 dBFS，更低的窗口视为数字静音
+	energyHistogramBinDB  = 1.0   // 直方图每格的宽度（dB）
+	energyNoisePercentile = 20    // 噪声底取的百分位
+	silenceMarginDB       = 6.0   // 静默阈值高于噪声底的幅度
+	minEnergyMarginDB     = 12.0  // 最小能量高于噪声底的幅度
+	minRecommendedEnergy  = 0.001
+	maxRecommendedEnergy  = 0.1
+)
+
+// energyHistogramBins 直方图格数，覆盖 [energyHistogramMinDB, 0] dBFS
+var energyHistogramBins = int(-energyHistogramMinDB / energyHistogramBinDB)
+
+// EnergyThresholds 静默检测与匹配使用的能量阈值（RMS，满幅为1）
+type EnergyThresholds struct {
+	Silence   float64 `json:"silenceThreshold"` // 20ms 窗口RMS低于该值视为静默
+	MinEnergy float64 `json:"minEnergy"`        // RMS低于该值的窗口不参与匹配，0表示不限制
+}
+
+// Validate 静默阈值须在 (0, 1] 内，最小能量在 [0, 1] 内
+func (t EnergyThresholds) Validate() error {
+	if t.Silence <= 0 || t.Silence > 1 {
+		return fmt.Errorf("silenceThreshold must be in (0, 1], got %g", t.Silence)
+	}
+	if t.MinEnergy < 0 || t.MinEnergy > 1 {
+		return fmt.Errorf("minEnergy must be in [0, 1], got %g", t.MinEnergy)
+	}
+	return nil
+}
+
+// EnergyTuner 支持调整能量阈值与能量校准的处理器（Engine）
+type EnergyTuner interface {
+	EnergyThresholds() EnergyThresholds
+	SetEnergyThresholds(thresholds EnergyThresholds) error
+	SetEnergyCalibration(calibration *EnergyCalibration)
+}
+
+// EnergyBin 直方图的一格
+type EnergyBin struct {
+	DB     float64 `json:"db"`     // 格的下界（dBFS）
+	RMS    float64 `json:"rms"`    // 下界对应的RMS
+	Frames int     `json:"frames"` // 落在该格的窗口数
+}
+
+// EnergyReport 能量校准报告
+type EnergyReport struct {
+	Complete         bool               `json:"complete"`         // 是否已收集到目标时长
+	CollectedSeconds float64            `json:"collectedSeconds"` // 已收集的音频时长
+	TargetSeconds    float64            `json:"targetSeconds"`    // 目标时长
+	Frames           int                `json:"frames"`           // 统计的窗口数
+	SilentFrames     int                `json:"silentFrames"`     // 数字静音的窗口数
+	Percentiles      map[string]float64 `json:"percentiles,omitempty"`
+	NoiseFloor       float64            `json:"noiseFloor,omitempty"`  // 噪声底（RMS）
+	Recommended      *EnergyThresholds  `json:"recommended,omitempty"` // 没有有效窗口时为nil
+	Current          *EnergyThresholds  `json:"current,omitempty"`     // 处理器当前使用的阈值
+	Applied          bool               `json:"applied"`               // 建议阈值是否已应用
+	Histogram        []EnergyBin        `json:"histogram"`             // 非空的格
+}
+
+// EnergyCalibration 部署初期的能量直方图
+type EnergyCalibration struct {
+	target    time.Duration // 需要收集的音频时长
+	autoApply bool          // 收集完成后是否直接应用建议阈值
+
+	mu        sync.Mutex
+	bins      []int
+	silent    int
+	collected float64 // 已收集的音频时长（秒）
+	applied   bool
+}
+
+// NewEnergyCalibration 创建能量校准，收集 target 时长的音频后给出建议，autoApply 时直接应用
+func NewEnergyCalibration(target time.Duration, autoApply bool) (*EnergyCalibration, error) {
+	if target <= 0 {
+		return nil, fmt.Errorf("energy calibration duration must be positive, got %v", target)
+	}
+	return &EnergyCalibration{target: target, autoApply: autoApply, bins: make([]int, energyHistogramBins)}, nil
+}
+
+// AutoApply 收集完成后是否直接应用建议阈值
+func (c *EnergyCalibration) AutoApply() bool {
+	return c.autoApply
+}
+
+// Observe 按 20ms 窗口统计音频块的RMS，本次调用使收集完成时返回 true；完成后不再统计
+func (c *EnergyCalibration) Observe(format StreamFormat, data []float64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.completeLocked() || len(data) == 0 {
+		return false
+	}
+
+	window := format.Samples(0.02)
+	if window < 10 { // 与静默检测相同，窗口至少10个样本
+		window = 10
+	}
+	for start := 0; start+window <= len(data); start += window {
+		energy := 0.0
+		for _, v := range data[start : start+window] {
+			energy += v * v
+		}
+		c.observeRMS(math.Sqrt(energy / float64(window)))
+	}
+	c.collected += format.Seconds(len(data))
+	return c.completeLocked()
+}
+
+// observeRMS 将一个窗口的RMS计入直方图（调用方需持有c.mu）
+func (c *EnergyCalibration) observeRMS(rms float64) {
+	if rms <= 0 {
+		c.silent++
+		return
+	}
+	db := 20 * math.Log10(rms)
+	if db < energyHistogramMinDB {
+		c.silent++
+		return
+	}
+	bin := int((db - energyHistogramMinDB) / energyHistogramBinDB)
+	if bin >= len(c.bins) {
+		bin = len(c.bins) - 1
+	}
+	c.bins[bin]++
+}
+
+// completeLocked 是否已收集到目标时长（调用方需持有c.mu）
+func (c *EnergyCalibration) completeLocked() bool {
+	return c.collected >= c.target.Seconds()
+}
+
+// MarkApplied 记录建议阈值已应用
+func (c *EnergyCalibration) MarkApplied() {
+	c.mu.Lock()
+	c.applied = true
+	c.mu.Unlock()
+}
+
+// Report 当前的直方图与建议阈值，收集完成前也可以查看
+func (c *EnergyCalibration) Report() EnergyReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := EnergyReport{
+		Complete:         c.completeLocked(),
+		CollectedSeconds: c.collected,
+		TargetSeconds:    c.target.Seconds(),
+		SilentFrames:     c.silent,
+		Applied:          c.applied,
+		Histogram:        []EnergyBin{},
+	}
+	for i, n := range c.bins {
+		report.Frames += n
+		if n > 0 {
+			db := energyHistogramMinDB + float64(i)*energyHistogramBinDB
+			report.Histogram = append(report.Histogram, EnergyBin{DB: db, RMS: dbToRMS(db), Frames: n})
+		}
+	}
+	report.Frames += c.silent
+	if report.Frames == c.silent {
+		return report
+	}
+
+	report.Percentiles = make(map[string]float64)
+	for _, p := range []int{10, 20, 50, 90, 99} {
+		report.Percentiles[fmt.Sprintf("p%d", p)] = c.percentileLocked(p)
+	}
+	report.NoiseFloor = c.percentileLocked(energyNoisePercentile)
+	report.Recommended = &EnergyThresholds{
+		Silence:   clampEnergy(report.NoiseFloor * dbToRMS(silenceMarginDB)),
+		MinEnergy: clampEnergy(report.NoiseFloor * dbToRMS(minEnergyMarginDB)),
+	}
+	return report
+}
+
+// percentileLocked 非静音窗口RMS的第 p 百分位，取所在格的上界（调用方需持有c.mu）
+func (c *EnergyCalibration) percentileLocked(p int) float64 {
+	total := 0
+	for _, n := range c.bins {
+		total += n
+	}
+	rank := (total*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	seen := 0
+	for i, n := range c.bins {
+		seen += n
+		if seen >= rank {
+			return dbToRMS(energyHistogramMinDB + float64(i+1)*energyHistogramBinDB)
+		}
+	}
+	return 1
+}
+
+// dbToRMS dBFS 对应的RMS
+func dbToRMS(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// clampEnergy 将建议阈值限制在 [minRecommendedEnergy, maxRecommendedEnergy] 内
+func clampEnergy(v float64) float64 {
+	return math.Max(minRecommendedEnergy, math.Min(maxRecommendedEnergy, v))
+}
+
+// EnergyThresholds 返回当前的静默阈值与最小能量
+func (e *Engine) EnergyThresholds() EnergyThresholds {
+	_, thresholds := e.triggerPolicy()
+	return thresholds
+}
+
+// SetEnergyThresholds 设置静默阈值与最小能量，之后分析的窗口使用新阈值
+func (e *Engine) SetEnergyThresholds(thresholds EnergyThresholds) error {
+	if err := thresholds.Validate(); err != nil {
+		return err
+	}
+
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+	e.thresholds = thresholds
+	return nil
+}
+
+// SetEnergyCalibration 设置能量校准，之后收到的时域音频计入直方图，传入nil关闭
+func (e *Engine) SetEnergyCalibration(calibration *EnergyCalibration) {
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+	e.calibration = calibration
+}
+
+// observeEnergy 将音频块计入能量直方图，收集完成且需要自动应用时替换阈值
+func (e *Engine) observeEnergy(data []float64) {
+	e.settingsMu.RLock()
+	calibration := e.calibration
+	e.settingsMu.RUnlock()
+	if calibration == nil || !calibration.Observe(StreamFormat{SampleRate: e.Config.SampleRate}, data) {
+		return
+	}
+
+	report := calibration.Report()
+	if report.Recommended == nil {
+		log.Printf("能量直方图收集完成，但没有非静音的窗口，保留当前阈值")
+		return
+	}
+	current := e.EnergyThresholds()
+	log.Printf("能量直方图收集完成: 噪声底=%.4f, 建议静默阈值=%.4f, 建议最小能量=%.4f (当前 %.4f / %.4f)",
+		report.NoiseFloor, report.Recommended.Silence, report.Recommended.MinEnergy, current.Silence, current.MinEnergy)
+	if calibration.AutoApply() {
+		if err := e.SetEnergyThresholds(*report.Recommended); err != nil {
+			log.Printf("应用建议的能量阈值失败: %v", err)
+			return
+		}
+		calibration.MarkApplied()
+		log.Printf("已应用建议的能量阈值")
+	}
+}
+
+// SetEnergyCalibration 设置 /admin/energy 使用的能量校准，为nil时不提供该接口
+func (s *AudioServer) SetEnergyCalibration(calibration *EnergyCalibration) {
+	s.energy = calibration
+}
+
+// handleEnergyCalibration 能量校准报告：GET /admin/energy 查看，POST /admin/energy {"apply": true} 应用建议阈值，需要管理令牌
+func (s *AudioServer) handleEnergyCalibration(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !s.checkAdmin(w, r) {
+		return
+	}
+	if s.energy == nil {
+		writeError(w, r, http.StatusServiceUnavailable, ErrCodeFeatureDisabled, "能量校准未启用", "需设置 -energy-calibration 启动")
+		return
+	}
+	tuner, _ := s.processor.(EnergyTuner)
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var request struct {
+			Apply bool `json:"apply"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "解析请求参数失败", err.Error())
+			return
+		}
+		if request.Apply {
+			report := s.energy.Report()
+			if !report.Complete || report.Recommended == nil {
+				writeError(w, r, http.StatusConflict, ErrCodeConflict, "能量直方图尚未收集完成",
+					fmt.Sprintf("collected %.0fs of %.0fs", report.CollectedSeconds, report.TargetSeconds))
+				return
+			}
+			if tuner == nil {
+				writeError(w, r, http.StatusNotImplemented, ErrCodeNotSupported, "当前处理器不支持调整能量阈值", "")
+				return
+			}
+			if err := tuner.SetEnergyThresholds(*report.Recommended); err != nil {
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "应用能量阈值失败", err.Error())
+				return
+			}
+			s.energy.MarkApplied()
+		}
+	default:
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	report := s.energy.Report()
+	if tuner != nil {
+		current := tuner.EnergyThresholds()
+		report.Current = &current
+	}
+	writeResponse(w, r, http.StatusOK, report)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// energyTestChunk 生成 seconds 秒、RMS 为 rms 的 200Hz 正弦（8kHz 时每个 20ms 窗口恰好 4 个周期）
+func energyTestChunk(seconds, rms float64) []float64 {
+	data := make([]float64, int(seconds*8000))
+	for i := range data {
+		data[i] = rms * math.Sqrt2 * math.Sin(2*math.Pi*200*float64(i)/8000)
+	}
+	return data
+}
+
+// TestEnergyCalibration 测试能量直方图与阈值建议
+// 测试内容：
+// 1. 噪声底取非静音窗口RMS的第20百分位，建议静默阈值与最小能量分别高出 6dB 与 12dB，数字静音单独计数
+// 2. 收集到目标时长的那次调用返回 true，之后不再统计
+// 3. /api/admin/energy 需要管理令牌，收集完成前应用返回 409，完成后应用建议阈值
+func TestEnergyCalibration(t *testing.T) {
+	format := StreamFormat{SampleRate: 8000}
+	calibration, err := NewEnergyCalibration(5*time.Second, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEnergyCalibration(0, false); err == nil {
+		t.Error("zero duration should be rejected")
+	}
+
+	if calibration.Observe(format, energyTestChunk(3, 0.005)) {
+		t.Error("calibration should not complete after 3s of 5s")
+	}
+	if report := calibration.Report(); report.Complete || report.Recommended == nil || report.Frames != 150 {
+		t.Errorf("partial report = %+v, want 150 frames with a provisional recommendation", report)
+	}
+	if !calibration.Observe(format, append(energyTestChunk(1, 0.2), make([]float64, 8000)...)) {
+		t.Error("calibration should complete at 5s")
+	}
+	if calibration.Observe(format, energyTestChunk(1, 0.5)) {
+		t.Error("completed calibration should not complete again")
+	}
+
+	report := calibration.Report()
+	if !report.Complete || report.Frames != 250 || report.SilentFrames != 50 {
+		t.Errorf("report = complete %v, frames %d, silent %d; want complete, 250, 50", report.Complete, report.Frames, report.SilentFrames)
+	}
+	// 0.005 约为 -46dBFS，取所在 1dB 格的上界
+	if report.NoiseFloor < 0.005 || report.NoiseFloor > 0.005*dbToRMS(1) {
+		t.Errorf("noise floor = %g, want about 0.005", report.NoiseFloor)
+	}
+	if r := report.Recommended; r == nil || math.Abs(r.Silence/report.NoiseFloor-dbToRMS(6)) > 1e-9 || math.Abs(r.MinEnergy/report.NoiseFloor-dbToRMS(12)) > 1e-9 {
+		t.Errorf("recommended = %+v, want noise floor +6dB / +12dB", report.Recommended)
+	}
+	if p99 := report.Percentiles["p99"]; p99 < 0.2 || p99 > 0.2*dbToRMS(1) {
+		t.Errorf("p99 = %g, want about 0.2", p99)
+	}
+	if len(report.Histogram) != 2 {
+		t.Errorf("histogram = %+v, want 2 non-empty bins", report.Histogram)
+	}
+
+	// 管理接口
+	manual := newTestEngine(t, AudioStreamConfig{SampleRate: 8000, BufferSize: 1024})
+	server := NewAudioServer(manual)
+	server.SetAdminToken("secret")
+	pending, _ := NewEnergyCalibration(2*time.Second, false)
+	server.SetEnergyCalibration(pending)
+	call := func(method, token, body string) (int, EnergyReport) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/admin/energy", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		server.handleEnergyCalibration(rec, req)
+		var report EnergyReport
+		json.Unmarshal(rec.Body.Bytes(), &report)
+		return rec.Code, report
+	}
+	if code, _ := call(http.MethodGet, "wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("wrong token status = %d, want 401", code)
+	}
+	if code, report := call(http.MethodGet, "secret", ""); code != http.StatusOK || report.Complete || report.Current == nil || report.Current.Silence != 0.02 {
+		t.Errorf("GET = %d %+v", code, report)
+	}
+	if code, _ := call(http.MethodPost, "secret", `{"apply": true}`); code != http.StatusConflict {
+		t.Errorf("apply before completion status = %d, want 409", code)
+	}
+	pending.Observe(format, energyTestChunk(2, 0.003))
+	code, applyReport := call(http.MethodPost, "secret", `{"apply": true}`)
+	if code != http.StatusOK || !applyReport.Applied || applyReport.Current == nil || *applyReport.Current != *applyReport.Recommended {
+		t.Errorf("apply = %d %+v", code, applyReport)
+	}
+	if manual.EnergyThresholds() != *applyReport.Recommended {
+		t.Errorf("processor thresholds = %+v, want %+v", manual.EnergyThresholds(), applyReport.Recommended)
+	}
+}
+
+// TestEngineEnergyThresholds 测试处理引擎使用并校准能量阈值
+// 测试内容：
+// 1. 引擎在收到的音频中统计能量直方图，收集完成后自动应用建议阈值
+// 2. 应用后的静默阈值用于触发条件的静默检测：低于原默认阈值的轻声叫声不再被当作静默跳过
+// 3. RMS 低于最小能量的窗口不参与匹配，无效的阈值被拒绝
+func TestEngineEnergyThresholds(t *testing.T) {
+	policy := DefaultTriggerPolicy()
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 8000, BufferSize: 1024, Deterministic: true, Trigger: &policy})
+	var tuner EnergyTuner = engine
+	if tuner.EnergyThresholds() != (EnergyThresholds{Silence: DefaultSilenceThreshold}) {
+		t.Errorf("default thresholds = %+v", tuner.EnergyThresholds())
+	}
+	if err := engine.SetEnergyThresholds(EnergyThresholds{Silence: 2}); err == nil {
+		t.Error("silence threshold above 1 should be rejected")
+	}
+
+	// 轻声叫声（RMS 0.01）低于默认静默阈值，窗口全部被跳过
+	quiet := energyTestChunk(1, 0.01)
+	results := func(stream string) int {
+		t.Helper()
+		session := engine.NewSession(stream)
+		engine.Append(session, quiet)
+		n := 0
+		if err := engine.Flush(session, func([]byte) { n++ }); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := results("before"); n != 0 {
+		t.Errorf("quiet call below the default silence threshold produced %d results", n)
+	}
+
+	auto, _ := NewEnergyCalibration(2*time.Second, true)
+	engine.SetEnergyCalibration(auto)
+	for i := 0; i < 4; i++ {
+		if _, err := engine.ProcessAudio("noise", energyTestChunk(0.5, 0.002)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	applied := engine.EnergyThresholds()
+	if !auto.Report().Applied || applied.Silence >= 0.01 || applied.MinEnergy <= applied.Silence {
+		t.Fatalf("auto-applied thresholds = %+v (applied %v)", applied, auto.Report().Applied)
+	}
+	if n := results("after"); n == 0 {
+		t.Error("quiet call above the calibrated silence threshold should be analyzed")
+	}
+
+	if err := engine.SetEnergyThresholds(EnergyThresholds{Silence: 0.001, MinEnergy: 0.05}); err != nil {
+		t.Fatal(err)
+	}
+	if n := results("min-energy"); n != 0 {
+		t.Errorf("windows below minEnergy produced %d results", n)
+	}
+}
@@ -0,0 +1,960 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"soundsdk/dsp"
+)
+
+// 统一处理引擎：CGO接口、移动端桥接与HTTP/WebSocket服务共用的流水线，
+// 按 BufferSize 分块 -> 汉明窗 -> 特征提取 -> 样本库匹配（含先验）-> 本地化短语 -> 情感变化事件。
+
+// StreamSettings 单个流的配置，服务端在开始会话时传入
+type StreamSettings struct {
+	Format          StreamFormat       // 客户端声明的数据格式，零值表示未声明
+	FrequencyPreset string             // 频率范围预设：kitten/adult/large-breed
+	Strategy        string             // 处理策略：standard/low-latency/accurate/template，为空时使用引擎默认策略
+	Cat             CatProfile         // 关联的猫咪档案
+	Context         string             // 上下文标签，如 feeding
+	Hints           []string           // 情境提示，如 pre-feeding、vet-visit（见 context_hints.go）
+	EmotionPriors   map[string]float64 // 直接注入的情感先验倍数
+	Lang            string             // 结果语言
+	EventDebounce   time.Duration      // 情感变化事件去抖时长，为0时使用默认值
+	Debug           bool               // 结果中附带逐窗口特征与评分
+	FeatureVector   bool               // 结果中附带最终特征向量，SDK配置开启时对所有流生效
+}
+
+// Engine 音频处理引擎
+type Engine struct {
+	Config  AudioStreamConfig
+	Library *SampleLibrary // 运行期间通过 SetLibrary 替换
+
+	libraryMu       sync.RWMutex
+	libraryLoadedAt time.Time // 样本库加载或替换的时间
+	events          *EmotionEventHub
+	settingsMu      sync.RWMutex                   // 保护触发条件、能量阈值、能量校准、归档与窗口工作协程数
+	trigger         *TriggerPolicy                 // 缓冲处理触发条件，为nil时每满一个分析窗口即处理（见 trigger_policy.go）
+	thresholds      EnergyThresholds               // 静默阈值（设置触发条件时判断静默）与最小能量
+	calibration     *EnergyCalibration             // 能量校准，为nil时不统计（见 energy_calibration.go）
+	archive         *AudioArchive                  // 处理音频归档，为nil时不归档（见 audio_archive.go）
+	windowWorkers   int                            // 并行提取窗口特征的工作协程数，0表示默认值（见 window_parallel.go）
+	mu              sync.Mutex                     // 只保护 sessions 映射，会话状态由各会话的 bufferMu 保护
+	sessions        map[string]*AudioStreamSession // 通过 AudioProcessor 接口创建的会话
+}
+
+// NewEngine 创建处理引擎
+func NewEngine(config AudioStreamConfig, library *SampleLibrary) *Engine {
+	engine := &Engine{
+		Config:          config,
+		Library:         library,
+		libraryLoadedAt: time.Now(),
+		events:          NewEmotionEventHub(),
+		thresholds:      EnergyThresholds{Silence: DefaultSilenceThreshold},
+		sessions:        make(map[string]*AudioStreamSession),
+	}
+	if config.Trigger != nil {
+		policy := *config.Trigger
+		engine.trigger = &policy
+	}
+	return engine
+}
+
+// LoadEngine 校验配置并加载样本库，创建处理引擎
+func LoadEngine(config AudioStreamConfig) (*Engine, error) {
+	if config.SampleRate < MinSampleRate || config.SampleRate > MaxSampleRate {
+		return nil, ErrInvalidSampleRate
+	}
+	if config.BufferSize <= 0 {
+		return nil, fmt.Errorf("invalid buffer size: %d", config.BufferSize)
+	}
+	if _, err := LookupFrequencyPreset(config.Extractor.FrequencyPreset); err != nil {
+		return nil, err
+	}
+	if _, err := LookupProcessingStrategy(config.Strategy); err != nil {
+		return nil, err
+	}
+	if err := ValidateAggregation(config.Extractor.Aggregation); err != nil {
+		return nil, err
+	}
+	if config.PreFilter != nil {
+		if err := config.PreFilter.Validate(config.SampleRate); err != nil {
+			return nil, err
+		}
+	}
+	if config.Trigger != nil {
+		if err := config.Trigger.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	library := NewSampleLibrary()
+	if config.BundlePath != "" {
+		// 资源包中的样本库优先，资源包带模型时解出路径写入配置
+		keys, err := ParseBundlePublicKeys(config.BundlePublicKeys)
+		if err != nil {
+			return nil, err
+		}
+		pack, err := OpenMeowPack(config.BundlePath, keys)
+		if err != nil {
+			return nil, fmt.Errorf("load bundle: %v", err)
+		}
+		library = pack.Library
+		if pack.ModelPath != "" {
+			config.ModelPath = pack.ModelPath
+		}
+	} else if config.SampleLibraryPath == "" {
+		// 未指定样本库文件时使用编入二进制的默认样本库
+		if len(embeddedEngineLibrary) == 0 {
+			return nil, fmt.Errorf("load sample library: no path given and binary built without -tags embedlib")
+		}
+		if err := library.LoadFromBytes(embeddedEngineLibrary); err != nil {
+			return nil, fmt.Errorf("load embedded sample library: %v", err)
+		}
+	} else if err := library.LoadFromFile(config.SampleLibraryPath); err != nil {
+		return nil, fmt.Errorf("load sample library: %v", err)
+	}
+	if len(library.Samples) == 0 {
+		return nil, fmt.Errorf("sample library is empty")
+	}
+
+	return NewEngine(config, library), nil
+}
+
+// NewSession 按引擎配置创建音频流会话
+func (e *Engine) NewSession(streamID string) *AudioStreamSession {
+	strategy, err := LookupProcessingStrategy(e.Config.Strategy)
+	if err != nil {
+		strategy, _ = LookupProcessingStrategy(DefaultStrategy)
+	}
+
+	session := &AudioStreamSession{
+		ID:               streamID,
+		FeatureExtractor: NewFeatureExtractorWithOptions(e.Config.SampleRate, e.Config.Extractor),
+		Buffer:           make([]float64, 0),
+		Active:           true,
+		Lang:             NormalizeLocale(e.Config.Lang),
+		Tracker:          NewEmotionTracker(time.Duration(e.Config.EventDebounceMs) * time.Millisecond),
+		EventChan:        make(chan []byte, 10),
+		Strategy:         strategy,
+		FeatureVector:    e.Config.FeatureVector,
+	}
+	setResultBuffer(session, e.Config.ResultBufferSize, e.Config.OverflowPolicy, e.Config.OverflowTimeoutMs)
+	return session
+}
+
+// SetFrequencyPreset 为会话切换频率范围预设
+// 与异步处理共用 bufferMu，切换不会与正在进行的分析交错
+func (e *Engine) SetFrequencyPreset(session *AudioStreamSession, preset string) error {
+	if _, err := LookupFrequencyPreset(preset); err != nil {
+		return err
+	}
+
+	options := e.Config.Extractor
+	options.FrequencyPreset = preset
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+	session.FeatureExtractor = NewFeatureExtractorWithOptions(e.Config.SampleRate, options)
+	session.frames = framePipeline{} // 缓存的帧中间结果按原预设的频率范围计算
+	return nil
+}
+
+// SetStrategy 为会话切换处理策略，未输出的段内累积结果被丢弃（同样持 bufferMu 切换）
+func (e *Engine) SetStrategy(session *AudioStreamSession, name string) error {
+	strategy, err := LookupProcessingStrategy(name)
+	if err != nil {
+		return err
+	}
+
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+	session.Strategy = strategy
+	resetSegment(session)
+	session.segmentAudio = nil
+	return nil
+}
+
+// resetSegment 丢弃会话当前段的累积结果
+func resetSegment(session *AudioStreamSession) {
+	session.segmentScores = nil
+	session.segmentWindows = 0
+	session.segmentDebug = nil
+	session.segmentVector = nil
+	session.segmentFeatures = nil
+	session.segmentResult = nil
+	session.segmentSpan = nil
+}
+
+// sampleArrival 一批样本的到达时间，End 为该批最后一个样本之后的累计样本序号
+type sampleArrival struct {
+	End int64
+	At  time.Time
+}
+
+// Append 将样本追加到会话缓冲区并记录到达时间，用于计算结果延迟；之后完成的叫声重新记入结果的 segments
+func (e *Engine) Append(session *AudioStreamSession, samples []float64) {
+	session.vocalizations = nil
+	session.Buffer = append(session.Buffer, samples...)
+	session.SamplesReceived += int64(len(samples))
+	session.arrivals = append(session.arrivals, sampleArrival{End: session.SamplesReceived, At: e.now(session)})
+}
+
+// bufferArrival 返回缓冲区第一个样本的到达时间，并丢弃已完全处理的批次
+func (e *Engine) bufferArrival(session *AudioStreamSession) time.Time {
+	consumed := session.SamplesReceived - int64(len(session.Buffer))
+	for len(session.arrivals) > 0 && session.arrivals[0].End <= consumed {
+		session.arrivals = session.arrivals[1:]
+	}
+	if len(session.arrivals) == 0 {
+		return e.now(session)
+	}
+	return session.arrivals[0].At
+}
+
+// Ready 会话缓冲区是否已满一个分析窗口
+// 设置了触发条件时还需满足触发条件，满足后处理到缓冲区不足一个窗口为止
+func (e *Engine) Ready(session *AudioStreamSession) bool {
+	window, hop := session.Strategy.frames(e.Config.BufferSize)
+	if len(session.Buffer) < window {
+		session.triggered = false
+		return false
+	}
+	policy, thresholds := e.triggerPolicy()
+	if policy == nil || session.triggered {
+		return true
+	}
+	if !e.shouldTrigger(session, policy, thresholds.Silence, window, hop) {
+		return false
+	}
+	session.triggered = true
+	session.lastTrigger = e.now(session)
+	return true
+}
+
+// now 返回会话处理使用的当前时间，确定性模式下为按样本数推算的流时钟
+func (e *Engine) now(session *AudioStreamSession) time.Time {
+	if e.Config.Deterministic {
+		return streamClock(session.SamplesReceived, e.Config.SampleRate)
+	}
+	return time.Now()
+}
+
+// Analyze 按会话的处理策略分析缓冲区中的一个窗口，窗口步进部分的样本从缓冲区移除
+// 策略需要累积多个窗口时，段未完成前返回标记 partial 的中间结果（段内当前最佳猜测）
+// 开启频带预筛选且窗口被跳过时返回 nil
+func (e *Engine) Analyze(session *AudioStreamSession) ([]byte, error) {
+	data, _, err := e.analyze(session)
+	return data, err
+}
+
+// analyze 同 Analyze，并返回结果是否为中间结果
+func (e *Engine) analyze(session *AudioStreamSession) ([]byte, bool, error) {
+	window, hop := session.Strategy.frames(e.Config.BufferSize)
+	if len(session.Buffer) < window {
+		return nil, false, fmt.Errorf("buffer size too small: %d < %d", len(session.Buffer), window)
+	}
+
+	// 目标频带能量不足的窗口直接跳过
+	if e.Config.PreFilter != nil {
+		if ok, _ := e.Config.PreFilter.Accept(session.Buffer[:window], e.Config.SampleRate); !ok {
+			session.Buffer = session.Buffer[hop:]
+			session.prefiltered++
+			return nil, false, nil
+		}
+	}
+
+	// 设置了触发条件时跳过静默窗口，段内静默累计达到 SilenceDuration 时结束当前段；
+	// 窗口之后已缓冲的样本以足够长的静默开头时，叫声在该窗口结束
+	closing := false
+	policy, thresholds := e.triggerPolicy()
+	level := math.Sqrt(calculateEnergy(session.Buffer[:window]) / float64(window))
+	if policy != nil {
+		rate := e.Config.SampleRate
+		required := int(math.Ceil(policy.SilenceDuration * float64(rate)))
+		if level < thresholds.Silence {
+			session.Buffer = session.Buffer[hop:]
+			session.silentRun += hop
+			if session.segmentResult != nil && session.silentRun >= required {
+				data, err := e.closeSegment(session, false)
+				return data, false, err
+			}
+			return nil, false, nil
+		}
+		session.silentRun = 0
+		closing = silentSamples(session.Buffer[window:], rate, thresholds.Silence, false) >= required
+	}
+	// RMS 低于最小能量的窗口不参与匹配，但不视为静默（见 energy_calibration.go）
+	if level < thresholds.MinEnergy {
+		session.Buffer = session.Buffer[hop:]
+		return nil, false, nil
+	}
+
+	processStart := e.now(session)
+	arrival := e.bufferArrival(session)
+	session.windowStart = session.SamplesReceived - int64(len(session.Buffer))
+	e.collectAudio(session, session.Buffer[:window], session.windowStart)
+
+	// 1-2. 应用汉明窗并提取特征，开启逐帧流水线时由帧的中间结果汇总
+	var windowedSamples []float64
+	rawFeatures, ok := e.extractFrames(session, window)
+	if !ok {
+		rawFeatures, ok = e.prefetchedWindow(session, window, hop)
+	}
+	if !ok {
+		windowedSamples = applyHammingWindow(session.Buffer[:window])
+		rawFeatures = session.FeatureExtractor.Extract(&AudioData{
+			Samples:    windowedSamples,
+			SampleRate: e.Config.SampleRate,
+		})
+	}
+	if e.Config.HealthChecks {
+		if windowedSamples == nil {
+			windowedSamples = applyHammingWindow(session.Buffer[:window])
+		}
+		session.health.observe(session.Cat.Baseline, windowedSamples, e.Config.SampleRate, rawFeatures)
+	}
+	session.Buffer = session.Buffer[hop:]
+
+	return e.evaluate(session, rawFeatures, window, processStart, arrival, closing, false)
+}
+
+// extractFrames 开启逐帧流水线时由帧的中间结果汇总缓冲区前 window 个样本的特征，未开启或窗口内没有完整的帧时返回 false
+func (e *Engine) extractFrames(session *AudioStreamSession, window int) (map[string]float64, bool) {
+	if !session.FeatureExtractor.options.FramePipeline {
+		return nil, false
+	}
+	offset := session.SamplesReceived - int64(len(session.Buffer))
+	return session.FeatureExtractor.extractFrames(&session.frames, session.Buffer, offset, window)
+}
+
+// analyzeSpectrum 分析频域会话的一帧幅度数据，直接从幅度计算特征，不经过缓冲与FFT
+func (e *Engine) analyzeSpectrum(session *AudioStreamSession, magnitudes []float64) ([]byte, bool, error) {
+	if len(magnitudes) == 0 {
+		return nil, false, ErrInvalidDataLength
+	}
+	processStart := e.now(session)
+	rawFeatures := session.FeatureExtractor.ExtractSpectrum(magnitudes, session.Format.BinHz(len(magnitudes)))
+	return e.evaluate(session, rawFeatures, len(magnitudes), processStart, processStart, false, false)
+}
+
+// evaluate 按会话策略对一个窗口的特征评分、选出情感并构造结果
+// audioLength 为窗口的样本数（频域为频点数），arrival 为窗口第一批数据到达的时间，
+// closing 表示叫声在该窗口之后结束，final 表示流结束前的最后一个窗口
+func (e *Engine) evaluate(session *AudioStreamSession, rawFeatures map[string]float64, audioLength int, processStart, arrival time.Time, closing, final bool) ([]byte, bool, error) {
+	segment := e.segmentCap(session)
+	segmentStart := session.windowStart
+	if segment > 1 {
+		if session.segmentWindows == 0 {
+			session.segmentArrival = arrival
+			session.segmentStart = session.windowStart
+		}
+		arrival = session.segmentArrival
+		segmentStart = session.segmentStart
+	}
+
+	// 3-4. 使用样本库评分，按策略累积多个窗口
+	scores := e.score(session, rawFeatures)
+	var debugWindows []DebugWindow
+	if session.Debug {
+		window := MapToAudioFeature(rawFeatures)
+		window.WindowIndex = session.windowsAnalyzed
+		debugWindows = []DebugWindow{{Features: window, Scores: scores}}
+	}
+	var vector []float64
+	if session.FeatureVector {
+		vector = featureValues(MapToAudioFeature(rawFeatures))
+	}
+	session.windowsAnalyzed++
+
+	partial := false
+	if segment > 1 {
+		if session.segmentScores == nil {
+			session.segmentScores = make(map[string]float64, len(scores))
+		}
+		for emotion, score := range scores {
+			session.segmentScores[emotion] += score
+		}
+		session.segmentWindows++
+		if session.Debug {
+			session.segmentDebug = append(session.segmentDebug, debugWindows...)
+			debugWindows = append([]DebugWindow(nil), session.segmentDebug...)
+		}
+		if session.FeatureVector {
+			// 段内各窗口特征的平均值，自定义特征在段内注册时重新累积
+			if len(session.segmentVector) != len(vector) {
+				session.segmentVector = make([]float64, len(vector))
+			}
+			for i, v := range vector {
+				session.segmentVector[i] += v
+				vector[i] = session.segmentVector[i] / float64(session.segmentWindows)
+			}
+		}
+
+		// 段内已累积窗口的评分，段未完成时作为当前最佳猜测：设置了汇总方式时为汇总特征的评分，否则为各窗口评分的平均
+		if mode := session.FeatureExtractor.options.Aggregation; mode != "" {
+			session.segmentFeatures = append(session.segmentFeatures, MapToAudioFeature(rawFeatures))
+			scores = e.scoreFeatures(session, aggregateFeatures(session.segmentFeatures, mode))
+		} else {
+			scores = make(map[string]float64, len(session.segmentScores))
+			for emotion, total := range session.segmentScores {
+				scores[emotion] = total / float64(session.segmentWindows)
+			}
+		}
+		if session.segmentWindows < segment && !closing && !final {
+			partial = true
+		} else {
+			resetSegment(session)
+		}
+	}
+
+	// 5. 结合时间与上下文先验选出情感
+	now := processStart
+	priors := streamPriors(session.Cat, session.Context, session.HintPriors, now)
+	emotion, confidence := selectEmotion(scores, priors)
+
+	// 6. 构造结果，连续叫声时提高提示短语的强度（见 call_cadence.go）
+	vars := phraseVarsFor(session.Cat, session.Context, emotion, confidence, math.Sqrt(rawFeatures["Energy"]))
+	var cadence *CallCadence
+	var span *SegmentResult
+	if session.Format.Domain != DomainFrequency {
+		rate := float64(e.Config.SampleRate)
+		span = &SegmentResult{Start: float64(segmentStart) / rate, End: float64(session.windowStart+int64(audioLength)) / rate}
+		observed := session.cadence.observe(span.Start, span.End)
+		cadence = &observed
+		vars.Repetitions = observed.Repetitions
+		if observed.Continuous() {
+			vars.Intensity = escalateIntensity(vars.Intensity)
+		}
+	}
+	result := AudioStreamResult{
+		StreamID:   session.ID,
+		ResultID:   fmt.Sprintf("%s_%d", newResultID(session.ID, now), session.windowsAnalyzed),
+		RequestID:  session.requestID,
+		Timestamp:  now.Unix(),
+		Emotion:    emotion,
+		Confidence: confidence,
+		Scores:     scores,
+		Label:      EmotionLabel(session.Lang, emotion),
+		Message:    ComposeLocalizedMessage(session.Lang, vars),
+		Partial:    partial,
+		Final:      final,
+		Cadence:    cadence,
+		Metadata: AudioStreamMeta{
+			AudioLength: audioLength,
+			Features:    rawFeatures,
+		},
+	}
+	if session.Debug {
+		result.Debug = &ResultDebug{Windows: debugWindows}
+	}
+	if session.FeatureVector {
+		result.FeatureVector = newFeatureVector(vector)
+	}
+	result.ClientTime = e.clientTime(session, segmentStart, session.windowStart+int64(audioLength))
+
+	// 中间结果不参与情感变化判断与稳定度统计，避免段未完成时的猜测触发事件
+	if partial {
+		result.ResultStability = session.stability.current()
+	} else {
+		result.ResultStability = session.stability.observe(emotion, confidence)
+		e.publishEmotionEvent(session, emotion, confidence, now)
+	}
+
+	// 7. 记录处理链路时间并序列化结果
+	processEnd := e.now(session)
+	result.LatencyMs = processEnd.Sub(arrival).Milliseconds()
+	result.Metadata.Timing = ResultTiming{
+		ReceivedAt:   arrival.UnixMilli(),
+		ProcessStart: processStart.UnixMilli(),
+		ProcessEnd:   processEnd.UnixMilli(),
+	}
+	if partial {
+		pending := result
+		session.segmentResult = &pending
+		session.segmentSpan = span
+	} else {
+		e.archiveSegment(session, &result)
+		recordVocalization(session, &result, span)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal result: %v", err)
+	}
+
+	return data, partial, nil
+}
+
+// segmentCap 会话一段最多累积的窗口数
+// 设置了触发条件时段在静默处结束，段长不超过 MaxBufferTime；否则按策略的 Segment 累积
+func (e *Engine) segmentCap(session *AudioStreamSession) int {
+	policy, _ := e.triggerPolicy()
+	if policy == nil {
+		return session.Strategy.Segment
+	}
+	_, hop := session.Strategy.frames(e.Config.BufferSize)
+	return max(session.Strategy.Segment, int(policy.MaxBufferTime*float64(e.Config.SampleRate))/hop)
+}
+
+// belowThresholds 样本的RMS是否低于最小能量，设置了触发条件时还包括是否低于静默阈值
+func (e *Engine) belowThresholds(samples []float64) bool {
+	policy, thresholds := e.triggerPolicy()
+	level := math.Sqrt(calculateEnergy(samples) / float64(len(samples)))
+	return level < thresholds.MinEnergy || (policy != nil && level < thresholds.Silence)
+}
+
+// closeSegment 段在静默处结束且之后没有新的窗口时，以段内最近的中间结果作为该段的最终结果
+func (e *Engine) closeSegment(session *AudioStreamSession, final bool) ([]byte, error) {
+	result := *session.segmentResult
+	span := session.segmentSpan
+	resetSegment(session)
+
+	now := e.now(session)
+	result.ResultID = fmt.Sprintf("%s_%d", newResultID(session.ID, now), session.windowsAnalyzed)
+	result.Partial = false
+	result.Final = final
+	result.ResultStability = session.stability.observe(result.Emotion, result.Confidence)
+	e.publishEmotionEvent(session, result.Emotion, result.Confidence, now)
+	result.LatencyMs = now.UnixMilli() - result.Metadata.Timing.ReceivedAt
+	result.Metadata.Timing.ProcessEnd = now.UnixMilli()
+	e.archiveSegment(session, &result)
+	recordVocalization(session, &result, span)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return data, nil
+}
+
+// recordVocalization 记录一声完成的叫声，结果附带本次送入的数据中已完成的各声（见 AudioStreamResult.Segments）
+// 与上一声首尾相接的段（未设置触发条件时相邻的窗口）合并为同一声，情感取最新的结果；频域流（span 为 nil）不记录
+func recordVocalization(session *AudioStreamSession, result *AudioStreamResult, span *SegmentResult) {
+	if span == nil {
+		return
+	}
+	call := *span
+	if n := len(session.vocalizations); n > 0 && call.Start <= session.vocalizations[n-1].End {
+		call.Start = session.vocalizations[n-1].Start
+		session.vocalizations = session.vocalizations[:n-1]
+	}
+	call.Emotion = result.Emotion
+	call.Confidence = result.Confidence
+	call.Label = result.Label
+	call.Message = result.Message
+	session.vocalizations = append(session.vocalizations, call)
+	result.Segments = append([]SegmentResult(nil), session.vocalizations...)
+}
+
+// score 按会话策略的匹配方式对一个窗口的特征评分
+func (e *Engine) score(session *AudioStreamSession, rawFeatures map[string]float64) map[string]float64 {
+	return e.scoreFeatures(session, MapToAudioFeature(rawFeatures))
+}
+
+// scoreFeatures 按会话策略的匹配方式对一组特征评分
+func (e *Engine) scoreFeatures(session *AudioStreamSession, features AudioFeature) map[string]float64 {
+	return normalizeScores(classifierFor(e.library(), session.Strategy.Matcher).Scores(features))
+}
+
+// library 当前使用的样本库
+func (e *Engine) library() *SampleLibrary {
+	e.libraryMu.RLock()
+	defer e.libraryMu.RUnlock()
+	return e.Library
+}
+
+// SetLibrary 替换样本库：正在进行的分析继续使用旧样本库，之后的分析使用新样本库
+func (e *Engine) SetLibrary(library *SampleLibrary) error {
+	if library == nil || len(library.Samples) == 0 {
+		return fmt.Errorf("sample library is empty")
+	}
+	e.libraryMu.Lock()
+	e.Library = library
+	e.libraryLoadedAt = time.Now()
+	e.libraryMu.Unlock()
+	return nil
+}
+
+// Drain 依次分析缓冲区中所有完整的窗口，每个结果产生后立即交给 emit
+func (e *Engine) Drain(session *AudioStreamSession, emit func([]byte)) error {
+	for e.Ready(session) {
+		data, err := e.Analyze(session)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			emit(data)
+		}
+	}
+	return nil
+}
+
+// MinFlushFraction 结束流时缓冲区剩余样本至少达到分析窗口的该比例才分析
+const MinFlushFraction = 0.25
+
+// Flush 结束流前处理缓冲区：不论触发条件先分析完整窗口，剩余样本不少于窗口的 MinFlushFraction 时作为最后一个窗口分析，
+// 产生标记 final 的结果，未完成的段随之结束；不足或为静默时丢弃剩余样本，未完成的段以最近的中间结果结束
+func (e *Engine) Flush(session *AudioStreamSession, emit func([]byte)) error {
+	session.vocalizations = nil
+	window, _ := session.Strategy.frames(e.Config.BufferSize)
+	for len(session.Buffer) >= window {
+		data, err := e.Analyze(session)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			emit(data)
+		}
+	}
+
+	// 剩余样本为静默或低于最小能量时同样丢弃
+	residual := len(session.Buffer)
+	if residual == 0 || float64(residual) < MinFlushFraction*float64(window) || e.belowThresholds(session.Buffer) {
+		session.Buffer = session.Buffer[:0]
+		if session.segmentResult == nil {
+			return nil
+		}
+		data, err := e.closeSegment(session, true)
+		if err != nil {
+			return err
+		}
+		emit(data)
+		return nil
+	}
+
+	processStart := e.now(session)
+	arrival := e.bufferArrival(session)
+	session.windowStart = session.SamplesReceived - int64(len(session.Buffer))
+	e.collectAudio(session, session.Buffer, session.windowStart)
+	rawFeatures, ok := e.extractFrames(session, residual)
+	if !ok {
+		rawFeatures = session.FeatureExtractor.Extract(&AudioData{
+			Samples:    applyHammingWindow(session.Buffer),
+			SampleRate: e.Config.SampleRate,
+		})
+	}
+	session.Buffer = session.Buffer[:0]
+
+	data, _, err := e.evaluate(session, rawFeatures, residual, processStart, arrival, true, true)
+	if err != nil {
+		return err
+	}
+	emit(data)
+	return nil
+}
+
+// publishEmotionEvent 将识别结果输入会话的情感跟踪器，情感确认变化时写入会话事件通道并向订阅者发布
+func (e *Engine) publishEmotionEvent(session *AudioStreamSession, emotion string, confidence float64, now time.Time) {
+	event, changed := session.Tracker.Observe(emotion, confidence, now)
+	if !changed {
+		return
+	}
+	event.StreamID = session.ID
+	e.events.Publish(event)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	select {
+	case session.EventChan <- data:
+	default:
+		// 通道已满，丢弃事件
+	}
+}
+
+// ---------------AudioProcessor---------------
+
+// ProcessAudio 将样本追加到流的缓冲区，缓冲区满一个窗口即处理
+// 返回本次最后一个结果，尚无结果（数据不足一个窗口或段未完成）时返回 waiting 状态；
+// 本次数据中有多声叫声（如“喵-喵-喵”）时，结果的 segments 按时间列出每一声
+func (e *Engine) ProcessAudio(streamID string, data []float64) ([]byte, error) {
+	return e.ProcessAudioRequest(streamID, "", data)
+}
+
+// ProcessAudioRequest 同 ProcessAudio，本次产生的结果携带音频块的请求ID
+func (e *Engine) ProcessAudioRequest(streamID, requestID string, data []float64) ([]byte, error) {
+	return e.ProcessAudioCaptured(streamID, requestID, data, 0)
+}
+
+// ProcessAudioCaptured 同 ProcessAudioRequest，captureTime 为音频块第一个样本在客户端时钟上的采集时间（毫秒时间戳），
+// 为0时不记录，结果按已记录的时间戳附带 clientTime
+func (e *Engine) ProcessAudioCaptured(streamID, requestID string, data []float64, captureTime int64) ([]byte, error) {
+	if captureTime < 0 {
+		return nil, validateCaptureTime(captureTime)
+	}
+
+	// 各流的处理只持有自己会话的锁，不同流可以同时处理
+	session := e.streamSession(streamID)
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+	session.requestID = requestID
+
+	// 频域会话每条消息即一帧完整频谱，直接分析
+	if session.Format.Domain == DomainFrequency {
+		result, _, err := e.analyzeSpectrum(session, data)
+		e.discardEvents(session)
+		return result, err
+	}
+
+	if len(session.Buffer)+len(data) > MaxBufferSize {
+		return nil, ErrBufferOverflow
+	}
+	e.observeEnergy(data)
+	e.Append(session, data)
+	if captureTime > 0 {
+		e.recordCapture(session, session.SamplesReceived-int64(len(data)), captureTime)
+	}
+
+	// 优先返回本次产生的最终结果，没有最终结果时返回最新的中间结果
+	var result, final []byte
+	for e.Ready(session) {
+		data, partial, err := e.analyze(session)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue // 预筛选跳过的窗口
+		}
+		result = data
+		if !partial {
+			final = data
+		}
+	}
+	if final != nil {
+		result = final
+	}
+	e.discardEvents(session)
+
+	if result != nil {
+		return result, nil
+	}
+	window, _ := session.Strategy.frames(e.Config.BufferSize)
+	waiting := map[string]interface{}{
+		"status":   "waiting",
+		"streamId": streamID,
+		"buffered": len(session.Buffer),
+		"required": window,
+	}
+	if requestID != "" {
+		waiting["requestId"] = requestID
+	}
+	return json.Marshal(waiting)
+}
+
+// AnalyzeFile 分析整段录音：重采样到引擎采样率后按静默切分，每个片段内按策略窗口评分取平均（或按汇总方式汇总特征后评分）后选出情感
+func (e *Engine) AnalyzeFile(audio *AudioData, settings StreamSettings) (*FileAnalysis, error) {
+	if audio == nil || len(audio.Samples) == 0 || audio.SampleRate <= 0 {
+		return nil, ErrInvalidDataLength
+	}
+
+	session := e.NewSession("file")
+	if err := e.applySettings(session, settings); err != nil {
+		return nil, err
+	}
+
+	rate := e.Config.SampleRate
+	samples := dsp.Resample(audio.Samples, audio.SampleRate, rate)
+	window, hop := session.Strategy.frames(e.Config.BufferSize)
+	now := e.now(session)
+	priors := streamPriors(session.Cat, session.Context, session.HintPriors, now)
+
+	analysis := &FileAnalysis{
+		Duration:   float64(len(audio.Samples)) / float64(audio.SampleRate),
+		SampleRate: audio.SampleRate,
+		Segments:   []SegmentResult{},
+	}
+	for _, segment := range vocalizationSegments(samples, rate, DefaultTriggerPolicy().SilenceDuration) {
+		data := samples[segment.Start:segment.End]
+		if len(data) < window {
+			// 短于一个窗口的叫声补零后分析
+			data = append(append(make([]float64, 0, window), data...), make([]float64, window-len(data))...)
+		}
+
+		totals := make(map[string]float64)
+		var features []AudioFeature
+		energy := 0.0
+		for start := 0; start+window <= len(data); start += hop {
+			rawFeatures := session.FeatureExtractor.Extract(&AudioData{
+				Samples:    applyHammingWindow(data[start : start+window]),
+				SampleRate: rate,
+			})
+			for emotion, score := range e.score(session, rawFeatures) {
+				totals[emotion] += score
+			}
+			energy = math.Max(energy, rawFeatures["Energy"])
+			features = append(features, MapToAudioFeature(rawFeatures))
+		}
+		for emotion := range totals {
+			totals[emotion] /= float64(len(features))
+		}
+		if mode := session.FeatureExtractor.options.Aggregation; mode != "" {
+			totals = e.scoreFeatures(session, aggregateFeatures(features, mode))
+		}
+
+		emotion, confidence := selectEmotion(totals, priors)
+		vars := phraseVarsFor(session.Cat, session.Context, emotion, confidence, math.Sqrt(energy))
+		analysis.Segments = append(analysis.Segments, SegmentResult{
+			Start:      float64(segment.Start) / float64(rate),
+			End:        float64(segment.End) / float64(rate),
+			Emotion:    emotion,
+			Confidence: confidence,
+			Scores:     totals,
+			Label:      EmotionLabel(session.Lang, emotion),
+			Message:    ComposeLocalizedMessage(session.Lang, vars),
+		})
+	}
+
+	log.Printf("录音分析完成: 时长=%.2f秒, 采样率=%d, 片段数=%d", analysis.Duration, audio.SampleRate, len(analysis.Segments))
+	return analysis, nil
+}
+
+// discardEvents 事件已通过 events 推送给订阅者，丢弃会话通道中的副本避免积压
+func (e *Engine) discardEvents(session *AudioStreamSession) {
+	for drained := false; !drained; {
+		select {
+		case <-session.EventChan:
+		default:
+			drained = true
+		}
+	}
+}
+
+// ConfigureStream 按配置（重新）创建流会话
+func (e *Engine) ConfigureStream(streamID string, settings StreamSettings) error {
+	if !settings.Format.IsZero() {
+		if err := e.checkFormat(settings.Format); err != nil {
+			return err
+		}
+	}
+
+	session := e.NewSession(streamID)
+	if !settings.Format.IsZero() {
+		session.Format = settings.Format.withDefaults()
+	}
+	if err := e.applySettings(session, settings); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.sessions[streamID] = session
+	e.mu.Unlock()
+
+	log.Printf("引擎会话已配置: StreamID=%s, 策略=%s, 语言=%s", streamID, session.Strategy.Name, session.Lang)
+	return nil
+}
+
+// applySettings 将频率预设、处理策略、猫咪档案、语言、事件去抖与调试开关应用到会话
+func (e *Engine) applySettings(session *AudioStreamSession, settings StreamSettings) error {
+	if settings.Cat.Priors != nil {
+		if err := settings.Cat.Priors.Validate(); err != nil {
+			return err
+		}
+	}
+	hintPriors, err := resolveHintPriors(settings.Hints, settings.EmotionPriors)
+	if err != nil {
+		return err
+	}
+	if settings.FrequencyPreset != "" {
+		if err := e.SetFrequencyPreset(session, settings.FrequencyPreset); err != nil {
+			return err
+		}
+	}
+	if settings.Strategy != "" {
+		if err := e.SetStrategy(session, settings.Strategy); err != nil {
+			return err
+		}
+	}
+	session.Cat = settings.Cat
+	session.Context = settings.Context
+	session.HintPriors = hintPriors
+	if settings.Lang != "" {
+		session.Lang = NormalizeLocale(settings.Lang)
+	}
+	if settings.EventDebounce > 0 {
+		session.Tracker = NewEmotionTracker(settings.EventDebounce)
+	}
+	session.Debug = settings.Debug
+	session.FeatureVector = settings.FeatureVector || e.Config.FeatureVector
+	if !session.FeatureVector {
+		session.segmentVector = nil
+	}
+	return nil
+}
+
+// checkFormat 检查客户端声明的格式能否由引擎处理
+// 时域数据按配置的采样率分帧，不对抽取做换算；频域数据每帧覆盖 0~奈奎斯特频率，与抽取无关，只要求原始采样率一致
+func (e *Engine) checkFormat(format StreamFormat) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
+	format = format.withDefaults()
+	if format.Domain == DomainFrequency {
+		if format.SampleRate != e.Config.SampleRate {
+			return fmt.Errorf("stream format: sample rate %dHz does not match engine sample rate %dHz", format.SampleRate, e.Config.SampleRate)
+		}
+		return nil
+	}
+	if rate := format.Rate(); rate != float64(e.Config.SampleRate) {
+		return fmt.Errorf("stream format: effective sample rate %.0fHz does not match engine sample rate %dHz", rate, e.Config.SampleRate)
+	}
+	return nil
+}
+
+// SetStreamLanguage 设置流返回结果使用的语言
+func (e *Engine) SetStreamLanguage(streamID string, lang string) {
+	session := e.streamSession(streamID)
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+	session.Lang = NormalizeLocale(lang)
+}
+
+// StopStream 结束流会话并丢弃未处理的数据
+func (e *Engine) StopStream(streamID string) {
+	e.mu.Lock()
+	session, ok := e.sessions[streamID]
+	delete(e.sessions, streamID)
+	e.mu.Unlock()
+
+	if ok {
+		session.bufferMu.Lock()
+		session.Active = false
+		session.bufferMu.Unlock()
+	}
+}
+
+// streamSession 返回流的会话，不存在时创建；e.mu 只在查找与插入期间持有，会话状态由调用方持会话的 bufferMu 访问
+func (e *Engine) streamSession(streamID string) *AudioStreamSession {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	session, ok := e.sessions[streamID]
+	if !ok {
+		session = e.NewSession(streamID)
+		e.sessions[streamID] = session
+	}
+	return session
+}
+
+// lookupSession 返回已存在的流会话，e.mu 只在查找期间持有
+func (e *Engine) lookupSession(streamID string) (*AudioStreamSession, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	session, ok := e.sessions[streamID]
+	return session, ok
+}
+
+// Events 返回情感变化事件分发器
+func (e *Engine) Events() *EmotionEventHub {
+	return e.events
+}
+
+// Emotions 当前样本库与领域配置下可识别的情感
+func (e *Engine) Emotions() []string {
+	return resolveEmotionSet(libraryEmotions(e.library()), CurrentDomainProfile())
+}
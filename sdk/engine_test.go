@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestEngine 使用测试样本库创建处理引擎
+func newTestEngine(t *testing.T, config AudioStreamConfig) *Engine {
+	testDir, err := setupTestEnvironment()
+	if err != nil {
+		t.Fatalf("Failed to setup test environment: %v", err)
+	}
+	t.Cleanup(func() { cleanupTestEnvironment(testDir) })
+
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatalf("Failed to create test sample library: %v", err)
+	}
+
+	config.SampleLibraryPath = testDir + "/sample_library.json"
+	engine, err := LoadEngine(config)
+	if err != nil {
+		t.Fatalf("LoadEngine() error = %v", err)
+	}
+	return engine
+}
+
+// TestEngineProcessAudio 测试引擎的分块处理
+// 测试内容：
+// 1. 不足一个窗口时返回 waiting 状态
+// 2. 满一个窗口后返回识别结果，剩余样本保留在缓冲区
+// 3. 非法配置无法创建引擎
+func TestEngineProcessAudio(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+
+	samples := generateTestAudio(440, 0.15, 44100) // 6615个样本
+
+	result, err := engine.ProcessAudio("cat1", samples[:2000])
+	if err != nil {
+		t.Fatalf("ProcessAudio() error = %v", err)
+	}
+	if !strings.Contains(string(result), `"status":"waiting"`) {
+		t.Errorf("不足一个窗口时应返回 waiting, got %s", result)
+	}
+
+	result, err = engine.ProcessAudio("cat1", samples[2000:])
+	if err != nil {
+		t.Fatalf("ProcessAudio() error = %v", err)
+	}
+	var parsed AudioStreamResult
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		t.Fatalf("结果不是合法JSON: %v", err)
+	}
+	if parsed.StreamID != "cat1" || parsed.Emotion == "" || parsed.Metadata.AudioLength != 4096 {
+		t.Errorf("unexpected result: %s", result)
+	}
+	if len(parsed.Scores) == 0 || parsed.Scores[parsed.Emotion] != parsed.Confidence {
+		t.Errorf("scores = %v, want per-emotion scores including %s=%v", parsed.Scores, parsed.Emotion, parsed.Confidence)
+	}
+	if got := len(engine.sessions["cat1"].Buffer); got != len(samples)-4096 {
+		t.Errorf("缓冲区剩余 %d 个样本, want %d", got, len(samples)-4096)
+	}
+
+	if _, err := LoadEngine(AudioStreamConfig{SampleRate: 100, BufferSize: 4096}); err == nil {
+		t.Error("非法采样率应返回错误")
+	}
+}
+
+// TestEngineSessionLock 测试各流的处理互不阻塞
+// 测试内容：
+// 1. 一个流的会话正被处理（持有会话锁）时，另一个流照常处理并返回结果
+// 2. 被占用的流在会话锁释放后继续处理
+func TestEngineSessionLock(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	samples := generateTestAudio(440, 0.1, 44100)
+
+	busy := engine.streamSession("cat1")
+	busy.bufferMu.Lock()
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := engine.ProcessAudio("cat1", samples)
+		blocked <- err
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := engine.ProcessAudio("cat2", samples)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ProcessAudio(cat2) error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("processing cat2 blocked on cat1's session")
+	}
+
+	select {
+	case <-blocked:
+		t.Fatal("cat1 should wait for its session lock")
+	default:
+	}
+	busy.bufferMu.Unlock()
+	if err := <-blocked; err != nil {
+		t.Fatalf("ProcessAudio(cat1) error = %v", err)
+	}
+}
+
+// TestEngineMatchesCGOPath 测试服务端与CGO接口使用同一流水线
+// 测试内容：确定性模式下，同一段音频经 SendAudioChunk 和 Engine.ProcessAudio 得到完全相同的结果
+func TestEngineMatchesCGOPath(t *testing.T) {
+	config := AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Deterministic: true}
+	engine := newTestEngine(t, config)
+
+	config.SampleLibraryPath = engine.Config.SampleLibraryPath
+	if !InitializeSDK(config) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	defer ReleaseSDK()
+	if err := StartAudioStream("cat1"); err != nil {
+		t.Fatalf("StartAudioStream() error = %v", err)
+	}
+	defer StopAudioStream("cat1")
+
+	mu.RLock()
+	session := sdk.Sessions["cat1"]
+	mu.RUnlock()
+
+	pcm := generateTestPCMData(3*4096.0/44100, 44100)
+	for i := 0; i < 3; i++ {
+		chunk := pcm[i*4096*2 : (i+1)*4096*2]
+		if err := SendAudioChunk("cat1", chunk); err != nil {
+			t.Fatalf("SendAudioChunk() error = %v", err)
+		}
+		want := <-session.ResultChan
+
+		samples, _ := decodePCM16(chunk)
+		got, err := engine.ProcessAudio("cat1", samples)
+		if err != nil {
+			t.Fatalf("ProcessAudio() error = %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("chunk %d: 服务端结果与CGO结果不一致\nserver: %s\ncgo:    %s", i, got, want)
+		}
+	}
+}
+
+// TestAudioServerWithEngine 测试服务端通过 AudioProcessor 接口驱动引擎
+// 测试内容：/start 配置语言后，/send 返回本地化结果，/recv 返回最新结果
+func TestAudioServerWithEngine(t *testing.T) {
+	server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+
+	rec := httptest.NewRecorder()
+	server.handleStart(rec, httptest.NewRequest(http.MethodPost, "/start", strings.NewReader(`{"streamId":"cat1","lang":"zh"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/start status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"streamId": "cat1",
+		"data":     generateTestAudio(440, 0.1, 44100),
+	})
+	rec = httptest.NewRecorder()
+	server.handleSend(rec, httptest.NewRequest(http.MethodPost, "/send", bytes.NewReader(body)))
+	var result AudioStreamResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.Emotion == "" {
+		t.Fatalf("/send body = %s", rec.Body)
+	}
+	if want := EmotionLabel("zh", result.Emotion); result.Label != want {
+		t.Errorf("label = %q, want %q", result.Label, want)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleReceive(rec, httptest.NewRequest(http.MethodGet, "/recv?streamId=cat1", nil))
+	if rec.Body.String() == "{}" {
+		t.Error("/recv 应返回最新结果")
+	}
+}
+
+// TestEnginePartialResults 测试段未完成时的中间结果
+// 测试内容：accurate 策略前3个窗口输出 partial 结果，第4个窗口输出最终结果
+func TestEnginePartialResults(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Strategy: StrategyAccurate})
+	session := engine.NewSession("cat1")
+	session.Buffer = generateTestAudio(440, 0.25, 44100)[:10240]
+
+	var results [][]byte
+	if err := engine.Drain(session, func(data []byte) { results = append(results, data) }); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	for i, data := range results {
+		var result AudioStreamResult
+		if err := json.Unmarshal(data, &result); err != nil || result.Emotion == "" {
+			t.Fatalf("result %d: %s", i, data)
+		}
+		if want := i < 3; result.Partial != want {
+			t.Errorf("result %d partial = %v, want %v", i, result.Partial, want)
+		}
+	}
+}
+
+// TestEngineLatency 测试结果中的延迟统计
+// 测试内容：确定性模式下，延迟为窗口第一批样本到达到处理时刻之间的流时钟差
+func TestEngineLatency(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Deterministic: true})
+	samples := generateTestAudio(440, 0.1, 44100)
+
+	var data []byte
+	for i := 0; i < 4; i++ {
+		var err error
+		if data, err = engine.ProcessAudio("cat1", samples[i*1024:(i+1)*1024]); err != nil {
+			t.Fatalf("ProcessAudio() error = %v", err)
+		}
+	}
+
+	var result AudioStreamResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("结果不是合法JSON: %s", data)
+	}
+	// 第一批（1024个样本）到达时流时钟为1024/44100秒，处理时为4096/44100秒
+	if want := streamClock(4096, 44100).Sub(streamClock(1024, 44100)).Milliseconds(); result.LatencyMs != want {
+		t.Errorf("latencyMs = %d, want %d", result.LatencyMs, want)
+	}
+	timing := result.Metadata.Timing
+	if timing.ReceivedAt > timing.ProcessStart || timing.ProcessStart > timing.ProcessEnd {
+		t.Errorf("timing out of order: %+v", timing)
+	}
+}
+
+// TestEngineFrequencyDomain 测试频域会话
+// 测试内容：声明 frequency 格式后每条消息作为一帧频谱直接处理，结果特征来自频点换算
+func TestEngineFrequencyDomain(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	if err := engine.ConfigureStream("cat1", StreamSettings{Format: StreamFormat{SampleRate: 44100, Decimation: 10, Domain: DomainFrequency}}); err != nil {
+		t.Fatalf("ConfigureStream() error = %v", err)
+	}
+
+	bins := make([]float64, 512)
+	bins[20] = 1 // 20 * 44100/2/512 ≈ 861Hz
+	data, err := engine.ProcessAudio("cat1", bins)
+	if err != nil {
+		t.Fatalf("ProcessAudio() error = %v", err)
+	}
+	var result AudioStreamResult
+	if err := json.Unmarshal(data, &result); err != nil || result.Emotion == "" {
+		t.Fatalf("unexpected result: %s", data)
+	}
+	if want := 20 * 44100.0 / 2 / 512; result.Metadata.Features["PeakFreq"] != want || result.Metadata.AudioLength != 512 {
+		t.Errorf("PeakFreq = %v (want %v), audioLength = %d", result.Metadata.Features["PeakFreq"], want, result.Metadata.AudioLength)
+	}
+	if got := len(engine.sessions["cat1"].Buffer); got != 0 {
+		t.Errorf("频域数据不应进入时域缓冲区, buffered %d", got)
+	}
+}
+
+// TestRebuildLibraryEndpoint 测试管理接口重建样本库
+// 测试内容：
+// 1. 未配置令牌或令牌错误时拒绝请求，处理器替身不支持替换
+// 2. 目录无可用样本时返回错误且保留原样本库
+// 3. 成功时处理 .wav/.WAV 文件，替换引擎样本库并按需导出
+func TestRebuildLibraryEndpoint(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	original := engine.library()
+
+	dir := t.TempDir()
+	tone := make([]int16, 44100/2)
+	for i := range tone {
+		tone[i] = int16(8000 * math.Sin(2*math.Pi*500*float64(i)/44100))
+	}
+	for _, file := range []string{"purr/a.wav", "purr/b.WAV", "hiss/a.wav", "hiss/notes.txt"} {
+		path := filepath.Join(dir, file)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, buildWAV(tone, 44100), 0644)
+	}
+	output := filepath.Join(t.TempDir(), "library.json")
+
+	rebuild := func(server *AudioServer, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/library/rebuild", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		server.handleRebuildLibrary(rec, req)
+		return rec
+	}
+
+	server := NewAudioServer(engine)
+	body := `{"dir": "` + dir + `", "output": "` + output + `"}`
+	if rec := rebuild(server, "secret", body); rec.Code != http.StatusForbidden {
+		t.Errorf("without admin token configured: status = %d, want 403", rec.Code)
+	}
+	server.SetAdminToken("secret")
+	if rec := rebuild(server, "wrong", body); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want 401", rec.Code)
+	}
+
+	mockServer := NewAudioServer(NewMockAudioProcessor())
+	mockServer.SetAdminToken("secret")
+	if rec := rebuild(mockServer, "secret", body); rec.Code != http.StatusNotImplemented {
+		t.Errorf("mock processor: status = %d, want 501", rec.Code)
+	}
+
+	if rec := rebuild(server, "secret", `{"dir": "`+t.TempDir()+`"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("empty dir: status = %d, want 422", rec.Code)
+	}
+	if engine.library() != original {
+		t.Fatal("library replaced after failed rebuild")
+	}
+
+	rec := rebuild(server, "secret", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rebuild status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		TotalSamples int            `json:"totalSamples"`
+		Emotions     map[string]int `json:"emotions"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.TotalSamples != 3 || resp.Emotions["purr"] != 2 || resp.Emotions["hiss"] != 1 {
+		t.Errorf("response = %+v, want 3 samples (purr 2, hiss 1)", resp)
+	}
+
+	library := engine.library()
+	if library == original || len(library.Samples["purr"]) != 2 || library.Statistics["purr"].SampleCount != 2 {
+		t.Errorf("engine library not replaced: %+v", library.Statistics)
+	}
+	if _, err := engine.ProcessAudio("cat1", generateTestAudio(500, 0.2, 44100)); err != nil {
+		t.Errorf("ProcessAudio() after rebuild error = %v", err)
+	}
+
+	exported := NewSampleLibrary()
+	if err := exported.LoadFromFile(output); err != nil || len(exported.Samples) != 2 {
+		t.Errorf("exported library = %+v, err = %v", exported.Samples, err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 叫声事件导出
+//
+// 报告只给出汇总，用户想把猫咪的叫声与喂食记录对照，或把一段时间的记录发给兽医时，需要逐条的事件。
+// GET /export 按与 /reports 相同的周期参数导出结果历史中的每个最终结果（时间、时长、情感、置信度、强度），
+// format=csv 时为带表头的CSV文件，便于用表格软件打开；默认为JSON。
+
+// 导出格式
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+)
+
+// VocalizationExport JSON格式的导出内容
+type VocalizationExport struct {
+	Period   string         `json:"period"`
+	CatID    string         `json:"catId,omitempty"`
+	StreamID string         `json:"streamId,omitempty"`
+	Start    int64          `json:"start"` // 区间开始（毫秒时间戳，含）
+	End      int64          `json:"end"`   // 区间结束（毫秒时间戳，不含）
+	Events   []ResultRecord `json:"events"`
+}
+
+// Export 返回区间内的最终结果，按时间排列，catID/streamID 不为空时只导出对应的猫咪或流
+func (h *ResultHistory) Export(period string, date time.Time, catID, streamID string) (VocalizationExport, error) {
+	start, end, err := reportRange(period, date)
+	if err != nil {
+		return VocalizationExport{}, err
+	}
+	if period == "" {
+		period = ReportPeriodDay
+	}
+	export := VocalizationExport{
+		Period:   period,
+		CatID:    catID,
+		StreamID: streamID,
+		Start:    start.UnixMilli(),
+		End:      end.UnixMilli(),
+		Events:   []ResultRecord{},
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, record := range h.results {
+		if record.Timestamp < export.Start || record.Timestamp >= export.End {
+			continue
+		}
+		if (catID != "" && record.CatID != catID) || (streamID != "" && record.StreamID != streamID) {
+			continue
+		}
+		export.Events = append(export.Events, record)
+	}
+	sort.SliceStable(export.Events, func(i, j int) bool { return export.Events[i].Timestamp < export.Events[j].Timestamp })
+	return export, nil
+}
+
+// WriteCSV 以CSV导出事件，每行一个结果，时间为 RFC 3339 格式的本地时间
+func (e *VocalizationExport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"timestamp", "durationMs", "emotion", "confidence", "intensity", "catId", "streamId"})
+	for _, event := range e.Events {
+		writer.Write([]string{
+			time.UnixMilli(event.Timestamp).Format(time.RFC3339),
+			strconv.FormatInt(event.DurationMs, 10),
+			event.Emotion,
+			strconv.FormatFloat(event.Confidence, 'f', 4, 64),
+			event.Intensity,
+			event.CatID,
+			event.StreamID,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// handleExport 导出叫声事件：GET /export?format=json|csv&period=day|week&date=2006-01-02&catId=...&streamId=...
+func (s *AudioServer) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	if s.history == nil {
+		writeError(w, r, http.StatusNotFound, ErrCodeFeatureDisabled, "未启用结果历史", "")
+		return
+	}
+
+	query := r.URL.Query()
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = ExportFormatJSON
+	}
+	if format != ExportFormatJSON && format != ExportFormatCSV {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "format参数无效，可选 json、csv", "")
+		return
+	}
+	date, err := queryDate(query)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "date参数无效，格式为 2006-01-02", "")
+		return
+	}
+	export, err := s.history.Export(strings.ToLower(query.Get("period")), date, query.Get("catId"), query.Get("streamId"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "period参数无效", err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("meowtalk-%s-%s.%s", export.Period, date.Format(historyDateLayout), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == ExportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		export.WriteCSV(w)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, export)
+}
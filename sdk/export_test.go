@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestVocalizationExport 测试叫声事件导出
+// 测试内容：
+// 1. 按区间与猫咪导出最终结果，按时间排列，强度由结果的能量特征得出
+// 2. format=csv 返回带表头的CSV附件，默认返回JSON
+// 3. format 参数无效时返回400
+func TestVocalizationExport(t *testing.T) {
+	history, _ := NewResultHistory("")
+	server := NewAudioServer(NewMockAudioProcessor())
+	server.SetResultHistory(history)
+
+	day := time.Date(2023, 11, 14, 0, 0, 0, 0, time.Local)
+	server.recordHistory("kitchen", "mimi", []byte(`{"status":"processed","emotion":"hungry","confidence":0.82,"metadata":{"features":{"Energy":0.01}}}`), day.Add(18*time.Hour))
+	server.recordHistory("kitchen", "mimi", []byte(`{"status":"processed","emotion":"hello","confidence":0.6}`), day.Add(18*time.Hour+2*time.Second))
+	history.AddResult("bedroom", "tom", "anxious", 0.7, "", day.Add(7*time.Hour))
+	history.AddResult("bedroom", "tom", "anxious", 0.7, "", day.Add(-time.Hour))
+
+	export, err := history.Export(ReportPeriodDay, day, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Events) != 3 || export.Events[0].CatID != "tom" || export.Events[1].Intensity != IntensityMedium || export.Events[2].DurationMs != 2000 {
+		t.Errorf("day export = %+v", export.Events)
+	}
+	if mimi, _ := history.Export(ReportPeriodWeek, day, "mimi", ""); len(mimi.Events) != 2 || mimi.Events[1].Intensity != "" {
+		t.Errorf("week export for mimi = %+v", mimi.Events)
+	}
+
+	rec := httptest.NewRecorder()
+	server.handleExport(rec, httptest.NewRequest(http.MethodGet, "/export?format=csv&date=2023-11-14", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") || !strings.Contains(rec.Header().Get("Content-Disposition"), "meowtalk-day-2023-11-14.csv") {
+		t.Errorf("csv headers = %v", rec.Header())
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(rows) != 4 || rows[0][0] != "timestamp" || rows[2][2] != "hungry" || rows[2][4] != IntensityMedium {
+		t.Errorf("csv rows = %v, %v", rows, err)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleExport(rec, httptest.NewRequest(http.MethodGet, "/export?date=2023-11-14&streamId=kitchen", nil))
+	var decoded VocalizationExport
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil || len(decoded.Events) != 2 || decoded.StreamID != "kitchen" {
+		t.Errorf("json export = %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleExport(rec, httptest.NewRequest(http.MethodGet, "/export?format=ics", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("format=ics = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+)
+
+// 段内窗口特征的汇总方式（ExtractorOptions.Aggregation）。处理引擎未设置时对段内各窗口分别评分后取平均，
+// 设置后先把段内窗口的特征汇总为一组再评分：
+//   max-energy       能量最高的窗口
+//   energy-weighted  各特征按窗口能量加权平均，安静的窗口贡献小，但单个窗口不能独占结果
+//   median           各特征分别取中位数，少数异常窗口不影响结果
+// 音高与基频只统计大于0的窗口（无声或无法估计的窗口为0），都为0时结果为0；自定义特征同样逐项汇总。
+
+// 窗口特征汇总方式
+const (
+	AggregationMaxEnergy      = "max-energy"
+	AggregationEnergyWeighted = "energy-weighted"
+	AggregationMedian         = "median"
+)
+
+// ValidateAggregation 校验窗口特征汇总方式，空字符串表示默认方式
+func ValidateAggregation(mode string) error {
+	switch mode {
+	case "", AggregationMaxEnergy, AggregationEnergyWeighted, AggregationMedian:
+		return nil
+	}
+	return fmt.Errorf("unknown aggregation %q, want %s, %s or %s", mode, AggregationMaxEnergy, AggregationEnergyWeighted, AggregationMedian)
+}
+
+// aggregatedField 参与汇总的一个特征
+type aggregatedField struct {
+	value  func(AudioFeature) float64
+	set    func(*AudioFeature, float64)
+	voiced bool // 只统计大于0的值
+}
+
+// aggregatedFields 参与汇总的内置特征
+var aggregatedFields = []aggregatedField{
+	{func(f AudioFeature) float64 { return f.Energy }, func(f *AudioFeature, v float64) { f.Energy = v }, false},
+	{func(f AudioFeature) float64 { return f.Pitch }, func(f *AudioFeature, v float64) { f.Pitch = v }, true},
+	{func(f AudioFeature) float64 { return f.Duration }, func(f *AudioFeature, v float64) { f.Duration = v }, false},
+	{func(f AudioFeature) float64 { return f.ZeroCrossRate }, func(f *AudioFeature, v float64) { f.ZeroCrossRate = v }, false},
+	{func(f AudioFeature) float64 { return f.RootMeanSquare }, func(f *AudioFeature, v float64) { f.RootMeanSquare = v }, false},
+	{func(f AudioFeature) float64 { return f.PeakFreq }, func(f *AudioFeature, v float64) { f.PeakFreq = v }, false},
+	{func(f AudioFeature) float64 { return f.SpectralCentroid }, func(f *AudioFeature, v float64) { f.SpectralCentroid = v }, false},
+	{func(f AudioFeature) float64 { return f.SpectralRolloff }, func(f *AudioFeature, v float64) { f.SpectralRolloff = v }, false},
+	{func(f AudioFeature) float64 { return f.FundamentalFreq }, func(f *AudioFeature, v float64) { f.FundamentalFreq = v }, true},
+}
+
+// aggregateFeatures 按汇总方式由窗口特征得到段的特征，未知的方式按 max-energy 处理
+func aggregateFeatures(windows []AudioFeature, mode string) AudioFeature {
+	if len(windows) == 0 {
+		return AudioFeature{}
+	}
+	var combine func(values, weights []float64) float64
+	switch mode {
+	case AggregationEnergyWeighted:
+		combine = weightedMean
+	case AggregationMedian:
+		combine = func(values, _ []float64) float64 { return median(values) }
+	default:
+		return loudestWindow(windows)
+	}
+
+	// 能量都为0时按等权处理
+	weights := make([]float64, len(windows))
+	total := 0.0
+	for i, window := range windows {
+		weights[i] = math.Max(window.Energy, 0)
+		total += weights[i]
+	}
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+
+	var final AudioFeature
+	for _, field := range aggregatedFields {
+		var values, fieldWeights []float64
+		for i, window := range windows {
+			v := field.value(window)
+			if field.voiced && v <= 0 {
+				continue
+			}
+			values = append(values, v)
+			fieldWeights = append(fieldWeights, weights[i])
+		}
+		if len(values) > 0 {
+			field.set(&final, combine(values, fieldWeights))
+		}
+	}
+	custom := make(map[string][]int) // 自定义特征 -> 含有该特征的窗口
+	for i, window := range windows {
+		for name := range window.Custom {
+			custom[name] = append(custom[name], i)
+		}
+	}
+	for name, indexes := range custom {
+		values := make([]float64, len(indexes))
+		fieldWeights := make([]float64, len(indexes))
+		for j, i := range indexes {
+			values[j] = windows[i].Custom[name]
+			fieldWeights[j] = weights[i]
+		}
+		if final.Custom == nil {
+			final.Custom = make(map[string]float64, len(custom))
+		}
+		final.Custom[name] = combine(values, fieldWeights)
+	}
+
+	log.Printf("按 %s 汇总 %d 个窗口的特征: 能量=%.6f, 音高=%.2f Hz, 峰值频率=%.2f Hz",
+		mode, len(windows), final.Energy, final.Pitch, final.PeakFreq)
+	return final
+}
+
+// loudestWindow 能量最高的窗口的特征
+func loudestWindow(windows []AudioFeature) AudioFeature {
+	loudest := windows[0]
+	for _, window := range windows[1:] {
+		if window.Energy > loudest.Energy {
+			loudest = window
+		}
+	}
+	log.Printf("使用最高能量窗口的特征: 窗口#%d，能量=%.6f", loudest.WindowIndex, loudest.Energy)
+	return loudest
+}
+
+// weightedMean 加权平均，权重之和为0时取算术平均
+func weightedMean(values, weights []float64) float64 {
+	var sum, total float64
+	for i, v := range values {
+		sum += v * weights[i]
+		total += weights[i]
+	}
+	if total == 0 {
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	}
+	return sum / total
+}
+
+// median 中位数，偶数个值时取中间两个的平均
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
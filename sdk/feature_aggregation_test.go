@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestAggregateFeatures 测试片段内窗口特征的汇总方式
+// 测试内容：
+// 1. 默认与 max-energy 取能量最高的窗口
+// 2. energy-weighted 按窗口能量加权平均，音高只统计大于0的窗口
+// 3. median 逐特征取中位数，单个异常窗口不影响结果
+// 4. 自定义特征只在含有该特征的窗口间汇总
+// 5. 未知的汇总方式校验失败
+func TestAggregateFeatures(t *testing.T) {
+	windows := []AudioFeature{
+		{Energy: 1, Pitch: 500, PeakFreq: 600, Duration: 1},
+		{Energy: 2, Pitch: 520, PeakFreq: 620, Duration: 1},
+		{Energy: 1, Pitch: 540, PeakFreq: 640, Duration: 1},
+		{Energy: 6, Pitch: 0, PeakFreq: 3000, Duration: 1}, // 一次碰撞：能量最高，没有音高
+	}
+
+	for _, mode := range []string{"", AggregationMaxEnergy} {
+		if got := aggregateFeatures(windows, mode); got.PeakFreq != 3000 || got.Energy != 6 {
+			t.Errorf("aggregation %q = %+v, want the loudest window", mode, got)
+		}
+	}
+
+	weighted := aggregateFeatures(windows, AggregationEnergyWeighted)
+	if want := (600 + 2*620 + 640 + 6*3000) / 10.0; math.Abs(weighted.PeakFreq-want) > 1e-9 {
+		t.Errorf("weighted PeakFreq = %v, want %v", weighted.PeakFreq, want)
+	}
+	if want := (500 + 2*520 + 540) / 4.0; math.Abs(weighted.Pitch-want) > 1e-9 {
+		t.Errorf("weighted Pitch = %v, want %v", weighted.Pitch, want)
+	}
+	if weighted.Duration != 1 {
+		t.Errorf("weighted Duration = %v, want 1", weighted.Duration)
+	}
+
+	med := aggregateFeatures(windows, AggregationMedian)
+	if med.PeakFreq != 630 || med.Pitch != 520 || med.Energy != 1.5 {
+		t.Errorf("median = %+v, want PeakFreq 630, Pitch 520, Energy 1.5", med)
+	}
+
+	custom := []AudioFeature{{Energy: 1, Custom: map[string]float64{"HNR": 10}}, {Energy: 3, Custom: map[string]float64{"HNR": 20}}, {Energy: 4}}
+	if got := aggregateFeatures(custom, AggregationEnergyWeighted).Custom["HNR"]; got != 17.5 {
+		t.Errorf("weighted custom HNR = %v, want 17.5 over the windows that have it", got)
+	}
+
+	silent := []AudioFeature{{PeakFreq: 100}, {PeakFreq: 300}}
+	if got := aggregateFeatures(silent, AggregationEnergyWeighted); got.PeakFreq != 200 || got.Pitch != 0 {
+		t.Errorf("silent weighted = %+v, want equal weights", got)
+	}
+	if got := aggregateFeatures(nil, AggregationMedian); !reflect.DeepEqual(got, AudioFeature{}) {
+		t.Errorf("empty median = %+v", got)
+	}
+
+	if err := ValidateAggregation("mean"); err == nil {
+		t.Error("unknown aggregation should fail")
+	}
+	for _, mode := range []string{"", AggregationMaxEnergy, AggregationEnergyWeighted, AggregationMedian} {
+		if err := ValidateAggregation(mode); err != nil {
+			t.Errorf("ValidateAggregation(%q): %v", mode, err)
+		}
+	}
+}
+
+// TestEngineAggregation 测试处理引擎按汇总方式计算段的评分
+// 测试内容：
+// 1. 未设置汇总方式时段的评分为段内各窗口评分的平均
+// 2. 设置 median 时段的评分为段内窗口特征中位数的评分
+// 3. 未知的汇总方式无法创建引擎
+func TestEngineAggregation(t *testing.T) {
+	const rate = 44100
+	policy := DefaultTriggerPolicy()
+	segment := func(mode string) AudioStreamResult {
+		t.Helper()
+		engine := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 4096, Deterministic: true, Trigger: &policy,
+			Extractor: ExtractorOptions{Aggregation: mode}})
+		session := engine.NewSession("cat1")
+		session.Debug = true
+		call := generateTestAudio(500, 0.3, rate)
+		bump := generateTestAudio(2500, 0.1, rate) // 叫声末尾的一次碰撞，窗口特征明显不同
+		engine.Append(session, append(call, bump...))
+		engine.Append(session, make([]float64, int(0.5*rate)))
+		var result AudioStreamResult
+		err := engine.Drain(session, func(data []byte) {
+			result = AudioStreamResult{}
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Partial || result.Debug == nil || len(result.Debug.Windows) < 3 {
+			t.Fatalf("aggregation %q: want a final result over several windows, got %+v", mode, result)
+		}
+		return result
+	}
+	near := func(got, want map[string]float64) bool {
+		for emotion, score := range want {
+			if math.Abs(got[emotion]-score) > 1e-9 {
+				return false
+			}
+		}
+		return len(got) == len(want)
+	}
+
+	mean := segment("")
+	want := make(map[string]float64)
+	for _, window := range mean.Debug.Windows {
+		for emotion, score := range window.Scores {
+			want[emotion] += score / float64(len(mean.Debug.Windows))
+		}
+	}
+	if !near(mean.Scores, want) {
+		t.Errorf("default scores = %v, want the window mean %v", mean.Scores, want)
+	}
+
+	med := segment(AggregationMedian)
+	var windows []AudioFeature
+	for _, window := range med.Debug.Windows {
+		windows = append(windows, window.Features)
+	}
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 4096})
+	session := engine.NewSession("check")
+	if want := engine.scoreFeatures(session, aggregateFeatures(windows, AggregationMedian)); !near(med.Scores, want) {
+		t.Errorf("median scores = %v, want the scores of the median features %v", med.Scores, want)
+	}
+	if near(med.Scores, mean.Scores) {
+		t.Errorf("median and mean aggregation gave the same scores %v", med.Scores)
+	}
+
+	if _, err := LoadEngine(AudioStreamConfig{SampleRate: rate, BufferSize: 4096, Extractor: ExtractorOptions{Aggregation: "mean"}}); err == nil {
+		t.Error("LoadEngine accepted an unknown aggregation")
+	}
+}
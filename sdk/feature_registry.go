@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// 自定义特征
+//
+// 内置特征（过零率、能量、音高等9项）写死在特征提取与样本库统计中，下游想加入MFCC、谱平坦度之类的特征
+// 只能修改DSP代码。RegisterFeature 注册的特征函数在每个窗口上与内置特征一起计算：出现在结果的
+// metadata.features 中，随样本保存在样本库（AudioFeature.Custom），并参与各匹配方式的距离与相似度计算。
+// 特征应在加载或构建样本库之前注册；样本库中缺少某个自定义特征时按0处理，注册新特征后应重新构建样本库。
+// 频谱输入（ExtractSpectrum）没有时域样本，不计算自定义特征。
+
+// FeatureFunc 自定义特征函数，输入一个窗口的样本（[-1, 1]）与采样率，返回特征值
+type FeatureFunc func(samples []float64, sampleRate int) float64
+
+// customFeature 一个已注册的自定义特征
+type customFeature struct {
+	name string
+	fn   FeatureFunc
+}
+
+var (
+	featureRegistryMu sync.RWMutex
+	customFeatureList []customFeature // 按注册顺序，决定特征向量中的位置
+)
+
+// RegisterFeature 注册自定义特征，名称不能为空、不能与内置特征或已注册的特征重名
+func RegisterFeature(name string, fn FeatureFunc) error {
+	if name == "" || fn == nil {
+		return fmt.Errorf("register feature: name and function are required")
+	}
+	for _, builtin := range featureLabels {
+		if name == builtin {
+			return fmt.Errorf("register feature: %q is a built-in feature", name)
+		}
+	}
+
+	featureRegistryMu.Lock()
+	defer featureRegistryMu.Unlock()
+	for _, feature := range customFeatureList {
+		if feature.name == name {
+			return fmt.Errorf("register feature: %q is already registered", name)
+		}
+	}
+	customFeatureList = append(customFeatureList, customFeature{name: name, fn: fn})
+	return nil
+}
+
+// RegisteredFeatures 按注册顺序返回自定义特征名
+func RegisteredFeatures() []string {
+	featureRegistryMu.RLock()
+	defer featureRegistryMu.RUnlock()
+	names := make([]string, len(customFeatureList))
+	for i, feature := range customFeatureList {
+		names[i] = feature.name
+	}
+	return names
+}
+
+// featureNames 与 featureValues 顺序一致的特征名：内置特征之后是自定义特征
+func featureNames() []string {
+	return append(append([]string(nil), featureLabels...), RegisteredFeatures()...)
+}
+
+// extractCustomFeatures 计算所有自定义特征，没有注册时返回nil
+func extractCustomFeatures(samples []float64, sampleRate int) map[string]float64 {
+	featureRegistryMu.RLock()
+	defer featureRegistryMu.RUnlock()
+	if len(customFeatureList) == 0 {
+		return nil
+	}
+	values := make(map[string]float64, len(customFeatureList))
+	for _, feature := range customFeatureList {
+		v := feature.fn(samples, sampleRate)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			v = 0
+		}
+		values[feature.name] = v
+	}
+	return values
+}
+
+// customFeatureValues 按注册顺序列出特征中的自定义特征值，缺少的特征为0
+func customFeatureValues(f AudioFeature) []float64 {
+	names := RegisteredFeatures()
+	values := make([]float64, len(names))
+	for i, name := range names {
+		values[i] = f.Custom[name]
+	}
+	return values
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// TestRegisterFeature 测试自定义特征注册
+// 测试内容：
+// 1. 名称为空、与内置特征或已注册特征重名时注册失败
+// 2. 注册的特征出现在 Extract 的结果与 AudioFeature.Custom 中
+// 3. 自定义特征随样本库保存与加载，各匹配方式都按其区分情感
+func TestRegisterFeature(t *testing.T) {
+	featureRegistryMu.Lock()
+	saved := customFeatureList
+	customFeatureList = nil
+	featureRegistryMu.Unlock()
+	t.Cleanup(func() {
+		featureRegistryMu.Lock()
+		customFeatureList = saved
+		featureRegistryMu.Unlock()
+	})
+
+	peak := func(samples []float64, sampleRate int) float64 {
+		max := 0.0
+		for _, s := range samples {
+			max = math.Max(max, math.Abs(s))
+		}
+		return max
+	}
+	if err := RegisterFeature("Peak", peak); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "Pitch", "Peak"} {
+		if err := RegisterFeature(name, peak); err == nil {
+			t.Errorf("RegisterFeature(%q) should fail", name)
+		}
+	}
+	if names := featureNames(); len(names) != len(featureLabels)+1 || names[len(names)-1] != "Peak" {
+		t.Errorf("featureNames = %v", names)
+	}
+
+	samples := make([]float64, 4410)
+	for i := range samples {
+		samples[i] = 0.5 * math.Sin(2*math.Pi*600*float64(i)/44100)
+	}
+	raw := NewFeatureExtractor(44100).Extract(&AudioData{Samples: samples, SampleRate: 44100})
+	if math.Abs(raw["Peak"]-0.5) > 0.01 {
+		t.Errorf("extracted Peak = %v, want 0.5", raw["Peak"])
+	}
+	if feature := MapToAudioFeature(raw); feature.Custom["Peak"] != raw["Peak"] || len(featureValues(feature)) != len(featureLabels)+1 {
+		t.Errorf("MapToAudioFeature custom = %v", feature.Custom)
+	}
+
+	// 两种情感的内置特征相同，只有自定义特征不同
+	library := NewSampleLibrary()
+	base := AudioFeature{Pitch: 600, Energy: 0.1, Duration: 1}
+	for _, sample := range []struct {
+		emotion string
+		peak    float64
+	}{{"hungry", 0.1}, {"hungry", 0.15}, {"hello", 0.8}, {"hello", 0.9}} {
+		feature := base
+		feature.Custom = map[string]float64{"Peak": sample.peak}
+		library.AddSample(AudioSample{Emotion: sample.emotion, Features: feature})
+	}
+	path := filepath.Join(t.TempDir(), "library.json")
+	if err := library.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewSampleLibrary()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Samples["hello"][1].Features.Custom["Peak"] != 0.9 {
+		t.Errorf("loaded custom feature = %v", loaded.Samples["hello"][1].Features.Custom)
+	}
+
+	query := base
+	query.Custom = map[string]float64{"Peak": 0.85}
+	for _, matcher := range []string{"", MatcherFast, MatcherTemplate} {
+		scores := classifierFor(loaded, matcher).Scores(query)
+		if scores["hello"] <= scores["hungry"] {
+			t.Errorf("matcher %q scores = %v, want hello ahead", matcher, scores)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+// 结果特征向量
+//
+// 外部系统常希望用识别所依据的特征自行建模（例如按家庭训练二级分类器），但结果中只有
+// metadata.features：单个窗口、键无序的原始特征表。流开启
+// featureVector（/start 或WebSocket配置中 "featureVector": true，或SDK配置对所有流开启）后，
+// 结果附带 featureVector 字段：与样本库匹配所用的最终特征，名称与取值按固定顺序一一对应，
+// 注册的自定义特征排在内置特征之后。多窗口段策略下为段内各窗口特征的平均值。
+
+// FeatureVector 结果附带的最终特征向量
+type FeatureVector struct {
+	Names  []string  `json:"names"`  // 特征名称，顺序与 Values 一致
+	Values []float64 `json:"values"` // 特征取值
+}
+
+// newFeatureVector 按样本库匹配的特征顺序构造特征向量
+func newFeatureVector(values []float64) *FeatureVector {
+	names := featureNames()
+	if len(values) < len(names) {
+		names = names[:len(values)]
+	}
+	return &FeatureVector{Names: names, Values: values}
+}
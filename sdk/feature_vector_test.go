@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// TestFeatureVector 测试结果特征向量
+// 测试内容：
+// 1. 未开启时结果不附带特征向量，流开启后名称与取值一一对应，取值与 metadata.features 一致
+// 2. SDK配置开启后所有流的结果都附带特征向量
+// 3. accurate 策略下特征向量为段内各窗口特征的平均值
+func TestFeatureVector(t *testing.T) {
+	process := func(engine *Engine, streamID string, seconds float64) AudioStreamResult {
+		t.Helper()
+		data, err := engine.ProcessAudio(streamID, generateTestAudio(440, seconds, 44100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result AudioStreamResult
+		if err := json.Unmarshal(data, &result); err != nil || result.Emotion == "" {
+			t.Fatalf("result = %s, %v", data, err)
+		}
+		return result
+	}
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	if result := process(engine, "plain", 0.1); result.FeatureVector != nil {
+		t.Errorf("feature vector without opt-in = %+v", result.FeatureVector)
+	}
+	if err := engine.ConfigureStream("vector", StreamSettings{FeatureVector: true}); err != nil {
+		t.Fatal(err)
+	}
+	result := process(engine, "vector", 0.1)
+	vector := result.FeatureVector
+	if vector == nil || len(vector.Names) != len(featureLabels) || len(vector.Values) != len(vector.Names) {
+		t.Fatalf("feature vector = %+v, want %d named values", vector, len(featureLabels))
+	}
+	for i, name := range vector.Names {
+		if vector.Values[i] != result.Metadata.Features[name] {
+			t.Errorf("%s = %v, want %v", name, vector.Values[i], result.Metadata.Features[name])
+		}
+	}
+
+	all := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, FeatureVector: true})
+	if result := process(all, "any", 0.1); result.FeatureVector == nil {
+		t.Error("config featureVector should apply to every stream")
+	}
+
+	if err := engine.ConfigureStream("review", StreamSettings{Strategy: StrategyAccurate, FeatureVector: true, Debug: true}); err != nil {
+		t.Fatal(err)
+	}
+	result = process(engine, "review", 0.2)
+	if result.FeatureVector == nil || result.Debug == nil || len(result.Debug.Windows) < 2 {
+		t.Fatalf("accurate result = %+v, want a segment feature vector", result)
+	}
+	mean := 0.0
+	for _, window := range result.Debug.Windows {
+		mean += window.Features.Energy
+	}
+	mean /= float64(len(result.Debug.Windows))
+	if energy := result.FeatureVector.Values[1]; math.Abs(energy-mean) > 1e-12 {
+		t.Errorf("segment Energy = %v, want window mean %v", energy, mean)
+	}
+
+}
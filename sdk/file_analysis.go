@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// 整段录音分析
+//
+// 流式接口要求客户端按实时节奏推送数据，对“分析这段5分钟的录音”这类场景很别扭。
+// 文件分析一次性接收整段录音（上传或URL），解码后按静默切分为叫声片段，
+// 逐段识别并返回带时间戳的结果。
+
+// 文件分析相关常量
+const (
+	MaxAnalyzeFileBytes = 64 << 20 // 上传或下载的录音文件最大字节数
+
+	segmentFrameDuration = 0.02 // 静默检测帧长（秒）
+	minSegmentDuration   = 0.1  // 标注片段的最短时长，短于该时长的视为噪声丢弃
+	minSilenceThreshold  = 0.01 // 静默判定的最低RMS阈值
+)
+
+// FileAnalysis 整段录音的分析结果
+type FileAnalysis struct {
+	Duration   float64         `json:"duration"`   // 录音时长（秒）
+	SampleRate int             `json:"sampleRate"` // 录音采样率
+	Segments   []SegmentResult `json:"segments"`   // 各叫声片段的识别结果，按时间排序
+}
+
+// SegmentResult 录音中一个叫声片段的识别结果
+type SegmentResult struct {
+	Start      float64            `json:"start"`             // 片段开始时间（秒）
+	End        float64            `json:"end"`               // 片段结束时间（秒）
+	Emotion    string             `json:"emotion"`           // 识别的情感
+	Confidence float64            `json:"confidence"`        // 置信度
+	Scores     map[string]float64 `json:"scores,omitempty"`  // 各情感在片段内的平均评分
+	Label      string             `json:"label,omitempty"`   // 本地化的情感名称
+	Message    string             `json:"message,omitempty"` // 面向用户的提示短语
+}
+
+// AudioSegment 录音中一个非静默片段的样本区间 [Start, End)
+type AudioSegment struct {
+	Start int
+	End   int
+}
+
+// isMP3 检查数据是否以ID3标签或MPEG帧同步字开头
+func isMP3(data []byte) bool {
+	if len(data) >= 3 && string(data[:3]) == "ID3" {
+		return true
+	}
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0
+}
+
+// decodeMP3 解码MP3数据，解码器输出16位双声道交织PCM，按选项合并为单声道
+func decodeMP3(r io.Reader, options DecodeOptions) (result *AudioData, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, fmt.Errorf("%w: %v", ErrInvalidAudioFile, p)
+		}
+	}()
+
+	decoder, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAudioFile, err)
+	}
+
+	weights := options.channelWeights(2)
+	samples := make([]float64, 0)
+	buf := make([]byte, 4096)
+	var pending []byte // 上一批末尾不足一帧的字节
+	for {
+		n, readErr := decoder.Read(buf)
+		frame := append(pending, buf[:n]...)
+		usable := len(frame) - len(frame)%4
+		for i := 0; i < usable; i += 4 {
+			left := int16(binary.LittleEndian.Uint16(frame[i:]))
+			right := int16(binary.LittleEndian.Uint16(frame[i+2:]))
+			samples = append(samples, (weights[0]*float64(left)+weights[1]*float64(right))/32768.0)
+		}
+		pending = append([]byte(nil), frame[usable:]...)
+
+		if len(samples) > MaxWAVSamples {
+			return nil, ErrAudioTooLong
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAudioFile, readErr)
+		}
+	}
+
+	return &AudioData{Samples: samples, SampleRate: decoder.SampleRate()}, nil
+}
+
+// vocalizationSegments 按静默切分录音，收缩到叫声的起止点（见 endpointing.go），
+// 再按领域配置的叫声时长范围过滤片段（见 DomainProfile.FilterSegments）
+func vocalizationSegments(samples []float64, sampleRate int, minSilence float64) []AudioSegment {
+	segments := splitSegments(samples, sampleRate, minSilence)
+	for i, segment := range segments {
+		segments[i] = trimSegment(samples, segment, sampleRate)
+	}
+	return CurrentDomainProfile().FilterSegments(segments, sampleRate)
+}
+
+// splitSegments 按静默切分录音
+// 以20ms帧的RMS判断静默，阈值取最响帧的10%（不低于 minSilenceThreshold），
+// 静默持续 minSilence 秒以上时切分；不按时长过滤，需要过滤时使用 vocalizationSegments
+func splitSegments(samples []float64, sampleRate int, minSilence float64) []AudioSegment {
+	frameSize := max(1, int(segmentFrameDuration*float64(sampleRate)))
+	frameCount := (len(samples) + frameSize - 1) / frameSize
+	if frameCount == 0 {
+		return nil
+	}
+
+	levels := make([]float64, frameCount)
+	peak := 0.0
+	for i := range levels {
+		end := (i + 1) * frameSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+		frame := samples[i*frameSize : end]
+		levels[i] = math.Sqrt(calculateEnergy(frame) / float64(len(frame)))
+		peak = max(peak, levels[i])
+	}
+	threshold := max(minSilenceThreshold, 0.1*peak)
+	silenceFrames := max(1, int(math.Ceil(minSilence/segmentFrameDuration)))
+
+	var segments []AudioSegment
+	start, lastLoud := -1, -1
+	closeSegment := func() {
+		if start >= 0 {
+			end := (lastLoud + 1) * frameSize
+			if end > len(samples) {
+				end = len(samples)
+			}
+			segments = append(segments, AudioSegment{Start: start * frameSize, End: end})
+		}
+		start = -1
+	}
+
+	for i, level := range levels {
+		if level >= threshold {
+			if start < 0 {
+				start = i
+			}
+			lastLoud = i
+		} else if start >= 0 && i-lastLoud >= silenceFrames {
+			closeSegment()
+		}
+	}
+	closeSegment()
+
+	return segments
+}
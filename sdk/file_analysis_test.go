@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"soundsdk/dsp"
+)
+
+// buildCallRecording 生成“0.5秒静默 + 0.4秒叫声 + 1秒静默 + 0.6秒叫声 + 0.5秒静默”的16kHz录音
+func buildCallRecording() []int16 {
+	var samples []int16
+	silence := func(seconds float64) {
+		samples = append(samples, make([]int16, int(seconds*16000))...)
+	}
+	call := func(seconds float64) {
+		for _, v := range generateTestAudio(600, seconds, 16000) {
+			samples = append(samples, int16(v*16000))
+		}
+	}
+	silence(0.5)
+	call(0.4)
+	silence(1.0)
+	call(0.6)
+	silence(0.5)
+	return samples
+}
+
+// TestSplitSegments 测试按静默切分录音
+// 测试内容：
+// 1. 两声叫声被切分为两个片段，边界误差不超过一帧
+// 2. 全静默录音没有片段
+// 3. 重采样后时长不变
+func TestSplitSegments(t *testing.T) {
+	pcm := buildCallRecording()
+	samples := make([]float64, len(pcm))
+	for i, v := range pcm {
+		samples[i] = float64(v) / 32768
+	}
+
+	segments := splitSegments(samples, 16000, 0.3)
+	if len(segments) != 2 {
+		t.Fatalf("got %d segments, want 2: %+v", len(segments), segments)
+	}
+	want := []AudioSegment{{Start: 8000, End: 14400}, {Start: 30400, End: 40000}}
+	for i, seg := range segments {
+		if abs(seg.Start-want[i].Start) > 320 || abs(seg.End-want[i].End) > 320 {
+			t.Errorf("segment %d = %+v, want about %+v", i, seg, want[i])
+		}
+	}
+
+	if got := splitSegments(make([]float64, 16000), 16000, 0.3); len(got) != 0 {
+		t.Errorf("全静默录音不应有片段: %+v", got)
+	}
+
+	if got := len(dsp.Resample(samples, 16000, 44100)); got != len(samples)*44100/16000 {
+		t.Errorf("resampled length = %d, want %d", got, len(samples)*44100/16000)
+	}
+}
+
+// abs 整数绝对值
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// TestAnalyzeFile 测试整段录音分析接口
+// 测试内容：
+// 1. multipart 上传WAV，引擎返回两个带时间戳的片段结果
+// 2. 通过URL下载录音得到相同结果
+// 3. 不支持的格式与缺少文件时返回400
+func TestAnalyzeFile(t *testing.T) {
+	wav := buildWAV(buildCallRecording(), 16000)
+	server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "cat.wav")
+	part.Write(wav)
+	form.WriteField("lang", "zh")
+	form.Close()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/analyze-file", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	server.handleAnalyzeFile(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var uploaded FileAnalysis
+	if err := json.Unmarshal(rec.Body.Bytes(), &uploaded); err != nil {
+		t.Fatalf("结果不是合法JSON: %s", rec.Body)
+	}
+	if uploaded.SampleRate != 16000 || uploaded.Duration != 3.0 || len(uploaded.Segments) != 2 {
+		t.Fatalf("unexpected analysis: %s", rec.Body)
+	}
+	for i, seg := range uploaded.Segments {
+		if seg.Emotion == "" || seg.End <= seg.Start || seg.Label != EmotionLabel("zh", seg.Emotion) {
+			t.Errorf("segment %d: %+v", i, seg)
+		}
+	}
+	if seg := uploaded.Segments[1]; seg.Start < 1.8 || seg.Start > 2.0 {
+		t.Errorf("second segment starts at %.2fs, want about 1.9s", seg.Start)
+	}
+
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(wav)
+	}))
+	defer files.Close()
+	fetcher := DefaultRemoteAudioFetcher()
+	fetcher.AllowPrivate = true // 测试服务器监听在回环地址
+	server.SetRemoteFetcher(fetcher)
+	rec = httptest.NewRecorder()
+	server.handleAnalyzeFile(rec, httptest.NewRequest(http.MethodPost, "/analyze-file", strings.NewReader(`{"url":"`+files.URL+`","lang":"zh"}`)))
+	var downloaded FileAnalysis
+	json.Unmarshal(rec.Body.Bytes(), &downloaded)
+	if len(downloaded.Segments) != len(uploaded.Segments) || !reflect.DeepEqual(downloaded.Segments[0], uploaded.Segments[0]) {
+		t.Errorf("URL分析结果与上传不一致: %s", rec.Body)
+	}
+
+	for _, reqBody := range []string{`{}`, `{"url":"ftp://example.com/cat.wav"}`} {
+		rec = httptest.NewRecorder()
+		server.handleAnalyzeFile(rec, httptest.NewRequest(http.MethodPost, "/analyze-file", strings.NewReader(reqBody)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", reqBody, rec.Code)
+		}
+	}
+	if _, err := decodeAudioFile([]byte("OggS....")); !errors.Is(err, ErrInvalidAudioFile) {
+		t.Errorf("decodeAudioFile(ogg) error = %v, want ErrInvalidAudioFile", err)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"math"
+
+	"soundsdk/dsp"
+)
+
+// 逐帧特征流水线
+//
+// 重叠窗口的策略（如 accurate，步进为窗口的一半）下，每个窗口有一半样本已在上一个窗口中处理过，
+// 但整窗提取（Extract）每次都从加窗后的样本重新分帧、重新计算自相关与频谱。开启
+// ExtractorOptions.FramePipeline 后，引擎按流中的绝对位置把样本切成固定的25ms帧，每帧的中间结果
+// （过零率、能量、自相关、幅度谱）只计算一次并缓存，窗口特征由窗口内各帧的中间结果汇总：
+//   ZeroCrossRate、Energy   窗口内各帧的平均值
+//   Pitch                   各帧自相关之和中基音周期处的峰值（帧的自相关需要帧后 maxLag 个样本，只汇总这部分也在窗口内的帧）
+//   PeakFreq                各帧幅度谱（逐帧加汉明窗）平均后在峰值频率范围内的峰值
+// 帧特征在未加窗的原始样本上计算，与样本库（整段原始样本）的口径一致，但与整窗模式的结果不同，
+// 切换该选项后识别结果会有变化。自定义特征仍在整个窗口上计算。窗口内没有完整的帧时退回整窗提取。
+
+// frameStats 一帧的中间结果
+type frameStats struct {
+	zcr      float64
+	energy   float64
+	spectrum []float64 // 幅度谱的前半部分
+	autocorr []float64 // 自相关，下标为 lag-minLag，帧后样本足够时才计算
+}
+
+// framePipeline 会话的帧缓存，键为帧在流中的序号（绝对样本位置 / 帧长）
+type framePipeline struct {
+	frames map[int64]*frameStats
+}
+
+// extractFrames 从缓存的帧中间结果汇总 buffer[:window] 的特征，offset 为 buffer[0] 在流中的绝对位置
+// 窗口内没有完整的帧时返回 false
+func (fe *FeatureExtractor) extractFrames(pipeline *framePipeline, buffer []float64, offset int64, window int) (map[string]float64, bool) {
+	size := int64(fe.frameSize)
+	first := (offset + size - 1) / size
+	end := (offset + int64(window)) / size // 窗口内最后一帧之后的帧序号
+	if size <= 0 || first >= end {
+		return nil, false
+	}
+
+	band := fe.band()
+	minLag := int(float64(fe.sampleRate) / band.PitchMax)
+	maxLag := int(float64(fe.sampleRate) / band.PitchMin)
+	if minLag < 1 {
+		minLag = 1
+	}
+
+	// 丢弃已在窗口之前的帧
+	if pipeline.frames == nil {
+		pipeline.frames = make(map[int64]*frameStats)
+	}
+	for index := range pipeline.frames {
+		if index < first {
+			delete(pipeline.frames, index)
+		}
+	}
+
+	var zcr, energy float64
+	var spectrum, autocorr []float64
+	pitchFrames := 0
+	for index := first; index < end; index++ {
+		start := int(index*size - offset)
+		frame := buffer[start : start+fe.frameSize]
+		stats, ok := pipeline.frames[index]
+		if !ok {
+			stats = fe.newFrameStats(frame)
+			pipeline.frames[index] = stats
+		}
+		zcr += stats.zcr
+		energy += stats.energy
+		if spectrum == nil {
+			spectrum = make([]float64, len(stats.spectrum))
+		}
+		for i, m := range stats.spectrum {
+			spectrum[i] += m
+		}
+
+		// 帧的自相关用到帧后 maxLag 个样本，超出窗口的帧不参与基频估计
+		if start+fe.frameSize+maxLag > window || maxLag < minLag {
+			continue
+		}
+		if stats.autocorr == nil {
+			stats.autocorr = frameAutocorrelation(buffer[start:start+fe.frameSize+maxLag], fe.frameSize, minLag, maxLag)
+		}
+		if autocorr == nil {
+			autocorr = make([]float64, len(stats.autocorr))
+		}
+		for i, c := range stats.autocorr {
+			autocorr[i] += c
+		}
+		pitchFrames++
+	}
+
+	count := float64(end - first)
+	features := map[string]float64{
+		"ZeroCrossRate": zcr / count,
+		"Energy":        energy / count,
+		"Pitch":         0,
+		"Duration":      float64(window) / float64(fe.sampleRate),
+		"PeakFreq":      fe.spectrumPeak(spectrum),
+	}
+	if pitchFrames > 0 {
+		if lag := autocorrPeakLag(autocorr); lag >= 0 {
+			features["Pitch"] = float64(fe.sampleRate) / float64(minLag+lag)
+		}
+	}
+	for name, v := range extractCustomFeatures(buffer[:window], fe.sampleRate) {
+		features[name] = v
+	}
+	return features, true
+}
+
+// newFrameStats 计算一帧的过零率、能量与幅度谱
+func (fe *FeatureExtractor) newFrameStats(frame []float64) *frameStats {
+	spectral := frame
+	if fe.options.PreEmphasis {
+		spectral = applyPreEmphasis(frame, fe.options.preEmphasisCoefficient())
+	}
+	windowed := make([]float64, len(spectral))
+	multiplyWindow(windowed, spectral, hammingWindow(len(spectral)))
+	fft := dsp.RealFFT(windowed)
+	magnitudes := make([]float64, len(fft)/2)
+	for i := range magnitudes {
+		magnitudes[i] = cmplxAbs(fft[i])
+	}
+	return &frameStats{
+		zcr:      fe.calculateZeroCrossRate(frame),
+		energy:   fe.calculateEnergy(frame),
+		spectrum: magnitudes,
+	}
+}
+
+// frameAutocorrelation 帧的自相关：lag 从 minLag 到 maxLag，对帧内每个样本与其后 lag 处的样本求积之和，
+// samples 为帧及其后 maxLag 个样本
+func frameAutocorrelation(samples []float64, frameSize, minLag, maxLag int) []float64 {
+	corr := make([]float64, maxLag-minLag+1)
+	for lag := minLag; lag <= maxLag; lag++ {
+		sum := 0.0
+		for i := 0; i < frameSize; i++ {
+			sum += samples[i] * samples[i+lag]
+		}
+		corr[lag-minLag] = sum
+	}
+	return corr
+}
+
+// framePitchTolerance 自相关峰值的容差：不低于最大值该比例的第一个局部极大值视为基音周期
+// 帧自相关没有整窗加窗带来的随 lag 衰减，周期整数倍处的峰值与基音周期处几乎相同，直接取最大值容易落在倍周期上
+const framePitchTolerance = 0.95
+
+// autocorrPeakLag 返回自相关中基音周期的下标，没有正的峰值时返回 -1
+func autocorrPeakLag(corr []float64) int {
+	maxCorr := 0.0
+	for _, c := range corr {
+		maxCorr = math.Max(maxCorr, c)
+	}
+	if maxCorr <= 0 {
+		return -1
+	}
+	for i, c := range corr {
+		if c < framePitchTolerance*maxCorr {
+			continue
+		}
+		if i+1 == len(corr) || c >= corr[i+1] {
+			return i
+		}
+	}
+	return -1
+}
+
+// spectrumPeak 幅度谱（前半部分）在峰值频率范围内的峰值频率
+func (fe *FeatureExtractor) spectrumPeak(magnitudes []float64) float64 {
+	if len(magnitudes) == 0 {
+		return 0
+	}
+	band := fe.band()
+	binHz := float64(fe.sampleRate) / float64(2*len(magnitudes))
+	peak, peakBin := 0.0, 0
+	for i, m := range magnitudes {
+		freq := float64(i) * binHz
+		if freq >= band.PeakMin && freq <= band.PeakMax && m > peak {
+			peak, peakBin = m, i
+		}
+	}
+	return float64(peakBin) * binHz
+}
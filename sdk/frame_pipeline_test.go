@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestFramePipelineReuse 测试逐帧流水线
+// 测试内容：
+// 1. 50%重叠的连续窗口复用缓存的帧，结果与不使用缓存逐窗口计算一致
+// 2. 窗口之前的帧被丢弃，缓存大小有界
+// 3. 纯音的基频与峰值频率落在纯音频率附近
+// 4. 窗口内没有完整的帧时返回 false
+func TestFramePipelineReuse(t *testing.T) {
+	const rate, window, hop = 44100, 4096, 2048
+	fe := NewFeatureExtractorWithOptions(rate, ExtractorOptions{FramePipeline: true})
+	samples := generateTestAudio(600, 1.0, rate)
+
+	var cached framePipeline
+	for offset := 0; offset+window <= len(samples); offset += hop {
+		buffer := samples[offset:]
+		got, ok := fe.extractFrames(&cached, buffer, int64(offset), window)
+		if !ok {
+			t.Fatalf("offset %d: 窗口内应有完整的帧", offset)
+		}
+		want, _ := fe.extractFrames(&framePipeline{}, buffer, int64(offset), window)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("offset %d: 复用帧的结果 %v 与重新计算 %v 不一致", offset, got, want)
+		}
+		if len(cached.frames) > window/fe.frameSize+1 {
+			t.Fatalf("offset %d: 缓存了 %d 帧，窗口之前的帧应被丢弃", offset, len(cached.frames))
+		}
+		if math.Abs(got["Pitch"]-600) > 15 || math.Abs(got["PeakFreq"]-600) > 25 {
+			t.Fatalf("offset %d: Pitch=%.1f PeakFreq=%.1f, want ≈600", offset, got["Pitch"], got["PeakFreq"])
+		}
+	}
+
+	if _, ok := fe.extractFrames(&framePipeline{}, samples, 100, fe.frameSize); ok {
+		t.Error("窗口内没有完整的帧时应返回 false")
+	}
+}
+
+// TestEngineFramePipeline 测试引擎使用逐帧流水线
+// 测试内容：
+// 1. accurate 策略下开启逐帧流水线后正常输出结果，特征来自帧汇总
+// 2. 切换频率预设后帧缓存被清空
+func TestEngineFramePipeline(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{
+		SampleRate: 44100,
+		BufferSize: 4096,
+		Strategy:   StrategyAccurate,
+		Extractor:  ExtractorOptions{FramePipeline: true},
+	})
+
+	result, err := engine.ProcessAudio("cat1", generateTestAudio(600, 0.5, 44100))
+	if err != nil {
+		t.Fatalf("ProcessAudio() error = %v", err)
+	}
+	var parsed AudioStreamResult
+	if err := json.Unmarshal(result, &parsed); err != nil || parsed.Emotion == "" {
+		t.Fatalf("应输出识别结果, got %s", result)
+	}
+	if pitch := parsed.Metadata.Features["Pitch"]; math.Abs(pitch-600) > 15 {
+		t.Errorf("Pitch = %.1f, want ≈600", pitch)
+	}
+
+	session := engine.sessions["cat1"]
+	if len(session.frames.frames) == 0 {
+		t.Fatal("应缓存帧的中间结果")
+	}
+	if err := engine.SetFrequencyPreset(session, FrequencyPresetKitten); err != nil {
+		t.Fatalf("SetFrequencyPreset() error = %v", err)
+	}
+	if len(session.frames.frames) != 0 {
+		t.Error("切换频率预设后帧缓存应清空")
+	}
+}
+
+// BenchmarkFramePipeline 逐帧流水线性能基准测试
+// 测试内容：
+// 1. 50%重叠的连续窗口上，逐帧流水线与整窗提取每个窗口的耗时
+func BenchmarkFramePipeline(b *testing.B) {
+	const rate, window, hop = 44100, 4096, 2048
+	samples := generateTestAudio(600, 2.0, rate)
+	b.Run("frames", func(b *testing.B) {
+		fe := NewFeatureExtractorWithOptions(rate, ExtractorOptions{FramePipeline: true})
+		var pipeline framePipeline
+		offset := 0
+		for i := 0; i < b.N; i++ {
+			if offset+window > len(samples) {
+				offset, pipeline = 0, framePipeline{}
+			}
+			fe.extractFrames(&pipeline, samples[offset:], int64(offset), window)
+			offset += hop
+		}
+	})
+	b.Run("window", func(b *testing.B) {
+		fe := NewFeatureExtractor(rate)
+		offset := 0
+		for i := 0; i < b.N; i++ {
+			if offset+window > len(samples) {
+				offset = 0
+			}
+			fe.Extract(&AudioData{Samples: applyHammingWindow(samples[offset : offset+window]), SampleRate: rate})
+			offset += hop
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// FrequencyRange 猫叫声相关的频率范围配置
+// 基频搜索、峰值频率搜索和特征验证都基于同一组范围，保证各处口径一致
+type FrequencyRange struct {
+	Name          string  `json:"name"`          // 预设名称
+	PitchMin      float64 `json:"pitchMin"`      // 基频搜索下限 (Hz)
+	PitchMax      float64 `json:"pitchMax"`      // 基频搜索上限 (Hz)
+	PeakMin       float64 `json:"peakMin"`       // 峰值频率搜索下限 (Hz)
+	PeakMax       float64 `json:"peakMax"`       // 峰值频率搜索上限 (Hz)
+	ValidPitchMax float64 `json:"validPitchMax"` // 特征验证时允许的最高音高 (Hz)
+}
+
+// 频率预设名称
+const (
+	FrequencyPresetKitten     = "kitten"
+	FrequencyPresetAdult      = "adult"
+	FrequencyPresetLargeBreed = "large-breed"
+
+	DefaultFrequencyPreset = FrequencyPresetAdult
+)
+
+// frequencyPresets 内置猫咪配置的频率预设
+// adult 为 70-1000Hz（基频）/ 70-2000Hz（峰值），
+// 比引擎原有的范围窄，引擎未指定预设时见 engineDefaultBand
+var frequencyPresets = map[string]FrequencyRange{
+	FrequencyPresetKitten: {
+		Name:          FrequencyPresetKitten,
+		PitchMin:      200,
+		PitchMax:      1800,
+		PeakMin:       200,
+		PeakMax:       3500,
+		ValidPitchMax: 2000,
+	},
+	FrequencyPresetAdult: {
+		Name:          FrequencyPresetAdult,
+		PitchMin:      70,
+		PitchMax:      1000,
+		PeakMin:       70,
+		PeakMax:       2000,
+		ValidPitchMax: 1500,
+	},
+	FrequencyPresetLargeBreed: {
+		Name:          FrequencyPresetLargeBreed,
+		PitchMin:      50,
+		PitchMax:      800,
+		PeakMin:       50,
+		PeakMax:       1500,
+		ValidPitchMax: 1200,
+	},
+}
+
+// engineDefaultBand 引擎特征提取器原有的频率范围：基频 70-2000Hz，峰值频率不限
+// 使用内置猫咪配置且未指定预设时沿用，默认配置下的特征值不因引入预设而改变
+var engineDefaultBand = FrequencyRange{
+	Name:          "default",
+	PitchMin:      70,
+	PitchMax:      2000,
+	PeakMin:       0,
+	PeakMax:       math.Inf(1),
+	ValidPitchMax: 2000,
+}
+
+// LookupFrequencyPreset 在当前领域配置中按名称查找频率预设，名称为空时返回默认预设
+func LookupFrequencyPreset(name string) (FrequencyRange, error) {
+	return CurrentDomainProfile().lookupPreset(name)
+}
+
+// lookupPreset 在该领域配置中按名称查找频率预设，名称为空时返回默认预设
+func (p *DomainProfile) lookupPreset(name string) (FrequencyRange, error) {
+	if name == "" {
+		name = p.DefaultPreset
+	}
+	preset, ok := p.FrequencyPresets[name]
+	if !ok {
+		return FrequencyRange{}, fmt.Errorf("unknown frequency preset: %s (available: %v)", name, p.presetNames())
+	}
+	return preset, nil
+}
+
+// checkPreset 检查预设名称在该领域配置中存在，nil 表示内置猫咪配置
+func (p *DomainProfile) checkPreset(name string) error {
+	if p == nil {
+		p = defaultProfile
+	}
+	_, err := p.lookupPreset(name)
+	return err
+}
+
+// FrequencyPresetNames 返回当前领域配置中的所有预设名称
+func FrequencyPresetNames() []string {
+	return CurrentDomainProfile().presetNames()
+}
+
+// presetNames 返回该领域配置中的所有预设名称
+func (p *DomainProfile) presetNames() []string {
+	presets := p.FrequencyPresets
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// frequencyRange 返回配置中生效的频率范围，未知预设回退到默认预设
+func (o ExtractorOptions) frequencyRange() FrequencyRange {
+	preset, err := LookupFrequencyPreset(o.FrequencyPreset)
+	if err != nil {
+		profile := CurrentDomainProfile()
+		return profile.FrequencyPresets[profile.DefaultPreset]
+	}
+	return preset
+}
+
+// band 返回特征提取器生效的频率范围，内置猫咪配置下未指定预设时为 engineDefaultBand
+func (fe *FeatureExtractor) band() FrequencyRange {
+	if fe.options.FrequencyPreset == "" && CurrentDomainProfile() == defaultProfile {
+		return engineDefaultBand
+	}
+	return fe.options.frequencyRange()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestLookupFrequencyPreset 测试频率预设查找
+// 测试内容：
+// 1. 空名称返回默认预设（成年猫）
+// 2. 内置预设均可查找
+// 3. 未知预设返回错误
+func TestLookupFrequencyPreset(t *testing.T) {
+	preset, err := LookupFrequencyPreset("")
+	if err != nil || preset.Name != FrequencyPresetAdult {
+		t.Fatalf("LookupFrequencyPreset(\"\") = %v, %v; want adult preset", preset.Name, err)
+	}
+	if preset.PitchMin != 70 || preset.PitchMax != 1000 || preset.PeakMax != 2000 {
+		t.Errorf("adult preset changed legacy bands: %+v", preset)
+	}
+
+	for _, name := range FrequencyPresetNames() {
+		p, err := LookupFrequencyPreset(name)
+		if err != nil {
+			t.Errorf("LookupFrequencyPreset(%q) error = %v", name, err)
+		}
+		if p.PitchMin >= p.PitchMax || p.PeakMin >= p.PeakMax {
+			t.Errorf("preset %q has invalid range: %+v", name, p)
+		}
+	}
+
+	if _, err := LookupFrequencyPreset("hamster"); err == nil {
+		t.Error("LookupFrequencyPreset(\"hamster\") expected error")
+	}
+
+	kitten := ExtractorOptions{FrequencyPreset: FrequencyPresetKitten}.frequencyRange()
+	adult := ExtractorOptions{}.frequencyRange()
+	if kitten.PitchMin <= adult.PitchMin {
+		t.Errorf("kitten pitch floor %.0f should be above adult %.0f", kitten.PitchMin, adult.PitchMin)
+	}
+}
+
+// TestEngineDefaultBand 测试引擎特征提取器未指定预设时的频率范围
+// 测试内容：
+// 1. 内置猫咪配置下未指定预设沿用引擎原有的范围：基频 70-2000Hz，峰值频率不限
+// 2. 显式指定成年猫预设、或使用自定义领域配置时按预设查找
+func TestEngineDefaultBand(t *testing.T) {
+	band := NewFeatureExtractorWithOptions(44100, ExtractorOptions{}).band()
+	if band.PitchMin != 70 || band.PitchMax != 2000 || band.PeakMin != 0 || !math.IsInf(band.PeakMax, 1) {
+		t.Errorf("default band = %+v, want 70-2000Hz pitch and unbounded peak", band)
+	}
+
+	adult := NewFeatureExtractorWithOptions(44100, ExtractorOptions{FrequencyPreset: FrequencyPresetAdult}).band()
+	if adult.Name != FrequencyPresetAdult || adult.PitchMax != 1000 {
+		t.Errorf("adult band = %+v", adult)
+	}
+
+	profile := DefaultDomainProfile()
+	profile.Name = "dog"
+	if err := SetDomainProfile(profile); err != nil {
+		t.Fatal(err)
+	}
+	defer SetDomainProfile(nil)
+	if band := NewFeatureExtractorWithOptions(44100, ExtractorOptions{}).band(); band.Name != profile.DefaultPreset {
+		t.Errorf("custom profile band = %+v, want profile default preset", band)
+	}
+}
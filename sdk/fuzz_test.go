@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// buildWAV 生成16位单声道PCM的WAV数据，用作模糊测试种子
+func buildWAV(samples []int16, sampleRate int) []byte {
+	var buf bytes.Buffer
+	dataSize := len(samples) * 2
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // 单声道
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+// FuzzDecodePCM16 模糊测试 SendAudioChunk 使用的PCM字节解析
+func FuzzDecodePCM16(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x01})
+	f.Add([]byte{0x00, 0x80, 0xff, 0x7f})
+
+	f.Fuzz(func(t *testing.T, chunk []byte) {
+		samples, err := decodePCM16(chunk)
+		if err != nil {
+			if len(chunk) != 0 && len(chunk)%2 == 0 {
+				t.Fatalf("unexpected error for %d bytes: %v", len(chunk), err)
+			}
+			return
+		}
+		if len(samples) != len(chunk)/2 {
+			t.Fatalf("got %d samples from %d bytes", len(samples), len(chunk))
+		}
+		for _, v := range samples {
+			if v < -1 || v >= 1 {
+				t.Fatalf("sample %v out of range", v)
+			}
+		}
+	})
+}
+
+// FuzzDecodeWAV 模糊测试WAV解码，损坏或截断的文件只能返回错误
+func FuzzDecodeWAV(f *testing.F) {
+	valid := buildWAV([]int16{0, 1000, -1000, 32767, -32768}, 44100)
+	f.Add(valid)
+	f.Add(valid[:20])
+	f.Add(valid[:44])
+	f.Add([]byte("RIFF\xff\xff\xff\xffWAVEdata\xff\xff\xff\x7f"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		samples, err := decodeWAV(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		if len(samples) > MaxWAVSamples {
+			t.Fatalf("decoded %d samples, limit %d", len(samples), MaxWAVSamples)
+		}
+	})
+}
+
+// FuzzDecodeSendAudioRequest 模糊测试 /api/send 请求体解析
+func FuzzDecodeSendAudioRequest(f *testing.F) {
+	f.Add(`{"streamId":"cat1","data":[0.1,-0.2,0.3]}`)
+	f.Add(`{"streamId":"cat1","data":["0.5","NaN","Inf"]}`)
+	f.Add(`{"streamId":"cat1","data":{"0":1}}`)
+	f.Add(`{"streamId":"cat1","data":[1e308,-1e308,null,true]}`)
+	f.Add(`[`)
+
+	f.Fuzz(func(t *testing.T, body string) {
+		_, samples, err := decodeSendAudioRequest(strings.NewReader(body))
+		if err != nil {
+			return
+		}
+		if len(samples) > MaxSendSamples {
+			t.Fatalf("decoded %d samples, limit %d", len(samples), MaxSendSamples)
+		}
+		for _, v := range samples {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Fatalf("non-finite sample %v accepted", v)
+			}
+		}
+	})
+}
+
+// TestDecodeHardening 测试输入解析的边界情况
+// 测试内容：
+// 1. WAV：有效文件正确归一化，截断头部返回错误
+// 2. PCM：奇数长度返回错误
+// 3. JSON：NaN样本与超长数组被拒绝
+func TestDecodeHardening(t *testing.T) {
+	samples, err := decodeWAV(bytes.NewReader(buildWAV([]int16{16384, -32768}, 16000)))
+	if err != nil || len(samples) != 2 || samples[0] != 0.5 || samples[1] != -1 {
+		t.Errorf("decodeWAV(valid) = %v, %v", samples, err)
+	}
+	if _, err := decodeWAV(bytes.NewReader([]byte("RIFF\x00"))); !errors.Is(err, ErrInvalidAudioFile) {
+		t.Errorf("decodeWAV(truncated) error = %v, want ErrInvalidAudioFile", err)
+	}
+
+	if _, err := decodePCM16([]byte{1, 2, 3}); !errors.Is(err, ErrInvalidDataLength) {
+		t.Errorf("decodePCM16(odd) error = %v", err)
+	}
+
+	if _, _, err := decodeSendAudioRequest(strings.NewReader(`{"data":["NaN"]}`)); !errors.Is(err, ErrInvalidSample) {
+		t.Errorf("NaN sample error = %v, want ErrInvalidSample", err)
+	}
+	huge := "[" + strings.Repeat("0,", MaxSendSamples) + "0]"
+	if _, _, err := decodeSendAudioRequest(strings.NewReader(`{"data":` + huge + `}`)); !errors.Is(err, ErrAudioTooLong) {
+		t.Errorf("huge array error = %v, want ErrAudioTooLong", err)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// 频带预筛选
+//
+// 监听时大部分窗口是环境噪声（人声、电视、家电），每个窗口都做完整的特征提取（FFT、自相关求音高）
+// 代价较高。Goertzel 算法只计算少数几个频点的能量，开销与频点数成正比，远低于完整FFT。
+// 开启 AudioStreamConfig.PreFilter 后，引擎先用 Goertzel 检测各目标频带（默认为呼噜声频带与叫声/
+// 颤音频带）的能量占窗口总能量的比例，所有频带都低于 MinRelativePower 的窗口直接跳过，不产生结果。
+//
+// 单个频点的带宽约为 采样率/块长，频带内的频点按 BandwidthHz 等间隔放置，并按该带宽选择块长，
+// 使频带内任意频率的纯音都能被相邻频点捕获。
+
+// 预筛选参数
+const (
+	DefaultPreFilterMinPower = 0.1  // 默认的频带能量占比下限
+	preFilterSilence         = 1e-8 // 窗口均方能量低于该值视为静音，直接跳过
+)
+
+// DetectorBand Goertzel 检测的一个频带
+type DetectorBand struct {
+	Name        string    `json:"name"`
+	Frequencies []float64 `json:"frequencies"` // 检测的频点（Hz），通常在频带内按 BandwidthHz 等间隔放置
+	BandwidthHz float64   `json:"bandwidthHz"` // 每个频点覆盖的带宽（Hz），决定Goertzel的块长
+}
+
+// BandPreFilter 频带预筛选配置
+type BandPreFilter struct {
+	Bands            []DetectorBand `json:"bands"`            // 为空时使用 DefaultDetectorBands
+	MinRelativePower float64        `json:"minRelativePower"` // 任一频带能量占窗口能量的比例达到该值时才分析窗口，为0时使用默认值
+}
+
+// DefaultDetectorBands 默认频带：呼噜声（25~150Hz）与叫声、颤音（300~1500Hz）
+func DefaultDetectorBands() []DetectorBand {
+	return []DetectorBand{
+		{Name: "purr", Frequencies: []float64{25, 50, 75, 100, 125, 150}, BandwidthHz: 25},
+		{Name: "trill", Frequencies: []float64{300, 400, 500, 600, 700, 800, 900, 1000, 1100, 1200, 1300, 1400, 1500}, BandwidthHz: 100},
+	}
+}
+
+// Validate 校验预筛选配置
+func (f *BandPreFilter) Validate(sampleRate int) error {
+	if f.MinRelativePower < 0 || f.MinRelativePower > 1 {
+		return fmt.Errorf("prefilter: minRelativePower %.2f out of range [0, 1]", f.MinRelativePower)
+	}
+	for _, band := range f.bands() {
+		if len(band.Frequencies) == 0 || band.BandwidthHz <= 0 {
+			return fmt.Errorf("prefilter band %q: frequencies and a positive bandwidthHz are required", band.Name)
+		}
+		for _, freq := range band.Frequencies {
+			if freq <= 0 || freq >= float64(sampleRate)/2 {
+				return fmt.Errorf("prefilter band %q: frequency %.0fHz must be between 0 and the Nyquist frequency", band.Name, freq)
+			}
+		}
+	}
+	return nil
+}
+
+// bands 生效的频带
+func (f *BandPreFilter) bands() []DetectorBand {
+	if len(f.Bands) == 0 {
+		return DefaultDetectorBands()
+	}
+	return f.Bands
+}
+
+// minPower 生效的能量占比下限
+func (f *BandPreFilter) minPower() float64 {
+	if f.MinRelativePower <= 0 {
+		return DefaultPreFilterMinPower
+	}
+	return f.MinRelativePower
+}
+
+// goertzelPower 用 Goertzel 算法计算样本在 freq 处的功率，按 2|X|²/N² 归一化，
+// 与样本均方值同量纲：幅度为 A 的纯音在其频率处的功率为 A²/2
+func goertzelPower(samples []float64, sampleRate int, freq float64) float64 {
+	n := len(samples)
+	if n == 0 {
+		return 0
+	}
+	coeff := 2 * math.Cos(2*math.Pi*freq/float64(sampleRate))
+	var s1, s2 float64
+	for _, x := range samples {
+		s0 := x + coeff*s1 - s2
+		s2, s1 = s1, s0
+	}
+	power := s1*s1 + s2*s2 - coeff*s1*s2
+	return 2 * power / float64(n*n)
+}
+
+// BandPower 频带能量占样本均方能量的比例（0~1）：样本按频带带宽分块，各块内频点功率之和取平均
+func (band DetectorBand) BandPower(samples []float64, sampleRate int) float64 {
+	energy := 0.0
+	for _, x := range samples {
+		energy += x * x
+	}
+	energy /= float64(len(samples))
+	if energy < preFilterSilence {
+		return 0
+	}
+
+	block := int(math.Round(float64(sampleRate) / band.BandwidthHz))
+	if block <= 0 || block > len(samples) {
+		block = len(samples)
+	}
+	total, blocks := 0.0, 0
+	for start := 0; start+block <= len(samples); start += block {
+		for _, freq := range band.Frequencies {
+			total += goertzelPower(samples[start:start+block], sampleRate, freq)
+		}
+		blocks++
+	}
+	return math.Min(1, total/float64(blocks)/energy)
+}
+
+// Accept 窗口中是否有目标频带的内容，返回各频带中最大的能量占比
+func (f *BandPreFilter) Accept(samples []float64, sampleRate int) (bool, float64) {
+	if len(samples) == 0 {
+		return false, 0
+	}
+	best := 0.0
+	for _, band := range f.bands() {
+		best = math.Max(best, band.BandPower(samples, sampleRate))
+	}
+	return best >= f.minPower(), best
+}
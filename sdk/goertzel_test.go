@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestDetectorBandPower 测试 Goertzel 频带能量
+// 测试内容：
+// 1. 频带内（包括两个频点之间）的纯音能量占比接近1
+// 2. 频带外的纯音与白噪声的能量占比很低
+// 3. 静音窗口的能量占比为0
+func TestDetectorBandPower(t *testing.T) {
+	const rate = 44100
+	trill := DefaultDetectorBands()[1]
+
+	for _, freq := range []float64{600, 650, 1230} {
+		if p := trill.BandPower(generateTestAudio(freq, 0.1, rate), rate); p < 0.5 {
+			t.Errorf("%.0fHz 纯音的颤音频带能量占比 = %.3f, 应接近1", freq, p)
+		}
+	}
+	if p := trill.BandPower(generateTestAudio(5000, 0.1, rate), rate); p > 0.05 {
+		t.Errorf("5000Hz 纯音的颤音频带能量占比 = %.3f, 应很低", p)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]float64, 4096)
+	for i := range noise {
+		noise[i] = rng.Float64()*2 - 1
+	}
+	if p := trill.BandPower(noise, rate); p > DefaultPreFilterMinPower {
+		t.Errorf("白噪声的颤音频带能量占比 = %.3f, 应低于 %.2f", p, DefaultPreFilterMinPower)
+	}
+	if p := trill.BandPower(make([]float64, 4096), rate); p != 0 {
+		t.Errorf("静音窗口的能量占比 = %.3f, want 0", p)
+	}
+}
+
+// TestBandPreFilterValidate 测试预筛选配置校验
+// 测试内容：
+// 1. 默认配置合法
+// 2. 能量占比超出范围、频带缺少频点或带宽、频点超过奈奎斯特频率时报错，引擎无法创建
+func TestBandPreFilterValidate(t *testing.T) {
+	if err := (&BandPreFilter{}).Validate(44100); err != nil {
+		t.Errorf("默认配置应合法: %v", err)
+	}
+	invalid := []BandPreFilter{
+		{MinRelativePower: 1.5},
+		{Bands: []DetectorBand{{Name: "empty", BandwidthHz: 50}}},
+		{Bands: []DetectorBand{{Name: "nobw", Frequencies: []float64{100}}}},
+		{Bands: []DetectorBand{{Name: "high", Frequencies: []float64{30000}, BandwidthHz: 100}}},
+	}
+	for _, filter := range invalid {
+		if err := filter.Validate(44100); err == nil {
+			t.Errorf("%+v 应校验失败", filter)
+		}
+	}
+
+	_, err := LoadEngine(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, PreFilter: &invalid[0]})
+	if err == nil {
+		t.Error("预筛选配置非法时不应创建引擎")
+	}
+}
+
+// TestEnginePreFilter 测试引擎的频带预筛选
+// 测试内容：
+// 1. 静音与高频纯音窗口被跳过，返回 waiting，跳过的窗口计入 windowsSkipped
+// 2. 频带内的纯音窗口正常分析
+func TestEnginePreFilter(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, PreFilter: &BandPreFilter{}})
+
+	result, err := engine.ProcessAudio("cat1", make([]float64, 4096))
+	if err != nil {
+		t.Fatalf("ProcessAudio() error = %v", err)
+	}
+	if !strings.Contains(string(result), `"status":"waiting"`) {
+		t.Errorf("静音窗口应被跳过, got %s", result)
+	}
+	result, err = engine.ProcessAudio("cat1", generateTestAudio(8000, 4096.0/44100, 44100))
+	if err != nil {
+		t.Fatalf("ProcessAudio() error = %v", err)
+	}
+	if !strings.Contains(string(result), `"status":"waiting"`) {
+		t.Errorf("高频纯音窗口应被跳过, got %s", result)
+	}
+
+	result, err = engine.ProcessAudio("cat1", generateTestAudio(600, 0.2, 44100))
+	if err != nil {
+		t.Fatalf("ProcessAudio() error = %v", err)
+	}
+	var parsed AudioStreamResult
+	if err := json.Unmarshal(result, &parsed); err != nil || parsed.Emotion == "" {
+		t.Errorf("频带内的纯音应正常分析, got %s", result)
+	}
+
+	engine.mu.Lock()
+	stats := sessionStats(engine.sessions["cat1"], false)
+	engine.mu.Unlock()
+	if stats.WindowsSkipped < 2 {
+		t.Errorf("windowsSkipped = %d, want >= 2", stats.WindowsSkipped)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// 路由挂载：Handler 返回挂载了全部接口的 http.Handler，可去掉路径前缀、添加CORS，服务程序把它挂载在 /api 下。
+// SDK 是带 CGO 导出的 main 包，其他 Go 模块无法导入 AudioServer；已有后端要接入时以反向代理转发到本服务。
+
+// HandlerOptions Handler 的选项
+type HandlerOptions struct {
+	Prefix string // 挂载的路径前缀，如 /meowtalk，此时接口为 /meowtalk/send 等；为空时挂载在根路径
+	CORS   bool   // 是否添加跨域响应头并应答预检请求，外层已有CORS中间件时不需要
+}
+
+// registerRoutes 在 mux 上注册 /init /start /send /recv /stop /pause /resume /events /emotions /video /timeline
+// /baseline /reports /export /analyze-file /jobs /library/stats /admin /ws /dashboard
+func (s *AudioServer) registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/init", s.handleInit)
+	mux.HandleFunc("/start", s.handleStart)
+	mux.HandleFunc("/send", s.handleSend)
+	mux.HandleFunc("/recv", s.handleReceive)
+	mux.HandleFunc("/stop", s.handleStop)
+	mux.HandleFunc("/pause", s.handlePause)
+	mux.HandleFunc("/resume", s.handleResume)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/emotions", s.handleEmotions)
+	mux.HandleFunc("/video", s.handleVideo)
+	mux.HandleFunc("/timeline", s.handleTimeline)
+	mux.HandleFunc("/baseline", s.handleBaseline)
+	mux.HandleFunc("/reports", s.handleReports)
+	mux.HandleFunc("/export", s.handleExport)
+	mux.HandleFunc("/analyze-file", s.handleAnalyzeFile)
+	mux.HandleFunc("/jobs", s.handleSubmitJob)
+	mux.HandleFunc("/jobs/", s.handleGetJob)
+	mux.HandleFunc("/library/stats", s.handleLibraryStats)
+	mux.HandleFunc("/admin/library/rebuild", s.handleRebuildLibrary)
+	mux.HandleFunc("/admin/reload", s.handleReload)
+	mux.HandleFunc("/admin/labeling/clips", s.handleLabeling)
+	mux.HandleFunc("/admin/labeling/clips/", s.handleLabeling)
+	mux.HandleFunc("/admin/energy", s.handleEnergyCalibration)
+
+	// 添加WebSocket支持
+	mux.HandleFunc("/ws", s.handleWebSocket)
+
+	// 调试面板
+	mux.HandleFunc("/dashboard", s.handleDashboard)
+	mux.HandleFunc("/dashboard/", s.handleDashboard)
+}
+
+// Handler 返回挂载了全部接口的 http.Handler，同一个服务可以多次调用
+func (s *AudioServer) Handler(opts HandlerOptions) http.Handler {
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+
+	var handler http.Handler = mux
+	if prefix := strings.TrimSuffix(opts.Prefix, "/"); prefix != "" {
+		handler = http.StripPrefix(prefix, handler)
+	}
+	if opts.CORS {
+		handler = corsMiddleware(handler)
+	}
+	return handler
+}
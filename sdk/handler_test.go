@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestEmbeddedHandler 测试挂载在子路径下的 Handler
+// 测试内容：
+// 1. 挂载在外层路由的子路径下，设置 Prefix 后接口与调试面板在该路径下可用
+// 2. CORS 选项控制跨域响应头
+// 3. 同一个服务可以得到多个 Handler
+// 4. 视频关联、标注与能量校准等后加的接口同样注册在 Handler 中
+func TestEmbeddedHandler(t *testing.T) {
+	server := NewAudioServer(NewMockAudioProcessor())
+	host := http.NewServeMux()
+	host.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	host.Handle("/meowtalk/", server.Handler(HandlerOptions{Prefix: "/meowtalk/"}))
+
+	get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	if rec := get(host, "/meowtalk/emotions"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "emotions") {
+		t.Errorf("/meowtalk/emotions = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := get(host, "/meowtalk/dashboard/state"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "sessions") {
+		t.Errorf("/meowtalk/dashboard/state = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := get(host, "/meowtalk/dashboard"); rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("/meowtalk/dashboard = %d, CORS %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if rec := get(host, "/emotions"); rec.Code != http.StatusNotFound {
+		t.Errorf("/emotions outside the prefix = %d, want 404", rec.Code)
+	}
+	if rec := get(host, "/health"); rec.Body.String() != "ok" {
+		t.Errorf("host route = %s", rec.Body.String())
+	}
+
+	for _, path := range []string{"/meowtalk/video", "/meowtalk/admin/labeling/clips", "/meowtalk/admin/labeling/clips/x", "/meowtalk/admin/energy"} {
+		if rec := get(host, path); rec.Code == http.StatusNotFound && !strings.Contains(rec.Body.String(), "error") {
+			t.Errorf("%s is not registered: %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	root := server.Handler(HandlerOptions{CORS: true})
+	if rec := get(root, "/emotions"); rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("root /emotions = %d, CORS %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 结果钩子表达式
+//
+// 结果钩子（result_hooks.go）的条件与取值使用一个很小的表达式语言，不依赖外部脚本引擎：
+//   字面量：数字 0.8、字符串 "hungry"、true/false
+//   变量：emotion、confidence、hour 等，带点的名称如 features.Pitch、recent.anxious（见 hookEnv）
+//   运算：! - * / + - < <= > >= == != && ||，括号分组；&& 与 || 短路求值
+// 数字统一为 float64；类型不匹配（如字符串与数字比较）在求值时报错，该条规则跳过。
+
+// hookValue 表达式的值：float64、string 或 bool
+type hookValue interface{}
+
+// hookEnv 表达式求值时的变量，未定义的变量报错，带点的名称按前缀查找（见 lookup）
+type hookEnv struct {
+	vars   map[string]hookValue
+	groups map[string]map[string]float64 // features.X、recent.X 等按前缀分组的数值，组内缺少的名称为0
+}
+
+// lookup 查找变量
+func (env hookEnv) lookup(name string) (hookValue, error) {
+	if v, ok := env.vars[name]; ok {
+		return v, nil
+	}
+	if prefix, key, ok := strings.Cut(name, "."); ok {
+		if group, ok := env.groups[prefix]; ok {
+			return group[key], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown variable %q", name)
+}
+
+// hookExpr 编译后的表达式
+type hookExpr func(env hookEnv) (hookValue, error)
+
+// hookToken 词法单元
+type hookToken struct {
+	kind  byte // 'n' 数字, 's' 字符串, 'i' 标识符, 'o' 运算符或括号
+	text  string
+	value hookValue
+}
+
+// tokenizeHookExpr 将表达式拆分为词法单元
+func tokenizeHookExpr(src string) ([]hookToken, error) {
+	var tokens []hookToken
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			v, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", src[i:j])
+			}
+			tokens = append(tokens, hookToken{kind: 'n', text: src[i:j], value: v})
+			i = j
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", src[i:j+1])
+			}
+			tokens = append(tokens, hookToken{kind: 's', text: src[i : j+1], value: s})
+			i = j + 1
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, hookToken{kind: 'i', text: src[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "(", ")"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+			tokens = append(tokens, hookToken{kind: 'o', text: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// hookParser 递归下降解析器，优先级从低到高：|| && 比较 加减 乘除 一元
+type hookParser struct {
+	tokens []hookToken
+	pos    int
+}
+
+// compileHookExpr 编译表达式
+func compileHookExpr(src string) (hookExpr, error) {
+	tokens, err := tokenizeHookExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	p := &hookParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return expr, nil
+}
+
+// accept 下一个词法单元是给定运算符之一时消费并返回它
+func (p *hookParser) accept(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != 'o' {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *hookParser) parseOr() (hookExpr, error) {
+	left, err := p.parseAnd()
+	for err == nil {
+		if _, ok := p.accept("||"); !ok {
+			return left, nil
+		}
+		var right hookExpr
+		if right, err = p.parseAnd(); err == nil {
+			left = logicalExpr(left, right, true)
+		}
+	}
+	return nil, err
+}
+
+func (p *hookParser) parseAnd() (hookExpr, error) {
+	left, err := p.parseComparison()
+	for err == nil {
+		if _, ok := p.accept("&&"); !ok {
+			return left, nil
+		}
+		var right hookExpr
+		if right, err = p.parseComparison(); err == nil {
+			left = logicalExpr(left, right, false)
+		}
+	}
+	return nil, err
+}
+
+func (p *hookParser) parseComparison() (hookExpr, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return binaryExpr(op, left, right), nil
+}
+
+func (p *hookParser) parseSum() (hookExpr, error) {
+	left, err := p.parseProduct()
+	for err == nil {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		var right hookExpr
+		if right, err = p.parseProduct(); err == nil {
+			left = binaryExpr(op, left, right)
+		}
+	}
+	return nil, err
+}
+
+func (p *hookParser) parseProduct() (hookExpr, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		var right hookExpr
+		if right, err = p.parseUnary(); err == nil {
+			left = binaryExpr(op, left, right)
+		}
+	}
+	return nil, err
+}
+
+func (p *hookParser) parseUnary() (hookExpr, error) {
+	op, ok := p.accept("!", "-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return func(env hookEnv) (hookValue, error) {
+		v, err := operand(env)
+		if err != nil {
+			return nil, err
+		}
+		if op == "!" {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("! needs a bool, got %v", v)
+			}
+			return !b, nil
+		}
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("- needs a number, got %v", v)
+		}
+		return -f, nil
+	}, nil
+}
+
+func (p *hookParser) parsePrimary() (hookExpr, error) {
+	if _, ok := p.accept("("); ok {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case 'n', 's':
+		value := token.value
+		return func(hookEnv) (hookValue, error) { return value, nil }, nil
+	case 'i':
+		switch token.text {
+		case "true", "false":
+			value := token.text == "true"
+			return func(hookEnv) (hookValue, error) { return value, nil }, nil
+		}
+		name := token.text
+		return func(env hookEnv) (hookValue, error) { return env.lookup(name) }, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token.text)
+}
+
+// logicalExpr && 与 ||，短路求值
+func logicalExpr(left, right hookExpr, or bool) hookExpr {
+	return func(env hookEnv) (hookValue, error) {
+		for _, operand := range []hookExpr{left, right} {
+			v, err := operand(env)
+			if err != nil {
+				return nil, err
+			}
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("logical operator needs bools, got %v", v)
+			}
+			if b == or {
+				return b, nil
+			}
+		}
+		return !or, nil
+	}
+}
+
+// binaryExpr 比较与算术运算，== 与 != 可比较任意同类型的值，其余运算只接受数字
+func binaryExpr(op string, left, right hookExpr) hookExpr {
+	return func(env hookEnv) (hookValue, error) {
+		a, err := left(env)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right(env)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "==", "!=":
+			if fmt.Sprintf("%T", a) != fmt.Sprintf("%T", b) {
+				return nil, fmt.Errorf("cannot compare %v and %v", a, b)
+			}
+			return (a == b) == (op == "=="), nil
+		}
+
+		x, ok1 := a.(float64)
+		y, ok2 := b.(float64)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s needs numbers, got %v and %v", op, a, b)
+		}
+		switch op {
+		case "<":
+			return x < y, nil
+		case "<=":
+			return x <= y, nil
+		case ">":
+			return x > y, nil
+		case ">=":
+			return x >= y, nil
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		default:
+			if y == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return x / y, nil
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 本地化
+//
+// 情感名称和提示短语按语言存放在 locales/<lang>.json 中并随SDK一起编译，
+// 请求或开始流时携带 lang 参数即可直接返回对应语言的结果，客户端无需维护翻译表。
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// DefaultLocale 默认语言，未知语言回退到该语言
+const DefaultLocale = "en"
+
+// LocaleCatalog 单个语言的翻译目录
+type LocaleCatalog struct {
+	Lang        string            `json:"lang"`        // 语言代码，如 en / zh
+	Domain      string            `json:"domain"`      // 适用的领域配置名称，与 DomainProfile.Name 对应
+	DefaultName string            `json:"defaultName"` // 未设置猫咪名字时模板中使用的称呼
+	Emotions    map[string]string `json:"emotions"`    // 情感ID -> 显示名称
+	Phrases     []PhraseRule      `json:"phrases"`     // 本地化短语目录，为空时使用领域配置中的短语
+}
+
+var (
+	locales     map[string]*LocaleCatalog
+	localesOnce sync.Once
+)
+
+// loadLocales 加载内置的全部语言目录
+func loadLocales() {
+	locales = make(map[string]*LocaleCatalog)
+
+	files, err := localeFS.ReadDir("locales")
+	if err != nil {
+		log.Printf("读取语言目录失败: %v", err)
+		return
+	}
+
+	for _, f := range files {
+		catalog, err := parseLocaleCatalog(path.Join("locales", f.Name()))
+		if err != nil {
+			log.Printf("加载语言文件 %s 失败: %v", f.Name(), err)
+			continue
+		}
+		locales[catalog.Lang] = catalog
+	}
+}
+
+// parseLocaleCatalog 解析并校验单个语言文件
+func parseLocaleCatalog(name string) (*LocaleCatalog, error) {
+	data, err := localeFS.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var catalog LocaleCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("parse locale: %v", err)
+	}
+	if catalog.Lang == "" {
+		return nil, fmt.Errorf("locale: lang is required")
+	}
+	for i, rule := range catalog.Phrases {
+		if _, err := parsePhraseTemplate(rule.Template); err != nil {
+			return nil, fmt.Errorf("locale %s: phrase #%d: %v", catalog.Lang, i, err)
+		}
+	}
+	return &catalog, nil
+}
+
+// NormalizeLocale 将语言标签规范为目录中的语言代码，如 zh-CN -> zh，未知语言返回默认语言
+func NormalizeLocale(lang string) string {
+	localesOnce.Do(loadLocales)
+
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := locales[lang]; ok {
+		return lang
+	}
+	return DefaultLocale
+}
+
+// LookupLocale 返回指定语言的翻译目录，未知语言回退到默认语言
+func LookupLocale(lang string) *LocaleCatalog {
+	lang = NormalizeLocale(lang)
+	return locales[lang]
+}
+
+// SupportedLocales 返回所有内置语言代码
+func SupportedLocales() []string {
+	localesOnce.Do(loadLocales)
+
+	langs := make([]string, 0, len(locales))
+	for lang := range locales {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// EmotionLabel 返回情感在指定语言下的显示名称，没有翻译时返回情感ID本身
+func EmotionLabel(lang, emotion string) string {
+	if catalog := LookupLocale(lang); catalog != nil {
+		if label, ok := catalog.Emotions[emotion]; ok {
+			return label
+		}
+	}
+	return emotion
+}
+
+// ComposeLocalizedMessage 按指定语言生成提示短语
+// 语言目录适用于当前领域配置且包含该情感的短语时使用本地化短语，否则回退到领域配置中的短语
+func ComposeLocalizedMessage(lang string, vars PhraseVars) string {
+	profile := CurrentDomainProfile()
+	rules := profile.Phrases
+
+	if catalog := LookupLocale(lang); catalog != nil && catalog.Domain == profile.Name {
+		if _, ok := selectPhraseRule(catalog.Phrases, vars); ok {
+			rules = catalog.Phrases
+		}
+		if vars.Name == "" && catalog.DefaultName != "" {
+			vars.Name = catalog.DefaultName
+		}
+	}
+
+	return ComposeMessage(rules, vars)
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+// TestNormalizeLocale 测试语言代码规范化
+// 测试内容：
+// 1. 区域标签截断（zh-CN -> zh）
+// 2. 大小写与空白处理
+// 3. 未知语言回退到默认语言
+func TestNormalizeLocale(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"zh", "zh"},
+		{"zh-CN", "zh"},
+		{" JA_jp ", "ja"},
+		{"es-419", "es"},
+		{"", DefaultLocale},
+		{"fr", DefaultLocale},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeLocale(tt.lang); got != tt.want {
+			t.Errorf("NormalizeLocale(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+// TestLocaleCatalogs 测试内置语言目录
+// 测试内容：
+// 1. en/zh/ja/es 均已加载
+// 2. 各语言覆盖英文目录中的全部情感
+func TestLocaleCatalogs(t *testing.T) {
+	en := LookupLocale("en")
+	for _, lang := range []string{"en", "zh", "ja", "es"} {
+		catalog := LookupLocale(lang)
+		if catalog == nil || catalog.Lang != lang {
+			t.Fatalf("locale %s not loaded", lang)
+		}
+		for emotion := range en.Emotions {
+			if _, ok := catalog.Emotions[emotion]; !ok {
+				t.Errorf("locale %s missing label for %s", lang, emotion)
+			}
+		}
+	}
+}
+
+// TestComposeLocalizedMessage 测试本地化短语与情感名称
+// 测试内容：
+// 1. 本地化短语与默认称呼
+// 2. 英文回退到领域配置中的短语
+// 3. 未翻译的情感名称返回情感ID
+func TestComposeLocalizedMessage(t *testing.T) {
+	vars := PhraseVars{Emotion: "for_food", Intensity: IntensityHigh}
+
+	if got, want := ComposeLocalizedMessage("zh", vars), "饭！你的猫咪快饿坏了，碗都空了！"; got != want {
+		t.Errorf("zh message = %q, want %q", got, want)
+	}
+
+	vars.Name = "Mimi"
+	if got, want := ComposeLocalizedMessage("en", vars), "FOOD! Mimi is starving, the bowl is empty!"; got != want {
+		t.Errorf("en message = %q, want %q", got, want)
+	}
+
+	if got := EmotionLabel("ja", "for_food"); got != "ごはんの催促" {
+		t.Errorf("ja label = %q", got)
+	}
+	if got := EmotionLabel("zh", "no_such_emotion"); got != "no_such_emotion" {
+		t.Errorf("missing label = %q, want emotion id", got)
+	}
+}
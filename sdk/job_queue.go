@@ -0,0 +1,512 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 批量分析任务队列
+//
+// 整理历史录音时一次要分析成百上千个文件，逐个调用 /api/analyze-file 既慢又容易因连接中断丢失进度。
+// 批量任务提交后立即返回任务ID，由固定数量的工作协程在后台逐个分析，客户端轮询任务状态。
+// 任务状态与上传的文件都保存在磁盘上，服务重启后未完成的文件会重新排队。
+// 查询结果附带进度：已处理/总文件数，以及按本次运行的处理速度估算的剩余时间；服务重启后从已完成的
+// 文件之后继续，速度重新统计，停机时间不计入估算。已完成的任务按 JobRetention 限制保留，
+// 超出条数或保存时长的任务连同状态文件在提交新任务或任务完成时删除。
+
+// 任务与文件状态
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// 任务队列相关常量
+const (
+	MaxJobItems       = 1000 // 单个任务最多包含的文件数
+	maxQueuedJobItems = 4096 // 队列中等待处理的文件数上限
+)
+
+// 任务队列错误
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrQueueClosed = errors.New("job queue is closed")
+)
+
+// AnalysisOptions 录音分析的可选参数，与 /start 中的同名字段含义相同
+type AnalysisOptions struct {
+	FrequencyPreset string `json:"frequencyPreset,omitempty"` // kitten/adult/large-breed
+	Strategy        string `json:"strategy,omitempty"`        // 处理策略
+	CatName         string `json:"catName,omitempty"`         // 猫咪名字，用于提示短语
+	Context         string `json:"context,omitempty"`         // 上下文标签
+	Lang            string `json:"lang,omitempty"`            // 结果语言
+}
+
+// analysisOptionsFromForm 从表单字段读取分析参数
+func analysisOptionsFromForm(r *http.Request) AnalysisOptions {
+	return AnalysisOptions{
+		FrequencyPreset: r.FormValue("frequencyPreset"),
+		Strategy:        r.FormValue("strategy"),
+		CatName:         r.FormValue("catName"),
+		Context:         r.FormValue("context"),
+		Lang:            r.FormValue("lang"),
+	}
+}
+
+// settings 转换为处理器使用的流配置
+func (o AnalysisOptions) settings() StreamSettings {
+	return StreamSettings{
+		FrequencyPreset: o.FrequencyPreset,
+		Strategy:        o.Strategy,
+		Cat:             CatProfile{Name: o.CatName},
+		Context:         o.Context,
+		Lang:            o.Lang,
+	}
+}
+
+// JobRetention 已完成任务的保留限制，为0的项不限制
+type JobRetention struct {
+	MaxJobs int           // 最多保留的已完成任务数
+	MaxAge  time.Duration // 任务完成后最长保留时间
+}
+
+// JobItem 批量任务中的一个录音
+type JobItem struct {
+	Source string        `json:"source"`           // 上传的文件名或录音URL
+	URL    string        `json:"url,omitempty"`    // 录音URL，上传的文件为空
+	Status string        `json:"status"`           // queued/running/done/failed
+	Error  string        `json:"error,omitempty"`  // 失败原因
+	Result *FileAnalysis `json:"result,omitempty"` // 分析结果
+}
+
+// Job 批量分析任务
+type Job struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status"` // 所有文件处理完之前为 queued/running，之后为 done
+	Options   AnalysisOptions `json:"options"`
+	Items     []JobItem       `json:"items"`
+	CreatedAt int64           `json:"createdAt"`          // 毫秒时间戳
+	UpdatedAt int64           `json:"updatedAt"`          // 毫秒时间戳
+	Progress  *JobProgress    `json:"progress,omitempty"` // 进度，只在查询结果中返回
+
+	runningSince time.Time // 本次运行中开始处理该任务的时间
+	finished     int       // 本次运行中处理完的文件数
+}
+
+// JobProgress 任务进度
+type JobProgress struct {
+	Done       int     `json:"done"`                 // 已处理的文件数（含失败）
+	Failed     int     `json:"failed"`               // 失败的文件数
+	Total      int     `json:"total"`                // 文件总数
+	ETASeconds float64 `json:"etaSeconds,omitempty"` // 预计剩余时间（秒），本次运行尚未处理完文件时为0
+}
+
+// JobUpload 提交任务时上传的文件
+type JobUpload struct {
+	Name string
+	Data []byte
+}
+
+// jobTask 队列中的一个待处理文件
+type jobTask struct {
+	jobID string
+	index int
+}
+
+// JobQueue 批量分析任务队列
+type JobQueue struct {
+	processor AudioProcessor
+	fetcher   *RemoteAudioFetcher
+	dir       string // 任务状态与上传文件的保存目录
+
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	tasks     chan jobTask
+	closed    bool
+	retention JobRetention
+	wg        sync.WaitGroup
+}
+
+// NewJobQueue 创建任务队列并启动 workers 个工作协程
+// dir 中已有的任务会被加载，未完成的文件重新排队
+func NewJobQueue(processor AudioProcessor, fetcher *RemoteAudioFetcher, dir string, workers int) (*JobQueue, error) {
+	if workers <= 0 {
+		return nil, fmt.Errorf("job queue: workers must be positive")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("job queue: %v", err)
+	}
+
+	q := &JobQueue{
+		processor: processor,
+		fetcher:   fetcher,
+		dir:       dir,
+		jobs:      make(map[string]*Job),
+		tasks:     make(chan jobTask, maxQueuedJobItems),
+	}
+	pending, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range pending {
+		q.tasks <- task
+	}
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	log.Printf("批量任务队列已启动: 目录=%s, 工作协程=%d, 恢复待处理文件=%d", dir, workers, len(pending))
+	return q, nil
+}
+
+// SetRetention 设置已完成任务的保留限制，立即删除超出限制的任务
+func (q *JobQueue) SetRetention(retention JobRetention) error {
+	if retention.MaxJobs < 0 || retention.MaxAge < 0 {
+		return fmt.Errorf("job retention must not be negative")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retention = retention
+	q.prune(time.Now())
+	return nil
+}
+
+// Close 停止接收任务并等待工作协程处理完队列中的文件
+func (q *JobQueue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	close(q.tasks)
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+// Submit 提交批量任务，返回任务快照
+func (q *JobQueue) Submit(uploads []JobUpload, urls []string, options AnalysisOptions) (*Job, error) {
+	count := len(uploads) + len(urls)
+	if count == 0 {
+		return nil, fmt.Errorf("job has no files or urls")
+	}
+	if count > MaxJobItems {
+		return nil, fmt.Errorf("job has %d items, max %d", count, MaxJobItems)
+	}
+
+	now := time.Now().UnixMilli()
+	job := &Job{
+		ID:        newJobID(),
+		Status:    JobQueued,
+		Options:   options,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := os.MkdirAll(q.uploadDir(job.ID), 0755); err != nil {
+		return nil, fmt.Errorf("job queue: %v", err)
+	}
+	for i, upload := range uploads {
+		if err := os.WriteFile(q.uploadPath(job.ID, i), upload.Data, 0644); err != nil {
+			os.RemoveAll(q.uploadDir(job.ID))
+			return nil, fmt.Errorf("job queue: %v", err)
+		}
+		job.Items = append(job.Items, JobItem{Source: upload.Name, Status: JobQueued})
+	}
+	for _, u := range urls {
+		job.Items = append(job.Items, JobItem{Source: u, URL: u, Status: JobQueued})
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		os.RemoveAll(q.uploadDir(job.ID))
+		return nil, ErrQueueClosed
+	}
+	// 只有 Submit 在持锁时向队列写入，剩余容量足够时不会阻塞
+	if cap(q.tasks)-len(q.tasks) < count {
+		os.RemoveAll(q.uploadDir(job.ID))
+		return nil, ErrQueueFull
+	}
+	q.prune(time.Now())
+	q.jobs[job.ID] = job
+	if err := q.save(job); err != nil {
+		delete(q.jobs, job.ID)
+		os.RemoveAll(q.uploadDir(job.ID))
+		return nil, err
+	}
+	for i := range job.Items {
+		q.tasks <- jobTask{jobID: job.ID, index: i}
+	}
+
+	log.Printf("提交批量任务: ID=%s, 文件数=%d", job.ID, count)
+	return snapshotJob(job), nil
+}
+
+// Get 返回任务快照
+func (q *JobQueue) Get(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return snapshotJob(job), nil
+}
+
+// worker 逐个处理队列中的文件
+func (q *JobQueue) worker() {
+	defer q.wg.Done()
+	for task := range q.tasks {
+		item, options, ok := q.start(task)
+		if !ok {
+			continue
+		}
+		result, err := q.analyze(task, item, options)
+		q.finish(task, result, err)
+	}
+}
+
+// start 将文件标记为处理中，返回文件与任务参数
+func (q *JobQueue) start(task jobTask) (JobItem, AnalysisOptions, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[task.jobID]
+	if !ok || task.index >= len(job.Items) {
+		return JobItem{}, AnalysisOptions{}, false
+	}
+	job.Items[task.index].Status = JobRunning
+	job.Status = JobRunning
+	if job.runningSince.IsZero() {
+		job.runningSince = time.Now()
+	}
+	q.touch(job)
+	return job.Items[task.index], job.Options, true
+}
+
+// analyze 读取（或下载）并分析一个文件
+func (q *JobQueue) analyze(task jobTask, item JobItem, options AnalysisOptions) (*FileAnalysis, error) {
+	var data []byte
+	var err error
+	if item.URL != "" {
+		data, err = q.fetcher.Fetch(item.URL)
+	} else {
+		data, err = os.ReadFile(q.uploadPath(task.jobID, task.index))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	audio, err := decodeAudioFile(data)
+	if err != nil {
+		return nil, err
+	}
+	return q.processor.AnalyzeFile(audio, options.settings())
+}
+
+// finish 记录文件的处理结果，所有文件处理完后任务标记为完成并删除上传的文件
+func (q *JobQueue) finish(task jobTask, result *FileAnalysis, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.jobs[task.jobID]
+	item := &job.Items[task.index]
+	if err != nil {
+		item.Status = JobFailed
+		item.Error = err.Error()
+		log.Printf("批量任务 %s 文件 %s 分析失败: %v", job.ID, item.Source, err)
+	} else {
+		item.Status = JobDone
+		item.Result = result
+	}
+	job.finished++
+
+	done := true
+	for _, it := range job.Items {
+		if it.Status == JobQueued || it.Status == JobRunning {
+			done = false
+			break
+		}
+	}
+	if done {
+		job.Status = JobDone
+		os.RemoveAll(q.uploadDir(job.ID))
+		log.Printf("批量任务完成: ID=%s", job.ID)
+	} else {
+		progress := job.progress(time.Now())
+		log.Printf("批量任务 %s 进度: %d/%d, 预计剩余 %s", job.ID, progress.Done, progress.Total,
+			time.Duration(progress.ETASeconds*float64(time.Second)).Round(time.Second))
+	}
+	q.touch(job)
+	if done {
+		q.prune(time.Now())
+	}
+}
+
+// prune 删除完成超过保存时长的任务，再按条数限制删除最早完成的任务（调用方需持有q.mu）
+// 未完成的任务不受影响
+func (q *JobQueue) prune(now time.Time) {
+	if q.retention.MaxJobs == 0 && q.retention.MaxAge == 0 {
+		return
+	}
+
+	var done []*Job
+	for _, job := range q.jobs {
+		if job.Status == JobDone {
+			done = append(done, job)
+		}
+	}
+	sort.Slice(done, func(i, j int) bool {
+		if done[i].UpdatedAt != done[j].UpdatedAt {
+			return done[i].UpdatedAt < done[j].UpdatedAt
+		}
+		return done[i].ID < done[j].ID
+	})
+
+	remove := 0
+	if q.retention.MaxAge > 0 {
+		cutoff := now.Add(-q.retention.MaxAge).UnixMilli()
+		for remove < len(done) && done[remove].UpdatedAt < cutoff {
+			remove++
+		}
+	}
+	if q.retention.MaxJobs > 0 && len(done)-remove > q.retention.MaxJobs {
+		remove = len(done) - q.retention.MaxJobs
+	}
+	for _, job := range done[:remove] {
+		delete(q.jobs, job.ID)
+		os.Remove(filepath.Join(q.dir, job.ID+".json"))
+		os.RemoveAll(q.uploadDir(job.ID))
+	}
+	if remove > 0 {
+		log.Printf("批量任务清理: 删除 %d 个已完成的任务", remove)
+	}
+}
+
+// touch 更新任务修改时间并保存（调用方需持有q.mu）
+func (q *JobQueue) touch(job *Job) {
+	job.UpdatedAt = time.Now().UnixMilli()
+	if err := q.save(job); err != nil {
+		log.Printf("保存批量任务 %s 失败: %v", job.ID, err)
+	}
+}
+
+// save 将任务状态写入磁盘，先写临时文件再重命名，避免中途崩溃留下损坏的文件
+func (q *JobQueue) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("job queue: %v", err)
+	}
+	path := filepath.Join(q.dir, job.ID+".json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("job queue: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("job queue: %v", err)
+	}
+	return nil
+}
+
+// load 加载目录中保存的任务，返回需要重新排队的文件（按任务创建时间排序）
+func (q *JobQueue) load() ([]jobTask, error) {
+	paths, err := filepath.Glob(filepath.Join(q.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("job queue: %v", err)
+	}
+
+	var jobs []*Job
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("job queue: %v", err)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			log.Printf("跳过无法解析的任务文件 %s: %v", path, err)
+			continue
+		}
+		jobs = append(jobs, &job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt < jobs[j].CreatedAt })
+
+	var pending []jobTask
+	for _, job := range jobs {
+		q.jobs[job.ID] = job
+		if job.Status == JobDone {
+			// 任务完成后服务在删除上传文件前停止时留下的目录
+			os.RemoveAll(q.uploadDir(job.ID))
+		}
+		for i := range job.Items {
+			// 服务停止时处理中的文件需要重新处理
+			if job.Items[i].Status == JobRunning {
+				job.Items[i].Status = JobQueued
+			}
+			if job.Items[i].Status == JobQueued {
+				pending = append(pending, jobTask{jobID: job.ID, index: i})
+			}
+		}
+	}
+	if len(pending) > cap(q.tasks) {
+		return nil, fmt.Errorf("job queue: %d pending items exceed queue capacity %d", len(pending), cap(q.tasks))
+	}
+	return pending, nil
+}
+
+// uploadDir 任务上传文件的保存目录
+func (q *JobQueue) uploadDir(jobID string) string {
+	return filepath.Join(q.dir, jobID)
+}
+
+// uploadPath 任务中第 index 个上传文件的保存路径
+func (q *JobQueue) uploadPath(jobID string, index int) string {
+	return filepath.Join(q.uploadDir(jobID), fmt.Sprintf("%d.audio", index))
+}
+
+// newJobID 生成随机任务ID
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// progress 统计任务进度（调用方需持有q.mu）
+func (job *Job) progress(now time.Time) *JobProgress {
+	progress := &JobProgress{Total: len(job.Items)}
+	for _, item := range job.Items {
+		switch item.Status {
+		case JobFailed:
+			progress.Failed++
+			progress.Done++
+		case JobDone:
+			progress.Done++
+		}
+	}
+	// 按本次运行的处理速度估算，重启前完成的文件与停机时间不计入
+	if remaining := progress.Total - progress.Done; remaining > 0 && job.finished > 0 {
+		perItem := now.Sub(job.runningSince).Seconds() / float64(job.finished)
+		progress.ETASeconds = math.Round(perItem * float64(remaining))
+	}
+	return progress
+}
+
+// snapshotJob 返回附带进度的任务副本（调用方需持有q.mu）
+func snapshotJob(job *Job) *Job {
+	clone := cloneJob(job)
+	clone.Progress = job.progress(time.Now())
+	return clone
+}
+
+// cloneJob 复制任务，避免调用方读取时与工作协程并发修改
+func cloneJob(job *Job) *Job {
+	clone := *job
+	clone.Items = append([]JobItem(nil), job.Items...)
+	return &clone
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitJob 轮询任务直到全部文件处理完成
+func waitJob(t *testing.T, q *JobQueue, id string) *Job {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", id, err)
+		}
+		if job.Status == JobDone {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return nil
+}
+
+// TestJobQueue 测试批量分析任务
+// 测试内容：
+// 1. 通过 /jobs 提交两个上传文件与一个URL，返回202与任务ID
+// 2. /jobs/{id} 返回每个文件的状态，无法解码的文件标记为 failed，其余带分析结果
+// 3. 任务完成后删除上传的文件，未知任务返回404
+// 4. 重启后从目录恢复任务，中断时处理中的文件重新处理
+func TestJobQueue(t *testing.T) {
+	wav := buildWAV(buildCallRecording(), 16000)
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wav)
+	}))
+	defer files.Close()
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	dir := t.TempDir()
+	fetcher := DefaultRemoteAudioFetcher()
+	fetcher.AllowPrivate = true // 测试服务器监听在回环地址
+	q, err := NewJobQueue(engine, fetcher, dir, 2)
+	if err != nil {
+		t.Fatalf("NewJobQueue() error = %v", err)
+	}
+	server := NewAudioServer(engine)
+	server.SetJobQueue(q)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "a.wav")
+	part.Write(wav)
+	part, _ = form.CreateFormFile("file", "broken.wav")
+	part.Write([]byte("not audio"))
+	form.WriteField("url", files.URL+"/c.wav")
+	form.WriteField("lang", "zh")
+	form.Close()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/jobs", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	server.handleSubmitJob(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("/jobs status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var submitted struct {
+		JobID string `json:"jobId"`
+		Items int    `json:"items"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &submitted)
+	if submitted.JobID == "" || submitted.Items != 3 {
+		t.Fatalf("unexpected submit response: %s", rec.Body)
+	}
+
+	waitJob(t, q, submitted.JobID)
+	rec = httptest.NewRecorder()
+	server.handleGetJob(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+submitted.JobID, nil))
+	var job Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+		t.Fatalf("结果不是合法JSON: %s", rec.Body)
+	}
+	wantStatus := []string{JobDone, JobFailed, JobDone}
+	for i, item := range job.Items {
+		if item.Status != wantStatus[i] {
+			t.Errorf("item %d (%s) status = %s, want %s (error=%s)", i, item.Source, item.Status, wantStatus[i], item.Error)
+		}
+	}
+	if r := job.Items[0].Result; r == nil || len(r.Segments) != 2 || r.Segments[0].Label != EmotionLabel("zh", r.Segments[0].Emotion) {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if _, err := os.Stat(q.uploadDir(job.ID)); !os.IsNotExist(err) {
+		t.Errorf("任务完成后应删除上传的文件, stat err = %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleGetJob(rec, httptest.NewRequest(http.MethodGet, "/jobs/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown job status = %d, want 404", rec.Code)
+	}
+	q.Close()
+
+	// 模拟服务中断：任务文件中仍有处理中的URL
+	interrupted := Job{
+		ID:     "interrupted",
+		Status: JobRunning,
+		Items:  []JobItem{{Source: files.URL, URL: files.URL, Status: JobRunning}},
+	}
+	data, _ := json.Marshal(interrupted)
+	os.WriteFile(filepath.Join(dir, "interrupted.json"), data, 0644)
+
+	q, err = NewJobQueue(engine, fetcher, dir, 1)
+	if err != nil {
+		t.Fatalf("NewJobQueue() error = %v", err)
+	}
+	defer q.Close()
+	if restored := waitJob(t, q, "interrupted"); restored.Items[0].Status != JobDone {
+		t.Errorf("restored item = %+v", restored.Items[0])
+	}
+	if finished, err := q.Get(job.ID); err != nil || finished.Status != JobDone {
+		t.Errorf("已完成的任务应原样恢复: %+v, %v", finished, err)
+	}
+}
+
+// TestJobProgress 测试批量任务的进度统计
+// 测试内容：
+// 1. 已处理数包含失败的文件
+// 2. 剩余时间只按本次运行的处理速度估算，本次运行尚未处理完文件时为0
+// 3. 进度只在查询结果中返回，不写入任务文件
+func TestJobProgress(t *testing.T) {
+	now := time.Now()
+	job := &Job{
+		ID: "partial",
+		Items: []JobItem{
+			{Status: JobDone}, // 重启前完成
+			{Status: JobFailed},
+			{Status: JobRunning},
+			{Status: JobQueued},
+		},
+	}
+	if progress := job.progress(now); progress.Done != 2 || progress.Failed != 1 || progress.Total != 4 || progress.ETASeconds != 0 {
+		t.Errorf("progress before any item finished = %+v", progress)
+	}
+
+	job.runningSince = now.Add(-10 * time.Second)
+	job.finished = 1
+	if progress := job.progress(now); progress.ETASeconds != 20 {
+		t.Errorf("ETA = %.1f, want 20 (10s per item, 2 remaining)", progress.ETASeconds)
+	}
+
+	q := &JobQueue{dir: t.TempDir(), jobs: map[string]*Job{job.ID: job}}
+	if snapshot, err := q.Get(job.ID); err != nil || snapshot.Progress == nil || snapshot.Progress.Done != 2 {
+		t.Errorf("Get() = %+v, %v, want progress", snapshot, err)
+	}
+	if err := q.save(job); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(q.dir, job.ID+".json"))
+	if bytes.Contains(data, []byte(`"progress"`)) {
+		t.Errorf("saved job contains progress: %s", data)
+	}
+}
+
+// TestJobRetention 测试已完成任务的保留限制
+// 测试内容：
+// 1. 完成超过保存时长的任务与超出条数的最早完成的任务连同任务文件、上传目录一起删除
+// 2. 未完成的任务不受保留限制影响
+// 3. 任务完成时按条数限制删除更早完成的任务
+func TestJobRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for _, job := range []Job{
+		{ID: "expired", Status: JobDone, UpdatedAt: now.Add(-2 * time.Hour).UnixMilli()},
+		{ID: "older", Status: JobDone, UpdatedAt: now.Add(-20 * time.Minute).UnixMilli()},
+		{ID: "newer", Status: JobDone, UpdatedAt: now.Add(-10 * time.Minute).UnixMilli()},
+		{ID: "pending", Status: JobQueued, UpdatedAt: now.Add(-3 * time.Hour).UnixMilli()},
+	} {
+		data, _ := json.Marshal(job)
+		os.WriteFile(filepath.Join(dir, job.ID+".json"), data, 0644)
+	}
+	os.MkdirAll(filepath.Join(dir, "older"), 0755)
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	q, err := NewJobQueue(engine, DefaultRemoteAudioFetcher(), dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if err := q.SetRetention(JobRetention{MaxJobs: -1}); err == nil {
+		t.Error("negative retention should be rejected")
+	}
+	if err := q.SetRetention(JobRetention{MaxJobs: 1, MaxAge: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	exists := func(id string) bool {
+		_, err := q.Get(id)
+		_, statErr := os.Stat(filepath.Join(dir, id+".json"))
+		return err == nil && statErr == nil
+	}
+	for id, want := range map[string]bool{"expired": false, "older": false, "newer": true, "pending": true} {
+		if got := exists(id); got != want {
+			t.Errorf("job %s retained = %v, want %v", id, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "older")); !os.IsNotExist(err) {
+		t.Errorf("upload dir of a pruned job should be removed, stat err = %v", err)
+	}
+
+	job, err := q.Submit([]JobUpload{{Name: "broken.wav", Data: []byte("not audio")}}, nil, AnalysisOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, q, job.ID)
+	if exists("newer") || !exists(job.ID) || !exists("pending") {
+		t.Errorf("after a new job finished: newer=%v new=%v pending=%v, want false true true", exists("newer"), exists(job.ID), exists("pending"))
+	}
+}
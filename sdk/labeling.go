@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 低置信度片段的人工标注
+//
+// 开启处理音频归档（-archive）后，每个识别结果的音频片段都保存在归档里，其中置信度低的正是样本库
+// 覆盖不到的叫声。标注接口把它们交给人来听、来标，标注后立即对片段重新提取特征并加入标注样本库，
+// 形成一个最小的人在回路的标注工具，不需要另外部署：
+//   GET  /api/admin/labeling/clips             置信度低于阈值且未标注的片段，?limit= 限制条数（默认50）
+//   GET  /api/admin/labeling/clips/{id}/audio  片段音频（WAV）
+//   POST /api/admin/labeling/clips/{id}        提交标注 {"emotion": "hungry", "catId": "mimi", "tags": ["夜间"]}
+// 标注样本库保存在 -label-library（默认为数据目录下的 labeled_library.json），处理器支持替换样本库
+// 时同时替换当前样本库。接口属于管理接口，需携带 -admin-token。
+
+// 标注相关常量
+const (
+	DefaultLabelMaxConfidence = 0.6                    // 默认只列出置信度低于该值的片段
+	DefaultLabelLibrary       = "labeled_library.json" // 标注样本库的默认文件名（位于数据目录下）
+	defaultLabelingLimit      = 50                     // 列表默认返回的片段数
+	maxClipTags               = 16                     // 每个片段最多的标签数
+)
+
+// ErrClipLabeled 片段已经标注过
+var ErrClipLabeled = errors.New("clip already labeled")
+
+// ClipLabel 片段的人工标注
+type ClipLabel struct {
+	Emotion   string   `json:"emotion"`
+	CatID     string   `json:"catId,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	LabeledAt int64    `json:"labeledAt"` // 毫秒时间戳
+}
+
+// LabelingQueue 从处理音频归档中挑出待标注的片段，标注后重新提取特征写入标注样本库
+type LabelingQueue struct {
+	archive       *AudioArchive
+	libraryPath   string  // 标注样本库路径
+	maxConfidence float64 // 只列出置信度低于该值的片段
+	sampleRate    int     // 特征提取采样率，应与运行时引擎一致
+
+	mu sync.Mutex // 同一时间只处理一个标注，避免并发写样本库
+}
+
+// NewLabelingQueue 创建标注队列
+func NewLabelingQueue(archive *AudioArchive, libraryPath string, maxConfidence float64, sampleRate int) (*LabelingQueue, error) {
+	if archive == nil {
+		return nil, fmt.Errorf("labeling requires an audio archive")
+	}
+	if maxConfidence <= 0 || maxConfidence > 1 {
+		return nil, fmt.Errorf("label max confidence must be in (0, 1]")
+	}
+	return &LabelingQueue{archive: archive, libraryPath: libraryPath, maxConfidence: maxConfidence, sampleRate: sampleRate}, nil
+}
+
+// Pending 返回置信度低于阈值且未标注的片段，最多 limit 个，置信度低的在前
+func (q *LabelingQueue) Pending(limit int) ([]ArchivedResult, error) {
+	records, err := q.archive.List()
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]ArchivedResult, 0)
+	for _, record := range records {
+		if record.Label == nil && record.Confidence < q.maxConfidence {
+			pending = append(pending, record)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Confidence < pending[j].Confidence })
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+// Label 标注一个片段：重新提取特征加入标注样本库并保存，返回更新后的样本库
+func (q *LabelingQueue) Label(resultID string, label ClipLabel) (*SampleLibrary, error) {
+	label.Emotion = NormalizeEmotionID(strings.TrimSpace(label.Emotion))
+	if label.Emotion == "" {
+		return nil, fmt.Errorf("emotion is required")
+	}
+	if len(label.Tags) > maxClipTags {
+		return nil, fmt.Errorf("too many tags: %d, max %d", len(label.Tags), maxClipTags)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	record, err := q.archive.Load(resultID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Label != nil {
+		return nil, ErrClipLabeled
+	}
+	audioPath, err := q.archive.AudioPath(resultID)
+	if err != nil {
+		return nil, err
+	}
+
+	processor := NewSampleProcessor(AudioStreamConfig{SampleRate: q.sampleRate})
+	if err := processor.Library.LoadFromFile(q.libraryPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("load label library: %v", err)
+	}
+	if err := processor.processAudioFile(audioPath, SampleLabel{Emotion: label.Emotion, CatID: label.CatID}); err != nil {
+		return nil, err
+	}
+	samples := processor.Library.Samples[label.Emotion]
+	samples[len(samples)-1].Tags = label.Tags
+	processor.calculateStatistics()
+	processor.Library.scoreQuality()
+	if err := processor.ExportLibrary(q.libraryPath); err != nil {
+		return nil, err
+	}
+
+	label.LabeledAt = time.Now().UnixMilli()
+	if _, err := q.archive.SetLabel(resultID, label); err != nil {
+		return nil, err
+	}
+	log.Printf("片段已标注: %s, 识别为 %s (置信度 %.2f), 标注为 %s", resultID, record.Emotion, record.Confidence, label.Emotion)
+	return processor.Library, nil
+}
+
+// SetLabelingQueue 设置低置信度片段的标注队列，为nil时不提供标注接口
+func (s *AudioServer) SetLabelingQueue(queue *LabelingQueue) {
+	s.labeling = queue
+}
+
+// handleLabeling 标注接口：/admin/labeling/clips[/{id}[/audio]]
+func (s *AudioServer) handleLabeling(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !s.checkAdmin(w, r) {
+		return
+	}
+	if s.labeling == nil {
+		writeError(w, r, http.StatusServiceUnavailable, ErrCodeFeatureDisabled, "标注未启用", "需设置 -archive 启动")
+		return
+	}
+
+	_, rest, _ := strings.Cut(r.URL.Path, "/labeling/clips")
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	switch {
+	case rest == "" || rest == "/":
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		limit := defaultLabelingLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "limit 无效", "")
+				return
+			}
+			limit = n
+		}
+		clips, err := s.labeling.Pending(limit)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "读取归档失败", err.Error())
+			return
+		}
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{"clips": clips})
+
+	case len(parts) == 2 && parts[1] == "audio":
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		path, err := s.labeling.archive.AudioPath(parts[0])
+		if err != nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "片段不存在", "")
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		http.ServeFile(w, r, path)
+
+	case len(parts) == 1:
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		var label ClipLabel
+		if err := json.NewDecoder(r.Body).Decode(&label); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "无效请求格式", "")
+			return
+		}
+		if _, err := s.labeling.archive.Load(parts[0]); err != nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "片段不存在", "")
+			return
+		}
+		library, err := s.labeling.Label(parts[0], label)
+		switch {
+		case errors.Is(err, ErrClipLabeled):
+			writeError(w, r, http.StatusConflict, ErrCodeConflict, "片段已标注", "")
+			return
+		case err != nil:
+			writeError(w, r, http.StatusUnprocessableEntity, ErrCodeUnprocessable, "标注失败", err.Error())
+			return
+		}
+
+		// 处理器支持替换样本库时立即使用标注样本库
+		applied := false
+		if updater, ok := s.processor.(LibraryUpdater); ok {
+			if err := updater.SetLibrary(library); err != nil {
+				log.Printf("替换样本库失败: %v", err)
+			} else {
+				applied = true
+			}
+		}
+		total := 0
+		for _, samples := range library.Samples {
+			total += len(samples)
+		}
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"resultId":     parts[0],
+			"emotion":      NormalizeEmotionID(strings.TrimSpace(label.Emotion)),
+			"totalSamples": total,
+			"applied":      applied,
+		})
+
+	default:
+		writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "接口不存在", "")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// TestLabelingQueue 测试低置信度片段的标注接口
+// 测试内容：
+// 1. 需要管理令牌，未设置标注队列时返回 feature_disabled
+// 2. 列表只含置信度低于阈值且未标注的片段，置信度低的在前
+// 3. 片段音频以 WAV 返回
+// 4. 标注后片段重新提取特征写入标注样本库，样本带猫咪ID与标签，片段不再出现在列表中
+// 5. 重复标注返回 409，缺少情感返回 422，不存在的片段返回 404
+func TestLabelingQueue(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := NewAudioArchive(filepath.Join(dir, "archive"), ArchiveRetention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tone := generateTestAudio(440, 1, 8000)
+	for i, confidence := range []float64{0.5, 0.9, 0.2} {
+		record := ArchivedResult{ResultID: []string{"a", "b", "c"}[i], Timestamp: int64(i), SampleRate: 8000, Emotion: "purr", Confidence: confidence}
+		if err := archive.Save(record, tone); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := NewAudioServer(NewMockAudioProcessor())
+	server.SetAdminToken("secret")
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		server.handleLabeling(w, req)
+		return w
+	}
+	pending := func() []string {
+		t.Helper()
+		w := do(http.MethodGet, "/api/admin/labeling/clips", nil)
+		var resp struct{ Clips []ArchivedResult }
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("list: %d %s", w.Code, w.Body)
+		}
+		var ids []string
+		for _, clip := range resp.Clips {
+			ids = append(ids, clip.ResultID)
+		}
+		return ids
+	}
+
+	if w := do(http.MethodGet, "/api/admin/labeling/clips", nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without queue status = %d, want 503", w.Code)
+	}
+	libraryPath := filepath.Join(dir, "labeled.json")
+	queue, err := NewLabelingQueue(archive, libraryPath, DefaultLabelMaxConfidence, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetLabelingQueue(queue)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/labeling/clips", nil)
+	w := httptest.NewRecorder()
+	server.handleLabeling(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("without token status = %d, want 401", w.Code)
+	}
+
+	if ids := pending(); len(ids) != 2 || ids[0] != "c" || ids[1] != "a" {
+		t.Errorf("pending = %v, want [c a]", ids)
+	}
+	if w := do(http.MethodGet, "/api/admin/labeling/clips/c/audio", nil); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "audio/wav" || w.Body.Len() < 44 {
+		t.Errorf("audio = %d %q, %d bytes", w.Code, w.Header().Get("Content-Type"), w.Body.Len())
+	}
+
+	w = do(http.MethodPost, "/api/admin/labeling/clips/c", map[string]interface{}{"emotion": "hungry", "catId": "mimi", "tags": []string{"夜间"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("label status = %d, body %s", w.Code, w.Body)
+	}
+	library := &SampleLibrary{}
+	if err := library.LoadFromFile(libraryPath); err != nil {
+		t.Fatal(err)
+	}
+	samples := library.Samples["hungry"]
+	if len(samples) != 1 || samples[0].CatID != "mimi" || len(samples[0].Tags) != 1 || samples[0].Features.Energy == 0 {
+		t.Errorf("labeled samples = %+v", samples)
+	}
+	if ids := pending(); len(ids) != 1 || ids[0] != "a" {
+		t.Errorf("pending after label = %v, want [a]", ids)
+	}
+
+	if w := do(http.MethodPost, "/api/admin/labeling/clips/c", map[string]string{"emotion": "angry"}); w.Code != http.StatusConflict {
+		t.Errorf("relabel status = %d, want 409", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/admin/labeling/clips/a", map[string]string{}); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing emotion status = %d, want 422", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/admin/labeling/clips/zzz", map[string]string{"emotion": "angry"}); w.Code != http.StatusNotFound {
+		t.Errorf("unknown clip status = %d, want 404", w.Code)
+	}
+}
+
+// TestLabelingEngineArchive 测试以处理引擎的归档标注片段
+// 测试内容：
+// 1. 处理引擎归档的片段出现在标注列表中
+// 2. 标注后标注样本库立即替换引擎当前的样本库
+func TestLabelingEngineArchive(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 8000, BufferSize: 1024, Deterministic: true})
+	archive, err := NewAudioArchive(t.TempDir(), ArchiveRetention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archiver AudioArchiver = engine
+	archiver.SetArchive(archive)
+	data, err := engine.ProcessAudio("cat", generateTestAudio(440, 0.2, 8000))
+	if err != nil || data == nil {
+		t.Fatalf("ProcessAudio() = %s, %v", data, err)
+	}
+	var result AudioStreamResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	queue, err := NewLabelingQueue(archiver.Archive(), filepath.Join(t.TempDir(), "labeled.json"), 1, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewAudioServer(engine)
+	server.SetAdminToken("secret")
+	server.SetLabelingQueue(queue)
+	clips, err := queue.Pending(0)
+	if err != nil || len(clips) == 0 || clips[len(clips)-1].ResultID != result.ResultID {
+		t.Fatalf("pending = %+v, %v; want the engine result %s", clips, err, result.ResultID)
+	}
+
+	body, _ := json.Marshal(map[string]string{"emotion": "hungry"})
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/labeling/clips/"+result.ResultID, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	server.handleLabeling(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("label status = %d, body %s", w.Code, w.Body)
+	}
+	if library := engine.library(); len(library.Samples) != 1 || len(library.Samples["hungry"]) != 1 {
+		t.Errorf("engine library = %+v, want the labeled library", library.Samples)
+	}
+}
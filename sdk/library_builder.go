@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"soundsdk/dsp"
+)
+
+// 从录制会话构建样本库
+//
+// 部署后用 -record 录制的会话，配合识别结果与用户反馈标签，可以直接作为新的训练样本。
+// library build --from-recordings 扫描录制目录，还原每个会话收到的音频，按标签切出叫声片段
+// 并提取特征，汇总为新的样本库，补上“部署—反馈—改进模型”的闭环。
+//
+// 录制文件 xxx.jsonl 的标签保存在同目录的 xxx.labels.json 中，没有标签文件的录制会被跳过：
+//
+//	{"labels": [
+//	  {"start": 1.2, "end": 2.0, "emotion": "hungry", "source": "feedback"},
+//	  {"emotion": "happy", "source": "result", "confidence": 0.92}
+//	]}
+//
+// start/end 为录音内的时间（秒，按会话声明的数据格式换算），都省略时表示整段录音，按静默切分为多个样本。
+// feedback 标签来自用户确认或纠正，总是采用；result 标签为服务端当时的识别结果，
+// 仅在置信度不低于阈值时采用，且同一录制存在反馈标签时全部忽略。
+
+// 标签来源
+const (
+	LabelSourceFeedback = "feedback" // 用户反馈
+	LabelSourceResult   = "result"   // 识别结果
+)
+
+// DefaultLabelMinConfidence 采用识别结果作为标签的默认最低置信度
+const DefaultLabelMinConfidence = 0.8
+
+// RecordingLabel 录制中一段音频的情感标签
+type RecordingLabel struct {
+	Start      float64 `json:"start,omitempty"`      // 开始时间（秒）
+	End        float64 `json:"end,omitempty"`        // 结束时间（秒），与 Start 都为0时表示整段录音
+	Emotion    string  `json:"emotion"`              // 情感
+	Source     string  `json:"source,omitempty"`     // 来源 feedback/result，为空时视为 feedback
+	Confidence float64 `json:"confidence,omitempty"` // 识别结果的置信度，仅 result 标签使用
+}
+
+// RecordingLabels 录制文件的标签文件内容
+type RecordingLabels struct {
+	Labels []RecordingLabel `json:"labels"`
+}
+
+// labelsPath 录制文件对应的标签文件路径
+func labelsPath(recordingPath string) string {
+	return strings.TrimSuffix(recordingPath, filepath.Ext(recordingPath)) + ".labels.json"
+}
+
+// loadRecordingLabels 读取标签文件
+func loadRecordingLabels(path string) (*RecordingLabels, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var labels RecordingLabels
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &labels, nil
+}
+
+// selectLabels 挑选可用的标签：有反馈标签时只用反馈标签，否则使用置信度足够的识别结果
+func selectLabels(labels []RecordingLabel, minConfidence float64) []RecordingLabel {
+	var feedback, results []RecordingLabel
+	for _, label := range labels {
+		if label.Emotion == "" {
+			continue
+		}
+		switch label.Source {
+		case "", LabelSourceFeedback:
+			feedback = append(feedback, label)
+		case LabelSourceResult:
+			if label.Confidence >= minConfidence {
+				results = append(results, label)
+			}
+		}
+	}
+	if len(feedback) > 0 {
+		return feedback
+	}
+	return results
+}
+
+// reconstructRecording 还原录制会话收到的时域音频
+// 数据格式取自 /api/start 请求体或 WebSocket 格式声明消息，未声明时按 LegacyStreamFormat；
+// 频域会话无法还原为样本，返回错误
+func reconstructRecording(records []RecordedRequest) (*AudioData, error) {
+	format := LegacyStreamFormat()
+	var samples []float64
+	for _, record := range records {
+		switch record.Kind {
+		case RecordKindHTTP:
+			var req struct {
+				Format *StreamFormat `json:"format"`
+				Data   interface{}   `json:"data"`
+			}
+			if err := json.Unmarshal(record.Payload, &req); err != nil {
+				continue
+			}
+			if req.Format != nil && !req.Format.IsZero() {
+				format = req.Format.withDefaults()
+			}
+			if req.Data == nil {
+				continue
+			}
+			data, err := decodeAudioData(req.Data)
+			if err != nil {
+				continue
+			}
+			samples = append(samples, data...)
+		case RecordKindWebSocket:
+			if declared, ok := decodeWebSocketFormat(record.Payload); ok {
+				format = declared.withDefaults()
+				continue
+			}
+			data, err := decodeWebSocketAudio(record.Payload)
+			if err != nil {
+				continue
+			}
+			samples = append(samples, data...)
+		}
+	}
+
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	if format.Domain != DomainTime {
+		return nil, fmt.Errorf("recording is %s domain, cannot reconstruct samples", format.Domain)
+	}
+	if len(samples) == 0 {
+		return nil, ErrInvalidDataLength
+	}
+	return &AudioData{Samples: samples, SampleRate: int(math.Round(format.Rate()))}, nil
+}
+
+// labelSegments 标签对应的样本区间，整段录音标签按静默切分，标注的区间收缩到叫声的起止点
+func labelSegments(label RecordingLabel, samples []float64, sampleRate int) []AudioSegment {
+	if label.Start == 0 && label.End == 0 {
+		return vocalizationSegments(samples, sampleRate, DefaultTriggerPolicy().SilenceDuration)
+	}
+
+	start := int(label.Start * float64(sampleRate))
+	end := int(label.End * float64(sampleRate))
+	if start < 0 {
+		start = 0
+	}
+	if end > len(samples) {
+		end = len(samples)
+	}
+	if start >= end {
+		return nil
+	}
+	segment := trimSegment(samples, AudioSegment{Start: start, End: end}, sampleRate)
+	if segment.End-segment.Start < int(minSegmentDuration*float64(sampleRate)) {
+		return nil
+	}
+	return []AudioSegment{segment}
+}
+
+// ProcessRecording 按标签从一个录制文件中提取样本，返回新增的样本数
+func (p *SampleProcessor) ProcessRecording(recordingPath string, minConfidence float64) (int, error) {
+	labels, err := loadRecordingLabels(labelsPath(recordingPath))
+	if err != nil {
+		return 0, err
+	}
+	selected := selectLabels(labels.Labels, minConfidence)
+	if len(selected) == 0 {
+		return 0, nil
+	}
+
+	records, err := LoadRecording(recordingPath)
+	if err != nil {
+		return 0, err
+	}
+	audio, err := reconstructRecording(records)
+	if err != nil {
+		return 0, err
+	}
+
+	// 统一重采样到样本库的采样率，使特征与运行时可比
+	samples := dsp.Resample(audio.Samples, audio.SampleRate, p.SampleRate)
+	extractor := NewFeatureExtractor(p.SampleRate)
+	metadata := recordingMetadata(recordingPath, audio)
+	added := 0
+	for _, label := range selected {
+		for _, segment := range labelSegments(label, samples, p.SampleRate) {
+			data := samples[segment.Start:segment.End]
+			quality := measureSampleQuality(data, samples, p.SampleRate)
+			if p.NormalizeLoudness {
+				data, _ = NormalizeLoudness(data, p.SampleRate, p.TargetLoudness)
+			}
+			start := float64(segment.Start) / float64(p.SampleRate)
+			end := float64(segment.End) / float64(p.SampleRate)
+			p.Library.Samples[label.Emotion] = append(p.Library.Samples[label.Emotion], AudioSample{
+				FilePath: fmt.Sprintf("%s#%.2f-%.2f", recordingPath, start, end),
+				Emotion:  label.Emotion,
+				Features: MapToAudioFeature(extractor.Extract(&AudioData{Samples: data, SampleRate: p.SampleRate})),
+				Quality:  quality,
+				Metadata: metadata,
+			})
+			added++
+		}
+	}
+	return added, nil
+}
+
+// ProcessRecordings 扫描录制目录，将所有带标签的录制加入样本库并计算统计特征
+func (p *SampleProcessor) ProcessRecordings(dirPath string, minConfidence float64) error {
+	files, err := filepath.Glob(filepath.Join(dirPath, "*.jsonl"))
+	if err != nil {
+		return fmt.Errorf("读取目录失败: %v", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("目录中没有录制文件: %s", dirPath)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		if _, err := os.Stat(labelsPath(file)); os.IsNotExist(err) {
+			continue
+		}
+		hash, reused := p.reuseSamples(file, "", labelsPath(file))
+		if reused {
+			fmt.Printf("录制未变化，复用样本: %s\n", file)
+			continue
+		}
+		added, err := p.ProcessRecording(file, minConfidence)
+		if err != nil {
+			fmt.Printf("警告: 处理录制失败 %s: %v\n", file, err)
+			continue
+		}
+		p.recordSource(file, hash, added)
+		fmt.Printf("处理录制: %s, 新增样本 %d\n", file, added)
+	}
+
+	fmt.Println("计算统计特征...")
+	p.calculateStatistics()
+	p.Library.scoreQuality()
+	return nil
+}
+
+func runLibrary(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "build":
+			return runLibraryBuild(args[1:])
+		case "prune":
+			return runLibraryPrune(args[1:])
+		case "viz":
+			return runLibraryViz(args[1:])
+		case "importance":
+			return runLibraryImportance(args[1:])
+		case "pack":
+			return runLibraryPack(args[1:])
+		case "keygen":
+			return runLibraryKeygen(args[1:])
+		case "sign":
+			return runLibrarySign(args[1:])
+		}
+	}
+	return fmt.Errorf("usage: library build|prune|viz|importance|pack|keygen|sign [options]")
+}
+
+func runLibraryBuild(args []string) error {
+	fs := newFlagSet("library build")
+	fromRecordings := fs.String("from-recordings", "", "录制目录（-record 的输出），录制文件旁需有 .labels.json 标签文件")
+	output := fs.String("o", "", "输出样本库路径，为空时写入数据目录下的 new_sample_library.json")
+	dataDir := addDataDirFlag(fs)
+	basePath := fs.String("base", "", "基础样本库路径，设置后在其样本之上追加")
+	minConfidence := fs.Float64("min-confidence", DefaultLabelMinConfidence, "采用识别结果作为标签的最低置信度")
+	sampleRate := fs.Int("sample-rate", 44100, "特征提取采样率，应与运行时引擎一致")
+	minQuality := fs.Float64("min-quality", 0, "构建后剔除质量分低于该值的样本，0表示不剔除")
+	minSampleRate := fs.Int("min-sample-rate", 0, "构建后剔除源音频采样率低于该值（Hz）的样本，0表示不剔除")
+	fromDir := fs.String("from-dir", "", "样本目录（emotion/xxx.wav 或 emotion_1.mp3 布局）")
+	force := fs.Bool("force", false, "忽略构建清单，重新提取所有源文件的特征")
+	labelSource := fs.String("label-source", LabelingFilename, "样本目录的标签来源：filename 按子目录名或文件名前缀，tags 读取 ID3 的 TXXX:emotion/cat，csv 读取旁挂标签文件")
+	labelsCSV := fs.String("labels-csv", "", "旁挂标签文件（-label-source csv），为空时为样本目录下的 labels.csv")
+	normalize := fs.Bool("normalize-loudness", false, "提取特征前将样本响度归一化到 -23 LUFS；运行时不归一化实时音频，仅在运行时输入同样经过增益归一化时开启")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *fromRecordings == "" && *fromDir == "" || fs.NArg() != 0 {
+		return fmt.Errorf("usage: library build --from-recordings <dir> | --from-dir <dir> [-label-source filename|tags|csv] [-labels-csv path] [-force] [-o path] [-data-dir dir] [-base path] [-min-confidence 0.8] [-sample-rate 44100] [-min-quality 0] [-min-sample-rate 0] [-normalize-loudness]")
+	}
+	if *output == "" {
+		path, err := dataPath(*dataDir, "new_sample_library.json")
+		if err != nil {
+			return err
+		}
+		*output = path
+	}
+
+	processor := NewSampleProcessor(AudioStreamConfig{SampleRate: *sampleRate})
+	processor.NormalizeLoudness = *normalize
+	if *basePath != "" {
+		if err := processor.Library.LoadFromFile(*basePath); err != nil {
+			return fmt.Errorf("load base library: %v", err)
+		}
+		// 基础样本库的特征与本次提取的特征须出自同一版本
+		if err := processor.Library.checkExtractorVersion(); err != nil {
+			return fmt.Errorf("base library %s: %v", *basePath, err)
+		}
+		log.Printf("已加载基础样本库 %s", *basePath)
+	}
+
+	// 影响特征的参数变化时不复用上次的样本
+	options := fmt.Sprintf("extractor=%s sample-rate=%d min-confidence=%g normalize=%t target-loudness=%g",
+		FeatureExtractorVersion, processor.SampleRate, *minConfidence, processor.NormalizeLoudness, processor.TargetLoudness)
+	if err := processor.StartIncremental(*output, options, *force); err != nil {
+		return err
+	}
+
+	if *fromDir != "" {
+		if *labelsCSV == "" {
+			*labelsCSV = filepath.Join(*fromDir, DefaultLabelsCSV)
+		}
+		labeler, err := NewSampleLabeler(*labelSource, *labelsCSV)
+		if err != nil {
+			return err
+		}
+		processor.Labeler = labeler
+		log.Printf("从样本目录构建样本库: %s (标签来源: %s)", *fromDir, *labelSource)
+		var failures LoadErrors
+		if err := processor.ProcessDirectory(*fromDir); err != nil && !errors.As(err, &failures) {
+			return err
+		}
+		for _, failure := range failures {
+			log.Printf("跳过无法加载的文件: %v", failure)
+		}
+	}
+	if *fromRecordings != "" {
+		log.Printf("从录制目录构建样本库: %s", *fromRecordings)
+		if err := processor.ProcessRecordings(*fromRecordings, *minConfidence); err != nil {
+			return err
+		}
+	}
+	if *minSampleRate > 0 {
+		removed := processor.Library.ExcludeLowSampleRate(*minSampleRate)
+		log.Printf("共剔除 %d 个采样率低于 %d Hz 的样本", removed, *minSampleRate)
+	}
+	if *minQuality > 0 {
+		removed := processor.Library.Prune(*minQuality)
+		log.Printf("共剔除 %d 个质量分低于 %.2f 的样本", removed, *minQuality)
+	}
+	if err := processor.ExportLibrary(*output); err != nil {
+		return err
+	}
+	return processor.SaveManifest(*output)
+}
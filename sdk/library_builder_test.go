@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestRecording 写入录制文件与标签文件，labels 为 nil 时不写标签文件
+func writeTestRecording(t *testing.T, dir, name string, records []RecordedRequest, labels []RecordingLabel) {
+	t.Helper()
+	file, err := os.Create(filepath.Join(dir, name+".jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if labels == nil {
+		return
+	}
+	data, _ := json.Marshal(RecordingLabels{Labels: labels})
+	if err := os.WriteFile(filepath.Join(dir, name+".labels.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// audioRecords 将样本按块封装为录制记录，HTTP记录使用 /api/send 请求体，WebSocket记录使用纯数组
+func audioRecords(kind, streamID string, samples []float64, chunk int) []RecordedRequest {
+	var records []RecordedRequest
+	for start := 0; start < len(samples); start += chunk {
+		end := start + chunk
+		if end > len(samples) {
+			end = len(samples)
+		}
+		var payload []byte
+		if kind == RecordKindHTTP {
+			payload, _ = json.Marshal(map[string]interface{}{"streamId": streamID, "data": samples[start:end]})
+		} else {
+			payload, _ = json.Marshal(samples[start:end])
+		}
+		records = append(records, RecordedRequest{Kind: kind, StreamID: streamID, Payload: payload})
+	}
+	return records
+}
+
+// TestBuildLibraryFromRecordings 测试从录制目录构建样本库
+// 测试内容：
+// 1. 按 /api/start 与 WebSocket 声明的格式还原录音，整段标签按静默切分为多个样本
+// 2. 有反馈标签时忽略识别结果，识别结果仅在置信度足够时采用
+// 3. 无标签文件的录制与频域录制被跳过，基础样本库的样本保留在输出中
+func TestBuildLibraryFromRecordings(t *testing.T) {
+	dir := t.TempDir()
+	const rate = 8000
+	silence := make([]float64, rate/2)
+	tone := generateTestAudio(400, 0.5, rate)
+	var calls []float64
+	calls = append(calls, silence...)
+	calls = append(calls, tone...)
+	calls = append(calls, silence...)
+	calls = append(calls, tone...)
+	calls = append(calls, silence...)
+
+	start, _ := json.Marshal(map[string]interface{}{
+		"streamId": "http-1",
+		"format":   StreamFormat{SampleRate: rate, Decimation: 1},
+	})
+	httpRecords := append([]RecordedRequest{{Kind: RecordKindHTTP, StreamID: "http-1", Payload: start}},
+		audioRecords(RecordKindHTTP, "http-1", calls, 4000)...)
+	writeTestRecording(t, dir, "http-1", httpRecords, []RecordingLabel{
+		{Emotion: "hungry", Source: LabelSourceFeedback},
+		{Emotion: "happy", Source: LabelSourceResult, Confidence: 0.95},
+	})
+
+	format, _ := json.Marshal(map[string]interface{}{"format": StreamFormat{SampleRate: rate * 2, Decimation: 2}})
+	wsRecords := append([]RecordedRequest{{Kind: RecordKindWebSocket, StreamID: "ws-1", Payload: format}},
+		audioRecords(RecordKindWebSocket, "ws-1", calls, 4000)...)
+	writeTestRecording(t, dir, "ws-1", wsRecords, []RecordingLabel{
+		{Emotion: "happy", Source: LabelSourceResult, Confidence: 0.5},
+		{Start: 0.5, End: 1.0, Emotion: "angry", Source: LabelSourceResult, Confidence: 0.9},
+	})
+
+	writeTestRecording(t, dir, "unlabeled", audioRecords(RecordKindWebSocket, "unlabeled", calls, 4000), nil)
+
+	spectrum, _ := json.Marshal(map[string]interface{}{"format": StreamFormat{SampleRate: 44100, Domain: DomainFrequency}})
+	writeTestRecording(t, dir, "spectrum",
+		append([]RecordedRequest{{Kind: RecordKindWebSocket, Payload: spectrum}}, audioRecords(RecordKindWebSocket, "spectrum", tone, 1024)...),
+		[]RecordingLabel{{Emotion: "hungry"}})
+
+	base := NewSampleProcessor(AudioStreamConfig{})
+	base.Library.Samples["content"] = []AudioSample{{FilePath: "base.wav", Emotion: "content", Features: AudioFeature{Energy: 1}}}
+	basePath := filepath.Join(dir, "base.json")
+	if err := base.ExportLibrary(basePath); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "out", "library.json")
+	if err := runLibrary([]string{"build", "--from-recordings", dir, "-o", output, "-base", basePath, "-sample-rate", "16000"}); err != nil {
+		t.Fatalf("runLibrary() error = %v", err)
+	}
+
+	library := NewSampleLibrary()
+	if err := library.LoadFromFile(output); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	counts := map[string]int{}
+	for emotion, samples := range library.Samples {
+		counts[emotion] = len(samples)
+	}
+	want := map[string]int{"hungry": 2, "angry": 1, "content": 1}
+	if len(counts) != len(want) {
+		t.Fatalf("sample counts = %v, want %v", counts, want)
+	}
+	for emotion, n := range want {
+		if counts[emotion] != n {
+			t.Errorf("samples[%s] = %d, want %d", emotion, counts[emotion], n)
+		}
+		if library.Statistics[emotion].SampleCount != n {
+			t.Errorf("statistics[%s].SampleCount = %d, want %d", emotion, library.Statistics[emotion].SampleCount, n)
+		}
+	}
+
+	for _, sample := range library.Samples["hungry"] {
+		if d := sample.Features.Duration; d < 0.4 || d > 0.6 {
+			t.Errorf("hungry sample %s duration = %.3f, want about 0.5", sample.FilePath, d)
+		}
+		if sample.Features.Energy <= 0 {
+			t.Errorf("hungry sample %s has no energy", sample.FilePath)
+		}
+	}
+
+	if err := runLibrary([]string{"build"}); err == nil {
+		t.Error("runLibrary() without --from-recordings should fail")
+	}
+}
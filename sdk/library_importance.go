@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// 特征重要性分析
+//
+// 对每个特征做方差分析（ANOVA）：组间方差相对组内方差越大，该特征越能区分情感。
+// 整体排名用所有情感分组的多类F值；每种情感再按“该情感 vs 其余”两组计算F值，
+// 可以看出哪些特征撑起了哪种情感的识别，据此调整特征权重、决定优先补充哪些新特征。
+
+// FeatureScore 一个特征的区分度
+type FeatureScore struct {
+	Feature string  `json:"feature"`
+	F       float64 `json:"f"` // ANOVA F值，组内无方差而组间有差异时为 +Inf
+}
+
+// EmotionImportance 一种情感的特征区分度排名
+type EmotionImportance struct {
+	Emotion  string         `json:"emotion"`
+	Samples  int            `json:"samples"`
+	Features []FeatureScore `json:"features"` // 按F值从大到小排序
+}
+
+// ImportanceReport 样本库的特征重要性报告
+type ImportanceReport struct {
+	Overall  []FeatureScore      `json:"overall"`  // 多类F值排名
+	Emotions []EmotionImportance `json:"emotions"` // 按情感名排序
+}
+
+// FeatureImportance 计算样本库各特征的区分度
+func FeatureImportance(library *SampleLibrary) (*ImportanceReport, error) {
+	emotions := make([]string, 0, len(library.Samples))
+	for emotion, samples := range library.Samples {
+		if len(samples) > 0 {
+			emotions = append(emotions, emotion)
+		}
+	}
+	if len(emotions) < 2 {
+		return nil, fmt.Errorf("need at least 2 emotions, got %d", len(emotions))
+	}
+	sort.Strings(emotions)
+
+	// values[e][j] 为情感 e 所有样本第 j 个特征的取值
+	names := featureNames()
+	values := make([][][]float64, len(emotions))
+	for e, emotion := range emotions {
+		values[e] = make([][]float64, len(names))
+		for _, sample := range library.Samples[emotion] {
+			for j, v := range featureValues(sample.Features) {
+				values[e][j] = append(values[e][j], v)
+			}
+		}
+	}
+
+	report := &ImportanceReport{}
+	for j, name := range names {
+		groups := make([][]float64, len(emotions))
+		for e := range emotions {
+			groups[e] = values[e][j]
+		}
+		report.Overall = append(report.Overall, FeatureScore{Feature: name, F: anovaF(groups)})
+	}
+	rankFeatures(report.Overall)
+
+	for e, emotion := range emotions {
+		importance := EmotionImportance{Emotion: emotion, Samples: len(library.Samples[emotion])}
+		for j, name := range names {
+			var rest []float64
+			for other := range emotions {
+				if other != e {
+					rest = append(rest, values[other][j]...)
+				}
+			}
+			importance.Features = append(importance.Features, FeatureScore{
+				Feature: name,
+				F:       anovaF([][]float64{values[e][j], rest}),
+			})
+		}
+		rankFeatures(importance.Features)
+		report.Emotions = append(report.Emotions, importance)
+	}
+	return report, nil
+}
+
+// anovaF 单因素方差分析的F值，样本不足以估计组内方差时为0
+func anovaF(groups [][]float64) float64 {
+	total, count := 0.0, 0
+	for _, group := range groups {
+		for _, v := range group {
+			total += v
+		}
+		count += len(group)
+	}
+	k := len(groups)
+	if k < 2 || count <= k {
+		return 0
+	}
+	mean := total / float64(count)
+
+	var between, within float64
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		groupMean := 0.0
+		for _, v := range group {
+			groupMean += v
+		}
+		groupMean /= float64(len(group))
+		between += float64(len(group)) * (groupMean - mean) * (groupMean - mean)
+		for _, v := range group {
+			within += (v - groupMean) * (v - groupMean)
+		}
+	}
+
+	if within == 0 {
+		if between > 0 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+	return (between / float64(k-1)) / (within / float64(count-k))
+}
+
+// rankFeatures 按F值从大到小排序，F值相同时按特征名排序
+func rankFeatures(scores []FeatureScore) {
+	sort.SliceStable(scores, func(a, b int) bool {
+		if scores[a].F != scores[b].F {
+			return scores[a].F > scores[b].F
+		}
+		return scores[a].Feature < scores[b].Feature
+	})
+}
+
+// Write 输出文本报告，每个排名列出前 top 个特征，top<=0 时全部列出
+func (r *ImportanceReport) Write(w io.Writer, top int) {
+	writeRanking := func(scores []FeatureScore) {
+		for i, score := range scores {
+			if top > 0 && i >= top {
+				break
+			}
+			fmt.Fprintf(w, "  %2d. %-18s F=%10.2f\n", i+1, score.Feature, score.F)
+		}
+	}
+
+	fmt.Fprintln(w, "整体（多类 ANOVA F 值）:")
+	writeRanking(r.Overall)
+	for _, emotion := range r.Emotions {
+		fmt.Fprintf(w, "\n情感 %s（%d 个样本，与其余情感比较）:\n", emotion.Emotion, emotion.Samples)
+		writeRanking(emotion.Features)
+	}
+}
+
+func runLibraryImportance(args []string) error {
+	fs := newFlagSet("library importance")
+	top := fs.Int("top", 0, "每个排名只列出前N个特征，0表示全部")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: library importance [-top N] <library.json>")
+	}
+
+	library := NewSampleLibrary()
+	if err := library.LoadFromFile(fs.Arg(0)); err != nil {
+		return fmt.Errorf("load library: %v", err)
+	}
+	report, err := FeatureImportance(library)
+	if err != nil {
+		return err
+	}
+	report.Write(os.Stdout, *top)
+	return nil
+}
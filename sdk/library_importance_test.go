@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestFeatureImportance 测试特征重要性分析
+// 测试内容：
+// 1. 区分各情感的特征在整体排名中靠前，常量特征F值为0
+// 2. 一对其余排名反映各情感各自依赖的特征
+// 3. 组内无方差时F值为 +Inf，文本报告按 -top 截断
+func TestFeatureImportance(t *testing.T) {
+	library := NewSampleLibrary()
+	for i := 0; i < 8; i++ {
+		noise := float64(i%4) * 0.01
+		library.AddSample(AudioSample{Emotion: "happy", Features: AudioFeature{Energy: 0.9 + noise, Pitch: 400 + noise*100, ZeroCrossRate: noise}})
+		library.AddSample(AudioSample{Emotion: "angry", Features: AudioFeature{Energy: 0.1 + noise, Pitch: 800 + noise*100, ZeroCrossRate: noise}})
+		library.AddSample(AudioSample{Emotion: "calm", Features: AudioFeature{Energy: 0.1 + noise, Pitch: 400 + noise*100, ZeroCrossRate: noise}})
+	}
+
+	report, err := FeatureImportance(library)
+	if err != nil {
+		t.Fatalf("FeatureImportance() error = %v", err)
+	}
+
+	top := map[string]bool{report.Overall[0].Feature: true, report.Overall[1].Feature: true}
+	if !top["Energy"] || !top["Pitch"] {
+		t.Errorf("overall top features = %v, want Energy and Pitch", report.Overall[:2])
+	}
+	for _, score := range report.Overall {
+		if score.Feature == "Duration" && score.F != 0 {
+			t.Errorf("constant feature F = %v, want 0", score.F)
+		}
+		if score.Feature == "ZeroCrossRate" && score.F > 1e-9 {
+			t.Errorf("shared-noise feature F = %v, want 0", score.F)
+		}
+	}
+
+	want := map[string]string{"happy": "Energy", "angry": "Pitch"}
+	for _, emotion := range report.Emotions {
+		if feature, ok := want[emotion.Emotion]; ok && emotion.Features[0].Feature != feature {
+			t.Errorf("%s top feature = %s, want %s", emotion.Emotion, emotion.Features[0].Feature, feature)
+		}
+		if emotion.Samples != 8 {
+			t.Errorf("%s samples = %d, want 8", emotion.Emotion, emotion.Samples)
+		}
+	}
+
+	if f := anovaF([][]float64{{1, 1}, {2, 2}}); !math.IsInf(f, 1) {
+		t.Errorf("anovaF(separated, no variance) = %v, want +Inf", f)
+	}
+	if f := anovaF([][]float64{{1}, {2}}); f != 0 {
+		t.Errorf("anovaF(too few samples) = %v, want 0", f)
+	}
+
+	var buf bytes.Buffer
+	report.Write(&buf, 2)
+	if n := strings.Count(buf.String(), " 3. "); n != 0 {
+		t.Errorf("report with top=2 lists third entries:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "情感 angry") {
+		t.Errorf("report missing emotion section:\n%s", buf.String())
+	}
+
+	single := NewSampleLibrary()
+	single.AddSample(AudioSample{Emotion: "happy"})
+	if _, err := FeatureImportance(single); err == nil {
+		t.Error("single-emotion library should fail")
+	}
+}
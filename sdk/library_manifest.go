@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 增量构建样本库
+//
+// library build 每次都从头解码所有音频、提取特征，样本目录里有几百个 MP3 时调整一个标签也要等
+// 全部重算。现在构建时在样本库旁写一个清单 xxx.manifest.json，记录每个源文件的内容哈希（录制文件
+// 连同其标签文件）与得到的样本数：
+//
+//	{"options": "sample-rate=44100 ...", "files": {"audios/for_food_1.mp3": {"hash": "...", "samples": 1}}}
+//
+// 再次构建到同一输出路径时，哈希未变、且上次的样本库中该文件的样本数与清单一致的源文件直接复用上次
+// 的样本，只对新增或修改的文件提取特征；已删除的文件的样本不再出现。统计特征与质量分总是按全部样本
+// 重新计算。影响特征的参数（采样率、置信度阈值等）与上次不同，或指定 -force 时全部重新提取。
+
+// ManifestEntry 清单中一个源文件的记录
+type ManifestEntry struct {
+	Hash    string `json:"hash"`    // 内容哈希（SHA-256）
+	Samples int    `json:"samples"` // 该文件得到的样本数
+}
+
+// LibraryManifest 样本库的构建清单，源文件路径相对样本库所在目录、以 / 分隔
+type LibraryManifest struct {
+	Options string                   `json:"options"` // 影响特征的构建参数
+	Files   map[string]ManifestEntry `json:"files"`
+}
+
+// manifestPath 样本库对应的清单路径
+func manifestPath(libraryPath string) string {
+	return strings.TrimSuffix(libraryPath, filepath.Ext(libraryPath)) + ".manifest.json"
+}
+
+// loadManifest 读取清单，文件不存在时返回 nil
+func loadManifest(path string) (*LibraryManifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest LibraryManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %v", path, err)
+	}
+	return &manifest, nil
+}
+
+// hashFiles 计算若干文件内容的哈希，不存在的文件跳过
+func hashFiles(paths ...string) (string, error) {
+	hash := sha256.New()
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\n", filepath.Base(path))
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sourceKey 源文件的比较键（绝对路径）
+func sourceKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// incrementalBuild 一次增量构建的状态
+type incrementalBuild struct {
+	libraryDir string
+	options    string
+	previous   map[string]ManifestEntry // 上次的清单，键为源文件的绝对路径
+	samples    map[string][]AudioSample // 上次样本库中按源文件分组的样本
+	next       map[string]ManifestEntry // 本次的清单
+	reused     int                      // 复用的源文件数
+	extracted  int                      // 重新提取的源文件数
+}
+
+// StartIncremental 开始增量构建：读取输出路径上的样本库与清单，未变化的源文件复用其中的样本
+// force 或构建参数与上次不同时不复用，仍会记录本次的清单
+func (p *SampleProcessor) StartIncremental(libraryPath, options string, force bool) error {
+	build := &incrementalBuild{
+		libraryDir: filepath.Dir(libraryPath),
+		options:    options,
+		previous:   make(map[string]ManifestEntry),
+		samples:    make(map[string][]AudioSample),
+		next:       make(map[string]ManifestEntry),
+	}
+	p.incremental = build
+	if force {
+		log.Printf("-force: 重新提取所有源文件的特征")
+		return nil
+	}
+
+	manifest, err := loadManifest(manifestPath(libraryPath))
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return nil
+	}
+	if manifest.Options != options {
+		log.Printf("构建参数已变化（上次 %s），重新提取所有源文件的特征", manifest.Options)
+		return nil
+	}
+	previous := NewSampleLibrary()
+	if err := previous.LoadFromFile(libraryPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("load previous library: %v", err)
+	}
+
+	for path, entry := range manifest.Files {
+		build.previous[sourceKey(resolveSamplePath(path, build.libraryDir))] = entry
+	}
+	for _, samples := range previous.Samples {
+		for _, sample := range samples {
+			source, _ := splitSampleSuffix(sample.FilePath)
+			key := sourceKey(source)
+			build.samples[key] = append(build.samples[key], sample)
+		}
+	}
+	log.Printf("已读取构建清单: %d 个源文件", len(build.previous))
+	return nil
+}
+
+// reuseSamples 源文件（与其附属文件）内容及 extra（影响样本的其他输入，如文件外的标签）未变化时
+// 把上次的样本加入样本库，返回源文件的内容哈希与是否已复用；未开始增量构建时返回空哈希
+func (p *SampleProcessor) reuseSamples(source, extra string, related ...string) (string, bool) {
+	build := p.incremental
+	if build == nil {
+		return "", false
+	}
+	hash, err := hashFiles(append([]string{source}, related...)...)
+	if err != nil {
+		log.Printf("计算文件哈希失败 %s: %v", source, err)
+		return "", false
+	}
+	if extra != "" {
+		sum := sha256.Sum256([]byte(hash + "\x00" + extra))
+		hash = hex.EncodeToString(sum[:])
+	}
+
+	key := sourceKey(source)
+	entry, ok := build.previous[key]
+	samples := build.samples[key]
+	if !ok || entry.Hash != hash || len(samples) != entry.Samples {
+		build.extracted++
+		return hash, false
+	}
+	for _, sample := range samples {
+		p.Library.Samples[sample.Emotion] = append(p.Library.Samples[sample.Emotion], sample)
+	}
+	build.next[key] = entry
+	build.reused++
+	return hash, true
+}
+
+// recordSource 记录重新提取的源文件，hash 为空时不记录（下次构建时重新提取）
+func (p *SampleProcessor) recordSource(source, hash string, samples int) {
+	if p.incremental == nil || hash == "" {
+		return
+	}
+	p.incremental.next[sourceKey(source)] = ManifestEntry{Hash: hash, Samples: samples}
+}
+
+// SaveManifest 将本次构建的清单写到样本库旁，应在导出样本库之后调用
+func (p *SampleProcessor) SaveManifest(libraryPath string) error {
+	build := p.incremental
+	if build == nil {
+		return nil
+	}
+	manifest := LibraryManifest{Options: build.options, Files: make(map[string]ManifestEntry, len(build.next))}
+	dir := filepath.Dir(libraryPath)
+	for key, entry := range build.next {
+		manifest.Files[relativeSamplePath(key, dir)] = entry
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestPath(libraryPath), data, 0644); err != nil {
+		return fmt.Errorf("write manifest: %v", err)
+	}
+	log.Printf("增量构建: 复用 %d 个源文件，重新提取 %d 个", build.reused, build.extracted)
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIncrementalLibraryBuild 测试按构建清单增量构建样本库
+// 测试内容：
+// 1. 首次构建提取全部文件并写出清单，清单中的路径相对样本库目录
+// 2. 再次构建时未变化的文件复用样本，只重新提取修改或新增的文件，删除的文件不再出现
+// 3. 构建参数变化或 -force 时全部重新提取
+// 4. 上次样本库中某文件的样本被剔除后，下次构建重新提取该文件
+func TestIncrementalLibraryBuild(t *testing.T) {
+	root := t.TempDir()
+	audioDir := filepath.Join(root, "audios")
+	output := filepath.Join(root, "lib", "library.json")
+	tone := func(freq float64) []byte {
+		samples := make([]int16, 22050)
+		for i := range samples {
+			samples[i] = int16(8000 * math.Sin(2*math.Pi*freq*float64(i)/44100))
+		}
+		return buildWAV(samples, 44100)
+	}
+	write := func(name string, data []byte) {
+		path := filepath.Join(audioDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("hungry_1.wav", tone(500))
+	write("hungry_2.wav", tone(600))
+	write("purr/a.wav", tone(150))
+
+	build := func(options string, force bool) *SampleProcessor {
+		t.Helper()
+		processor := NewSampleProcessor(AudioStreamConfig{})
+		if err := processor.StartIncremental(output, options, force); err != nil {
+			t.Fatalf("StartIncremental: %v", err)
+		}
+		if err := processor.ProcessDirectory(audioDir); err != nil {
+			t.Fatalf("ProcessDirectory: %v", err)
+		}
+		if err := processor.ExportLibrary(output); err != nil {
+			t.Fatalf("ExportLibrary: %v", err)
+		}
+		if err := processor.SaveManifest(output); err != nil {
+			t.Fatalf("SaveManifest: %v", err)
+		}
+		return processor
+	}
+	counts := func(p *SampleProcessor, reused, extracted int) {
+		t.Helper()
+		if p.incremental.reused != reused || p.incremental.extracted != extracted {
+			t.Errorf("reused %d, extracted %d; want %d, %d", p.incremental.reused, p.incremental.extracted, reused, extracted)
+		}
+	}
+
+	counts(build("a", false), 0, 3)
+	manifest, err := loadManifest(manifestPath(output))
+	if err != nil || manifest == nil {
+		t.Fatalf("loadManifest = %v, %v", manifest, err)
+	}
+	if entry, ok := manifest.Files["../audios/purr/a.wav"]; !ok || entry.Samples != 1 || entry.Hash == "" {
+		t.Errorf("manifest files = %v, want ../audios/purr/a.wav with 1 sample", manifest.Files)
+	}
+
+	counts(build("a", false), 3, 0)
+
+	write("hungry_2.wav", tone(700))
+	write("hungry_3.wav", tone(800))
+	os.Remove(filepath.Join(audioDir, "purr", "a.wav"))
+	p := build("a", false)
+	counts(p, 1, 2)
+	if len(p.Library.Samples["hungry"]) != 3 || len(p.Library.Samples["purr"]) != 0 {
+		t.Errorf("samples = hungry %d, purr %d; want 3, 0", len(p.Library.Samples["hungry"]), len(p.Library.Samples["purr"]))
+	}
+
+	counts(build("b", false), 0, 3)
+	counts(build("b", true), 0, 3)
+
+	// 剔除一个文件的样本后重新提取该文件
+	library := NewSampleLibrary()
+	if err := library.LoadFromFile(output); err != nil {
+		t.Fatal(err)
+	}
+	library.Samples["hungry"] = library.Samples["hungry"][1:]
+	if err := library.SaveToFile(output); err != nil {
+		t.Fatal(err)
+	}
+	counts(build("b", false), 2, 1)
+}
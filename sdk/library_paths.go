@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// 样本库中的样本路径：保存时写为相对样本库目录、以 / 分隔的路径，加载时按样本库所在目录还原为本机路径，
+// 无法表示为相对路径的路径保持原样，只统一分隔符。
+
+// relativeSamplePath 返回样本路径相对样本库目录、以 / 分隔的形式
+func relativeSamplePath(path, libraryDir string) string {
+	if path == "" {
+		return path
+	}
+	file, suffix := splitSampleSuffix(path)
+	if !filepath.IsAbs(file) && !isForeignAbs(file) {
+		// 相对路径按当前目录解释（与构建时打开文件的方式一致）
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+	}
+	if dir, err := filepath.Abs(libraryDir); err == nil && filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(dir, file); err == nil {
+			file = rel
+		}
+	}
+	return strings.ReplaceAll(filepath.ToSlash(file), `\`, "/") + suffix
+}
+
+// resolveSamplePath 将样本库中的相对路径还原为本机路径，其他平台的分隔符一并转换
+func resolveSamplePath(path, libraryDir string) string {
+	if path == "" {
+		return path
+	}
+	file, suffix := splitSampleSuffix(path)
+	file = strings.ReplaceAll(file, `\`, "/")
+	if isForeignAbs(file) {
+		return file + suffix
+	}
+	file = filepath.FromSlash(file)
+	if !filepath.IsAbs(file) && libraryDir != "" {
+		file = filepath.Join(libraryDir, file)
+	}
+	return file + suffix
+}
+
+// splitSampleSuffix 拆出录制片段的 #起点-终点 后缀
+func splitSampleSuffix(path string) (string, string) {
+	if i := strings.LastIndex(path, "#"); i >= 0 && strings.Contains(path[i:], "-") {
+		return path[:i], path[i:]
+	}
+	return path, ""
+}
+
+// isForeignAbs 是否为本机无法识别的其他平台绝对路径（Linux 上的 d:\... 或 d:/...）
+func isForeignAbs(path string) bool {
+	if filepath.IsAbs(path) {
+		return false
+	}
+	if len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') {
+		c := path[0] | 0x20
+		return c >= 'a' && c <= 'z'
+	}
+	return strings.HasPrefix(path, `\\`)
+}
+
+// withRelativePaths 返回样本路径改为相对 libraryDir 的样本副本，不修改样本库本身
+func withRelativePaths(samples map[string][]AudioSample, libraryDir string) map[string][]AudioSample {
+	relative := make(map[string][]AudioSample, len(samples))
+	for emotion, list := range samples {
+		copied := make([]AudioSample, len(list))
+		for i, sample := range list {
+			sample.FilePath = relativeSamplePath(sample.FilePath, libraryDir)
+			copied[i] = sample
+		}
+		relative[emotion] = copied
+	}
+	return relative
+}
+
+// resolvePaths 将加载的样本路径还原为本机路径
+func (sl *SampleLibrary) resolvePaths(libraryDir string) {
+	for _, list := range sl.Samples {
+		for i := range list {
+			list[i].FilePath = resolveSamplePath(list[i].FilePath, libraryDir)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestLibrarySamplePaths 测试样本库中样本路径的保存与还原
+// 测试内容：
+// 1. 保存时样本路径写为相对样本库目录、以 / 分隔的形式，录制片段后缀保留
+// 2. 加载时还原为本机路径，Windows 风格的相对路径也能还原
+// 3. 其他平台的绝对路径保持原样，只统一分隔符
+// 4. 保存不修改内存中的样本库；输出到其他目录时路径按新目录重新计算
+// 5. 数据目录：-data-dir 与 XDG_DATA_HOME 生效，绝对路径原样使用
+func TestLibrarySamplePaths(t *testing.T) {
+	root := t.TempDir()
+	libraryDir := filepath.Join(root, "lib")
+	audio := filepath.Join(root, "lib", "audios", "for_food_1.mp3")
+	recording := filepath.Join(root, "recordings", "rec.wav") + "#1.20-2.40"
+
+	library := NewSampleLibrary()
+	library.AddSample(AudioSample{FilePath: audio, Emotion: "for_food"})
+	library.AddSample(AudioSample{FilePath: recording, Emotion: "for_food"})
+	library.AddSample(AudioSample{FilePath: `d:\uso_dev\MeowTalk\audios\x.mp3`, Emotion: "for_food"})
+	if err := os.MkdirAll(libraryDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(libraryDir, "library.json")
+	if err := library.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+	if got := library.Samples["for_food"][0].FilePath; got != audio {
+		t.Errorf("SaveToFile changed in-memory path to %q", got)
+	}
+
+	var saved struct{ Samples map[string][]AudioSample }
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"audios/for_food_1.mp3", "../recordings/rec.wav#1.20-2.40", "d:/uso_dev/MeowTalk/audios/x.mp3"}
+	for i, sample := range saved.Samples["for_food"] {
+		if sample.FilePath != want[i] {
+			t.Errorf("saved path %d = %q, want %q", i, sample.FilePath, want[i])
+		}
+	}
+
+	loaded := NewSampleLibrary()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if got := loaded.Samples["for_food"][0].FilePath; got != audio {
+		t.Errorf("loaded path = %q, want %q", got, audio)
+	}
+	if got := loaded.Samples["for_food"][1].FilePath; got != recording {
+		t.Errorf("loaded recording path = %q, want %q", got, recording)
+	}
+
+	// Windows 上构建的样本库
+	if got, want := resolveSamplePath(`audios\for_food_1.mp3`, libraryDir), audio; got != want {
+		t.Errorf("resolveSamplePath(windows) = %q, want %q", got, want)
+	}
+
+	// 导出到其他目录时按新目录重新计算
+	processor := NewSampleProcessor(AudioStreamConfig{})
+	processor.Library = loaded
+	exported := filepath.Join(root, "out", "library.json")
+	if err := processor.ExportLibrary(exported); err != nil {
+		t.Fatalf("ExportLibrary: %v", err)
+	}
+	rebased := NewSampleLibrary()
+	if err := rebased.LoadFromFile(exported); err != nil {
+		t.Fatal(err)
+	}
+	if got := rebased.Samples["for_food"][0].FilePath; got != audio {
+		t.Errorf("rebased path = %q, want %q", got, audio)
+	}
+	data, err = os.ReadFile(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Samples["for_food"][0].FilePath; got != "../lib/audios/for_food_1.mp3" {
+		t.Errorf("exported path = %q, want ../lib/audios/for_food_1.mp3", got)
+	}
+
+	// 数据目录
+	dir := filepath.Join(root, "data")
+	if got, err := dataPath(dir, "archive"); err != nil || got != filepath.Join(dir, "archive") {
+		t.Errorf("dataPath(dir) = %q, %v", got, err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("data dir not created: %v", err)
+	}
+	if got, err := dataPath(dir, audio); err != nil || got != audio {
+		t.Errorf("dataPath(abs) = %q, %v, want %q", got, err, audio)
+	}
+	t.Setenv("XDG_DATA_HOME", filepath.Join(root, "xdg"))
+	if got, err := DefaultDataDir(); err == nil && runtime.GOOS == "linux" && got != filepath.Join(root, "xdg", dataDirName) {
+		t.Errorf("DefaultDataDir = %q, want under XDG_DATA_HOME", got)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+)
+
+// 样本质量评分
+//
+// 样本库统计对每个样本一视同仁，一条削波严重或几乎全是底噪的录音与干净样本同样影响均值与方差。
+// 构建样本库时为每个样本计算质量分（0~1），由信噪比、削波比例、时长合理性
+// 以及与所属情感中心的距离组成，library prune 据此剔除低质量样本。
+
+// 质量评分参数
+const (
+	DefaultMinSampleQuality = 0.5 // prune 默认的最低质量分
+
+	qualityGoodSNR         = 30.0  // 信噪比达到该值（dB）时满分
+	qualityMaxClipping     = 0.01  // 削波样本比例达到该值时零分
+	qualityClipLevel       = 0.999 // 幅度达到该值视为削波
+	qualityMaxDuration     = 10.0  // 样本时长上限（秒），下限为 minSegmentDuration
+	qualityNoisePercentile = 0.1   // 以响度排在该分位的帧估计底噪
+	qualityNoiseFloor      = 1e-5  // 底噪下限，避免数字静音时信噪比无穷大
+	qualityNearCentroid    = 2.0   // 偏离情感均值的标准差倍数在该值以内时满分
+	qualityFarCentroid     = 3.0   // 偏离情感均值的标准差倍数达到该值时零分
+
+	// 综合质量分中各项的权重
+	qualityWeightSNR      = 0.3
+	qualityWeightClipping = 0.2
+	qualityWeightDuration = 0.2
+	qualityWeightCentroid = 0.3
+)
+
+// SampleQuality 样本质量评分
+type SampleQuality struct {
+	SNR              float64 // 信噪比（dB）
+	Clipping         float64 // 削波样本比例
+	Duration         float64 // 时长（秒）
+	CentroidDistance float64 // 偏离所属情感均值最多的特征偏离了几个标准差
+	Score            float64 // 综合质量分 0~1
+}
+
+// measureSampleQuality 计算样本的信号质量指标，context 为样本所在的完整录音，用于估计底噪
+// 与情感中心的距离依赖整个样本库，由 scoreQuality 补全
+func measureSampleQuality(samples, context []float64, sampleRate int) *SampleQuality {
+	quality := &SampleQuality{Duration: float64(len(samples)) / float64(sampleRate)}
+	if len(samples) == 0 {
+		return quality
+	}
+
+	clipped := 0
+	for _, v := range samples {
+		if math.Abs(v) >= qualityClipLevel {
+			clipped++
+		}
+	}
+	quality.Clipping = float64(clipped) / float64(len(samples))
+
+	signal := math.Sqrt(calculateEnergy(samples) / float64(len(samples)))
+	noise := max(noiseFloor(context, sampleRate), qualityNoiseFloor)
+	quality.SNR = 20 * math.Log10(max(signal, qualityNoiseFloor)/noise)
+	return quality
+}
+
+// noiseFloor 以帧RMS的低分位数估计录音的底噪
+func noiseFloor(samples []float64, sampleRate int) float64 {
+	frameSize := max(1, int(segmentFrameDuration*float64(sampleRate)))
+	var levels []float64
+	for start := 0; start < len(samples); start += frameSize {
+		end := start + frameSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+		frame := samples[start:end]
+		levels = append(levels, math.Sqrt(calculateEnergy(frame)/float64(len(frame))))
+	}
+	if len(levels) == 0 {
+		return 0
+	}
+	sort.Float64s(levels)
+	return levels[int(qualityNoisePercentile*float64(len(levels)-1))]
+}
+
+// clampUnit 将数值限制在 [0, 1]
+func clampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// score 根据各项指标计算综合质量分：各项得分的加权几何平均，任一项为零时总分为零
+func (q *SampleQuality) score() float64 {
+	snr := clampUnit(q.SNR / qualityGoodSNR)
+	clipping := clampUnit(1 - q.Clipping/qualityMaxClipping)
+	duration := 0.0
+	if q.Duration >= minSegmentDuration && q.Duration <= qualityMaxDuration {
+		duration = 1
+	}
+	// 偏离 qualityNearCentroid 个标准差以内满分，qualityFarCentroid 个以外零分
+	centroid := clampUnit((qualityFarCentroid - q.CentroidDistance) / (qualityFarCentroid - qualityNearCentroid))
+
+	return math.Pow(snr, qualityWeightSNR) * math.Pow(clipping, qualityWeightClipping) *
+		math.Pow(duration, qualityWeightDuration) * math.Pow(centroid, qualityWeightCentroid)
+}
+
+// featureValues 按固定顺序列出特征值，内置特征之后是自定义特征
+func featureValues(f AudioFeature) []float64 {
+	return append([]float64{
+		f.ZeroCrossRate, f.Energy, f.Pitch, f.Duration, f.PeakFreq,
+		f.RootMeanSquare, f.SpectralCentroid, f.SpectralRolloff, f.FundamentalFreq,
+	}, customFeatureValues(f)...)
+}
+
+// centroidDistance 样本偏离情感均值最多的特征偏离了几个标准差，没有离散度的特征不参与
+func centroidDistance(feature AudioFeature, stats EmotionStatistics) float64 {
+	values := featureValues(feature)
+	means := featureValues(stats.MeanFeature)
+	stdDevs := featureValues(stats.StdDevFeature)
+
+	distance := 0.0
+	for i, v := range values {
+		if stdDevs[i] > 0 {
+			distance = math.Max(distance, math.Abs(v-means[i])/stdDevs[i])
+		}
+	}
+	return distance
+}
+
+// scoreQuality 按当前统计信息补全各样本到情感中心的距离并计算综合质量分，没有质量指标的样本跳过
+func (sl *SampleLibrary) scoreQuality() {
+	for emotion, samples := range sl.Samples {
+		stats, ok := sl.Statistics[emotion]
+		if !ok {
+			continue
+		}
+		for _, sample := range samples {
+			if sample.Quality == nil {
+				continue
+			}
+			sample.Quality.CentroidDistance = centroidDistance(sample.Features, stats)
+			sample.Quality.Score = sample.Quality.score()
+		}
+	}
+}
+
+// Prune 剔除质量分低于阈值的样本并重新计算统计信息，返回剔除的样本数
+// 没有质量评分的样本（旧样本库）保留；样本被全部剔除的情感从样本库中移除
+func (sl *SampleLibrary) Prune(minQuality float64) int {
+	removed := 0
+	for emotion, samples := range sl.Samples {
+		kept := samples[:0]
+		for _, sample := range samples {
+			if sample.Quality != nil && sample.Quality.Score < minQuality {
+				log.Printf("剔除低质量样本: %s (情感=%s, 质量分=%.2f)", sample.FilePath, emotion, sample.Quality.Score)
+				removed++
+				continue
+			}
+			kept = append(kept, sample)
+		}
+		if len(kept) == 0 {
+			delete(sl.Samples, emotion)
+			delete(sl.Statistics, emotion)
+			continue
+		}
+		sl.Samples[emotion] = kept
+	}
+
+	if removed > 0 {
+		sl.NeedUpdate = true
+		sl.updateStatistics()
+	}
+	return removed
+}
+
+func runLibraryPrune(args []string) error {
+	fs := newFlagSet("library prune")
+	minQuality := fs.Float64("min-quality", DefaultMinSampleQuality, "最低质量分，低于该值的样本被剔除")
+	output := fs.String("o", "", "输出样本库路径，为空时覆盖输入文件")
+	minSampleRate := fs.Int("min-sample-rate", 0, "剔除源音频采样率低于该值（Hz）的样本，0表示不剔除")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: library prune [-min-quality 0.5] [-min-sample-rate 0] [-o path] <library.json>")
+	}
+	input := fs.Arg(0)
+	if *output == "" {
+		*output = input
+	}
+
+	processor := NewSampleProcessor(AudioStreamConfig{})
+	if err := processor.Library.LoadFromFile(input); err != nil {
+		return fmt.Errorf("load library: %v", err)
+	}
+	if *minSampleRate > 0 {
+		removed := processor.Library.ExcludeLowSampleRate(*minSampleRate)
+		log.Printf("共剔除 %d 个采样率低于 %d Hz 的样本", removed, *minSampleRate)
+	}
+	removed := processor.Library.Prune(*minQuality)
+	log.Printf("共剔除 %d 个质量分低于 %.2f 的样本", removed, *minQuality)
+	return processor.ExportLibrary(*output)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// TestSampleQuality 测试样本质量评分与剔除
+// 测试内容：
+// 1. 静默背景中的干净叫声信噪比高、无削波；削波噪声与过短片段得分低
+// 2. 远离情感中心的样本距离更大、质量分更低
+// 3. Prune 剔除低分样本并重算统计，无评分的旧样本保留，样本全被剔除的情感被移除
+// 4. library prune 命令读写样本库文件
+func TestSampleQuality(t *testing.T) {
+	const rate = 8000
+	tone := generateTestAudio(400, 0.5, rate)
+	for i := range tone {
+		tone[i] *= 0.5
+	}
+	context := append(append(make([]float64, rate), tone...), make([]float64, rate)...)
+
+	clean := measureSampleQuality(tone, context, rate)
+	if clean.SNR < qualityGoodSNR || clean.Clipping != 0 || clean.Duration != 0.5 {
+		t.Errorf("clean quality = %+v, want high SNR, no clipping, 0.5s", clean)
+	}
+	if score := clean.score(); score < 0.99 {
+		t.Errorf("clean score = %.3f, want 1", score)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]float64, rate)
+	for i := range noise {
+		noise[i] = rng.Float64()*4 - 2
+		if noise[i] > 1 {
+			noise[i] = 1
+		} else if noise[i] < -1 {
+			noise[i] = -1
+		}
+	}
+	garbage := measureSampleQuality(noise, noise, rate)
+	if garbage.Clipping < 0.4 || garbage.SNR > 3 {
+		t.Errorf("garbage quality = %+v, want heavy clipping and low SNR", garbage)
+	}
+	if score := garbage.score(); score > DefaultMinSampleQuality {
+		t.Errorf("garbage score = %.3f, want below %.2f", score, DefaultMinSampleQuality)
+	}
+
+	short := measureSampleQuality(tone[:rate/50], context, rate)
+	if short.score() >= clean.score() {
+		t.Errorf("short score = %.3f, want below clean %.3f", short.score(), clean.score())
+	}
+
+	processor := NewSampleProcessor(AudioStreamConfig{})
+	library := processor.Library
+	for i, energy := range []float64{1.0, 1.1, 0.9, 1.05, 0.95, 1.0, 1.1, 0.9, 1.05, 0.95, 9} {
+		q := *clean
+		library.Samples["happy"] = append(library.Samples["happy"], AudioSample{
+			FilePath: filepath.Join("happy", string(rune('a'+i))),
+			Emotion:  "happy",
+			Features: AudioFeature{Energy: energy, Pitch: 400},
+			Quality:  &q,
+		})
+	}
+	q := *garbage
+	library.Samples["angry"] = []AudioSample{{FilePath: "angry/a", Emotion: "angry", Features: AudioFeature{Energy: 5}, Quality: &q}}
+	library.Samples["legacy"] = []AudioSample{{FilePath: "legacy/a", Emotion: "legacy", Features: AudioFeature{Energy: 2}}}
+	library.NeedUpdate = true
+	library.updateStatistics()
+	library.scoreQuality()
+
+	happy := library.Samples["happy"]
+	outlier := happy[len(happy)-1].Quality
+	if outlier.CentroidDistance <= happy[0].Quality.CentroidDistance || outlier.Score >= happy[0].Quality.Score {
+		t.Errorf("outlier quality = %+v, typical = %+v; want outlier farther and lower", outlier, happy[0].Quality)
+	}
+
+	path := filepath.Join(t.TempDir(), "library.json")
+	if err := processor.ExportLibrary(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := runLibrary([]string{"prune", "-min-quality", "0.5", path}); err != nil {
+		t.Fatalf("runLibrary(prune) error = %v", err)
+	}
+	pruned := NewSampleLibrary()
+	if err := pruned.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pruned.Samples["angry"]; ok {
+		t.Error("angry samples should be pruned")
+	}
+	if _, ok := pruned.Statistics["angry"]; ok {
+		t.Error("angry statistics should be removed")
+	}
+	if len(pruned.Samples["legacy"]) != 1 {
+		t.Error("unscored legacy sample should be kept")
+	}
+	if n := len(pruned.Samples["happy"]); n != 10 {
+		t.Errorf("happy samples = %d, want 10 after pruning the outlier", n)
+	}
+	if stats := pruned.Statistics["happy"]; stats.SampleCount != 10 || stats.MeanFeature.Energy > 1.1 {
+		t.Errorf("happy statistics = %+v, want recomputed without outlier", stats)
+	}
+	if pruned.Samples["happy"][0].Quality == nil {
+		t.Error("quality should survive export and reload")
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// 样本库统计
+//
+// 识别不准时用户往往不知道是样本库不够：某个情感只有两三个样本，匹配时几乎不可能选中它。
+// GET /library/stats 返回当前样本库各情感的样本数与特征均值/标准差、特征提取版本与加载时间，
+// 样本数少于 MinRecommendedSamples 的情感列在 needsSamples 中，客户端可以据此提示
+// "'sad' 只有 2 个样本，请再录制一些"。样本库被 /admin/library/rebuild 替换后统计随之更新。
+
+// FeatureExtractorVersion 特征提取的版本，特征的计算方式改变时递增，不同版本提取的样本库不可混用
+// 2：特征提取器的FFT改为补零到2的幂（原递归实现对25ms帧丢样本），峰值频率等频谱特征随之改变
+const FeatureExtractorVersion = "2"
+
+// MinRecommendedSamples 每种情感建议的最少样本数
+const MinRecommendedSamples = 5
+
+// LibraryStatsProvider 能提供当前样本库统计的处理器（Engine）
+type LibraryStatsProvider interface {
+	LibraryStats() LibraryStats
+}
+
+// EmotionLibraryStats 一种情感的样本统计
+type EmotionLibraryStats struct {
+	Samples int                `json:"samples"`
+	Mean    map[string]float64 `json:"mean"`   // 特征名 -> 均值
+	StdDev  map[string]float64 `json:"stdDev"` // 特征名 -> 标准差
+}
+
+// LibraryStats 样本库统计
+type LibraryStats struct {
+	ExtractorVersion string                         `json:"extractorVersion"` // 样本库记录的特征提取版本，旧版样本库为空
+	StaleExtractor   bool                           `json:"staleExtractor"`   // 与当前 FeatureExtractorVersion 不一致，需重建样本库
+	LoadedAt         int64                          `json:"loadedAt"`         // 样本库加载或替换的时间（毫秒时间戳）
+	TotalSamples     int                            `json:"totalSamples"`
+	Emotions         map[string]EmotionLibraryStats `json:"emotions"`
+	NeedsSamples     []string                       `json:"needsSamples"` // 样本数少于 MinRecommendedSamples 的情感，按名称排序
+	MinRecommended   int                            `json:"minRecommended"`
+}
+
+// ComputeLibraryStats 按样本重新计算各情感的统计，不修改样本库
+func ComputeLibraryStats(library *SampleLibrary, loadedAt time.Time) LibraryStats {
+	stats := LibraryStats{
+		ExtractorVersion: library.ExtractorVersion,
+		StaleExtractor:   library.checkExtractorVersion() != nil,
+		LoadedAt:         loadedAt.UnixMilli(),
+		Emotions:         make(map[string]EmotionLibraryStats, len(library.Samples)),
+		NeedsSamples:     []string{},
+		MinRecommended:   MinRecommendedSamples,
+	}
+	names := featureNames()
+	for emotion, samples := range library.Samples {
+		stats.TotalSamples += len(samples)
+		if len(samples) < MinRecommendedSamples {
+			stats.NeedsSamples = append(stats.NeedsSamples, emotion)
+		}
+
+		sums := make([]float64, len(names))
+		for _, sample := range samples {
+			for i, v := range featureValues(sample.Features) {
+				sums[i] += v
+			}
+		}
+		squares := make([]float64, len(names))
+		for _, sample := range samples {
+			for i, v := range featureValues(sample.Features) {
+				d := v - sums[i]/float64(len(samples))
+				squares[i] += d * d
+			}
+		}
+
+		entry := EmotionLibraryStats{
+			Samples: len(samples),
+			Mean:    make(map[string]float64, len(names)),
+			StdDev:  make(map[string]float64, len(names)),
+		}
+		if len(samples) > 0 {
+			for i, name := range names {
+				entry.Mean[name] = sums[i] / float64(len(samples))
+				entry.StdDev[name] = math.Sqrt(squares[i] / float64(len(samples)))
+			}
+		}
+		stats.Emotions[emotion] = entry
+	}
+	sort.Strings(stats.NeedsSamples)
+	return stats
+}
+
+// LibraryStats 当前样本库的统计
+func (e *Engine) LibraryStats() LibraryStats {
+	e.libraryMu.RLock()
+	library, loadedAt := e.Library, e.libraryLoadedAt
+	e.libraryMu.RUnlock()
+	return ComputeLibraryStats(library, loadedAt)
+}
+
+// handleLibraryStats 样本库统计：GET /library/stats
+func (s *AudioServer) handleLibraryStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	provider, ok := s.processor.(LibraryStatsProvider)
+	if !ok {
+		writeError(w, r, http.StatusNotImplemented, ErrCodeNotSupported, "当前处理器不支持样本库统计", "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(provider.LibraryStats())
+}
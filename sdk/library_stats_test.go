@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLibraryStats 测试样本库统计
+// 测试内容：
+// 1. 各情感的样本数、特征均值与标准差，样本不足的情感列入 needsSamples
+// 2. 引擎替换样本库后统计与加载时间随之更新
+// 3. /library/stats 只接受 GET，处理器替身返回 501
+func TestLibraryStats(t *testing.T) {
+	library := NewSampleLibrary()
+	for _, pitch := range []float64{400, 600} {
+		library.AddSample(AudioSample{Emotion: "sad", Features: AudioFeature{Pitch: pitch, Energy: 0.5}})
+	}
+	for i := 0; i < MinRecommendedSamples; i++ {
+		library.AddSample(AudioSample{Emotion: "purr", Features: AudioFeature{Pitch: 100}})
+	}
+
+	loadedAt := time.UnixMilli(1700000000000)
+	stats := ComputeLibraryStats(library, loadedAt)
+	if stats.TotalSamples != 2+MinRecommendedSamples || stats.LoadedAt != 1700000000000 || stats.ExtractorVersion != FeatureExtractorVersion {
+		t.Errorf("stats = %+v", stats)
+	}
+	sad := stats.Emotions["sad"]
+	if sad.Samples != 2 || sad.Mean["Pitch"] != 500 || math.Abs(sad.StdDev["Pitch"]-100) > 1e-9 || sad.StdDev["Energy"] != 0 {
+		t.Errorf("sad = %+v, want 2 samples, pitch 500±100", sad)
+	}
+	if len(stats.NeedsSamples) != 1 || stats.NeedsSamples[0] != "sad" {
+		t.Errorf("needsSamples = %v, want [sad]", stats.NeedsSamples)
+	}
+
+	engine := NewEngine(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}, library)
+	before := engine.LibraryStats()
+	replacement := NewSampleLibrary()
+	replacement.AddSample(AudioSample{Emotion: "hiss", Features: AudioFeature{Pitch: 800}})
+	time.Sleep(2 * time.Millisecond)
+	if err := engine.SetLibrary(replacement); err != nil {
+		t.Fatal(err)
+	}
+	after := engine.LibraryStats()
+	if after.TotalSamples != 1 || after.Emotions["hiss"].Samples != 1 || after.LoadedAt <= before.LoadedAt {
+		t.Errorf("stats after SetLibrary = %+v (before loadedAt %d)", after, before.LoadedAt)
+	}
+
+	get := func(server *AudioServer, method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.handleLibraryStats(rec, httptest.NewRequest(method, "/library/stats", nil))
+		return rec
+	}
+	rec := get(NewAudioServer(engine), http.MethodGet)
+	var response LibraryStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); rec.Code != http.StatusOK || err != nil || response.TotalSamples != 1 {
+		t.Errorf("GET status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := get(NewAudioServer(engine), http.MethodPost); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+	if rec := get(NewAudioServer(NewMockAudioProcessor()), http.MethodGet); rec.Code != http.StatusNotImplemented {
+		t.Errorf("mock processor status = %d, want 501", rec.Code)
+	}
+}
+
+// TestLibraryExtractorVersion 测试样本库记录的特征提取版本
+// 测试内容：
+// 1. 新建的样本库记录当前版本，保存后重新加载版本不变，统计中 staleExtractor 为 false
+// 2. 未记录版本的旧样本库加载到已有版本的样本库对象时版本被重置为空，统计标记为过期
+// 3. 旧版本的样本库不能作为 library build -base 的基础样本库
+func TestLibraryExtractorVersion(t *testing.T) {
+	dir := t.TempDir()
+	library := NewSampleLibrary()
+	library.AddSample(AudioSample{Emotion: "purr", Features: AudioFeature{Pitch: 100}})
+	path := dir + "/library.json"
+	if err := library.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewSampleLibrary()
+	if err := loaded.LoadFromFile(path); err != nil || loaded.ExtractorVersion != FeatureExtractorVersion {
+		t.Fatalf("reloaded version = %q, %v", loaded.ExtractorVersion, err)
+	}
+	if stats := ComputeLibraryStats(loaded, time.Now()); stats.StaleExtractor || stats.ExtractorVersion != FeatureExtractorVersion {
+		t.Errorf("stats = %+v, want current extractor", stats)
+	}
+
+	legacy := []byte(`{"Samples": {"purr": [{"Emotion": "purr", "Features": {"Pitch": 100}}]}}`)
+	if err := loaded.LoadFromBytes(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ExtractorVersion != "" || loaded.checkExtractorVersion() == nil {
+		t.Errorf("legacy library version = %q, want empty and stale", loaded.ExtractorVersion)
+	}
+	if stats := ComputeLibraryStats(loaded, time.Now()); !stats.StaleExtractor {
+		t.Errorf("legacy stats = %+v, want staleExtractor", stats)
+	}
+
+	legacyPath := dir + "/legacy.json"
+	if err := os.WriteFile(legacyPath, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+	samples := dir + "/samples"
+	os.MkdirAll(samples, 0755)
+	err := runLibraryBuild([]string{"--from-dir", samples, "-base", legacyPath, "-o", dir + "/out.json"})
+	if err == nil || !strings.Contains(err.Error(), "extractor version") {
+		t.Errorf("build on a stale base = %v, want extractor version error", err)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 样本库可视化
+//
+// 识别不准时，先要确认样本库里各情感的特征本身是否可分。library viz 对所有样本的特征向量做
+// 主成分分析（PCA），投影到前两个主成分并导出 JSON/CSV，用任意绘图工具画散点图即可看出
+// 各情感是否形成独立的簇。特征量纲差异很大（音高数百Hz、能量不到1），投影前先逐特征标准化。
+
+// featureLabels 与 featureValues 顺序一致的特征名
+var featureLabels = []string{
+	"ZeroCrossRate", "Energy", "Pitch", "Duration", "PeakFreq",
+	"RootMeanSquare", "SpectralCentroid", "SpectralRolloff", "FundamentalFreq",
+}
+
+// pcaIterations 幂迭代求主成分的最大迭代次数
+const pcaIterations = 500
+
+// ProjectedSample 投影到二维平面的样本
+type ProjectedSample struct {
+	FilePath string   `json:"filePath"`
+	Emotion  string   `json:"emotion"`
+	X        float64  `json:"x"`                 // 第一主成分坐标
+	Y        float64  `json:"y"`                 // 第二主成分坐标
+	Quality  *float64 `json:"quality,omitempty"` // 样本质量分，旧样本库中为空
+}
+
+// LibraryProjection 样本库的二维投影
+type LibraryProjection struct {
+	Method            string               `json:"method"`            // 投影方法，目前为 pca
+	Features          []string             `json:"features"`          // 参与投影的特征（无离散度的特征被排除）
+	Components        [][]float64          `json:"components"`        // 两个主成分在标准化特征上的载荷
+	ExplainedVariance []float64            `json:"explainedVariance"` // 两个主成分解释的方差比例
+	Samples           []ProjectedSample    `json:"samples"`
+	Centroids         map[string][]float64 `json:"centroids"` // 各情感在投影平面上的中心 [x, y]
+}
+
+// ProjectLibrary 对样本库做PCA并投影到前两个主成分
+func ProjectLibrary(library *SampleLibrary) (*LibraryProjection, error) {
+	emotions := make([]string, 0, len(library.Samples))
+	for emotion := range library.Samples {
+		emotions = append(emotions, emotion)
+	}
+	sort.Strings(emotions)
+
+	var samples []AudioSample
+	for _, emotion := range emotions {
+		samples = append(samples, library.Samples[emotion]...)
+	}
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("need at least 2 samples to project, got %d", len(samples))
+	}
+
+	// 逐特征标准化，排除所有样本取值相同的特征
+	rows := make([][]float64, len(samples))
+	for i, sample := range samples {
+		rows[i] = featureValues(sample.Features)
+	}
+	names := featureNames()
+	var columns []int
+	var means, stdDevs []float64
+	for j := range names {
+		mean := 0.0
+		for _, row := range rows {
+			mean += row[j]
+		}
+		mean /= float64(len(rows))
+		variance := 0.0
+		for _, row := range rows {
+			variance += (row[j] - mean) * (row[j] - mean)
+		}
+		stdDev := math.Sqrt(variance / float64(len(rows)))
+		if stdDev == 0 || math.IsNaN(stdDev) || math.IsInf(stdDev, 0) {
+			continue
+		}
+		columns = append(columns, j)
+		means = append(means, mean)
+		stdDevs = append(stdDevs, stdDev)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("all features are constant, nothing to project")
+	}
+
+	data := make([][]float64, len(rows))
+	for i, row := range rows {
+		data[i] = make([]float64, len(columns))
+		for k, j := range columns {
+			data[i][k] = (row[j] - means[k]) / stdDevs[k]
+		}
+	}
+
+	components, variances := principalComponents(covariance(data), 2)
+	total := float64(len(columns)) // 标准化后总方差等于特征数
+
+	projection := &LibraryProjection{
+		Method:            "pca",
+		Components:        components,
+		ExplainedVariance: make([]float64, len(variances)),
+		Samples:           make([]ProjectedSample, len(samples)),
+		Centroids:         make(map[string][]float64),
+	}
+	for _, j := range columns {
+		projection.Features = append(projection.Features, names[j])
+	}
+	for i, v := range variances {
+		projection.ExplainedVariance[i] = v / total
+	}
+
+	counts := make(map[string]int)
+	for i, sample := range samples {
+		point := ProjectedSample{
+			FilePath: sample.FilePath,
+			Emotion:  sample.Emotion,
+			X:        dot(data[i], components[0]),
+			Y:        dot(data[i], components[1]),
+		}
+		if sample.Quality != nil {
+			score := sample.Quality.Score
+			point.Quality = &score
+		}
+		projection.Samples[i] = point
+
+		if _, ok := projection.Centroids[sample.Emotion]; !ok {
+			projection.Centroids[sample.Emotion] = []float64{0, 0}
+		}
+		projection.Centroids[sample.Emotion][0] += point.X
+		projection.Centroids[sample.Emotion][1] += point.Y
+		counts[sample.Emotion]++
+	}
+	for emotion, centroid := range projection.Centroids {
+		centroid[0] /= float64(counts[emotion])
+		centroid[1] /= float64(counts[emotion])
+	}
+
+	return projection, nil
+}
+
+// covariance 已中心化数据的协方差矩阵
+func covariance(data [][]float64) [][]float64 {
+	n := len(data[0])
+	cov := make([][]float64, n)
+	for a := range cov {
+		cov[a] = make([]float64, n)
+		for b := range cov[a] {
+			for _, row := range data {
+				cov[a][b] += row[a] * row[b]
+			}
+			cov[a][b] /= float64(len(data))
+		}
+	}
+	return cov
+}
+
+// principalComponents 用幂迭代加收缩求对称矩阵最大的 k 个特征向量与特征值
+// 特征数少于 k 时，其余主成分为零向量
+func principalComponents(matrix [][]float64, k int) ([][]float64, []float64) {
+	n := len(matrix)
+	work := make([][]float64, n)
+	for i := range matrix {
+		work[i] = append([]float64(nil), matrix[i]...)
+	}
+
+	components := make([][]float64, k)
+	values := make([]float64, k)
+	for c := 0; c < k; c++ {
+		components[c] = make([]float64, n)
+		if c >= n {
+			continue
+		}
+
+		// 确定性的初始向量，避免结果随运行变化
+		vector := make([]float64, n)
+		for i := range vector {
+			vector[i] = 1 / math.Sqrt(float64(n)) * (1 + float64(i)/float64(n))
+		}
+		var value float64
+		for iter := 0; iter < pcaIterations; iter++ {
+			next := make([]float64, n)
+			for i := range work {
+				next[i] = dot(work[i], vector)
+			}
+			norm := math.Sqrt(dot(next, next))
+			if norm < 1e-12 {
+				break
+			}
+			for i := range next {
+				next[i] /= norm
+			}
+			delta := 0.0
+			for i := range next {
+				delta = math.Max(delta, math.Abs(next[i]-vector[i]))
+			}
+			vector, value = next, norm
+			if delta < 1e-10 {
+				break
+			}
+		}
+
+		// 统一符号：最大载荷为正
+		largest := 0
+		for i := range vector {
+			if math.Abs(vector[i]) > math.Abs(vector[largest]) {
+				largest = i
+			}
+		}
+		if vector[largest] < 0 {
+			for i := range vector {
+				vector[i] = -vector[i]
+			}
+		}
+
+		components[c], values[c] = vector, value
+		for i := range work {
+			for j := range work[i] {
+				work[i][j] -= value * vector[i] * vector[j]
+			}
+		}
+	}
+	return components, values
+}
+
+// dot 向量点积
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// WriteCSV 以CSV导出投影结果，每行一个样本
+func (p *LibraryProjection) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"filePath", "emotion", "x", "y", "quality"})
+	for _, sample := range p.Samples {
+		quality := ""
+		if sample.Quality != nil {
+			quality = strconv.FormatFloat(*sample.Quality, 'f', 4, 64)
+		}
+		writer.Write([]string{
+			sample.FilePath,
+			sample.Emotion,
+			strconv.FormatFloat(sample.X, 'f', 6, 64),
+			strconv.FormatFloat(sample.Y, 'f', 6, 64),
+			quality,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func runLibraryViz(args []string) error {
+	fs := newFlagSet("library viz")
+	output := fs.String("o", "", "输出路径，为空时写入数据目录下的 library_projection.json")
+	dataDir := addDataDirFlag(fs)
+	format := fs.String("format", "", "输出格式 json/csv，为空时按输出文件扩展名判断")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: library viz [-o path] [-data-dir dir] [-format json|csv] <library.json>")
+	}
+	if *output == "" {
+		name := "library_projection.json"
+		if *format == "csv" {
+			name = "library_projection.csv"
+		}
+		path, err := dataPath(*dataDir, name)
+		if err != nil {
+			return err
+		}
+		*output = path
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q, want json or csv", *format)
+	}
+
+	library := NewSampleLibrary()
+	if err := library.LoadFromFile(fs.Arg(0)); err != nil {
+		return fmt.Errorf("load library: %v", err)
+	}
+	projection, err := ProjectLibrary(library)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if *format == "csv" {
+		err = projection.WriteCSV(file)
+	} else {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(projection)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("样本库投影已导出到 %s: %d 个样本, 主成分解释方差 %.1f%% / %.1f%%",
+		*output, len(projection.Samples), projection.ExplainedVariance[0]*100, projection.ExplainedVariance[1]*100)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProjectLibrary 测试样本库二维投影
+// 测试内容：
+// 1. 两个特征上分离的情感在第一主成分上分开，常量特征被排除
+// 2. 解释方差比例与主成分正交性
+// 3. library viz 按扩展名导出 JSON 与 CSV
+func TestProjectLibrary(t *testing.T) {
+	processor := NewSampleProcessor(AudioStreamConfig{})
+	library := processor.Library
+	for i := 0; i < 10; i++ {
+		jitter := float64(i%5) * 0.01
+		library.Samples["happy"] = append(library.Samples["happy"], AudioSample{
+			FilePath: "happy.wav", Emotion: "happy",
+			Features: AudioFeature{Energy: 0.1 + jitter, Pitch: 300 + jitter*100, Duration: 1},
+		})
+		library.Samples["angry"] = append(library.Samples["angry"], AudioSample{
+			FilePath: "angry.wav", Emotion: "angry",
+			Features: AudioFeature{Energy: 0.8 + jitter, Pitch: 700 - jitter*100, Duration: 1},
+		})
+	}
+	score := 0.9
+	library.Samples["happy"][0].Quality = &SampleQuality{Score: score}
+
+	projection, err := ProjectLibrary(library)
+	if err != nil {
+		t.Fatalf("ProjectLibrary() error = %v", err)
+	}
+	if len(projection.Features) != 2 || projection.Features[0] != "Energy" || projection.Features[1] != "Pitch" {
+		t.Errorf("features = %v, want [Energy Pitch]", projection.Features)
+	}
+	if projection.ExplainedVariance[0] < 0.9 {
+		t.Errorf("explained variance = %v, want first component > 0.9", projection.ExplainedVariance)
+	}
+	if d := dot(projection.Components[0], projection.Components[1]); math.Abs(d) > 1e-6 {
+		t.Errorf("components not orthogonal: dot = %v", d)
+	}
+
+	happy, angry := projection.Centroids["happy"], projection.Centroids["angry"]
+	if math.Abs(happy[0]-angry[0]) < 1.5 {
+		t.Errorf("centroids happy=%v angry=%v, want separated on x", happy, angry)
+	}
+	for _, sample := range projection.Samples {
+		centroid := projection.Centroids[sample.Emotion]
+		if math.Abs(sample.X-centroid[0]) > math.Abs(happy[0]-angry[0])/2 {
+			t.Errorf("sample %+v closer to the other cluster", sample)
+		}
+	}
+
+	dir := t.TempDir()
+	libraryPath := filepath.Join(dir, "library.json")
+	if err := processor.ExportLibrary(libraryPath); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonPath := filepath.Join(dir, "projection.json")
+	if err := runLibrary([]string{"viz", "-o", jsonPath, libraryPath}); err != nil {
+		t.Fatalf("runLibrary(viz json) error = %v", err)
+	}
+	data, _ := os.ReadFile(jsonPath)
+	var decoded LibraryProjection
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Samples) != 20 || decoded.Method != "pca" {
+		t.Errorf("json projection = %+v, err = %v", decoded, err)
+	}
+
+	csvPath := filepath.Join(dir, "projection.csv")
+	if err := runLibrary([]string{"viz", "-o", csvPath, libraryPath}); err != nil {
+		t.Fatalf("runLibrary(viz csv) error = %v", err)
+	}
+	file, _ := os.Open(csvPath)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil || len(records) != 21 || records[0][0] != "filePath" {
+		t.Fatalf("csv records = %v, err = %v", records, err)
+	}
+	qualities := 0
+	for _, record := range records[1:] {
+		if record[4] != "" {
+			qualities++
+		}
+	}
+	if qualities != 1 {
+		t.Errorf("csv rows with quality = %d, want 1", qualities)
+	}
+
+	if err := runLibrary([]string{"viz", "-format", "png", libraryPath}); err == nil {
+		t.Error("unknown format should fail")
+	}
+	if _, err := ProjectLibrary(NewSampleLibrary()); err == nil {
+		t.Error("empty library should fail")
+	}
+}
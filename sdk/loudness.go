@@ -0,0 +1,183 @@
+package main
+
+import "math"
+
+// 响度测量与归一化（EBU R128 / ITU-R BS.1770）
+//
+// 不同手机录制的样本增益差异很大，直接提取的Energy/RMS特征不可比。
+// 样本工具可以在提取特征前先把音频归一化到统一的积分响度，使各样本对样本库统计信息的贡献处于同一量级。
+// 运行时引擎不对实时音频归一化，归一化后的样本 Energy/RMS 与运行时不可比，因此默认关闭
+// （library build -normalize-loudness 开启）。
+
+const (
+	DefaultTargetLoudness = -23.0 // EBU R128 目标响度 (LUFS)
+
+	loudnessAbsoluteGate = -70.0 // 绝对门限 (LUFS)
+	loudnessRelativeGate = -10.0 // 相对门限 (LU)
+	loudnessBlockSeconds = 0.4   // 门限块长度 400ms
+	loudnessBlockStep    = 0.1   // 块步进 100ms（75%重叠）
+	loudnessMaxPeak      = 0.999 // 归一化后允许的最大峰值，避免削波
+)
+
+// biquad 二阶IIR滤波器（a0已归一化为1）
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+}
+
+// apply 对信号应用滤波器（直接II型转置结构）
+func (f biquad) apply(x []float64) []float64 {
+	y := make([]float64, len(x))
+	var z1, z2 float64
+	for i, in := range x {
+		out := f.b0*in + z1
+		z1 = f.b1*in - f.a1*out + z2
+		z2 = f.b2*in - f.a2*out
+		y[i] = out
+	}
+	return y
+}
+
+// kWeightingFilters 按采样率计算K加权滤波器系数（高频搁架 + RLB高通）
+// 当采样率过低、搁架滤波器中心频率超过奈奎斯特频率时，仅使用高通部分
+func kWeightingFilters(sampleRate int) []biquad {
+	fs := float64(sampleRate)
+	filters := make([]biquad, 0, 2)
+
+	// 第一级：高频搁架滤波器，模拟头部声学效应
+	const shelfFreq = 1681.974450955533
+	if shelfFreq < fs/2 {
+		const gain = 3.999843853973347
+		const q = 0.7071752369554196
+		k := math.Tan(math.Pi * shelfFreq / fs)
+		vh := math.Pow(10, gain/20)
+		vb := math.Pow(vh, 0.4996667741545416)
+		a0 := 1 + k/q + k*k
+		filters = append(filters, biquad{
+			b0: (vh + vb*k/q + k*k) / a0,
+			b1: 2 * (k*k - vh) / a0,
+			b2: (vh - vb*k/q + k*k) / a0,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		})
+	}
+
+	// 第二级：RLB高通滤波器
+	const highPassFreq = 38.13547087602444
+	const highPassQ = 0.5003270373238773
+	k := math.Tan(math.Pi * highPassFreq / fs)
+	a0 := 1 + k/highPassQ + k*k
+	filters = append(filters, biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/highPassQ + k*k) / a0,
+	})
+
+	return filters
+}
+
+// MeasureLoudness 测量单声道音频的积分响度 (LUFS)
+// 信号为空或全部低于绝对门限时返回 -Inf
+func MeasureLoudness(samples []float64, sampleRate int) float64 {
+	if len(samples) == 0 || sampleRate <= 0 {
+		return math.Inf(-1)
+	}
+
+	weighted := samples
+	for _, f := range kWeightingFilters(sampleRate) {
+		weighted = f.apply(weighted)
+	}
+
+	// 计算400ms块的均方值，样本不足一个块时整体作为一个块
+	blockSize := int(loudnessBlockSeconds * float64(sampleRate))
+	stepSize := int(loudnessBlockStep * float64(sampleRate))
+	if blockSize <= 0 || blockSize > len(weighted) {
+		blockSize = len(weighted)
+	}
+	if stepSize <= 0 {
+		stepSize = blockSize
+	}
+
+	var blocks []float64
+	for start := 0; start+blockSize <= len(weighted); start += stepSize {
+		sum := 0.0
+		for _, v := range weighted[start : start+blockSize] {
+			sum += v * v
+		}
+		blocks = append(blocks, sum/float64(blockSize))
+	}
+
+	// 绝对门限
+	gated := make([]float64, 0, len(blocks))
+	for _, z := range blocks {
+		if blockLoudness(z) > loudnessAbsoluteGate {
+			gated = append(gated, z)
+		}
+	}
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+
+	// 相对门限：低于绝对门限后平均响度10LU的块不参与计算
+	relativeGate := blockLoudness(meanOf(gated)) + loudnessRelativeGate
+	final := gated[:0]
+	for _, z := range gated {
+		if blockLoudness(z) > relativeGate {
+			final = append(final, z)
+		}
+	}
+	if len(final) == 0 {
+		return math.Inf(-1)
+	}
+
+	return blockLoudness(meanOf(final))
+}
+
+// NormalizeLoudness 将音频归一化到目标响度，返回归一化后的数据和所施加的增益(dB)
+// 增益会被限制以保证峰值不超过满量程；无法测量响度的信号原样返回
+func NormalizeLoudness(samples []float64, sampleRate int, targetLUFS float64) ([]float64, float64) {
+	loudness := MeasureLoudness(samples, sampleRate)
+	if math.IsInf(loudness, -1) || math.IsNaN(loudness) {
+		return samples, 0
+	}
+
+	gain := math.Pow(10, (targetLUFS-loudness)/20)
+
+	peak := 0.0
+	for _, v := range samples {
+		if a := math.Abs(v); a > peak {
+			peak = a
+		}
+	}
+	if peak > 0 && peak*gain > loudnessMaxPeak {
+		gain = loudnessMaxPeak / peak
+	}
+
+	normalized := make([]float64, len(samples))
+	for i, v := range samples {
+		normalized[i] = v * gain
+	}
+	return normalized, 20 * math.Log10(gain)
+}
+
+// blockLoudness 将均方值转换为响度 (LUFS)
+func blockLoudness(meanSquare float64) float64 {
+	if meanSquare <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+// meanOf 计算平均值
+func meanOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
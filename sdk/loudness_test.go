@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMeasureLoudness 测试响度测量
+// 测试内容：
+// 1. 满量程997Hz正弦波约为-3.01 LUFS（BS.1770参考值）
+// 2. 静音信号返回-Inf
+// 3. 振幅减半响度降低约6dB
+func TestMeasureLoudness(t *testing.T) {
+	sine := generateTestAudio(997, 2.0, 48000)
+	got := MeasureLoudness(sine, 48000)
+	if math.Abs(got-(-3.01)) > 0.1 {
+		t.Errorf("MeasureLoudness(full-scale sine) = %.3f, want -3.01", got)
+	}
+
+	if got := MeasureLoudness(make([]float64, 48000), 48000); !math.IsInf(got, -1) {
+		t.Errorf("MeasureLoudness(silence) = %v, want -Inf", got)
+	}
+
+	half := make([]float64, len(sine))
+	for i, v := range sine {
+		half[i] = v * 0.5
+	}
+	if diff := got - MeasureLoudness(half, 48000); math.Abs(diff-6.02) > 0.1 {
+		t.Errorf("half amplitude loudness delta = %.3f, want 6.02", diff)
+	}
+}
+
+// TestNormalizeLoudness 测试响度归一化
+// 测试内容：
+// 1. 不同增益的样本归一化后响度一致
+// 2. 增益受峰值限制
+func TestNormalizeLoudness(t *testing.T) {
+	quiet := generateTestAudio(440, 1.0, 44100)
+	for i := range quiet {
+		quiet[i] *= 0.05
+	}
+
+	normalized, gain := NormalizeLoudness(quiet, 44100, DefaultTargetLoudness)
+	if gain <= 0 {
+		t.Errorf("gain = %.2f dB, want positive for quiet input", gain)
+	}
+	if got := MeasureLoudness(normalized, 44100); math.Abs(got-DefaultTargetLoudness) > 0.1 {
+		t.Errorf("normalized loudness = %.3f, want %.1f", got, DefaultTargetLoudness)
+	}
+
+	loud := generateTestAudio(440, 1.0, 44100)
+	limited, _ := NormalizeLoudness(loud, 44100, 0)
+	for _, v := range limited {
+		if math.Abs(v) > loudnessMaxPeak+1e-9 {
+			t.Fatalf("peak %.4f exceeds limit %.4f", v, loudnessMaxPeak)
+		}
+	}
+}
@@ -22,8 +22,19 @@ MeowTalk SDK - 猫咪情感识别库
 
 package main
 
+/*
+#include <stdlib.h>
+
+typedef void (*ResultCallback)(const char* streamId, const char* result, void* userData);
+
+static void invokeResultCallback(ResultCallback cb, const char* streamId, const char* result, void* userData) {
+	cb(streamId, result, userData);
+}
+*/
 import "C"
 import (
+	"encoding/json"
+	"strings"
 	"sync"
 	"unsafe"
 )
@@ -67,6 +78,65 @@ func InitSDK(cConfig *C.AudioConfig) C.ErrorCode {
 	return C.ERR_SUCCESS
 }
 
+//export InitSDKFromBundle
+func InitSDKFromBundle(bundlePath *C.char) C.ErrorCode {
+	if bundlePath == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	// 采样率、缓冲区大小等取自资源包中的默认配置
+	config, err := BundleConfig(C.GoString(bundlePath), "")
+	if err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	if !InitializeSDK(config) {
+		return C.ERR_NOT_INITIALIZED
+	}
+
+	return C.ERR_SUCCESS
+}
+
+//export InitSDKFromSignedBundle
+func InitSDKFromSignedBundle(bundlePath *C.char, publicKeys *C.char) C.ErrorCode {
+	if bundlePath == nil || publicKeys == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	// 资源包须由其中任一公钥签名，运行期间切换的资源包同样校验
+	keys := C.GoString(publicKeys)
+	if strings.TrimSpace(keys) == "" {
+		return C.ERR_INVALID_PARAM
+	}
+	config, err := BundleConfig(C.GoString(bundlePath), keys)
+	if err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	if !InitializeSDK(config) {
+		return C.ERR_NOT_INITIALIZED
+	}
+
+	return C.ERR_SUCCESS
+}
+
+//export VerifyBundle
+func VerifyBundle(bundlePath *C.char, publicKeys *C.char) C.ErrorCode {
+	if bundlePath == nil || publicKeys == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	keys, err := ParseBundlePublicKeys(C.GoString(publicKeys))
+	if err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+	if err := VerifyMeowPack(C.GoString(bundlePath), keys); err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	return C.ERR_SUCCESS
+}
+
 //export StartStream
 func StartStream(streamId *C.char) C.ErrorCode {
 	if streamId == nil {
@@ -81,6 +151,79 @@ func StartStream(streamId *C.char) C.ErrorCode {
 	return C.ERR_SUCCESS
 }
 
+//export StartStreamEx
+func StartStreamEx(streamId *C.char, optionsJSON *C.char, callback C.ResultCallback, userData unsafe.Pointer) C.ErrorCode {
+	if streamId == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	var data []byte
+	if optionsJSON != nil {
+		data = []byte(C.GoString(optionsJSON))
+	}
+	options, err := ParseStreamOptions(data)
+	if err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	// 回调在处理结果的线程上调用，字符串在回调返回后释放
+	id := C.GoString(streamId)
+	var onResult func([]byte)
+	if callback != nil {
+		onResult = func(result []byte) {
+			cStreamID, cResult := C.CString(id), C.CString(string(result))
+			defer C.free(unsafe.Pointer(cStreamID))
+			defer C.free(unsafe.Pointer(cResult))
+			C.invokeResultCallback(callback, cStreamID, cResult, userData)
+		}
+	}
+
+	if err := StartAudioStreamWithOptions(id, options, onResult); err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	return C.ERR_SUCCESS
+}
+
+//export SetStreamPreset
+func SetStreamPreset(streamId *C.char, preset *C.char) C.ErrorCode {
+	if streamId == nil || preset == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	if err := SetStreamFrequencyPreset(C.GoString(streamId), C.GoString(preset)); err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	return C.ERR_SUCCESS
+}
+
+//export SetStreamMode
+func SetStreamMode(streamId *C.char, strategy *C.char) C.ErrorCode {
+	if streamId == nil || strategy == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	if err := SetStreamStrategy(C.GoString(streamId), C.GoString(strategy)); err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	return C.ERR_SUCCESS
+}
+
+//export SetStreamLang
+func SetStreamLang(streamId *C.char, lang *C.char) C.ErrorCode {
+	if streamId == nil || lang == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	if err := SetStreamLanguage(C.GoString(streamId), C.GoString(lang)); err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	return C.ERR_SUCCESS
+}
+
 //export SendAudio
 func SendAudio(streamId *C.char, data *C.uchar, length C.int) C.bool {
 	id := C.GoString(streamId)
@@ -92,12 +235,36 @@ func SendAudio(streamId *C.char, data *C.uchar, length C.int) C.bool {
 func RecvMessage(streamId *C.char) *C.char {
 	id := C.GoString(streamId)
 	result, err := RecvMessage(id)
-	if err != nil || result == nil {
+	if err != nil {
+		return C.CString(string(errorMessage(err)))
+	}
+	if result == nil {
 		return nil
 	}
 	return C.CString(string(result))
 }
 
+//export GetStreamStats
+func GetStreamStats(streamId *C.char) *C.char {
+	id := C.GoString(streamId)
+	stats, err := AudioStreamStats(id)
+	if err != nil {
+		return C.CString(string(errorMessage(err)))
+	}
+	data, _ := json.Marshal(stats)
+	return C.CString(string(data))
+}
+
+//export RecvEvent
+func RecvEvent(streamId *C.char) *C.char {
+	id := C.GoString(streamId)
+	event, err := RecvEmotionEvent(id)
+	if err != nil || event == nil {
+		return nil
+	}
+	return C.CString(string(event))
+}
+
 //export StopStream
 func StopStream(streamId *C.char) C.ErrorCode {
 	if streamId == nil {
@@ -112,6 +279,53 @@ func StopStream(streamId *C.char) C.ErrorCode {
 	return C.ERR_SUCCESS
 }
 
+//export PauseStream
+func PauseStream(streamId *C.char) C.ErrorCode {
+	if streamId == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	if err := PauseAudioStream(C.GoString(streamId)); err != nil {
+		return C.ERR_SESSION_NOT_FOUND
+	}
+
+	return C.ERR_SUCCESS
+}
+
+//export ResumeStream
+func ResumeStream(streamId *C.char) C.ErrorCode {
+	if streamId == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	if err := ResumeAudioStream(C.GoString(streamId)); err != nil {
+		return C.ERR_SESSION_NOT_FOUND
+	}
+
+	return C.ERR_SUCCESS
+}
+
+//export UpdateConfig
+func UpdateConfig(configJSON *C.char) C.ErrorCode {
+	if configJSON == nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	// 只需给出要修改的字段，如 {"sampleLibraryPath": "new_library.json"}
+	config, err := ParseConfigUpdate([]byte(C.GoString(configJSON)))
+	if err != nil {
+		if _, ok := SDKConfig(); !ok {
+			return C.ERR_NOT_INITIALIZED
+		}
+		return C.ERR_INVALID_PARAM
+	}
+	if err := UpdateSDKConfig(config); err != nil {
+		return C.ERR_INVALID_PARAM
+	}
+
+	return C.ERR_SUCCESS
+}
+
 //export ReleaseSDK
 func ReleaseSDK() {
 	ReleaseSDK()
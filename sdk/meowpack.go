@@ -0,0 +1,491 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 模型资源包（.meowpack）：样本库、领域配置、默认配置等打成一个 zip 文件，根目录的 manifest.json 描述其中的内容：
+//   format         资源包格式版本，当前为1
+//   name/version   资源包名称与版本，仅用于日志
+//   config         SDK默认配置（sampleRate、bufferSize、strategy 等，格式同 UpdateConfig），可省略
+//   library        样本库（必需）
+//   profile        领域配置，含接受阈值与短语目录，可省略
+//   normalization  按样本库计算的特征标准化参数，特征列表与当前构建不一致时拒绝加载
+//   model          可选的 ONNX/TFLite 模型，加载时解出到临时目录，路径写入配置的 model 字段
+//   files          各文件的 SHA-256，加载时校验
+// 配置 bundlePublicKeys 后只加载受信任公钥签名的资源包（见 meowpack_sign.go）；资源包用 library pack 子命令生成。
+
+// 资源包常量
+const (
+	MeowPackExt      = ".meowpack"
+	MeowPackFormat   = 1
+	packManifestName = "manifest.json"
+	packModelDir     = "meowpack-models" // 解出模型的临时目录名
+)
+
+// 资源包中的模型格式
+const (
+	ModelFormatONNX   = "onnx"
+	ModelFormatTFLite = "tflite"
+)
+
+// PackManifest 资源包清单
+type PackManifest struct {
+	Format        int               `json:"format"`
+	Name          string            `json:"name,omitempty"`
+	Version       string            `json:"version,omitempty"`
+	CreatedAt     int64             `json:"createdAt"` // 毫秒时间戳
+	Config        json.RawMessage   `json:"config,omitempty"`
+	Library       string            `json:"library"`
+	Profile       string            `json:"profile,omitempty"`
+	Normalization string            `json:"normalization,omitempty"`
+	Model         *PackModel        `json:"model,omitempty"`
+	Files         map[string]string `json:"files"` // 文件名 -> SHA-256
+}
+
+// PackModel 资源包中的模型
+type PackModel struct {
+	File   string `json:"file"`
+	Format string `json:"format"` // onnx / tflite
+}
+
+// FeatureNormalization 特征标准化参数，顺序与 Features 一致
+type FeatureNormalization struct {
+	Features []string  `json:"features"`
+	Mean     []float64 `json:"mean"`
+	StdDev   []float64 `json:"stdDev"`
+}
+
+// MeowPack 已加载的资源包
+type MeowPack struct {
+	Path          string
+	Manifest      PackManifest
+	Library       *SampleLibrary
+	Profile       *DomainProfile        // 资源包中没有领域配置时为nil
+	Normalization *FeatureNormalization // 资源包中没有标准化参数时为nil
+	ModelPath     string                // 解出的模型路径，资源包中没有模型时为空
+}
+
+// PackOptions 生成资源包的输入文件
+type PackOptions struct {
+	Name        string
+	Version     string
+	Config      json.RawMessage // SDK默认配置，可为空
+	LibraryPath string
+	ProfilePath string // 可为空
+	ModelPath   string // 可为空，扩展名为 .onnx 或 .tflite
+}
+
+// computeNormalization 按样本库全部样本计算各特征的均值与标准差
+func computeNormalization(library *SampleLibrary) *FeatureNormalization {
+	names := featureNames()
+	norm := &FeatureNormalization{
+		Features: names,
+		Mean:     make([]float64, len(names)),
+		StdDev:   make([]float64, len(names)),
+	}
+	var all [][]float64
+	for _, samples := range library.Samples {
+		for _, sample := range samples {
+			all = append(all, featureValues(sample.Features))
+		}
+	}
+	if len(all) == 0 {
+		return norm
+	}
+	for _, values := range all {
+		for j := range norm.Mean {
+			norm.Mean[j] += values[j] / float64(len(all))
+		}
+	}
+	for _, values := range all {
+		for j := range norm.StdDev {
+			norm.StdDev[j] += (values[j] - norm.Mean[j]) * (values[j] - norm.Mean[j]) / float64(len(all))
+		}
+	}
+	for j := range norm.StdDev {
+		norm.StdDev[j] = math.Sqrt(norm.StdDev[j])
+	}
+	return norm
+}
+
+// modelFormat 按扩展名确定模型格式
+func modelFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".onnx":
+		return ModelFormatONNX, nil
+	case ".tflite":
+		return ModelFormatTFLite, nil
+	}
+	return "", fmt.Errorf("unsupported model %s, want .onnx or .tflite", path)
+}
+
+// WriteMeowPack 按输入文件生成资源包，写入前校验样本库、领域配置与默认配置
+func WriteMeowPack(output string, options PackOptions) (*PackManifest, error) {
+	manifest := PackManifest{
+		Format:    MeowPackFormat,
+		Name:      options.Name,
+		Version:   options.Version,
+		CreatedAt: time.Now().UnixMilli(),
+		Config:    options.Config,
+		Files:     make(map[string]string),
+	}
+	files := make(map[string][]byte)
+	add := func(name string, data []byte) string {
+		sum := sha256.Sum256(data)
+		manifest.Files[name] = hex.EncodeToString(sum[:])
+		files[name] = data
+		return name
+	}
+
+	if len(options.Config) > 0 {
+		if _, err := packConfig(options.Config); err != nil {
+			return nil, err
+		}
+	}
+
+	library := NewSampleLibrary()
+	if err := library.LoadFromFile(options.LibraryPath); err != nil {
+		return nil, fmt.Errorf("load sample library: %v", err)
+	}
+	if len(library.Samples) == 0 {
+		return nil, fmt.Errorf("sample library is empty")
+	}
+	data, err := os.ReadFile(options.LibraryPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Library = add("library.json", data)
+
+	normalization, err := json.MarshalIndent(computeNormalization(library), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	manifest.Normalization = add("normalization.json", normalization)
+
+	if options.ProfilePath != "" {
+		if _, err := LoadDomainProfile(options.ProfilePath); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(options.ProfilePath)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Profile = add("profile.json", data)
+	}
+
+	if options.ModelPath != "" {
+		format, err := modelFormat(options.ModelPath)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(options.ModelPath)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Model = &PackModel{File: add("model."+format, data), Format: format}
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[packManifestName] = manifestData
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range append([]string{packManifestName}, names...) {
+		w, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// openPack 打开资源包并读取清单，keys 不为空时先校验清单的签名（见 meowpack_sign.go）
+func openPack(path string, keys []ed25519.PublicKey) (*zip.ReadCloser, *PackManifest, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open bundle: %v", err)
+	}
+	var manifest PackManifest
+	data, err := readZipFile(archive, packManifestName)
+	if err == nil {
+		err = json.Unmarshal(data, &manifest)
+	}
+	if err != nil {
+		archive.Close()
+		return nil, nil, fmt.Errorf("bundle manifest: %v", err)
+	}
+	if err := verifyManifestSignature(archive, data, keys); err != nil {
+		archive.Close()
+		return nil, nil, err
+	}
+	if manifest.Format != MeowPackFormat {
+		archive.Close()
+		return nil, nil, fmt.Errorf("unsupported bundle format %d, want %d", manifest.Format, MeowPackFormat)
+	}
+	if manifest.Library == "" {
+		archive.Close()
+		return nil, nil, fmt.Errorf("bundle has no sample library")
+	}
+	return archive, &manifest, nil
+}
+
+// readZipFile 读取资源包中的文件
+func readZipFile(archive *zip.ReadCloser, name string) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// readPackFile 读取清单中列出的文件并校验 SHA-256
+func readPackFile(archive *zip.ReadCloser, manifest *PackManifest, name string) ([]byte, error) {
+	sum, ok := manifest.Files[name]
+	if !ok {
+		return nil, fmt.Errorf("bundle file %s: not listed in manifest", name)
+	}
+	data, err := readZipFile(archive, name)
+	if err != nil {
+		return nil, fmt.Errorf("bundle file %s: %v", name, err)
+	}
+	actual := sha256.Sum256(data)
+	if hex.EncodeToString(actual[:]) != sum {
+		return nil, fmt.Errorf("bundle file %s: checksum mismatch", name)
+	}
+	return data, nil
+}
+
+// OpenMeowPack 加载资源包：校验签名（keys 不为空时）与各文件，读取样本库、领域配置与标准化参数，解出模型
+func OpenMeowPack(path string, keys []ed25519.PublicKey) (*MeowPack, error) {
+	archive, manifest, err := openPack(path, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	pack := &MeowPack{Path: path, Manifest: *manifest}
+
+	data, err := readPackFile(archive, manifest, manifest.Library)
+	if err != nil {
+		return nil, err
+	}
+	pack.Library = NewSampleLibrary()
+	if err := pack.Library.LoadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("bundle sample library: %v", err)
+	}
+
+	if pack.Profile, err = readPackProfile(archive, manifest); err != nil {
+		return nil, err
+	}
+
+	if manifest.Normalization != "" {
+		data, err := readPackFile(archive, manifest, manifest.Normalization)
+		if err != nil {
+			return nil, err
+		}
+		var norm FeatureNormalization
+		if err := json.Unmarshal(data, &norm); err != nil {
+			return nil, fmt.Errorf("bundle normalization: %v", err)
+		}
+		if names := featureNames(); strings.Join(norm.Features, ",") != strings.Join(names, ",") {
+			return nil, fmt.Errorf("bundle normalization built for features %v, this build extracts %v", norm.Features, names)
+		}
+		pack.Normalization = &norm
+	}
+
+	if model := manifest.Model; model != nil {
+		if model.Format != ModelFormatONNX && model.Format != ModelFormatTFLite {
+			return nil, fmt.Errorf("unsupported bundle model format %q", model.Format)
+		}
+		data, err := readPackFile(archive, manifest, model.File)
+		if err != nil {
+			return nil, err
+		}
+		if pack.ModelPath, err = extractModel(data, model.Format); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("已加载资源包 %s: %s %s", path, manifest.Name, manifest.Version)
+	return pack, nil
+}
+
+// readPackProfile 读取资源包中的领域配置，没有时返回nil
+func readPackProfile(archive *zip.ReadCloser, manifest *PackManifest) (*DomainProfile, error) {
+	if manifest.Profile == "" {
+		return nil, nil
+	}
+	data, err := readPackFile(archive, manifest, manifest.Profile)
+	if err != nil {
+		return nil, err
+	}
+	var profile DomainProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("bundle domain profile: %v", err)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// extractModel 将模型写入临时目录，文件名取内容的哈希，相同模型只写一次
+func extractModel(data []byte, format string) (string, error) {
+	dir := filepath.Join(os.TempDir(), packModelDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("extract bundle model: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	path := filepath.Join(dir, hex.EncodeToString(sum[:8])+"."+format)
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return path, nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", fmt.Errorf("extract bundle model: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("extract bundle model: %v", err)
+	}
+	return path, nil
+}
+
+// loadBundleProfile 读取资源包中的领域配置，路径为空或资源包中没有领域配置时返回nil
+func loadBundleProfile(path string, keys []ed25519.PublicKey) (*DomainProfile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	archive, manifest, err := openPack(path, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	return readPackProfile(archive, manifest)
+}
+
+// applyBundleProfile 应用资源包中的领域配置，路径为空或资源包中没有领域配置时不修改当前领域配置
+func applyBundleProfile(path string, keys []ed25519.PublicKey) error {
+	profile, err := loadBundleProfile(path, keys)
+	if err != nil || profile == nil {
+		return err
+	}
+	if err := SetDomainProfile(profile); err != nil {
+		return fmt.Errorf("failed to apply bundle domain profile: %v", err)
+	}
+	return nil
+}
+
+// packConfig 以默认采样率与缓冲区大小为基础应用资源包中的默认配置，未知字段报错
+func packConfig(data json.RawMessage) (AudioStreamConfig, error) {
+	config := AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}
+	if len(data) == 0 {
+		return config, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return AudioStreamConfig{}, fmt.Errorf("bundle config: %v", err)
+	}
+	return config, nil
+}
+
+// BundleConfig 返回以资源包为来源的SDK配置：资源包中的默认配置，bundle 指向该资源包
+// publicKeys 不为空时资源包须带有其中任一公钥的有效签名，返回的配置以其为受信任公钥，资源包不能修改
+func BundleConfig(path, publicKeys string) (AudioStreamConfig, error) {
+	keys, err := ParseBundlePublicKeys(publicKeys)
+	if err != nil {
+		return AudioStreamConfig{}, err
+	}
+	archive, manifest, err := openPack(path, keys)
+	if err != nil {
+		return AudioStreamConfig{}, err
+	}
+	archive.Close()
+	config, err := packConfig(manifest.Config)
+	if err != nil {
+		return AudioStreamConfig{}, err
+	}
+	config.BundlePath = path
+	config.BundlePublicKeys = publicKeys
+	return config, nil
+}
+
+func runLibraryPack(args []string) error {
+	fs := newFlagSet("library pack")
+	output := fs.String("o", "", "输出资源包路径，为空时为样本库同名的 .meowpack 文件")
+	profile := fs.String("profile", "", "领域配置文件（含接受阈值与短语目录），为空时不打包")
+	model := fs.String("model", "", "ONNX/TFLite 模型文件，为空时不打包")
+	config := fs.String("config", "", "SDK默认配置（JSON，格式同 UpdateConfig），如 {\"sampleRate\": 16000, \"bufferSize\": 4096}")
+	name := fs.String("name", "", "资源包名称")
+	version := fs.String("version", "", "资源包版本")
+	signKey := fs.String("sign-key", "", "签名私钥（PKCS#8 PEM，见 library keygen），为空时不签名")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: library pack [-o out.meowpack] [-profile profile.json] [-model model.onnx] [-config json] [-name name] [-version v] [-sign-key signing.pem] <library.json>")
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(fs.Arg(0), filepath.Ext(fs.Arg(0))) + MeowPackExt
+	}
+
+	var key ed25519.PrivateKey
+	if *signKey != "" {
+		var err error
+		if key, err = LoadSigningKey(*signKey); err != nil {
+			return err
+		}
+	}
+
+	manifest, err := WriteMeowPack(*output, PackOptions{
+		Name:        *name,
+		Version:     *version,
+		Config:      json.RawMessage(*config),
+		LibraryPath: fs.Arg(0),
+		ProfilePath: *profile,
+		ModelPath:   *model,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("资源包已写入 %s（%d 个文件）", *output, len(manifest.Files))
+	if key != nil {
+		if err := SignMeowPack(*output, key); err != nil {
+			return err
+		}
+		log.Printf("资源包已签名，公钥: %s", publicKeyText(key))
+	}
+	return nil
+}
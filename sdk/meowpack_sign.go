@@ -0,0 +1,209 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// 资源包签名
+//
+// 移动应用会在线下载更新的资源包，清单中的 SHA-256 只能发现传输损坏，挡不住被替换的资源包。
+// 发布方用 ed25519 私钥对 manifest.json 的原始字节签名，签名（64字节）写入资源包中的 manifest.sig；
+// 清单列出了其余每个文件的 SHA-256，因此签名覆盖资源包的全部内容。
+// 配置的 bundlePublicKeys 给出受信任的公钥（32字节公钥的 base64，多个以逗号分隔）时，资源包须带有其中任一公钥的
+// 有效签名才会被读取（样本库、领域配置与默认配置都一样），未签名或签名无效时加载失败；没有设置时不校验签名。
+//   library keygen -o signing.pem            生成私钥（PKCS#8 PEM，openssl genpkey -algorithm ed25519 生成的同样可用）并输出公钥
+//   library pack -sign-key signing.pem ...   打包时签名
+//   library sign -key signing.pem x.meowpack 为已有的资源包签名（替换原签名）
+// CGO 接口 InitSDKFromSignedBundle 与 VerifyBundle 接受逗号分隔的公钥。
+
+// packSignatureName 资源包中签名文件的名称
+const packSignatureName = "manifest.sig"
+
+// 签名校验错误
+var (
+	ErrBundleUnsigned     = errors.New("bundle is not signed")
+	ErrBundleBadSignature = errors.New("bundle signature does not match any trusted key")
+)
+
+// ParseBundlePublicKeys 解析逗号分隔、base64 编码的 ed25519 公钥，空文本返回nil
+func ParseBundlePublicKeys(text string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, encoded := range strings.Split(text, ",") {
+		if encoded = strings.TrimSpace(encoded); encoded == "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(data) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid bundle public key %q: want base64 of %d bytes", encoded, ed25519.PublicKeySize)
+		}
+		keys = append(keys, ed25519.PublicKey(data))
+	}
+	return keys, nil
+}
+
+// verifyManifestSignature 校验清单的签名，keys 为空时不校验
+func verifyManifestSignature(archive *zip.ReadCloser, manifest []byte, keys []ed25519.PublicKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	signature, err := readZipFile(archive, packSignatureName)
+	if err != nil {
+		return ErrBundleUnsigned
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, manifest, signature) {
+			return nil
+		}
+	}
+	return ErrBundleBadSignature
+}
+
+// VerifyMeowPack 校验资源包的签名与各文件的校验和，不加载内容；下载后替换旧资源包前调用
+func VerifyMeowPack(path string, keys []ed25519.PublicKey) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("no trusted bundle public keys")
+	}
+	archive, manifest, err := openPack(path, keys)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	for name := range manifest.Files {
+		if _, err := readPackFile(archive, manifest, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadSigningKey 读取 PKCS#8 PEM 格式的 ed25519 私钥
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s: no PEM block", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %v", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s: not an ed25519 key", path)
+	}
+	return key, nil
+}
+
+// SignMeowPack 用私钥对资源包的清单签名，已有的签名被替换
+func SignMeowPack(path string, key ed25519.PrivateKey) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("open bundle: %v", err)
+	}
+	defer archive.Close()
+	manifest, err := readZipFile(archive, packManifestName)
+	if err != nil {
+		return fmt.Errorf("bundle manifest: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, file := range archive.File {
+		if file.Name == packSignatureName {
+			continue
+		}
+		if err := writer.Copy(file); err != nil {
+			return err
+		}
+	}
+	w, err := writer.Create(packSignatureName)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(ed25519.Sign(key, manifest)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	// 先写临时文件再替换，签名失败时不破坏原资源包
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// publicKeyText 公钥的 base64 文本，即配置 bundlePublicKeys 中的写法
+func publicKeyText(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+func runLibraryKeygen(args []string) error {
+	fs := newFlagSet("library keygen")
+	output := fs.String("o", "", "私钥输出路径（PKCS#8 PEM）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" || fs.NArg() != 0 {
+		return fmt.Errorf("usage: library keygen -o signing.pem")
+	}
+	if _, err := os.Stat(*output); err == nil {
+		return fmt.Errorf("%s already exists", *output)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*output, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return err
+	}
+	log.Printf("私钥已写入 %s，请妥善保管", *output)
+	fmt.Println(publicKeyText(key))
+	return nil
+}
+
+func runLibrarySign(args []string) error {
+	fs := newFlagSet("library sign")
+	keyPath := fs.String("key", "", "私钥路径（PKCS#8 PEM）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keyPath == "" || fs.NArg() != 1 {
+		return fmt.Errorf("usage: library sign -key signing.pem <bundle.meowpack>")
+	}
+
+	key, err := LoadSigningKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	if err := SignMeowPack(fs.Arg(0), key); err != nil {
+		return err
+	}
+	log.Printf("资源包 %s 已签名，公钥: %s", fs.Arg(0), publicKeyText(key))
+	return nil
+}
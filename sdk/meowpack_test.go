@@ -0,0 +1,215 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMeowPack 测试模型资源包的生成与加载
+// 测试内容：
+// 1. 资源包包含样本库、领域配置、标准化参数与模型，加载后内容一致，模型解出到临时目录
+// 2. 资源包中的默认配置：未给出的字段使用默认采样率与缓冲区大小，未知字段在打包时报错
+// 3. 文件内容被改动时校验失败
+// 4. 以资源包初始化SDK：样本库与领域配置取自资源包，模型路径写入引擎配置
+func TestMeowPack(t *testing.T) {
+	testDir, err := setupTestEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestEnvironment(testDir)
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatal(err)
+	}
+	modelPath := filepath.Join(testDir, "emotion.onnx")
+	os.WriteFile(modelPath, []byte("onnx model bytes"), 0644)
+	options := PackOptions{
+		Name:        "cat",
+		Version:     "2024.1",
+		Config:      json.RawMessage(`{"sampleRate": 16000, "deterministic": true}`),
+		LibraryPath: filepath.Join(testDir, "sample_library.json"),
+		ProfilePath: filepath.Join("profiles", "dog.json"),
+		ModelPath:   modelPath,
+	}
+	bundle := filepath.Join(testDir, "cat"+MeowPackExt)
+	if _, err := WriteMeowPack(bundle, options); err != nil {
+		t.Fatalf("WriteMeowPack: %v", err)
+	}
+
+	pack, err := OpenMeowPack(bundle, nil)
+	if err != nil {
+		t.Fatalf("OpenMeowPack: %v", err)
+	}
+	library := NewSampleLibrary()
+	library.LoadFromFile(options.LibraryPath)
+	if len(pack.Library.Samples) != len(library.Samples) || pack.Profile == nil || pack.Profile.Name != "dog" {
+		t.Errorf("pack = %d emotions, profile %+v", len(pack.Library.Samples), pack.Profile)
+	}
+	if pack.Normalization == nil || len(pack.Normalization.Mean) != len(featureNames()) {
+		t.Errorf("normalization = %+v", pack.Normalization)
+	}
+	if data, err := os.ReadFile(pack.ModelPath); err != nil || string(data) != "onnx model bytes" || filepath.Ext(pack.ModelPath) != ".onnx" {
+		t.Errorf("model %s = %q, %v", pack.ModelPath, data, err)
+	}
+
+	config, err := BundleConfig(bundle, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.SampleRate != 16000 || config.BufferSize != 4096 || !config.Deterministic || config.BundlePath != bundle {
+		t.Errorf("BundleConfig = %+v", config)
+	}
+	bad := options
+	bad.Config = json.RawMessage(`{"sampleRat": 16000}`)
+	if _, err := WriteMeowPack(filepath.Join(testDir, "bad"+MeowPackExt), bad); err == nil {
+		t.Error("unknown config field should fail")
+	}
+
+	// 改动样本库后重新打包，清单中的校验和不变
+	reader, err := zip.OpenReader(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, file := range reader.File {
+		data, _ := readZipFile(reader, file.Name)
+		if file.Name == "library.json" {
+			data = bytes.Replace(data, []byte("Samples"), []byte("Samples "), 1)
+		}
+		w, _ := writer.Create(file.Name)
+		w.Write(data)
+	}
+	writer.Close()
+	reader.Close()
+	tampered := filepath.Join(testDir, "tampered"+MeowPackExt)
+	os.WriteFile(tampered, buf.Bytes(), 0644)
+	if _, err := OpenMeowPack(tampered, nil); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("tampered bundle error = %v, want checksum mismatch", err)
+	}
+
+	ReleaseSDK()
+	defer SetDomainProfile(nil)
+	if !InitializeSDK(config) {
+		t.Fatal("InitializeSDK with bundle failed")
+	}
+	defer ReleaseSDK()
+	if name := CurrentDomainProfile().Name; name != "dog" {
+		t.Errorf("profile = %q, want dog from bundle", name)
+	}
+	mu.RLock()
+	engine := sdk.Engine
+	mu.RUnlock()
+	if engine.Config.ModelPath != pack.ModelPath || len(engine.Library.Samples) != len(library.Samples) {
+		t.Errorf("engine model %q, %d emotions", engine.Config.ModelPath, len(engine.Library.Samples))
+	}
+}
+
+// TestMeowPackSignature 测试资源包签名
+// 测试内容：
+// 1. 设置受信任公钥后，未签名与其他私钥签名的资源包加载失败，签名有效时加载成功
+// 2. 签名后改动清单时签名失效，改动其余文件时校验和不符
+// 3. 资源包中的默认配置不能改写受信任公钥
+// 4. 读取 PKCS#8 PEM 私钥，重新签名替换原签名
+func TestMeowPackSignature(t *testing.T) {
+	dir := t.TempDir()
+	library := NewSampleLibrary()
+	library.AddSample(AudioSample{FilePath: "a.wav", Emotion: "hungry", Features: AudioFeature{Energy: 1, Pitch: 600}})
+	library.AddSample(AudioSample{FilePath: "b.wav", Emotion: "purr", Features: AudioFeature{Energy: 0.2, Pitch: 150}})
+	libraryPath := filepath.Join(dir, "library.json")
+	if err := library.SaveToFile(libraryPath); err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(dir, "cat"+MeowPackExt)
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	config := `{"bufferSize": 2048, "bundlePublicKeys": "` + base64.StdEncoding.EncodeToString(other) + `"}`
+	if _, err := WriteMeowPack(bundle, PackOptions{LibraryPath: libraryPath, Config: json.RawMessage(config)}); err != nil {
+		t.Fatal(err)
+	}
+
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	trusted := base64.StdEncoding.EncodeToString(public)
+	keys, err := ParseBundlePublicKeys(trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMeowPack(bundle, keys); err != ErrBundleUnsigned {
+		t.Errorf("unsigned bundle error = %v, want %v", err, ErrBundleUnsigned)
+	}
+
+	der, _ := x509.MarshalPKCS8PrivateKey(private)
+	keyPath := filepath.Join(dir, "signing.pem")
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	signingKey, err := LoadSigningKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, wrong, _ := ed25519.GenerateKey(rand.Reader)
+	SignMeowPack(bundle, wrong)
+	if _, err := OpenMeowPack(bundle, keys); err != ErrBundleBadSignature {
+		t.Errorf("bundle signed by another key error = %v, want %v", err, ErrBundleBadSignature)
+	}
+	if err := SignMeowPack(bundle, signingKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyMeowPack(bundle, keys); err != nil {
+		t.Errorf("VerifyMeowPack = %v", err)
+	}
+	if pack, err := OpenMeowPack(bundle, keys); err != nil || len(pack.Library.Samples) != 2 {
+		t.Errorf("OpenMeowPack signed = %v", err)
+	}
+	loaded, err := BundleConfig(bundle, trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.BufferSize != 2048 || loaded.BundlePublicKeys != trusted {
+		t.Errorf("BundleConfig = %+v, want trusted keys kept", loaded)
+	}
+
+	// 签名后改动文件
+	rewrite := func(target string, edit func([]byte) []byte) string {
+		reader, err := zip.OpenReader(bundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		var buf bytes.Buffer
+		writer := zip.NewWriter(&buf)
+		for _, file := range reader.File {
+			data, _ := readZipFile(reader, file.Name)
+			if file.Name == target {
+				data = edit(data)
+			}
+			w, _ := writer.Create(file.Name)
+			w.Write(data)
+		}
+		writer.Close()
+		path := filepath.Join(dir, "edited-"+target+MeowPackExt)
+		os.WriteFile(path, buf.Bytes(), 0644)
+		return path
+	}
+	edited := rewrite(packManifestName, func(data []byte) []byte {
+		return bytes.Replace(data, []byte(`"bufferSize": 2048`), []byte(`"bufferSize": 1024`), 1)
+	})
+	if _, err := OpenMeowPack(edited, keys); err != ErrBundleBadSignature {
+		t.Errorf("edited manifest error = %v, want %v", err, ErrBundleBadSignature)
+	}
+	edited = rewrite("library.json", func(data []byte) []byte { return append(data, ' ') })
+	if err := VerifyMeowPack(edited, keys); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("edited library error = %v, want checksum mismatch", err)
+	}
+
+	if _, err := LoadEngine(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, BundlePath: bundle,
+		BundlePublicKeys: base64.StdEncoding.EncodeToString(other)}); err == nil {
+		t.Error("LoadEngine should reject a bundle not signed by the trusted key")
+	}
+}
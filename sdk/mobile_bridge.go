@@ -0,0 +1,71 @@
+package main
+
+// #include <stdlib.h>
+import "C"
+import (
+	"encoding/json"
+	"sync"
+	"unsafe"
+)
+
+// 移动端桥接：Android 的 JNI 层（android/app/src/main/cpp/meow_bridge.c）逐块送入浮点样本，
+// 与CGO流接口、HTTP服务一样交给处理引擎，结果与 Engine.ProcessAudio 相同。
+// SDK 已初始化时使用其引擎，否则按默认配置（44100Hz、4096个样本、内置样本库）创建一个引擎。
+
+// mobileStreamID 桥接送入的音频所属的流
+const mobileStreamID = "mobile-stream"
+
+var (
+	mobileEngineOnce sync.Once
+	mobileEngine     *Engine
+	mobileEngineErr  error
+)
+
+// bridgeEngine 返回桥接使用的处理引擎
+func bridgeEngine() (*Engine, error) {
+	mu.RLock()
+	if sdk != nil {
+		engine := sdk.Engine
+		mu.RUnlock()
+		return engine, nil
+	}
+	mu.RUnlock()
+
+	mobileEngineOnce.Do(func() {
+		mobileEngine, mobileEngineErr = LoadEngine(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	})
+	return mobileEngine, mobileEngineErr
+}
+
+// processMobileAudio 处理桥接送入的一块样本，出错时返回 {"status": "error"} 结果
+func processMobileAudio(samples []float64) []byte {
+	engine, err := bridgeEngine()
+	var result []byte
+	if err == nil {
+		result, err = engine.ProcessAudio(mobileStreamID, samples)
+	}
+	if err != nil {
+		result, _ = json.Marshal(map[string]string{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+	return result
+}
+
+//export ProcessAudioData
+func ProcessAudioData(data *C.float, length C.int) *C.char {
+	// 将C数组转换为Go切片
+	goData := make([]float64, int(length))
+	for i, v := range unsafe.Slice((*float32)(unsafe.Pointer(data)), int(length)) {
+		goData[i] = float64(v)
+	}
+
+	// 返回JSON结果
+	return C.CString(string(processMobileAudio(goData)))
+}
+
+//export FreeCString
+func FreeCString(str *C.char) {
+	C.free(unsafe.Pointer(str))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestProcessMobileAudio 测试移动端桥接
+// 测试内容：
+// 1. SDK 已初始化时使用其处理引擎，不足一个窗口返回 waiting，满一个窗口返回与 Engine.ProcessAudio 相同格式的结果
+// 2. SDK 未初始化且未编入内置样本库时返回 error 结果
+func TestProcessMobileAudio(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	if !InitializeSDK(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, SampleLibraryPath: engine.Config.SampleLibraryPath}) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	samples := generateTestAudio(440, 0.15, 44100)
+	if result := processMobileAudio(samples[:2000]); !strings.Contains(string(result), `"status":"waiting"`) {
+		t.Errorf("不足一个窗口时应返回 waiting, got %s", result)
+	}
+	var parsed AudioStreamResult
+	if err := json.Unmarshal(processMobileAudio(samples[2000:]), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.StreamID != mobileStreamID || parsed.Emotion == "" {
+		t.Errorf("unexpected result: %+v", parsed)
+	}
+	ReleaseSDK()
+
+	if len(embeddedEngineLibrary) == 0 {
+		if result := processMobileAudio(samples); !strings.Contains(string(result), `"status":"error"`) {
+			t.Errorf("未初始化且没有内置样本库时应返回 error, got %s", result)
+		}
+	}
+}
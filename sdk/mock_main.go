@@ -1,11 +1,215 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
+// newFlagSet 创建子命令的参数解析器
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ExitOnError)
+}
+
+// applyProfileFlag 加载并应用 -profile 指定的领域配置，设置了 -bundle 时先应用资源包中的领域配置，
+// 两者都没有时使用内置猫咪配置
+func applyProfileFlag(path, bundle, bundleKeys string) error {
+	keys, err := ParseBundlePublicKeys(bundleKeys)
+	if err != nil {
+		return err
+	}
+	if err := applyBundleProfile(bundle, keys); err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+	profile, err := LoadDomainProfile(path)
+	if err != nil {
+		return err
+	}
+	if err := SetDomainProfile(profile); err != nil {
+		return err
+	}
+	log.Printf("已加载领域配置: %s", profile.Name)
+	return nil
+}
+
+// applyServeDefaults serve 子命令的默认值：以 -tags embedlib 构建时使用内置样本库，
+// 任务目录放在临时目录，容器中无需挂载任何路径即可启动；命令行显式指定的参数仍然优先
+func applyServeDefaults(fs *flag.FlagSet) {
+	if len(embeddedEngineLibrary) > 0 {
+		fs.Set("library", "")
+	}
+	fs.Set("jobs-dir", filepath.Join(os.TempDir(), "meowtalk-jobs"))
+}
+
+// engineFlags 选择处理引擎的命令行参数，服务与 replay 子命令共用
+type engineFlags struct {
+	library    *string
+	bundle     *string
+	bundleKeys *string
+	sampleRate *int
+	bufferSize *int
+	strategy   *string
+	trigger    *string
+	archive    *string
+	dataDir    *string
+	archiveMax *int
+	archiveAge *time.Duration
+	health     *bool
+	prefilter  *bool
+	windows    *int
+	aggregate  *string
+	energy     *time.Duration
+	energyAuto *bool
+}
+
+// addEngineFlags 注册 -library/-sample-rate/-buffer-size 等处理引擎参数
+func addEngineFlags(fs *flag.FlagSet) *engineFlags {
+	return &engineFlags{
+		library:    fs.String("library", "sample_library.json", "样本库文件路径，为空时使用内置样本库（需以 -tags embedlib 构建）"),
+		bundle:     fs.String("bundle", "", "资源包路径（.meowpack），设置后样本库与领域配置取自资源包，-profile 仍可覆盖领域配置"),
+		bundleKeys: fs.String("bundle-keys", "", "受信任的资源包签名公钥（base64，逗号分隔），设置后 -bundle 须由其中任一公钥签名"),
+		sampleRate: fs.Int("sample-rate", 44100, "输入音频采样率"),
+		bufferSize: fs.Int("buffer-size", 4096, "每次处理的样本数"),
+		strategy:   fs.String("strategy", DefaultStrategy, "默认处理策略：standard/low-latency/accurate/template，开始会话时可按流覆盖"),
+		trigger:    fs.String("trigger", "", "缓冲处理触发条件文件路径（JSON），为空时使用默认条件；按条件跳过静默窗口并在叫声结束时输出最终结果"),
+		archive:    fs.String("archive", "", "处理音频归档目录，设置后每个识别结果的音频片段与特征写入该目录，相对路径位于数据目录下"),
+		dataDir:    addDataDirFlag(fs),
+		archiveMax: fs.Int("archive-max-entries", 1000, "归档最多保留的条目数，0表示不限制"),
+		archiveAge: fs.Duration("archive-max-age", 7*24*time.Hour, "归档条目最长保留时间，0表示不限制"),
+		health:     fs.Bool("health-checks", false, "统计叫声的谐噪比与基频漂移，/stop 响应附带非诊断性的健康提示"),
+		prefilter:  fs.Bool("prefilter", false, "用 Goertzel 检测呼噜声与叫声频带的能量，跳过没有这些频带内容的窗口"),
+		windows:    fs.Int("window-workers", 0, "并行提取缓冲区中各窗口特征的工作协程数，0表示按CPU核数（不超过8），1表示逐个计算"),
+		aggregate:  fs.String("aggregation", "", "段内窗口特征的汇总方式：为空时各窗口分别评分后取平均，max-energy 取能量最高的窗口，energy-weighted 按能量加权平均，median 逐特征取中位数"),
+		energy:     fs.Duration("energy-calibration", 0, "统计收到的前这么长音频的能量直方图并给出静默阈值与最小能量建议，0表示不统计"),
+		energyAuto: fs.Bool("energy-calibration-apply", false, "能量直方图收集完成后直接应用建议阈值（需 -energy-calibration）"),
+	}
+}
+
+// newProcessor 按参数创建音频处理器
+func (f *engineFlags) newProcessor(debounce time.Duration, deterministic bool) (AudioProcessor, error) {
+	policy := DefaultTriggerPolicy()
+	if *f.trigger != "" {
+		loaded, err := LoadTriggerPolicy(*f.trigger)
+		if err != nil {
+			return nil, err
+		}
+		policy = loaded
+		log.Printf("已加载触发条件: %+v", policy)
+	}
+
+	var archive *AudioArchive
+	if *f.archive != "" {
+		dir, err := dataPath(*f.dataDir, *f.archive)
+		if err != nil {
+			return nil, err
+		}
+		archive, err = NewAudioArchive(dir, ArchiveRetention{MaxEntries: *f.archiveMax, MaxAge: *f.archiveAge})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("处理音频归档已开启，目录: %s", dir)
+	}
+
+	var prefilter *BandPreFilter
+	if *f.prefilter {
+		prefilter = &BandPreFilter{}
+	}
+	engine, err := LoadEngine(AudioStreamConfig{
+		SampleRate:        *f.sampleRate,
+		BufferSize:        *f.bufferSize,
+		SampleLibraryPath: *f.library,
+		BundlePath:        *f.bundle,
+		BundlePublicKeys:  *f.bundleKeys,
+		EventDebounceMs:   int(debounce / time.Millisecond),
+		Deterministic:     deterministic,
+		Strategy:          *f.strategy,
+		HealthChecks:      *f.health,
+		PreFilter:         prefilter,
+		Trigger:           &policy,
+		Extractor:         ExtractorOptions{Aggregation: *f.aggregate},
+	})
+	if err != nil {
+		return nil, err
+	}
+	engine.SetArchive(archive)
+	if err := engine.SetWindowWorkers(*f.windows); err != nil {
+		return nil, err
+	}
+	library := *f.library
+	if *f.bundle != "" {
+		library = *f.bundle
+	} else if library == "" {
+		library = "内置"
+	}
+	log.Printf("使用处理引擎: 样本库=%s, 采样率=%d, 缓冲区=%d, 策略=%s", library, *f.sampleRate, *f.bufferSize, *f.strategy)
+	return engine, nil
+}
+
 func main() {
+	// 子命令
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:]); err != nil {
+			log.Fatalf("回放失败: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "library" {
+		if err := runLibrary(os.Args[2:]); err != nil {
+			log.Fatalf("构建样本库失败: %v", err)
+		}
+		return
+	}
+	// serve 子命令与直接启动相同，只是默认值适合容器
+	serve := len(os.Args) > 1 && os.Args[1] == "serve"
+	if serve {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	addr := flag.String("addr", ":8081", "监听地址")
+	profilePath := flag.String("profile", "", "领域配置文件路径（JSON），为空时使用内置猫咪配置")
+	debounce := flag.Duration("debounce", DefaultEventDebounce, "情感变化事件去抖时长")
+	recordDir := flag.String("record", "", "录制目录，设置后将每个会话的请求写入该目录，可用 replay 子命令回放")
+	recordIdle := flag.Duration("record-idle", DefaultRecordIdleTimeout, "录制的会话超过该时长没有新请求时关闭录制文件，0表示只在会话结束时关闭")
+	deterministic := flag.Bool("deterministic", false, "确定性模式：按样本数而非墙上时钟触发处理")
+	fetchHosts := flag.String("fetch-hosts", "", "/api/analyze-file 允许下载录音的主机（逗号分隔，含子域名），为空时不限制")
+	fetchTimeout := flag.Duration("fetch-timeout", 30*time.Second, "/api/analyze-file 下载录音的超时时间")
+	fetchPrivate := flag.Bool("fetch-allow-private", false, "/api/analyze-file 允许从回环、私有、链路本地地址下载录音（内网存储），默认拒绝")
+	jobsDir := flag.String("jobs-dir", "jobs", "批量分析任务状态与上传文件的保存目录")
+	jobWorkers := flag.Int("job-workers", 2, "批量分析任务的工作协程数")
+	jobMaxJobs := flag.Int("job-max-jobs", 1000, "最多保留的已完成批量任务数，0表示不限制")
+	jobMaxAge := flag.Duration("job-max-age", 7*24*time.Hour, "已完成的批量任务最长保留时间，0表示不限制")
+	resumeGrace := flag.Duration("resume-grace", DefaultResumeGrace, "WebSocket断线后会话保留时长，期间客户端可凭恢复令牌重连，0表示不支持恢复")
+	pingInterval := flag.Duration("ws-ping-interval", DefaultPingInterval, "WebSocket心跳间隔，0表示不发送心跳")
+	pongTimeout := flag.Duration("ws-pong-timeout", DefaultPongTimeout, "WebSocket超过该时长无任何回应视为断线，需大于心跳间隔")
+	wsCompression := flag.Bool("ws-compression", true, "WebSocket协商 permessage-deflate 压缩（客户端不支持时不压缩）")
+	wsCompressionLevel := flag.Int("ws-compression-level", DefaultCompressionLevel, "WebSocket压缩级别 -2~9，1为最快")
+	tlsCert := flag.String("tls-cert", "", "TLS证书文件（PEM），与 -tls-key 同时设置时以HTTPS/WSS监听，证书文件更新后自动重新加载")
+	tlsKey := flag.String("tls-key", "", "TLS私钥文件（PEM）")
+	sessionStore := flag.String("session-store", "", "会话存储，为空时保存在进程内存；redis://[:password@]host:6379/0 时多个副本共享会话配置、缓冲区与结果")
+	sessionTTL := flag.Duration("session-ttl", DefaultSessionTTL, "外部会话存储中会话无数据后的保留时长")
+	debugAddr := flag.String("debug-addr", "", "诊断接口（pprof、expvar）监听地址，如 127.0.0.1:6060，为空时不启用")
+	sendPolicy := flag.String("send-policy", SendPolicyAutoCreate, "/api/send 收到未经 /start 创建的流时的策略：auto-create 按默认配置创建会话，reject 返回 stream_not_found")
+	adminToken := flag.String("admin-token", "", "管理接口令牌（Authorization: Bearer <token>），为空时不启用 /api/admin 接口")
+	distressAlert := flag.Bool("distress-alert", false, "启用持续不适告警（默认2分钟内不适叫声累计超过30秒），告警通过 /api/events 与 WebSocket 推送")
+	baselineDir := flag.String("baseline-dir", "", "猫咪长期基线目录，设置后按 catId 累积叫声统计并在结果中给出与基线的偏离")
+	historyDir := flag.String("history-dir", "", "结果历史目录，设置后按天保存最终结果与告警并生成每日报告，为空时只在内存中保留最近8天")
+	resultHooks := flag.String("result-hooks", "", "结果钩子文件路径（JSON），其中的规则可按表达式改写最终结果的情感与置信度或附加字段")
+	distressRule := flag.String("distress-rule", "", "持续不适告警规则文件路径（JSON），设置后启用告警，未出现的字段使用默认值")
+	labelLibrary := flag.String("label-library", DefaultLabelLibrary, "标注样本库路径（需 -archive），/api/admin/labeling 标注的片段重新提取特征后写入该样本库，相对路径位于数据目录下")
+	labelMaxConfidence := flag.Float64("label-max-confidence", DefaultLabelMaxConfidence, "/api/admin/labeling 只列出置信度低于该值的归档片段")
+	engineOpts := addEngineFlags(flag.CommandLine)
+	if serve {
+		applyServeDefaults(flag.CommandLine)
+	}
+	flag.Parse()
+
 	log.Println("=== MeowTalk SDK 服务启动中 ===")
 	log.Println("版本: 1.2.0")
 	log.Println("支持功能:")
@@ -17,28 +221,165 @@ func main() {
 	log.Println(" - 跨域资源共享(CORS)支持")
 	log.Println("==============================")
 
+	// 加载领域配置
+	if err := applyProfileFlag(*profilePath, *engineOpts.bundle, *engineOpts.bundleKeys); err != nil {
+		log.Fatalf("加载领域配置失败: %v", err)
+	}
+
 	// 创建音频处理器
-	processor := NewMockAudioProcessor()
+	processor, err := engineOpts.newProcessor(*debounce, *deterministic)
+	if err != nil {
+		log.Fatalf("创建处理器失败: %v", err)
+	}
+	server := NewAudioServer(processor)
 
-	// 设置HTTP路由
-	mux := http.NewServeMux()
+	// 远程录音下载限制
+	fetcher := DefaultRemoteAudioFetcher()
+	fetcher.Timeout = *fetchTimeout
+	fetcher.AllowPrivate = *fetchPrivate
+	if *fetchHosts != "" {
+		fetcher.AllowedHosts = strings.Split(*fetchHosts, ",")
+	}
+	server.SetRemoteFetcher(fetcher)
+
+	// 批量分析任务队列
+	jobs, err := NewJobQueue(processor, fetcher, *jobsDir, *jobWorkers)
+	if err != nil {
+		log.Fatalf("创建批量任务队列失败: %v", err)
+	}
+	defer jobs.Close()
+	if err := jobs.SetRetention(JobRetention{MaxJobs: *jobMaxJobs, MaxAge: *jobMaxAge}); err != nil {
+		log.Fatalf("批量任务保留限制无效: %v", err)
+	}
+	server.SetJobQueue(jobs)
+	server.SetAdminToken(*adminToken)
+	if err := server.SetSendPolicy(*sendPolicy); err != nil {
+		log.Fatalf("会话创建策略无效: %v", err)
+	}
+	server.SetResumeGrace(*resumeGrace)
+	if err := server.SetHeartbeat(*pingInterval, *pongTimeout); err != nil {
+		log.Fatalf("心跳参数无效: %v", err)
+	}
+	if err := server.SetCompression(*wsCompression, *wsCompressionLevel); err != nil {
+		log.Fatalf("压缩参数无效: %v", err)
+	}
+
+	// 低置信度片段标注
+	if archiver, ok := processor.(AudioArchiver); ok && archiver.Archive() != nil {
+		path, err := dataPath(*engineOpts.dataDir, *labelLibrary)
+		if err != nil {
+			log.Fatalf("标注样本库路径无效: %v", err)
+		}
+		queue, err := NewLabelingQueue(archiver.Archive(), path, *labelMaxConfidence, *engineOpts.sampleRate)
+		if err != nil {
+			log.Fatalf("创建标注队列失败: %v", err)
+		}
+		server.SetLabelingQueue(queue)
+		log.Printf("片段标注已开启，标注样本库: %s", path)
+	}
 
-	// 设置CORS中间件
-	corsMiddleware := func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, Authorization")
+	// 能量校准报告
+	if *engineOpts.energy > 0 {
+		tuner, ok := processor.(EnergyTuner)
+		if !ok {
+			log.Fatalf("当前处理器不支持能量校准")
+		}
+		calibration, err := NewEnergyCalibration(*engineOpts.energy, *engineOpts.energyAuto)
+		if err != nil {
+			log.Fatalf("创建能量校准失败: %v", err)
+		}
+		tuner.SetEnergyCalibration(calibration)
+		server.SetEnergyCalibration(calibration)
+		log.Printf("能量校准已开启，收集 %v 音频后给出阈值建议", *engineOpts.energy)
+	}
 
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
+	// 猫咪长期基线
+	if *baselineDir != "" {
+		baselines, err := NewCatBaselineStore(*baselineDir)
+		if err != nil {
+			log.Fatalf("创建猫咪基线目录失败: %v", err)
+		}
+		defer baselines.Close()
+		server.SetBaselineStore(baselines)
+		log.Printf("猫咪长期基线已开启，目录: %s", *baselineDir)
+	}
+
+	// 结果钩子
+	if *resultHooks != "" {
+		hooks, err := LoadResultHooks(*resultHooks)
+		if err != nil {
+			log.Fatalf("加载结果钩子失败: %v", err)
+		}
+		server.SetResultHooks(hooks)
+		log.Printf("结果钩子已加载: %s（%d 条规则）", *resultHooks, len(hooks.Rules))
+	}
+
+	// 结果历史与情感报告
+	history, err := NewResultHistory(*historyDir)
+	if err != nil {
+		log.Fatalf("创建结果历史失败: %v", err)
+	}
+	stopReports := history.StartReportJob()
+	defer stopReports()
+	server.SetResultHistory(history)
+
+	// 持续不适告警
+	if *distressAlert || *distressRule != "" {
+		rule := DefaultDistressRule()
+		if *distressRule != "" {
+			if rule, err = LoadDistressRule(*distressRule); err != nil {
+				log.Fatalf("加载告警规则失败: %v", err)
 			}
+		}
+		if err := server.SetDistressRule(&rule); err != nil {
+			log.Fatalf("告警规则无效: %v", err)
+		}
+		log.Printf("持续不适告警: %s（%v 内累计 %v）", rule.Name,
+			time.Duration(rule.WindowMs)*time.Millisecond, time.Duration(rule.SustainedMs)*time.Millisecond)
+	}
+
+	// 配置热加载：SIGHUP 或 /api/admin/reload 重新读取 -profile 与 -trigger 文件
+	reloader := &ConfigReloader{ProfilePath: *profilePath, TriggerPath: *engineOpts.trigger, Processor: processor}
+	server.SetReloader(reloader)
+	stopReload := reloader.ReloadOnSIGHUP()
+	defer stopReload()
 
-			h.ServeHTTP(w, r)
-		})
+	// 多副本共享的会话存储
+	if *sessionStore != "" {
+		store, err := NewRedisSessionStore(*sessionStore, *sessionTTL)
+		if err != nil {
+			log.Fatalf("连接会话存储失败: %v", err)
+		}
+		defer store.Close()
+		// 只共享配置、未处理的样本与最新结果，段内累积、平滑与事件状态在换副本时重置
+		server.SetSessionStore(store)
+		log.Printf("会话存储: Redis %s（TTL %v）", store.client.addr, *sessionTTL)
 	}
 
+	// 运行时诊断
+	if *debugAddr != "" {
+		diagnostics, err := StartDiagnostics(*debugAddr, server)
+		if err != nil {
+			log.Fatalf("启动诊断接口失败: %v", err)
+		}
+		defer diagnostics.Close()
+	}
+
+	// 请求录制
+	if *recordDir != "" {
+		recorder, err := NewSessionRecorder(*recordDir)
+		if err != nil {
+			log.Fatalf("创建录制器失败: %v", err)
+		}
+		defer recorder.Close()
+		recorder.SetIdleTimeout(*recordIdle)
+		server.SetRecorder(recorder)
+		log.Printf("请求录制已开启，目录: %s", *recordDir)
+	}
+
+	// 设置HTTP路由
+	mux := http.NewServeMux()
+
 	// API文档和介绍页面
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
@@ -60,54 +401,292 @@ func main() {
 		<body>
 			<h1>MeowTalk SDK - 猫咪声音情感识别API</h1>
 			<p>这个服务提供猫咪声音的实时情感分析，支持HTTP和WebSocket接口。</p>
+			<p>测试识别效果请打开 <a href="/dashboard">/dashboard</a> 调试面板：查看活跃会话与样本库统计，用麦克风推流并实时查看波形、频谱图与情感时间线。</p>
 			
 			<h2>HTTP接口</h2>
 			
+			<p>全部接口（/api/init、/api/start、/api/send、/api/recv、/api/stop 等）由 <code>Handler</code> 挂载在 /api 下，
+			其他后端需要接入时以反向代理转发到本服务；WebSocket 端点 /ws 与调试面板 /dashboard 在根路径，也可通过 /api/ws、/api/dashboard 访问</p>
+			
+			<p>/api/send、/api/analyze-file 与 /api/jobs 的响应可改用 MessagePack 编码：请求头 <code>Accept: application/msgpack</code>
+			或查询参数 <code>?format=msgpack</code>，字段与JSON响应相同，带特征表的结果体积明显更小</p>
+			
+			<p>多副本部署：以 <code>-session-store redis://host:6379/0</code> 启动的各副本通过 Redis 共享会话配置、缓冲区与最新结果，
+			负载均衡可以把同一个流的 /api/send 分发到任意副本（接续缓冲区中未处理的样本），同一个流的音频块在各副本间依次处理。WebSocket 连接与情感变化事件仍由所在副本处理。
+			存储只在副本间传递未处理的样本：多窗口策略的段内累积、情感平滑与事件去抖保存在副本内，同一个流换到另一个副本时从头开始，
+			需要连续的平滑与事件时应让负载均衡按流固定副本</p>
+			
 			<div class="endpoint">
 				<p><span class="method">POST</span> /api/send</p>
 				<p>发送音频数据进行分析</p>
 				<p>请求体格式:</p>
 				<pre>{
   "streamId": "唯一标识符",
-  "data": [浮点数音频数据数组]
+  "data": [浮点数音频数据数组],
+  "lang": "zh",  // 可选：结果语言 en/zh/ja/es
+  "seq": 12,  // 可选：音频块序号，每块加1
+  "captureTime": 1700000000123  // 可选：第一个样本在客户端时钟上的采集时间（毫秒时间戳）
 }</pre>
-				<p>响应格式:</p>
+				<p>带 <code>seq</code> 时超时重发是幂等的：序号不大于已接收的最大序号的块不再追加进缓冲区，返回
+				<code>{"status": "duplicate", "seq": 12}</code>；跳号的块照常处理，响应附带 <code>"gap": {"from": 10, "to": 11, "missing": 2}</code>。
+				/api/stop 的响应以 <code>chunks</code> 汇总该流的重复块与缺失块数</p>
+				<p>带 <code>captureTime</code> 时按样本数把每个结果覆盖的音频映射回客户端时钟，结果附带 <code>clientTime</code>
+				（段内第一个样本与最后一个样本之后的采集时间），便于与同时录制的视频对齐；不带时间戳的块按最近的时间戳与样本数推算，
+				<code>driftMs</code> 为客户端时钟相对样本数推算时间的累计偏差</p>
+				<p>响应格式（与SDK的 RecvMessage 结果一致）:</p>
 				<pre>{
-  "status": "success|empty|no_cat_sound|too_short",
+  "streamId": "唯一标识符",
+  "resultId": "cat1_1700000000000000000_3",  // 结果ID，与日志及归档条目对应
+  "requestId": "9f2c4e1a7b3d5f60",  // 产生该结果的音频块的请求ID
+  "timestamp": 1700000000,
   "emotion": "识别的情感",
-  "confidence": 0.85  // 置信度0-1
+  "confidence": 0.85,  // 置信度0-1
+  "label": "本地化的情感名称",
+  "message": "面向用户的提示短语",
+  "partial": true,  // 叫声段未结束时出现，表示当前最佳猜测
+  "latencyMs": 95,  // 从段内第一个样本到达到结果产生的耗时
+  "clientTime": {"start": 1700000000123, "end": 1700000000216, "driftMs": 3},  // 仅在带 captureTime 时出现
+  "metadata": {"audioLength": 4096, "features": {...}, "timing": {"receivedAt": ..., "processStart": ..., "processEnd": ...}}
+}</pre>
+				<p>请求 <code>/api/send?debug=1</code>（或 /api/start 时设置 <code>"debug": true</code>）后该流的结果附带 <code>debug</code> 字段，
+				列出参与本次结果的每个窗口的特征与各情感评分：<code>{"windows": [{"features": {...}, "scores": {...}}]}</code>，<code>?debug=0</code> 关闭</p>
+				<p>/api/start 与 WebSocket 连接配置可带情境提示 <code>"hints": ["pre-feeding"]</code>（内置 pre-feeding/vet-visit/playtime/bedtime/cuddling/stranger，
+				领域配置的 <code>hints</code> 可覆盖或增加）与直接注入的情感先验 <code>"emotionPriors": {"for_food": 1.5}</code>，对该流的所有结果生效；
+				未知提示或非正的倍数返回 400</p>
+				<p>每个音频块分配请求ID，可通过请求头 <code>X-Request-ID</code> 自带（1~64个字母、数字、<code>_</code>、<code>-</code>），
+				响应头回显本次使用的ID；服务日志以 <code>request=... result=...</code> 记录每个音频块，便于按用户反馈的时间追查对应的音频与特征。
+				WebSocket 结果消息同样带有 <code>requestId</code></p>
+				<p>按 <code>-trigger</code> 触发条件（默认3个窗口、0.3秒静默、最长缓冲5秒）处理缓冲区，静默窗口跳过不分析；
+				一声叫声内的每个窗口返回 <code>partial</code> 结果，叫声后出现足够长的静默（或段长达到最长缓冲时间、流结束）时返回该段的最终结果</p>
+				<p>数据不足一个处理窗口或未满足触发条件时返回 <code>{"status": "waiting", "buffered": 2000, "required": 4096}</code></p>
+				<p>结果附带该流最近的叫声节奏 <code>{"cadence": {"callsPerMinute": 100, "interCallInterval": 0.6, "repetitions": 3}}</code>，
+				连续叫声达到3次时提示短语的强度提高一级；以叫声段为一声（首尾相接的窗口合并），频域流不统计</p>
+				<p>一次送入的音频中有多声叫声（如连续三声“喵-喵-喵”）时，返回最后一声的最终结果，
+				<code>segments</code> 按时间顺序列出本次完成的每一声，时间为距流开始的秒数（中间结果与频域流没有该字段）：
+				<code>{"segments": [{"start": 0.2, "end": 0.6, "emotion": "for_food", "confidence": 0.8}, ...]}</code></p>
+				<p>未经 /api/start 创建的流默认按默认配置自动创建会话；以 <code>-send-policy reject</code> 启动时返回 404，
+				错误码 <code>stream_not_found</code>，客户端需先调用 /api/start</p>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/events?streamId=唯一标识符</p>
+				<p>以 Server-Sent Events 推送情感变化事件，仅在平滑后的情感发生变化并持续超过去抖时长时发出</p>
+				<pre>event: emotion_change
+data: {"type":"emotion_change","streamId":"cat1","previous":"hello","emotion":"for_food","confidence":0.72,"since":1700000000000,"timestamp":1700000002000}</pre>
+				<p>以 <code>-distress-alert</code> 或 <code>-distress-rule</code> 启动时，不适情感在统计窗口内累计超过阈值（默认2分钟内30秒）
+				另发出 <code>alert</code> 事件，同一流在冷却时间内不重复告警；WebSocket 连接同样推送该事件</p>
+				<pre>event: alert
+data: {"type":"alert","streamId":"cat1","rule":"sustained-distress","emotion":"anxious","distressMs":30500,"windowMs":120000,"since":1700000000000,"timestamp":1700000060000}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">POST</span> /api/pause 与 /api/resume</p>
+				<p>应用切到后台时暂停流，请求体 <code>{"streamId": "唯一标识符"}</code>。暂停期间 /api/send 返回 409，
+				WebSocket 音频消息被丢弃并回复 <code>{"type": "error", "error": "stream paused"}</code>；缓冲区与情感状态保留到恢复</p>
+				<pre>{"success": true, "streamId": "cat1", "paused": true}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">POST</span> /api/video</p>
+				<p>将流之后的结果关联到外部视频片段（宠物摄像头切换到新片段时调用），也可在 /api/start 或 WebSocket 连接配置中以
+				<code>"video": {"clipId": "...", "cameraTime": ...}</code> 给出：</p>
+				<pre>{"streamId": "唯一标识符", "clipId": "cam1-0001", "cameraTime": 1700000000000}  // cameraTime 为片段第一帧在摄像头时钟上的毫秒时间戳</pre>
+				<p>之后的结果附带 <code>"video": {"clipId": "cam1-0001", "cameraTime": 1700000004200, "offsetMs": 4200}</code>，
+				offsetMs 为结果在片段中的位置：音频块带 <code>captureTime</code>（与摄像头同一时钟）时按采集时间计算，否则按服务端收到音频的时间相对关联时推算。
+				/api/stop 的响应以 <code>videos</code> 汇总每个片段关联的最终结果数与第一个、最后一个结果的位置</p>
+			</div>
+
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/timeline?catId=猫咪ID&amp;since=毫秒时间戳</p>
+				<p>同一只猫的多个设备（在 /api/start、WebSocket 配置消息或 /api/send 中带相同 <code>catId</code>）的最终结果汇总为一条时间线：
+				不同设备在时间上重叠的检测合并为一条，情感取置信度最高的来源；保留最近1小时，<code>since</code> 可选</p>
+				<pre>{
+  "catId": "mimi",
+  "streams": ["bedroom", "kitchen"],
+  "detections": [
+    {"start": 1700000000000, "end": 1700000000600, "emotion": "for_food", "confidence": 0.81,
+     "sources": [{"streamId": "kitchen", "resultId": "...", "emotion": "for_food", "confidence": 0.81},
+                 {"streamId": "bedroom", "resultId": "...", "emotion": "hello", "confidence": 0.55}]}
+  ]
+}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/baseline?catId=猫咪ID</p>
+				<p>以 <code>-baseline-dir</code> 启动时按 <code>catId</code> 长期累积叫声统计（基频范围、叫声频率、每天的叫声数），
+				保存在该目录下，关联猫咪的流的每个最终结果附带 <code>baseline</code> 字段给出与基线的偏离；累计30声前 <code>learning</code> 为 true</p>
+				<pre>{
+  "catId": "mimi",
+  "calls": 1520,
+  "updatedAt": 1700000000000,
+  "deviation": {"learning": false, "pitchLow": 380, "pitchHigh": 720, "callRate": 4.2, "typicalCallRate": 1.5,
+                "todayCount": 96, "typicalDailyCount": 48.5, "unusual": true, "unusualReason": "call_rate"},
+  "daily": [{"date": "2023-11-13", "count": 51}, {"date": "2023-11-14", "count": 96}]
+}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/reports?period=day|week&amp;date=2023-11-14</p>
+				<p>汇总当天（或截至 <code>date</code> 的7天）的最终结果：各情感的结果数、叫声分钟数、每小时的结果数与最繁忙的时段、
+				持续不适告警；可选 <code>catId</code>/<code>streamId</code> 只统计某只猫或某个流，<code>lang</code> 指定情感名称的语言。
+				以 <code>-history-dir</code> 启动时历史按天保存在该目录，每天的报告写入其中的 <code>reports/</code></p>
+				<pre>{
+  "period": "day",
+  "start": 1699891200000,
+  "end": 1699977600000,
+  "results": 182,
+  "emotions": [{"emotion": "hungry", "label": "饿了", "count": 64}, {"emotion": "hello", "label": "打招呼", "count": 40}],
+  "vocalizationMinutes": 9.6,
+  "hourly": [0, 0, 0, 0, 0, 0, 12, 31, 8, 0, 0, 0, 0, 0, 0, 0, 0, 4, 40, 22, 9, 0, 0, 0],
+  "busiestHours": [{"hour": 18, "count": 40}, {"hour": 7, "count": 31}, {"hour": 19, "count": 22}],
+  "alerts": []
+}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/export?format=json|csv&amp;period=day|week&amp;date=2023-11-14</p>
+				<p>按与 <code>/api/reports</code> 相同的区间与 <code>catId</code>/<code>streamId</code> 参数导出每个最终结果，
+				便于与喂食记录对照或发给兽医；<code>format=csv</code> 时返回带表头的CSV文件（timestamp, durationMs, emotion, confidence, intensity, catId, streamId）</p>
+				<pre>{
+  "period": "day",
+  "start": 1699891200000,
+  "end": 1699977600000,
+  "events": [{"timestamp": 1699956000000, "streamId": "kitchen", "catId": "mimi", "emotion": "hungry",
+              "confidence": 0.82, "durationMs": 2000, "intensity": "medium"}]
+}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">POST</span> /api/analyze-file</p>
+				<p>分析整段录音（WAV或MP3，最大64MB）：以 multipart/form-data 上传 <code>file</code> 字段，
+				或提交 <code>{"url": "https://..."}</code> 由服务端下载（仅 http/https，最多3次重定向，内容类型须为音频或 octet-stream，
+				可用 <code>-fetch-hosts</code> 限制主机，默认拒绝回环、私有与链路本地地址，内网存储需 <code>-fetch-allow-private</code>）；可选字段 lang/frequencyPreset/strategy/catName/context</p>
+				<p>录音按静默切分为叫声片段，返回各片段的识别结果:</p>
+				<pre>{
+  "duration": 300.5,
+  "sampleRate": 44100,
+  "segments": [
+    {"start": 1.24, "end": 2.10, "emotion": "for_food", "confidence": 0.81, "label": "...", "message": "..."}
+  ]
 }</pre>
 			</div>
 			
+			<div class="endpoint">
+				<p><span class="method">POST</span> /api/jobs</p>
+				<p>提交批量分析任务：multipart/form-data 中可包含多个 <code>file</code> 与 <code>url</code> 字段，
+				或提交 <code>{"urls": ["https://...", ...]}</code>；可选字段同 /api/analyze-file。返回 <code>{"jobId": "...", "status": "queued", "items": 3}</code></p>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/jobs/{id}</p>
+				<p>查询任务进度，每个文件的 <code>status</code> 为 queued/running/done/failed，完成的文件带 /api/analyze-file 格式的 <code>result</code>。
+				<code>progress</code> 为 <code>{"done": 12, "failed": 1, "total": 40, "etaSeconds": 85}</code>，done 含失败的文件，
+				etaSeconds 按本次运行的处理速度估算。任务状态保存在 <code>-jobs-dir</code> 目录，服务重启后从未完成的文件继续处理。
+				已完成的任务按 <code>-job-max-jobs</code> 与 <code>-job-max-age</code> 保留，超出后删除，查询返回404</p>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/library/stats</p>
+				<p>当前样本库各情感的样本数与特征均值/标准差、特征提取版本与加载时间，
+				样本数少于 <code>minRecommended</code> 的情感列在 <code>needsSamples</code> 中，可据此提示用户补充录音。
+				样本库由旧版特征提取构建（或未记录版本）时 <code>staleExtractor</code> 为 true，加载时也会打印警告，需重新构建样本库</p>
+				<pre>{"extractorVersion": "2", "staleExtractor": false, "loadedAt": 1700000000000, "totalSamples": 42, "minRecommended": 5, "needsSamples": ["sad"],
+ "emotions": {"sad": {"samples": 2, "mean": {"Pitch": 410.5, ...}, "stdDev": {"Pitch": 35.2, ...}}, ...}}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">POST</span> /api/admin/library/rebuild</p>
+				<p>管理接口（需以 <code>-admin-token</code> 启动并携带 <code>Authorization: Bearer &lt;token&gt;</code>）：
+				在服务器上处理按情感分目录的样本目录，构建完成后替换处理引擎当前的样本库，<code>output</code> 可选，设置后同时导出到该文件</p>
+				<pre>{"dir": "emotion_samples", "output": "sample_library.json"}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">POST</span> /api/admin/reload</p>
+				<p>管理接口：重新读取 <code>-profile</code>（叫声时长阈值、情感别名、提示短语）与 <code>-trigger</code>（缓冲处理触发条件）文件，
+				全部校验通过后才替换，进行中的会话不中断。向服务进程发送 <code>SIGHUP</code> 效果相同。
+				结果钩子（<code>-result-hooks</code>）、样本库与其他启动参数不会重新加载，修改后需重启服务</p>
+				<pre>{"profile": "cat", "phrases": 24, "trigger": {...}, "reloadedAt": 1700000000000}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/admin/labeling/clips</p>
+				<p>管理接口（需设置 <code>-archive</code> 启动）：列出置信度低于 <code>-label-max-confidence</code> 且未标注的归档片段，
+				置信度低的在前，<code>?limit=</code> 限制条数（默认50）。<code>GET /api/admin/labeling/clips/{resultId}/audio</code> 返回片段音频（WAV）</p>
+				<pre>{"clips": [{"resultId": "...", "emotion": "hungry", "confidence": 0.41, "audio": "....wav", ...}]}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">POST</span> /api/admin/labeling/clips/{resultId}</p>
+				<p>管理接口：标注片段，重新提取特征后加入 <code>-label-library</code> 标注样本库，<code>catId</code> 与 <code>tags</code> 可选，
+				已标注的片段返回 409</p>
+				<pre>{"emotion": "hungry", "catId": "mimi", "tags": ["夜间"]}
+→ {"resultId": "...", "emotion": "hungry", "totalSamples": 12, "applied": false}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /api/admin/energy</p>
+				<p>管理接口（需设置 <code>-energy-calibration 10m</code> 启动）：收到的前10分钟音频按20ms窗口统计的RMS直方图（dBFS），
+				噪声底取第20百分位，建议静默阈值（触发条件的静默检测与叫声分段）为噪声底+6dB、最小能量为噪声底+12dB（RMS低于最小能量的窗口不参与匹配）。
+				收集完成后 <code>POST {"apply": true}</code> 应用建议阈值，未完成时返回 409；以 <code>-energy-calibration-apply</code> 启动时自动应用</p>
+				<pre>{"complete": true, "collectedSeconds": 600.2, "targetSeconds": 600, "frames": 30010, "silentFrames": 12,
+ "percentiles": {"p10": 0.0028, "p20": 0.0032, "p50": 0.0079, "p90": 0.071, "p99": 0.22},
+ "noiseFloor": 0.0032, "recommended": {"silenceThreshold": 0.0063, "minEnergy": 0.0126},
+ "current": {"silenceThreshold": 0.02, "minEnergy": 0}, "applied": false,
+ "histogram": [{"db": -51, "rms": 0.0028, "frames": 2950}, ...]}</pre>
+			</div>
+			
+			<div class="endpoint">
+				<p><span class="method">GET</span> /debug/pprof/、/debug/vars（单独端口）</p>
+				<p>以 <code>-debug-addr 127.0.0.1:6060</code> 启动后在该地址提供 pprof 与 expvar，不经过API端口，
+				例如 <code>go tool pprof http://127.0.0.1:6060/debug/pprof/profile?seconds=30</code> 采集CPU，
+				<code>/debug/pprof/allocs</code> 采集内存分配；<code>/debug/vars</code> 另含 <code>goroutines</code> 与 <code>activeSessions</code></p>
+			</div>
+			
 			<h2>WebSocket接口</h2>
 			
 			<div class="endpoint">
 				<p><span class="method">WebSocket</span> /ws</p>
-				<p>建立WebSocket连接进行实时音频分析</p>
+				<p>建立WebSocket连接进行实时音频分析，可通过 <code>/ws?lang=zh</code> 指定结果语言</p>
+				<p>HTTPS 页面中的网页应用只能连接 <code>wss://</code>：以 <code>-tls-cert</code>、<code>-tls-key</code> 启动后服务以HTTPS监听（普通接口自动协商HTTP/2），
+				证书文件续期替换后自动重新加载</p>
+				<p>发送音频前可先发送连接配置，字段与 /api/start 相同（另有 <code>sampleRate</code> 简写未抽取时域数据的采样率、<code>lowLatency</code> 简写 low-latency 策略），
+				服务端校验后回复 <code>{"type": "config"}</code> 或 <code>{"type": "error"}</code>，出错时可修正后重新发送:</p>
+				<pre>{"type": "config", "sampleRate": 16000, "catId": "mimi", "lang": "zh", "lowLatency": true}</pre>
+				<p>也可以只声明数据格式（/api/start 的 <code>format</code> 字段相同），服务端回复 <code>{"type": "format"}</code> 或 <code>{"type": "error"}</code>。
+				未声明时按与引擎采样率一致的未抽取时域数据处理，处理引擎只接受这种时域数据。
+				<code>frequency</code> 模式下每条消息为一帧覆盖 0~sampleRate/2 的线性幅度，服务端不再做FFT，直接按频点宽度计算特征:</p>
+				<pre>{"format": {"sampleRate": 44100, "decimation": 10, "domain": "time|frequency"}}</pre>
+				<p>连接后服务端首先发送 <code>{"type": "init", "streamId": "...", "resumed": false, "resumeToken": "...", "resumeGraceMs": 30000}</code>。
+				断线后会话保留 <code>resumeGraceMs</code> 毫秒（<code>-resume-grace</code> 参数），期间以 <code>/ws?resume=&lt;resumeToken&gt;</code> 重连即接回同一会话，
+				已缓冲的音频继续处理，<code>resumed</code> 为 true；令牌过期或无效时创建新会话。恢复后重复声明相同的格式不会清空缓冲区</p>
+				<p>以 <code>/ws?debug=1</code> 连接或在连接配置中设置 <code>"debug": true</code> 时，结果附带与 /api/send 相同的 <code>debug</code> 字段</p>
+				<p>客户端支持时服务端协商 permessage-deflate 压缩（<code>-ws-compression</code>、<code>-ws-compression-level</code>），发送JSON浮点数组时可大幅减少流量</p>
+				<p>服务端每隔 <code>-ws-ping-interval</code> 发送 ping，超过 <code>-ws-pong-timeout</code> 未收到 pong 或任何消息即断开连接（浏览器会自动回复 pong）</p>
 				<p>发送消息格式:</p>
 				<pre>{
   "streamId": "唯一标识符",
-  "data": [浮点数音频数据数组]
+  "data": [浮点数音频数据数组],
+  "seq": 12,  // 可选：音频块序号，同 /api/send
+  "captureTime": 1700000000123  // 可选：采集时间，同 /api/send
 }</pre>
+				<p>带序号时重连后重发的块只回复 <code>{"type": "duplicate", "seq": 12}</code>，跳号时先回复
+				<code>{"type": "gap", "seq": 15, "gap": {"from": 13, "to": 14, "missing": 2}}</code> 再照常处理；恢复会话时序号接续</p>
 				<p>接收消息格式:</p>
 				<pre>{
   "status": "success|empty|no_cat_sound|too_short",
   "emotion": "识别的情感",
-  "confidence": 0.85  // 置信度0-1
+  "confidence": 0.85,  // 置信度0-1
+  "label": "本地化的情感名称",
+  "message": "面向用户的提示短语"
 }</pre>
 			</div>
 			
 			<h2>支持的情感类别</h2>
+			<p>由当前样本库与领域配置决定，也可通过 GET /api/emotions?lang=zh 获取</p>
 			<ul>
-				<li>angry - 生气</li>
-				<li>happy - 开心</li>
-				<li>excited - 兴奋</li>
-				<li>curious - 好奇</li>
-				<li>contented - 满足</li>
-				<li>sad - 悲伤</li>
-				<li>sleepy - 困倦</li>
-				<li>affectionate - 亲昵</li>
-				<li>unknown - 未知情感</li>
+` + emotionListHTML(processor.Emotions(), "zh") + `
+				<li>unknown - ` + EmotionLabel("zh", "unknown") + `</li>
 			</ul>
 			
 			<h2>调用示例</h2>
@@ -132,22 +711,46 @@ fetch('/api/send', {
 		w.Write([]byte(html))
 	})
 
-	// 音频处理API
-	mux.HandleFunc("/api/send", processor.handleSend)
+	// 全部接口挂载在 /api 下（见 registerRoutes）
+	mux.Handle("/api/", server.Handler(HandlerOptions{Prefix: "/api"}))
 
-	// WebSocket端点
-	mux.HandleFunc("/ws", processor.handleWebSocket)
+	// WebSocket端点与调试面板沿用根路径
+	mux.HandleFunc("/ws", server.handleWebSocket)
+	mux.HandleFunc("/dashboard", server.handleDashboard)
+	mux.HandleFunc("/dashboard/", server.handleDashboard)
 
 	// 将应用包装在CORS中间件中
 	handler := corsMiddleware(mux)
 
-	// 启动服务器
-	log.Println("正在启动HTTP服务器，监听端口: 8081...")
-	log.Println("API端点: http://localhost:8081/api/send")
-	log.Println("WebSocket端点: ws://localhost:8081/ws")
+	// 启动服务器，设置证书时以HTTPS监听，网页应用才能从HTTPS页面连接 wss://
+	tlsOptions := TLSOptions{CertFile: *tlsCert, KeyFile: *tlsKey}
+	if err := tlsOptions.Validate(); err != nil {
+		log.Fatalf("TLS参数无效: %v", err)
+	}
+	httpScheme, wsScheme := "http", "ws"
+	if tlsOptions.Enabled() {
+		httpScheme, wsScheme = "https", "wss"
+	}
+	host := *addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	log.Printf("正在启动HTTP服务器，监听地址: %s...", *addr)
+	log.Printf("API端点: %s://%s/api/send", httpScheme, host)
+	log.Printf("事件流端点: %s://%s/api/events?streamId=...", httpScheme, host)
+	log.Printf("情感集合端点: %s://%s/api/emotions", httpScheme, host)
+	log.Printf("猫咪时间线端点: %s://%s/api/timeline?catId=...", httpScheme, host)
+	if *baselineDir != "" {
+		log.Printf("猫咪基线端点: %s://%s/api/baseline?catId=...", httpScheme, host)
+	}
+	log.Printf("情感报告端点: %s://%s/api/reports?period=day", httpScheme, host)
+	log.Printf("叫声导出端点: %s://%s/api/export?format=csv", httpScheme, host)
+	log.Printf("录音分析端点: %s://%s/api/analyze-file", httpScheme, host)
+	log.Printf("批量任务端点: %s://%s/api/jobs", httpScheme, host)
+	log.Printf("WebSocket端点: %s://%s/ws", wsScheme, host)
+	log.Printf("调试面板: %s://%s/dashboard", httpScheme, host)
 
-	err := http.ListenAndServe(":8081", handler)
-	if err != nil {
+	if err := listenAndServe(*addr, handler, tlsOptions); err != nil {
 		log.Fatalf("服务器启动失败: %v", err)
 	}
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MockAudioProcessor 服务端测试使用的处理器替身：不分析音频，每个音频块返回固定情感的结果，
+// 结果格式与 Engine 相同。只实现 AudioProcessor 接口，不支持替换样本库、样本库统计等可选接口，
+// 需要真实识别结果的测试使用 newTestEngine
+type MockAudioProcessor struct {
+	Emotion    string  // 返回的情感，默认 for_food
+	Confidence float64 // 返回的置信度，默认0.8
+
+	mu       sync.Mutex
+	events   *EmotionEventHub
+	settings map[string]StreamSettings
+	results  map[string]int
+}
+
+// NewMockAudioProcessor 创建处理器替身
+func NewMockAudioProcessor() *MockAudioProcessor {
+	return &MockAudioProcessor{
+		Emotion:    "for_food",
+		Confidence: 0.8,
+		events:     NewEmotionEventHub(),
+		settings:   make(map[string]StreamSettings),
+		results:    make(map[string]int),
+	}
+}
+
+// ProcessAudio 返回固定情感的结果
+func (m *MockAudioProcessor) ProcessAudio(streamID string, data []float64) ([]byte, error) {
+	return m.ProcessAudioRequest(streamID, "", data)
+}
+
+// ProcessAudioRequest 返回固定情感的结果，空音频块返回错误
+func (m *MockAudioProcessor) ProcessAudioRequest(streamID, requestID string, data []float64) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty audio data")
+	}
+	m.mu.Lock()
+	lang := NormalizeLocale(m.settings[streamID].Lang)
+	m.results[streamID]++
+	count := m.results[streamID]
+	m.mu.Unlock()
+
+	now := time.Now()
+	return json.Marshal(AudioStreamResult{
+		StreamID:   streamID,
+		ResultID:   fmt.Sprintf("%s_%d", newResultID(streamID, now), count),
+		RequestID:  requestID,
+		Timestamp:  now.UnixMilli(),
+		Emotion:    m.Emotion,
+		Confidence: m.Confidence,
+		Label:      EmotionLabel(lang, m.Emotion),
+		Metadata:   AudioStreamMeta{AudioLength: len(data), Features: map[string]float64{}},
+	})
+}
+
+// ConfigureStream 校验并记录流的配置
+func (m *MockAudioProcessor) ConfigureStream(streamID string, settings StreamSettings) error {
+	if !settings.Format.IsZero() {
+		if err := settings.Format.Validate(); err != nil {
+			return err
+		}
+	}
+	if settings.Strategy != "" {
+		if _, err := LookupProcessingStrategy(settings.Strategy); err != nil {
+			return err
+		}
+	}
+	if settings.FrequencyPreset != "" {
+		if _, err := LookupFrequencyPreset(settings.FrequencyPreset); err != nil {
+			return err
+		}
+	}
+	if _, err := resolveHintPriors(settings.Hints, settings.EmotionPriors); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.settings[streamID] = settings
+	return nil
+}
+
+// SetStreamLanguage 设置流的结果语言
+func (m *MockAudioProcessor) SetStreamLanguage(streamID string, lang string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	settings := m.settings[streamID]
+	settings.Lang = lang
+	m.settings[streamID] = settings
+}
+
+// SetStreamDebug 替身不提供调试信息
+func (m *MockAudioProcessor) SetStreamDebug(streamID string, enabled bool) {}
+
+// StopStream 丢弃流的配置
+func (m *MockAudioProcessor) StopStream(streamID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.settings, streamID)
+	delete(m.results, streamID)
+}
+
+// Events 返回情感变化事件分发器
+func (m *MockAudioProcessor) Events() *EmotionEventHub {
+	return m.events
+}
+
+// AnalyzeFile 整段录音作为一个片段返回固定情感
+func (m *MockAudioProcessor) AnalyzeFile(audio *AudioData, settings StreamSettings) (*FileAnalysis, error) {
+	if audio == nil || len(audio.Samples) == 0 || audio.SampleRate <= 0 {
+		return nil, fmt.Errorf("empty audio")
+	}
+	duration := float64(len(audio.Samples)) / float64(audio.SampleRate)
+	return &FileAnalysis{
+		Duration:   duration,
+		SampleRate: audio.SampleRate,
+		Segments:   []SegmentResult{{Start: 0, End: duration, Emotion: m.Emotion, Confidence: m.Confidence}},
+	}, nil
+}
+
+// Emotions 返回当前领域配置的情感集合
+func (m *MockAudioProcessor) Emotions() []string {
+	return resolveEmotionSet(nil, CurrentDomainProfile())
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MessagePack 响应编码
+//
+// 结果中的特征表、评分与批量任务结果以JSON返回时数字都是文本，体积较大。客户端通过
+// Accept: application/msgpack 或 ?format=msgpack 请求时改用 MessagePack 返回。
+// 响应先按原有方式编码为JSON再转换，字段名与结构和JSON完全一致，客户端只需换解码器。
+
+// MsgpackContentType MessagePack 响应的内容类型
+const MsgpackContentType = "application/msgpack"
+
+// wantsMsgpack 判断请求是否要求 MessagePack 响应
+func wantsMsgpack(r *http.Request) bool {
+	if strings.EqualFold(r.URL.Query().Get("format"), "msgpack") {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, MsgpackContentType) || strings.Contains(accept, "application/x-msgpack")
+}
+
+// writeResponse 按请求协商的编码写出响应对象
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "生成响应失败", err.Error())
+		return
+	}
+	writeJSONResponse(w, r, status, data)
+}
+
+// writeJSONResponse 写出已编码为JSON的响应，请求 MessagePack 时转换后写出
+func writeJSONResponse(w http.ResponseWriter, r *http.Request, status int, data []byte) {
+	if wantsMsgpack(r) {
+		packed, err := jsonToMsgpack(data)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "生成响应失败", err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", MsgpackContentType)
+		w.WriteHeader(status)
+		w.Write(packed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// jsonToMsgpack 将JSON文档转换为 MessagePack，整数保持整数编码，对象的键按字典序排列
+func jsonToMsgpack(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("msgpack: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := encodeMsgpack(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeMsgpack 编码JSON解码得到的值（nil/bool/json.Number/string/数组/对象）
+func encodeMsgpack(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			encodeMsgpackInt(buf, n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("msgpack: invalid number %s", v)
+		}
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		encodeMsgpackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		encodeMsgpackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encodeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		encodeMsgpackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			encodeMsgpack(buf, key)
+			if err := encodeMsgpack(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+	return nil
+}
+
+// encodeMsgpackInt 按最短形式编码整数
+func encodeMsgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= 0x7f:
+		buf.WriteByte(byte(n))
+	case n >= -32 && n < 0:
+		buf.WriteByte(byte(0xe0 | (n + 32)))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// encodeMsgpackHeader 写出字符串/数组/对象的长度头：fix 形式的前缀与上限，以及8/16/32位长度的类型字节（8位为0表示不支持）
+func encodeMsgpackHeader(buf *bytes.Buffer, n int, fix byte, fixLimit int, code8, code16, code32 byte) {
+	switch {
+	case n < fixLimit:
+		buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMsgpackResponse 测试 MessagePack 响应编码
+// 测试内容：
+// 1. 各类型按规范的最短形式编码，整数保持整数，对象键有序
+// 2. 编码结果解码后与JSON一致
+// 3. Accept 请求头与 ?format=msgpack 协商，默认仍返回JSON
+func TestMsgpackResponse(t *testing.T) {
+	for _, tc := range []struct {
+		json string
+		want []byte
+	}{
+		{`null`, []byte{0xc0}},
+		{`true`, []byte{0xc3}},
+		{`5`, []byte{0x05}},
+		{`-3`, []byte{0xfd}},
+		{`200`, []byte{0xd1, 0x00, 0xc8}},
+		{`-100`, []byte{0xd0, 0x9c}},
+		{`"ab"`, []byte{0xa2, 'a', 'b'}},
+		{`[1,2]`, []byte{0x92, 0x01, 0x02}},
+		{`{"b":1,"a":2}`, []byte{0x82, 0xa1, 'a', 0x02, 0xa1, 'b', 0x01}},
+		{`0.5`, []byte{0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0}},
+	} {
+		got, err := jsonToMsgpack([]byte(tc.json))
+		if err != nil || !bytes.Equal(got, tc.want) {
+			t.Errorf("jsonToMsgpack(%s) = % x, %v, want % x", tc.json, got, err, tc.want)
+		}
+	}
+
+	doc := `{"emotion":"hello","confidence":0.85,"features":{"zcr":0.12,"bins":[` + strings.Repeat("1,", 40) + `-70000]},"text":"` + strings.Repeat("喵", 100) + `"}`
+	packed, err := jsonToMsgpack([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, rest, err := decodeMsgpackForTest(packed)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("decode = %v, %d trailing bytes", err, len(rest))
+	}
+	var want interface{}
+	json.Unmarshal([]byte(doc), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %v, want %v", got, want)
+	}
+
+	server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+	send := func(target string, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"streamId":"cat1","data":[0.1,0.2]}`))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		server.handleSend(rec, req)
+		return rec
+	}
+	for _, rec := range []*httptest.ResponseRecorder{send("/send?format=msgpack", ""), send("/send", "application/msgpack, application/json")} {
+		if ct := rec.Header().Get("Content-Type"); ct != MsgpackContentType {
+			t.Fatalf("Content-Type = %q, want %s", ct, MsgpackContentType)
+		}
+		body, _, err := decodeMsgpackForTest(rec.Body.Bytes())
+		if m, _ := body.(map[string]interface{}); err != nil || m["status"] != "waiting" {
+			t.Errorf("msgpack body = %v, %v", body, err)
+		}
+	}
+	if rec := send("/send", ""); rec.Header().Get("Content-Type") != "application/json" || !json.Valid(rec.Body.Bytes()) {
+		t.Errorf("default response = %q %s, want JSON", rec.Header().Get("Content-Type"), rec.Body)
+	}
+}
+
+// decodeMsgpackForTest 解码 jsonToMsgpack 会产生的类型，数字统一为 float64 以便与 encoding/json 结果比较
+func decodeMsgpackForTest(data []byte) (interface{}, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("unexpected end")
+	}
+	c, data := data[0], data[1:]
+	readN := func(size int) (uint64, error) {
+		if len(data) < size {
+			return 0, fmt.Errorf("unexpected end")
+		}
+		var n uint64
+		for _, b := range data[:size] {
+			n = n<<8 | uint64(b)
+		}
+		data = data[size:]
+		return n, nil
+	}
+	length := -1
+	kind := byte(0)
+	switch {
+	case c <= 0x7f:
+		return float64(c), data, nil
+	case c >= 0xe0:
+		return float64(int8(c)), data, nil
+	case c == 0xc0:
+		return nil, data, nil
+	case c == 0xc2 || c == 0xc3:
+		return c == 0xc3, data, nil
+	case c >= 0xd0 && c <= 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := readN(size)
+		shift := 64 - 8*size
+		return float64(int64(n<<shift) >> shift), data, err
+	case c == 0xcb:
+		n, err := readN(8)
+		return math.Float64frombits(n), data, err
+	case c >= 0xa0 && c <= 0xbf:
+		kind, length = 's', int(c&0x1f)
+	case c >= 0x90 && c <= 0x9f:
+		kind, length = 'a', int(c&0x0f)
+	case c >= 0x80 && c <= 0x8f:
+		kind, length = 'm', int(c&0x0f)
+	default:
+		sizes := map[byte]struct {
+			kind byte
+			size int
+		}{0xd9: {'s', 1}, 0xda: {'s', 2}, 0xdb: {'s', 4}, 0xdc: {'a', 2}, 0xdd: {'a', 4}, 0xde: {'m', 2}, 0xdf: {'m', 4}}
+		spec, ok := sizes[c]
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported type byte %#x", c)
+		}
+		n, err := readN(spec.size)
+		if err != nil {
+			return nil, nil, err
+		}
+		kind, length = spec.kind, int(n)
+	}
+
+	switch kind {
+	case 's':
+		if len(data) < length {
+			return nil, nil, fmt.Errorf("unexpected end")
+		}
+		return string(data[:length]), data[length:], nil
+	case 'a':
+		items := make([]interface{}, 0, length)
+		for i := 0; i < length; i++ {
+			item, rest, err := decodeMsgpackForTest(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			items, data = append(items, item), rest
+		}
+		return items, data, nil
+	default:
+		m := make(map[string]interface{}, length)
+		for i := 0; i < length; i++ {
+			key, rest, err := decodeMsgpackForTest(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			value, rest, err := decodeMsgpackForTest(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key.(string)], data = value, rest
+		}
+		return m, data, nil
+	}
+}
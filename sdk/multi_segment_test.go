@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// TestMultiVocalizationSegments 测试一次送入的数据中多声叫声的分段结果
+// 测试内容：
+// 1. 设置触发条件时静默分隔的三声叫声各有一项，按时间顺序排列，时间为距流开始的秒数
+// 2. 返回的是最后一声的最终结果，节奏统计为连续三声
+// 3. 下一次送入的数据只列出其中完成的叫声
+// 4. 未设置触发条件时一声连续的叫声（相邻窗口）合并为一项
+// 5. 中间结果没有 segments
+func TestMultiVocalizationSegments(t *testing.T) {
+	const rate = 44100
+	meow := func(seconds float64) []float64 { return generateTestAudio(600, seconds, rate) }
+	silence := func(seconds float64) []float64 { return make([]float64, int(seconds*rate)) }
+	join := func(chunks ...[]float64) []float64 {
+		var data []float64
+		for _, chunk := range chunks {
+			data = append(data, chunk...)
+		}
+		return data
+	}
+	process := func(engine *Engine, data []float64) AudioStreamResult {
+		t.Helper()
+		raw, err := engine.ProcessAudio("cat1", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result AudioStreamResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	policy := DefaultTriggerPolicy()
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 4096, Deterministic: true, Trigger: &policy})
+	result := process(engine, join(silence(0.2), meow(0.4), silence(0.4), meow(0.4), silence(0.4), meow(0.4), silence(0.5)))
+	if result.Partial || len(result.Segments) != 3 {
+		t.Fatalf("got %d segments (partial %v), want 3: %+v", len(result.Segments), result.Partial, result.Segments)
+	}
+	for i, segment := range result.Segments {
+		start := 0.2 + 0.8*float64(i)
+		if math.Abs(segment.Start-start) > 0.1 || math.Abs(segment.End-(start+0.4)) > 0.1 {
+			t.Errorf("segment %d = %.3f-%.3f, want about %.1f-%.1f", i, segment.Start, segment.End, start, start+0.4)
+		}
+		if segment.Emotion == "" || segment.Message == "" {
+			t.Errorf("segment %d = %+v, want an emotion and message", i, segment)
+		}
+	}
+	if last := result.Segments[2]; last.Emotion != result.Emotion || last.Confidence != result.Confidence {
+		t.Errorf("top level = %s %.3f, want the last call %s %.3f", result.Emotion, result.Confidence, last.Emotion, last.Confidence)
+	}
+	if result.Cadence == nil || result.Cadence.Repetitions != 3 {
+		t.Errorf("cadence = %+v, want 3 repetitions", result.Cadence)
+	}
+
+	next := process(engine, join(meow(0.4), silence(0.5)))
+	if len(next.Segments) != 1 || next.Segments[0].Start < 2.4 {
+		t.Errorf("next chunk segments = %+v, want only the new call after 2.4s", next.Segments)
+	}
+	if partial := process(engine, meow(0.3)); !partial.Partial || partial.Segments != nil {
+		t.Errorf("partial result = %+v, want no segments", partial)
+	}
+
+	plain := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 4096, Deterministic: true})
+	if single := process(plain, meow(1.5)); len(single.Segments) != 1 || single.Segments[0].End-single.Segments[0].Start < 1.3 {
+		t.Errorf("single vocalization segments = %+v, want one call spanning the tone", single.Segments)
+	}
+}
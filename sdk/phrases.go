@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"sync"
+	"text/template"
+)
+
+// PhraseRule 情感到提示短语的映射规则
+// Intensity/Context 为空表示匹配任意强度/上下文；规则越具体优先级越高
+type PhraseRule struct {
+	Emotion   string `json:"emotion"`             // 情感ID
+	Intensity string `json:"intensity,omitempty"` // 强度：low/medium/high
+	Context   string `json:"context,omitempty"`   // 上下文标签，如 feeding、night
+	Template  string `json:"template"`            // 文本模板，支持 {{.Name}} 等变量
+}
+
+// PhraseVars 短语模板可用的变量
+type PhraseVars struct {
+	Name       string // 猫咪名字
+	Emotion    string // 识别出的情感
+	Intensity  string // 叫声强度
+	Context    string // 当前上下文
+	Confidence int    // 置信度百分比
+
+	Repetitions int // 当前连续叫声的次数（见 call_cadence.go），未统计时为0
+}
+
+// 叫声强度等级
+const (
+	IntensityLow    = "low"
+	IntensityMedium = "medium"
+	IntensityHigh   = "high"
+
+	defaultCatName = "Your cat"
+)
+
+// IntensityFromRMS 根据均方根幅值估计叫声强度
+func IntensityFromRMS(rms float64) string {
+	switch {
+	case rms >= 0.2:
+		return IntensityHigh
+	case rms >= 0.05:
+		return IntensityMedium
+	default:
+		return IntensityLow
+	}
+}
+
+// ComposeMessage 按规则生成面向用户的提示短语，没有匹配规则时返回空字符串
+func ComposeMessage(rules []PhraseRule, vars PhraseVars) string {
+	rule, ok := selectPhraseRule(rules, vars)
+	if !ok {
+		return ""
+	}
+
+	if vars.Name == "" {
+		vars.Name = defaultCatName
+	}
+
+	tmpl, err := parsePhraseTemplate(rule.Template)
+	if err != nil {
+		log.Printf("短语模板解析失败 [%s]: %v", rule.Emotion, err)
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		log.Printf("短语模板渲染失败 [%s]: %v", rule.Emotion, err)
+		return ""
+	}
+	return buf.String()
+}
+
+// selectPhraseRule 选择与情感匹配且最具体的规则
+// 上下文匹配优先于强度匹配；规则中声明了但不匹配的条件会使规则失效
+func selectPhraseRule(rules []PhraseRule, vars PhraseVars) (PhraseRule, bool) {
+	bestScore := -1
+	var best PhraseRule
+
+	for _, rule := range rules {
+		if rule.Emotion != vars.Emotion {
+			continue
+		}
+
+		score := 0
+		if rule.Context != "" {
+			if rule.Context != vars.Context {
+				continue
+			}
+			score += 2
+		}
+		if rule.Intensity != "" {
+			if rule.Intensity != vars.Intensity {
+				continue
+			}
+			score++
+		}
+
+		if score > bestScore {
+			bestScore = score
+			best = rule
+		}
+	}
+
+	return best, bestScore >= 0
+}
+
+var phraseTemplates sync.Map // 模板缓存 text -> *template.Template
+
+// parsePhraseTemplate 解析并缓存短语模板
+func parsePhraseTemplate(text string) (*template.Template, error) {
+	if cached, ok := phraseTemplates.Load(text); ok {
+		return cached.(*template.Template), nil
+	}
+	tmpl, err := template.New("phrase").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	phraseTemplates.Store(text, tmpl)
+	return tmpl, nil
+}
+
+// phraseVarsFor 由识别结果构造模板变量
+func phraseVarsFor(cat CatProfile, context string, emotion string, confidence float64, rms float64) PhraseVars {
+	return PhraseVars{
+		Name:       cat.Name,
+		Emotion:    emotion,
+		Intensity:  IntensityFromRMS(rms),
+		Context:    context,
+		Confidence: int(math.Round(confidence * 100)),
+	}
+}
+
+// defaultCatPhrases 内置猫咪短语目录，情感ID与前端 emotions.ts 保持一致
+var defaultCatPhrases = []PhraseRule{
+	{Emotion: "call", Template: "Hey! {{.Name}} is calling for you."},
+	{Emotion: "comfortable", Template: "I feel so comfy and relaxed right now."},
+	{Emotion: "flighty", Template: "I love you, come cuddle with me!"},
+	{Emotion: "satisfy", Template: "Ahh, that was just what I wanted."},
+	{Emotion: "yummy", Template: "Mmm, this is delicious!"},
+	{Emotion: "hello", Template: "Hi there! Nice to see you."},
+	{Emotion: "for_food", Template: "I'm hungry, feed me!"},
+	{Emotion: "for_food", Intensity: IntensityHigh, Template: "FOOD! {{.Name}} is starving, the bowl is empty!"},
+	{Emotion: "for_food", Context: "feeding", Template: "It's dinner time — where's my food?"},
+	{Emotion: "ask_for_play", Template: "Let's play together!"},
+	{Emotion: "ask_for_hunting", Template: "I see something — let's go hunting!"},
+	{Emotion: "discomfort", Template: "I don't feel good, please leave me alone."},
+	{Emotion: "find_mom", Template: "Where are you? I need help!"},
+	{Emotion: "anxious", Template: "I'm scared, please stay close."},
+	{Emotion: "anxious", Context: "vet", Template: "I don't like it here, can we go home?"},
+	{Emotion: "courtship", Template: "Is anyone out there looking for love?"},
+	{Emotion: "curious", Template: "Hmm, what's that?"},
+	{Emotion: "goaway", Template: "Go away!"},
+	{Emotion: "goout", Template: "Get out of my space!"},
+	{Emotion: "dieaway", Template: "Back off right now!"},
+	{Emotion: "warning", Template: "This is your warning — stay away."},
+	{Emotion: "unhappy", Template: "I'm not happy about this. Leave me be."},
+	{Emotion: "alert", Template: "Something's not right, I'm on guard."},
+	{Emotion: "for_fight", Template: "Don't come any closer, I'm ready to fight!"},
+}
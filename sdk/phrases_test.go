@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+// TestComposeMessage 测试情感短语生成
+// 测试内容：
+// 1. 基本情感映射
+// 2. 强度、上下文更具体的规则优先
+// 3. 模板变量替换与默认名字
+// 4. 无匹配规则返回空字符串
+func TestComposeMessage(t *testing.T) {
+	rules := []PhraseRule{
+		{Emotion: "for_food", Template: "I'm hungry, feed me!"},
+		{Emotion: "for_food", Intensity: IntensityHigh, Template: "{{.Name}} is starving!"},
+		{Emotion: "for_food", Context: "feeding", Template: "Dinner time, {{.Name}}!"},
+	}
+
+	tests := []struct {
+		name string
+		vars PhraseVars
+		want string
+	}{
+		{"基本映射", PhraseVars{Emotion: "for_food", Intensity: IntensityLow}, "I'm hungry, feed me!"},
+		{"强度匹配", PhraseVars{Emotion: "for_food", Intensity: IntensityHigh, Name: "Mimi"}, "Mimi is starving!"},
+		{"上下文优先", PhraseVars{Emotion: "for_food", Intensity: IntensityHigh, Context: "feeding", Name: "Mimi"}, "Dinner time, Mimi!"},
+		{"默认名字", PhraseVars{Emotion: "for_food", Intensity: IntensityHigh}, defaultCatName + " is starving!"},
+		{"无匹配", PhraseVars{Emotion: "curious"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComposeMessage(rules, tt.vars); got != tt.want {
+				t.Errorf("ComposeMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDefaultCatPhrases 测试内置短语目录均可解析
+func TestDefaultCatPhrases(t *testing.T) {
+	if err := DefaultDomainProfile().Validate(); err != nil {
+		t.Fatalf("default profile invalid: %v", err)
+	}
+	if got := ComposeMessage(defaultCatPhrases, PhraseVars{Emotion: "for_food", Intensity: IntensityMedium}); got != "I'm hungry, feed me!" {
+		t.Errorf("for_food phrase = %q", got)
+	}
+}
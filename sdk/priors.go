@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// 时间与上下文先验
+//
+// 声学特征相近的叫声（如 for_food 与 call）单凭匹配分数很难区分，
+// 但结合时间和场景往往一目了然：喂食时间附近更可能是要吃的，深夜更可能是困倦。
+// 先验以倍数形式表示（1为中性），按贝叶斯方式与匹配分数相乘后重新选择最佳情感。
+
+// 先验默认值
+const (
+	defaultFeedingWindow = 30.0 // 喂食时间前后的影响范围（分钟）
+	defaultFeedingBoost  = 2.0  // 喂食时间点上 for_food/yummy 的先验倍数
+)
+
+// feedingEmotions 喂食时间附近先验增强的情感
+var feedingEmotions = []string{"for_food", "yummy"}
+
+// TimeWindowPrior 时间段先验，Start/End 为 "HH:MM"，End 早于 Start 时表示跨越午夜
+type TimeWindowPrior struct {
+	Start    string             `json:"start"`    // 开始时间
+	End      string             `json:"end"`      // 结束时间
+	Emotions map[string]float64 `json:"emotions"` // 情感 -> 先验倍数
+}
+
+// ContextPriors 猫咪的时间与上下文先验配置
+type ContextPriors struct {
+	FeedingTimes  []string                      `json:"feedingTimes,omitempty"`  // 喂食时间 "HH:MM"
+	FeedingWindow float64                       `json:"feedingWindow,omitempty"` // 喂食时间影响范围（分钟），为0时使用默认值30
+	FeedingBoost  float64                       `json:"feedingBoost,omitempty"`  // 喂食时间点的先验倍数，为0时使用默认值2
+	Windows       []TimeWindowPrior             `json:"windows,omitempty"`       // 其他时间段先验，如夜间 sleepy
+	Contexts      map[string]map[string]float64 `json:"contexts,omitempty"`      // 上下文标签 -> 情感 -> 先验倍数
+	Strength      float64                       `json:"strength,omitempty"`      // 先验强度 0-1，为0时使用1（完全按先验）
+}
+
+// Validate 校验先验配置
+func (p *ContextPriors) Validate() error {
+	for _, t := range p.FeedingTimes {
+		if _, err := parseClock(t); err != nil {
+			return fmt.Errorf("priors: feeding time: %v", err)
+		}
+	}
+	for i, w := range p.Windows {
+		if _, err := parseClock(w.Start); err != nil {
+			return fmt.Errorf("priors: window #%d start: %v", i, err)
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return fmt.Errorf("priors: window #%d end: %v", i, err)
+		}
+		if err := validatePriorWeights(w.Emotions); err != nil {
+			return fmt.Errorf("priors: window #%d: %v", i, err)
+		}
+	}
+	for ctx, weights := range p.Contexts {
+		if err := validatePriorWeights(weights); err != nil {
+			return fmt.Errorf("priors: context %s: %v", ctx, err)
+		}
+	}
+	if p.FeedingWindow < 0 || p.FeedingBoost < 0 || p.Strength < 0 || p.Strength > 1 {
+		return fmt.Errorf("priors: feedingWindow/feedingBoost must be non-negative and strength within 0-1")
+	}
+	return nil
+}
+
+// validatePriorWeights 先验倍数必须为正数
+func validatePriorWeights(weights map[string]float64) error {
+	for emotion, w := range weights {
+		if w <= 0 {
+			return fmt.Errorf("prior for %s must be positive", emotion)
+		}
+	}
+	return nil
+}
+
+// Evaluate 计算指定时刻和上下文下各情感的先验倍数，未出现的情感视为1
+func (p *ContextPriors) Evaluate(now time.Time, context string) map[string]float64 {
+	priors := make(map[string]float64)
+	if p == nil {
+		return priors
+	}
+
+	multiply := func(emotion string, w float64) {
+		if cur, ok := priors[emotion]; ok {
+			priors[emotion] = cur * w
+		} else {
+			priors[emotion] = w
+		}
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+
+	// 喂食时间：越接近喂食时间点先验越强，到窗口边缘线性衰减为1
+	window := p.FeedingWindow
+	if window == 0 {
+		window = defaultFeedingWindow
+	}
+	boost := p.FeedingBoost
+	if boost == 0 {
+		boost = defaultFeedingBoost
+	}
+	nearest := -1.0
+	for _, t := range p.FeedingTimes {
+		feeding, err := parseClock(t)
+		if err != nil {
+			continue
+		}
+		if d := float64(clockDistance(minute, feeding)); nearest < 0 || d < nearest {
+			nearest = d
+		}
+	}
+	if nearest >= 0 && nearest < window {
+		w := 1 + (boost-1)*(1-nearest/window)
+		for _, emotion := range feedingEmotions {
+			multiply(emotion, w)
+		}
+	}
+
+	// 时间段先验
+	for _, tw := range p.Windows {
+		start, err1 := parseClock(tw.Start)
+		end, err2 := parseClock(tw.End)
+		if err1 != nil || err2 != nil || !clockInRange(minute, start, end) {
+			continue
+		}
+		for emotion, w := range tw.Emotions {
+			multiply(emotion, w)
+		}
+	}
+
+	// 上下文先验
+	if context != "" {
+		for emotion, w := range p.Contexts[context] {
+			multiply(emotion, w)
+		}
+	}
+
+	// 按先验强度向中性值收缩
+	if p.Strength > 0 && p.Strength < 1 {
+		for emotion, w := range priors {
+			priors[emotion] = 1 + (w-1)*p.Strength
+		}
+	}
+
+	return priors
+}
+
+// applyEmotionPriors 将先验与匹配分数结合，返回后验最优的情感及其调整后的置信度
+// 先验先在候选情感上归一化（平均值为1），保证整体置信度量级不变，只改变候选之间的相对关系
+func applyEmotionPriors(scores map[string]float64, priors map[string]float64) (string, float64) {
+	if len(scores) == 0 {
+		return "", 0
+	}
+
+	weights := make(map[string]float64, len(scores))
+	total := 0.0
+	for emotion := range scores {
+		// 先验按规范情感ID（for_food）配置，未规范化的情感ID先转换后再查找
+		w := 1.0
+		if p, ok := priors[emotion]; ok {
+			w = p
+		} else if p, ok := priors[NormalizeEmotionID(emotion)]; ok {
+			w = p
+		}
+		weights[emotion] = w
+		total += w
+	}
+	mean := total / float64(len(scores))
+
+	bestEmotion := ""
+	bestScore := -1.0
+	for emotion, score := range scores {
+		posterior := score * weights[emotion] / mean
+		if posterior > 1 {
+			posterior = 1
+		}
+		if posterior > bestScore || (posterior == bestScore && emotion < bestEmotion) {
+			bestScore = posterior
+			bestEmotion = emotion
+		}
+	}
+
+	log.Printf("应用时间/上下文先验: 情感=%s, 调整后置信度=%.4f", bestEmotion, bestScore)
+	return bestEmotion, bestScore
+}
+
+// parseClock 解析 "HH:MM" 为一天中的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// clockDistance 一天中两个时刻之间的最短距离（分钟），考虑跨越午夜
+func clockDistance(a, b int) int {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	if d > 12*60 {
+		d = 24*60 - d
+	}
+	return d
+}
+
+// clockInRange 判断时刻是否在 [start, end) 范围内，end 早于 start 时表示跨越午夜
+func clockInRange(minute, start, end int) bool {
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
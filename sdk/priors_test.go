@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestContextPriorsEvaluate 测试先验倍数计算
+// 测试内容：
+// 1. 喂食时间点先验最强，随距离线性衰减，窗口外为中性
+// 2. 跨越午夜的时间段
+// 3. 上下文先验与强度收缩
+func TestContextPriorsEvaluate(t *testing.T) {
+	priors := &ContextPriors{
+		FeedingTimes: []string{"07:30", "18:00"},
+		Windows: []TimeWindowPrior{
+			{Start: "22:00", End: "06:00", Emotions: map[string]float64{"sleepy": 3}},
+		},
+		Contexts: map[string]map[string]float64{
+			"vet": {"anxious": 2},
+		},
+	}
+	if err := priors.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	at := func(clock string) time.Time {
+		tm, _ := time.Parse("15:04", clock)
+		return tm
+	}
+
+	tests := []struct {
+		name    string
+		clock   string
+		context string
+		emotion string
+		want    float64
+	}{
+		{"喂食时间点", "18:00", "", "for_food", 2},
+		{"喂食窗口中点", "07:45", "", "yummy", 1.5},
+		{"喂食窗口外", "12:00", "", "for_food", 1},
+		{"跨午夜时间段", "01:00", "", "sleepy", 3},
+		{"时间段外", "12:00", "", "sleepy", 1},
+		{"上下文先验", "12:00", "vet", "anxious", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := priors.Evaluate(at(tt.clock), tt.context)[tt.emotion]
+			if !ok {
+				got = 1
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("prior[%s] at %s = %.3f, want %.3f", tt.emotion, tt.clock, got, tt.want)
+			}
+		})
+	}
+
+	priors.Strength = 0.5
+	if got := priors.Evaluate(at("18:00"), "")["for_food"]; math.Abs(got-1.5) > 1e-9 {
+		t.Errorf("prior with strength 0.5 = %.3f, want 1.5", got)
+	}
+
+	var none *ContextPriors
+	if got := none.Evaluate(at("18:00"), "vet"); len(got) != 0 {
+		t.Errorf("nil priors = %v, want empty", got)
+	}
+}
+
+// TestApplyEmotionPriors 测试先验与匹配分数的结合
+// 测试内容：
+// 1. 匹配分数接近时先验决定结果
+// 2. 匹配分数差距明显时先验不改变结果
+// 3. 连字符情感ID按前端ID查找先验
+func TestApplyEmotionPriors(t *testing.T) {
+	priors := map[string]float64{"for_food": 2}
+
+	emotion, _ := applyEmotionPriors(map[string]float64{"call": 0.62, "for-food": 0.60}, priors)
+	if emotion != "for-food" {
+		t.Errorf("ambiguous case: emotion = %s, want for-food", emotion)
+	}
+
+	emotion, _ = applyEmotionPriors(map[string]float64{"call": 0.9, "for_food": 0.3}, priors)
+	if emotion != "call" {
+		t.Errorf("clear case: emotion = %s, want call", emotion)
+	}
+
+	if err := (&ContextPriors{FeedingTimes: []string{"25:00"}}).Validate(); err == nil {
+		t.Error("Validate() should reject invalid feeding time")
+	}
+}
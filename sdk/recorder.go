@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// 请求录制与回放
+//
+// 用户反馈识别错误时，单凭描述很难复现。录制模式把每个会话收到的 /api/start、/api/send、/api/stop 请求体
+// 和 WebSocket 消息（含连接配置）按到达时间写入 JSONL 文件，replay 命令再按原始时间间隔送回服务端的
+// 同一条处理路径（序号去重、采集时间、结果钩子与基线都照常生效），从而得到与现场一致的处理过程。
+// 带序号的重复块在去重后丢弃，不会录制。会话停止、WebSocket 会话结束或超过空闲时长没有新请求时关闭录制文件。
+
+// 录制条目类型
+const (
+	RecordKindHTTP      = "http" // /api/send 请求体
+	RecordKindWebSocket = "ws"   // WebSocket 消息
+)
+
+// DefaultRecordIdleTimeout 会话超过该时长没有新请求时关闭录制文件
+const DefaultRecordIdleTimeout = 10 * time.Minute
+
+// RecordedRequest 录制文件中的一条记录
+type RecordedRequest struct {
+	OffsetMs int64           `json:"offsetMs"`       // 相对会话第一条记录的时间偏移（毫秒）
+	Kind     string          `json:"kind"`           // 记录类型：http / ws
+	Path     string          `json:"path,omitempty"` // HTTP 请求的接口：/start /send /stop，为空时为 /send
+	StreamID string          `json:"streamId"`       // 流ID
+	Payload  json.RawMessage `json:"payload"`        // 原始请求体或消息
+}
+
+// recordingFile 单个会话的录制文件
+type recordingFile struct {
+	file  *os.File
+	start time.Time
+	idle  *time.Timer // 空闲超时后关闭文件
+}
+
+// SessionRecorder 按会话将请求写入录制目录，每个会话一个 JSONL 文件
+type SessionRecorder struct {
+	dir         string
+	idleTimeout time.Duration
+	mu          sync.Mutex
+	sessions    map[string]*recordingFile
+}
+
+// NewSessionRecorder 创建录制器，目录不存在时自动创建
+func NewSessionRecorder(dir string) (*SessionRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create record dir: %v", err)
+	}
+	return &SessionRecorder{
+		dir:         dir,
+		idleTimeout: DefaultRecordIdleTimeout,
+		sessions:    make(map[string]*recordingFile),
+	}, nil
+}
+
+// SetIdleTimeout 设置会话空闲多久后关闭录制文件，0表示只在会话结束时关闭
+func (r *SessionRecorder) SetIdleTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.idleTimeout = timeout
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// Record 记录一条请求，payload 必须是合法的JSON
+func (r *SessionRecorder) Record(kind, streamID string, payload []byte) {
+	r.write(RecordedRequest{Kind: kind, StreamID: streamID, Payload: payload})
+}
+
+// RecordHTTP 记录一个 HTTP 请求体，path 为接口路径（如 /send）
+func (r *SessionRecorder) RecordHTTP(path, streamID string, payload []byte) {
+	r.write(RecordedRequest{Kind: RecordKindHTTP, Path: path, StreamID: streamID, Payload: payload})
+}
+
+// write 将记录追加到会话的录制文件，会话第一条记录时创建文件
+func (r *SessionRecorder) write(record RecordedRequest) {
+	streamID, payload := record.StreamID, record.Payload
+	if !json.Valid(payload) {
+		log.Printf("录制跳过非JSON数据: StreamID=%s", streamID)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[streamID]
+	if !ok {
+		name := fmt.Sprintf("%s-%d.jsonl", unsafeFileChars.ReplaceAllString(streamID, "_"), time.Now().UnixNano())
+		file, err := os.Create(filepath.Join(r.dir, name))
+		if err != nil {
+			log.Printf("创建录制文件失败: %v", err)
+			return
+		}
+		session = &recordingFile{file: file, start: time.Now()}
+		r.sessions[streamID] = session
+		log.Printf("开始录制会话: StreamID=%s, 文件=%s", streamID, file.Name())
+	}
+	if r.idleTimeout > 0 {
+		if session.idle == nil {
+			session.idle = time.AfterFunc(r.idleTimeout, func() { r.closeIdle(streamID, session) })
+		} else {
+			session.idle.Reset(r.idleTimeout)
+		}
+	}
+
+	record.OffsetMs = time.Since(session.start).Milliseconds()
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("序列化录制记录失败: %v", err)
+		return
+	}
+	if _, err := session.file.Write(append(line, '\n')); err != nil {
+		log.Printf("写入录制文件失败: %v", err)
+	}
+}
+
+// CloseSession 结束指定会话的录制
+func (r *SessionRecorder) CloseSession(streamID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[streamID]; ok {
+		session.close()
+		delete(r.sessions, streamID)
+	}
+}
+
+// closeIdle 空闲超时：会话仍在使用该录制文件时结束录制
+func (r *SessionRecorder) closeIdle(streamID string, session *recordingFile) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sessions[streamID] == session {
+		session.close()
+		delete(r.sessions, streamID)
+		log.Printf("会话空闲超时，结束录制: StreamID=%s", streamID)
+	}
+}
+
+// Close 结束所有会话的录制
+func (r *SessionRecorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		session.close()
+		delete(r.sessions, id)
+	}
+}
+
+// close 停止空闲计时并关闭文件
+func (f *recordingFile) close() {
+	if f.idle != nil {
+		f.idle.Stop()
+	}
+	f.file.Close()
+}
+
+// recordWebSocket 开启录制时记录一条 WebSocket 消息
+func (s *AudioServer) recordWebSocket(streamID string, message []byte) {
+	if s.recorder != nil {
+		s.recorder.Record(RecordKindWebSocket, streamID, message)
+	}
+}
+
+// recordHTTP 开启录制时记录一个 HTTP 请求体
+func (s *AudioServer) recordHTTP(path, streamID string, payload []byte) {
+	if s.recorder != nil {
+		s.recorder.RecordHTTP(path, streamID, payload)
+	}
+}
+
+// LoadRecording 读取录制文件
+func LoadRecording(path string) ([]RecordedRequest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []RecordedRequest
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record RecordedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// ReplayRecording 按录制时的时间间隔将记录送回处理器，并把每个响应写入 out（JSONL）
+// speed 为回放倍速，1为原始速度，<=0 表示不等待直接回放
+func ReplayRecording(p AudioProcessor, records []RecordedRequest, speed float64, out io.Writer) error {
+	return NewAudioServer(p).Replay(records, speed, out)
+}
+
+// Replay 按录制时的时间间隔将记录送入服务端的处理路径：HTTP 记录交给对应接口，WebSocket 记录按连接的消息处理，
+// 每个响应写入 out（JSONL，{"index", "offsetMs", "status", "response"}）。请求ID按记录序号生成，确定性模式下输出可重复
+// speed 为回放倍速，1为原始速度，<=0 表示不等待直接回放
+func (s *AudioServer) Replay(records []RecordedRequest, speed float64, out io.Writer) error {
+	handlers := map[string]http.HandlerFunc{
+		"/start": s.handleStart,
+		"/send":  s.handleSend,
+		"":       s.handleSend, // 旧录制文件只有 /send 请求体
+		"/stop":  s.handleStop,
+	}
+	type replayedSocket struct {
+		session      *wsSession
+		audioStarted bool
+	}
+	sockets := make(map[string]*replayedSocket)
+	var order []string
+	start := time.Now()
+
+	for i, record := range records {
+		if speed > 0 {
+			due := time.Duration(float64(record.OffsetMs)/speed) * time.Millisecond
+			if wait := due - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		requestID := fmt.Sprintf("replay-%d", i)
+		emit := func(status int, response []byte) {
+			if len(bytes.TrimSpace(response)) == 0 {
+				response = []byte("null")
+			}
+			fmt.Fprintf(out, "{\"index\":%d,\"offsetMs\":%d,\"status\":%d,\"response\":%s}\n", i, record.OffsetMs, status, bytes.TrimSpace(response))
+		}
+
+		switch record.Kind {
+		case RecordKindHTTP:
+			handler, ok := handlers[record.Path]
+			if !ok {
+				return fmt.Errorf("record #%d: unknown path %q", i, record.Path)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api"+record.Path, bytes.NewReader(record.Payload))
+			req.Header.Set(RequestIDHeader, requestID)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			emit(rec.Code, rec.Body.Bytes())
+		case RecordKindWebSocket:
+			socket, ok := sockets[record.StreamID]
+			if !ok {
+				socket = &replayedSocket{session: &wsSession{streamID: record.StreamID, attached: true}}
+				sockets[record.StreamID] = socket
+				order = append(order, record.StreamID)
+			}
+			socket.audioStarted = s.handleWebSocketMessage(socket.session, record.Payload, requestID, socket.audioStarted, false, func(v interface{}) error {
+				data, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				emit(http.StatusOK, data)
+				return nil
+			})
+		default:
+			return fmt.Errorf("record #%d: unknown kind %q", i, record.Kind)
+		}
+	}
+
+	// 回放结束即连接关闭
+	for _, streamID := range order {
+		s.endWebSocketSession(streamID)
+	}
+	return nil
+}
+
+// runReplay replay 子命令：mock_server replay [-speed 1] <录制文件>
+func runReplay(args []string) error {
+	fs := newFlagSet("replay")
+	speed := fs.Float64("speed", 1, "回放倍速，0表示不等待直接回放")
+	profilePath := fs.String("profile", "", "领域配置文件路径（JSON）")
+	deterministic := fs.Bool("deterministic", false, "确定性模式：按样本数而非墙上时钟触发处理，配合 -speed 0 用于回归测试")
+	engineOpts := addEngineFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: replay [-speed 1] [-deterministic] [-profile path] [-library path] <recording.jsonl>")
+	}
+
+	if err := applyProfileFlag(*profilePath, *engineOpts.bundle, *engineOpts.bundleKeys); err != nil {
+		return err
+	}
+
+	records, err := LoadRecording(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	log.Printf("回放录制文件 %s: %d 条记录, 倍速 %.1f", fs.Arg(0), len(records), *speed)
+
+	processor, err := engineOpts.newProcessor(DefaultEventDebounce, *deterministic)
+	if err != nil {
+		return err
+	}
+	return ReplayRecording(processor, records, *speed, os.Stdout)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSessionRecorderRoundTrip 测试请求录制与读取
+// 测试内容：
+// 1. 服务端录制 /start、/send、/stop 请求体，记录接口路径且不影响处理
+// 2. 会话停止时关闭录制文件，之后的请求录制到新文件
+// 3. WebSocket消息与HTTP请求按流分别写入文件，非JSON消息被跳过
+// 4. 超过空闲时长没有新请求时关闭录制文件
+func TestSessionRecorderRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewSessionRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewSessionRecorder() error = %v", err)
+	}
+	server := NewAudioServer(NewMockAudioProcessor())
+	server.SetRecorder(recorder)
+	post := func(handler http.HandlerFunc, body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+	}
+
+	send := `{"streamId":"cat/1","data":[0.1,0.2,0.3],"lang":"zh"}`
+	post(server.handleStart, `{"streamId":"cat/1","lang":"zh"}`)
+	post(server.handleSend, send)
+	post(server.handleSend, send)
+	post(server.handleStop, `{"streamId":"cat/1"}`)
+	post(server.handleSend, send)
+	recorder.Record(RecordKindWebSocket, "ws-1", []byte(`{"data":[0.5,0.6]}`))
+	recorder.Record(RecordKindWebSocket, "ws-1", []byte(`not json`))
+	recorder.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "cat_1-*.jsonl"))
+	if len(files) != 2 {
+		t.Fatalf("http recording files = %v, want 2 (stop closes the first)", files)
+	}
+	var paths []string
+	for _, file := range files {
+		records, err := LoadRecording(file)
+		if err != nil {
+			t.Fatalf("LoadRecording() error = %v", err)
+		}
+		for _, record := range records {
+			if record.Kind != RecordKindHTTP || record.StreamID != "cat/1" {
+				t.Errorf("record = %+v", record)
+			}
+			paths = append(paths, record.Path)
+		}
+		if len(records) == 4 && records[2].OffsetMs < records[1].OffsetMs {
+			t.Errorf("offsets not monotonic: %d, %d", records[1].OffsetMs, records[2].OffsetMs)
+		}
+	}
+	if got := strings.Join(paths, " "); got != "/start /send /send /stop /send" && got != "/send /start /send /send /stop" {
+		t.Errorf("recorded paths = %q", got)
+	}
+
+	files, _ = filepath.Glob(filepath.Join(dir, "ws-1-*.jsonl"))
+	if len(files) != 1 {
+		t.Fatalf("ws recording files = %v, want 1", files)
+	}
+	records, err := LoadRecording(files[0])
+	if err != nil || len(records) != 1 {
+		t.Fatalf("ws records = %+v, err = %v", records, err)
+	}
+	data, err := decodeWebSocketAudio(records[0].Payload)
+	if err != nil || len(data) != 2 || data[1] != 0.6 {
+		t.Errorf("decoded ws audio = %v, err = %v", data, err)
+	}
+
+	// 空闲超时
+	recorder.SetIdleTimeout(20 * time.Millisecond)
+	recorder.Record(RecordKindWebSocket, "idle", []byte(`{"data":[0.1]}`))
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		recorder.mu.Lock()
+		open := len(recorder.sessions)
+		recorder.mu.Unlock()
+		if open == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("idle recording was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRecorderSkipsDuplicateChunks 测试去重后录制
+// 测试内容：
+// 1. 带序号的重复块不录制，HTTP 与 WebSocket 相同
+// 2. 暂停期间被拒绝的块不录制
+func TestRecorderSkipsDuplicateChunks(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewSessionRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewAudioServer(NewMockAudioProcessor())
+	server.SetRecorder(recorder)
+
+	for _, seq := range []int{1, 1, 2} {
+		body := fmt.Sprintf(`{"streamId":"cat","seq":%d,"data":[0.1,0.2,0.3]}`, seq)
+		server.handleSend(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(body)))
+	}
+	server.setPaused("cat", true)
+	server.handleSend(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(`{"streamId":"cat","seq":3,"data":[0.1]}`)))
+
+	session := &wsSession{streamID: "ws-1", attached: true}
+	started := false
+	for _, message := range []string{`{"type":"config","lang":"zh"}`, `{"seq":1,"data":[0.1,0.2]}`, `{"seq":1,"data":[0.1,0.2]}`} {
+		started = server.handleWebSocketMessage(session, []byte(message), "r", started, false, func(interface{}) error { return nil })
+	}
+	recorder.Close()
+
+	for pattern, want := range map[string]int{"cat-*.jsonl": 2, "ws-1-*.jsonl": 2} {
+		files, _ := filepath.Glob(filepath.Join(dir, pattern))
+		if len(files) != 1 {
+			t.Fatalf("%s files = %v, want 1", pattern, files)
+		}
+		records, err := LoadRecording(files[0])
+		if err != nil || len(records) != want {
+			t.Errorf("%s records = %+v, err = %v; want %d", pattern, records, err, want)
+		}
+	}
+}
+
+// TestReplayThroughServer 测试录制经服务端处理路径回放
+// 测试内容：
+// 1. /start 的会话配置、WebSocket 连接配置在回放时生效
+// 2. 带序号的重复块按去重返回 duplicate，不再处理
+// 3. 请求ID按记录序号生成，结果中携带
+func TestReplayThroughServer(t *testing.T) {
+	chunk := func(seq int) json.RawMessage {
+		data := make([]float64, 22050)
+		for i := range data {
+			data[i] = 0.4 * float64((i/20)%2*2-1)
+		}
+		payload, _ := json.Marshal(map[string]interface{}{"streamId": "cat", "seq": seq, "data": data})
+		return payload
+	}
+	records := []RecordedRequest{
+		{Kind: RecordKindHTTP, Path: "/start", StreamID: "cat", Payload: json.RawMessage(`{"streamId":"cat","lang":"en"}`)},
+		{Kind: RecordKindHTTP, Path: "/send", StreamID: "cat", Payload: chunk(0)},
+		{Kind: RecordKindHTTP, Path: "/send", StreamID: "cat", Payload: chunk(0)},
+		{Kind: RecordKindHTTP, Path: "/send", StreamID: "cat", Payload: chunk(1)},
+		{Kind: RecordKindWebSocket, StreamID: "ws-1", Payload: json.RawMessage(`{"type":"config","lang":"zh"}`)},
+		{Kind: RecordKindWebSocket, StreamID: "ws-1", Payload: chunk(0)},
+		{Kind: RecordKindWebSocket, StreamID: "ws-1", Payload: chunk(0)},
+		{Kind: RecordKindHTTP, Path: "/stop", StreamID: "cat", Payload: json.RawMessage(`{"streamId":"cat"}`)},
+	}
+
+	processor := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Deterministic: true})
+	var out bytes.Buffer
+	if err := ReplayRecording(processor, records, 0, &out); err != nil {
+		t.Fatalf("ReplayRecording() error = %v", err)
+	}
+
+	type line struct {
+		Index    int
+		Status   int
+		Response map[string]interface{}
+	}
+	var lines []line
+	for _, text := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var l line
+		if err := json.Unmarshal([]byte(text), &l); err != nil {
+			t.Fatalf("output line %q: %v", text, err)
+		}
+		lines = append(lines, l)
+	}
+	byIndex := make(map[int][]map[string]interface{})
+	for _, l := range lines {
+		if l.Status != http.StatusOK {
+			t.Errorf("record #%d status = %d, response %v", l.Index, l.Status, l.Response)
+		}
+		byIndex[l.Index] = append(byIndex[l.Index], l.Response)
+	}
+
+	if r := byIndex[1]; len(r) != 1 || r[0]["requestId"] != "replay-1" {
+		t.Errorf("first chunk response = %v, want requestId replay-1", r)
+	}
+	if r := byIndex[2]; len(r) != 1 || r[0]["status"] != "duplicate" {
+		t.Errorf("resent chunk response = %v, want duplicate", r)
+	}
+	if r := byIndex[4]; len(r) != 1 || r[0]["type"] != "config" || r[0]["lang"] != "zh" {
+		t.Errorf("ws config response = %v", r)
+	}
+	if r := byIndex[6]; len(r) != 1 || r[0]["type"] != "duplicate" {
+		t.Errorf("resent ws chunk response = %v, want duplicate", r)
+	}
+	if r := byIndex[7]; len(r) != 1 || r[0]["success"] != true {
+		t.Errorf("stop response = %v", r)
+	}
+}
@@ -2,23 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/cmplx"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-audio/audio"
 	"github.com/go-audio/wav"
+	"soundsdk/dsp"
 )
 
 // NewSampleProcessor 创建新的样本处理器实例
 func NewSampleProcessor(config AudioStreamConfig) *SampleProcessor {
 	return &SampleProcessor{
-		Library: &SampleLibrary{
-			Samples:    make(map[string][]AudioSample),
-			Statistics: make(map[string]EmotionStatistics),
-		},
+		Library: NewSampleLibrary(),
 		SampleRate: func() int {
 			if config.SampleRate == 0 {
 				return 44100
@@ -31,43 +32,122 @@ func NewSampleProcessor(config AudioStreamConfig) *SampleProcessor {
 			}
 			return config.BufferSize
 		}(), // 默认窗口大小
-		FFTSize:     2048, // 默认FFT大小
-		FrameLength: 25.0, // 默认帧长（毫秒）
+		FFTSize:           2048,                  // 默认FFT大小
+		FrameLength:       25.0,                  // 默认帧长（毫秒）
+		NormalizeLoudness: false,                 // 默认不做响度归一化：运行时不归一化实时音频，归一化后的样本 Energy/RMS 与运行时不可比
+		TargetLoudness:    DefaultTargetLoudness, // EBU R128 目标响度
 	}
 }
 
-// 加载音频文件
-func loadAudioFile(filePath string) ([]float64, error) {
-	file, err := os.Open(filePath)
+// sampleFileExtensions 样本目录中可加载的音频格式（扩展名不区分大小写）
+var sampleFileExtensions = map[string]bool{".wav": true, ".mp3": true}
+
+// unsupportedAudioExtensions 能识别但无法解码的音频格式，出现时作为加载错误报告而不是静默跳过
+var unsupportedAudioExtensions = map[string]bool{".flac": true, ".ogg": true, ".m4a": true, ".aac": true}
+
+// FileLoadError 单个样本文件的加载错误
+type FileLoadError struct {
+	Path string
+	Err  error
+}
+
+func (e FileLoadError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// LoadErrors 目录中加载失败的样本文件，加载成功的样本仍然加入样本库
+type LoadErrors []FileLoadError
+
+func (e LoadErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d sample files failed to load, first: %v", len(e), e[0])
+}
+
+// 加载音频文件，按文件头识别WAV/MP3
+func loadAudioFile(filePath string) (*AudioData, error) {
+	return LoadAudio(filePath, DecodeOptions{})
+}
+
+// sampleEmotion 样本文件的情感类别：情感子目录中的文件取目录名，
+// 目录顶层的文件取文件名中第一个 "_" 之前的部分（如 hungry_1.mp3 为 hungry）
+func sampleEmotion(dirName, fileName string) string {
+	if dirName != "" {
+		return dirName
+	}
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	return strings.SplitN(name, "_", 2)[0]
+}
+
+// decodeWAV 解码WAV数据为[-1, 1]范围的浮点样本
+func decodeWAV(r io.ReadSeeker) ([]float64, error) {
+	audioData, err := decodeWAVAudio(r, DecodeOptions{})
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return audioData.Samples, nil
+}
 
-	decoder := wav.NewDecoder(file)
+// decodeWAVAudio 解码WAV数据为单声道浮点样本及其采样率，多声道按选项合并
+// 输入可能来自不可信的客户端，头部损坏、截断或声明超大数据块时返回错误而不是崩溃
+func decodeWAVAudio(r io.ReadSeeker, options DecodeOptions) (result *AudioData, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, fmt.Errorf("%w: %v", ErrInvalidAudioFile, p)
+		}
+	}()
+
+	decoder := wav.NewDecoder(r)
 	if !decoder.IsValidFile() {
-		return nil, fmt.Errorf("invalid WAV file")
+		return nil, fmt.Errorf("%w: invalid WAV file", ErrInvalidAudioFile)
 	}
 
+	switch decoder.BitDepth {
+	case 8, 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported bit depth %d", ErrInvalidAudioFile, decoder.BitDepth)
+	}
+	if decoder.NumChans == 0 || decoder.SampleRate == 0 {
+		return nil, fmt.Errorf("%w: missing format chunk", ErrInvalidAudioFile)
+	}
+
+	// 按位深归一化，8位PCM为无符号数
+	scale := math.Pow(2, float64(decoder.BitDepth-1))
+	offset := 0.0
+	if decoder.BitDepth == 8 {
+		offset = 128
+	}
+	channels := int(decoder.NumChans)
+	weights := options.channelWeights(channels)
+
 	audioData := make([]float64, 0)
-	buf := &audio.IntBuffer{Data: make([]int, 1024), Format: &audio.Format{}}
+	buf := &audio.IntBuffer{Data: make([]int, 1024*channels), Format: &audio.Format{}}
+	var pending []int // 上一批末尾不足一帧的样本
 
 	for {
 		n, err := decoder.PCMBuffer(buf)
-		if err != nil {
+		if err != nil || n == 0 {
 			break
 		}
-		if n == 0 {
-			break
+		if len(audioData)+n/channels > MaxWAVSamples {
+			return nil, ErrAudioTooLong
 		}
 
-		// 转换为float64
-		for _, sample := range buf.Data[:n] {
-			audioData = append(audioData, float64(sample)/32768.0) // 16-bit归一化
+		// 转换为float64，多声道交织样本按权重合并为单声道
+		frame := append(pending, buf.Data[:n]...)
+		usable := len(frame) - len(frame)%channels
+		for i := 0; i < usable; i += channels {
+			sum := 0.0
+			for c, sample := range frame[i : i+channels] {
+				sum += weights[c] * (float64(sample) - offset) / scale
+			}
+			audioData = append(audioData, sum)
 		}
+		pending = append([]int(nil), frame[usable:]...)
 	}
 
-	return audioData, nil
+	return &AudioData{Samples: audioData, SampleRate: int(decoder.SampleRate)}, nil
 }
 
 // 预处理音频数据
@@ -85,10 +165,7 @@ func preprocess(audioData []float64) []float64 {
 	}
 
 	// 2. 应用汉明窗
-	for i := range processed {
-		multiplier := 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(len(processed)-1))
-		processed[i] *= multiplier
-	}
+	multiplyWindow(processed, processed, hammingWindow(len(processed)))
 
 	return processed
 }
@@ -147,26 +224,51 @@ func extractFeatures(processedAudio []float64) AudioFeature {
 
 // ProcessAudioFile处理单个音频文件
 func (p *SampleProcessor) ProcessAudioFile(filePath string, emotion string) error {
-	// 1. 加载音频文件
-	audioData, err := loadAudioFile(filePath)
+	return p.processAudioFile(filePath, SampleLabel{Emotion: emotion})
+}
+
+// processAudioFile 按标签处理单个音频文件
+func (p *SampleProcessor) processAudioFile(filePath string, label SampleLabel) error {
+	emotion := label.Emotion
+
+	// 1. 加载音频文件，统一重采样到处理器采样率
+	audio, metadata, err := loadAudioWithMetadata(filePath)
 	if err != nil {
 		return fmt.Errorf("加载音频失败: %v", err)
 	}
+	recording := dsp.Resample(audio.Samples, audio.SampleRate, p.SampleRate)
 
-	// 2. 预处理
+	// 2. 去掉叫声前后的静音，特征只反映叫声本身
+	start, end := trimSilence(recording, p.SampleRate)
+	audioData := recording[start:end]
+
+	// 3. 在原始录音上评估信号质量（削波、信噪比需要归一化前的幅度，底噪取自整段录音）
+	quality := measureSampleQuality(audioData, recording, p.SampleRate)
+
+	// 4. 响度归一化，消除不同录音设备的增益差异
+	if p.NormalizeLoudness {
+		var gain float64
+		audioData, gain = NormalizeLoudness(audioData, p.SampleRate, p.TargetLoudness)
+		fmt.Printf("响度归一化: %s, 增益 %.2f dB\n", filePath, gain)
+	}
+
+	// 5. 预处理
 	processedAudio := preprocess(audioData)
 
-	// 3. 提取特征
+	// 6. 提取特征
 	features := extractFeatures(processedAudio)
 
-	// 4. 创建样本
+	// 7. 创建样本
 	sample := AudioSample{
 		FilePath: filePath,
 		Emotion:  emotion,
+		CatID:    label.CatID,
 		Features: features,
+		Quality:  quality,
+		Metadata: metadata,
 	}
 
-	// 5. 添加到样本库
+	// 8. 添加到样本库
 	p.Library.Samples[emotion] = append(p.Library.Samples[emotion], sample)
 
 	return nil
@@ -275,48 +377,61 @@ func (p *SampleProcessor) calculateStatistics() {
 }
 
 // ProcessDirectory 处理指定目录下的所有音频文件
+// 支持两种布局：dirPath/emotion/xxx.wav 按子目录归类，dirPath/emotion_1.mp3 按文件名前缀归类。
+// 单个文件加载失败不影响其余文件，失败的文件汇总为 LoadErrors 返回
 func (p *SampleProcessor) ProcessDirectory(dirPath string) error {
 	// 确保目录存在
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		return fmt.Errorf("目录不存在: %s", dirPath)
 	}
 
-	// 遍历目录结构：dirPath/emotion/audio_files
-	emotions, err := os.ReadDir(dirPath)
+	entries, err := os.ReadDir(dirPath)
 	if err != nil {
 		return fmt.Errorf("读取目录失败: %v", err)
 	}
 
-	for _, emotion := range emotions {
-		if !emotion.IsDir() {
+	var failures LoadErrors
+	processFile := func(dirName, filePath string) {
+		ext := strings.ToLower(filepath.Ext(filePath))
+		if unsupportedAudioExtensions[ext] {
+			failures = append(failures, FileLoadError{Path: filePath, Err: fmt.Errorf("unsupported format %s (want WAV or MP3)", ext)})
+			return
+		}
+		if !sampleFileExtensions[ext] {
+			return
+		}
+
+		// 标签不在音频文件中时（CSV）也要参与比较，标签改变的文件重新提取
+		label := p.Labeler.label(dirPath, dirName, filePath)
+		hash, reused := p.reuseSamples(filePath, label.Emotion+"\x00"+label.CatID)
+		if reused {
+			fmt.Printf("文件未变化，复用样本: %s\n", filePath)
+			return
+		}
+		fmt.Printf("处理文件: %s\n", filePath)
+		if err := p.processAudioFile(filePath, label); err != nil {
+			fmt.Printf("警告: 处理文件失败 %s: %v\n", filePath, err)
+			failures = append(failures, FileLoadError{Path: filePath, Err: err})
+			return
+		}
+		p.recordSource(filePath, hash, 1)
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(dirPath, entry.Name())
+		if !entry.IsDir() {
+			processFile("", entryPath)
 			continue
 		}
 
-		emotionPath := filepath.Join(dirPath, emotion.Name())
-		audioFiles, err := os.ReadDir(emotionPath)
+		audioFiles, err := os.ReadDir(entryPath)
 		if err != nil {
-			fmt.Printf("警告: 无法读取情感目录 %s: %v\n", emotionPath, err)
+			failures = append(failures, FileLoadError{Path: entryPath, Err: err})
 			continue
 		}
-
-		// 处理每个音频文件
 		for _, audioFile := range audioFiles {
-			if audioFile.IsDir() {
-				continue
-			}
-
-			// 只处理.wav文件
-			if filepath.Ext(audioFile.Name()) != ".WAV" {
-				continue
-			}
-
-			filePath := filepath.Join(emotionPath, audioFile.Name())
-			fmt.Printf("处理文件: %s\n", filePath)
-
-			err = p.ProcessAudioFile(filePath, emotion.Name())
-			if err != nil {
-				fmt.Printf("警告: 处理文件失败 %s: %v\n", filePath, err)
-				continue
+			if !audioFile.IsDir() {
+				processFile(entry.Name(), filepath.Join(entryPath, audioFile.Name()))
 			}
 		}
 	}
@@ -324,10 +439,27 @@ func (p *SampleProcessor) ProcessDirectory(dirPath string) error {
 	// 处理完所有文件后计算统计特征
 	fmt.Println("计算统计特征...")
 	p.calculateStatistics()
+	p.Library.scoreQuality()
 
+	if len(failures) > 0 {
+		return failures
+	}
 	return nil
 }
 
+// BuildLibraryFromDirectory 处理样本目录，返回新建的样本库与加载失败的文件，目录中没有可用样本时返回错误
+func BuildLibraryFromDirectory(dirPath string) (*SampleLibrary, LoadErrors, error) {
+	processor := NewSampleProcessor(AudioStreamConfig{})
+	var failures LoadErrors
+	if err := processor.ProcessDirectory(dirPath); err != nil && !errors.As(err, &failures) {
+		return nil, nil, err
+	}
+	if len(processor.Library.Samples) == 0 {
+		return nil, failures, fmt.Errorf("no samples found in %s", dirPath)
+	}
+	return processor.Library, failures, nil
+}
+
 // ExportLibrary 将样本库导出到JSON文件
 func (p *SampleProcessor) ExportLibrary(outputPath string) error {
 	// 检查是否有样本数据
@@ -343,15 +475,18 @@ func (p *SampleProcessor) ExportLibrary(outputPath string) error {
 
 	// 准备导出数据
 	type ExportData struct {
-		TotalSamples int                          `json:"totalSamples"`
-		Emotions     []string                     `json:"emotions"`
-		Samples      map[string][]AudioSample     `json:"samples"`
-		Statistics   map[string]EmotionStatistics `json:"statistics"`
+		TotalSamples     int                          `json:"totalSamples"`
+		Emotions         []string                     `json:"emotions"`
+		Samples          map[string][]AudioSample     `json:"samples"`
+		Statistics       map[string]EmotionStatistics `json:"statistics"`
+		ExtractorVersion string                       `json:"extractorVersion"`
 	}
 
+	// 样本路径写为相对样本库所在目录的形式，样本库与音频一起拷贝到其他平台后仍可用
 	exportData := ExportData{
-		Samples:    p.Library.Samples,
-		Statistics: p.Library.Statistics,
+		Samples:          withRelativePaths(p.Library.Samples, outputDir),
+		Statistics:       p.Library.Statistics,
+		ExtractorVersion: p.Library.ExtractorVersion,
 	}
 
 	// 计算总样本数和情感列表
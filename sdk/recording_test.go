@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProcessDirectoryLayouts 测试样本目录加载
+// 测试内容：
+// 1. 扩展名不区分大小写，情感取自子目录名或顶层文件名前缀
+// 2. 解码失败与不支持的格式汇总为 LoadErrors，其余样本照常加入样本库
+// 3. 非音频文件静默跳过，采样率不同的文件重采样后时长不变
+func TestProcessDirectoryLayouts(t *testing.T) {
+	dir := t.TempDir()
+	tone := func(rate int) []byte {
+		samples := make([]int16, rate/2)
+		for i := range samples {
+			samples[i] = int16(8000 * math.Sin(2*math.Pi*500*float64(i)/float64(rate)))
+		}
+		return buildWAV(samples, rate)
+	}
+	files := map[string][]byte{
+		"purr/a.wav":          tone(44100),
+		"purr/b.WAV":          tone(22050),
+		"purr/readme.txt":     []byte("not audio"),
+		"hungry_1.Wav":        tone(44100),
+		"hungry_2.wav":        tone(16000),
+		"alert.wav":           tone(44100),
+		"broken_1.mp3":        []byte("ID3 definitely not an mp3"),
+		"hiss/recording.flac": []byte("fLaC"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	processor := NewSampleProcessor(AudioStreamConfig{})
+	err := processor.ProcessDirectory(dir)
+	var failures LoadErrors
+	if !errors.As(err, &failures) || len(failures) != 2 {
+		t.Fatalf("ProcessDirectory() error = %v, want 2 load errors", err)
+	}
+	failed := map[string]bool{}
+	for _, failure := range failures {
+		failed[filepath.Base(failure.Path)] = true
+	}
+	if !failed["broken_1.mp3"] || !failed["recording.flac"] {
+		t.Errorf("failures = %v, want broken_1.mp3 and recording.flac", failures)
+	}
+
+	want := map[string]int{"purr": 2, "hungry": 2, "alert": 1}
+	if len(processor.Library.Samples) != len(want) {
+		t.Errorf("emotions = %v, want %v", processor.Library.Samples, want)
+	}
+	for emotion, n := range want {
+		if got := len(processor.Library.Samples[emotion]); got != n {
+			t.Errorf("samples[%s] = %d, want %d", emotion, got, n)
+		}
+	}
+	for _, sample := range processor.Library.Samples["hungry"] {
+		if d := sample.Quality.Duration; math.Abs(d-0.5) > 0.01 {
+			t.Errorf("%s duration = %.3f, want 0.5 after resampling", sample.FilePath, d)
+		}
+	}
+
+	library, loadErrors, err := BuildLibraryFromDirectory(dir)
+	if err != nil || len(loadErrors) != 2 || len(library.Samples) != 3 {
+		t.Errorf("BuildLibraryFromDirectory() = %d emotions, %v, %v", len(library.Samples), loadErrors, err)
+	}
+}
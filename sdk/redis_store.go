@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redis 会话存储
+//
+// 多副本共享的会话存储，只用到 GET/SET/DEL/EXPIRE/PEXPIRE 与 WATCH/MULTI/EXEC 几条简单命令，直接实现 RESP 协议，不引入客户端依赖。
+// 每个流三个键：<prefix><streamId>:session（配置，JSON）、:result（最新结果）、:buffer（缓冲区，二进制），
+// 每次写入刷新过期时间，流停止发送数据 TTL 后自动清理。
+// 流锁为 :lock 键，SET NX PX 获取，值为随机令牌；持有期间每 LockTTL/3 在 WATCH 下比对令牌并续期，
+// 处理较慢的音频块不会让锁中途过期；释放时同样比对令牌再删除。持有者崩溃时锁在 LockTTL 后自动过期，
+// 不会误删过期后被其他副本重新获取的锁。
+
+// DefaultRedisKeyPrefix Redis 键前缀
+const DefaultRedisKeyPrefix = "meowtalk:stream:"
+
+// DefaultStreamLockTTL 流锁的过期时间，也是获取流锁的最长等待时间
+const DefaultStreamLockTTL = 10 * time.Second
+
+// streamLockRetry 流锁被占用时的重试间隔
+const streamLockRetry = 5 * time.Millisecond
+
+// streamLockRenewals 持有流锁期间每个 LockTTL 内的续期次数
+const streamLockRenewals = 3
+
+// RedisSessionStore 保存在 Redis 中的会话存储
+type RedisSessionStore struct {
+	Prefix  string        // 键前缀
+	TTL     time.Duration // 键的过期时间
+	LockTTL time.Duration // 流锁的过期时间
+
+	client *redisClient
+}
+
+// NewRedisSessionStore 按 redis://[:password@]host:port[/db] 连接 Redis
+func NewRedisSessionStore(rawURL string, ttl time.Duration) (*RedisSessionStore, error) {
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.do("PING"); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &RedisSessionStore{Prefix: DefaultRedisKeyPrefix, TTL: ttl, LockTTL: DefaultStreamLockTTL, client: client}, nil
+}
+
+// Close 关闭连接
+func (r *RedisSessionStore) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisSessionStore) key(streamID, kind string) string {
+	return r.Prefix + streamID + ":" + kind
+}
+
+func (r *RedisSessionStore) ttlSeconds() string {
+	return strconv.Itoa(int(math.Ceil(r.TTL.Seconds())))
+}
+
+// CreateSession 保存会话配置，清空结果与缓冲区
+func (r *RedisSessionStore) CreateSession(streamID string, session StoredSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	if _, err := r.client.do("DEL", r.key(streamID, "buffer")); err != nil {
+		return err
+	}
+	if _, err := r.client.do("SET", r.key(streamID, "result"), "", "EX", r.ttlSeconds()); err != nil {
+		return err
+	}
+	_, err = r.client.do("SET", r.key(streamID, "session"), string(data), "EX", r.ttlSeconds())
+	return err
+}
+
+// Session 返回会话配置
+func (r *RedisSessionStore) Session(streamID string) (StoredSession, bool, error) {
+	var session StoredSession
+	data, ok, err := r.get(r.key(streamID, "session"))
+	if err != nil || !ok {
+		return session, false, err
+	}
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return session, false, fmt.Errorf("redis: decode session: %v", err)
+	}
+	return session, true, nil
+}
+
+// SaveResult 保存最新结果，会话不存在（未 /start 或已过期）时不保存
+func (r *RedisSessionStore) SaveResult(streamID string, result []byte) error {
+	if _, err := r.client.do("SET", r.key(streamID, "result"), string(result), "EX", r.ttlSeconds(), "XX"); err != nil {
+		return err
+	}
+	_, err := r.client.do("EXPIRE", r.key(streamID, "session"), r.ttlSeconds())
+	return err
+}
+
+// Result 返回最新结果
+func (r *RedisSessionStore) Result(streamID string) ([]byte, bool, error) {
+	data, ok, err := r.get(r.key(streamID, "result"))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if data == "" {
+		return nil, true, nil
+	}
+	return []byte(data), true, nil
+}
+
+// SaveBuffer 保存缓冲区：令牌、换行、已接收样本数（8字节）与小端 float64 样本
+func (r *RedisSessionStore) SaveBuffer(streamID string, buffer StoredBuffer) error {
+	data := make([]byte, 0, len(buffer.Token)+1+8+8*len(buffer.Samples))
+	data = append(data, buffer.Token...)
+	data = append(data, '\n')
+	data = binary.LittleEndian.AppendUint64(data, uint64(buffer.Received))
+	for _, sample := range buffer.Samples {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(sample))
+	}
+	_, err := r.client.do("SET", r.key(streamID, "buffer"), string(data), "EX", r.ttlSeconds())
+	return err
+}
+
+// Buffer 返回缓冲区
+func (r *RedisSessionStore) Buffer(streamID string) (StoredBuffer, bool, error) {
+	var buffer StoredBuffer
+	data, ok, err := r.get(r.key(streamID, "buffer"))
+	if err != nil || !ok {
+		return buffer, false, err
+	}
+	newline := strings.IndexByte(data, '\n')
+	if newline < 0 || (len(data)-newline-1) < 8 || (len(data)-newline-1)%8 != 0 {
+		return buffer, false, fmt.Errorf("redis: malformed buffer for stream %s", streamID)
+	}
+	buffer.Token = data[:newline]
+	raw := []byte(data[newline+1:])
+	buffer.Received = int64(binary.LittleEndian.Uint64(raw))
+	buffer.Samples = make([]float64, 0, len(raw)/8-1)
+	for i := 8; i < len(raw); i += 8 {
+		buffer.Samples = append(buffer.Samples, math.Float64frombits(binary.LittleEndian.Uint64(raw[i:])))
+	}
+	return buffer, true, nil
+}
+
+// DeleteSession 删除会话的全部键
+func (r *RedisSessionStore) DeleteSession(streamID string) error {
+	_, err := r.client.do("DEL", r.key(streamID, "session"), r.key(streamID, "result"), r.key(streamID, "buffer"))
+	return err
+}
+
+// LockStream 获取流锁，锁被其他副本持有时等待，超过 LockTTL 仍未获取时返回错误
+func (r *RedisSessionStore) LockStream(streamID string) (func(), error) {
+	key := r.key(streamID, "lock")
+	token := newRequestID()
+	ttl := strconv.FormatInt(r.LockTTL.Milliseconds(), 10)
+	deadline := time.Now().Add(r.LockTTL)
+	for {
+		reply, err := r.client.do("SET", key, token, "NX", "PX", ttl)
+		if err != nil {
+			return nil, err
+		}
+		if reply != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("redis: stream %s is locked by another replica", streamID)
+		}
+		time.Sleep(streamLockRetry)
+	}
+
+	// 持有期间定期续期，释放时先停止续期
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	interval := r.LockTTL / streamLockRenewals
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				held, err := r.renew(key, token, ttl)
+				if err != nil {
+					log.Printf("流锁续期失败 stream=%s: %v", streamID, err)
+					continue
+				}
+				if !held {
+					log.Printf("流锁已过期并被其他副本获取 stream=%s", streamID)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+		if err := r.unlock(key, token); err != nil {
+			log.Printf("释放流锁失败 stream=%s: %v", streamID, err)
+		}
+	}, nil
+}
+
+// renew 锁仍由令牌持有时将过期时间重置为 ttl 毫秒，返回锁是否仍由令牌持有
+func (r *RedisSessionStore) renew(key, token, ttl string) (bool, error) {
+	held := false
+	err := r.client.session(func(do func(args ...string) (interface{}, error)) error {
+		if _, err := do("WATCH", key); err != nil {
+			return err
+		}
+		holder, err := do("GET", key)
+		if err != nil {
+			return err
+		}
+		if holder != token {
+			_, err := do("UNWATCH")
+			return err
+		}
+		if _, err := do("MULTI"); err != nil {
+			return err
+		}
+		if _, err := do("PEXPIRE", key, ttl); err != nil {
+			return err
+		}
+		reply, err := do("EXEC")
+		held = reply != nil
+		return err
+	})
+	return held, err
+}
+
+// unlock 锁仍由令牌持有时删除锁键；WATCH 之后锁键被改写时 EXEC 返回空数组，删除不生效
+func (r *RedisSessionStore) unlock(key, token string) error {
+	return r.client.session(func(do func(args ...string) (interface{}, error)) error {
+		if _, err := do("WATCH", key); err != nil {
+			return err
+		}
+		holder, err := do("GET", key)
+		if err != nil {
+			return err
+		}
+		if holder != token {
+			_, err := do("UNWATCH")
+			return err
+		}
+		if _, err := do("MULTI"); err != nil {
+			return err
+		}
+		if _, err := do("DEL", key); err != nil {
+			return err
+		}
+		_, err = do("EXEC")
+		return err
+	})
+}
+
+// get 读取字符串键，键不存在时 ok 为 false
+func (r *RedisSessionStore) get(key string) (string, bool, error) {
+	reply, err := r.client.do("GET", key)
+	if err != nil || reply == nil {
+		return "", false, err
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return "", false, fmt.Errorf("redis: unexpected reply %v", reply)
+	}
+	return data, true, nil
+}
+
+// redisClient 单连接的 RESP 客户端，命令串行执行，连接出错后下一条命令重新连接
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// newRedisClient 解析 redis:// 地址
+func newRedisClient(rawURL string) (*redisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("redis: invalid url %q, want redis://[:password@]host:port[/db]", rawURL)
+	}
+	client := &redisClient{addr: u.Host, timeout: 5 * time.Second}
+	if u.Port() == "" {
+		client.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		client.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if client.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("redis: invalid db %q", db)
+		}
+	}
+	return client, nil
+}
+
+// do 执行一条命令，返回 string、int64、[]interface{} 或 nil（空回复）
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	var reply interface{}
+	err := c.session(func(do func(args ...string) (interface{}, error)) error {
+		var err error
+		reply, err = do(args...)
+		return err
+	})
+	return reply, err
+}
+
+// session 在同一连接上连续执行多条命令，期间其他命令等待；WATCH/MULTI/EXEC 不能与其他命令交错
+func (c *redisClient) session(fn func(do func(args ...string) (interface{}, error)) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return err
+		}
+	}
+	err := fn(func(args ...string) (interface{}, error) {
+		return c.roundTrip(args)
+	})
+	if _, isReplyErr := err.(redisError); err != nil && !isReplyErr && c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return err
+}
+
+// connect 建立连接并按地址完成认证与选库（调用方需持有c.mu）
+func (c *redisClient) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err := c.roundTrip([]string{"AUTH", c.password}); err != nil {
+			conn.Close()
+			c.conn = nil
+			return err
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTrip([]string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			conn.Close()
+			c.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// roundTrip 发送命令并读取回复（调用方需持有c.mu）
+func (c *redisClient) roundTrip(args []string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, cmd.String()); err != nil {
+		return nil, fmt.Errorf("redis: %v", err)
+	}
+	return readRedisReply(c.reader)
+}
+
+// Close 关闭连接
+func (c *redisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// redisError 服务端返回的错误回复，连接仍然可用
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readRedisReply 读取一条 RESP 回复，数组回复（EXEC）返回 []interface{}，空数组回复返回 nil
+func readRedisReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, fmt.Errorf("redis: %v", err)
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array length %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			// 元素中的错误回复（如 EXEC 中失败的命令）保留为 redisError，不中断读取
+			item, err := readRedisReply(reader)
+			if replyErr, ok := err.(redisError); ok {
+				item, err = replyErr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
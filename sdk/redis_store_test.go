@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redisExchange 对话记录中的一次往返：客户端应发送的命令与服务端的原始回复
+type redisExchange struct {
+	request string
+	reply   string
+}
+
+// replayRedis 按对话记录逐条比对客户端发送的命令并回放回复，与记录不一致时报告错误并断开
+func replayRedis(t *testing.T, transcript []redisExchange) *redisClient {
+	t.Helper()
+	server, conn := net.Pipe()
+	t.Cleanup(func() { server.Close() })
+	go func() {
+		defer server.Close()
+		reader := bufio.NewReader(server)
+		for i, exchange := range transcript {
+			got := make([]byte, len(exchange.request))
+			if _, err := io.ReadFull(reader, got); err != nil || string(got) != exchange.request {
+				t.Errorf("exchange #%d: request = %q, want %q", i, got, exchange.request)
+				return
+			}
+			io.WriteString(server, exchange.reply)
+		}
+	}()
+	return &redisClient{timeout: time.Second, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// TestRedisClientTranscript 测试 RESP 客户端的命令编码与回复解析
+// 测试内容：
+// 1. 命令编码为批量字符串数组，参数中的空字符串与二进制内容按长度发送
+// 2. 简单字符串、整数、批量字符串、空批量字符串与错误回复的解析，错误回复后连接保留
+// 3. 数组回复：EXEC 的结果数组（含嵌套数组与元素中的错误回复）、空数组与 WATCH 被改写时的空回复
+// 4. session 内的多条命令在同一连接上依次执行
+func TestRedisClientTranscript(t *testing.T) {
+	client := replayRedis(t, []redisExchange{
+		{"*1\r\n$4\r\nPING\r\n", "+PONG\r\n"},
+		{"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$0\r\n\r\n", "+OK\r\n"},
+		{"*3\r\n$3\r\nSET\r\n$1\r\nb\r\n$4\r\na\r\nb\r\n", "+OK\r\n"},
+		{"*2\r\n$3\r\nGET\r\n$1\r\nb\r\n", "$4\r\na\r\nb\r\n"},
+		{"*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n", "$-1\r\n"},
+		{"*2\r\n$3\r\nDEL\r\n$1\r\nk\r\n", ":1\r\n"},
+		{"*1\r\n$5\r\nBOGUS\r\n", "-ERR unknown command 'BOGUS'\r\n"},
+		{"*2\r\n$5\r\nWATCH\r\n$4\r\nlock\r\n", "+OK\r\n"},
+		{"*1\r\n$5\r\nMULTI\r\n", "+OK\r\n"},
+		{"*2\r\n$3\r\nDEL\r\n$4\r\nlock\r\n", "+QUEUED\r\n"},
+		{"*1\r\n$4\r\nEXEC\r\n", "*4\r\n:1\r\n$2\r\nok\r\n*2\r\n+a\r\n$-1\r\n-WRONGTYPE bad\r\n"},
+		{"*1\r\n$4\r\nEXEC\r\n", "*0\r\n"},
+		{"*1\r\n$4\r\nEXEC\r\n", "*-1\r\n"},
+	})
+
+	call := func(args ...string) interface{} {
+		t.Helper()
+		reply, err := client.do(args...)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return reply
+	}
+	if reply := call("PING"); reply != "PONG" {
+		t.Errorf("PING = %v", reply)
+	}
+	call("SET", "k", "")
+	call("SET", "b", "a\r\nb")
+	if reply := call("GET", "b"); reply != "a\r\nb" {
+		t.Errorf("GET binary = %q", reply)
+	}
+	if reply := call("GET", "missing"); reply != nil {
+		t.Errorf("GET missing = %v, want nil", reply)
+	}
+	if reply := call("DEL", "k"); reply != int64(1) {
+		t.Errorf("DEL = %#v, want int64 1", reply)
+	}
+	if _, err := client.do("BOGUS"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("BOGUS error = %v", err)
+	}
+	if client.conn == nil {
+		t.Fatal("error reply should keep the connection")
+	}
+
+	var exec interface{}
+	err := client.session(func(do func(args ...string) (interface{}, error)) error {
+		for _, args := range [][]string{{"WATCH", "lock"}, {"MULTI"}, {"DEL", "lock"}} {
+			if _, err := do(args...); err != nil {
+				return err
+			}
+		}
+		var err error
+		exec, err = do("EXEC")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{int64(1), "ok", []interface{}{"a", nil}, redisError("WRONGTYPE bad")}
+	if !reflect.DeepEqual(exec, want) {
+		t.Errorf("EXEC = %#v, want %#v", exec, want)
+	}
+	if reply := call("EXEC"); !reflect.DeepEqual(reply, []interface{}{}) {
+		t.Errorf("empty array = %#v", reply)
+	}
+	if reply := call("EXEC"); reply != nil {
+		t.Errorf("null array = %#v, want nil", reply)
+	}
+}
+
+// TestReadRedisReplyMalformed 测试格式错误的回复
+// 测试内容：未知类型、非法长度与截断的数组元素返回错误
+func TestReadRedisReplyMalformed(t *testing.T) {
+	for _, raw := range []string{"?x\r\n", "$abc\r\n", "*x\r\n", "*2\r\n:1\r\n", "$5\r\nab\r\n", "\r\n"} {
+		if reply, err := readRedisReply(bufio.NewReader(strings.NewReader(raw))); err == nil {
+			t.Errorf("readRedisReply(%q) = %#v, want error", raw, reply)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 远程录音下载
+//
+// 云存储中的录音可以直接把地址交给 /api/analyze-file，由服务端下载，避免数据绕经客户端。
+// 下载地址来自不可信的请求，因此限制协议、主机、重定向次数、耗时、大小与内容类型。为防止借服务端访问内网
+// （SSRF），默认拒绝连接回环、私有、链路本地与未指定地址：检查在建立连接时按解析出的IP进行，
+// 域名解析到内网地址或重定向到内网地址同样被拒绝。
+
+// 远程下载相关错误
+var (
+	ErrRemoteURL         = errors.New("invalid remote audio url")
+	ErrRemoteFetch       = errors.New("remote audio download failed")
+	ErrRemoteContentType = errors.New("unsupported remote audio content type")
+)
+
+// remoteAudioContentTypes 允许的录音内容类型，云存储常以 octet-stream 返回任意文件
+var remoteAudioContentTypes = map[string]bool{
+	"audio/wav":                true,
+	"audio/wave":               true,
+	"audio/x-wav":              true,
+	"audio/vnd.wave":           true,
+	"audio/mpeg":               true,
+	"audio/mp3":                true,
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+}
+
+// RemoteAudioFetcher 远程录音下载器
+type RemoteAudioFetcher struct {
+	Timeout      time.Duration // 整个下载（含重定向）的超时时间
+	MaxBytes     int64         // 录音文件最大字节数
+	MaxRedirects int           // 最多跟随的重定向次数
+	AllowedHosts []string      // 允许的主机（含子域名），为空时不限制
+	AllowPrivate bool          // 允许连接回环、私有、链路本地地址，用于测试或内网部署
+}
+
+// DefaultRemoteAudioFetcher 默认下载器：30秒超时、最大 MaxAnalyzeFileBytes、最多3次重定向、不限制主机、不允许内网地址
+func DefaultRemoteAudioFetcher() *RemoteAudioFetcher {
+	return &RemoteAudioFetcher{
+		Timeout:      30 * time.Second,
+		MaxBytes:     MaxAnalyzeFileBytes,
+		MaxRedirects: 3,
+	}
+}
+
+// checkURL 检查下载地址的协议与主机
+func (f *RemoteAudioFetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q not allowed", ErrRemoteURL, u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrRemoteURL)
+	}
+	if len(f.AllowedHosts) == 0 {
+		return nil
+	}
+	for _, allowed := range f.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: host %s not allowed", ErrRemoteURL, host)
+}
+
+// checkDial 建立连接前检查解析出的IP，不允许内网地址时拒绝回环、私有、链路本地与未指定地址
+func (f *RemoteAudioFetcher) checkDial(network, address string, _ syscall.RawConn) error {
+	if f.AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoteURL, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: address %s not allowed", ErrRemoteURL, host)
+	}
+	return nil
+}
+
+// Fetch 下载录音文件
+// 地址非法或连接内网地址返回 ErrRemoteURL，连接失败或状态码异常返回 ErrRemoteFetch，
+// 内容类型不是音频返回 ErrRemoteContentType，超过大小限制返回 ErrAudioTooLong
+func (f *RemoteAudioFetcher) Fetch(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRemoteURL, err)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	// 不经过代理直接连接，连接的地址即检查的地址
+	dialer := &net.Dialer{Timeout: f.Timeout, Control: f.checkDial}
+	transport := &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: f.Timeout}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Timeout:   f.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > f.MaxRedirects {
+				return fmt.Errorf("%w: too many redirects", ErrRemoteFetch)
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		// 重定向到不允许的地址时保留 ErrRemoteURL
+		if errors.Is(err, ErrRemoteURL) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrRemoteFetch, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrRemoteFetch, resp.Status)
+	}
+	if resp.ContentLength > f.MaxBytes {
+		return nil, ErrAudioTooLong
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !remoteAudioContentTypes[mediaType] {
+			return nil, fmt.Errorf("%w: %s", ErrRemoteContentType, contentType)
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRemoteFetch, err)
+	}
+	if int64(len(data)) > f.MaxBytes {
+		return nil, ErrAudioTooLong
+	}
+	return data, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestRemoteAudioFetcher 测试远程录音下载限制
+// 测试内容：
+// 1. 正常下载音频内容
+// 2. 非 http/https 协议、不在白名单的主机、重定向到不允许的主机被拒绝
+// 3. 非音频内容类型、超过大小限制、状态码异常分别返回对应错误与HTTP状态码
+// 4. 默认拒绝连接回环、私有、链路本地与未指定地址，AllowPrivate 时允许
+func TestRemoteAudioFetcher(t *testing.T) {
+	wav := buildWAV(make([]int16, 100), 16000)
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat.wav":
+			w.Header().Set("Content-Type", "audio/wav")
+			w.Write(wav)
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html></html>"))
+		case "/redirect":
+			http.Redirect(w, r, "http://elsewhere.example/cat.wav", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer files.Close()
+	host, _ := url.Parse(files.URL)
+
+	fetcher := DefaultRemoteAudioFetcher()
+	for _, address := range []string{files.URL + "/cat.wav", "http://localhost:" + host.Port() + "/cat.wav", "http://10.0.0.1/cat.wav", "http://169.254.169.254/latest/meta-data", "http://[::1]:" + host.Port() + "/cat.wav", "http://0.0.0.0:" + host.Port() + "/cat.wav"} {
+		if _, err := fetcher.Fetch(address); !errors.Is(err, ErrRemoteURL) {
+			t.Errorf("Fetch(%s) error = %v, want ErrRemoteURL for a private address", address, err)
+		}
+	}
+	// 检查在每次建立连接时进行，重定向后的连接同样检查
+	if err := fetcher.checkDial("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("public address rejected: %v", err)
+	}
+
+	fetcher.AllowPrivate = true // 测试服务器监听在回环地址
+	if data, err := fetcher.Fetch(files.URL + "/cat.wav"); err != nil || len(data) != len(wav) {
+		t.Fatalf("Fetch() = %d bytes, %v", len(data), err)
+	}
+
+	fetcher.AllowedHosts = []string{host.Hostname()}
+	tests := []struct {
+		url    string
+		want   error
+		status int
+	}{
+		{"file:///etc/passwd", ErrRemoteURL, http.StatusBadRequest},
+		{"http://storage.example/cat.wav", ErrRemoteURL, http.StatusBadRequest},
+		{files.URL + "/redirect", ErrRemoteURL, http.StatusBadRequest},
+		{files.URL + "/page.html", ErrRemoteContentType, http.StatusUnsupportedMediaType},
+		{files.URL + "/missing.wav", ErrRemoteFetch, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		_, err := fetcher.Fetch(tt.url)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Fetch(%s) error = %v, want %v", tt.url, err, tt.want)
+		}
+		if got := remoteFetchStatus(err); got != tt.status {
+			t.Errorf("Fetch(%s) status = %d, want %d", tt.url, got, tt.status)
+		}
+	}
+
+	fetcher.MaxBytes = 64
+	if _, err := fetcher.Fetch(files.URL + "/cat.wav"); !errors.Is(err, ErrAudioTooLong) {
+		t.Errorf("oversized download error = %v, want ErrAudioTooLong", err)
+	}
+
+	server := NewAudioServer(NewMockAudioProcessor())
+	server.SetRemoteFetcher(fetcher)
+	rec := httptest.NewRecorder()
+	server.handleAnalyzeFile(rec, httptest.NewRequest(http.MethodPost, "/analyze-file", strings.NewReader(`{"url":"`+files.URL+`/cat.wav"}`)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("/analyze-file status = %d, want 413", rec.Code)
+	}
+}
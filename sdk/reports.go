@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 情感报告：结果历史按天追加到 <dir>/results-<日期>.jsonl，GET /reports?period=day|week 按情感计数、叫声分钟数、
+// 最繁忙的时段与告警汇总，报告任务为已经结束的一天生成 <dir>/reports/<日期>.json。
+
+// 历史参数
+const (
+	MaxHistoryAge      = 8 * 24 * time.Hour // 内存中保留的历史时长，覆盖一周报告
+	MaxVocalizationGap = 5 * time.Second    // 结果代表自上一个结果以来的叫声，间隔超过该值时只计该值
+	ReportJobInterval  = time.Hour          // 报告任务的检查间隔
+	BusiestHours       = 3                  // 报告列出的最繁忙时段数
+	historyDateLayout  = "2006-01-02"
+)
+
+// 报告周期
+const (
+	ReportPeriodDay  = "day"
+	ReportPeriodWeek = "week"
+)
+
+// ResultRecord 历史中的一个最终结果
+type ResultRecord struct {
+	Timestamp  int64   `json:"timestamp"` // 结果产生的时间（毫秒时间戳）
+	StreamID   string  `json:"streamId"`
+	CatID      string  `json:"catId,omitempty"`
+	Emotion    string  `json:"emotion"`
+	Confidence float64 `json:"confidence"`
+	DurationMs int64   `json:"durationMs"`          // 该结果代表的叫声时长（毫秒）
+	Intensity  string  `json:"intensity,omitempty"` // 叫声强度 low/medium/high，结果不含特征时为空
+}
+
+// historyEntry 历史文件中的一行
+type historyEntry struct {
+	Result *ResultRecord  `json:"result,omitempty"`
+	Alert  *DistressAlert `json:"alert,omitempty"`
+	CatID  string         `json:"catId,omitempty"` // 告警所属流关联的猫咪
+}
+
+// EmotionCount 一种情感的结果数
+type EmotionCount struct {
+	Emotion string `json:"emotion"`
+	Label   string `json:"label,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// HourCount 一个小时（本地时间 0~23）内的结果数
+type HourCount struct {
+	Hour  int `json:"hour"`
+	Count int `json:"count"`
+}
+
+// EmotionReport /reports 的响应
+type EmotionReport struct {
+	Period              string          `json:"period"`             // day / week
+	CatID               string          `json:"catId,omitempty"`    // 只统计该猫咪时的猫咪ID
+	StreamID            string          `json:"streamId,omitempty"` // 只统计该流时的流ID
+	Start               int64           `json:"start"`              // 统计区间开始（毫秒时间戳，含）
+	End                 int64           `json:"end"`                // 统计区间结束（毫秒时间戳，不含）
+	Results             int             `json:"results"`            // 最终结果数
+	Emotions            []EmotionCount  `json:"emotions"`           // 按结果数从多到少排列，不含 unknown
+	VocalizationMinutes float64         `json:"vocalizationMinutes"`
+	Hourly              [24]int         `json:"hourly"`       // 每个小时的结果数
+	BusiestHours        []HourCount     `json:"busiestHours"` // 结果最多的几个小时
+	Alerts              []DistressAlert `json:"alerts"`       // 区间内的持续不适告警
+}
+
+// ResultHistory 最终结果与告警的历史
+type ResultHistory struct {
+	dir string // 历史目录，为空时只保存在内存
+
+	mu      sync.Mutex
+	results []ResultRecord
+	alerts  []historyEntry
+	last    map[string]time.Time // 每个流上一个结果的时间
+}
+
+// NewResultHistory 创建结果历史，dir 不为空时加载其中最近的历史并将新记录追加到该目录
+func NewResultHistory(dir string) (*ResultHistory, error) {
+	h := &ResultHistory{dir: dir, last: make(map[string]time.Time)}
+	if dir == "" {
+		return h, nil
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "reports"), 0755); err != nil {
+		return nil, fmt.Errorf("create history dir: %v", err)
+	}
+
+	cutoff := time.Now().Add(-MaxHistoryAge)
+	for day := cutoff; !day.After(time.Now()); day = day.AddDate(0, 0, 1) {
+		if err := h.load(h.path(day)); err != nil {
+			return nil, err
+		}
+	}
+	h.prune(cutoff)
+	return h, nil
+}
+
+// path 某一天的历史文件
+func (h *ResultHistory) path(day time.Time) string {
+	return filepath.Join(h.dir, "results-"+day.Format(historyDateLayout)+".jsonl")
+}
+
+// load 加载一个历史文件，文件不存在时忽略
+func (h *ResultHistory) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open history: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// 进程退出时可能留下写了一半的最后一行
+			log.Printf("跳过无法解析的历史记录: %s: %v", path, err)
+			continue
+		}
+		switch {
+		case entry.Result != nil:
+			h.results = append(h.results, *entry.Result)
+		case entry.Alert != nil:
+			h.alerts = append(h.alerts, entry)
+		}
+	}
+	return scanner.Err()
+}
+
+// append 将一条记录追加到当天的历史文件（调用方需持有h.mu）
+func (h *ResultHistory) append(entry historyEntry, now time.Time) {
+	if h.dir == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(h.path(now), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("写入结果历史失败: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("写入结果历史失败: %v", err)
+	}
+}
+
+// AddResult 记录一个最终结果，叫声时长为距该流上一个结果的时间（不超过 MaxVocalizationGap）
+// intensity 为叫声强度，未知时为空
+func (h *ResultHistory) AddResult(streamID, catID, emotion string, confidence float64, intensity string, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	duration := MaxVocalizationGap
+	if last, ok := h.last[streamID]; ok && now.Sub(last) < duration {
+		duration = now.Sub(last)
+	}
+	h.last[streamID] = now
+
+	record := ResultRecord{
+		Timestamp:  now.UnixMilli(),
+		StreamID:   streamID,
+		CatID:      catID,
+		Emotion:    emotion,
+		Confidence: confidence,
+		DurationMs: duration.Milliseconds(),
+		Intensity:  intensity,
+	}
+	h.results = append(h.results, record)
+	h.append(historyEntry{Result: &record}, now)
+}
+
+// AddAlert 记录一条持续不适告警
+func (h *ResultHistory) AddAlert(alert DistressAlert, catID string, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry := historyEntry{Alert: &alert, CatID: catID}
+	h.alerts = append(h.alerts, entry)
+	h.append(entry, now)
+}
+
+// Forget 流结束后不再需要上一个结果的时间
+func (h *ResultHistory) Forget(streamID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.last, streamID)
+}
+
+// prune 删除早于 cutoff 的内存记录（调用方需持有h.mu或在创建期间调用）
+func (h *ResultHistory) prune(cutoff time.Time) {
+	limit := cutoff.UnixMilli()
+	results := h.results[:0]
+	for _, record := range h.results {
+		if record.Timestamp >= limit {
+			results = append(results, record)
+		}
+	}
+	h.results = results
+	alerts := h.alerts[:0]
+	for _, entry := range h.alerts {
+		if entry.Alert.Timestamp >= limit {
+			alerts = append(alerts, entry)
+		}
+	}
+	h.alerts = alerts
+}
+
+// reportRange 报告周期对应的区间：day 为 date 当天，week 为截至 date 当天的7天
+func reportRange(period string, date time.Time) (time.Time, time.Time, error) {
+	end := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()).AddDate(0, 0, 1)
+	switch period {
+	case "", ReportPeriodDay:
+		return end.AddDate(0, 0, -1), end, nil
+	case ReportPeriodWeek:
+		return end.AddDate(0, 0, -7), end, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown period %q (available: %s, %s)", period, ReportPeriodDay, ReportPeriodWeek)
+	}
+}
+
+// Report 汇总区间内的结果，catID/streamID 不为空时只统计对应的猫咪或流，情感名称使用 lang 语言
+func (h *ResultHistory) Report(period string, date time.Time, catID, streamID, lang string) (EmotionReport, error) {
+	start, end, err := reportRange(period, date)
+	if err != nil {
+		return EmotionReport{}, err
+	}
+	if period == "" {
+		period = ReportPeriodDay
+	}
+
+	report := EmotionReport{
+		Period:       period,
+		CatID:        catID,
+		StreamID:     streamID,
+		Start:        start.UnixMilli(),
+		End:          end.UnixMilli(),
+		Emotions:     []EmotionCount{},
+		BusiestHours: []HourCount{},
+		Alerts:       []DistressAlert{},
+	}
+	matches := func(stream, cat string) bool {
+		return (catID == "" || cat == catID) && (streamID == "" || stream == streamID)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := make(map[string]int)
+	var vocalization time.Duration
+	for _, record := range h.results {
+		if record.Timestamp < report.Start || record.Timestamp >= report.End || !matches(record.StreamID, record.CatID) {
+			continue
+		}
+		report.Results++
+		report.Hourly[time.UnixMilli(record.Timestamp).In(date.Location()).Hour()]++
+		if record.Emotion == "unknown" {
+			continue
+		}
+		counts[record.Emotion]++
+		vocalization += time.Duration(record.DurationMs) * time.Millisecond
+	}
+	for _, entry := range h.alerts {
+		if entry.Alert.Timestamp >= report.Start && entry.Alert.Timestamp < report.End && matches(entry.Alert.StreamID, entry.CatID) {
+			report.Alerts = append(report.Alerts, *entry.Alert)
+		}
+	}
+
+	for emotion, count := range counts {
+		report.Emotions = append(report.Emotions, EmotionCount{Emotion: emotion, Label: EmotionLabel(lang, emotion), Count: count})
+	}
+	sort.Slice(report.Emotions, func(i, j int) bool {
+		a, b := report.Emotions[i], report.Emotions[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Emotion < b.Emotion)
+	})
+	report.VocalizationMinutes = vocalization.Minutes()
+
+	for hour, count := range report.Hourly {
+		if count > 0 {
+			report.BusiestHours = append(report.BusiestHours, HourCount{Hour: hour, Count: count})
+		}
+	}
+	sort.SliceStable(report.BusiestHours, func(i, j int) bool { return report.BusiestHours[i].Count > report.BusiestHours[j].Count })
+	if len(report.BusiestHours) > BusiestHours {
+		report.BusiestHours = report.BusiestHours[:BusiestHours]
+	}
+	return report, nil
+}
+
+// writeDailyReport 为已经结束的一天生成报告文件，文件已存在时跳过
+func (h *ResultHistory) writeDailyReport(day time.Time) error {
+	path := filepath.Join(h.dir, "reports", day.Format(historyDateLayout)+".json")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	report, err := h.Report(ReportPeriodDay, day, "", "", "")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// StartReportJob 启动报告任务：定期清理内存中过期的历史，设置历史目录时为前一天生成报告文件；返回停止函数
+func (h *ResultHistory) StartReportJob() func() {
+	done := make(chan struct{})
+	run := func() {
+		now := time.Now()
+		h.mu.Lock()
+		h.prune(now.Add(-MaxHistoryAge))
+		h.mu.Unlock()
+		if h.dir == "" {
+			return
+		}
+		if err := h.writeDailyReport(now.AddDate(0, 0, -1)); err != nil {
+			log.Printf("生成每日报告失败: %v", err)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(ReportJobInterval)
+		defer ticker.Stop()
+		for run(); ; {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// SetResultHistory 设置结果历史，为nil时不记录历史且不提供 /reports 接口
+func (s *AudioServer) SetResultHistory(history *ResultHistory) {
+	s.history = history
+}
+
+// recordHistory 将流的最终结果加入历史
+func (s *AudioServer) recordHistory(streamID, catID string, result []byte, now time.Time) {
+	if s.history == nil {
+		return
+	}
+	var parsed struct {
+		Status     string  `json:"status"`
+		Emotion    string  `json:"emotion"`
+		Confidence float64 `json:"confidence"`
+		Partial    bool    `json:"partial"`
+		Metadata   struct {
+			Features map[string]float64 `json:"features"`
+		} `json:"metadata"`
+	}
+	if len(result) == 0 || json.Unmarshal(result, &parsed) != nil {
+		return
+	}
+	if parsed.Emotion == "" || parsed.Partial || parsed.Status == "waiting" {
+		return
+	}
+	intensity := ""
+	if energy, ok := parsed.Metadata.Features["Energy"]; ok {
+		intensity = IntensityFromRMS(math.Sqrt(energy))
+	}
+	s.history.AddResult(streamID, catID, parsed.Emotion, parsed.Confidence, intensity, now)
+}
+
+// forgetHistory 流结束后清除其上一个结果的时间
+func (s *AudioServer) forgetHistory(streamID string) {
+	if s.history != nil {
+		s.history.Forget(streamID)
+	}
+}
+
+// queryDate 请求中的 date 参数（本地时间），未设置时为今天
+func queryDate(query url.Values) (time.Time, error) {
+	value := query.Get("date")
+	if value == "" {
+		return time.Now(), nil
+	}
+	return time.ParseInLocation(historyDateLayout, value, time.Local)
+}
+
+// handleReports 返回情感报告：GET /reports?period=day|week&date=2006-01-02&catId=...&streamId=...&lang=zh
+func (s *AudioServer) handleReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	if s.history == nil {
+		writeError(w, r, http.StatusNotFound, ErrCodeFeatureDisabled, "未启用结果历史", "")
+		return
+	}
+
+	query := r.URL.Query()
+	date, err := queryDate(query)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "date参数无效，格式为 2006-01-02", "")
+		return
+	}
+	report, err := s.history.Report(strings.ToLower(query.Get("period")), date, query.Get("catId"), query.Get("streamId"), query.Get("lang"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "period参数无效", err.Error())
+		return
+	}
+	writeResponse(w, r, http.StatusOK, report)
+}
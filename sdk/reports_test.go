@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestEmotionReports 测试情感报告
+// 测试内容：
+// 1. 按情感计数（不含 unknown）、叫声分钟数（间隔超过 MaxVocalizationGap 时只计该值）与最繁忙的时段
+// 2. 周报告包含前几天的结果，按猫咪过滤，告警计入报告
+// 3. 历史按天保存，重新打开目录后仍可生成报告，报告任务为前一天写入报告文件
+// 4. /reports 校验 period 与 date 参数，中间结果不计入历史
+func TestEmotionReports(t *testing.T) {
+	dir := t.TempDir()
+	history, err := NewResultHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+	evening := yesterday.Add(18 * time.Hour)
+	// 18点：厨房的 mimi 每2秒一个结果，共10个
+	for i := 0; i < 10; i++ {
+		emotion := "hungry"
+		if i%5 == 4 {
+			emotion = "unknown"
+		}
+		history.AddResult("kitchen", "mimi", emotion, 0.8, "", evening.Add(time.Duration(i)*2*time.Second))
+	}
+	// 7点：卧室的 tom 两个结果，间隔1分钟
+	history.AddResult("bedroom", "tom", "hello", 0.7, "", yesterday.Add(7*time.Hour))
+	history.AddResult("bedroom", "tom", "hello", 0.7, "", yesterday.Add(7*time.Hour+time.Minute))
+	history.AddAlert(DistressAlert{Type: EventTypeAlert, StreamID: "kitchen", Emotion: "anxious", Timestamp: evening.UnixMilli()}, "mimi", evening)
+	// 三天前的结果只出现在周报告中
+	history.AddResult("kitchen", "mimi", "anxious", 0.9, "", yesterday.AddDate(0, 0, -3).Add(12*time.Hour))
+
+	report, err := history.Report(ReportPeriodDay, yesterday, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Results != 12 || len(report.Emotions) != 2 || report.Emotions[0].Emotion != "hungry" || report.Emotions[0].Count != 8 {
+		t.Errorf("day report emotions = %d results, %+v", report.Results, report.Emotions)
+	}
+	// mimi：首个结果5秒 + 7个2秒（unknown 不计）；tom：5秒 + 5秒
+	if want := (5 + 7*2 + 5 + 5) / 60.0; report.VocalizationMinutes < want-0.001 || report.VocalizationMinutes > want+0.001 {
+		t.Errorf("vocalization minutes = %.3f, want %.3f", report.VocalizationMinutes, want)
+	}
+	if report.Hourly[18] != 10 || report.Hourly[7] != 2 || len(report.BusiestHours) != 2 || report.BusiestHours[0].Hour != 18 {
+		t.Errorf("hourly = %v, busiest = %+v", report.Hourly, report.BusiestHours)
+	}
+	if len(report.Alerts) != 1 || report.Alerts[0].Emotion != "anxious" {
+		t.Errorf("alerts = %+v", report.Alerts)
+	}
+
+	week, _ := history.Report(ReportPeriodWeek, yesterday, "mimi", "", "")
+	if week.Results != 11 || week.Emotions[0].Emotion != "hungry" || len(week.Emotions) != 2 || len(week.Alerts) != 1 {
+		t.Errorf("week report for mimi = %+v", week)
+	}
+	if tom, _ := history.Report(ReportPeriodDay, yesterday, "tom", "", ""); tom.Results != 2 || len(tom.Alerts) != 0 {
+		t.Errorf("day report for tom = %+v", tom)
+	}
+
+	// 重新打开目录后历史仍在，报告任务写入前一天的报告
+	reopened, err := NewResultHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := reopened.Report(ReportPeriodDay, yesterday, "", "", ""); again.Results != 12 || len(again.Alerts) != 1 {
+		t.Errorf("reopened report = %+v", again)
+	}
+	stop := reopened.StartReportJob()
+	defer stop()
+	path := filepath.Join(dir, "reports", yesterday.Format(historyDateLayout)+".json")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if err == nil && json.Valid(data) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("daily report was not written: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server := NewAudioServer(NewMockAudioProcessor())
+	rec := httptest.NewRecorder()
+	server.handleReports(rec, httptest.NewRequest(http.MethodGet, "/reports", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("/reports without history = %d", rec.Code)
+	}
+
+	server.SetResultHistory(reopened)
+	server.recordHistory("kitchen", "mimi", []byte(`{"status":"processed","emotion":"hello","confidence":0.6,"partial":true}`), now)
+	server.recordHistory("kitchen", "mimi", []byte(`{"status":"processed","emotion":"hello","confidence":0.6}`), now)
+	rec = httptest.NewRecorder()
+	server.handleReports(rec, httptest.NewRequest(http.MethodGet, "/reports?catId=mimi", nil))
+	var today EmotionReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &today); err != nil || today.Period != ReportPeriodDay || today.Results != 1 {
+		t.Errorf("/reports = %s, want only the final result", rec.Body.String())
+	}
+	for _, query := range []string{"?period=month", "?date=yesterday"} {
+		rec = httptest.NewRecorder()
+		server.handleReports(rec, httptest.NewRequest(http.MethodGet, "/reports"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("/reports%s = %d, want 400", query, rec.Code)
+		}
+	}
+}
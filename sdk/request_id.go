@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+)
+
+// 请求关联ID
+//
+// 用户反馈“14:32 那次识别的情感不对”时，需要从日志找到当时那块音频、缓冲区与特征。
+// 每个音频块（一次 /send 请求或一条 WebSocket 音频消息）分配请求ID，HTTP 客户端也可以通过
+// X-Request-ID 请求头自带ID；每个识别结果分配结果ID。结果中携带结果ID与产生它的请求ID，
+// 日志以 key=value 形式输出两者，开启归档时归档条目也记录请求ID。
+
+// RequestIDHeader 请求ID的HTTP头，响应中回显本次使用的请求ID
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID 客户端自带的请求ID只接受文件名安全字符，避免污染日志与归档
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// newRequestID 生成随机请求ID
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("生成请求ID失败: %v", err)
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// requestIDFrom 返回客户端通过 X-Request-ID 提供的请求ID，未提供或格式无效时生成新ID
+func requestIDFrom(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); validRequestID.MatchString(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// logChunk 输出一个音频块的处理记录，result 为处理器返回的JSON
+func logChunk(requestID, streamID, transport string, samples int, result []byte) {
+	var parsed struct {
+		Status     string  `json:"status"`
+		ResultID   string  `json:"resultId"`
+		Emotion    string  `json:"emotion"`
+		Confidence float64 `json:"confidence"`
+	}
+	json.Unmarshal(result, &parsed)
+	if parsed.ResultID == "" {
+		log.Printf("音频块 request=%s stream=%s transport=%s samples=%d status=%s",
+			requestID, streamID, transport, samples, parsed.Status)
+		return
+	}
+	log.Printf("音频块 request=%s stream=%s transport=%s samples=%d result=%s emotion=%s confidence=%.2f",
+		requestID, streamID, transport, samples, parsed.ResultID, parsed.Emotion, parsed.Confidence)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRequestID 测试请求关联ID
+// 测试内容：
+// 1. /send 回显客户端的 X-Request-ID，结果中携带请求ID与结果ID
+// 2. 未提供或格式无效的请求ID由服务端生成
+// 3. 归档条目记录产生结果的请求ID
+func TestRequestID(t *testing.T) {
+	server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+	send := func(requestID string) (*httptest.ResponseRecorder, AudioStreamResult) {
+		body, _ := json.Marshal(map[string]interface{}{"streamId": "cat1", "data": generateTestAudio(440, 0.1, 44100)})
+		req := httptest.NewRequest(http.MethodPost, "/send", bytes.NewReader(body))
+		if requestID != "" {
+			req.Header.Set(RequestIDHeader, requestID)
+		}
+		rec := httptest.NewRecorder()
+		server.handleSend(rec, req)
+		var result AudioStreamResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("send = %d %s", rec.Code, rec.Body.String())
+		}
+		return rec, result
+	}
+
+	rec, result := send("client-42")
+	if got := rec.Header().Get(RequestIDHeader); got != "client-42" {
+		t.Errorf("%s = %q, want client-42", RequestIDHeader, got)
+	}
+	if result.RequestID != "client-42" || result.ResultID == "" {
+		t.Errorf("result requestId = %q, resultId = %q", result.RequestID, result.ResultID)
+	}
+
+	for _, header := range []string{"", "bad id\nwith newline"} {
+		rec, result := send(header)
+		generated := rec.Header().Get(RequestIDHeader)
+		if len(generated) != 16 || result.RequestID != generated {
+			t.Errorf("header %q: generated = %q, result requestId = %q", header, generated, result.RequestID)
+		}
+	}
+
+	archive, err := NewAudioArchive(t.TempDir(), ArchiveRetention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 8000, BufferSize: 1024})
+	engine.SetArchive(archive)
+	data, err := engine.ProcessAudioRequest("cat2", "chunk-7", generateTestAudio(440, 0.2, 8000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var analysis AudioStreamResult
+	if err := json.Unmarshal(data, &analysis); err != nil {
+		t.Fatal(err)
+	}
+	record, err := archive.Load(analysis.ResultID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.RequestID != "chunk-7" || record.RequestID != "chunk-7" {
+		t.Errorf("result requestId = %q, archived requestId = %q", analysis.RequestID, record.RequestID)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// 结果缓冲：CGO 接口的结果先放入每个流的缓冲通道，容量与溢出策略可在SDK配置或 StartStreamEx 的选项中设置，
+// 丢弃的结果数计入流的统计（GetStreamStats）。
+
+// 溢出策略
+const (
+	OverflowDropNewest = "drop-newest" // 丢弃新结果（默认）
+	OverflowDropOldest = "drop-oldest" // 丢弃最旧的结果，只关心最新状态时使用
+	OverflowBlock      = "block"       // 等待调用方取走结果，超时后丢弃新结果；等待期间该流的处理暂停
+)
+
+// DefaultResultBufferSize 结果缓冲默认可容纳的结果数
+const DefaultResultBufferSize = 10
+
+// MaxResultBufferSize 结果缓冲最多可容纳的结果数
+const MaxResultBufferSize = 1000
+
+// DefaultOverflowTimeout block 策略默认的等待时长
+const DefaultOverflowTimeout = time.Second
+
+// validateResultBuffer 校验结果缓冲容量、溢出策略与等待时长
+func validateResultBuffer(size int, policy string, timeoutMs int) error {
+	if size < 0 || size > MaxResultBufferSize {
+		return fmt.Errorf("resultBufferSize %d out of range [0, %d]", size, MaxResultBufferSize)
+	}
+	switch policy {
+	case "", OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+	default:
+		return fmt.Errorf("unknown overflowPolicy %q (available: %s, %s, %s)", policy, OverflowDropNewest, OverflowDropOldest, OverflowBlock)
+	}
+	if timeoutMs < 0 {
+		return fmt.Errorf("overflowTimeoutMs %d must not be negative", timeoutMs)
+	}
+	return nil
+}
+
+// resultBuffer 流的结果缓冲与交付计数
+type resultBuffer struct {
+	policy    string        // 溢出策略，为空时为 drop-newest
+	timeout   time.Duration // block 策略的等待时长
+	delivered atomic.Int64  // 已交付（放入缓冲或回调）的结果数
+	dropped   atomic.Int64  // 因缓冲已满丢弃的结果数
+}
+
+// setResultBuffer 按容量、溢出策略与等待时长（毫秒）设置会话的结果缓冲，为零值的项使用默认值
+func setResultBuffer(session *AudioStreamSession, size int, policy string, timeoutMs int) {
+	if size <= 0 {
+		size = DefaultResultBufferSize
+	}
+	if policy == "" {
+		policy = OverflowDropNewest
+	}
+	timeout := DefaultOverflowTimeout
+	if timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+	session.ResultChan = make(chan []byte, size)
+	session.results.policy = policy
+	session.results.timeout = timeout
+}
+
+// deliverResult 按会话的取回方式与溢出策略交付结果
+func deliverResult(session *AudioStreamSession, result []byte) {
+	if session.Callback != nil {
+		session.Callback(result)
+		session.results.delivered.Add(1)
+		return
+	}
+
+	select {
+	case session.ResultChan <- result:
+		session.results.delivered.Add(1)
+		return
+	default:
+	}
+
+	switch session.results.policy {
+	case OverflowDropOldest:
+		for {
+			// 通道已满，丢弃最旧的结果后重试
+			select {
+			case <-session.ResultChan:
+				session.results.dropped.Add(1)
+			default:
+			}
+			select {
+			case session.ResultChan <- result:
+				session.results.delivered.Add(1)
+				return
+			default:
+			}
+		}
+	case OverflowBlock:
+		timer := time.NewTimer(session.results.timeout)
+		defer timer.Stop()
+		select {
+		case session.ResultChan <- result:
+			session.results.delivered.Add(1)
+			return
+		case <-timer.C:
+		}
+	}
+
+	// 通道已满，丢弃结果
+	session.results.dropped.Add(1)
+}
+
+// StreamStats 流的统计信息
+type StreamStats struct {
+	StreamID         string `json:"streamId"`
+	SamplesReceived  int64  `json:"samplesReceived"`  // 已接收的样本数
+	SamplesBuffered  int    `json:"samplesBuffered"`  // 缓冲区中尚未分析的样本数
+	ResultsDelivered int64  `json:"resultsDelivered"` // 已交付的结果数
+	ResultsDropped   int64  `json:"resultsDropped"`   // 因结果缓冲已满丢弃的结果数
+	ResultsQueued    int    `json:"resultsQueued"`    // 结果缓冲中等待取回的结果数
+	ResultCapacity   int    `json:"resultCapacity"`   // 结果缓冲容量
+	OverflowPolicy   string `json:"overflowPolicy"`   // 溢出策略，回调模式下为空
+	WindowsSkipped   int    `json:"windowsSkipped"`   // 频带预筛选跳过的窗口数
+
+	Health *HealthSummary `json:"health,omitempty"` // 声音健康汇总，开启 HealthChecks 时提供
+}
+
+// sessionStats 返回会话的统计信息，health 为 true 时附带声音健康汇总
+func sessionStats(session *AudioStreamSession, health bool) StreamStats {
+	session.bufferMu.Lock()
+	received, buffered, skipped := session.SamplesReceived, len(session.Buffer), session.prefiltered
+	var summary *HealthSummary
+	if health {
+		s := session.health.summary()
+		summary = &s
+	}
+	session.bufferMu.Unlock()
+
+	stats := StreamStats{
+		StreamID:         session.ID,
+		SamplesReceived:  received,
+		SamplesBuffered:  buffered,
+		ResultsDelivered: session.results.delivered.Load(),
+		ResultsDropped:   session.results.dropped.Load(),
+		WindowsSkipped:   skipped,
+		Health:           summary,
+	}
+	if session.Callback == nil {
+		stats.ResultsQueued = len(session.ResultChan)
+		stats.ResultCapacity = cap(session.ResultChan)
+		stats.OverflowPolicy = session.results.policy
+	}
+	return stats
+}
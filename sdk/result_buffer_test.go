@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestResultBufferOverflow 测试结果缓冲的容量与溢出策略
+// 测试内容：
+// 1. 配置中的容量与溢出策略校验，流选项覆盖SDK配置
+// 2. drop-newest 保留最早的结果，drop-oldest 保留最新的结果，丢弃数计入统计
+// 3. block 策略在调用方取走结果时继续交付，超时后丢弃
+func TestResultBufferOverflow(t *testing.T) {
+	for _, bad := range []AudioStreamConfig{
+		{ResultBufferSize: -1},
+		{ResultBufferSize: MaxResultBufferSize + 1},
+		{OverflowPolicy: "ring"},
+		{OverflowTimeoutMs: -5},
+	} {
+		if err := validateResultBuffer(bad.ResultBufferSize, bad.OverflowPolicy, bad.OverflowTimeoutMs); err == nil {
+			t.Errorf("config %+v should be invalid", bad)
+		}
+	}
+
+	engine := NewEngine(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, ResultBufferSize: 2, OverflowPolicy: OverflowDropOldest}, NewSampleLibrary())
+	session := engine.NewSession("s")
+	if cap(session.ResultChan) != 2 || session.results.policy != OverflowDropOldest {
+		t.Fatalf("session buffer = %d %s, want 2 drop-oldest", cap(session.ResultChan), session.results.policy)
+	}
+	if err := (StreamOptions{OverflowPolicy: OverflowDropNewest}).apply(engine, session, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cap(session.ResultChan) != 2 || session.results.policy != OverflowDropNewest {
+		t.Errorf("options should override the policy and keep the configured size, got %d %s", cap(session.ResultChan), session.results.policy)
+	}
+
+	fill := func(session *AudioStreamSession, n int) {
+		for i := 1; i <= n; i++ {
+			deliverResult(session, []byte(fmt.Sprint(i)))
+		}
+	}
+	fill(session, 5)
+	if first := <-session.ResultChan; string(first) != "1" {
+		t.Errorf("drop-newest kept %s first, want 1", first)
+	}
+	if stats := sessionStats(session, false); stats.ResultsDelivered != 2 || stats.ResultsDropped != 3 || stats.ResultsQueued != 1 || stats.OverflowPolicy != OverflowDropNewest {
+		t.Errorf("drop-newest stats = %+v", stats)
+	}
+
+	setResultBuffer(session, 2, OverflowDropOldest, 0)
+	fill(session, 5)
+	if first := <-session.ResultChan; string(first) != "4" {
+		t.Errorf("drop-oldest kept %s first, want 4", first)
+	}
+	if stats := sessionStats(session, false); stats.ResultsDropped != 6 {
+		t.Errorf("dropped after drop-oldest = %d, want 3 more", stats.ResultsDropped)
+	}
+
+	setResultBuffer(session, 1, OverflowBlock, 50)
+	fill(session, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-session.ResultChan
+	}()
+	start := time.Now()
+	fill(session, 1)
+	if len(session.ResultChan) != 1 {
+		t.Error("block policy should deliver once the caller drains the buffer")
+	}
+	deliverResult(session, []byte("late"))
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("block policy returned after %v, want at least the 50ms timeout", elapsed)
+	}
+	if stats := sessionStats(session, false); stats.ResultsDropped != 7 || stats.ResultCapacity != 1 {
+		t.Errorf("block stats = %+v", stats)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// 结果调试信息：流开启调试（/start 或WebSocket配置中 "debug": true，或请求带 ?debug=1）后，
+// 结果的 debug 字段列出参与本次结果的每个窗口的特征与各情感评分。
+
+// ResultDebug 结果附带的调试信息
+type ResultDebug struct {
+	Windows []DebugWindow `json:"windows"` // 参与本次结果的各窗口，按时间顺序
+}
+
+// DebugWindow 一个分析窗口的特征与评分
+type DebugWindow struct {
+	Features AudioFeature       `json:"features"`
+	Scores   map[string]float64 `json:"scores,omitempty"` // 该窗口单独评分时各情感的得分
+}
+
+// debugRequested 读取请求中的 debug 查询参数，第二个返回值表示请求是否指定了该参数
+func debugRequested(r *http.Request) (bool, bool) {
+	value := r.URL.Query().Get("debug")
+	if value == "" {
+		return false, false
+	}
+	enabled, err := strconv.ParseBool(value)
+	return enabled && err == nil, true
+}
+
+// SetStreamDebug 设置流的结果是否附带逐窗口特征与评分
+func (e *Engine) SetStreamDebug(streamID string, enabled bool) {
+	session := e.streamSession(streamID)
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+	session.Debug = enabled
+	if !enabled {
+		session.segmentDebug = nil
+	}
+}
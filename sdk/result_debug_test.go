@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestResultDebug 测试结果调试信息
+// 测试内容：
+// 1. /send?debug=1 后引擎结果附带当前窗口的特征与评分，未开启时不附带
+// 2. accurate 策略下调试信息累积段内的全部窗口，窗口序号递增
+func TestResultDebug(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	server := NewAudioServer(engine)
+	send := func(target, streamID string, seconds float64) AudioStreamResult {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{
+			"streamId": streamID,
+			"data":     generateTestAudio(440, seconds, 44100),
+		})
+		rec := httptest.NewRecorder()
+		server.handleSend(rec, httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body)))
+		var result AudioStreamResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.Emotion == "" {
+			t.Fatalf("%s body = %s", target, rec.Body)
+		}
+		return result
+	}
+
+	if result := send("/send", "plain", 0.1); result.Debug != nil {
+		t.Errorf("debug without ?debug=1 = %+v", result.Debug)
+	}
+	result := send("/send?debug=1", "plain", 0.1)
+	if result.Debug == nil || len(result.Debug.Windows) != 1 {
+		t.Fatalf("debug = %+v, want one window", result.Debug)
+	}
+	window := result.Debug.Windows[0]
+	if window.Features.Energy != result.Metadata.Features["Energy"] || len(window.Scores) == 0 {
+		t.Errorf("window = %+v, want features %v and scores", window, result.Metadata.Features)
+	}
+	if result := send("/send?debug=0", "plain", 0.1); result.Debug != nil {
+		t.Errorf("debug after ?debug=0 = %+v", result.Debug)
+	}
+
+	if err := engine.ConfigureStream("review", StreamSettings{Strategy: StrategyAccurate, Debug: true}); err != nil {
+		t.Fatal(err)
+	}
+	result = send("/send", "review", 0.2)
+	if result.Debug == nil || len(result.Debug.Windows) < 2 {
+		t.Fatalf("accurate debug = %+v, want the segment's windows", result.Debug)
+	}
+	for i, window := range result.Debug.Windows[1:] {
+		if window.Features.WindowIndex != result.Debug.Windows[i].Features.WindowIndex+1 {
+			t.Errorf("window indexes = %d, %d; want consecutive", result.Debug.Windows[i].Features.WindowIndex, window.Features.WindowIndex)
+		}
+	}
+
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// 结果钩子
+//
+// 不同的安装环境常有各自的规则，例如"晚上10点后厨房里的 hungry 多半是在要零食"、"多猫家庭里
+// 低音高的 warning 置信度打八折"。结果钩子文件中的规则在每个最终结果上按顺序求值（表达式语法见
+// hook_expr.go），条件为真时可以改写情感与置信度、附加自定义字段，无需重新编译。钩子在服务端的
+// 结果管线最前面执行，时间线、基线、告警与历史看到的都是改写后的结果；处理器内部已经发出的情感变化
+// 事件不受影响。改写过的结果附带 hook 字段，记录应用的规则与原始情感、置信度。
+//
+// 表达式可用的变量：
+//   emotion confidence label    结果的情感、置信度与名称（前面的规则改写后为改写后的值）
+//   streamId catId               流ID与关联的猫咪ID（未关联时为空字符串）
+//   hour                         本地时间的小时 0~23
+//   features.<名称>              结果 metadata.features 中的特征，缺少时为0
+//   recent.<情感>  recentCount   该流之前最近 HookRecentResults 个最终结果中该情感的个数与结果总数
+
+// HookRecentResults 表达式中 recent 统计的最近结果数
+const HookRecentResults = 10
+
+// ResultHookRule 一条结果钩子规则
+type ResultHookRule struct {
+	Name       string            `json:"name"`
+	When       string            `json:"when"`       // 条件表达式，结果为 true 时应用该规则
+	Emotion    string            `json:"emotion"`    // 改写后的情感ID，为空时不改写
+	Confidence string            `json:"confidence"` // 改写后置信度的表达式，为空时不改写，结果截断到 [0, 1]
+	Fields     map[string]string `json:"fields"`     // 附加字段：字段名 -> 表达式，放在结果的 hook.fields 中
+
+	when       hookExpr
+	confidence hookExpr
+	fields     map[string]hookExpr
+}
+
+// ResultHooks 编译后的结果钩子与各流最近的结果
+type ResultHooks struct {
+	Rules []ResultHookRule `json:"rules"`
+
+	mu     sync.Mutex
+	recent map[string][]string // streamID -> 最近的最终结果情感
+}
+
+// ResultHookInfo 改写过的结果附带的 hook 字段
+type ResultHookInfo struct {
+	Rules              []string               `json:"rules"` // 条件成立的规则
+	OriginalEmotion    string                 `json:"originalEmotion"`
+	OriginalConfidence float64                `json:"originalConfidence"`
+	Fields             map[string]interface{} `json:"fields,omitempty"`
+}
+
+// LoadResultHooks 从JSON文件加载并编译结果钩子
+func LoadResultHooks(path string) (*ResultHooks, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read result hooks: %v", err)
+	}
+	var hooks ResultHooks
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("parse result hooks: %v", err)
+	}
+	if err := hooks.compile(); err != nil {
+		return nil, err
+	}
+	return &hooks, nil
+}
+
+// compile 编译各规则的表达式
+func (h *ResultHooks) compile() error {
+	for i := range h.Rules {
+		rule := &h.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if rule.When == "" {
+			return fmt.Errorf("result hook %s: when is required", rule.Name)
+		}
+		var err error
+		if rule.when, err = compileHookExpr(rule.When); err != nil {
+			return fmt.Errorf("result hook %s: when: %v", rule.Name, err)
+		}
+		if rule.Confidence != "" {
+			if rule.confidence, err = compileHookExpr(rule.Confidence); err != nil {
+				return fmt.Errorf("result hook %s: confidence: %v", rule.Name, err)
+			}
+		}
+		rule.fields = make(map[string]hookExpr, len(rule.Fields))
+		for name, src := range rule.Fields {
+			if rule.fields[name], err = compileHookExpr(src); err != nil {
+				return fmt.Errorf("result hook %s: field %s: %v", rule.Name, name, err)
+			}
+		}
+	}
+	h.recent = make(map[string][]string)
+	return nil
+}
+
+// Apply 在一个结果上求值所有规则，返回改写后的结果；不是最终结果或没有规则成立时原样返回
+func (h *ResultHooks) Apply(streamID, catID string, result []byte, now time.Time) []byte {
+	var parsed map[string]interface{}
+	if len(result) == 0 || json.Unmarshal(result, &parsed) != nil {
+		return result
+	}
+	emotion, _ := parsed["emotion"].(string)
+	confidence, _ := parsed["confidence"].(float64)
+	label, _ := parsed["label"].(string)
+	if emotion == "" || parsed["partial"] == true || parsed["status"] == "waiting" {
+		return result
+	}
+
+	features := make(map[string]float64)
+	if metadata, ok := parsed["metadata"].(map[string]interface{}); ok {
+		if values, ok := metadata["features"].(map[string]interface{}); ok {
+			for name, v := range values {
+				if f, ok := v.(float64); ok {
+					features[name] = f
+				}
+			}
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	recent := make(map[string]float64)
+	for _, e := range h.recent[streamID] {
+		recent[e]++
+	}
+	env := hookEnv{
+		vars: map[string]hookValue{
+			"emotion":     emotion,
+			"confidence":  confidence,
+			"label":       label,
+			"streamId":    streamID,
+			"catId":       catID,
+			"hour":        float64(now.Hour()),
+			"recentCount": float64(len(h.recent[streamID])),
+		},
+		groups: map[string]map[string]float64{"features": features, "recent": recent},
+	}
+
+	info := ResultHookInfo{OriginalEmotion: emotion, OriginalConfidence: confidence}
+	for _, rule := range h.Rules {
+		v, err := rule.when(env)
+		if err != nil {
+			log.Printf("[%s] 结果钩子 %s 求值失败: %v", streamID, rule.Name, err)
+			continue
+		}
+		if matched, ok := v.(bool); !ok || !matched {
+			if !ok {
+				log.Printf("[%s] 结果钩子 %s 的条件不是布尔值: %v", streamID, rule.Name, v)
+			}
+			continue
+		}
+		if err := rule.apply(env, &info); err != nil {
+			log.Printf("[%s] 结果钩子 %s 求值失败: %v", streamID, rule.Name, err)
+			continue
+		}
+		info.Rules = append(info.Rules, rule.Name)
+	}
+
+	emotion = env.vars["emotion"].(string)
+	h.recent[streamID] = append(h.recent[streamID], emotion)
+	if len(h.recent[streamID]) > HookRecentResults {
+		h.recent[streamID] = h.recent[streamID][1:]
+	}
+	if len(info.Rules) == 0 {
+		return result
+	}
+
+	if emotion != info.OriginalEmotion {
+		parsed["emotion"] = emotion
+		if label != "" {
+			parsed["label"] = relabelEmotion(info.OriginalEmotion, label, emotion)
+		}
+		delete(parsed, "message") // 提示短语是按原情感生成的
+	}
+	parsed["confidence"] = env.vars["confidence"]
+	parsed["hook"] = info
+	data, err := json.Marshal(parsed)
+	if err != nil {
+		return result
+	}
+	return data
+}
+
+// apply 应用一条条件成立的规则，改写 env 中的情感与置信度；任何表达式出错时不改写
+func (rule *ResultHookRule) apply(env hookEnv, info *ResultHookInfo) error {
+	confidence := env.vars["confidence"]
+	if rule.confidence != nil {
+		v, err := rule.confidence(env)
+		if err != nil {
+			return err
+		}
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("confidence must be a number, got %v", v)
+		}
+		confidence = math.Max(0, math.Min(1, f))
+	}
+	fields := make(map[string]interface{}, len(rule.fields))
+	for name, expr := range rule.fields {
+		v, err := expr(env)
+		if err != nil {
+			return err
+		}
+		fields[name] = v
+	}
+
+	env.vars["confidence"] = confidence
+	if rule.Emotion != "" {
+		env.vars["emotion"] = NormalizeEmotionID(rule.Emotion)
+	}
+	for name, v := range fields {
+		if info.Fields == nil {
+			info.Fields = make(map[string]interface{})
+		}
+		info.Fields[name] = v
+	}
+	return nil
+}
+
+// Forget 流结束后清除其最近的结果
+func (h *ResultHooks) Forget(streamID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.recent, streamID)
+}
+
+// relabelEmotion 按原结果名称所用的语言给出新情感的名称，找不到该语言时返回情感ID
+func relabelEmotion(original, originalLabel, emotion string) string {
+	for _, lang := range SupportedLocales() {
+		if EmotionLabel(lang, original) == originalLabel {
+			return EmotionLabel(lang, emotion)
+		}
+	}
+	return emotion
+}
+
+// SetResultHooks 设置结果钩子，为nil时不改写结果
+func (s *AudioServer) SetResultHooks(hooks *ResultHooks) {
+	s.hooks = hooks
+}
+
+// applyHooks 在流的结果上应用结果钩子
+func (s *AudioServer) applyHooks(streamID string, result []byte, now time.Time) []byte {
+	if s.hooks == nil {
+		return result
+	}
+	return s.hooks.Apply(streamID, s.streamCat(streamID), result, now)
+}
+
+// forgetHooks 流结束后清除结果钩子中该流最近的结果
+func (s *AudioServer) forgetHooks(streamID string) {
+	if s.hooks != nil {
+		s.hooks.Forget(streamID)
+	}
+}
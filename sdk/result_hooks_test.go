@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHookExpr 测试结果钩子表达式
+// 测试内容：
+// 1. 运算符优先级、括号、短路求值与分组变量
+// 2. 语法错误在编译时报告，类型错误与未知变量在求值时报告
+func TestHookExpr(t *testing.T) {
+	env := hookEnv{
+		vars:   map[string]hookValue{"emotion": "hungry", "confidence": 0.6, "hour": 23.0},
+		groups: map[string]map[string]float64{"features": {"Pitch": 820}},
+	}
+	for src, want := range map[string]hookValue{
+		`1 + 2 * 3`:                         7.0,
+		`(1 + 2) * 3`:                       9.0,
+		`-confidence + 1`:                   0.4,
+		`emotion == "hungry" && hour >= 22`: true,
+		`emotion != "hungry" || features.Pitch > 800`: true,
+		`!(features.Missing == 0)`:                    false,
+		`false && unknown > 1`:                        false,
+		`"a\"b" == "a\"b"`:                            true,
+	} {
+		expr, err := compileHookExpr(src)
+		if err != nil {
+			t.Errorf("compile %s: %v", src, err)
+			continue
+		}
+		if got, err := expr(env); err != nil || got != want {
+			t.Errorf("%s = %v (%v), want %v", src, got, err, want)
+		}
+	}
+
+	for _, src := range []string{``, `1 +`, `(1`, `1 2`, `"open`, `a # b`} {
+		if _, err := compileHookExpr(src); err == nil {
+			t.Errorf("compile %q should fail", src)
+		}
+	}
+	for _, src := range []string{`emotion > 1`, `unknown == 1`, `confidence / 0`, `emotion == 1`, `!confidence`} {
+		expr, err := compileHookExpr(src)
+		if err != nil {
+			t.Fatalf("compile %s: %v", src, err)
+		}
+		if _, err := expr(env); err == nil {
+			t.Errorf("%s should fail at evaluation", src)
+		}
+	}
+}
+
+// TestResultHooks 测试结果钩子
+// 测试内容：
+// 1. 条件成立的规则按顺序改写情感与置信度、附加字段，结果附带 hook 字段与原始值
+// 2. recent 统计该流之前的最终结果，中间结果与没有规则成立的结果不改写
+// 3. 规则编译错误在加载时报告；服务端管线在时间线之前应用钩子
+func TestResultHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hooks.json")
+	os.WriteFile(path, []byte(`{"rules": [
+		{"name": "night-snack", "when": "emotion == \"hungry\" && hour >= 22", "emotion": "for_food",
+		 "fields": {"site": "\"kitchen\"", "pitch": "features.Pitch"}},
+		{"name": "repeated", "when": "recent.for_food >= 1", "confidence": "confidence * 1.5"}
+	]}`), 0644)
+	hooks, err := LoadResultHooks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	night := time.Date(2023, 11, 14, 23, 0, 0, 0, time.Local)
+	result := []byte(`{"status":"processed","emotion":"hungry","confidence":0.5,"label":"hungry","message":"...","metadata":{"features":{"Pitch":640}}}`)
+	var first struct {
+		Emotion    string          `json:"emotion"`
+		Confidence float64         `json:"confidence"`
+		Message    *string         `json:"message"`
+		Hook       *ResultHookInfo `json:"hook"`
+	}
+	json.Unmarshal(hooks.Apply("kitchen", "", result, night), &first)
+	if first.Emotion != "for_food" || first.Confidence != 0.5 || first.Message != nil || first.Hook == nil {
+		t.Fatalf("first result = %+v", first)
+	}
+	if first.Hook.OriginalEmotion != "hungry" || len(first.Hook.Rules) != 1 || first.Hook.Fields["site"] != "kitchen" || first.Hook.Fields["pitch"] != 640.0 {
+		t.Errorf("hook info = %+v", first.Hook)
+	}
+
+	var second struct {
+		Confidence float64         `json:"confidence"`
+		Hook       *ResultHookInfo `json:"hook"`
+	}
+	json.Unmarshal(hooks.Apply("kitchen", "", result, night), &second)
+	if second.Confidence != 0.75 || len(second.Hook.Rules) != 2 {
+		t.Errorf("second result = %+v, want both rules", second)
+	}
+
+	daytime := []byte(`{"status":"processed","emotion":"hello","confidence":0.5}`)
+	if got := hooks.Apply("bedroom", "", daytime, night.Add(-12*time.Hour)); string(got) != string(daytime) {
+		t.Errorf("unmatched result was rewritten: %s", got)
+	}
+	partial := []byte(`{"status":"processed","emotion":"hungry","confidence":0.5,"partial":true}`)
+	if got := hooks.Apply("kitchen", "", partial, night); string(got) != string(partial) {
+		t.Errorf("partial result was rewritten: %s", got)
+	}
+
+	os.WriteFile(path, []byte(`{"rules": [{"name": "broken", "when": "emotion =="}]}`), 0644)
+	if _, err := LoadResultHooks(path); err == nil {
+		t.Error("broken expression should fail to load")
+	}
+
+	server := NewAudioServer(NewMockAudioProcessor())
+	hooks.Forget("kitchen")
+	server.SetResultHooks(hooks)
+	server.bindCat("kitchen", "mimi")
+	rewritten := server.applyHooks("kitchen", result, night)
+	server.recordDetection("kitchen", rewritten, time.Now())
+	if timeline := server.timeline("mimi", 0); len(timeline.Detections) != 1 || timeline.Detections[0].Emotion != "for_food" {
+		t.Errorf("timeline = %+v, want the rewritten emotion", timeline.Detections)
+	}
+}
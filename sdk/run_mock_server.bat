@@ -1,3 +1,3 @@
 @echo off
 echo "编译并运行模拟服务器..."
-go run mock_main.go types.go sample_library.go recording.go stream.go sound_identify.go mock_stream.go
\ No newline at end of file
+go run .
\ No newline at end of file
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// 样本目录的标签来源：library build --from-dir 的 -label-source 选择样本情感（与猫咪ID）的来源：
+//   filename  子目录名或文件名前缀（默认）
+//   tags      MP3 的 ID3v2 自定义文本帧 TXXX，描述为 emotion 与 cat（不区分大小写）
+//   csv       旁挂的 CSV 文件（默认为样本目录下的 labels.csv，可用 -labels-csv 指定），表头为
+//             file,emotion,cat，file 为相对样本目录、以 / 分隔的路径（也可以只写文件名），cat 可省略
+// tags 与 csv 方式下没有标签的文件仍按文件名确定情感。
+
+// 样本目录的标签来源
+const (
+	LabelingFilename = "filename"
+	LabelingTags     = "tags"
+	LabelingCSV      = "csv"
+)
+
+// DefaultLabelsCSV 样本目录下默认的旁挂标签文件名
+const DefaultLabelsCSV = "labels.csv"
+
+// SampleLabel 样本文件的标签
+type SampleLabel struct {
+	Emotion string
+	CatID   string
+}
+
+// SampleLabeler 按标签来源确定样本目录中文件的标签
+type SampleLabeler struct {
+	source string
+	csv    map[string]SampleLabel // CSV 中的标签，键为相对样本目录的路径与文件名
+}
+
+// NewSampleLabeler 创建标签来源，source 为 csv 时读取 csvPath
+func NewSampleLabeler(source, csvPath string) (*SampleLabeler, error) {
+	labeler := &SampleLabeler{source: source}
+	switch source {
+	case "", LabelingFilename, LabelingTags:
+	case LabelingCSV:
+		labels, err := loadLabelsCSV(csvPath)
+		if err != nil {
+			return nil, err
+		}
+		labeler.csv = labels
+		log.Printf("已读取标签文件 %s", csvPath)
+	default:
+		return nil, fmt.Errorf("unknown label source %q, want %s, %s or %s", source, LabelingFilename, LabelingTags, LabelingCSV)
+	}
+	return labeler, nil
+}
+
+// loadLabelsCSV 读取旁挂标签文件，表头须包含 file 与 emotion 列
+func loadLabelsCSV(path string) (map[string]SampleLabel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("labels csv %s: %v", path, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	fileCol, ok1 := columns["file"]
+	emotionCol, ok2 := columns["emotion"]
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("labels csv %s: header must contain file and emotion columns", path)
+	}
+	catCol, hasCat := columns["cat"]
+
+	labels := map[string]SampleLabel{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("labels csv %s: %v", path, err)
+		}
+		if fileCol >= len(record) || emotionCol >= len(record) || record[fileCol] == "" || record[emotionCol] == "" {
+			return nil, fmt.Errorf("labels csv %s:%d: missing file or emotion", path, line)
+		}
+		label := SampleLabel{Emotion: NormalizeEmotionID(strings.TrimSpace(record[emotionCol]))}
+		if hasCat && catCol < len(record) {
+			label.CatID = strings.TrimSpace(record[catCol])
+		}
+		name := strings.ReplaceAll(strings.TrimSpace(record[fileCol]), `\`, "/")
+		labels[name] = label
+		if _, ok := labels[filepath.Base(name)]; !ok {
+			labels[filepath.Base(name)] = label
+		}
+	}
+	return labels, nil
+}
+
+// label 返回文件的标签，dirPath 为样本目录，dirName 为文件所在的情感子目录（顶层文件为空）
+// 标签来源中没有该文件时按子目录名或文件名前缀确定情感
+func (l *SampleLabeler) label(dirPath, dirName, filePath string) SampleLabel {
+	fallback := SampleLabel{Emotion: sampleEmotion(dirName, filepath.Base(filePath))}
+	if l == nil {
+		return fallback
+	}
+
+	var label SampleLabel
+	var ok bool
+	switch l.source {
+	case LabelingCSV:
+		rel, err := filepath.Rel(dirPath, filePath)
+		if err != nil {
+			rel = filePath
+		}
+		if label, ok = l.csv[filepath.ToSlash(rel)]; !ok {
+			label, ok = l.csv[filepath.Base(filePath)]
+		}
+	case LabelingTags:
+		data, err := os.ReadFile(filePath)
+		if err == nil {
+			label = id3Labels(data)
+			ok = label.Emotion != ""
+		}
+	default:
+		return fallback
+	}
+	if !ok {
+		log.Printf("%s 没有 %s 标签，按文件名确定情感: %s", filePath, l.source, fallback.Emotion)
+		return fallback
+	}
+	return label
+}
+
+// id3Labels 读取 ID3v2.3/2.4 中描述为 emotion、cat 的 TXXX 帧
+func id3Labels(data []byte) SampleLabel {
+	var label SampleLabel
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return label
+	}
+	end := 10 + syncsafe(data[6:10])
+	if end > len(data) {
+		return label
+	}
+	tag, version := data[10:end], data[3]
+	for offset := 0; offset+10 <= len(tag) && tag[offset] != 0; {
+		var size int
+		if version >= 4 {
+			size = syncsafe(tag[offset+4 : offset+8])
+		} else {
+			size = int(binary.BigEndian.Uint32(tag[offset+4:]))
+		}
+		if size <= 0 || offset+10+size > len(tag) {
+			break
+		}
+		if string(tag[offset:offset+4]) == "TXXX" {
+			description, value := splitTXXX(tag[offset+10 : offset+10+size])
+			switch strings.ToLower(description) {
+			case "emotion":
+				label.Emotion = NormalizeEmotionID(value)
+			case "cat":
+				label.CatID = value
+			}
+		}
+		offset += 10 + size
+	}
+	return label
+}
+
+// splitTXXX 拆分 TXXX 帧的描述与值，两者以编码对应的结束符分隔
+func splitTXXX(frame []byte) (string, string) {
+	if len(frame) < 2 {
+		return "", ""
+	}
+	encoding, text := frame[0], frame[1:]
+	if encoding != 1 && encoding != 2 {
+		// ISO-8859-1 / UTF-8，日期、情感ID与猫咪ID按 UTF-8 处理
+		parts := bytes.SplitN(text, []byte{0}, 2)
+		if len(parts) != 2 {
+			return "", ""
+		}
+		return cleanTagText(parts[0]), cleanTagText(parts[1])
+	}
+	// UTF-16：结束符为位于偶数位置的两个零字节
+	for i := 0; i+1 < len(text); i += 2 {
+		if text[i] == 0 && text[i+1] == 0 {
+			return decodeUTF16(text[:i], encoding == 2), decodeUTF16(text[i+2:], encoding == 2)
+		}
+	}
+	return "", ""
+}
+
+// decodeUTF16 解码 UTF-16 文本，有字节序标记时按标记，否则按 bigEndian
+func decodeUTF16(b []byte, bigEndian bool) string {
+	if len(b) >= 2 && (b[0] == 0xFF && b[1] == 0xFE || b[0] == 0xFE && b[1] == 0xFF) {
+		bigEndian = b[0] == 0xFE
+		b = b[2:]
+	}
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		if bigEndian {
+			units = append(units, binary.BigEndian.Uint16(b[i:]))
+		} else {
+			units = append(units, binary.LittleEndian.Uint16(b[i:]))
+		}
+	}
+	return strings.TrimSpace(strings.TrimRight(string(utf16.Decode(units)), "\x00"))
+}
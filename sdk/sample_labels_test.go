@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// TestSampleLabeler 测试样本目录的标签来源
+// 测试内容：
+// 1. csv：按相对路径或文件名匹配，表头不区分大小写并允许 BOM，没有标签的文件按文件名确定情感
+// 2. 样本带上 CSV 中的猫咪ID
+// 3. tags：读取 ID3v2 TXXX:emotion/cat，支持 ISO-8859-1 与带字节序标记的 UTF-16
+// 4. 未知来源与缺少列的 CSV 返回错误
+// 5. 增量构建时 CSV 中标签改变的文件重新提取
+func TestSampleLabeler(t *testing.T) {
+	dir := t.TempDir()
+	tone := func(freq float64) []byte {
+		samples := make([]int16, 22050)
+		for i := range samples {
+			samples[i] = int16(8000 * math.Sin(2*math.Pi*freq*float64(i)/44100))
+		}
+		return buildWAV(samples, 44100)
+	}
+	write := func(name string, data []byte) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.wav", tone(500))
+	write("purr/x.wav", tone(150))
+	write("hungry_2.wav", tone(600))
+	write(DefaultLabelsCSV, []byte("\ufeffFile, Emotion, Cat\na.wav,hungry,mimi\npurr/x.wav,angry,\n"))
+
+	build := func() *SampleProcessor {
+		t.Helper()
+		labeler, err := NewSampleLabeler(LabelingCSV, filepath.Join(dir, DefaultLabelsCSV))
+		if err != nil {
+			t.Fatalf("NewSampleLabeler: %v", err)
+		}
+		processor := NewSampleProcessor(AudioStreamConfig{})
+		processor.Labeler = labeler
+		output := filepath.Join(dir, "out", "library.json")
+		if err := processor.StartIncremental(output, "", false); err != nil {
+			t.Fatal(err)
+		}
+		if err := processor.ProcessDirectory(dir); err != nil {
+			t.Fatalf("ProcessDirectory: %v", err)
+		}
+		if err := processor.ExportLibrary(output); err != nil {
+			t.Fatal(err)
+		}
+		if err := processor.SaveManifest(output); err != nil {
+			t.Fatal(err)
+		}
+		return processor
+	}
+
+	p := build()
+	if len(p.Library.Samples["hungry"]) != 2 || len(p.Library.Samples["angry"]) != 1 || len(p.Library.Samples["purr"]) != 0 {
+		t.Errorf("emotions = hungry %d, angry %d, purr %d; want 2, 1, 0",
+			len(p.Library.Samples["hungry"]), len(p.Library.Samples["angry"]), len(p.Library.Samples["purr"]))
+	}
+	for _, sample := range p.Library.Samples["hungry"] {
+		want := ""
+		if filepath.Base(sample.FilePath) == "a.wav" {
+			want = "mimi"
+		}
+		if sample.CatID != want {
+			t.Errorf("%s cat = %q, want %q", sample.FilePath, sample.CatID, want)
+		}
+	}
+
+	// 只改标签也要重新提取该文件
+	write(DefaultLabelsCSV, []byte("file,emotion,cat\na.wav,hungry,tom\npurr/x.wav,angry,\n"))
+	p = build()
+	if p.incremental.reused != 2 || p.incremental.extracted != 1 {
+		t.Errorf("reused %d, extracted %d; want 2, 1", p.incremental.reused, p.incremental.extracted)
+	}
+
+	// ID3v2.3 TXXX 帧
+	frame := func(payload []byte) []byte {
+		header := []byte("TXXX")
+		header = binary.BigEndian.AppendUint32(header, uint32(len(payload)))
+		return append(append(header, 0, 0), payload...)
+	}
+	utf16le := func(s string) []byte {
+		b := []byte{0xFF, 0xFE}
+		for _, u := range utf16.Encode([]rune(s)) {
+			b = binary.LittleEndian.AppendUint16(b, u)
+		}
+		return b
+	}
+	var tag []byte
+	tag = append(tag, frame(append([]byte{0}, "Emotion\x00hungry"...))...)
+	cat := append(append([]byte{1}, utf16le("cat")...), 0, 0)
+	tag = append(tag, frame(append(cat, utf16le("咪咪")...))...)
+	mp3 := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(tag))}, tag...)
+	if label := id3Labels(mp3); label.Emotion != "hungry" || label.CatID != "咪咪" {
+		t.Errorf("id3Labels = %+v, want hungry/咪咪", label)
+	}
+
+	if _, err := NewSampleLabeler("exif", ""); err == nil {
+		t.Error("unknown label source should fail")
+	}
+	bad := filepath.Join(dir, "bad.csv")
+	os.WriteFile(bad, []byte("name,emotion\na.wav,hungry\n"), 0644)
+	if _, err := NewSampleLabeler(LabelingCSV, bad); err == nil {
+		t.Error("csv without file column should fail")
+	}
+}
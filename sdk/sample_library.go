@@ -2,21 +2,26 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"math"
 	"os"
+	"path/filepath"
 )
 
 // NewSampleLibrary 创建新的样本库
 func NewSampleLibrary() *SampleLibrary {
 	return &SampleLibrary{
-		Samples:    make(map[string][]AudioSample),
-		Statistics: make(map[string]EmotionStatistics),
-		NeedUpdate: false,
+		Samples:          make(map[string][]AudioSample),
+		Statistics:       make(map[string]EmotionStatistics),
+		NeedUpdate:       false,
+		ExtractorVersion: FeatureExtractorVersion,
 	}
 }
 
 // AddSample 添加样本
 func (sl *SampleLibrary) AddSample(sample AudioSample) {
+	sample.Emotion = NormalizeEmotionID(sample.Emotion)
 	emotion := sample.Emotion
 	if _, exists := sl.Samples[emotion]; !exists {
 		sl.Samples[emotion] = make([]AudioSample, 0)
@@ -88,19 +93,70 @@ func (sl *SampleLibrary) updateStatistics() {
 		stats.StdDevFeature.SpectralRolloff = math.Sqrt(stats.StdDevFeature.SpectralRolloff / count)
 		stats.StdDevFeature.FundamentalFreq = math.Sqrt(stats.StdDevFeature.FundamentalFreq / count)
 
+		stats.MeanFeature.Custom, stats.StdDevFeature.Custom = customStatistics(samples)
+
 		sl.Statistics[emotion] = stats
 	}
 
 	sl.NeedUpdate = false
 }
 
+// customStatistics 各自定义特征的均值与标准差，没有注册自定义特征时为nil
+func customStatistics(samples []AudioSample) (map[string]float64, map[string]float64) {
+	names := RegisteredFeatures()
+	if len(names) == 0 {
+		return nil, nil
+	}
+	count := float64(len(samples))
+	means := make(map[string]float64, len(names))
+	stdDevs := make(map[string]float64, len(names))
+	for _, name := range names {
+		for _, sample := range samples {
+			means[name] += sample.Features.Custom[name] / count
+		}
+		for _, sample := range samples {
+			stdDevs[name] += math.Pow(sample.Features.Custom[name]-means[name], 2)
+		}
+		stdDevs[name] = math.Sqrt(stdDevs[name] / count)
+	}
+	return means, stdDevs
+}
+
 // Match 匹配音频特征
 func (sl *SampleLibrary) Match(feature AudioFeature) (string, float64) {
-	sl.updateStatistics()
+	return selectEmotion(sl.Scores(feature), nil)
+}
+
+// MatchWithPriors 结合时间与上下文先验匹配音频特征，先验为空时等同于 Match
+func (sl *SampleLibrary) MatchWithPriors(feature AudioFeature, priors map[string]float64) (string, float64) {
+	return selectEmotion(sl.Scores(feature), priors)
+}
+
+// selectEmotion 从各情感评分中选出最佳情感，有先验时按先验调整后再选择
+func selectEmotion(scores map[string]float64, priors map[string]float64) (string, float64) {
+	if len(priors) > 0 {
+		return applyEmotionPriors(scores, priors)
+	}
 
 	var bestMatch string
 	var maxScore float64 = -1
 
+	for emotion, score := range scores {
+		// 评分相同时按情感ID排序，避免结果依赖map遍历顺序
+		if score > maxScore || (score == maxScore && emotion < bestMatch) {
+			maxScore = score
+			bestMatch = emotion
+		}
+	}
+
+	return bestMatch, maxScore
+}
+
+// Scores 计算音频特征与每个情感的综合评分
+func (sl *SampleLibrary) Scores(feature AudioFeature) map[string]float64 {
+	sl.updateStatistics()
+
+	scores := make(map[string]float64, len(sl.Samples))
 	for emotion, samples := range sl.Samples {
 		if len(samples) == 0 {
 			continue
@@ -120,15 +176,10 @@ func (sl *SampleLibrary) Match(feature AudioFeature) (string, float64) {
 		mahalanobisDistance := calculateMahalanobisDistance(feature, stats.MeanFeature, stats.StdDevFeature)
 
 		// 综合评分（结合欧氏距离和马氏距离）
-		score := 0.6*(1.0/(1.0+minEuclideanDistance)) + 0.4*(1.0/(1.0+mahalanobisDistance))
-
-		if score > maxScore {
-			maxScore = score
-			bestMatch = emotion
-		}
+		scores[emotion] = 0.6*(1.0/(1.0+minEuclideanDistance)) + 0.4*(1.0/(1.0+mahalanobisDistance))
 	}
 
-	return bestMatch, maxScore
+	return scores
 }
 
 // SaveToFile 保存样本库到文件
@@ -141,8 +192,11 @@ func (sl *SampleLibrary) SaveToFile(filename string) error {
 	}
 	defer file.Close()
 
+	// 样本路径写为相对样本库所在目录的形式
+	saved := *sl
+	saved.Samples = withRelativePaths(sl.Samples, filepath.Dir(filename))
 	encoder := json.NewEncoder(file)
-	return encoder.Encode(sl)
+	return encoder.Encode(&saved)
 }
 
 // LoadFromFile 从文件加载样本库
@@ -153,8 +207,73 @@ func (sl *SampleLibrary) LoadFromFile(filename string) error {
 	}
 	defer file.Close()
 
+	sl.ExtractorVersion = "" // 旧版样本库文件没有该字段
 	decoder := json.NewDecoder(file)
-	return decoder.Decode(sl)
+	if err := decoder.Decode(sl); err != nil {
+		return err
+	}
+	sl.resolvePaths(filepath.Dir(filename))
+	sl.normalizeEmotions()
+	if err := sl.checkExtractorVersion(); err != nil {
+		log.Printf("警告: 样本库 %s: %v，请重新构建样本库", filename, err)
+	}
+	return nil
+}
+
+// LoadFromBytes 从JSON数据加载样本库，用于编入二进制的默认样本库
+func (sl *SampleLibrary) LoadFromBytes(data []byte) error {
+	sl.ExtractorVersion = ""
+	if err := json.Unmarshal(data, sl); err != nil {
+		return err
+	}
+	sl.resolvePaths("")
+	sl.normalizeEmotions()
+	if err := sl.checkExtractorVersion(); err != nil {
+		log.Printf("警告: 样本库: %v，请重新构建样本库", err)
+	}
+	return nil
+}
+
+// checkExtractorVersion 样本库的特征提取版本与当前版本不一致时返回错误，此时样本特征与实时提取的特征不可比
+func (sl *SampleLibrary) checkExtractorVersion() error {
+	if sl.ExtractorVersion == FeatureExtractorVersion {
+		return nil
+	}
+	version := sl.ExtractorVersion
+	if version == "" {
+		version = "unversioned"
+	}
+	return fmt.Errorf("library features were extracted by extractor version %s, current version is %s", version, FeatureExtractorVersion)
+}
+
+// normalizeEmotions 将样本库中的情感ID规范化，别名与规范ID的样本合并后重新计算统计信息
+func (sl *SampleLibrary) normalizeEmotions() {
+	changed := false
+	for emotion := range sl.Samples {
+		if NormalizeEmotionID(emotion) != emotion {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+
+	samples := make(map[string][]AudioSample, len(sl.Samples))
+	for emotion, list := range sl.Samples {
+		id := NormalizeEmotionID(emotion)
+		for _, sample := range list {
+			sample.Emotion = id
+			samples[id] = append(samples[id], sample)
+		}
+		if _, ok := samples[id]; !ok {
+			samples[id] = nil
+		}
+	}
+	sl.Samples = samples
+	sl.Statistics = make(map[string]EmotionStatistics)
+	sl.NeedUpdate = true
+	sl.updateStatistics()
 }
 
 // calculateEuclideanDistance 计算欧氏距离
@@ -168,10 +287,27 @@ func calculateEuclideanDistance(f1, f2 AudioFeature) float64 {
 			math.Pow(f1.RootMeanSquare-f2.RootMeanSquare, 2) +
 			math.Pow(f1.SpectralCentroid-f2.SpectralCentroid, 2) +
 			math.Pow(f1.SpectralRolloff-f2.SpectralRolloff, 2) +
-			math.Pow(f1.FundamentalFreq-f2.FundamentalFreq, 2),
+			math.Pow(f1.FundamentalFreq-f2.FundamentalFreq, 2) +
+			customSquaredDistance(f1, f2, nil, 0),
 	)
 }
 
+// customSquaredDistance 自定义特征差值的平方和，stdDev 不为nil时各差值先除以对应的标准差（加 epsilon）
+func customSquaredDistance(feature, mean AudioFeature, stdDev *AudioFeature, epsilon float64) float64 {
+	featureRegistryMu.RLock()
+	defer featureRegistryMu.RUnlock()
+
+	total := 0.0
+	for _, custom := range customFeatureList {
+		d := feature.Custom[custom.name] - mean.Custom[custom.name]
+		if stdDev != nil {
+			d /= stdDev.Custom[custom.name] + epsilon
+		}
+		total += d * d
+	}
+	return total
+}
+
 // calculateMahalanobisDistance 计算马氏距离
 func calculateMahalanobisDistance(feature, mean, stdDev AudioFeature) float64 {
 	const epsilon = 1e-10 // 避免除以零
@@ -185,6 +321,7 @@ func calculateMahalanobisDistance(feature, mean, stdDev AudioFeature) float64 {
 			math.Pow((feature.RootMeanSquare-mean.RootMeanSquare)/(stdDev.RootMeanSquare+epsilon), 2) +
 			math.Pow((feature.SpectralCentroid-mean.SpectralCentroid)/(stdDev.SpectralCentroid+epsilon), 2) +
 			math.Pow((feature.SpectralRolloff-mean.SpectralRolloff)/(stdDev.SpectralRolloff+epsilon), 2) +
-			math.Pow((feature.FundamentalFreq-mean.FundamentalFreq)/(stdDev.FundamentalFreq+epsilon), 2),
+			math.Pow((feature.FundamentalFreq-mean.FundamentalFreq)/(stdDev.FundamentalFreq+epsilon), 2) +
+			customSquaredDistance(feature, mean, &stdDev, epsilon),
 	)
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// SDK 生命周期：InitializeSDK/ReleaseSDK 按引用计数共享一个实例，配置不同的初始化返回失败；UpdateSDKConfig 在锁外
+// 构建新引擎后在音频块之间替换，引擎级设置对已有的流立即生效，会话级默认值只影响之后创建的流。
+
+// updateMu 串行化配置更新，构建新引擎期间不持有 mu，不阻塞音频处理
+var updateMu sync.Mutex
+
+// validateSDKConfig 校验SDK配置中与样本库、引擎无关的部分
+func validateSDKConfig(config AudioStreamConfig) error {
+	if config.SampleRate < MinSampleRate || config.SampleRate > MaxSampleRate {
+		return fmt.Errorf("invalid sample rate %d", config.SampleRate)
+	}
+	if config.BufferSize <= 0 {
+		return fmt.Errorf("invalid buffer size %d", config.BufferSize)
+	}
+	if config.SampleLibraryPath == "" && config.BundlePath == "" {
+		return fmt.Errorf("sample library path or bundle not specified")
+	}
+	if _, err := ParseBundlePublicKeys(config.BundlePublicKeys); err != nil {
+		return err
+	}
+	if err := validateResultBuffer(config.ResultBufferSize, config.OverflowPolicy, config.OverflowTimeoutMs); err != nil {
+		return fmt.Errorf("invalid result buffer: %v", err)
+	}
+	if config.Trigger != nil {
+		if err := config.Trigger.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadConfigProfile 加载配置中的领域配置但不应用：先读取资源包中的，设置了 DomainProfilePath 时以其为准，
+// 两者都没有时返回nil
+func loadConfigProfile(config AudioStreamConfig) (*DomainProfile, error) {
+	keys, err := ParseBundlePublicKeys(config.BundlePublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := loadBundleProfile(config.BundlePath, keys)
+	if err != nil {
+		return nil, err
+	}
+	if config.DomainProfilePath != "" {
+		if profile, err = LoadDomainProfile(config.DomainProfilePath); err != nil {
+			return nil, fmt.Errorf("failed to load domain profile: %v", err)
+		}
+	}
+	return profile, nil
+}
+
+// applyConfigProfile 应用配置中的领域配置，配置中没有领域配置时不修改当前领域配置
+func applyConfigProfile(config AudioStreamConfig) error {
+	profile, err := loadConfigProfile(config)
+	if err != nil || profile == nil {
+		return err
+	}
+	if err := SetDomainProfile(profile); err != nil {
+		return fmt.Errorf("failed to apply domain profile: %v", err)
+	}
+	return nil
+}
+
+// newSampleProcessor 创建与引擎共用样本库的样本处理器
+func newSampleProcessor(config AudioStreamConfig, engine *Engine) *SampleProcessor {
+	return &SampleProcessor{
+		Library:     engine.Library,
+		SampleRate:  config.SampleRate,
+		WindowSize:  config.BufferSize,
+		FFTSize:     2048, // 标准FFT大小
+		FrameLength: 20.0, // 20ms的帧长
+	}
+}
+
+// SDKConfig 返回当前生效的SDK配置，未初始化时第二个返回值为 false
+func SDKConfig() (AudioStreamConfig, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if sdk == nil {
+		return AudioStreamConfig{}, false
+	}
+	return sdk.Config, true
+}
+
+// ParseConfigUpdate 以当前配置为基础应用JSON形式的部分配置，未出现的字段保持不变，未知字段报错
+func ParseConfigUpdate(data []byte) (AudioStreamConfig, error) {
+	current, ok := SDKConfig()
+	if !ok {
+		return AudioStreamConfig{}, fmt.Errorf("SDK not initialized")
+	}
+	// 经JSON复制一份，更新不会写入当前配置共享的预筛选频带等切片
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return AudioStreamConfig{}, err
+	}
+	var config AudioStreamConfig
+	if err := json.Unmarshal(encoded, &config); err != nil {
+		return AudioStreamConfig{}, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return AudioStreamConfig{}, fmt.Errorf("config update: %v", err)
+	}
+	return config, nil
+}
+
+// UpdateSDKConfig 在运行期间替换SDK配置，语义见文件开头；失败时原配置与引擎保持不变
+func UpdateSDKConfig(config AudioStreamConfig) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	mu.RLock()
+	current := sdk
+	var previous AudioStreamConfig
+	var events *EmotionEventHub
+	if current != nil {
+		previous, events = current.Config, current.Engine.events
+	}
+	mu.RUnlock()
+	if current == nil {
+		return fmt.Errorf("SDK not initialized")
+	}
+
+	if err := validateSDKConfig(config); err != nil {
+		return err
+	}
+	if config.SampleRate != previous.SampleRate || config.BufferSize != previous.BufferSize || config.Deterministic != previous.Deterministic {
+		return fmt.Errorf("sampleRate, bufferSize and deterministic cannot change at runtime; release and re-initialize the SDK")
+	}
+
+	// 领域配置是全局的：新配置先加载到局部变量并校验频率预设，再一次性替换，
+	// 并发的音频处理只会看到原配置或新配置；之后构建引擎失败时恢复原配置
+	profileMu.RLock()
+	profile := activeProfile
+	profileMu.RUnlock()
+	if config.DomainProfilePath != previous.DomainProfilePath || config.BundlePath != previous.BundlePath {
+		next, err := loadConfigProfile(config)
+		if err != nil {
+			return err
+		}
+		if err := next.checkPreset(config.Extractor.FrequencyPreset); err != nil {
+			return err
+		}
+		if err := SetDomainProfile(next); err != nil {
+			return fmt.Errorf("failed to apply domain profile: %v", err)
+		}
+	}
+	engine, err := LoadEngine(config)
+	if err != nil {
+		SetDomainProfile(profile)
+		return fmt.Errorf("failed to load engine: %v", err)
+	}
+	engine.events = events
+
+	mu.Lock()
+	defer mu.Unlock()
+	if sdk != current {
+		SetDomainProfile(profile)
+		return fmt.Errorf("SDK was released during the config update")
+	}
+	sdk.Config = config
+	sdk.Engine = engine
+	sdk.Processor = newSampleProcessor(config, engine)
+	return nil
+}
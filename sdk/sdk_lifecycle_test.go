@@ -0,0 +1,207 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSDKLifecycle 测试SDK的引用计数与重新初始化
+// 测试内容：
+// 1. 相同配置重复初始化增加引用计数，释放一次后流仍可使用，释放到0后实例失效
+// 2. 已初始化时以不同配置初始化失败，原实例不受影响
+// 3. 释放后再次初始化得到新实例，未初始化时释放无效果
+func TestSDKLifecycle(t *testing.T) {
+	testDir, err := setupTestEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestEnvironment(testDir)
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatal(err)
+	}
+	config := AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, SampleLibraryPath: testDir + "/sample_library.json", Deterministic: true}
+
+	ReleaseSDK()
+	if !InitializeSDK(config) || !InitializeSDK(config) {
+		t.Fatal("initializing twice with the same config should succeed")
+	}
+	other := config
+	other.BufferSize = 2048
+	if InitializeSDK(other) {
+		t.Error("initializing with a different config should fail")
+	}
+	if current, _ := SDKConfig(); current.BufferSize != 4096 {
+		t.Errorf("config after rejected init = %+v", current)
+	}
+	if err := StartAudioStream("shared"); err != nil {
+		t.Fatal(err)
+	}
+
+	ReleaseSDK()
+	if err := SendAudioChunk("shared", generateTestPCMData(0.1, 44100)); err != nil {
+		t.Errorf("stream should survive the first release: %v", err)
+	}
+	ReleaseSDK()
+	if _, ok := SDKConfig(); ok {
+		t.Fatal("SDK should be released when the count reaches zero")
+	}
+	if err := SendAudioChunk("shared", generateTestPCMData(0.1, 44100)); err == nil {
+		t.Error("send after the final release should fail")
+	}
+	ReleaseSDK()
+
+	if !InitializeSDK(other) {
+		t.Fatal("re-initializing after release should succeed")
+	}
+	defer ReleaseSDK()
+	if err := SendAudioChunk("shared", generateTestPCMData(0.1, 44100)); err == nil {
+		t.Error("streams of the released instance should be gone")
+	}
+}
+
+// TestUpdateSDKConfig 测试运行期间更新SDK配置
+// 测试内容：
+// 1. 部分JSON更新只修改给出的字段，未知字段与不可修改的字段报错，失败时原配置不变
+// 2. 更新后已有的流使用新引擎（新样本库）继续处理，情感事件通道保持不变
+// 3. 更新与音频处理并发时无数据竞争
+func TestUpdateSDKConfig(t *testing.T) {
+	testDir, err := setupTestEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestEnvironment(testDir)
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatal(err)
+	}
+	config := AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, SampleLibraryPath: testDir + "/sample_library.json"}
+
+	if _, err := ParseConfigUpdate([]byte(`{}`)); err == nil {
+		t.Error("update without SDK should fail")
+	}
+	if !InitializeSDK(config) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	defer ReleaseSDK()
+	if err := StartAudioStream("live"); err != nil {
+		t.Fatal(err)
+	}
+	mu.RLock()
+	oldEngine := sdk.Engine
+	mu.RUnlock()
+
+	for _, bad := range []string{`{"sampleRate":16000}`, `{"strategy":"nope"}`, `{"sampleLibraryPath":"missing.json"}`} {
+		update, err := ParseConfigUpdate([]byte(bad))
+		if err == nil {
+			err = UpdateSDKConfig(update)
+		}
+		if err == nil {
+			t.Errorf("update %s should fail", bad)
+		}
+	}
+	if _, err := ParseConfigUpdate([]byte(`{"smaplerate":1}`)); err == nil || !strings.Contains(err.Error(), "smaplerate") {
+		t.Errorf("unknown field error = %v", err)
+	}
+	if current, _ := SDKConfig(); current != config {
+		t.Fatalf("config after failed updates = %+v", current)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			SendAudioChunk("live", generateTestPCMData(0.1, 44100))
+		}
+	}()
+	update, err := ParseConfigUpdate([]byte(`{"healthChecks":true,"preFilter":{"minRelativePower":0.2},"lang":"zh"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSDKConfig(update); err != nil {
+		t.Fatalf("UpdateSDKConfig() error = %v", err)
+	}
+	wg.Wait()
+
+	current, _ := SDKConfig()
+	if !current.HealthChecks || current.PreFilter == nil || current.PreFilter.MinRelativePower != 0.2 || current.Lang != "zh" || current.SampleLibraryPath != config.SampleLibraryPath {
+		t.Errorf("config after update = %+v", current)
+	}
+	mu.RLock()
+	newEngine := sdk.Engine
+	mu.RUnlock()
+	if newEngine == oldEngine || newEngine.events != oldEngine.events || len(newEngine.Library.Samples) == 0 {
+		t.Error("update should swap in a new engine sharing the event hub")
+	}
+	if err := SendAudioChunk("live", generateTestPCMData(0.1, 44100)); err != nil {
+		t.Errorf("existing stream after update: %v", err)
+	}
+	if stats, err := AudioStreamStats("live"); err != nil || stats.Health == nil {
+		t.Errorf("stats after enabling health checks = %+v, %v", stats, err)
+	}
+}
+
+// TestUpdateSDKConfigProfile 测试运行期间更新领域配置
+// 测试内容：
+// 1. 切换领域配置时并发读取只会看到原配置或新配置，不会短暂回到内置配置
+// 2. 新领域配置中不存在配置指定的频率预设时更新失败，原领域配置保持生效
+func TestUpdateSDKConfigProfile(t *testing.T) {
+	testDir, err := setupTestEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestEnvironment(testDir)
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetDomainProfile(nil) })
+	config := AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, SampleLibraryPath: testDir + "/sample_library.json", DomainProfilePath: "profiles/dog.json"}
+	if !InitializeSDK(config) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	defer ReleaseSDK()
+
+	done := make(chan struct{})
+	var sawBuiltin bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if CurrentDomainProfile() == defaultProfile {
+					sawBuiltin = true
+				}
+			}
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		path := []string{"profiles/cat.json", "profiles/dog.json"}[i%2]
+		update, err := ParseConfigUpdate([]byte(`{"domainProfilePath":"` + path + `"}`))
+		if err == nil {
+			err = UpdateSDKConfig(update)
+		}
+		if err != nil {
+			t.Fatalf("update to %s: %v", path, err)
+		}
+	}
+	close(done)
+	wg.Wait()
+	if sawBuiltin {
+		t.Error("a concurrent reader saw the built-in profile during the update")
+	}
+
+	update, err := ParseConfigUpdate([]byte(`{"domainProfilePath":"profiles/cat.json","extractor":{"frequencyPreset":"small"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSDKConfig(update); err == nil || !strings.Contains(err.Error(), "small") {
+		t.Errorf("update with a preset missing from the new profile error = %v", err)
+	}
+	if name := CurrentDomainProfile().Name; name != "dog" {
+		t.Errorf("profile after failed update = %q, want dog", name)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// /api/send 收到未经 /start 的流时按策略处理：auto-create（默认）按默认配置在会话存储中创建会话，
+// reject 返回 404 与错误码 stream_not_found。
+
+// 会话创建策略
+const (
+	SendPolicyAutoCreate = "auto-create" // 按默认配置创建会话（默认）
+	SendPolicyReject     = "reject"      // 拒绝，客户端需先调用 /start
+)
+
+// SetSendPolicy 设置 /api/send 收到未创建的流时的处理策略，为空时为 auto-create
+func (s *AudioServer) SetSendPolicy(policy string) error {
+	switch policy {
+	case "":
+		policy = SendPolicyAutoCreate
+	case SendPolicyAutoCreate, SendPolicyReject:
+	default:
+		return fmt.Errorf("unknown send policy %q (available: %s, %s)", policy, SendPolicyAutoCreate, SendPolicyReject)
+	}
+	s.sendPolicy = policy
+	return nil
+}
+
+// ensureStream 确认流已通过 /start 创建，未创建时按策略以默认配置创建或写出错误响应并返回 false
+func (s *AudioServer) ensureStream(w http.ResponseWriter, r *http.Request, streamID string) bool {
+	store, _ := s.sessionStore(streamID)
+	_, ok, err := store.Session(streamID)
+	if err != nil {
+		// 存储暂时不可用时不拦截数据，与 syncStream 的处理一致
+		log.Printf("读取会话存储失败 stream=%s: %v", streamID, err)
+		return true
+	}
+	if ok {
+		return true
+	}
+
+	if s.sendPolicy == SendPolicyReject {
+		writeError(w, r, http.StatusNotFound, ErrCodeStreamNotFound, "会话不存在，请先调用 /start", streamID)
+		return false
+	}
+	if err := s.startStream(streamID, StreamSettings{}); err != nil {
+		log.Printf("自动创建会话失败: StreamID=%s, %v", streamID, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "保存会话失败", err.Error())
+		return false
+	}
+	log.Printf("自动创建会话: StreamID=%s（未调用 /start，使用默认配置）", streamID)
+	return true
+}
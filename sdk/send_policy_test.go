@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSendPolicy 测试 /api/send 的会话创建策略
+// 测试内容：
+// 1. 默认 auto-create：未 /start 的流在会话存储中按默认配置创建，已创建的流不受影响
+// 2. reject：未 /start 的流返回 404 与 stream_not_found，/start 后正常处理，/stop 后再次拒绝
+// 3. 未知策略返回错误
+func TestSendPolicy(t *testing.T) {
+	send := func(server *AudioServer, streamID string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{"streamId": streamID, "data": generateTestAudio(440, 0.1, 8000)})
+		rec := httptest.NewRecorder()
+		server.handleSend(rec, httptest.NewRequest(http.MethodPost, "/send", strings.NewReader(string(body))))
+		return rec
+	}
+	post := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	server := NewAudioServer(NewMockAudioProcessor())
+	if rec := send(server, "auto"); rec.Code != http.StatusOK {
+		t.Fatalf("auto-create send = %d %s", rec.Code, rec.Body)
+	}
+	stored, ok, err := server.store.Session("auto")
+	if err != nil || !ok || stored.Token == "" {
+		t.Fatalf("auto-created session = %+v, %v, %v", stored, ok, err)
+	}
+	if rec := send(server, "auto"); rec.Code != http.StatusOK {
+		t.Fatalf("second send = %d", rec.Code)
+	}
+	if again, _, _ := server.store.Session("auto"); again.Token != stored.Token {
+		t.Error("existing session should not be recreated")
+	}
+
+	if err := server.SetSendPolicy(SendPolicyReject); err != nil {
+		t.Fatal(err)
+	}
+	rec := send(server, "unknown")
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if rec.Code != http.StatusNotFound || apiErr.Code != ErrCodeStreamNotFound || !strings.Contains(apiErr.Message, "/start") {
+		t.Errorf("reject send = %d %+v", rec.Code, apiErr)
+	}
+	if _, ok, _ := server.store.Session("unknown"); ok {
+		t.Error("rejected stream should not be created")
+	}
+	if rec := post(server.handleStart, `{"streamId":"started"}`); rec.Code != http.StatusOK {
+		t.Fatalf("start = %d %s", rec.Code, rec.Body)
+	}
+	if rec := send(server, "started"); rec.Code != http.StatusOK {
+		t.Errorf("send after /start = %d %s", rec.Code, rec.Body)
+	}
+	post(server.handleStop, `{"streamId":"started"}`)
+	if rec := send(server, "started"); rec.Code != http.StatusNotFound {
+		t.Errorf("send after /stop = %d, want 404", rec.Code)
+	}
+
+	if err := server.SetSendPolicy("lazy"); err == nil {
+		t.Error("unknown policy should be rejected")
+	}
+}
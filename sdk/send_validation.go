@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// /api/send 请求校验：处理前校验 streamId 的格式、data 的类型与长度以及每个样本，第一个不合法的位置以具体的错误说明
+// 返回（如 "data[512] is a string"），错误码为 invalid_request，超长的音频块为 payload_too_large。
+
+// MaxStreamIDLength streamId 的最大长度
+const MaxStreamIDLength = 128
+
+// validStreamID 合法的 streamId：字母、数字与 _ - . : / @
+var validStreamID = regexp.MustCompile(`^[A-Za-z0-9_.:/@-]+$`)
+
+// streamId 校验错误
+var (
+	ErrMissingStreamID = errors.New("streamId is required")
+	ErrInvalidStreamID = errors.New("invalid streamId")
+)
+
+// validateStreamID 校验 streamId 的长度与字符
+func validateStreamID(streamID string) error {
+	switch {
+	case streamID == "":
+		return ErrMissingStreamID
+	case len(streamID) > MaxStreamIDLength:
+		return fmt.Errorf("streamId is %d characters, limit %d: %w", len(streamID), MaxStreamIDLength, ErrInvalidStreamID)
+	case !validStreamID.MatchString(streamID):
+		return fmt.Errorf("streamId %q may only contain letters, digits and _ - . : / @: %w", streamID, ErrInvalidStreamID)
+	}
+	return nil
+}
+
+// jsonTypeName 解码后的JSON值的类型名，用于错误说明
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// sendErrorStatus 请求校验错误对应的状态码与错误码
+func sendErrorStatus(err error) (int, string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrMissingStreamID):
+		return http.StatusBadRequest, ErrCodeMissingStreamID
+	case errors.Is(err, ErrInvalidStreamID):
+		return http.StatusBadRequest, ErrCodeInvalidStreamID
+	case errors.Is(err, ErrAudioTooLong), errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge
+	default:
+		return http.StatusBadRequest, ErrCodeInvalidRequest
+	}
+}
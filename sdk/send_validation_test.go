@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestSendValidation 测试 /api/send 请求校验
+// 测试内容：
+// 1. 缺少或格式不合法的 streamId 被拒绝
+// 2. 非数组、空数组与非数字样本返回指出位置与类型的错误，不再按0处理
+// 3. 超长音频块返回 payload_too_large 与 413
+// 4. 合法请求正常解析
+func TestSendValidation(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr error
+		message string
+	}{
+		{`{"data":[0.1]}`, ErrMissingStreamID, "streamId is required"},
+		{`{"streamId":"cat 1","data":[0.1]}`, ErrInvalidStreamID, "may only contain"},
+		{`{"streamId":"` + strings.Repeat("a", MaxStreamIDLength+1) + `","data":[0.1]}`, ErrInvalidStreamID, "limit 128"},
+		{`{"streamId":"cat1"}`, nil, "data is required"},
+		{`{"streamId":"cat1","data":{"0":1}}`, nil, "data is an object"},
+		{`{"streamId":"cat1","data":[]}`, nil, "data is empty"},
+		{`{"streamId":"cat1","data":[0.1,"0.5"]}`, ErrInvalidSample, "data[1] is a string"},
+		{`{"streamId":"cat1","data":[0.1,0.2,null]}`, ErrInvalidSample, "data[2] is null"},
+		{`{"streamId":"cat1","data":[true]}`, ErrInvalidSample, "data[0] is a boolean"},
+	}
+	for _, tt := range tests {
+		_, _, err := decodeSendAudioRequest(strings.NewReader(tt.body))
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%.60s: error = %v, want %q", tt.body, err, tt.message)
+			continue
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%.60s: error = %v, want %v", tt.body, err, tt.wantErr)
+		}
+	}
+
+	req, samples, err := decodeSendAudioRequest(strings.NewReader(`{"streamId":"home:cat-1/mic@2","data":[0.1,-0.2]}`))
+	if err != nil || req.StreamID != "home:cat-1/mic@2" || len(samples) != 2 || samples[1] != -0.2 {
+		t.Errorf("valid request = %+v, %v, %v", req, samples, err)
+	}
+
+	server := NewAudioServer(NewMockAudioProcessor())
+	send := func(body string) (*httptest.ResponseRecorder, APIError) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.handleSend(rec, httptest.NewRequest(http.MethodPost, "/send", strings.NewReader(body)))
+		var apiErr APIError
+		if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+			t.Fatalf("body = %q, want an error envelope", rec.Body)
+		}
+		return rec, apiErr
+	}
+	if rec, apiErr := send(`{"streamId":"cat1","data":[0.1,"x"]}`); rec.Code != http.StatusBadRequest || apiErr.Code != ErrCodeInvalidRequest || !strings.Contains(apiErr.Details, "data[1] is a string") {
+		t.Errorf("string sample = %d %+v", rec.Code, apiErr)
+	}
+	if rec, apiErr := send(`{"streamId":"a b","data":[0.1]}`); rec.Code != http.StatusBadRequest || apiErr.Code != ErrCodeInvalidStreamID {
+		t.Errorf("invalid streamId = %d %+v", rec.Code, apiErr)
+	}
+	huge := "[" + strings.Repeat("0,", MaxSendSamples) + "0]"
+	if rec, apiErr := send(`{"streamId":"cat1","data":` + huge + `}`); rec.Code != http.StatusRequestEntityTooLarge || apiErr.Code != ErrCodePayloadTooLarge || !strings.Contains(apiErr.Details, "chunk exceeds 10s") {
+		t.Errorf("huge chunk = %d %+v", rec.Code, apiErr)
+	}
+}
+
+// TestWebSocketSampleValidation 测试WebSocket音频消息的样本校验
+// 测试内容：
+// 1. {"data": [...]} 与纯数组中的非数字样本返回与 /api/send 相同的错误说明，不再按0处理
+// 2. 出错后连接保持可用，合法的音频块正常得到结果
+func TestWebSocketSampleValidation(t *testing.T) {
+	for _, tt := range []struct{ message, want string }{
+		{`{"data":[0.1,0.2,"x"]}`, "data[2] is a string"},
+		{`[0.1,null]`, "data[1] is null"},
+		{`{"data":"0.1"}`, "data is a string"},
+		{`"0.1"`, "message is a string"},
+	} {
+		if _, err := decodeWebSocketAudio([]byte(tt.message)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("decodeWebSocketAudio(%s) error = %v, want %q", tt.message, err, tt.want)
+		}
+	}
+	if data, err := decodeWebSocketAudio([]byte(`{"type":"ping"}`)); err != nil || data != nil {
+		t.Errorf("message without data = %v, %v, want no audio", data, err)
+	}
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	server := NewAudioServer(engine)
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var reply map[string]interface{}
+	if err := conn.ReadJSON(&reply); err != nil || reply["type"] != "init" {
+		t.Fatalf("init = %v, %v", reply, err)
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"data":[0.1,"0.5"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	reply = nil
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply["type"] != "error" || !strings.Contains(reply["error"].(string), "data[1] is a string") {
+		t.Errorf("string sample reply = %v", reply)
+	}
+
+	chunk, _ := json.Marshal(map[string]interface{}{"data": generateTestAudio(440, 1, 44100)})
+	if err := conn.WriteMessage(websocket.TextMessage, chunk); err != nil {
+		t.Fatal(err)
+	}
+	reply = nil
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply["type"] == "error" {
+		t.Errorf("valid chunk after an error = %v", reply)
+	}
+}
@@ -0,0 +1,1100 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// AudioProcessor 音频处理接口，服务端只通过该接口驱动处理流程
+// 实际部署使用 Engine（与CGO接口同一条流水线）
+type AudioProcessor interface {
+	ProcessAudio(streamID string, data []float64) ([]byte, error)
+	ProcessAudioRequest(streamID, requestID string, data []float64) ([]byte, error)
+	ConfigureStream(streamID string, settings StreamSettings) error
+	SetStreamLanguage(streamID string, lang string)
+	SetStreamDebug(streamID string, enabled bool)
+	StopStream(streamID string)
+	Events() *EmotionEventHub
+	AnalyzeFile(audio *AudioData, settings StreamSettings) (*FileAnalysis, error)
+	Emotions() []string
+}
+
+// LibraryUpdater 支持运行时替换样本库的处理器（Engine）
+type LibraryUpdater interface {
+	SetLibrary(library *SampleLibrary) error
+}
+
+// AudioServer 音频分析HTTP/WebSocket服务
+type AudioServer struct {
+	processor  AudioProcessor
+	recorder   *SessionRecorder    // 请求录制，为nil时不录制
+	fetcher    *RemoteAudioFetcher // /analyze-file 按URL下载录音
+	jobs       *JobQueue           // 批量分析任务队列，为nil时不提供 /jobs 接口
+	adminToken string              // 管理接口令牌，为空时不启用管理接口
+	tls        TLSOptions          // Start 使用的TLS证书，未设置时以HTTP监听
+	reloader   *ConfigReloader     // /admin/reload 使用的配置加载器，为nil时不支持重新加载
+	rebuildMu  sync.Mutex          // 同一时间只允许一次样本库重建
+	sendPolicy string              // /api/send 收到未创建的流时的处理策略
+
+	storeMu      sync.Mutex             // 保护 store 与 localStreams
+	store        SessionStore           // 会话配置、缓冲区与最新结果，默认保存在进程内存
+	localStreams map[string]localStream // 本副本上各流与存储的对应关系
+
+	timelines catTimelines       // 按猫咪汇总的多设备结果时间线
+	pauses    streamPauses       // 已暂停的流
+	distress  distressMonitor    // 持续不适告警
+	baselines *CatBaselineStore  // 猫咪长期基线，为nil时不累积
+	history   *ResultHistory     // 结果历史，为nil时不记录
+	hooks     *ResultHooks       // 结果钩子，为nil时不改写结果
+	labeling  *LabelingQueue     // 低置信度片段标注，为nil时不提供标注接口
+	energy    *EnergyCalibration // 能量校准，为nil时不提供能量报告接口
+	chunks    chunkSequences     // 各流的音频块序号，用于丢弃重发的块
+	videos    videoLinks         // 各流关联的视频片段
+
+	activityMu sync.Mutex                 // 保护 activity
+	activity   map[string]*StreamActivity // 调试面板展示的会话活动 streamID -> 活动
+
+	wsMu             sync.Mutex            // 保护以下WebSocket会话恢复、心跳与压缩配置
+	wsSessions       map[string]*wsSession // 恢复令牌 -> 会话
+	resumeGrace      time.Duration         // 断线后会话保留时长，0表示不支持恢复
+	pingInterval     time.Duration         // WebSocket心跳间隔，0表示不发送心跳
+	pongTimeout      time.Duration         // 超过该时长无任何回应视为连接已断开
+	compression      bool                  // 是否协商 permessage-deflate 压缩
+	compressionLevel int                   // 压缩级别
+}
+
+// NewAudioServer 创建使用指定处理器的服务
+func NewAudioServer(processor AudioProcessor) *AudioServer {
+	return &AudioServer{
+		processor:        processor,
+		sendPolicy:       SendPolicyAutoCreate,
+		store:            NewMemorySessionStore(),
+		localStreams:     make(map[string]localStream),
+		fetcher:          DefaultRemoteAudioFetcher(),
+		resumeGrace:      DefaultResumeGrace,
+		pingInterval:     DefaultPingInterval,
+		pongTimeout:      DefaultPongTimeout,
+		compression:      true,
+		compressionLevel: DefaultCompressionLevel,
+	}
+}
+
+// SetRemoteFetcher 设置 /analyze-file 下载远程录音使用的下载器
+func (s *AudioServer) SetRemoteFetcher(fetcher *RemoteAudioFetcher) {
+	s.fetcher = fetcher
+}
+
+// SetRecorder 设置请求录制器，WebSocket消息会被写入录制文件
+func (s *AudioServer) SetRecorder(recorder *SessionRecorder) {
+	s.recorder = recorder
+}
+
+// SetJobQueue 设置批量分析任务队列
+func (s *AudioServer) SetJobQueue(jobs *JobQueue) {
+	s.jobs = jobs
+}
+
+// SetAdminToken 设置管理接口令牌，请求需携带 Authorization: Bearer <token>
+func (s *AudioServer) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SendAudioRequest 发送音频数据的请求
+type SendAudioRequest struct {
+	StreamID    string      `json:"streamId"`
+	Data        interface{} `json:"data"`        // 使用interface{}以支持多种格式
+	Lang        string      `json:"lang"`        // 可选：结果语言 en/zh/ja/es
+	CatID       string      `json:"catId"`       // 可选：猫咪ID，多个设备的结果按猫咪汇总到 /timeline
+	Seq         *uint64     `json:"seq"`         // 可选：音频块序号，用于丢弃重发的块并报告缺失的块（见 chunk_sequence.go）
+	CaptureTime *int64      `json:"captureTime"` // 可选：第一个样本在客户端时钟上的采集时间（毫秒时间戳，见 capture_clock.go）
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // 允许所有来源，仅用于测试
+	},
+}
+
+// Start 在默认路由上注册全部接口（见 registerRoutes）并启动服务
+func (s *AudioServer) Start(port int) error {
+	s.registerRoutes(http.DefaultServeMux)
+
+	// 启动服务器
+	addr := fmt.Sprintf(":%d", port)
+	scheme := "http"
+	if s.tls.Enabled() {
+		scheme = "https"
+	}
+	log.Printf("猫咪声音情感分析服务启动在 %s://localhost%s\n", scheme, addr)
+	return listenAndServe(addr, corsMiddleware(hideDiagnostics(http.DefaultServeMux)), s.tls)
+}
+
+// CORS中间件
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 设置CORS头
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+
+		// 处理预检请求
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// 继续处理实际请求
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleInit 初始化处理
+func (s *AudioServer) handleInit(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// handleStart 开始会话
+func (s *AudioServer) handleStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	var req struct {
+		StreamID        string             `json:"streamId"`
+		Format          StreamFormat       `json:"format"`          // 可选：数据格式，未声明时由处理器按默认格式处理
+		FrequencyPreset string             `json:"frequencyPreset"` // 可选：kitten/adult/large-breed
+		Strategy        string             `json:"strategy"`        // 可选：处理策略 standard/low-latency/accurate/template
+		CatID           string             `json:"catId"`           // 可选：猫咪ID
+		CatName         string             `json:"catName"`         // 可选：猫咪名字，用于提示短语
+		Context         string             `json:"context"`         // 可选：上下文标签，如 feeding
+		Lang            string             `json:"lang"`            // 可选：结果语言 en/zh/ja/es
+		Priors          *ContextPriors     `json:"priors"`          // 可选：时间与上下文先验
+		Hints           []string           `json:"hints"`           // 可选：情境提示，如 pre-feeding、vet-visit
+		EmotionPriors   map[string]float64 `json:"emotionPriors"`   // 可选：直接注入的情感先验倍数
+		DebounceMs      int                `json:"debounceMs"`      // 可选：情感变化事件去抖时长（毫秒）
+		Debug           bool               `json:"debug"`           // 可选：结果中附带逐窗口特征与评分
+		FeatureVector   bool               `json:"featureVector"`   // 可选：结果中附带最终特征向量
+		Video           *VideoLink         `json:"video"`           // 可选：关联的视频片段，结果中附带在片段中的位置
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxSendBodyBytes))
+	if err != nil || json.Unmarshal(body, &req) != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "无效请求格式", "")
+		return
+	}
+
+	if req.StreamID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingStreamID, "StreamID不能为空", "")
+		return
+	}
+	if req.Video != nil {
+		if err := req.Video.Validate(); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "视频关联无效", err.Error())
+			return
+		}
+	}
+
+	settings := StreamSettings{
+		Format:          req.Format,
+		FrequencyPreset: req.FrequencyPreset,
+		Strategy:        req.Strategy,
+		Cat:             CatProfile{ID: req.CatID, Name: req.CatName, Priors: req.Priors},
+		Context:         req.Context,
+		Hints:           req.Hints,
+		EmotionPriors:   req.EmotionPriors,
+		Lang:            req.Lang,
+		EventDebounce:   time.Duration(req.DebounceMs) * time.Millisecond,
+		Debug:           req.Debug,
+		FeatureVector:   req.FeatureVector,
+	}
+	if err := s.processor.ConfigureStream(req.StreamID, settings); err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "会话配置无效", err.Error())
+		return
+	}
+
+	// 创建新会话
+	if err := s.startStream(req.StreamID, settings); err != nil {
+		log.Printf("保存会话失败: StreamID=%s, %v", req.StreamID, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "保存会话失败", "")
+		return
+	}
+	s.trackActivity(req.StreamID, "http", 0, nil)
+	s.bindCat(req.StreamID, req.CatID)
+	s.resetChunks(req.StreamID)
+	s.forgetVideo(req.StreamID)
+	if req.Video != nil {
+		s.linkVideo(req.StreamID, *req.Video, time.Now())
+	}
+	// 重新开始的会话录制到新文件
+	if s.recorder != nil {
+		s.recorder.CloseSession(req.StreamID)
+	}
+	s.recordHTTP("/start", req.StreamID, body)
+	log.Printf("创建新会话: StreamID=%s", req.StreamID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// handleSend 处理发送音频数据
+func (s *AudioServer) handleSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	requestID := requestIDFrom(r)
+	w.Header().Set(RequestIDHeader, requestID)
+
+	// 解析请求并转换音频数据，限制请求体大小防止超大数组耗尽内存；开启录制时保留请求体
+	var body bytes.Buffer
+	req, audioData, err := decodeSendAudioRequest(io.TeeReader(http.MaxBytesReader(w, r.Body, MaxSendBodyBytes), &body))
+	if err != nil {
+		log.Printf("音频块 request=%s 请求无效: %v", requestID, err)
+		status, code := sendErrorStatus(err)
+		writeError(w, r, status, code, "无效请求格式", err.Error())
+		return
+	}
+
+	if !s.ensureStream(w, r, req.StreamID) {
+		return
+	}
+
+	if s.isPaused(req.StreamID) {
+		writeError(w, r, http.StatusConflict, ErrCodeStreamPaused, "会话已暂停", "")
+		return
+	}
+
+	if req.Lang != "" {
+		s.processor.SetStreamLanguage(req.StreamID, req.Lang)
+	}
+	if debug, ok := debugRequested(r); ok {
+		s.processor.SetStreamDebug(req.StreamID, debug)
+	}
+	if req.CatID != "" {
+		s.bindCat(req.StreamID, req.CatID)
+	}
+
+	// 带序号的块：重发的块不再追加，直接确认；同一个流的块依次处理，处理失败时撤销登记
+	var gap *ChunkGap
+	if req.Seq != nil {
+		defer s.serializeChunks(req.StreamID)()
+		var duplicate bool
+		if duplicate, gap = s.acceptChunk(req.StreamID, *req.Seq); duplicate {
+			log.Printf("音频块 request=%s stream=%s seq=%d 重复，已丢弃", requestID, req.StreamID, *req.Seq)
+			writeResponse(w, r, http.StatusOK, map[string]interface{}{
+				"status":    "duplicate",
+				"seq":       *req.Seq,
+				"requestId": requestID,
+			})
+			return
+		}
+		if gap != nil {
+			log.Printf("音频块 stream=%s 缺失序号 %d-%d", req.StreamID, gap.From, gap.To)
+		}
+	}
+
+	// 录制去重后实际处理的块，回放时不会重复追加
+	s.recordHTTP("/send", req.StreamID, body.Bytes())
+
+	// 处理音频，多副本部署时先按会话存储接续其他副本处理过的会话，写回前其他副本不处理该流
+	defer s.lockStream(req.StreamID)()
+	s.syncStream(req.StreamID)
+	result, err := s.processChunk(req.StreamID, requestID, audioData, req.CaptureTime)
+	if err != nil {
+		if req.Seq != nil {
+			s.rejectChunk(req.StreamID, *req.Seq, gap)
+		}
+		log.Printf("音频块 request=%s stream=%s 处理失败: %v", requestID, req.StreamID, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "处理音频失败", err.Error())
+		return
+	}
+	logChunk(requestID, req.StreamID, "http", len(audioData), result)
+	s.trackActivity(req.StreamID, "http", len(audioData), result)
+	result = s.applyHooks(req.StreamID, result, time.Now())
+	catID, counted := s.recordDetection(req.StreamID, result, time.Now())
+	result = s.applyBaseline(catID, counted, result, time.Now())
+	result = s.applyVideo(req.StreamID, result, time.Now())
+	s.checkDistress(req.StreamID, catID, result, time.Now())
+	s.recordHistory(req.StreamID, catID, result, time.Now())
+
+	if len(result) == 0 {
+		// 还没有结果，返回缓冲状态
+		response := map[string]interface{}{
+			"status":       "waiting",
+			"samplesCount": len(audioData),
+			"requestId":    requestID,
+		}
+		if req.Seq != nil {
+			response["seq"] = *req.Seq
+		}
+		if gap != nil {
+			response["gap"] = gap
+		}
+		writeResponse(w, r, http.StatusOK, response)
+		return
+	}
+
+	// 写回缓冲区；如果会话已开始，保存最新结果供 /recv 查询
+	s.saveStream(req.StreamID, requestID, result)
+	if req.Seq != nil {
+		result = appendJSONField(result, "seq", *req.Seq)
+	}
+	if gap != nil {
+		result = appendJSONField(result, "gap", gap)
+	}
+	writeJSONResponse(w, r, http.StatusOK, result)
+}
+
+// decodeSendAudioRequest 解析并校验 /api/send 请求体，转换其中的音频数据（见 send_validation.go）
+func decodeSendAudioRequest(body io.Reader) (SendAudioRequest, []float64, error) {
+	var req SendAudioRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return req, nil, err
+	}
+	audioData, err := decodeAudioData(req.Data)
+	if err != nil {
+		return req, nil, err
+	}
+	if req.CaptureTime != nil {
+		if err := validateCaptureTime(*req.CaptureTime); err != nil {
+			return req, nil, err
+		}
+	}
+	return req, audioData, validateStreamID(req.StreamID)
+}
+
+// decodeAudioData 将请求中的音频数据转换为浮点数组
+// 数据来自不可信的客户端：拒绝空数组、超长数组、非数字样本以及 NaN/Inf 等非法样本，错误说明指出第一个不合法的样本
+func decodeAudioData(data interface{}) ([]float64, error) {
+	var audioData []float64
+	switch data := data.(type) {
+	case []interface{}:
+		if len(data) > MaxSendSamples {
+			return nil, fmt.Errorf("data has %d samples, chunk exceeds %ds (%d samples): %w", len(data), MaxSendSamples/44100, MaxSendSamples, ErrAudioTooLong)
+		}
+		audioData = make([]float64, len(data))
+		for i, v := range data {
+			val, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("data[%d] is %s, want a number: %w", i, jsonTypeName(v), ErrInvalidSample)
+			}
+			audioData[i] = val
+		}
+	case []float64:
+		audioData = data
+	case nil:
+		return nil, fmt.Errorf("data is required")
+	default:
+		return nil, fmt.Errorf("data is %s, want an array of numbers", jsonTypeName(data))
+	}
+	if len(audioData) == 0 {
+		return nil, fmt.Errorf("data is empty")
+	}
+
+	return audioData, validateSamples(audioData)
+}
+
+// decodeWebSocketAudio 解析WebSocket消息中的音频数据，支持纯数组和 {"data": [...]} 两种格式
+// 非数字样本按 /api/send 的方式报告第一个不合法的位置（如 "data[512] is a string"）；没有 data 字段的消息返回空数据
+func decodeWebSocketAudio(message []byte) ([]float64, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(message, &decoded); err != nil {
+		return nil, err
+	}
+	switch decoded := decoded.(type) {
+	case []interface{}:
+		if len(decoded) == 0 {
+			return nil, nil
+		}
+		return decodeAudioData(decoded)
+	case map[string]interface{}:
+		data, ok := decoded["data"]
+		if !ok {
+			return nil, nil
+		}
+		return decodeAudioData(data)
+	default:
+		return nil, fmt.Errorf("message is %s, want an array of numbers or an object with data", jsonTypeName(decoded))
+	}
+}
+
+// validateSamples 检查样本数量与取值，后续特征提取无法处理 NaN/Inf
+func validateSamples(samples []float64) error {
+	if len(samples) > MaxSendSamples {
+		return fmt.Errorf("chunk exceeds %ds (%d samples): %w", MaxSendSamples/44100, MaxSendSamples, ErrAudioTooLong)
+	}
+	for i, v := range samples {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("data[%d] is %v: %w", i, v, ErrInvalidSample)
+		}
+	}
+	return nil
+}
+
+// handleEmotions 返回当前可识别的情感集合，显示名称的语言可通过 ?lang= 指定
+func (s *AudioServer) handleEmotions(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	lang := NormalizeLocale(r.URL.Query().Get("lang"))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"lang":     lang,
+		"emotions": DescribeEmotions(s.processor.Emotions(), lang),
+	})
+}
+
+// handleReceive 获取处理结果
+func (s *AudioServer) handleReceive(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	streamID := r.URL.Query().Get("streamId")
+	if streamID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingStreamID, "StreamID参数缺失", "")
+		return
+	}
+
+	// 获取会话的最新结果
+	store, _ := s.sessionStore(streamID)
+	result, ok, err := store.Result(streamID)
+	if err != nil {
+		log.Printf("读取会话结果失败: StreamID=%s, %v", streamID, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "读取会话结果失败", "")
+		return
+	}
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrCodeStreamNotFound, "会话不存在", "")
+		return
+	}
+
+	if result != nil {
+		writeJSONResponse(w, r, http.StatusOK, result)
+	} else {
+		writeJSONResponse(w, r, http.StatusOK, []byte("{}"))
+	}
+}
+
+// handleStop 停止会话
+func (s *AudioServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	// 解析请求参数
+	var body bytes.Buffer
+	decoder := json.NewDecoder(io.TeeReader(r.Body, &body))
+	var request struct {
+		StreamID string `json:"streamId"`
+	}
+	err := decoder.Decode(&request)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "解析请求参数失败", err.Error())
+		return
+	}
+
+	// 检查 StreamID 是否存在
+	if request.StreamID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingStreamID, "缺少 StreamID", "")
+		return
+	}
+
+	// 清理任何与此streamID相关的数据，停止前取得会话的健康汇总
+	log.Printf("停止会话 %s", request.StreamID)
+	health := s.streamHealth(request.StreamID)
+	s.processor.StopStream(request.StreamID)
+	s.stopStream(request.StreamID)
+	s.forgetActivity(request.StreamID)
+	s.bindCat(request.StreamID, "")
+	s.setPaused(request.StreamID, false)
+	chunks := s.chunkStats(request.StreamID)
+	s.resetChunks(request.StreamID)
+	videos := s.videoSummaries(request.StreamID)
+	s.forgetVideo(request.StreamID)
+	s.distress.forget(request.StreamID)
+	s.forgetHistory(request.StreamID)
+	s.forgetHooks(request.StreamID)
+	s.saveBaselines()
+	s.recordHTTP("/stop", request.StreamID, body.Bytes())
+	if s.recorder != nil {
+		s.recorder.CloseSession(request.StreamID)
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Success bool           `json:"success"`
+		Message string         `json:"message"`
+		Health  *HealthSummary `json:"health,omitempty"` // 声音健康汇总，引擎开启 HealthChecks 时提供
+		Chunks  *ChunkStats    `json:"chunks,omitempty"` // 音频块序号统计，客户端带序号时提供
+		Videos  []VideoSummary `json:"videos,omitempty"` // 各视频片段关联的结果，客户端关联视频时提供
+	}{
+		Success: true,
+		Message: "成功停止会话 " + request.StreamID,
+		Health:  health,
+		Chunks:  chunks,
+		Videos:  videos,
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "生成响应失败", err.Error())
+		return
+	}
+
+	w.Write(jsonResponse)
+}
+
+// handleEvents 以 Server-Sent Events 推送指定流的情感变化事件
+func (s *AudioServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	streamID := r.URL.Query().Get("streamId")
+	if streamID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingStreamID, "StreamID参数缺失", "")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "不支持事件流", "")
+		return
+	}
+
+	events, unsubscribe := s.processor.Events().Subscribe(streamID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+	log.Printf("事件订阅建立: StreamID=%s", streamID)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Printf("事件订阅关闭: StreamID=%s", streamID)
+			return
+		case event := <-events:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType(event), event)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleAnalyzeFile 分析整段录音文件
+// 支持 multipart/form-data 上传（字段 file，WAV或MP3），或在表单/JSON中通过 url 字段指定录音地址；
+// 其余可选字段与 /start 相同
+func (s *AudioServer) handleAnalyzeFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	var req struct {
+		URL string `json:"url"` // 可选：录音地址，未上传文件时使用
+		AnalysisOptions
+	}
+
+	// 额外预留1MB给multipart边界与其他字段
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAnalyzeFileBytes+1<<20)
+
+	var data []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "无效请求格式", "")
+			return
+		}
+		req.URL = r.FormValue("url")
+		req.AnalysisOptions = analysisOptionsFromForm(r)
+
+		if file, _, err := r.FormFile("file"); err == nil {
+			data, err = io.ReadAll(io.LimitReader(file, MaxAnalyzeFileBytes+1))
+			file.Close()
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "读取上传文件失败", "")
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "无效请求格式", "")
+		return
+	}
+
+	if data == nil && req.URL != "" {
+		var err error
+		if data, err = s.fetcher.Fetch(req.URL); err != nil {
+			writeError(w, r, remoteFetchStatus(err), remoteFetchCode(err), "获取远程录音失败", err.Error())
+			return
+		}
+	}
+	if len(data) == 0 {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingParameter, "缺少录音文件或URL", "")
+		return
+	}
+	if len(data) > MaxAnalyzeFileBytes {
+		writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, "录音文件过大", "")
+		return
+	}
+
+	audio, err := decodeAudioFile(data)
+	if errors.Is(err, ErrAudioTooLong) {
+		writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, "录音时长超过限制", "")
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "无法解码录音文件", err.Error())
+		return
+	}
+
+	analysis, err := s.processor.AnalyzeFile(audio, req.settings())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "分析录音失败", err.Error())
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, analysis)
+}
+
+// handleSubmitJob 提交批量分析任务
+// multipart/form-data 中可包含多个 file 字段与多个 url 字段，JSON请求体为 {"urls": [...]}；
+// 其余可选字段与 /analyze-file 相同，返回任务ID后通过 /jobs/{id} 轮询结果
+func (s *AudioServer) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	if s.jobs == nil {
+		writeError(w, r, http.StatusServiceUnavailable, ErrCodeFeatureDisabled, "批量任务未启用", "")
+		return
+	}
+
+	var req struct {
+		URLs []string `json:"urls"`
+		AnalysisOptions
+	}
+	var uploads []JobUpload
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "无效请求格式", "")
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+		req.URLs = r.MultipartForm.Value["url"]
+		req.AnalysisOptions = analysisOptionsFromForm(r)
+
+		for _, header := range r.MultipartForm.File["file"] {
+			if header.Size > MaxAnalyzeFileBytes {
+				writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, "录音文件过大", header.Filename)
+				return
+			}
+			file, err := header.Open()
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "读取上传文件失败", "")
+				return
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "读取上传文件失败", "")
+				return
+			}
+			uploads = append(uploads, JobUpload{Name: header.Filename, Data: data})
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "无效请求格式", "")
+		return
+	}
+
+	job, err := s.jobs.Submit(uploads, req.URLs, req.AnalysisOptions)
+	if errors.Is(err, ErrQueueFull) || errors.Is(err, ErrQueueClosed) {
+		writeError(w, r, http.StatusServiceUnavailable, ErrCodeUnavailable, "任务队列暂不可用", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "提交任务失败", err.Error())
+		return
+	}
+
+	writeResponse(w, r, http.StatusAccepted, map[string]interface{}{
+		"jobId":  job.ID,
+		"status": job.Status,
+		"items":  len(job.Items),
+	})
+}
+
+// handleGetJob 查询批量分析任务：GET /jobs/{id}
+func (s *AudioServer) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	if s.jobs == nil {
+		writeError(w, r, http.StatusServiceUnavailable, ErrCodeFeatureDisabled, "批量任务未启用", "")
+		return
+	}
+
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	job, err := s.jobs.Get(id)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "任务不存在", "")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, job)
+}
+
+// checkAdmin 校验管理接口令牌，失败时写入错误响应并返回 false
+func (s *AudioServer) checkAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.adminToken == "" {
+		writeError(w, r, http.StatusForbidden, ErrCodeFeatureDisabled, "管理接口未启用", "")
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+		writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "未授权", "")
+		return false
+	}
+	return true
+}
+
+// handleRebuildLibrary 从服务器上按情感分目录的样本目录重建样本库并替换当前样本库
+// 请求体 {"dir": "emotion_samples", "output": "sample_library.json"}，output 可选，设置后同时导出到该文件。
+// 新样本库构建完成后才替换，构建失败时继续使用原样本库
+func (s *AudioServer) handleRebuildLibrary(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	if !s.checkAdmin(w, r) {
+		return
+	}
+
+	updater, ok := s.processor.(LibraryUpdater)
+	if !ok {
+		writeError(w, r, http.StatusNotImplemented, ErrCodeNotSupported, "当前处理器不支持替换样本库", "")
+		return
+	}
+
+	var req struct {
+		Dir    string `json:"dir"`
+		Output string `json:"output"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Dir == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "无效请求格式", "")
+		return
+	}
+
+	if !s.rebuildMu.TryLock() {
+		writeError(w, r, http.StatusConflict, ErrCodeConflict, "样本库正在重建", "")
+		return
+	}
+	defer s.rebuildMu.Unlock()
+
+	start := time.Now()
+	library, failures, err := BuildLibraryFromDirectory(req.Dir)
+	if err != nil {
+		log.Printf("重建样本库失败: %v", err)
+		writeError(w, r, http.StatusUnprocessableEntity, ErrCodeUnprocessable, "重建样本库失败", err.Error())
+		return
+	}
+	if req.Output != "" {
+		exporter := &SampleProcessor{Library: library}
+		if err := exporter.ExportLibrary(req.Output); err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "导出样本库失败", err.Error())
+			return
+		}
+	}
+	if err := updater.SetLibrary(library); err != nil {
+		writeError(w, r, http.StatusUnprocessableEntity, ErrCodeUnprocessable, "替换样本库失败", err.Error())
+		return
+	}
+
+	counts := make(map[string]int, len(library.Samples))
+	total := 0
+	for emotion, samples := range library.Samples {
+		counts[emotion] = len(samples)
+		total += len(samples)
+	}
+	failed := make([]map[string]string, len(failures))
+	for i, failure := range failures {
+		failed[i] = map[string]string{"path": failure.Path, "error": failure.Err.Error()}
+	}
+	log.Printf("样本库已重建并替换: 目录=%s, 样本数=%d, 情感数=%d, 失败文件=%d, 耗时=%v", req.Dir, total, len(counts), len(failed), time.Since(start))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"totalSamples": total,
+		"emotions":     counts,
+		"failed":       failed,
+	})
+}
+
+// remoteFetchStatus 远程下载错误对应的HTTP状态码
+func remoteFetchStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAudioTooLong):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrRemoteContentType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrRemoteFetch):
+		return http.StatusBadGateway
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// handleWebSocket 处理WebSocket连接
+func (s *AudioServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// 升级HTTP连接为WebSocket
+	conn, err := s.upgradeWebSocket(w, r)
+	if err != nil {
+		log.Printf("WebSocket升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(MaxSendBodyBytes)
+
+	// 带有效恢复令牌时接回断线前的会话，否则创建新会话
+	session, resumed := s.attachWebSocket(r.URL.Query().Get("resume"))
+	streamID := session.streamID
+	defer s.detachWebSocket(session)
+	log.Printf("WebSocket连接建立: StreamID=%s, 恢复=%t", streamID, resumed)
+
+	// 结果语言可通过 ?lang= 查询参数指定，恢复会话时未指定则沿用原语言
+	lang := r.URL.Query().Get("lang")
+	if resumed && lang == "" {
+		lang = session.lang
+	}
+	session.lang = lang
+	s.processor.SetStreamLanguage(streamID, lang)
+
+	// ?debug=1 时结果附带逐窗口特征与评分，恢复会话时未指定则沿用原设置
+	if debug, ok := debugRequested(r); ok {
+		session.debug = debug
+	}
+	s.processor.SetStreamDebug(streamID, session.debug || session.settings.Debug)
+
+	// 订阅本连接的情感变化事件，随结果一起推送
+	events, unsubscribe := s.processor.Events().Subscribe(streamID)
+	defer unsubscribe()
+
+	// 发送初始化消息，携带恢复令牌时客户端断线后可在宽限期内重连
+	initMsg := map[string]interface{}{
+		"type":     "init",
+		"streamId": streamID,
+		"resumed":  resumed,
+	}
+	if session.token != "" {
+		initMsg["resumeToken"] = session.token
+		initMsg["resumeGraceMs"] = s.resumeGrace.Milliseconds()
+	}
+	if err := conn.WriteJSON(initMsg); err != nil {
+		log.Printf("发送初始化消息失败: %v", err)
+		return
+	}
+
+	// 心跳：客户端停止响应时读取超时，连接关闭
+	alive, stopHeartbeat := s.startHeartbeat(conn, streamID)
+	defer stopHeartbeat()
+
+	// 处理接收的消息，音频开始前可以发送连接配置
+	for audioStarted := false; ; {
+		// 读取消息
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			log.Printf("读取WebSocket消息失败: %v", err)
+			break
+		}
+		alive()
+
+		audioStarted = s.handleWebSocketMessage(session, message, newRequestID(), audioStarted, resumed, conn.WriteJSON)
+
+		// 推送本次处理产生的情感变化事件
+		for drained := false; !drained; {
+			select {
+			case event := <-events:
+				if err := conn.WriteMessage(websocket.TextMessage, event); err != nil {
+					log.Printf("发送WebSocket事件失败: %v", err)
+				}
+			default:
+				drained = true
+			}
+		}
+	}
+
+	log.Printf("WebSocket连接关闭: StreamID=%s", streamID)
+}
+
+// handleWebSocketMessage 处理一条WebSocket消息：音频开始前的连接配置或一个音频块，回复交给 reply 发送，
+// 返回音频是否已经开始。连接与录制回放（见 recorder.go）共用该处理路径
+func (s *AudioServer) handleWebSocketMessage(session *wsSession, message []byte, requestID string, audioStarted, resumed bool, reply func(v interface{}) error) bool {
+	streamID := session.streamID
+	if !audioStarted {
+		if config, ok := decodeWebSocketConfig(message); ok {
+			s.recordWebSocket(streamID, message)
+			if err := reply(s.configureWebSocket(session, config, resumed)); err != nil {
+				log.Printf("发送配置确认失败: %v", err)
+			}
+			return false
+		}
+	}
+
+	// 解析音频数据
+	audioData, err := decodeWebSocketAudio(message)
+	if err != nil {
+		log.Printf("解析WebSocket消息失败: %v", err)
+		if err := reply(map[string]interface{}{"type": "error", "error": err.Error()}); err != nil {
+			log.Printf("发送解析错误失败: %v", err)
+		}
+		return true
+	}
+	meta, err := decodeWebSocketChunkMeta(message)
+	if err != nil {
+		if err := reply(map[string]interface{}{"type": "error", "error": err.Error()}); err != nil {
+			log.Printf("发送序号错误失败: %v", err)
+		}
+		return true
+	}
+	seq := meta.Seq
+
+	if len(audioData) == 0 {
+		return true
+	}
+	if s.isPaused(streamID) {
+		if err := reply(map[string]interface{}{"type": "error", "error": ErrStreamPaused.Error()}); err != nil {
+			log.Printf("发送暂停提示失败: %v", err)
+		}
+		return true
+	}
+
+	// 带序号的块：重发的块只回复 duplicate，跳号时先报告缺失的范围；同一个流的块依次处理
+	var gap *ChunkGap
+	if seq != nil {
+		defer s.serializeChunks(streamID)()
+		var duplicate bool
+		if duplicate, gap = s.acceptChunk(streamID, *seq); duplicate {
+			if err := reply(map[string]interface{}{"type": "duplicate", "seq": *seq}); err != nil {
+				log.Printf("发送重复确认失败: %v", err)
+			}
+			return true
+		}
+		if gap != nil {
+			log.Printf("音频块 stream=%s 缺失序号 %d-%d", streamID, gap.From, gap.To)
+			if err := reply(map[string]interface{}{"type": "gap", "seq": *seq, "gap": gap}); err != nil {
+				log.Printf("发送缺失报告失败: %v", err)
+			}
+		}
+	}
+
+	// 处理音频数据，录制去重后实际处理的块
+	s.recordWebSocket(streamID, message)
+	result, err := s.processChunk(streamID, requestID, audioData, meta.CaptureTime)
+	if err != nil {
+		if seq != nil {
+			s.rejectChunk(streamID, *seq, gap)
+		}
+		log.Printf("音频块 request=%s stream=%s 处理WebSocket音频失败: %v", requestID, streamID, err)
+		return true
+	}
+	logChunk(requestID, streamID, "ws", len(audioData), result)
+	s.trackActivity(streamID, "ws", len(audioData), result)
+	result = s.applyHooks(streamID, result, time.Now())
+	catID, counted := s.recordDetection(streamID, result, time.Now())
+	result = s.applyBaseline(catID, counted, result, time.Now())
+	result = s.applyVideo(streamID, result, time.Now())
+	s.checkDistress(streamID, catID, result, time.Now())
+	s.recordHistory(streamID, catID, result, time.Now())
+
+	// 如果有结果，发送回客户端
+	if result != nil {
+		var resultObj interface{}
+		json.Unmarshal(result, &resultObj)
+
+		response := map[string]interface{}{
+			"type":      "result",
+			"requestId": requestID,
+			"result":    resultObj,
+		}
+		if seq != nil {
+			response["seq"] = *seq
+		}
+
+		if err := reply(response); err != nil {
+			log.Printf("发送WebSocket结果失败: %v", err)
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLS 与 HTTP/2
+//
+// 部署在 HTTPS 站点上的网页应用不允许连接 ws:// 地址，只能使用 wss://。设置证书与私钥后
+// 服务以 HTTPS 监听，普通接口自动协商 HTTP/2，WebSocket 仍走 HTTP/1.1 升级。
+// 证书文件被替换（如 certbot 续期）后下一次握手自动加载新证书，无需重启服务。
+
+// TLSOptions 服务端TLS配置，CertFile 与 KeyFile 均为空时不启用TLS
+type TLSOptions struct {
+	CertFile string // PEM 证书（可包含中间证书链）
+	KeyFile  string // PEM 私钥
+}
+
+// Enabled 是否启用TLS
+func (o TLSOptions) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != ""
+}
+
+// Validate 检查证书与私钥是否成对设置
+func (o TLSOptions) Validate() error {
+	if o.Enabled() && (o.CertFile == "" || o.KeyFile == "") {
+		return fmt.Errorf("tls: both certificate and key files are required")
+	}
+	return nil
+}
+
+// certReloader 证书文件修改后在下一次握手时重新加载
+type certReloader struct {
+	options TLSOptions
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // 已加载证书与私钥中较新的修改时间
+}
+
+// newCertReloader 加载证书，证书或私钥无效时返回错误
+func newCertReloader(options TLSOptions) (*certReloader, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	r := &certReloader{options: options}
+	if _, err := r.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate 用于 tls.Config.GetCertificate，文件有更新时重新加载，加载失败时继续使用旧证书
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := r.latestModTime()
+	if err != nil && r.cert == nil {
+		return nil, err
+	}
+	if r.cert != nil && (err != nil || !modTime.After(r.modTime)) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.options.CertFile, r.options.KeyFile)
+	if err != nil {
+		if r.cert != nil {
+			log.Printf("重新加载TLS证书失败，继续使用旧证书: %v", err)
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("tls: %v", err)
+	}
+	if r.cert != nil {
+		log.Printf("TLS证书已更新: %s", r.options.CertFile)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return r.cert, nil
+}
+
+// latestModTime 证书与私钥文件中较新的修改时间
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.options.CertFile, r.options.KeyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// newHTTPServer 创建HTTP服务，启用TLS时配置证书加载，返回的服务需以 ListenAndServeTLS("", "") 启动
+func newHTTPServer(addr string, handler http.Handler, options TLSOptions) (*http.Server, error) {
+	server := &http.Server{Addr: addr, Handler: handler}
+	if !options.Enabled() {
+		return server, nil
+	}
+	reloader, err := newCertReloader(options)
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	return server, nil
+}
+
+// listenAndServe 按配置以 HTTP 或 HTTPS 启动服务；HTTPS 下 net/http 自动协商 HTTP/2
+func listenAndServe(addr string, handler http.Handler, options TLSOptions) error {
+	server, err := newHTTPServer(addr, handler, options)
+	if err != nil {
+		return err
+	}
+	if server.TLSConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
+// SetTLS 设置 Start 使用的TLS证书与私钥
+func (s *AudioServer) SetTLS(options TLSOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	s.tls = options
+	return nil
+}
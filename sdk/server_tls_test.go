@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeTestCertificate 生成自签名证书并写入 cert/key 文件
+func writeTestCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+// TestServerTLS 测试HTTPS监听
+// 测试内容：
+// 1. 证书与私钥必须成对设置，文件无效时启动失败
+// 2. HTTPS下普通接口协商HTTP/2，WebSocket可以通过 wss:// 连接
+// 3. 证书文件替换后新连接使用新证书
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	options := TLSOptions{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	if err := (TLSOptions{CertFile: options.CertFile}).Validate(); err == nil {
+		t.Error("certificate without key should fail")
+	}
+	if _, err := newHTTPServer(":0", nil, options); err == nil {
+		t.Error("missing certificate files should fail")
+	}
+	writeTestCertificate(t, options.CertFile, options.KeyFile, "first")
+
+	audioServer := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/emotions", audioServer.handleEmotions)
+	mux.HandleFunc("/ws", audioServer.handleWebSocket)
+	server, err := newHTTPServer("127.0.0.1:0", mux, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.ServeTLS(listener, "", "")
+	defer server.Close()
+	addr := listener.Addr().String()
+
+	clientTLS := &tls.Config{InsecureSkipVerify: true}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS.Clone(), ForceAttemptHTTP2: true}}
+	resp, err := client.Get("https://" + addr + "/emotions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+		t.Errorf("GET /emotions = %d over %s, want 200 over HTTP/2", resp.StatusCode, resp.Proto)
+	}
+	if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; cn != "first" {
+		t.Errorf("certificate CN = %q, want first", cn)
+	}
+
+	dialer := websocket.Dialer{TLSClientConfig: clientTLS}
+	conn, _, err := dialer.Dial("wss://"+addr+"/ws", nil)
+	if err != nil {
+		t.Fatalf("wss dial: %v", err)
+	}
+	var init map[string]interface{}
+	if err := conn.ReadJSON(&init); err != nil || init["type"] != "init" {
+		t.Errorf("wss init = %v, %v", init, err)
+	}
+	conn.Close()
+
+	writeTestCertificate(t, options.CertFile, options.KeyFile, "second")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(options.CertFile, future, future)
+	conn2, err := tls.Dial("tcp", addr, clientTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	if cn := conn2.ConnectionState().PeerCertificates[0].Subject.CommonName; cn != "second" {
+		t.Errorf("certificate CN after renewal = %q, want second", cn)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// 会话存储：会话配置、未处理的样本与最新结果可放到外部存储（Redis）供多副本接续，处理同一个流的音频块时持有流锁。
+// WebSocket 连接、段内累积、情感平滑与事件状态留在副本内。
+
+// DefaultSessionTTL 外部存储中会话无数据后的保留时长
+const DefaultSessionTTL = 10 * time.Minute
+
+// StoredSession 存储中的会话配置
+type StoredSession struct {
+	Token    string         `json:"token"`    // 每次 /start 生成，副本据此判断本地会话是否过期
+	Settings StreamSettings `json:"settings"` // /start 时的流配置
+}
+
+// StoredBuffer 存储中的流缓冲区：最近一次处理后尚未分析的样本
+type StoredBuffer struct {
+	Token    string    // 写入时处理的音频块的请求ID
+	Received int64     // 流已接收的样本数
+	Samples  []float64 // 缓冲区中的样本
+}
+
+// SessionStore 会话配置、缓冲区与最新结果的存储
+type SessionStore interface {
+	CreateSession(streamID string, session StoredSession) error
+	Session(streamID string) (StoredSession, bool, error)
+	SaveResult(streamID string, result []byte) error // 会话不存在时不保存
+	Result(streamID string) ([]byte, bool, error)    // 会话存在但尚无结果时返回 nil, true
+	SaveBuffer(streamID string, buffer StoredBuffer) error
+	Buffer(streamID string) (StoredBuffer, bool, error)
+	DeleteSession(streamID string) error
+	LockStream(streamID string) (unlock func(), err error) // 跨副本互斥处理同一个流的音频块
+}
+
+// StreamBufferer 支持导出与恢复流缓冲区的处理器（Engine），副本间据此接续缓冲区
+type StreamBufferer interface {
+	StreamBuffer(streamID string) (StoredBuffer, bool)
+	RestoreStreamBuffer(streamID string, buffer StoredBuffer)
+}
+
+// MemorySessionStore 进程内存中的会话存储，单副本部署的默认存储
+// 缓冲区本来就在处理器中，不重复保存
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]StoredSession
+	results  map[string][]byte
+}
+
+// NewMemorySessionStore 创建进程内存会话存储
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]StoredSession),
+		results:  make(map[string][]byte),
+	}
+}
+
+// CreateSession 保存会话配置并清空结果
+func (m *MemorySessionStore) CreateSession(streamID string, session StoredSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[streamID] = session
+	m.results[streamID] = nil
+	return nil
+}
+
+// Session 返回会话配置
+func (m *MemorySessionStore) Session(streamID string) (StoredSession, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[streamID]
+	return session, ok, nil
+}
+
+// SaveResult 保存会话的最新结果
+func (m *MemorySessionStore) SaveResult(streamID string, result []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.results[streamID]; ok {
+		m.results[streamID] = result
+	}
+	return nil
+}
+
+// Result 返回会话的最新结果
+func (m *MemorySessionStore) Result(streamID string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, ok := m.results[streamID]
+	return result, ok, nil
+}
+
+// SaveBuffer 不保存缓冲区
+func (m *MemorySessionStore) SaveBuffer(string, StoredBuffer) error {
+	return nil
+}
+
+// Buffer 没有保存的缓冲区
+func (m *MemorySessionStore) Buffer(string) (StoredBuffer, bool, error) {
+	return StoredBuffer{}, false, nil
+}
+
+// DeleteSession 删除会话配置与结果
+func (m *MemorySessionStore) DeleteSession(streamID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, streamID)
+	delete(m.results, streamID)
+	return nil
+}
+
+// LockStream 单副本部署时处理器自身已串行处理同一个流，不需要额外加锁
+func (m *MemorySessionStore) LockStream(string) (func(), error) {
+	return func() {}, nil
+}
+
+// localStream 本副本上流的状态与存储的对应关系
+type localStream struct {
+	session string // 本地会话按哪次 /start 的配置创建
+	buffer  string // 本地缓冲区对应的存储缓冲区令牌
+}
+
+// SetSessionStore 设置会话存储，多副本部署时使用共享的外部存储
+// 存储只保存会话配置、未处理的样本与最新结果；段内累积、情感平滑与事件去抖状态留在处理器中，
+// 同一个流换到另一个副本处理时这些状态重置
+func (s *AudioServer) SetSessionStore(store SessionStore) {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	s.store = store
+	s.localStreams = make(map[string]localStream)
+}
+
+// sessionStore 返回会话存储与流在本副本上的状态
+func (s *AudioServer) sessionStore(streamID string) (SessionStore, localStream) {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	return s.store, s.localStreams[streamID]
+}
+
+// setLocalStream 更新流在本副本上的状态
+func (s *AudioServer) setLocalStream(streamID string, update func(local *localStream)) {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	local := s.localStreams[streamID]
+	update(&local)
+	s.localStreams[streamID] = local
+}
+
+// syncStream 处理音频块前按存储接续会话：配置由其他副本创建或已重新开始的会话，恢复其他副本写入的缓冲区
+func (s *AudioServer) syncStream(streamID string) {
+	store, local := s.sessionStore(streamID)
+
+	stored, ok, err := store.Session(streamID)
+	if err != nil {
+		log.Printf("读取会话存储失败 stream=%s: %v", streamID, err)
+		return
+	}
+	if ok && stored.Token != local.session {
+		if err := s.processor.ConfigureStream(streamID, stored.Settings); err != nil {
+			log.Printf("按存储配置会话失败 stream=%s: %v", streamID, err)
+		}
+		s.bindCat(streamID, stored.Settings.Cat.ID)
+		local = localStream{session: stored.Token}
+		s.setLocalStream(streamID, func(l *localStream) { *l = local })
+	}
+
+	bufferer, ok := s.processor.(StreamBufferer)
+	if !ok {
+		return
+	}
+	buffer, ok, err := store.Buffer(streamID)
+	if err != nil {
+		log.Printf("读取会话缓冲区失败 stream=%s: %v", streamID, err)
+		return
+	}
+	if ok && buffer.Token != local.buffer {
+		bufferer.RestoreStreamBuffer(streamID, buffer)
+		s.setLocalStream(streamID, func(l *localStream) { l.buffer = buffer.Token })
+		log.Printf("已接续其他副本的缓冲区 stream=%s buffered=%d", streamID, len(buffer.Samples))
+	}
+}
+
+// lockStream 获取流锁，返回释放函数；存储暂时不可用时不加锁继续处理，与 syncStream 的处理一致
+func (s *AudioServer) lockStream(streamID string) (unlock func()) {
+	store, _ := s.sessionStore(streamID)
+	unlock, err := store.LockStream(streamID)
+	if err != nil {
+		log.Printf("获取流锁失败 stream=%s: %v", streamID, err)
+		return func() {}
+	}
+	return unlock
+}
+
+// saveStream 处理音频块后写回缓冲区与最新结果
+func (s *AudioServer) saveStream(streamID, requestID string, result []byte) {
+	store, _ := s.sessionStore(streamID)
+
+	if bufferer, ok := s.processor.(StreamBufferer); ok {
+		if buffer, ok := bufferer.StreamBuffer(streamID); ok {
+			buffer.Token = requestID
+			if err := store.SaveBuffer(streamID, buffer); err != nil {
+				log.Printf("保存会话缓冲区失败 stream=%s: %v", streamID, err)
+			} else {
+				s.setLocalStream(streamID, func(l *localStream) { l.buffer = requestID })
+			}
+		}
+	}
+	if err := store.SaveResult(streamID, result); err != nil {
+		log.Printf("保存会话结果失败 stream=%s: %v", streamID, err)
+	}
+}
+
+// startStream /start 时在存储中创建会话
+func (s *AudioServer) startStream(streamID string, settings StreamSettings) error {
+	store, _ := s.sessionStore(streamID)
+	session := StoredSession{Token: newRequestID(), Settings: settings}
+	if err := store.CreateSession(streamID, session); err != nil {
+		return err
+	}
+	s.setLocalStream(streamID, func(l *localStream) { *l = localStream{session: session.Token} })
+	return nil
+}
+
+// stopStream /stop 时删除存储中的会话
+func (s *AudioServer) stopStream(streamID string) {
+	store, _ := s.sessionStore(streamID)
+	if err := store.DeleteSession(streamID); err != nil {
+		log.Printf("删除会话存储失败 stream=%s: %v", streamID, err)
+	}
+	s.storeMu.Lock()
+	delete(s.localStreams, streamID)
+	s.storeMu.Unlock()
+}
+
+// StreamBuffer 导出流的缓冲区
+func (e *Engine) StreamBuffer(streamID string) (StoredBuffer, bool) {
+	session, ok := e.lookupSession(streamID)
+	if !ok {
+		return StoredBuffer{}, false
+	}
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+	return StoredBuffer{
+		Received: session.SamplesReceived,
+		Samples:  append([]float64(nil), session.Buffer...),
+	}, true
+}
+
+// RestoreStreamBuffer 以其他副本导出的缓冲区替换流的缓冲区，缓冲样本的到达时间记为当前时间
+func (e *Engine) RestoreStreamBuffer(streamID string, buffer StoredBuffer) {
+	session := e.streamSession(streamID)
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+	session.Buffer = append([]float64(nil), buffer.Samples...)
+	session.SamplesReceived = buffer.Received
+	session.prefetched = windowPrefetch{}
+	session.arrivals = []sampleArrival{{End: session.SamplesReceived, At: e.now(session)}}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis 测试用的最小 RESP 服务，支持 AUTH/SELECT/PING/GET/SET(EX, PX, NX, XX)/DEL/EXPIRE/PEXPIRE 与 WATCH/MULTI/EXEC/UNWATCH
+// 只有 PX 与 PEXPIRE 设置的毫秒级过期时间生效，键过期视为一次修改
+type fakeRedis struct {
+	password string
+	mu       sync.Mutex
+	data     map[string]string
+	expires  map[string]time.Time // 键的过期时间
+	versions map[string]int       // 键的修改次数，EXEC 据此判断 WATCH 的键是否被改写
+}
+
+// serve 在本地端口上启动服务，返回 redis:// 地址
+func (f *fakeRedis) serve(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	f.data = make(map[string]string)
+	f.expires = make(map[string]time.Time)
+	f.versions = make(map[string]int)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(conn)
+		}
+	}()
+	return fmt.Sprintf("redis://:%s@%s/2", f.password, listener.Addr())
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authed := f.password == ""
+	var watched map[string]int // WATCH 时键的修改次数
+	var queued [][]string      // MULTI 之后排队的命令，nil 表示不在事务中
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, count)
+		for i := range args {
+			header, _ := reader.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:size])
+		}
+		command := strings.ToUpper(args[0])
+		if command == "AUTH" {
+			authed = args[1] == f.password
+		}
+		if !authed {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+
+		f.mu.Lock()
+		switch {
+		case command == "MULTI":
+			queued = [][]string{}
+			io.WriteString(conn, "+OK\r\n")
+		case command == "EXEC":
+			aborted := false
+			for key, version := range watched {
+				f.expire(key)
+				aborted = aborted || f.versions[key] != version
+			}
+			if aborted {
+				io.WriteString(conn, "*-1\r\n")
+			} else {
+				fmt.Fprintf(conn, "*%d\r\n", len(queued))
+				for _, cmd := range queued {
+					io.WriteString(conn, f.exec(cmd))
+				}
+			}
+			watched, queued = nil, nil
+		case queued != nil:
+			queued = append(queued, args)
+			io.WriteString(conn, "+QUEUED\r\n")
+		case command == "WATCH":
+			if watched == nil {
+				watched = make(map[string]int)
+			}
+			for _, key := range args[1:] {
+				f.expire(key)
+				watched[key] = f.versions[key]
+			}
+			io.WriteString(conn, "+OK\r\n")
+		case command == "UNWATCH":
+			watched = nil
+			io.WriteString(conn, "+OK\r\n")
+		default:
+			io.WriteString(conn, f.exec(args))
+		}
+		f.mu.Unlock()
+	}
+}
+
+// expire 删除已过期的键（调用方需持有f.mu）
+func (f *fakeRedis) expire(key string) {
+	if expiry, ok := f.expires[key]; ok && time.Now().After(expiry) {
+		delete(f.data, key)
+		delete(f.expires, key)
+		f.versions[key]++
+	}
+}
+
+// exec 执行一条数据命令，返回编码后的回复（调用方需持有f.mu）
+func (f *fakeRedis) exec(args []string) string {
+	for _, key := range args[1:] {
+		f.expire(key)
+	}
+	switch strings.ToUpper(args[0]) {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		if value, ok := f.data[args[1]]; ok {
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+		}
+		return "$-1\r\n"
+	case "SET":
+		_, exists := f.data[args[1]]
+		for _, option := range args[3:] {
+			switch strings.ToUpper(option) {
+			case "XX":
+				if !exists {
+					return "$-1\r\n"
+				}
+			case "NX":
+				if exists {
+					return "$-1\r\n"
+				}
+			}
+		}
+		f.data[args[1]] = args[2]
+		delete(f.expires, args[1])
+		for i, option := range args[3:] {
+			if strings.ToUpper(option) == "PX" && 3+i+1 < len(args) {
+				ms, _ := strconv.Atoi(args[3+i+1])
+				f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		}
+		f.versions[args[1]]++
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := f.data[key]; ok {
+				delete(f.data, key)
+				delete(f.expires, key)
+				f.versions[key]++
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "EXPIRE":
+		return ":1\r\n"
+	case "PEXPIRE":
+		if _, ok := f.data[args[1]]; !ok {
+			return ":0\r\n"
+		}
+		ms, _ := strconv.Atoi(args[2])
+		f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		f.versions[args[1]]++
+		return ":1\r\n"
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+// TestRedisSessionStore 测试多副本共享会话存储
+// 测试内容：
+// 1. 地址中的密码错误时连接失败，缓冲区编码往返后样本一致
+// 2. 副本A /start 后，副本B按存储中的配置接续会话，接上A缓冲区中未处理的样本产生结果
+// 3. 副本A的 /recv 查到B产生的结果，B /stop 后A查不到会话
+func TestRedisSessionStore(t *testing.T) {
+	redis := &fakeRedis{password: "secret"}
+	addr := redis.serve(t)
+	if _, err := NewRedisSessionStore(strings.Replace(addr, "secret", "wrong", 1), 0); err == nil {
+		t.Error("wrong password should fail")
+	}
+	if _, err := NewRedisSessionStore("http://localhost", 0); err == nil {
+		t.Error("non-redis url should fail")
+	}
+
+	newReplica := func() *AudioServer {
+		store, err := NewRedisSessionStore(addr, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+		server.SetSessionStore(store)
+		return server
+	}
+	a, b := newReplica(), newReplica()
+
+	store, _ := a.sessionStore("x")
+	want := StoredBuffer{Token: "t1", Received: 42, Samples: []float64{0.5, -0.25, 1e-9}}
+	store.SaveBuffer("roundtrip", want)
+	if got, ok, err := store.Buffer("roundtrip"); err != nil || !ok || got.Token != want.Token || got.Received != 42 || len(got.Samples) != 3 || got.Samples[2] != 1e-9 {
+		t.Errorf("buffer round trip = %+v, %v, %v", got, ok, err)
+	}
+
+	rec := httptest.NewRecorder()
+	a.handleStart(rec, httptest.NewRequest(http.MethodPost, "/start", strings.NewReader(`{"streamId":"cat1","lang":"zh"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start = %d %s", rec.Code, rec.Body.String())
+	}
+
+	audio := generateTestAudio(440, 0.1, 44100)
+	send := func(server *AudioServer, samples []float64) AudioStreamResult {
+		body, _ := json.Marshal(map[string]interface{}{"streamId": "cat1", "data": samples})
+		rec := httptest.NewRecorder()
+		server.handleSend(rec, httptest.NewRequest(http.MethodPost, "/send", bytes.NewReader(body)))
+		var result AudioStreamResult
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return result
+	}
+	if first := send(a, audio[:3000]); first.ResultID != "" {
+		t.Fatalf("3000 samples should not fill a window, got %+v", first)
+	}
+	second := send(b, audio[3000:4200])
+	if second.ResultID == "" || second.Label != EmotionLabel("zh", second.Emotion) {
+		t.Fatalf("replica B should continue A's buffer with the stored settings, got %+v", second)
+	}
+
+	recv := func(server *AudioServer) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.handleReceive(rec, httptest.NewRequest(http.MethodGet, "/recv?streamId=cat1", nil))
+		return rec
+	}
+	var latest AudioStreamResult
+	if rec := recv(a); json.Unmarshal(rec.Body.Bytes(), &latest) != nil || latest.ResultID != second.ResultID {
+		t.Errorf("recv on A = %s, want result %s from B", rec.Body.String(), second.ResultID)
+	}
+
+	b.handleStop(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/stop", strings.NewReader(`{"streamId":"cat1"}`)))
+	if rec := recv(a); rec.Code != http.StatusNotFound {
+		t.Errorf("recv on A after stop on B = %d, want 404", rec.Code)
+	}
+}
+
+// TestRedisStreamLock 测试跨副本的流锁
+// 测试内容：
+// 1. 锁被持有时第二个副本等待，释放后获取；超过 LockTTL 仍未获取时返回错误
+// 2. 锁键已被其他持有者改写时，释放不删除他人的锁
+// 3. 两个副本同时向同一个流发送音频块，接续与写回不交错，存储中的已接收样本数等于全部发送的样本数
+func TestRedisStreamLock(t *testing.T) {
+	redis := &fakeRedis{}
+	addr := redis.serve(t)
+	open := func() *RedisSessionStore {
+		store, err := NewRedisSessionStore(addr, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	}
+	a, b := open(), open()
+
+	unlock, err := a.LockStream("s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan func())
+	go func() {
+		unlockB, err := b.LockStream("s")
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- unlockB
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second replica acquired a held lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	unlockB := <-acquired
+
+	b.LockTTL = 20 * time.Millisecond
+	if _, err := b.LockStream("s"); err == nil {
+		t.Error("lock held past LockTTL should fail")
+	}
+
+	// 锁过期后被其他副本重新获取：原持有者释放时不删除
+	if _, err := a.client.do("SET", a.key("s", "lock"), "other"); err != nil {
+		t.Fatal(err)
+	}
+	unlockB()
+	if holder, ok, _ := a.get(a.key("s", "lock")); !ok || holder != "other" {
+		t.Errorf("lock after stale unlock = %q, %v; want other holder kept", holder, ok)
+	}
+	a.client.do("DEL", a.key("s", "lock"))
+
+	newReplica := func() *AudioServer {
+		server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+		server.SetSessionStore(open())
+		return server
+	}
+	replicas := []*AudioServer{newReplica(), newReplica()}
+	rec := httptest.NewRecorder()
+	replicas[0].handleStart(rec, httptest.NewRequest(http.MethodPost, "/start", strings.NewReader(`{"streamId":"cat2"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start = %d %s", rec.Code, rec.Body.String())
+	}
+
+	const chunks, size = 20, 100
+	body, _ := json.Marshal(map[string]interface{}{"streamId": "cat2", "data": generateTestAudio(440, 0.1, 44100)[:size]})
+	var wg sync.WaitGroup
+	for _, server := range replicas {
+		wg.Add(1)
+		go func(server *AudioServer) {
+			defer wg.Done()
+			for i := 0; i < chunks; i++ {
+				rec := httptest.NewRecorder()
+				server.handleSend(rec, httptest.NewRequest(http.MethodPost, "/send", bytes.NewReader(body)))
+				if rec.Code != http.StatusOK {
+					t.Errorf("send = %d %s", rec.Code, rec.Body.String())
+				}
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	buffer, ok, err := a.Buffer("cat2")
+	if err != nil || !ok || buffer.Received != 2*chunks*size {
+		t.Errorf("stored buffer received = %d (%v, %v), want %d", buffer.Received, ok, err, 2*chunks*size)
+	}
+}
+
+// TestRedisStreamLockRenewal 测试流锁续期
+// 测试内容：
+// 1. 持有流锁的时间超过 LockTTL 时锁持续续期，其他副本等待 LockTTL 后获取失败
+// 2. 释放后续期停止，其他副本可以立即获取
+// 3. 未续期的锁（持有者崩溃）在 LockTTL 后过期，可被其他副本获取
+func TestRedisStreamLockRenewal(t *testing.T) {
+	fake := &fakeRedis{}
+	addr := fake.serve(t)
+	open := func() *RedisSessionStore {
+		t.Helper()
+		store, err := NewRedisSessionStore(addr, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		store.LockTTL = 90 * time.Millisecond
+		return store
+	}
+	a, b := open(), open()
+
+	unlock, err := a.LockStream("cat1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(3 * a.LockTTL)
+	if _, err := b.LockStream("cat1"); err == nil {
+		t.Fatal("lock held past LockTTL should be renewed, not taken over")
+	}
+	unlock()
+	unlockB, err := b.LockStream("cat1")
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	unlockB()
+
+	// 持有者崩溃：直接写入锁键，不续期
+	if _, err := a.client.do("SET", a.key("cat2", "lock"), "crashed", "PX", "90"); err != nil {
+		t.Fatal(err)
+	}
+	unlockB, err = b.LockStream("cat2")
+	if err != nil {
+		t.Fatalf("lock of a crashed holder should expire: %v", err)
+	}
+	unlockB()
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 	"math"
 	"os"
+
+	"soundsdk/dsp"
 )
 
 // AudioData 表示音频数据
@@ -16,13 +18,20 @@ type AudioData struct {
 type FeatureExtractor struct {
 	sampleRate int
 	frameSize  int
+	options    ExtractorOptions
 }
 
 // 创建新的特征提取器
 func NewFeatureExtractor(sampleRate int) *FeatureExtractor {
+	return NewFeatureExtractorWithOptions(sampleRate, ExtractorOptions{})
+}
+
+// NewFeatureExtractorWithOptions 按配置创建特征提取器
+func NewFeatureExtractorWithOptions(sampleRate int, options ExtractorOptions) *FeatureExtractor {
 	return &FeatureExtractor{
 		sampleRate: sampleRate,
 		frameSize:  int(float64(sampleRate) * 0.025), // 25ms帧
+		options:    options,
 	}
 }
 
@@ -56,10 +65,7 @@ func LoadWavFile(filename string) (*AudioData, error) {
 
 	// 转换为float64
 	samples := make([]float64, len(data)/2)
-	for i := 0; i < len(samples); i++ {
-		sample := float64(int16(binary.LittleEndian.Uint16(data[i*2 : (i+1)*2])))
-		samples[i] = sample / 32768.0 // 归一化到 [-1,1]
-	}
+	convertPCM16(samples, data) // 归一化到 [-1,1]
 
 	return &AudioData{
 		Samples:    samples,
@@ -78,18 +84,118 @@ func (fe *FeatureExtractor) Extract(audio *AudioData) map[string]float64 {
 		totalEnergy += fe.calculateEnergy(frame)
 	}
 
+	// 频谱类特征可选地基于预加重后的信号计算
+	spectral := audio.Samples
+	if fe.options.PreEmphasis {
+		spectral = applyPreEmphasis(audio.Samples, fe.options.preEmphasisCoefficient())
+	}
+
 	numFrames := float64(len(frames))
 	feature := map[string]float64{
 		"ZeroCrossRate": totalZCR / numFrames,    // 使用帧平均值
 		"Energy":        totalEnergy / numFrames, // 使用帧平均值
 		"Pitch":         fe.estimatePitch(audio.Samples),
 		"Duration":      float64(len(audio.Samples)) / float64(audio.SampleRate),
-		"PeakFreq":      fe.calculatePeakFrequency(audio.Samples),
+		"PeakFreq":      fe.calculatePeakFrequency(spectral),
+	}
+	for name, v := range extractCustomFeatures(audio.Samples, audio.SampleRate) {
+		feature[name] = v
 	}
 
 	return feature
 }
 
+// ExtractSpectrum 从一帧频域幅度直接计算特征，跳过分帧与FFT
+// magnitudes[i] 为第 i 个频点的线性幅度（从0Hz开始），binHz 为频点宽度。
+// 频谱中没有相位与时序信息，过零率与持续时间无法计算，不出现在结果中
+func (fe *FeatureExtractor) ExtractSpectrum(magnitudes []float64, binHz float64) map[string]float64 {
+	if len(magnitudes) == 0 || binHz <= 0 {
+		return map[string]float64{}
+	}
+
+	band := fe.band()
+	var power, weighted, total, peakMagnitude, peakFreq float64
+	for i, magnitude := range magnitudes {
+		magnitude = math.Abs(magnitude)
+		freq := float64(i) * binHz
+		power += magnitude * magnitude
+		weighted += freq * magnitude
+		total += magnitude
+		if freq >= band.PeakMin && freq <= band.PeakMax && magnitude > peakMagnitude {
+			peakMagnitude = magnitude
+			peakFreq = freq
+		}
+	}
+
+	centroid := 0.0
+	if total > 0 {
+		centroid = weighted / total
+	}
+
+	// 频谱滚降点：累积能量达到85%的频率
+	rolloff := 0.0
+	cumulative := 0.0
+	for i, magnitude := range magnitudes {
+		cumulative += magnitude * magnitude
+		if cumulative >= 0.85*power {
+			rolloff = float64(i) * binHz
+			break
+		}
+	}
+
+	pitch := fe.estimateSpectrumPitch(magnitudes, binHz)
+	energy := power / float64(len(magnitudes))
+	return map[string]float64{
+		"Energy":           energy,
+		"RootMeanSquare":   math.Sqrt(energy),
+		"Pitch":            pitch,
+		"FundamentalFreq":  pitch,
+		"PeakFreq":         peakFreq,
+		"SpectralCentroid": centroid,
+		"SpectralRolloff":  rolloff,
+	}
+}
+
+// estimateSpectrumPitch 谐波求和法估计基频：在预设音高范围内选出基频及其2、3次谐波加权幅度之和最大的频点
+// 第 h 次谐波的权重为 1/h，避免把真实基频的一半误判为基频
+func (fe *FeatureExtractor) estimateSpectrumPitch(magnitudes []float64, binHz float64) float64 {
+	band := fe.band()
+	minBin := int(math.Ceil(band.PitchMin / binHz))
+	maxBin := int(band.PitchMax / binHz)
+	if minBin < 1 {
+		minBin = 1
+	}
+
+	bestSum := 0.0
+	bestBin := 0
+	for k := minBin; k <= maxBin && k < len(magnitudes); k++ {
+		sum := 0.0
+		for h := 1; h <= 3 && h*k < len(magnitudes); h++ {
+			sum += math.Abs(magnitudes[h*k]) / float64(h)
+		}
+		if sum > bestSum {
+			bestSum = sum
+			bestBin = k
+		}
+	}
+	return float64(bestBin) * binHz
+}
+
+// applyPreEmphasis 预加重滤波 y[n] = x[n] - a*x[n-1]
+// 提升高频分量，使高音调猫叫的频谱质心、滚降点等特征更稳定
+func applyPreEmphasis(samples []float64, coefficient float64) []float64 {
+	if len(samples) == 0 {
+		return samples
+	}
+
+	emphasized := make([]float64, len(samples))
+	emphasized[0] = samples[0]
+	for i := 1; i < len(samples); i++ {
+		emphasized[i] = samples[i] - coefficient*samples[i-1]
+	}
+	return emphasized
+}
+
 // splitFrames 将音频分帧
 func (fe *FeatureExtractor) splitFrames(samples []float64) [][]float64 {
 	frameCount := len(samples) / fe.frameSize
@@ -140,9 +246,13 @@ func (fe *FeatureExtractor) estimatePitch(samples []float64) float64 {
 		return 0
 	}
 
-	// 使用自相关法估计基频
-	minLag := fe.sampleRate / 2000 // 最高2000Hz
-	maxLag := fe.sampleRate / 70   // 最低70Hz
+	// 使用自相关法估计基频，搜索范围由频率预设决定
+	band := fe.band()
+	minLag := int(float64(fe.sampleRate) / band.PitchMax) // 最高频率
+	maxLag := int(float64(fe.sampleRate) / band.PitchMin) // 最低频率
+	if minLag < 1 {
+		minLag = 1
+	}
 	maxCorr := 0.0
 	bestLag := 0
 
@@ -174,23 +284,21 @@ func (fe *FeatureExtractor) calculatePeakFrequency(samples []float64) float64 {
 
 	// 应用汉明窗
 	windowed := make([]float64, fe.frameSize)
-	for i := 0; i < fe.frameSize; i++ {
-		// 汉明窗函数
-		window := 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(fe.frameSize-1))
-		windowed[i] = samples[i] * window
-	}
+	multiplyWindow(windowed, samples[:fe.frameSize], hammingWindow(fe.frameSize))
 
-	// 执行FFT
-	fft := make([]complex128, fe.frameSize)
-	for i := 0; i < fe.frameSize; i++ {
-		fft[i] = complex(windowed[i], 0)
-	}
-	fft = fe.fft(fft)
+	// 执行FFT，帧长不是2的幂时补零
+	fft := dsp.RealFFT(windowed)
+	n := len(fft)
 
-	// 寻找峰值频率
+	// 在预设频率范围内寻找峰值频率
+	band := fe.band()
 	maxMagnitude := 0.0
 	peakBin := 0
-	for i := 0; i < fe.frameSize/2; i++ {
+	for i := 0; i < n/2; i++ {
+		freq := float64(i) * float64(fe.sampleRate) / float64(n)
+		if freq < band.PeakMin || freq > band.PeakMax {
+			continue
+		}
 		magnitude := cmplxAbs(fft[i])
 		if magnitude > maxMagnitude {
 			maxMagnitude = magnitude
@@ -199,58 +307,10 @@ func (fe *FeatureExtractor) calculatePeakFrequency(samples []float64) float64 {
 	}
 
 	// 转换为频率
-	return float64(peakBin) * float64(fe.sampleRate) / float64(fe.frameSize)
-}
-
-// fft 快速傅里叶变换
-func (fe *FeatureExtractor) fft(x []complex128) []complex128 {
-	N := len(x)
-	if N <= 1 {
-		return x
-	}
-
-	even := make([]complex128, N/2)
-	odd := make([]complex128, N/2)
-	for i := 0; i < N/2; i++ {
-		even[i] = x[2*i]
-		odd[i] = x[2*i+1]
-	}
-
-	even = fe.fft(even)
-	odd = fe.fft(odd)
-
-	factor := -2 * math.Pi / float64(N)
-	result := make([]complex128, N)
-	for k := 0; k < N/2; k++ {
-		phase := factor * float64(k)
-		t := cmplxMul(cmplxFromAngle(phase), odd[k])
-		result[k] = cmplxAdd(even[k], t)
-		result[k+N/2] = cmplxSub(even[k], t)
-	}
-
-	return result
-}
-
-// 复数运算辅助函数
-func cmplxFromAngle(angle float64) complex128 {
-	return complex(math.Cos(angle), math.Sin(angle))
-}
-
-func cmplxMul(a, b complex128) complex128 {
-	return complex(
-		real(a)*real(b)-imag(a)*imag(b),
-		real(a)*imag(b)+imag(a)*real(b),
-	)
-}
-
-func cmplxAdd(a, b complex128) complex128 {
-	return complex(real(a)+real(b), imag(a)+imag(b))
-}
-
-func cmplxSub(a, b complex128) complex128 {
-	return complex(real(a)-real(b), imag(a)-imag(b))
+	return float64(peakBin) * float64(fe.sampleRate) / float64(n)
 }
 
+// cmplxAbs 复数的模
 func cmplxAbs(a complex128) float64 {
 	return math.Sqrt(real(a)*real(a) + imag(a)*imag(a))
 }
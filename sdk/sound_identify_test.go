@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"testing"
 )
 
@@ -85,3 +86,73 @@ func TestFFT(t *testing.T) {
 func TestLoadWavFile(t *testing.T) {
 	t.Skip("TODO: Implement test")
 }
+
+// TestApplyPreEmphasis 测试预加重滤波
+// 测试内容：
+// 1. 首个样本保持不变
+// 2. 直流信号被大幅衰减
+// 3. 空输入的处理
+func TestApplyPreEmphasis(t *testing.T) {
+	dc := []float64{1, 1, 1, 1}
+	got := applyPreEmphasis(dc, DefaultPreEmphasisCoefficient)
+	if got[0] != 1 {
+		t.Errorf("first sample = %v, want 1", got[0])
+	}
+	for i := 1; i < len(got); i++ {
+		if diff := got[i] - 0.03; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("sample %d = %v, want 0.03", i, got[i])
+		}
+	}
+
+	if out := applyPreEmphasis(nil, DefaultPreEmphasisCoefficient); len(out) != 0 {
+		t.Errorf("empty input produced %d samples", len(out))
+	}
+
+	if c := (ExtractorOptions{}).preEmphasisCoefficient(); c != DefaultPreEmphasisCoefficient {
+		t.Errorf("default coefficient = %v, want %v", c, DefaultPreEmphasisCoefficient)
+	}
+}
+
+// TestExtractSpectrum 测试从频域幅度直接提取特征
+// 测试内容：
+// 1. 基频 600Hz 及其谐波的频谱：峰值频率与基频按频点宽度换算
+// 2. 频谱质心位于基频与最高谐波之间
+// 3. 空输入返回空特征
+func TestExtractSpectrum(t *testing.T) {
+	binHz := 44100.0 / 2 / 1024
+	magnitudes := make([]float64, 1024)
+	fundamental := int(600 / binHz)
+	magnitudes[fundamental] = 1.0
+	magnitudes[2*fundamental] = 0.5
+	magnitudes[3*fundamental] = 0.25
+
+	fe := NewFeatureExtractor(44100)
+	features := fe.ExtractSpectrum(magnitudes, binHz)
+	want := float64(fundamental) * binHz
+	if features["PeakFreq"] != want || features["Pitch"] != want {
+		t.Errorf("PeakFreq = %v, Pitch = %v, want %v", features["PeakFreq"], features["Pitch"], want)
+	}
+	if c := features["SpectralCentroid"]; c <= want || c >= 3*want {
+		t.Errorf("SpectralCentroid = %v, want between %v and %v", c, want, 3*want)
+	}
+	if _, ok := features["ZeroCrossRate"]; ok {
+		t.Error("频谱中无法计算过零率，不应出现在结果中")
+	}
+
+	if got := fe.ExtractSpectrum(nil, binHz); len(got) != 0 {
+		t.Errorf("empty input produced %v", got)
+	}
+}
+
+// TestFeatureExtractorPeakFrequency 测试特征提取器的峰值频率
+// 测试内容：
+// 1. 帧长不是2的幂（25ms）时峰值频率仍落在纯音频率附近
+func TestFeatureExtractorPeakFrequency(t *testing.T) {
+	fe := NewFeatureExtractor(44100)
+	for _, freq := range []float64{440, 800, 1500} {
+		got := fe.calculatePeakFrequency(generateTestAudio(freq, 0.05, 44100))
+		if math.Abs(got-freq) > 44100.0/2048 {
+			t.Errorf("%.0fHz 纯音的峰值频率 = %.1fHz", freq, got)
+		}
+	}
+}
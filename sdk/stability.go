@@ -0,0 +1,69 @@
+package main
+
+// 预测稳定度
+//
+// 单个结果只反映一个窗口或一段叫声，刚开始识别或叫声变化时情感会来回跳动，界面直接展示显得不可靠。
+// 每个流保留最近 StabilityWindow 个结果，结果中附带滚动平均置信度与稳定度（最近的结果中与多数情感
+// 一致的比例），界面可以在 stable 为 true 之前显示"分析中…"。
+
+// 稳定度参数
+const (
+	StabilityWindow = 5   // 参与统计的最近结果数
+	StableThreshold = 0.8 // 稳定度达到该值时视为稳定
+)
+
+// ResultStability 结果的滚动统计，嵌入识别结果
+type ResultStability struct {
+	AvgConfidence float64 `json:"avgConfidence"` // 最近结果的平均置信度
+	Stability     float64 `json:"stability"`     // 最近结果中与多数情感一致的比例，结果不足窗口数时按窗口数计算
+	Stable        bool    `json:"stable"`        // 稳定度达到 StableThreshold
+}
+
+// stabilityWindow 流最近的识别结果（调用方需保证同一流的结果串行输入）
+type stabilityWindow struct {
+	emotions    []string
+	confidences []float64
+}
+
+// observe 输入一次识别结果，返回包含该结果在内的滚动统计
+func (w *stabilityWindow) observe(emotion string, confidence float64) ResultStability {
+	w.emotions = append(w.emotions, emotion)
+	w.confidences = append(w.confidences, confidence)
+	if len(w.emotions) > StabilityWindow {
+		w.emotions = w.emotions[1:]
+		w.confidences = w.confidences[1:]
+	}
+	return w.current()
+}
+
+// current 返回当前的滚动统计，不输入新结果（段未完成的中间结果使用）
+func (w *stabilityWindow) current() ResultStability {
+	if len(w.emotions) == 0 {
+		return ResultStability{}
+	}
+
+	// unknown 不计入多数情感，只降低稳定度
+	counts := make(map[string]int, len(w.emotions))
+	majority := 0
+	for _, emotion := range w.emotions {
+		if emotion == "" || emotion == "unknown" {
+			continue
+		}
+		counts[emotion]++
+		if counts[emotion] > majority {
+			majority = counts[emotion]
+		}
+	}
+
+	total := 0.0
+	for _, confidence := range w.confidences {
+		total += confidence
+	}
+
+	stability := float64(majority) / StabilityWindow
+	return ResultStability{
+		AvgConfidence: total / float64(len(w.confidences)),
+		Stability:     stability,
+		Stable:        stability >= StableThreshold,
+	}
+}
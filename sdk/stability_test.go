@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// TestResultStability 测试结果的滚动平均置信度与稳定度
+// 测试内容：
+// 1. 结果不足窗口数时稳定度按窗口数计算，unknown 不计入多数情感
+// 2. 只保留最近 StabilityWindow 个结果
+// 3. 引擎持续识别同一段声音时稳定度逐步上升至稳定
+func TestResultStability(t *testing.T) {
+	var window stabilityWindow
+	if stats := window.current(); stats != (ResultStability{}) {
+		t.Errorf("empty window = %+v, want zero", stats)
+	}
+	if stats := window.observe("hello", 0.6); stats.Stability != 0.2 || stats.AvgConfidence != 0.6 || stats.Stable {
+		t.Errorf("first result = %+v, want stability 0.2", stats)
+	}
+	window.observe("unknown", 0.2)
+	for i := 0; i < 3; i++ {
+		window.observe("hello", 0.8)
+	}
+	if stats := window.current(); stats.Stability != 0.8 || !stats.Stable {
+		t.Errorf("4 of 5 agreeing = %+v, want stable at 0.8", stats)
+	}
+	if stats := window.observe("warning", 0.4); stats.Stability != 0.6 || stats.Stable || len(window.emotions) != StabilityWindow {
+		t.Errorf("after a change = %+v with %d results, want stability 0.6 over %d", stats, len(window.emotions), StabilityWindow)
+	}
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Deterministic: true})
+	samples := generateTestAudio(440, 4096.0/44100, 44100)
+	var last AudioStreamResult
+	for final := 0; final < StabilityWindow; {
+		data, err := engine.ProcessAudio("cat1", samples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result AudioStreamResult
+		if err := json.Unmarshal(data, &result); err != nil || result.Emotion == "" || result.Partial {
+			continue
+		}
+		final++
+		if result.Stability < last.Stability {
+			t.Errorf("stability dropped from %.2f to %.2f on identical audio", last.Stability, result.Stability)
+		}
+		last = result
+	}
+	if last.Stability != 1 || !last.Stable || math.Abs(last.AvgConfidence-last.Confidence) > 1e-9 {
+		t.Errorf("after %d identical results = %+v, want fully stable", StabilityWindow, last.ResultStability)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 处理策略
+//
+// 通话类应用要求尽快给出结果，录音回看类应用更看重准确率，两者对窗口长度、
+// 是否累积多个窗口以及匹配方式的取舍正好相反。策略在开始流时按名称选择，
+// 窗口长度以配置中的 BufferSize 为基准按比例换算，因此与采样率无关。
+
+// 处理策略名称
+const (
+	StrategyStandard   = "standard"
+	StrategyLowLatency = "low-latency"
+	StrategyAccurate   = "accurate"
+	StrategyTemplate   = "template"
+
+	DefaultStrategy = StrategyStandard
+)
+
+// 匹配方式
+const (
+	MatcherStandard = "standard" // 欧氏距离与马氏距离综合评分
+	MatcherFast     = "fast"     // 仅使用最近样本的欧氏距离
+	MatcherEnsemble = "ensemble" // 综合评分与模板匹配的软投票（见 classifier.go）
+	MatcherTemplate = "template" // 与各情感模板（样本均值）的余弦相似度
+)
+
+// ProcessingStrategy 处理策略
+type ProcessingStrategy struct {
+	Name        string  `json:"name"`        // 策略名称
+	WindowScale float64 `json:"windowScale"` // 分析窗口长度相对 BufferSize 的比例
+	HopScale    float64 `json:"hopScale"`    // 相邻窗口步进相对窗口长度的比例，1表示不重叠
+	Segment     int     `json:"segment"`     // 累积多少个窗口后输出一次结果，1表示每个窗口立即输出；引擎设置了触发条件时改为按静默划分段（见 trigger_policy.go）
+	Matcher     string  `json:"matcher"`     // 匹配方式
+}
+
+// processingStrategies 内置处理策略
+// standard 与原先每 BufferSize 个样本输出一次结果的行为一致
+var processingStrategies = map[string]ProcessingStrategy{
+	StrategyStandard: {
+		Name:        StrategyStandard,
+		WindowScale: 1,
+		HopScale:    1,
+		Segment:     1,
+		Matcher:     MatcherStandard,
+	},
+	StrategyLowLatency: {
+		Name:        StrategyLowLatency,
+		WindowScale: 0.5,
+		HopScale:    1,
+		Segment:     1,
+		Matcher:     MatcherFast,
+	},
+	StrategyAccurate: {
+		Name:        StrategyAccurate,
+		WindowScale: 1,
+		HopScale:    0.5,
+		Segment:     4,
+		Matcher:     MatcherEnsemble,
+	},
+	StrategyTemplate: {
+		Name:        StrategyTemplate,
+		WindowScale: 1,
+		HopScale:    1,
+		Segment:     1,
+		Matcher:     MatcherTemplate,
+	},
+}
+
+// LookupProcessingStrategy 按名称查找处理策略，名称为空时返回默认策略
+func LookupProcessingStrategy(name string) (ProcessingStrategy, error) {
+	if name == "" {
+		name = DefaultStrategy
+	}
+	strategy, ok := processingStrategies[name]
+	if !ok {
+		return ProcessingStrategy{}, fmt.Errorf("unknown processing strategy: %s (available: %v)", name, ProcessingStrategyNames())
+	}
+	return strategy, nil
+}
+
+// ProcessingStrategyNames 返回所有内置策略名称
+func ProcessingStrategyNames() []string {
+	names := make([]string, 0, len(processingStrategies))
+	for name := range processingStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// frames 返回策略在指定 BufferSize 下的窗口长度与步进（样本数）
+func (s ProcessingStrategy) frames(bufferSize int) (window, hop int) {
+	window = int(math.Round(float64(bufferSize) * s.WindowScale))
+	if window <= 0 {
+		window = bufferSize
+	}
+	hop = int(math.Round(float64(window) * s.HopScale))
+	if hop <= 0 || hop > window {
+		hop = window
+	}
+	return window, hop
+}
+
+// fastScores 仅按最近样本的欧氏距离评分，跳过统计信息与马氏距离计算
+func fastScores(library *SampleLibrary, feature AudioFeature) map[string]float64 {
+	scores := make(map[string]float64, len(library.Samples))
+	for emotion, samples := range library.Samples {
+		if len(samples) == 0 {
+			continue
+		}
+		nearest := math.MaxFloat64
+		for _, sample := range samples {
+			if d := calculateEuclideanDistance(feature, sample.Features); d < nearest {
+				nearest = d
+			}
+		}
+		scores[emotion] = 1.0 / (1.0 + nearest)
+	}
+	return scores
+}
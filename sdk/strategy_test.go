@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestLookupProcessingStrategy 测试处理策略查找与窗口换算
+// 测试内容：
+// 1. 空名称返回 standard，窗口与步进都等于 BufferSize
+// 2. low-latency 窗口减半，accurate 窗口重叠50%
+// 3. 未知策略返回错误
+func TestLookupProcessingStrategy(t *testing.T) {
+	standard, err := LookupProcessingStrategy("")
+	if err != nil || standard.Name != StrategyStandard {
+		t.Fatalf("LookupProcessingStrategy(\"\") = %v, %v; want standard", standard.Name, err)
+	}
+	if window, hop := standard.frames(4096); window != 4096 || hop != 4096 {
+		t.Errorf("standard frames = %d/%d, want 4096/4096", window, hop)
+	}
+
+	lowLatency, _ := LookupProcessingStrategy(StrategyLowLatency)
+	if window, hop := lowLatency.frames(4096); window != 2048 || hop != 2048 {
+		t.Errorf("low-latency frames = %d/%d, want 2048/2048", window, hop)
+	}
+
+	accurate, _ := LookupProcessingStrategy(StrategyAccurate)
+	if window, hop := accurate.frames(4096); window != 4096 || hop != 2048 {
+		t.Errorf("accurate frames = %d/%d, want 4096/2048", window, hop)
+	}
+
+	if _, err := LookupProcessingStrategy("turbo"); err == nil {
+		t.Error("LookupProcessingStrategy(\"turbo\") expected error")
+	}
+}
+
+// TestEngineStrategies 测试按流选择处理策略
+// 测试内容：
+// 1. low-latency 每半个 BufferSize 输出一次结果
+// 2. accurate 累积4个重叠窗口后才输出最终结果
+// 3. 开始流时指定未知策略返回错误
+func TestEngineStrategies(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	samples := generateTestAudio(440, 0.25, 44100)
+
+	if err := engine.ConfigureStream("fast", StreamSettings{Strategy: StrategyLowLatency}); err != nil {
+		t.Fatalf("ConfigureStream() error = %v", err)
+	}
+	result, _ := engine.ProcessAudio("fast", samples[:2048])
+	var parsed AudioStreamResult
+	if err := json.Unmarshal(result, &parsed); err != nil || parsed.Emotion == "" || parsed.Metadata.AudioLength != 2048 {
+		t.Errorf("low-latency 应在2048个样本后输出结果, got %s", result)
+	}
+
+	if err := engine.ConfigureStream("review", StreamSettings{Strategy: StrategyAccurate}); err != nil {
+		t.Fatalf("ConfigureStream() error = %v", err)
+	}
+	// 4个窗口需要 4096 + 3*2048 = 10240 个样本
+	result, _ = engine.ProcessAudio("review", samples[:10239])
+	if !strings.Contains(string(result), `"partial":true`) {
+		t.Errorf("accurate 段未完成时应返回中间结果, got %s", result)
+	}
+	result, _ = engine.ProcessAudio("review", samples[10239:10240])
+	if strings.Contains(string(result), `"partial"`) || !strings.Contains(string(result), `"emotion"`) {
+		t.Errorf("accurate 累积4个窗口后应输出最终结果, got %s", result)
+	}
+
+	if err := engine.ConfigureStream("bad", StreamSettings{Strategy: "turbo"}); err == nil {
+		t.Error("未知策略应返回错误")
+	}
+}
@@ -30,91 +30,77 @@ SDK 内部流程
 package main
 
 import (
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"sync"
-	"time"
 )
 
 // 全局SDK实例
 var (
-	sdk           *MeowTalkSDK // from types.go
-	mu            sync.RWMutex
-	debugMode     bool // 调试模式标志
-	mockProcessor *MockAudioProcessor
+	sdk     *MeowTalkSDK // from types.go
+	sdkRefs int          // InitializeSDK 成功的次数减去 ReleaseSDK 的次数，见 sdk_lifecycle.go
+	mu      sync.RWMutex
 )
 
 // InitializeSDK 初始化SDK
+// 已初始化时：配置相同则增加引用计数并返回 true，配置不同则返回 false（运行期间修改配置使用 UpdateSDKConfig）
 func InitializeSDK(config AudioStreamConfig) bool {
 	mu.Lock()
 	defer mu.Unlock()
 
-	// 验证配置参数
-	if config.SampleRate < MinSampleRate || config.SampleRate > MaxSampleRate {
-		fmt.Println("Error: Invalid sample rate")
-		return false
+	if sdk != nil {
+		if !reflect.DeepEqual(sdk.Config, config) {
+			fmt.Println("Error: SDK already initialized with a different config, use UpdateConfig to change it")
+			return false
+		}
+		sdkRefs++
+		return true
 	}
 
-	if config.BufferSize <= 0 {
-		fmt.Println("Error: Invalid buffer size")
+	if err := validateSDKConfig(config); err != nil {
+		fmt.Printf("Error: %v\n", err)
 		return false
 	}
 
-	if config.SampleLibraryPath == "" {
-		fmt.Println("Error: Sample library path not specified")
+	// 加载领域配置（频率范围、时长限制、情感集合等），资源包中的领域配置先于 DomainProfilePath
+	if err := applyConfigProfile(config); err != nil {
+		fmt.Println(err)
 		return false
 	}
 
-	// 创建样本库
-	sampleLib := NewSampleLibrary()
-
-	// 加载样本库文件
-	err := sampleLib.LoadFromFile(config.SampleLibraryPath)
+	// 创建处理引擎（校验频率预设并加载样本库），HTTP/WebSocket服务使用同一引擎
+	engine, err := LoadEngine(config)
 	if err != nil {
-		fmt.Printf("Failed to load sample library: %v\n", err)
+		fmt.Printf("Failed to initialize engine: %v\n", err)
 		return false
 	}
 
-	// 创建样本处理器
-	processor := &SampleProcessor{
-		Library:     sampleLib,
-		SampleRate:  config.SampleRate,
-		WindowSize:  config.BufferSize,
-		FFTSize:     2048, // 标准FFT大小
-		FrameLength: 20.0, // 20ms的帧长
-	}
-
 	// 初始化SDK实例
 	sdk = &MeowTalkSDK{
 		Config:    config,
 		Sessions:  make(map[string]*AudioStreamSession),
-		Processor: processor,
-	}
-
-	// 验证初始化
-	if len(sdk.Processor.Library.Samples) == 0 {
-		fmt.Println("Warning: Sample library is empty")
-		return false
+		Processor: newSampleProcessor(config, engine),
+		Engine:    engine,
 	}
+	sdkRefs = 1
 
 	fmt.Printf("SDK initialized with sample rate: %d Hz, buffer size: %d\n",
 		config.SampleRate, config.BufferSize)
 	return true
 }
 
-// SetDebugMode 设置调试模式
-func SetDebugMode(enabled bool) {
-	mu.Lock()
-	defer mu.Unlock()
-	debugMode = enabled
-	if enabled {
-		mockProcessor = &MockAudioProcessor{}
-	}
-}
-
 // StartAudioStream 开始音频流会话
 func StartAudioStream(streamId string) error {
+	return StartAudioStreamWithOptions(streamId, StreamOptions{}, nil)
+}
+
+// StartAudioStreamWithOptions 按流选项开始音频流会话，callback 仅在 callback 模式下使用
+func StartAudioStreamWithOptions(streamId string, options StreamOptions, callback func([]byte)) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -129,12 +115,25 @@ func StartAudioStream(streamId string) error {
 	}
 
 	// 创建新的音频流会话
-	session := &AudioStreamSession{
-		ID:               streamId,
-		FeatureExtractor: NewFeatureExtractor(sdk.Config.SampleRate),
-		Buffer:           make([]float64, 0),
-		ResultChan:       make(chan []byte, 10),
-		Active:           true,
+	session := sdk.Engine.NewSession(streamId)
+	if err := options.apply(sdk.Engine, session, callback); err != nil {
+		return err
+	}
+
+	// 开启落盘时打开流的落盘文件，resume 时以其中的样本恢复缓冲区
+	if dir := sdk.Config.SpillDir; dir != "" {
+		if previous, ok := sdk.Sessions[streamId]; ok {
+			closeSpill(previous)
+		}
+		spill, samples, base, err := openBufferSpill(dir, streamId, options.Resume)
+		if err != nil {
+			return err
+		}
+		session.spill = spill
+		if len(samples) > 0 {
+			session.SamplesReceived = base
+			sdk.Engine.Append(session, samples)
+		}
 	}
 
 	// 添加到会话映射
@@ -143,63 +142,183 @@ func StartAudioStream(streamId string) error {
 	return nil
 }
 
+// SetStreamFrequencyPreset 为指定音频流设置频率范围预设（kitten/adult/large-breed）
+func SetStreamFrequencyPreset(streamId string, preset string) error {
+	if _, err := LookupFrequencyPreset(preset); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if sdk == nil {
+		return fmt.Errorf("SDK not initialized")
+	}
+
+	session, exists := sdk.Sessions[streamId]
+	if !exists {
+		return fmt.Errorf("session not found")
+	}
+
+	return sdk.Engine.SetFrequencyPreset(session, preset)
+}
+
+// SetStreamStrategy 为指定音频流设置处理策略（standard/low-latency/accurate/template）
+func SetStreamStrategy(streamId string, strategy string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if sdk == nil {
+		return fmt.Errorf("SDK not initialized")
+	}
+
+	session, exists := sdk.Sessions[streamId]
+	if !exists {
+		return fmt.Errorf("session not found")
+	}
+
+	return sdk.Engine.SetStrategy(session, strategy)
+}
+
+// SetStreamCatProfile 设置音频流关联的猫咪档案与上下文，用于生成提示短语
+func SetStreamCatProfile(streamId string, cat CatProfile, context string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if sdk == nil {
+		return fmt.Errorf("SDK not initialized")
+	}
+
+	session, exists := sdk.Sessions[streamId]
+	if !exists {
+		return fmt.Errorf("session not found")
+	}
+
+	if cat.Priors != nil {
+		if err := cat.Priors.Validate(); err != nil {
+			return err
+		}
+	}
+
+	session.Cat = cat
+	session.Context = context
+	return nil
+}
+
+// SetStreamLanguage 设置音频流结果使用的语言，未知语言回退到英文
+func SetStreamLanguage(streamId string, lang string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if sdk == nil {
+		return fmt.Errorf("SDK not initialized")
+	}
+
+	session, exists := sdk.Sessions[streamId]
+	if !exists {
+		return fmt.Errorf("session not found")
+	}
+
+	session.Lang = NormalizeLocale(lang)
+	return nil
+}
+
 // SendAudioChunk 发送音频数据块
 func SendAudioChunk(streamId string, chunk []byte) error {
 	mu.RLock()
+	if sdk == nil {
+		mu.RUnlock()
+		return fmt.Errorf("SDK not initialized")
+	}
 	session, exists := sdk.Sessions[streamId]
+	exists = exists && session.Active
+	paused := exists && session.paused
+	engine, deterministic := sdk.Engine, sdk.Config.Deterministic
 	mu.RUnlock()
 
 	if !exists {
 		return fmt.Errorf("session not found")
 	}
+	if paused {
+		return ErrStreamPaused
+	}
 
-	// 1. 检查数据有效性
-	if len(chunk) == 0 {
-		return ErrEmptyData
+	// 1. 在分配内存前检查缓冲区溢出
+	incoming := len(chunk) / sampleWidth(session.sampleFormat)
+	if session.resampler != nil {
+		incoming = session.resampler.OutputLen(incoming)
 	}
-	if len(chunk)%2 != 0 {
-		return ErrInvalidDataLength
+	session.bufferMu.Lock()
+	overflow := len(session.Buffer)+incoming > MaxBufferSize
+	session.bufferMu.Unlock()
+	if overflow {
+		return ErrBufferOverflow
 	}
 
 	// 2. 转换音频数据为float64并检查范围
-	samples := make([]float64, len(chunk)/2)
-	for i := 0; i < len(samples); i++ {
-		sample := int16(binary.LittleEndian.Uint16(chunk[i*2 : (i+1)*2]))
-		if sample > MaxSampleValue || sample < MinSampleValue {
-			return ErrSampleOutOfRange
-		}
-		samples[i] = float64(sample) / 32768.0
+	samples, err := decodeSamples(chunk, session.sampleFormat)
+	if err != nil {
+		return err
 	}
 
-	// 3. 检查缓冲区溢出
-	if len(session.Buffer)+len(samples) > MaxBufferSize {
-		return ErrBufferOverflow
+	// 3. 采样率与配置不同时重采样后 4. 添加到缓冲区；重采样器跨块保留状态，与追加一起持锁
+	session.bufferMu.Lock()
+	if session.resampler != nil {
+		samples = session.resampler.Process(samples)
 	}
-
-	// 4. 添加到缓冲区
-	session.Buffer = append(session.Buffer, samples...)
+	engine.Append(session, samples)
+	session.spill.append(samples)
+	ready := engine.Ready(session)
+	session.bufferMu.Unlock()
 
 	// 5. 当缓冲区达到处理窗口大小时进行处理
-	if len(session.Buffer) >= sdk.Config.BufferSize {
-		go func() {
-			result, err := processBuffer(session)
-			if err == nil && result != nil {
-				select {
-				case session.ResultChan <- result:
-				default:
-					// 通道已满，丢弃结果
-				}
-			}
-		}()
+	if ready {
+		process := func() {
+			// 异步处理与后续数据追加共用同一缓冲区，需持锁处理
+			session.bufferMu.Lock()
+			defer session.bufferMu.Unlock()
+
+			processBuffer(engine, session, func(result []byte) {
+				deliverResult(session, result)
+			})
+			session.spill.compact(session)
+		}
+
+		// 确定性模式下同步处理，保证结果顺序与输入一致
+		if deterministic {
+			process()
+		} else {
+			go process()
+		}
 	}
 
 	return nil
 }
 
+// decodePCM16 将16位小端PCM数据转换为[-1, 1)范围的浮点样本
+func decodePCM16(chunk []byte) ([]float64, error) {
+	if len(chunk) == 0 {
+		return nil, ErrEmptyData
+	}
+	if len(chunk)%2 != 0 {
+		return nil, ErrInvalidDataLength
+	}
+
+	samples := make([]float64, len(chunk)/2)
+	convertPCM16(samples, chunk)
+	return samples, nil
+}
+
 // RecvMessage 接收处理结果
+// 流停止后仍可取回缓冲中剩余的结果（包括标记 final 的最后一个结果），取空后会话被移除
 func RecvMessage(streamId string) ([]byte, error) {
 	mu.RLock()
+	if sdk == nil {
+		mu.RUnlock()
+		return nil, fmt.Errorf("SDK not initialized")
+	}
 	session, exists := sdk.Sessions[streamId]
+	draining := exists && session.draining
 	mu.RUnlock()
 
 	if !exists {
@@ -210,85 +329,141 @@ func RecvMessage(streamId string) ([]byte, error) {
 	case result := <-session.ResultChan:
 		return result, nil
 	default:
-		return nil, nil
 	}
-}
 
-// processBuffer 处理音频缓冲区并返回结果
-func processBuffer(session *AudioStreamSession) ([]byte, error) {
-	if debugMode && mockProcessor != nil {
-		// 在调试模式下使用mock处理器
-		return mockProcessor.ProcessAudio(session.ID, session.Buffer)
+	if draining {
+		mu.Lock()
+		if sdk != nil && sdk.Sessions[streamId] == session {
+			delete(sdk.Sessions, streamId)
+		}
+		mu.Unlock()
 	}
+	return nil, nil
+}
 
-	if len(session.Buffer) < sdk.Config.BufferSize {
-		return nil, fmt.Errorf("buffer size too small: %d < %d", len(session.Buffer), sdk.Config.BufferSize)
+// AudioStreamStats 返回音频流的统计信息，包括因结果缓冲已满丢弃的结果数
+func AudioStreamStats(streamId string) (StreamStats, error) {
+	mu.RLock()
+	if sdk == nil {
+		mu.RUnlock()
+		return StreamStats{}, fmt.Errorf("SDK not initialized")
 	}
+	session, exists := sdk.Sessions[streamId]
+	health := sdk.Engine.Config.HealthChecks
+	mu.RUnlock()
 
-	// 1. 应用汉明窗
-	windowedSamples := applyHammingWindow(session.Buffer[:sdk.Config.BufferSize])
-
-	// 2. 提取特征
-	rawFeatures := session.FeatureExtractor.Extract(&AudioData{
-		Samples:    windowedSamples,
-		SampleRate: sdk.Config.SampleRate,
-	})
-
-	// 3. 转换为AudioFeature结构
-	feature := MapToAudioFeature(rawFeatures)
+	if !exists {
+		return StreamStats{}, fmt.Errorf("session not found")
+	}
+	return sessionStats(session, health), nil
+}
 
-	// 4. 使用样本库进行匹配
-	emotion, confidence := sdk.Processor.Library.Match(feature)
+// errorMessage 将错误包装为 {"error": "..."}，供CGO接口在无法返回结果时告知调用方原因
+func errorMessage(err error) []byte {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return data
+}
 
-	// 5. 构造结果
-	result := AudioStreamResult{
-		StreamID:   session.ID,
-		Timestamp:  time.Now().Unix(),
-		Emotion:    emotion,
-		Confidence: confidence,
-		Metadata: AudioStreamMeta{
-			AudioLength: sdk.Config.BufferSize,
-			Features:    rawFeatures,
-		},
+// RecvEmotionEvent 接收情感变化事件，没有新事件时返回nil
+func RecvEmotionEvent(streamId string) ([]byte, error) {
+	mu.RLock()
+	if sdk == nil {
+		mu.RUnlock()
+		return nil, fmt.Errorf("SDK not initialized")
 	}
+	session, exists := sdk.Sessions[streamId]
+	mu.RUnlock()
 
-	// 6. 序列化结果
-	data, err := json.Marshal(result)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %v", err)
+	if !exists {
+		return nil, fmt.Errorf("session not found")
 	}
 
-	// 7. 更新缓冲区（保留未处理的数据）
-	session.Buffer = session.Buffer[sdk.Config.BufferSize:]
+	select {
+	case event := <-session.EventChan:
+		return event, nil
+	default:
+		return nil, nil
+	}
+}
 
-	return data, nil
+// processBuffer 处理音频缓冲区，每个结果（包括段未完成时的中间结果）产生后立即交给 emit
+func processBuffer(engine *Engine, session *AudioStreamSession, emit func([]byte)) error {
+	return engine.Drain(session, emit)
 }
 
 // StopAudioStream 停止音频流会话
+// 停止前处理缓冲区中剩余的样本（叫声的结尾往往最能说明情感），最后一个结果标记 final；
+// 结果缓冲中还有结果时会话保留到 RecvMessage 取空为止
 func StopAudioStream(streamId string) error {
 	mu.Lock()
-	defer mu.Unlock()
-
+	if sdk == nil {
+		mu.Unlock()
+		return fmt.Errorf("SDK not initialized")
+	}
 	session, exists := sdk.Sessions[streamId]
-	if !exists {
+	if !exists || !session.Active {
+		mu.Unlock()
 		return fmt.Errorf("session not found")
 	}
-
 	session.Active = false
-	delete(sdk.Sessions, streamId)
+	engine := sdk.Engine
+	mu.Unlock()
+
+	flushSession(engine, session)
+	removeSpill(session)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if sdk == nil || sdk.Sessions[streamId] != session {
+		return nil
+	}
+	if session.Callback != nil || len(session.ResultChan) == 0 {
+		delete(sdk.Sessions, streamId)
+	} else {
+		session.draining = true
+	}
 	return nil
 }
 
+// flushSession 处理会话缓冲区中剩余的样本，结果按会话的取回方式交付
+func flushSession(engine *Engine, session *AudioStreamSession) {
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+
+	emit := func(result []byte) {
+		deliverResult(session, result)
+	}
+	if err := engine.Flush(session, emit); err != nil {
+		fmt.Printf("Failed to flush stream %s: %v\n", session.ID, err)
+	}
+}
+
 // ReleaseSDK 释放SDK资源
+// 引用计数减一，减到0时才停止所有会话并释放实例；未初始化时不做任何事
 func ReleaseSDK() {
 	mu.Lock()
-	defer mu.Unlock()
-
-	if sdk != nil {
-		// 停止所有会话
-		for id := range sdk.Sessions {
-			StopAudioStream(id)
+	if sdk == nil {
+		mu.Unlock()
+		return
+	}
+	if sdkRefs--; sdkRefs > 0 {
+		mu.Unlock()
+		return
+	}
+	released := sdk
+	sdk = nil
+	var sessions []*AudioStreamSession
+	for _, session := range released.Sessions {
+		if session.Active {
+			session.Active = false
+			sessions = append(sessions, session)
 		}
-		sdk = nil
+	}
+	mu.Unlock()
+
+	// 实例已摘下，之后的 InitializeSDK 创建新实例；回调模式的流仍会收到最后的结果
+	for _, session := range sessions {
+		flushSession(released.Engine, session)
+		removeSpill(session)
 	}
 }
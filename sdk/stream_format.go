@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// 客户端数据格式
+//
+// 前端为了减少传输量会对采集到的音频抽取后再发送（如 index % 100 === 0），有的客户端
+// 发送的甚至是 AnalyserNode 的频域幅度而不是时域样本。服务端无法从数据本身判断这些，
+// 因此由客户端在开始流时（/api/start 或 WebSocket 第一条消息）声明格式，
+// 所有时长、窗口长度与频率分辨率都据此换算。
+
+// 数据域
+const (
+	DomainTime      = "time"      // 时域样本
+	DomainFrequency = "frequency" // 频域幅度，每条消息为一帧覆盖 0~奈奎斯特频率 的频谱
+)
+
+// StreamFormat 客户端声明的数据格式
+type StreamFormat struct {
+	SampleRate int    `json:"sampleRate"` // 客户端采集时的原始采样率（Hz）
+	Decimation int    `json:"decimation"` // 抽取倍数：每隔多少个原始样本保留一个，1表示未抽取
+	Domain     string `json:"domain"`     // 数据域 time/frequency，为空时为 time
+}
+
+// LegacyStreamFormat 未声明格式的客户端使用的格式：44100Hz 采集后按 index % 100 抽取的时域数据
+func LegacyStreamFormat() StreamFormat {
+	return StreamFormat{SampleRate: 44100, Decimation: 100, Domain: DomainTime}
+}
+
+// IsZero 客户端是否未声明格式
+func (f StreamFormat) IsZero() bool {
+	return f == StreamFormat{}
+}
+
+// withDefaults 补全可省略的字段
+func (f StreamFormat) withDefaults() StreamFormat {
+	if f.Decimation == 0 {
+		f.Decimation = 1
+	}
+	if f.Domain == "" {
+		f.Domain = DomainTime
+	}
+	return f
+}
+
+// Validate 校验数据格式
+func (f StreamFormat) Validate() error {
+	f = f.withDefaults()
+	if f.SampleRate < MinSampleRate || f.SampleRate > MaxSampleRate {
+		return fmt.Errorf("stream format: sampleRate %d out of range [%d, %d]", f.SampleRate, MinSampleRate, MaxSampleRate)
+	}
+	if f.Decimation < 1 || f.Decimation > f.SampleRate {
+		return fmt.Errorf("stream format: decimation %d out of range [1, %d]", f.Decimation, f.SampleRate)
+	}
+	if f.Domain != DomainTime && f.Domain != DomainFrequency {
+		return fmt.Errorf("stream format: unknown domain %q (available: %s, %s)", f.Domain, DomainTime, DomainFrequency)
+	}
+	return nil
+}
+
+// Rate 收到的数据每秒包含的样本数
+func (f StreamFormat) Rate() float64 {
+	f = f.withDefaults()
+	return float64(f.SampleRate) / float64(f.Decimation)
+}
+
+// Samples 指定时长（秒）对应的样本数，至少为1
+func (f StreamFormat) Samples(seconds float64) int {
+	n := int(math.Round(seconds * f.Rate()))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// Seconds 指定样本数对应的时长（秒）
+func (f StreamFormat) Seconds(samples int) float64 {
+	return float64(samples) / f.Rate()
+}
+
+// Clock 确定性模式下按已接收的样本数推算的流时钟
+func (f StreamFormat) Clock(samples int64) time.Time {
+	return deterministicEpoch.Add(time.Duration(float64(samples) / f.Rate() * float64(time.Second)))
+}
+
+// BinHz 频域数据中每个频点的宽度（Hz），bins 为一帧的频点数
+func (f StreamFormat) BinHz(bins int) float64 {
+	if bins <= 0 {
+		return 0
+	}
+	return float64(f.SampleRate) / 2 / float64(bins)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestStreamFormat 测试客户端数据格式的校验与换算
+// 测试内容：
+// 1. 非法采样率、抽取倍数与数据域被拒绝，省略的字段使用默认值
+// 2. 时长与样本数按有效采样率换算，频点宽度按原始采样率换算
+// 3. 引擎拒绝与其采样率不一致的格式
+func TestStreamFormat(t *testing.T) {
+	invalid := []StreamFormat{
+		{SampleRate: 100},
+		{SampleRate: 44100, Decimation: -1},
+		{SampleRate: 44100, Domain: "wavelet"},
+	}
+	for i, f := range invalid {
+		if err := f.Validate(); err == nil {
+			t.Errorf("format #%d should be invalid: %+v", i, f)
+		}
+	}
+
+	format := StreamFormat{SampleRate: 44100, Decimation: 10}
+	if err := format.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := format.Samples(0.5); got != 2205 {
+		t.Errorf("Samples(0.5) = %d, want 2205", got)
+	}
+	if got := format.Seconds(4410); got != 1 {
+		t.Errorf("Seconds(4410) = %v, want 1", got)
+	}
+	if got := format.BinHz(1024); math.Abs(got-21.533) > 0.001 {
+		t.Errorf("BinHz(1024) = %v, want 21.533", got)
+	}
+
+	server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+	for body, want := range map[string]int{
+		`{"streamId":"cat1","format":{"sampleRate":44100}}`:                      http.StatusOK,
+		`{"streamId":"cat2","format":{"sampleRate":44100,"decimation":10}}`:      http.StatusBadRequest,
+		`{"streamId":"cat3","format":{"sampleRate":48000,"domain":"frequency"}}`: http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		server.handleStart(rec, httptest.NewRequest(http.MethodPost, "/start", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Errorf("/start %s status = %d, want %d", body, rec.Code, want)
+		}
+	}
+
+	if f, ok := decodeWebSocketFormat([]byte(`{"format":{"sampleRate":48000,"decimation":4}}`)); !ok || f.SampleRate != 48000 || f.Decimation != 4 {
+		t.Errorf("decodeWebSocketFormat() = %+v, %v", f, ok)
+	}
+	if _, ok := decodeWebSocketFormat([]byte(`[0.1,0.2]`)); ok {
+		t.Error("音频消息不应被识别为格式声明")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"soundsdk/dsp"
+)
+
+// 流选项
+//
+// CGO 接口的 StartStream 只接收流ID，采样率、数据格式、猫咪档案等只能靠 InitSDK 的全局配置
+// 或事后逐项调用 SetStream*，结果的取回方式与缓冲大小则完全无法设置。StartStreamEx 接收一段
+// JSON 形式的选项，在会话创建时一次性应用；所有字段可省略，省略时与 StartStream 的行为一致。
+
+// 样本格式
+const (
+	SampleFormatPCM16   = "pcm16"   // 16位有符号小端整数
+	SampleFormatFloat32 = "float32" // 32位小端浮点数，范围 [-1, 1]
+)
+
+// 结果取回方式
+const (
+	CallbackModePoll     = "poll"     // 调用方通过 RecvMessage 轮询，缓冲已满时按溢出策略处理
+	CallbackModeLatest   = "latest"   // 轮询，溢出策略固定为 drop-oldest，只关心最新状态的界面使用
+	CallbackModeCallback = "callback" // 每个结果产生后立即回调，不经过缓冲
+)
+
+// latencyPresets 延迟预设对应的处理策略
+var latencyPresets = map[string]string{
+	"low":      StrategyLowLatency,
+	"balanced": StrategyStandard,
+	"high":     StrategyAccurate,
+}
+
+// StreamOptions StartStreamEx 的流选项
+type StreamOptions struct {
+	SampleRate        int        `json:"sampleRate"`        // 送入数据的采样率，与 InitSDK 配置不同时重采样，为0时与配置一致
+	Format            string     `json:"format"`            // 样本格式 pcm16/float32，为空时为 pcm16
+	Cat               CatProfile `json:"cat"`               // 关联的猫咪档案
+	Context           string     `json:"context"`           // 上下文标签，如 feeding
+	Lang              string     `json:"lang"`              // 结果语言 en/zh/ja/es
+	FrequencyPreset   string     `json:"frequencyPreset"`   // 频率范围预设 kitten/adult/large-breed
+	Latency           string     `json:"latency"`           // 延迟预设 low/balanced/high，为空时使用引擎默认策略
+	CallbackMode      string     `json:"callbackMode"`      // 结果取回方式 poll/latest/callback，为空时为 poll
+	ResultBufferSize  int        `json:"resultBufferSize"`  // 结果缓冲可容纳的结果数，为0时使用SDK配置
+	OverflowPolicy    string     `json:"overflowPolicy"`    // 结果缓冲已满时的策略 drop-newest/drop-oldest/block，为空时使用SDK配置
+	OverflowTimeoutMs int        `json:"overflowTimeoutMs"` // block 策略的等待时长（毫秒），为0时使用SDK配置
+	Resume            bool       `json:"resume"`            // 从SDK配置 spillDir 中该流的落盘文件恢复缓冲区，用于应用被系统杀掉后重启
+
+	*StreamHints // 情境提示与情感先验，JSON中与其他选项同级；以指针嵌入使 StreamOptions 仍可比较
+}
+
+// StreamHints 开始流时传入的情境提示与情感先验（见 context_hints.go）
+type StreamHints struct {
+	Hints         []string           `json:"hints"`         // 情境提示，如 pre-feeding、vet-visit
+	EmotionPriors map[string]float64 `json:"emotionPriors"` // 直接注入的情感先验倍数，如 {"for_food": 1.5}
+}
+
+// ParseStreamOptions 解析并校验JSON形式的流选项，空字符串表示全部使用默认值
+func ParseStreamOptions(data []byte) (StreamOptions, error) {
+	var options StreamOptions
+	if len(data) == 0 {
+		return options, nil
+	}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return options, fmt.Errorf("stream options: %v", err)
+	}
+	return options, options.Validate()
+}
+
+// Validate 校验流选项，频率预设与先验在应用到会话时校验
+func (o StreamOptions) Validate() error {
+	if o.SampleRate != 0 && (o.SampleRate < MinSampleRate || o.SampleRate > MaxSampleRate) {
+		return fmt.Errorf("stream options: sampleRate %d out of range [%d, %d]", o.SampleRate, MinSampleRate, MaxSampleRate)
+	}
+	switch o.Format {
+	case "", SampleFormatPCM16, SampleFormatFloat32:
+	default:
+		return fmt.Errorf("stream options: unknown format %q (available: %s, %s)", o.Format, SampleFormatPCM16, SampleFormatFloat32)
+	}
+	if _, ok := latencyPresets[o.Latency]; o.Latency != "" && !ok {
+		return fmt.Errorf("stream options: unknown latency %q (available: low, balanced, high)", o.Latency)
+	}
+	switch o.CallbackMode {
+	case "", CallbackModePoll, CallbackModeLatest, CallbackModeCallback:
+	default:
+		return fmt.Errorf("stream options: unknown callbackMode %q (available: %s, %s, %s)",
+			o.CallbackMode, CallbackModePoll, CallbackModeLatest, CallbackModeCallback)
+	}
+	if err := validateResultBuffer(o.ResultBufferSize, o.OverflowPolicy, o.OverflowTimeoutMs); err != nil {
+		return fmt.Errorf("stream options: %v", err)
+	}
+	if o.CallbackMode == CallbackModeLatest && o.OverflowPolicy != "" && o.OverflowPolicy != OverflowDropOldest {
+		return fmt.Errorf("stream options: callbackMode %s implies overflowPolicy %s, got %s", CallbackModeLatest, OverflowDropOldest, o.OverflowPolicy)
+	}
+	return nil
+}
+
+// apply 将选项应用到新建的会话，callback 仅在 callback 模式下使用
+func (o StreamOptions) apply(engine *Engine, session *AudioStreamSession, callback func([]byte)) error {
+	if o.CallbackMode == CallbackModeCallback && callback == nil {
+		return fmt.Errorf("stream options: callbackMode %s requires a callback", CallbackModeCallback)
+	}
+
+	settings := StreamSettings{
+		FrequencyPreset: o.FrequencyPreset,
+		Strategy:        latencyPresets[o.Latency],
+		Cat:             o.Cat,
+		Context:         o.Context,
+		Lang:            o.Lang,
+	}
+	if o.StreamHints != nil {
+		settings.Hints = o.Hints
+		settings.EmotionPriors = o.EmotionPriors
+	}
+	if err := engine.applySettings(session, settings); err != nil {
+		return err
+	}
+
+	if o.SampleRate > 0 && o.SampleRate != engine.Config.SampleRate {
+		session.resampler = dsp.NewResampler(o.SampleRate, engine.Config.SampleRate)
+	}
+	session.sampleFormat = o.Format
+
+	// 未设置的缓冲选项沿用SDK配置
+	size, policy, timeoutMs := o.ResultBufferSize, o.OverflowPolicy, o.OverflowTimeoutMs
+	if size == 0 {
+		size = engine.Config.ResultBufferSize
+	}
+	if o.CallbackMode == CallbackModeLatest {
+		policy = OverflowDropOldest
+	}
+	if policy == "" {
+		policy = engine.Config.OverflowPolicy
+	}
+	if timeoutMs == 0 {
+		timeoutMs = engine.Config.OverflowTimeoutMs
+	}
+	setResultBuffer(session, size, policy, timeoutMs)
+	if o.CallbackMode == CallbackModeCallback {
+		session.Callback = callback
+	}
+	return nil
+}
+
+// sampleWidth 每个样本的字节数
+func sampleWidth(format string) int {
+	if format == SampleFormatFloat32 {
+		return 4
+	}
+	return 2
+}
+
+// decodeSamples 按会话的样本格式解码数据
+func decodeSamples(chunk []byte, format string) ([]float64, error) {
+	if format != SampleFormatFloat32 {
+		return decodePCM16(chunk)
+	}
+	if len(chunk) == 0 {
+		return nil, ErrEmptyData
+	}
+	if len(chunk)%4 != 0 {
+		return nil, ErrInvalidDataLength
+	}
+
+	samples := make([]float64, len(chunk)/4)
+	for i := range samples {
+		sample := float64(math.Float32frombits(binary.LittleEndian.Uint32(chunk[i*4:])))
+		if math.IsNaN(sample) || math.IsInf(sample, 0) {
+			return nil, ErrInvalidSample
+		}
+		if sample < -1 || sample > 1 {
+			return nil, ErrSampleOutOfRange
+		}
+		samples[i] = sample
+	}
+	return samples, nil
+}
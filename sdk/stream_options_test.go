@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// TestStreamOptions 测试带选项开始音频流
+// 测试内容：
+// 1. 选项JSON的解析与校验，callback 模式缺少回调时失败
+// 2. float32 格式、16kHz 数据重采样后产生结果，callback 模式每个结果回调一次
+// 3. latest 模式缓冲满时保留最新的结果，延迟预设与语言应用到会话
+func TestStreamOptions(t *testing.T) {
+	for _, bad := range []string{
+		`{"sampleRate": 100}`,
+		`{"format": "mulaw"}`,
+		`{"latency": "fast"}`,
+		`{"callbackMode": "push"}`,
+		`{"resultBufferSize": 5000}`,
+		`{"sampleRate": "16000"}`,
+	} {
+		if _, err := ParseStreamOptions([]byte(bad)); err == nil {
+			t.Errorf("ParseStreamOptions(%s) should fail", bad)
+		}
+	}
+	if options, err := ParseStreamOptions(nil); err != nil || options != (StreamOptions{}) {
+		t.Errorf("empty options = %+v, %v", options, err)
+	}
+
+	testDir := t.TempDir()
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatal(err)
+	}
+	if !InitializeSDK(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, SampleLibraryPath: testDir + "/sample_library.json", Deterministic: true}) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	defer ReleaseSDK()
+
+	if err := StartAudioStreamWithOptions("cb", StreamOptions{CallbackMode: CallbackModeCallback}, nil); err == nil {
+		t.Error("callback mode without a callback should fail")
+	}
+
+	options, err := ParseStreamOptions([]byte(`{"sampleRate": 16000, "format": "float32", "lang": "zh", "callbackMode": "callback"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results [][]byte
+	if err := StartAudioStreamWithOptions("cb", options, func(result []byte) { results = append(results, result) }); err != nil {
+		t.Fatal(err)
+	}
+	defer StopAudioStream("cb")
+	audio := generateTestAudio(440, 0.5, 16000)
+	chunk := make([]byte, 4*len(audio))
+	for i, sample := range audio {
+		binary.LittleEndian.PutUint32(chunk[i*4:], math.Float32bits(float32(sample*0.5)))
+	}
+	if err := SendAudioChunk("cb", chunk); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Fatal("callback mode produced no results")
+	}
+	var parsed AudioStreamResult
+	if err := json.Unmarshal(results[0], &parsed); err != nil || parsed.Label != EmotionLabel("zh", parsed.Emotion) {
+		t.Errorf("callback result = %s, %v", results[0], err)
+	}
+	if queued := len(sdk.Sessions["cb"].ResultChan); queued != 0 {
+		t.Errorf("callback mode should not queue results, got %d", queued)
+	}
+	if err := SendAudioChunk("cb", chunk[:6]); err != ErrInvalidDataLength {
+		t.Errorf("float32 chunk of 6 bytes = %v, want ErrInvalidDataLength", err)
+	}
+
+	options = StreamOptions{Latency: "low", CallbackMode: CallbackModeLatest, ResultBufferSize: 1}
+	if err := StartAudioStreamWithOptions("latest", options, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer StopAudioStream("latest")
+	session := sdk.Sessions["latest"]
+	if session.Strategy.Name != StrategyLowLatency || cap(session.ResultChan) != 1 {
+		t.Errorf("session strategy = %s, buffer = %d", session.Strategy.Name, cap(session.ResultChan))
+	}
+	deliverResult(session, []byte("first"))
+	deliverResult(session, []byte("second"))
+	if result := <-session.ResultChan; string(result) != "second" {
+		t.Errorf("latest mode kept %q, want second", result)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 暂停与恢复
+//
+// 应用切到后台时通常会停止录音，但停止流会丢掉缓冲区、段内累积与情感平滑状态，回到前台后
+// 只能从头开始。暂停期间流不接收也不处理音频（不占用CPU），会话状态原样保留；恢复时缓冲样本的
+// 到达时间顺延暂停的时长，结果延迟不把后台时间计算在内。
+// HTTP/WebSocket 服务的暂停状态保存在本副本中，多副本部署时 /pause 与 /send 需由同一副本处理。
+
+// StreamPauser 支持暂停与恢复流的处理器（Engine），恢复时顺延缓冲样本的到达时间
+type StreamPauser interface {
+	PauseStream(streamID string)
+	ResumeStream(streamID string)
+}
+
+// pauseSession 记录会话暂停的时间（调用方需保证会话不在处理中）
+func (e *Engine) pauseSession(session *AudioStreamSession) {
+	if session.pausedAt.IsZero() {
+		session.pausedAt = e.now(session)
+	}
+}
+
+// resumeSession 将缓冲样本与当前段的到达时间顺延暂停的时长（调用方需保证会话不在处理中）
+func (e *Engine) resumeSession(session *AudioStreamSession) {
+	if session.pausedAt.IsZero() {
+		return
+	}
+	paused := e.now(session).Sub(session.pausedAt)
+	session.pausedAt = time.Time{}
+	for i := range session.arrivals {
+		session.arrivals[i].At = session.arrivals[i].At.Add(paused)
+	}
+	if !session.segmentArrival.IsZero() {
+		session.segmentArrival = session.segmentArrival.Add(paused)
+	}
+}
+
+// PauseStream 暂停流
+func (e *Engine) PauseStream(streamID string) {
+	if session, ok := e.lookupSession(streamID); ok {
+		session.bufferMu.Lock()
+		e.pauseSession(session)
+		session.bufferMu.Unlock()
+	}
+}
+
+// ResumeStream 恢复流
+func (e *Engine) ResumeStream(streamID string) {
+	if session, ok := e.lookupSession(streamID); ok {
+		session.bufferMu.Lock()
+		e.resumeSession(session)
+		session.bufferMu.Unlock()
+	}
+}
+
+// PauseAudioStream 暂停音频流会话：保留缓冲区与情感状态，恢复前 SendAudioChunk 返回 ErrStreamPaused
+func PauseAudioStream(streamId string) error {
+	mu.Lock()
+	if sdk == nil {
+		mu.Unlock()
+		return fmt.Errorf("SDK not initialized")
+	}
+	session, exists := sdk.Sessions[streamId]
+	if !exists || !session.Active {
+		mu.Unlock()
+		return fmt.Errorf("session not found")
+	}
+	session.paused = true
+	engine := sdk.Engine
+	mu.Unlock()
+
+	// 等待正在进行的处理结束
+	session.bufferMu.Lock()
+	engine.pauseSession(session)
+	session.bufferMu.Unlock()
+	return nil
+}
+
+// ResumeAudioStream 恢复暂停的音频流会话
+func ResumeAudioStream(streamId string) error {
+	mu.Lock()
+	if sdk == nil {
+		mu.Unlock()
+		return fmt.Errorf("SDK not initialized")
+	}
+	session, exists := sdk.Sessions[streamId]
+	if !exists || !session.Active {
+		mu.Unlock()
+		return fmt.Errorf("session not found")
+	}
+	engine := sdk.Engine
+	mu.Unlock()
+
+	session.bufferMu.Lock()
+	engine.resumeSession(session)
+	session.bufferMu.Unlock()
+
+	mu.Lock()
+	session.paused = false
+	mu.Unlock()
+	return nil
+}
+
+// streamPauses HTTP/WebSocket 服务中已暂停的流
+type streamPauses struct {
+	mu     sync.Mutex
+	paused map[string]bool
+}
+
+// setPaused 设置流的暂停状态，处理器支持时同步暂停或恢复
+func (s *AudioServer) setPaused(streamID string, paused bool) {
+	p := &s.pauses
+	p.mu.Lock()
+	if p.paused == nil {
+		p.paused = make(map[string]bool)
+	}
+	wasPaused := p.paused[streamID]
+	if paused {
+		p.paused[streamID] = true
+	} else {
+		delete(p.paused, streamID)
+	}
+	p.mu.Unlock()
+
+	if pauser, ok := s.processor.(StreamPauser); ok && wasPaused != paused {
+		if paused {
+			pauser.PauseStream(streamID)
+		} else {
+			pauser.ResumeStream(streamID)
+		}
+	}
+}
+
+// isPaused 流是否已暂停
+func (s *AudioServer) isPaused(streamID string) bool {
+	s.pauses.mu.Lock()
+	defer s.pauses.mu.Unlock()
+	return s.pauses.paused[streamID]
+}
+
+// handlePause 暂停流：POST /pause {"streamId": "..."}
+func (s *AudioServer) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.handlePauseState(w, r, true)
+}
+
+// handleResume 恢复流：POST /resume {"streamId": "..."}
+func (s *AudioServer) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.handlePauseState(w, r, false)
+}
+
+// handlePauseState 处理 /pause 与 /resume 请求
+func (s *AudioServer) handlePauseState(w http.ResponseWriter, r *http.Request, paused bool) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	var request struct {
+		StreamID string `json:"streamId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "解析请求参数失败", err.Error())
+		return
+	}
+	if request.StreamID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingStreamID, "缺少 StreamID", "")
+		return
+	}
+
+	s.setPaused(request.StreamID, paused)
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"streamId": request.StreamID,
+		"paused":   paused,
+	})
+}
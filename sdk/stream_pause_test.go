@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPauseAudioStream 测试暂停与恢复流
+// 测试内容：
+// 1. CGO接口暂停后 SendAudioChunk 返回 ErrStreamPaused，缓冲区保留，恢复后接着缓冲区继续处理
+// 2. 恢复时缓冲样本的到达时间顺延暂停的时长
+// 3. HTTP 暂停后 /send 返回 409，恢复后正常处理，/stop 清除暂停状态
+func TestPauseAudioStream(t *testing.T) {
+	testDir := t.TempDir()
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatal(err)
+	}
+	if !InitializeSDK(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, SampleLibraryPath: testDir + "/sample_library.json", Deterministic: true}) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	defer ReleaseSDK()
+
+	if err := StartAudioStream("cat1"); err != nil {
+		t.Fatal(err)
+	}
+	defer StopAudioStream("cat1")
+	session := sdk.Sessions["cat1"]
+
+	if err := SendAudioChunk("cat1", generateTestPCMData(3000.0/44100, 44100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := PauseAudioStream("cat1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SendAudioChunk("cat1", generateTestPCMData(0.1, 44100)); err != ErrStreamPaused {
+		t.Errorf("send while paused = %v, want ErrStreamPaused", err)
+	}
+	if len(session.Buffer) != 3000 {
+		t.Errorf("buffer while paused = %d samples, want 3000", len(session.Buffer))
+	}
+	if err := PauseAudioStream("missing"); err == nil {
+		t.Error("pausing a missing stream should fail")
+	}
+
+	// 确定性模式下流时钟由样本数推算，模拟暂停期间的时间流逝
+	arrival := session.arrivals[0].At
+	session.pausedAt = session.pausedAt.Add(-5 * time.Second)
+	if err := ResumeAudioStream("cat1"); err != nil {
+		t.Fatal(err)
+	}
+	if shifted := session.arrivals[0].At.Sub(arrival); shifted != 5*time.Second {
+		t.Errorf("arrival shifted by %v, want 5s", shifted)
+	}
+	if err := SendAudioChunk("cat1", generateTestPCMData(1200.0/44100, 44100)); err != nil {
+		t.Fatal(err)
+	}
+	if len(session.ResultChan) != 1 {
+		t.Errorf("resumed stream should continue the buffer and produce a result, queued %d", len(session.ResultChan))
+	}
+
+	server := NewAudioServer(NewMockAudioProcessor())
+	post := func(path, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+	send := func() int {
+		body, _ := json.Marshal(map[string]interface{}{"streamId": "web", "data": generateTestAudio(440, 0.05, 44100)})
+		rec := httptest.NewRecorder()
+		server.handleSend(rec, httptest.NewRequest(http.MethodPost, "/send", bytes.NewReader(body)))
+		return rec.Code
+	}
+	if rec := post("/pause", `{"streamId":"web"}`, server.handlePause); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"paused":true`) {
+		t.Fatalf("pause = %d %s", rec.Code, rec.Body.String())
+	}
+	if code := send(); code != http.StatusConflict {
+		t.Errorf("send while paused = %d, want 409", code)
+	}
+	post("/resume", `{"streamId":"web"}`, server.handleResume)
+	if code := send(); code != http.StatusOK {
+		t.Errorf("send after resume = %d, want 200", code)
+	}
+	post("/pause", `{"streamId":"web"}`, server.handlePause)
+	post("/stop", `{"streamId":"web"}`, server.handleStop)
+	if server.isPaused("web") {
+		t.Error("stop should clear the paused state")
+	}
+	if rec := post("/pause", `{}`, server.handlePause); rec.Code != http.StatusBadRequest {
+		t.Errorf("pause without streamId = %d, want 400", rec.Code)
+	}
+}
@@ -503,3 +503,157 @@ func TestConcurrentStreams(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TestUninitializedSDK 测试SDK未初始化时的接口
+// 测试内容：接收事件、停止流返回错误而不是空指针崩溃，错误可包装为带 error 字段的JSON
+func TestUninitializedSDK(t *testing.T) {
+	ReleaseSDK()
+
+	if _, err := RecvEmotionEvent("cat1"); err == nil {
+		t.Error("RecvEmotionEvent() without SDK should fail")
+	}
+	err := StopAudioStream("cat1")
+	if err == nil {
+		t.Fatal("StopAudioStream() without SDK should fail")
+	}
+
+	var message struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(errorMessage(err), &message) != nil || message.Error != "SDK not initialized" {
+		t.Errorf("errorMessage() = %s", errorMessage(err))
+	}
+}
+
+// TestStopAudioStreamFlush 测试停止音频流时处理剩余样本
+// 测试内容：
+// 1. accurate 策略下停止时剩余样本产生标记 final 的结果，未完成的段随之结束
+// 2. 结果缓冲中还有结果时会话保留，停止后不再接收数据，重复停止返回错误
+// 3. 剩余样本不足窗口的四分之一时丢弃，会话立即移除
+func TestStopAudioStreamFlush(t *testing.T) {
+	testDir := t.TempDir()
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatal(err)
+	}
+	config := AudioStreamConfig{
+		SampleRate:        44100,
+		BufferSize:        4096,
+		SampleLibraryPath: testDir + "/sample_library.json",
+		Strategy:          StrategyAccurate,
+		Deterministic:     true,
+	}
+	if !InitializeSDK(config) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	defer ReleaseSDK()
+
+	collect := func(session *AudioStreamSession) []AudioStreamResult {
+		var results []AudioStreamResult
+		for len(session.ResultChan) > 0 {
+			var result AudioStreamResult
+			json.Unmarshal(<-session.ResultChan, &result)
+			results = append(results, result)
+		}
+		return results
+	}
+
+	if err := StartAudioStream("flush"); err != nil {
+		t.Fatal(err)
+	}
+	session := sdk.Sessions["flush"]
+	// 6000个样本：1个窗口（段未完成）后剩余3952个样本
+	if err := SendAudioChunk("flush", generateTestPCMData(6000.0/44100, 44100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := StopAudioStream("flush"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sdk.Sessions["flush"]; !ok {
+		t.Fatal("session with queued results should stay until drained")
+	}
+	if err := SendAudioChunk("flush", generateTestPCMData(0.1, 44100)); err == nil {
+		t.Error("stopped stream should not accept audio")
+	}
+	if err := StopAudioStream("flush"); err == nil {
+		t.Error("stopping twice should fail")
+	}
+	results := collect(session)
+	if len(results) != 2 || !results[0].Partial || results[1].Partial || !results[1].Final || results[1].Metadata.AudioLength != 6000-2048 {
+		t.Fatalf("results = %+v, want a partial then a final result over 3952 samples", results)
+	}
+
+	if err := StartAudioStream("short"); err != nil {
+		t.Fatal(err)
+	}
+	session = sdk.Sessions["short"]
+	if err := SendAudioChunk("short", generateTestPCMData(800.0/44100, 44100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := StopAudioStream("short"); err != nil {
+		t.Fatal(err)
+	}
+	if results := collect(session); len(results) != 0 {
+		t.Errorf("800 residual samples should be discarded, got %+v", results)
+	}
+	if _, ok := sdk.Sessions["short"]; ok {
+		t.Error("session without queued results should be removed on stop")
+	}
+}
+
+// TestStreamSettingsSwitchDuringProcessing 测试处理过程中切换流的策略与频率预设
+// 测试内容：切换与异步处理共用 bufferMu，数据发送与切换并发进行时没有数据竞争（配合 -race 运行）
+func TestStreamSettingsSwitchDuringProcessing(t *testing.T) {
+	testDir, err := setupTestEnvironment()
+	if err != nil {
+		t.Fatalf("Failed to setup test environment: %v", err)
+	}
+	defer cleanupTestEnvironment(testDir)
+	if err := createTestSampleLibrary(testDir); err != nil {
+		t.Fatalf("Failed to create test sample library: %v", err)
+	}
+
+	config := AudioStreamConfig{
+		SampleRate:        44100,
+		BufferSize:        4096,
+		SampleLibraryPath: testDir + "/sample_library.json",
+	}
+	if !InitializeSDK(config) {
+		t.Fatal("Failed to initialize SDK")
+	}
+	defer ReleaseSDK()
+
+	streamID := "switch_stream"
+	if err := StartAudioStream(streamID); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		strategies := []string{"low-latency", "standard"}
+		presets := []string{FrequencyPresetKitten, FrequencyPresetAdult}
+		for i := 0; i < 20; i++ {
+			if err := SetStreamStrategy(streamID, strategies[i%2]); err != nil {
+				t.Error(err)
+			}
+			if err := SetStreamFrequencyPreset(streamID, presets[i%2]); err != nil {
+				t.Error(err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	chunk := generateTestPCMData(0.1, 44100)
+	for i := 0; i < 10; i++ {
+		if err := SendAudioChunk(streamID, chunk); err != nil && err != ErrBufferOverflow {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	wg.Wait()
+
+	if err := StopAudioStream(streamID); err != nil {
+		t.Fatal(err)
+	}
+}
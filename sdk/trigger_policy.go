@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// DefaultSilenceThreshold 默认静默阈值：20ms窗口的RMS低于该值视为静默
+const DefaultSilenceThreshold = 0.02
+
+// TriggerPolicy 缓冲处理触发条件，满足任一条件即处理缓冲区
+// 通话类应用希望尽早出结果，录音分析类应用希望等叫声完整结束，因此各条件均可配置。
+// 处理引擎在配置了 AudioStreamConfig.Trigger（或 SetTriggerPolicy）时按触发条件处理，同时跳过静默窗口、按静默划分叫声段：段内每个窗口输出 partial 中间结果，
+// 叫声后出现 SilenceDuration 的静默或段长达到 MaxBufferTime 时输出最终结果。未配置时每满一个分析窗口即处理
+type TriggerPolicy struct {
+	MinWindows      int     `json:"minWindows"`      // 至少形成多少个滑动窗口后处理，0表示不按窗口数触发
+	SilenceDuration float64 `json:"silenceDuration"` // 判定叫声结束的最短静默时长（秒），同时用于分段
+	MaxBufferTime   float64 `json:"maxBufferTime"`   // 缓冲区达到该时长（秒）时强制处理
+	MinProcessTime  float64 `json:"minProcessTime"`  // 缓冲区至少达到该时长（秒），0表示不启用该条件
+	MinInterval     float64 `json:"minInterval"`     // 与 MinProcessTime 配合：距上次处理超过该间隔（秒）时处理
+}
+
+// DefaultTriggerPolicy 默认触发条件：3个窗口、0.3秒静默、最长缓冲5秒、1秒缓冲且间隔0.5秒
+func DefaultTriggerPolicy() TriggerPolicy {
+	return TriggerPolicy{
+		MinWindows:      3,
+		SilenceDuration: 0.3,
+		MaxBufferTime:   5.0,
+		MinProcessTime:  1.0,
+		MinInterval:     0.5,
+	}
+}
+
+// Validate 校验触发条件
+func (p TriggerPolicy) Validate() error {
+	if p.MinWindows < 0 || p.MinProcessTime < 0 || p.MinInterval < 0 {
+		return fmt.Errorf("trigger policy: minWindows/minProcessTime/minInterval must be non-negative")
+	}
+	if p.SilenceDuration <= 0 {
+		return fmt.Errorf("trigger policy: silenceDuration must be positive")
+	}
+	if p.MaxBufferTime <= 0 {
+		return fmt.Errorf("trigger policy: maxBufferTime must be positive")
+	}
+	if p.MinProcessTime > p.MaxBufferTime {
+		return fmt.Errorf("trigger policy: minProcessTime %.2fs exceeds maxBufferTime %.2fs", p.MinProcessTime, p.MaxBufferTime)
+	}
+	return nil
+}
+
+// LoadTriggerPolicy 从JSON文件加载触发条件，文件中未出现的字段使用默认值
+func LoadTriggerPolicy(path string) (TriggerPolicy, error) {
+	policy := DefaultTriggerPolicy()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("read trigger policy: %v", err)
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("parse trigger policy: %v", err)
+	}
+	return policy, policy.Validate()
+}
+
+// SetTriggerPolicy 设置缓冲处理触发条件，之后的处理按新条件触发
+func (e *Engine) SetTriggerPolicy(policy TriggerPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+	e.trigger = &policy
+	return nil
+}
+
+// triggerPolicy 返回当前的触发条件（未设置时为nil）与能量阈值
+func (e *Engine) triggerPolicy() (*TriggerPolicy, EnergyThresholds) {
+	e.settingsMu.RLock()
+	defer e.settingsMu.RUnlock()
+	return e.trigger, e.thresholds
+}
+
+// shouldTrigger 会话缓冲区是否满足触发条件，满足任一条件即处理
+func (e *Engine) shouldTrigger(session *AudioStreamSession, policy *TriggerPolicy, silence float64, window, hop int) bool {
+	rate := float64(e.Config.SampleRate)
+	buffered := float64(len(session.Buffer)) / rate
+
+	// 条件1：至少形成 MinWindows 个完整窗口
+	if policy.MinWindows > 0 && 1+(len(session.Buffer)-window)/hop >= policy.MinWindows {
+		return true
+	}
+	// 条件2：缓冲区超过最大缓冲时间
+	if buffered >= policy.MaxBufferTime {
+		return true
+	}
+	// 条件3：超过最小处理时间，且自上次处理已经过去了足够长的时间
+	if policy.MinProcessTime > 0 && buffered >= policy.MinProcessTime &&
+		e.now(session).Sub(session.lastTrigger).Seconds() >= policy.MinInterval {
+		return true
+	}
+	// 条件4：缓冲区以足够长的静默结尾且之前有声音，叫声可能已结束
+	trailing := silentSamples(session.Buffer, e.Config.SampleRate, silence, true)
+	return trailing < len(session.Buffer) && float64(trailing) >= policy.SilenceDuration*rate
+}
+
+// silentSamples 从 samples 开头（fromEnd 时从结尾）数起连续静默的20ms帧覆盖的样本数
+func silentSamples(samples []float64, sampleRate int, threshold float64, fromEnd bool) int {
+	frame := max(10, int(segmentFrameDuration*float64(sampleRate))) // 窗口至少10个样本
+	n := 0
+	for n+frame <= len(samples) {
+		chunk := samples[n : n+frame]
+		if fromEnd {
+			chunk = samples[len(samples)-n-frame : len(samples)-n]
+		}
+		if math.Sqrt(calculateEnergy(chunk)/float64(frame)) >= threshold {
+			break
+		}
+		n += frame
+	}
+	return n
+}
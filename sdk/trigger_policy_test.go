@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTriggerPolicy 测试缓冲处理触发条件
+// 测试内容：
+// 1. 默认条件合法，非法条件被拒绝
+// 2. 从文件加载时未出现的字段使用默认值
+// 3. 缩短最大缓冲时间后，默认条件下仍在等待的数据会被处理
+func TestTriggerPolicy(t *testing.T) {
+	if err := DefaultTriggerPolicy().Validate(); err != nil {
+		t.Fatalf("default policy invalid: %v", err)
+	}
+
+	invalid := []TriggerPolicy{
+		{MinWindows: -1, SilenceDuration: 0.3, MaxBufferTime: 5},
+		{SilenceDuration: 0, MaxBufferTime: 5},
+		{SilenceDuration: 0.3, MaxBufferTime: 0},
+		{SilenceDuration: 0.3, MaxBufferTime: 1, MinProcessTime: 2},
+	}
+	for i, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("policy #%d should be invalid: %+v", i, p)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "trigger.json")
+	os.WriteFile(path, []byte(`{"minWindows": 0, "maxBufferTime": 0.5, "minProcessTime": 0}`), 0644)
+	policy, err := LoadTriggerPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadTriggerPolicy() error = %v", err)
+	}
+	if policy.MinWindows != 0 || policy.MaxBufferTime != 0.5 || policy.SilenceDuration != 0.3 {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+
+	// 0.6秒的连续正弦波：没有静默、不足3个窗口、不足1秒
+	samples := generateTestAudio(440, 0.6, 44100)
+	process := func(policy TriggerPolicy) string {
+		t.Helper()
+		engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 16384, Trigger: &policy})
+		result, err := engine.ProcessAudio("cat1", samples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(result)
+	}
+	if result := process(DefaultTriggerPolicy()); !strings.Contains(result, `"status":"waiting"`) {
+		t.Errorf("默认条件下应等待更多数据, got %s", result)
+	}
+	if result := process(policy); strings.Contains(result, `"status":"waiting"`) {
+		t.Errorf("超过最大缓冲时间后应处理, got %s", result)
+	}
+}
+
+// TestEngineTriggerPolicy 测试处理引擎按触发条件处理缓冲区
+// 测试内容：
+// 1. 非法条件在创建引擎与初始化SDK时被拒绝，引擎支持热加载触发条件
+// 2. 不足3个窗口、没有静默时不处理，形成3个窗口后处理到缓冲区不足一个窗口为止
+// 3. 不按窗口数触发时，叫声后缓冲了足够长的静默即处理
+// 4. 结束流时不论触发条件处理剩余样本
+func TestEngineTriggerPolicy(t *testing.T) {
+	policy := DefaultTriggerPolicy()
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Deterministic: true, Trigger: &policy})
+	if _, ok := interface{}(engine).(TriggerPolicySetter); !ok {
+		t.Error("engine should support trigger policy reloads")
+	}
+	invalid := TriggerPolicy{SilenceDuration: 0, MaxBufferTime: 5}
+	if _, err := LoadEngine(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Trigger: &invalid}); err == nil {
+		t.Error("LoadEngine should reject an invalid trigger policy")
+	}
+	if err := validateSDKConfig(AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, SampleLibraryPath: "lib.json", Trigger: &invalid}); err == nil {
+		t.Error("validateSDKConfig should reject an invalid trigger policy")
+	}
+	if err := engine.SetTriggerPolicy(invalid); err == nil {
+		t.Error("SetTriggerPolicy should reject an invalid trigger policy")
+	}
+
+	tone := generateTestAudio(440, 1, 44100)
+	count := func(session *AudioStreamSession, flush bool) int {
+		t.Helper()
+		n := 0
+		emit := func([]byte) { n++ }
+		var err error
+		if flush {
+			err = engine.Flush(session, emit)
+		} else {
+			err = engine.Drain(session, emit)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	session := engine.NewSession("windows")
+	engine.Append(session, tone[:8192])
+	if n := count(session, false); n != 0 {
+		t.Errorf("2 windows without silence produced %d results, want 0", n)
+	}
+	engine.Append(session, tone[8192:14000])
+	if n := count(session, false); n != 3 || len(session.Buffer) >= 4096 {
+		t.Errorf("3 windows produced %d results with %d samples left, want 3", n, len(session.Buffer))
+	}
+
+	if err := engine.SetTriggerPolicy(TriggerPolicy{SilenceDuration: 0.3, MaxBufferTime: 5}); err != nil {
+		t.Fatal(err)
+	}
+	session = engine.NewSession("silence")
+	engine.Append(session, tone[:8192])
+	engine.Append(session, make([]float64, 8000))
+	if n := count(session, false); n != 0 {
+		t.Errorf("0.18s of silence triggered %d results, want 0", n)
+	}
+	engine.Append(session, make([]float64, 6000))
+	if count(session, false); len(session.Buffer) >= 4096 {
+		t.Errorf("0.32s of silence after a call should trigger processing, %d samples left", len(session.Buffer))
+	}
+
+	session = engine.NewSession("flush")
+	engine.Append(session, tone[:10000])
+	if n := count(session, true); n != 3 {
+		t.Errorf("flush produced %d results, want 2 windows and a final result", n)
+	}
+}
+
+// TestEngineVocalizationSegments 测试设置触发条件时处理引擎按静默划分叫声段
+// 测试内容：
+// 1. 叫声内的窗口输出 partial 结果，叫声后缓冲了足够长的静默时该窗口输出最终结果，静默窗口跳过
+// 2. 叫声结束时静默尚未缓冲，之后的静默窗口累计达到静默时长时以最近的中间结果结束该段
+// 3. 结束流时未完成的段以最近的中间结果作为 final 结果
+func TestEngineVocalizationSegments(t *testing.T) {
+	policy := DefaultTriggerPolicy()
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096, Deterministic: true, Trigger: &policy})
+
+	tone := generateTestAudio(440, 1, 44100)
+	silence := make([]float64, 22050)
+	drain := func(session *AudioStreamSession, flush bool) []AudioStreamResult {
+		t.Helper()
+		var results []AudioStreamResult
+		emit := func(data []byte) {
+			var result AudioStreamResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatalf("bad result %s: %v", data, err)
+			}
+			results = append(results, result)
+		}
+		var err error
+		if flush {
+			err = engine.Flush(session, emit)
+		} else {
+			err = engine.Drain(session, emit)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+	partials := func(results []AudioStreamResult) int {
+		n := 0
+		for _, result := range results {
+			if result.Partial {
+				n++
+			}
+		}
+		return n
+	}
+
+	// 0.5秒叫声后0.5秒静默：前5个窗口为叫声，第6个窗口跨过叫声结尾且之后已缓冲足够长的静默
+	session := engine.NewSession("buffered")
+	engine.Append(session, tone[:22050])
+	engine.Append(session, silence)
+	results := drain(session, false)
+	if len(results) != 6 || partials(results) != 5 || results[5].Partial || results[5].Final {
+		t.Errorf("results = %+v, want 5 partial results then a closing result", results)
+	}
+
+	session = engine.NewSession("later")
+	engine.Append(session, tone[:20480])
+	if results := drain(session, false); len(results) != 5 || partials(results) != 5 {
+		t.Errorf("results = %+v, want 5 partial results while the call continues", results)
+	}
+	engine.Append(session, silence)
+	results = drain(session, false)
+	if len(results) != 1 || results[0].Partial || results[0].Emotion == "" {
+		t.Errorf("results = %+v, want the segment closed after 0.3s of silence", results)
+	}
+
+	session = engine.NewSession("flush")
+	engine.Append(session, tone[:20480])
+	drain(session, false)
+	results = drain(session, true)
+	if len(results) != 1 || results[0].Partial || !results[0].Final {
+		t.Errorf("results = %+v, want the open segment closed as final", results)
+	}
+}
@@ -1,6 +1,12 @@
 package main
 
-import "errors"
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"soundsdk/dsp"
+)
 
 // // AudioFeature 存储提取的特征
 // type AudioFeature struct {
@@ -20,9 +26,13 @@ import "errors"
 
 // AudioSample 音频样本
 type AudioSample struct {
-	FilePath string       // 音频文件路径
-	Emotion  string       // 情感类型
-	Features AudioFeature // 提取的特征
+	FilePath string         // 音频文件路径
+	Emotion  string         // 情感类型
+	CatID    string         `json:",omitempty"` // 猫咪ID，来自 ID3 标签或旁挂 CSV，未标注时为空
+	Tags     []string       `json:",omitempty"` // 人工标注的标签，见 labeling.go
+	Features AudioFeature   // 提取的特征
+	Quality  *SampleQuality `json:",omitempty"` // 质量评分，构建样本库时计算，旧样本库中为空
+	Metadata *AudioMetadata `json:",omitempty"` // 源音频的元数据，构建样本库时记录，旧样本库中为空
 }
 
 // EmotionStatistics 情感统计信息
@@ -34,52 +44,154 @@ type EmotionStatistics struct {
 
 // SampleLibrary 样本库
 type SampleLibrary struct {
-	Samples    map[string][]AudioSample     // 按情感类型存储的原始样本
-	Statistics map[string]EmotionStatistics // 每种情感的统计信息
-	NeedUpdate bool                         // 是否需要更新统计信息
+	Samples          map[string][]AudioSample     // 按情感类型存储的原始样本
+	Statistics       map[string]EmotionStatistics // 每种情感的统计信息
+	NeedUpdate       bool                         // 是否需要更新统计信息
+	ExtractorVersion string                       // 提取样本特征时的 FeatureExtractorVersion，旧版样本库为空
 }
 
 // SampleProcessor 样本处理器
 type SampleProcessor struct {
-	Library     *SampleLibrary // 样本库
-	SampleRate  int            // 采样率
-	WindowSize  int            // 窗口大小
-	FFTSize     int            // FFT大小
-	FrameLength float64        // 帧长（毫秒）
+	Library           *SampleLibrary // 样本库
+	SampleRate        int            // 采样率
+	WindowSize        int            // 窗口大小
+	FFTSize           int            // FFT大小
+	FrameLength       float64        // 帧长（毫秒）
+	NormalizeLoudness bool           // 提取特征前是否进行响度归一化
+	TargetLoudness    float64        // 响度归一化目标 (LUFS)
+
+	Labeler     *SampleLabeler    // 样本目录的标签来源，为空时按子目录名或文件名前缀
+	incremental *incrementalBuild // 增量构建状态，StartIncremental 后设置
 }
 
 // ---------------Stream SDK---------------
 // AudioStreamConfig SDK配置
 type AudioStreamConfig struct {
-	ModelPath         string `json:"model"`
-	SampleRate        int    `json:"sampleRate"`
-	BufferSize        int    `json:"bufferSize"`
-	SampleLibraryPath string `json:"sampleLibraryPath"`
+	ModelPath         string           `json:"model"`
+	SampleRate        int              `json:"sampleRate"`
+	BufferSize        int              `json:"bufferSize"`
+	SampleLibraryPath string           `json:"sampleLibraryPath"`
+	Extractor         ExtractorOptions `json:"extractor"`
+	DomainProfilePath string           `json:"domainProfilePath"` // 领域配置文件，为空时使用内置猫咪配置
+	Lang              string           `json:"lang"`              // 结果默认语言 en/zh/ja/es，为空时使用英文
+	EventDebounceMs   int              `json:"eventDebounceMs"`   // 情感变化事件去抖时长（毫秒），为0时使用默认值
+	Deterministic     bool             `json:"deterministic"`     // 确定性模式：同步处理，时间由样本数推算
+	Strategy          string           `json:"strategy"`          // 默认处理策略：standard/low-latency/accurate/template
+	ResultBufferSize  int              `json:"resultBufferSize"`  // CGO接口每个流的结果缓冲容量，为0时为10
+	OverflowPolicy    string           `json:"overflowPolicy"`    // 结果缓冲已满时的策略：drop-newest/drop-oldest/block，为空时为 drop-newest
+	OverflowTimeoutMs int              `json:"overflowTimeoutMs"` // block 策略的等待时长（毫秒），为0时为1秒
+	HealthChecks      bool             `json:"healthChecks"`      // 统计叫声的谐噪比与基频漂移，在会话汇总中给出非诊断性的健康提示
+	PreFilter         *BandPreFilter   `json:"preFilter"`         // 频带预筛选，目标频带能量不足的窗口跳过不分析，为nil时不筛选
+	FeatureVector     bool             `json:"featureVector"`     // 所有流的结果附带最终特征向量（见 feature_vector.go）
+	SpillDir          string           `json:"spillDir"`          // CGO接口流的缓冲区落盘目录，应用被系统杀掉后可恢复（见 buffer_spill.go），为空时不落盘
+	BundlePath        string           `json:"bundle"`            // 资源包（.meowpack），设置后样本库与领域配置取自资源包（见 meowpack.go）
+	BundlePublicKeys  string           `json:"bundlePublicKeys"`  // 受信任的资源包签名公钥（base64，逗号分隔），设置后只加载其中任一公钥签名的资源包（见 meowpack_sign.go）
+	Trigger           *TriggerPolicy   `json:"trigger"`           // 缓冲处理触发条件，设置后跳过静默窗口并按静默划分叫声段（见 trigger_policy.go），为nil时每满一个分析窗口即处理
+}
+
+// ExtractorOptions 特征提取配置
+type ExtractorOptions struct {
+	PreEmphasis            bool    `json:"preEmphasis"`            // 是否启用预加重滤波
+	PreEmphasisCoefficient float64 `json:"preEmphasisCoefficient"` // 预加重系数，为0时使用默认值0.97
+	FrequencyPreset        string  `json:"frequencyPreset"`        // 频率范围预设：kitten/adult/large-breed
+	FramePipeline          bool    `json:"framePipeline"`          // 逐帧流水线：重叠窗口复用帧的中间结果（见 frame_pipeline.go）
+	Aggregation            string  `json:"aggregation"`            // 段内窗口特征的汇总方式 max-energy/energy-weighted/median，为空时各窗口分别评分后取平均（见 feature_aggregation.go）
 }
 
 // AudioStreamResult 实时识别结果
 type AudioStreamResult struct {
-	StreamID   string          `json:"streamId"`
-	Timestamp  int64           `json:"timestamp"`
-	Emotion    string          `json:"emotion"`
-	Confidence float64         `json:"confidence"`
-	Metadata   AudioStreamMeta `json:"metadata"`
+	StreamID   string             `json:"streamId"`
+	ResultID   string             `json:"resultId"`            // 结果ID，与日志中的 result= 对应
+	RequestID  string             `json:"requestId,omitempty"` // 产生该结果的音频块的请求ID
+	Timestamp  int64              `json:"timestamp"`
+	Emotion    string             `json:"emotion"`
+	Confidence float64            `json:"confidence"`
+	Scores     map[string]float64 `json:"scores,omitempty"`     // 各情感的评分（段内平均，先验调整前），同一情感的多个样本合并为一项
+	Label      string             `json:"label,omitempty"`      // 本地化的情感名称
+	Message    string             `json:"message,omitempty"`    // 面向用户的提示短语
+	Partial    bool               `json:"partial,omitempty"`    // 段未完成时的中间结果（当前最佳猜测）
+	Final      bool               `json:"final,omitempty"`      // 结束流时由缓冲区剩余样本产生的最后一个结果
+	LatencyMs  int64              `json:"latencyMs"`            // 从段内第一个样本到达到结果产生的耗时（毫秒）
+	ClientTime *ClientTime        `json:"clientTime,omitempty"` // 段内样本在客户端时钟上的采集时间，客户端带 captureTime 时提供
+	Cadence    *CallCadence       `json:"cadence,omitempty"`    // 流最近的叫声节奏（见 call_cadence.go），频域流不统计
+	Segments   []SegmentResult    `json:"segments,omitempty"`   // 本次送入的数据中已完成的各声叫声，按时间排序，时间为距流开始的秒数；频域流与中间结果没有该字段
+	Metadata   AudioStreamMeta    `json:"metadata"`
+	Debug      *ResultDebug       `json:"debug,omitempty"` // 流开启调试时附带的逐窗口特征与评分
+
+	FeatureVector *FeatureVector `json:"featureVector,omitempty"` // 流开启特征向量时附带的最终特征
+
+	ResultStability // 滚动平均置信度与稳定度
 }
 
 // AudioStreamMeta 元数据
 type AudioStreamMeta struct {
 	AudioLength int                `json:"audioLength"`
 	Features    map[string]float64 `json:"features"`
+	Timing      ResultTiming       `json:"timing"`
+}
+
+// ResultTiming 结果在处理链路上的时间点（毫秒时间戳），确定性模式下为流时钟
+type ResultTiming struct {
+	ReceivedAt   int64 `json:"receivedAt"`   // 段内第一个样本到达的时间
+	ProcessStart int64 `json:"processStart"` // 开始处理的时间
+	ProcessEnd   int64 `json:"processEnd"`   // 处理完成的时间
 }
 
 // AudioStreamSession 音频流会话
 type AudioStreamSession struct {
-	ID               string            // 会话ID
-	FeatureExtractor *FeatureExtractor // 特征提取器
-	Buffer           []float64         // 音频缓冲区
-	Callback         func([]byte)      // 回调函数
-	Active           bool              // 会话是否活跃
-	ResultChan       chan []byte       // 结果通道
+	ID               string             // 会话ID
+	FeatureExtractor *FeatureExtractor  // 特征提取器
+	Buffer           []float64          // 音频缓冲区
+	Callback         func([]byte)       // 回调函数
+	Active           bool               // 会话是否活跃
+	ResultChan       chan []byte        // 结果通道
+	Cat              CatProfile         // 关联的猫咪档案
+	Context          string             // 当前上下文标签
+	HintPriors       map[string]float64 // 开始流时传入的情境提示与情感先验，为nil时只用猫咪档案的先验
+	Lang             string             // 结果语言
+	Tracker          *EmotionTracker    // 情感平滑与去抖
+	EventChan        chan []byte        // 情感变化事件通道
+	SamplesReceived  int64              // 已接收的样本数，确定性模式下用于推算流时钟
+	Strategy         ProcessingStrategy // 处理策略
+	Format           StreamFormat       // 客户端声明的数据格式，零值表示与配置一致的时域样本
+	Debug            bool               // 结果中附带逐窗口特征与评分
+	FeatureVector    bool               // 结果中附带最终特征向量
+
+	bufferMu        sync.Mutex         // 保护会话状态，CGO接口异步处理与服务端处理时与数据追加、切换互斥
+	segmentScores   map[string]float64 // 当前段内各窗口评分之和
+	segmentWindows  int                // 当前段已累积的窗口数
+	segmentArrival  time.Time          // 当前段第一个样本到达的时间
+	segmentDebug    []DebugWindow      // 当前段各窗口的调试信息，仅在开启调试时记录
+	segmentVector   []float64          // 当前段各窗口特征向量之和，仅在开启特征向量时记录
+	segmentFeatures []AudioFeature     // 当前段各窗口的特征，仅在设置了汇总方式时记录（见 feature_aggregation.go）
+	windowsAnalyzed int                // 已分析的窗口数，用作调试信息中的窗口序号
+	requestID       string             // 当前处理的音频块的请求ID
+	arrivals        []sampleArrival    // 缓冲区中各批样本的到达时间
+	resampler       *dsp.Resampler     // CGO接口送入数据的采样率与配置不同时的流式重采样器
+	sampleFormat    string             // CGO接口送入数据的样本格式，为空时为 pcm16
+	results         resultBuffer       // 结果缓冲的溢出策略与交付计数
+	draining        bool               // 已停止并处理完剩余样本，结果缓冲取空后移除会话
+	paused          bool               // CGO接口的流已暂停，恢复前不接收音频
+	spill           *bufferSpill       // CGO接口流的缓冲区落盘文件，未开启时为nil
+	pausedAt        time.Time          // 暂停的时间，恢复时据此顺延缓冲样本的到达时间
+	stability       stabilityWindow    // 最近的识别结果，用于计算结果的稳定度
+	health          vocalHealth        // 声音健康统计，开启 HealthChecks 时记录
+	prefiltered     int                // 预筛选跳过的窗口数
+	frames          framePipeline      // 逐帧流水线的帧中间结果，开启 Extractor.FramePipeline 时使用
+	prefetched      windowPrefetch     // 并行预先提取的窗口特征（见 window_parallel.go）
+	windowStart     int64              // 正在分析的窗口第一个样本的序号
+	segmentStart    int64              // 当前段第一个样本的序号
+	clientClock     []clockAnchor      // 客户端采集时间锚点，按样本序号递增（见 capture_clock.go）
+	clockOrigin     clockAnchor        // 第一个锚点，用于计算客户端时钟的漂移
+	triggered       bool               // 已满足触发条件，处理到缓冲区不足一个窗口为止
+	lastTrigger     time.Time          // 上次满足触发条件的时间
+	silentRun       int                // 连续跳过的静默窗口的步进样本数
+	segmentResult   *AudioStreamResult // 当前段最近的中间结果，段在静默处结束时作为最终结果
+	cadence         callCadence        // 最近的叫声节奏
+	segmentSpan     *SegmentResult     // 当前段的起止时间，频域流为 nil
+	vocalizations   []SegmentResult    // 本次送入的数据中已完成的叫声
+	segmentAudio    []float64          // 当前段已分析的音频，开启归档时记录（见 audio_archive.go）
+	segmentAudioEnd int64              // segmentAudio 最后一个样本之后的样本序号
 }
 
 // MeowTalkSDK SDK实例
@@ -87,6 +199,7 @@ type MeowTalkSDK struct {
 	Config    AudioStreamConfig
 	Sessions  map[string]*AudioStreamSession
 	Processor *SampleProcessor
+	Engine    *Engine // 处理引擎，与HTTP/WebSocket服务共用
 }
 
 // 错误定义
@@ -96,6 +209,10 @@ var (
 	ErrSampleOutOfRange  = errors.New("sample value out of range")
 	ErrBufferOverflow    = errors.New("buffer overflow")
 	ErrInvalidSampleRate = errors.New("invalid sample rate")
+	ErrInvalidAudioFile  = errors.New("invalid audio file")
+	ErrAudioTooLong      = errors.New("audio data too long")
+	ErrInvalidSample     = errors.New("invalid sample value")
+	ErrStreamPaused      = errors.New("stream paused")
 )
 
 // 音频相关常量
@@ -105,10 +222,25 @@ const (
 	MaxSampleValue = 32767
 	MinSampleValue = -32768
 	MaxBufferSize  = 1024 * 1024 // 1MB
+
+	MaxSendBodyBytes = 8 << 20                 // /api/send 与 WebSocket 单条消息的最大字节数
+	MaxSendSamples   = 10 * 44100              // 单次请求的最大样本数
+	MaxWAVSamples    = 10 * 60 * MaxSampleRate // WAV文件最多读取10分钟
+
+	DefaultPreEmphasisCoefficient = 0.97 // 标准预加重系数
 )
 
-// MapToAudioFeature 将特征映射转换为AudioFeature结构
+// MapToAudioFeature 将特征映射转换为AudioFeature结构，映射中已注册的自定义特征放入 Custom
 func MapToAudioFeature(features map[string]float64) AudioFeature {
+	var custom map[string]float64
+	for _, name := range RegisteredFeatures() {
+		if v, ok := features[name]; ok {
+			if custom == nil {
+				custom = make(map[string]float64)
+			}
+			custom[name] = v
+		}
+	}
 	return AudioFeature{
 		ZeroCrossRate:    features["ZeroCrossRate"],
 		Energy:           features["Energy"],
@@ -119,5 +251,14 @@ func MapToAudioFeature(features map[string]float64) AudioFeature {
 		SpectralCentroid: features["SpectralCentroid"],
 		SpectralRolloff:  features["SpectralRolloff"],
 		FundamentalFreq:  features["FundamentalFreq"],
+		Custom:           custom,
+	}
+}
+
+// preEmphasisCoefficient 返回生效的预加重系数
+func (o ExtractorOptions) preEmphasisCoefficient() float64 {
+	if o.PreEmphasisCoefficient <= 0 || o.PreEmphasisCoefficient >= 1 {
+		return DefaultPreEmphasisCoefficient
 	}
+	return o.PreEmphasisCoefficient
 }
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 结果与视频片段的关联
+//
+// 宠物摄像头类的产品在视频旁边显示情感，需要知道每个结果对应视频中的哪一段。客户端在开始流时
+// （/api/start 与 WebSocket 连接配置的 video 字段），或摄像头切换到新的视频片段时（POST /api/video），
+// 给出外部视频片段ID与片段第一帧在摄像头时钟上的时间 cameraTime（毫秒时间戳），之后的结果附带
+// video {"clipId", "cameraTime", "offsetMs"}：
+//   - 结果带 clientTime（音频块带 captureTime，见 capture_clock.go）时视为与摄像头同一时钟，
+//     cameraTime 为段内第一个样本的采集时间，offsetMs 为其在片段中的位置
+//   - 否则按服务端收到段内第一个样本的时间相对关联时的时间推算，网络抖动会带来误差
+//   - 只给出 clipId 时结果只带片段ID与按服务端时间推算的 offsetMs
+// /api/stop 的响应以 videos 汇总各片段关联的最终结果数与位置范围。关联保存在本副本中。
+
+// maxVideoClipIDLength 视频片段ID的最大长度
+const maxVideoClipIDLength = 256
+
+// VideoLink 客户端给出的视频片段
+type VideoLink struct {
+	ClipID     string `json:"clipId"`               // 外部视频片段ID
+	CameraTime int64  `json:"cameraTime,omitempty"` // 片段第一帧在摄像头时钟上的时间（毫秒时间戳）
+}
+
+// Validate 片段ID与摄像头时间至少给出一个
+func (l VideoLink) Validate() error {
+	if l.ClipID == "" && l.CameraTime == 0 {
+		return fmt.Errorf("video link requires clipId or cameraTime")
+	}
+	if len(l.ClipID) > maxVideoClipIDLength {
+		return fmt.Errorf("video clipId exceeds %d bytes", maxVideoClipIDLength)
+	}
+	if l.CameraTime < 0 {
+		return fmt.Errorf("video cameraTime must be a positive millisecond timestamp")
+	}
+	return nil
+}
+
+// ResultVideo 结果附带的 video 字段
+type ResultVideo struct {
+	ClipID     string `json:"clipId,omitempty"`
+	CameraTime int64  `json:"cameraTime,omitempty"` // 段内第一个样本在摄像头时钟上的时间
+	OffsetMs   int64  `json:"offsetMs"`             // 在视频片段中的位置
+}
+
+// VideoSummary 一个视频片段关联的最终结果
+type VideoSummary struct {
+	VideoLink
+	Results       int   `json:"results"`       // 关联的最终结果数
+	FirstOffsetMs int64 `json:"firstOffsetMs"` // 第一个结果在片段中的位置
+	LastOffsetMs  int64 `json:"lastOffsetMs"`  // 最后一个结果在片段中的位置
+}
+
+// streamVideo 流当前关联的视频片段与已关联过的片段汇总
+type streamVideo struct {
+	link      VideoLink
+	linkedAt  time.Time // 关联时的服务端时间
+	summaries []VideoSummary
+}
+
+// videoLinks HTTP/WebSocket 服务中各流关联的视频片段
+type videoLinks struct {
+	mu      sync.Mutex
+	streams map[string]*streamVideo
+}
+
+// linkVideo 将流之后的结果关联到视频片段，之前关联的片段保留在汇总中；重复关联当前片段时不变
+func (s *AudioServer) linkVideo(streamID string, link VideoLink, now time.Time) {
+	v := &s.videos
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.streams == nil {
+		v.streams = make(map[string]*streamVideo)
+	}
+	stream, ok := v.streams[streamID]
+	if !ok {
+		stream = &streamVideo{}
+		v.streams[streamID] = stream
+	} else if stream.link == link {
+		return
+	}
+	stream.link = link
+	stream.linkedAt = now
+	stream.summaries = append(stream.summaries, VideoSummary{VideoLink: link})
+}
+
+// applyVideo 为流关联了视频片段时在结果中附带 video 字段，等待状态的响应原样返回
+func (s *AudioServer) applyVideo(streamID string, result []byte, now time.Time) []byte {
+	var parsed struct {
+		Status     string      `json:"status"`
+		Emotion    string      `json:"emotion"`
+		Partial    bool        `json:"partial"`
+		ClientTime *ClientTime `json:"clientTime"`
+		Metadata   struct {
+			Timing struct {
+				ReceivedAt int64 `json:"receivedAt"`
+			} `json:"timing"`
+		} `json:"metadata"`
+	}
+	if len(result) == 0 || json.Unmarshal(result, &parsed) != nil || parsed.Emotion == "" || parsed.Status == "waiting" {
+		return result
+	}
+
+	v := &s.videos
+	v.mu.Lock()
+	stream, ok := v.streams[streamID]
+	if !ok {
+		v.mu.Unlock()
+		return result
+	}
+	video := ResultVideo{ClipID: stream.link.ClipID}
+	switch {
+	case parsed.ClientTime != nil && stream.link.CameraTime > 0:
+		video.CameraTime = parsed.ClientTime.Start
+		video.OffsetMs = parsed.ClientTime.Start - stream.link.CameraTime
+	default:
+		received := now
+		if parsed.Metadata.Timing.ReceivedAt > 0 {
+			received = time.UnixMilli(parsed.Metadata.Timing.ReceivedAt)
+		}
+		video.OffsetMs = received.Sub(stream.linkedAt).Milliseconds()
+		if stream.link.CameraTime > 0 {
+			video.CameraTime = stream.link.CameraTime + video.OffsetMs
+		}
+	}
+	if !parsed.Partial {
+		summary := &stream.summaries[len(stream.summaries)-1]
+		if summary.Results == 0 {
+			summary.FirstOffsetMs = video.OffsetMs
+		}
+		summary.Results++
+		summary.LastOffsetMs = video.OffsetMs
+	}
+	v.mu.Unlock()
+
+	return appendJSONField(result, "video", video)
+}
+
+// videoSummaries 流关联过的视频片段汇总，没有关联时返回nil
+func (s *AudioServer) videoSummaries(streamID string) []VideoSummary {
+	s.videos.mu.Lock()
+	defer s.videos.mu.Unlock()
+	if stream, ok := s.videos.streams[streamID]; ok {
+		return append([]VideoSummary(nil), stream.summaries...)
+	}
+	return nil
+}
+
+// forgetVideo 会话结束后清除视频关联
+func (s *AudioServer) forgetVideo(streamID string) {
+	s.videos.mu.Lock()
+	delete(s.videos.streams, streamID)
+	s.videos.mu.Unlock()
+}
+
+// handleVideo 关联视频片段：POST /video {"streamId": "...", "clipId": "...", "cameraTime": 1700000000000}
+func (s *AudioServer) handleVideo(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	var request struct {
+		StreamID string `json:"streamId"`
+		VideoLink
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "解析请求参数失败", err.Error())
+		return
+	}
+	if request.StreamID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeMissingStreamID, "缺少 StreamID", "")
+		return
+	}
+	if err := request.VideoLink.Validate(); err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "视频关联无效", err.Error())
+		return
+	}
+
+	s.linkVideo(request.StreamID, request.VideoLink, time.Now())
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"streamId": request.StreamID,
+		"video":    request.VideoLink,
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestVideoLink 测试结果与视频片段的关联
+// 测试内容：
+// 1. 结果带 clientTime 时按采集时间计算在片段中的位置，否则按收到音频的时间相对关联时推算
+// 2. 等待状态的响应与未关联视频的流不附带 video
+// 3. /api/start 的 video 字段与 /api/video 切换片段，/api/stop 按片段汇总最终结果
+// 4. 重复关联当前片段不产生新的汇总，无效的关联返回 400
+func TestVideoLink(t *testing.T) {
+	server := NewAudioServer(NewMockAudioProcessor())
+	linked := time.UnixMilli(1700000000000)
+	videoOf := func(result []byte) *ResultVideo {
+		t.Helper()
+		var parsed struct {
+			Video *ResultVideo `json:"video"`
+		}
+		if err := json.Unmarshal(result, &parsed); err != nil {
+			t.Fatal(err)
+		}
+		return parsed.Video
+	}
+
+	result := []byte(`{"emotion": "for_food", "metadata": {"timing": {"receivedAt": 1700000002500}}}`)
+	if video := videoOf(server.applyVideo("a", result, linked)); video != nil {
+		t.Errorf("stream without video link got %+v", video)
+	}
+	server.linkVideo("a", VideoLink{ClipID: "clip-1", CameraTime: 1699999990000}, linked)
+	if video := videoOf(server.applyVideo("a", result, linked)); video == nil || *video != (ResultVideo{ClipID: "clip-1", CameraTime: 1699999992500, OffsetMs: 2500}) {
+		t.Errorf("video by server time = %+v", video)
+	}
+	captured := []byte(`{"emotion": "for_food", "partial": true, "clientTime": {"start": 1699999995000, "end": 1699999995100}}`)
+	if video := videoOf(server.applyVideo("a", captured, linked)); video == nil || video.OffsetMs != 5000 || video.CameraTime != 1699999995000 {
+		t.Errorf("video by capture time = %+v", video)
+	}
+	waiting := []byte(`{"status": "waiting", "streamId": "a"}`)
+	if !bytes.Equal(server.applyVideo("a", waiting, linked), waiting) {
+		t.Error("waiting response should be unchanged")
+	}
+	if summaries := server.videoSummaries("a"); len(summaries) != 1 || summaries[0].Results != 1 || summaries[0].FirstOffsetMs != 2500 {
+		t.Errorf("summaries = %+v, want 1 final result at 2500", summaries)
+	}
+
+	// HTTP 接口
+	call := func(handler http.HandlerFunc, body string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/x", strings.NewReader(body)))
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+	if code, _ := call(server.handleStart, `{"streamId": "b", "video": {}}`); code != http.StatusBadRequest {
+		t.Errorf("start with empty video status = %d, want 400", code)
+	}
+	if code, _ := call(server.handleStart, `{"streamId": "b", "video": {"clipId": "clip-1"}}`); code != http.StatusOK {
+		t.Fatalf("start with video status = %d", code)
+	}
+	chunk := strings.TrimSuffix(strings.Repeat("0.1,", 1000), ",")
+	send := func() {
+		t.Helper()
+		for i := 0; i < 3; i++ {
+			code, response := call(server.handleSend, fmt.Sprintf(`{"streamId": "b", "data": [%s]}`, chunk))
+			if code != http.StatusOK {
+				t.Fatalf("send status = %d", code)
+			}
+			if response["emotion"] != nil && response["video"] == nil {
+				t.Errorf("result without video: %v", response)
+			}
+		}
+	}
+	send()
+	call(server.handleVideo, `{"streamId": "b", "clipId": "clip-2"}`)
+	call(server.handleVideo, `{"streamId": "b", "clipId": "clip-2"}`)
+	send()
+	if code, _ := call(server.handleVideo, `{"streamId": "b", "clipId": "`+strings.Repeat("x", 300)+`"}`); code != http.StatusBadRequest {
+		t.Errorf("long clip id status = %d, want 400", code)
+	}
+	_, stop := call(server.handleStop, `{"streamId": "b"}`)
+	videos, _ := stop["videos"].([]interface{})
+	if len(videos) != 2 {
+		t.Fatalf("stop videos = %v, want 2 clips", stop["videos"])
+	}
+	if clip, _ := videos[1].(map[string]interface{}); clip["clipId"] != "clip-2" {
+		t.Errorf("second clip = %v", clip)
+	}
+	if server.videoSummaries("b") != nil {
+		t.Error("video links should be cleared after stop")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import "math"
+
+// 声音健康提示
+//
+// 叫声持续嘶哑（谐噪比下降）或基频明显偏离平时，有时是呼吸道或其他健康问题的早期表现。
+// 开启 AudioStreamConfig.HealthChecks 后，引擎对每个有声窗口计算基频与谐噪比（HNR），与猫咪档案中的
+// 基线（CatProfile.Baseline，未设置时取本次会话最初的有声窗口）比较；异常窗口在基线之后的窗口中
+// 占多数时，会话汇总（GetStreamStats、/stop 响应）附带标记与"可考虑请兽医检查"的提示。
+// 这只是基于声音特征的启发式提示，不构成诊断。
+
+// 健康提示参数
+const (
+	HealthBaselineWindows = 20    // 未设置基线时用于学习基线的有声窗口数
+	MinHealthWindows      = 20    // 基线之后至少观察到该数量的有声窗口才给出标记
+	HealthFlagRatio       = 0.5   // 异常窗口占比达到该值时给出标记
+	HoarsenessDropDB      = 6.0   // 谐噪比低于基线该分贝数视为嘶哑
+	PitchDriftRatio       = 0.2   // 基频偏离基线该比例视为漂移
+	healthMinEnergy       = 1e-4  // 有声窗口的最低能量（帧平均）
+	maxHNR                = 30.0  // 谐噪比上限（分贝），避免纯音时数值发散
+	minHarmonicity        = 0.001 // 归一化自相关下限，避免取对数时发散
+)
+
+// 健康标记类型
+const (
+	HealthFlagHoarseness = "hoarseness"  // 持续嘶哑：谐噪比低于基线
+	HealthFlagPitchDrift = "pitch_drift" // 基频持续偏离基线
+)
+
+// HealthAdvice 有健康标记时附带的提示
+const HealthAdvice = "Unusual vocal characteristics persisted in this session. This is not a diagnosis; consider a vet check if it continues."
+
+// VocalBaseline 猫咪平时的声音特征，由长期记录得出
+type VocalBaseline struct {
+	PitchHz float64 `json:"pitchHz"` // 平时的平均基频（Hz）
+	HNR     float64 `json:"hnr"`     // 平时的平均谐噪比（分贝）
+}
+
+// HealthFlag 一项健康标记
+type HealthFlag struct {
+	Kind     string  `json:"kind"`     // hoarseness / pitch_drift
+	Ratio    float64 `json:"ratio"`    // 基线之后异常窗口的占比
+	Baseline float64 `json:"baseline"` // 基线值（HNR为分贝，基频为Hz）
+	Observed float64 `json:"observed"` // 基线之后的平均值
+}
+
+// HealthSummary 会话的声音健康汇总
+type HealthSummary struct {
+	VoicedWindows int           `json:"voicedWindows"`    // 已分析的有声窗口数（含学习基线的窗口）
+	Baseline      VocalBaseline `json:"baseline"`         // 使用的基线，学习未完成时为零值
+	Flags         []HealthFlag  `json:"flags"`            // 健康标记，没有异常时为空
+	Advice        string        `json:"advice,omitempty"` // 有标记时的提示，非诊断
+}
+
+// vocalHealth 单个会话的声音健康统计（调用方需保证同一会话串行输入）
+type vocalHealth struct {
+	baseline  VocalBaseline
+	learned   int     // 已用于学习基线的窗口数
+	voiced    int     // 有声窗口总数
+	observed  int     // 基线之后的有声窗口数
+	hoarse    int     // 基线之后谐噪比偏低的窗口数
+	drifted   int     // 基线之后基频偏离的窗口数
+	pitchSum  float64 // 基线之后的基频之和
+	hnrSum    float64 // 基线之后的谐噪比之和
+	learnPSum float64 // 学习基线期间的基频之和
+	learnHSum float64 // 学习基线期间的谐噪比之和
+}
+
+// harmonicsToNoise 按基频周期处的归一化自相关估计谐噪比（分贝）
+func harmonicsToNoise(samples []float64, sampleRate int, pitch float64) float64 {
+	if pitch <= 0 {
+		return 0
+	}
+	lag := int(math.Round(float64(sampleRate) / pitch))
+	if lag <= 0 || lag >= len(samples) {
+		return 0
+	}
+
+	var cross, head, tail float64
+	for i := 0; i+lag < len(samples); i++ {
+		cross += samples[i] * samples[i+lag]
+		head += samples[i] * samples[i]
+		tail += samples[i+lag] * samples[i+lag]
+	}
+	if head == 0 || tail == 0 {
+		return 0
+	}
+	r := cross / math.Sqrt(head*tail)
+	r = math.Max(minHarmonicity, math.Min(r, 1-minHarmonicity))
+	return math.Min(maxHNR, 10*math.Log10(r/(1-r)))
+}
+
+// observe 输入一个窗口的样本与特征，静音或无基频的窗口不计入
+func (h *vocalHealth) observe(profile *VocalBaseline, samples []float64, sampleRate int, features map[string]float64) {
+	pitch := features["Pitch"]
+	if pitch <= 0 || features["Energy"] < healthMinEnergy {
+		return
+	}
+	hnr := harmonicsToNoise(samples, sampleRate, pitch)
+	h.voiced++
+
+	if profile != nil && profile.PitchHz > 0 {
+		h.baseline = *profile
+	} else if h.learned < HealthBaselineWindows {
+		h.learned++
+		h.learnPSum += pitch
+		h.learnHSum += hnr
+		if h.learned == HealthBaselineWindows {
+			h.baseline = VocalBaseline{PitchHz: h.learnPSum / HealthBaselineWindows, HNR: h.learnHSum / HealthBaselineWindows}
+		}
+		return
+	}
+
+	h.observed++
+	h.pitchSum += pitch
+	h.hnrSum += hnr
+	if hnr < h.baseline.HNR-HoarsenessDropDB {
+		h.hoarse++
+	}
+	if math.Abs(pitch-h.baseline.PitchHz) > PitchDriftRatio*h.baseline.PitchHz {
+		h.drifted++
+	}
+}
+
+// summary 返回当前的健康汇总
+func (h *vocalHealth) summary() HealthSummary {
+	summary := HealthSummary{VoicedWindows: h.voiced, Baseline: h.baseline, Flags: []HealthFlag{}}
+	if h.observed < MinHealthWindows {
+		return summary
+	}
+
+	n := float64(h.observed)
+	if ratio := float64(h.hoarse) / n; ratio >= HealthFlagRatio {
+		summary.Flags = append(summary.Flags, HealthFlag{Kind: HealthFlagHoarseness, Ratio: ratio, Baseline: h.baseline.HNR, Observed: h.hnrSum / n})
+	}
+	if ratio := float64(h.drifted) / n; ratio >= HealthFlagRatio {
+		summary.Flags = append(summary.Flags, HealthFlag{Kind: HealthFlagPitchDrift, Ratio: ratio, Baseline: h.baseline.PitchHz, Observed: h.pitchSum / n})
+	}
+	if len(summary.Flags) > 0 {
+		summary.Advice = HealthAdvice
+	}
+	return summary
+}
+
+// StreamHealthReporter 支持声音健康汇总的处理器（开启 HealthChecks 的 Engine）
+type StreamHealthReporter interface {
+	StreamHealth(streamID string) (HealthSummary, bool)
+}
+
+// StreamHealth 返回流的声音健康汇总，未开启健康提示或流不存在时返回 false
+func (e *Engine) StreamHealth(streamID string) (HealthSummary, bool) {
+	if !e.Config.HealthChecks {
+		return HealthSummary{}, false
+	}
+	session, ok := e.lookupSession(streamID)
+	if !ok {
+		return HealthSummary{}, false
+	}
+	session.bufferMu.Lock()
+	defer session.bufferMu.Unlock()
+	return session.health.summary(), true
+}
+
+// streamHealth /stop 响应中附带的健康汇总，处理器不支持时为nil
+func (s *AudioServer) streamHealth(streamID string) *HealthSummary {
+	reporter, ok := s.processor.(StreamHealthReporter)
+	if !ok {
+		return nil
+	}
+	if summary, ok := reporter.StreamHealth(streamID); ok {
+		return &summary
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestVocalHealth 测试声音健康提示
+// 测试内容：
+// 1. 纯音的谐噪比高于加噪声的同频音
+// 2. 以会话最初的窗口学习基线，之后持续嘶哑时给出 hoarseness 标记与提示
+// 3. 猫咪档案提供基线时不再学习，基频偏离时给出 pitch_drift 标记
+// 4. 开启 HealthChecks 的引擎在 /stop 响应中附带健康汇总
+func TestVocalHealth(t *testing.T) {
+	const rate = 44100
+	clean := generateTestAudio(440, 4096.0/rate, rate)
+	noisy := make([]float64, len(clean))
+	random := rand.New(rand.NewSource(1))
+	for i, sample := range clean {
+		noisy[i] = 0.5*sample + random.NormFloat64()*0.5
+	}
+	cleanHNR, noisyHNR := harmonicsToNoise(clean, rate, 440), harmonicsToNoise(noisy, rate, 440)
+	if cleanHNR < 20 || noisyHNR > cleanHNR-HoarsenessDropDB {
+		t.Fatalf("HNR clean = %.1f dB, noisy = %.1f dB; want a clear drop", cleanHNR, noisyHNR)
+	}
+	if hnr := harmonicsToNoise(clean, rate, 0); hnr != 0 {
+		t.Errorf("HNR without pitch = %.1f, want 0", hnr)
+	}
+
+	voiced := map[string]float64{"Pitch": 440, "Energy": 0.1}
+	var health vocalHealth
+	for i := 0; i < HealthBaselineWindows; i++ {
+		health.observe(nil, clean, rate, voiced)
+	}
+	health.observe(nil, clean, rate, map[string]float64{"Pitch": 440, "Energy": 0}) // 静音窗口不计入
+	if summary := health.summary(); summary.Baseline.PitchHz != 440 || len(summary.Flags) != 0 || summary.VoicedWindows != HealthBaselineWindows {
+		t.Errorf("after learning = %+v, want a 440Hz baseline without flags", summary)
+	}
+	for i := 0; i < MinHealthWindows; i++ {
+		health.observe(nil, noisy, rate, voiced)
+	}
+	summary := health.summary()
+	if len(summary.Flags) != 1 || summary.Flags[0].Kind != HealthFlagHoarseness || summary.Advice != HealthAdvice {
+		t.Errorf("persistent hoarseness = %+v", summary)
+	}
+
+	var drift vocalHealth
+	baseline := &VocalBaseline{PitchHz: 300, HNR: cleanHNR}
+	for i := 0; i < MinHealthWindows; i++ {
+		drift.observe(baseline, clean, rate, voiced)
+	}
+	if summary := drift.summary(); len(summary.Flags) != 1 || summary.Flags[0].Kind != HealthFlagPitchDrift || summary.Flags[0].Observed != 440 {
+		t.Errorf("pitch drift from the profile baseline = %+v", summary)
+	}
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 4096, Deterministic: true, HealthChecks: true})
+	if _, err := engine.ProcessAudio("cat1", generateTestAudio(440, 0.5, rate)); err != nil {
+		t.Fatal(err)
+	}
+	if summary, ok := engine.StreamHealth("cat1"); !ok || summary.VoicedWindows == 0 {
+		t.Errorf("StreamHealth = %+v, %t; want voiced windows", summary, ok)
+	}
+	server := NewAudioServer(engine)
+	rec := httptest.NewRecorder()
+	server.handleStop(rec, httptest.NewRequest(http.MethodPost, "/stop", strings.NewReader(`{"streamId":"cat1"}`)))
+	var response struct {
+		Health *HealthSummary `json:"health"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Health == nil || response.Health.VoicedWindows == 0 {
+		t.Errorf("/stop response = %s, want a health summary", rec.Body.String())
+	}
+	if _, ok := engine.StreamHealth("cat1"); ok {
+		t.Error("stopped stream should have no health summary")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// 窗口的并行特征提取：引擎的缓冲区中有多个完整窗口时，各窗口的特征先由工作协程池并行计算，之后仍按窗口顺序逐个评分，
+// 结果与逐个计算一致。工作协程数默认取 GOMAXPROCS（不超过 maxWindowWorkers），设为1时逐个计算；
+// 开启逐帧流水线时帧的中间结果跨窗口复用，不并行。自定义特征（见 feature_registry.go）会被并发调用。
+
+// maxWindowWorkers 默认工作协程数的上限，窗口数通常不超过20，更多的协程没有收益
+const maxWindowWorkers = 8
+
+// defaultWindowWorkers 默认的窗口工作协程数
+func defaultWindowWorkers() int {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > maxWindowWorkers {
+		workers = maxWindowWorkers
+	}
+	return workers
+}
+
+// validateWindowWorkers 校验工作协程数
+func validateWindowWorkers(workers int) error {
+	if workers < 0 {
+		return fmt.Errorf("window workers must not be negative")
+	}
+	return nil
+}
+
+// parallelWindows 用 workers 个协程对序号 0~count-1 的窗口调用 extract，workers 为0时取默认值
+// 各协程按序号领取窗口，结果由 extract 写入各自的位置，无需再排序
+func parallelWindows(count, workers int, extract func(index int)) {
+	if workers == 0 {
+		workers = defaultWindowWorkers()
+	}
+	if workers > count {
+		workers = count
+	}
+	if workers <= 1 {
+		for index := 0; index < count; index++ {
+			extract(index)
+		}
+		return
+	}
+
+	indexes := make(chan int, count)
+	for index := 0; index < count; index++ {
+		indexes <- index
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				extract(index)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// SetWindowWorkers 设置并行提取窗口特征的工作协程数，0表示使用默认值，1表示逐个计算
+func (e *Engine) SetWindowWorkers(workers int) error {
+	if err := validateWindowWorkers(workers); err != nil {
+		return err
+	}
+	e.settingsMu.Lock()
+	e.windowWorkers = workers
+	e.settingsMu.Unlock()
+	return nil
+}
+
+// windowPrefetch 并行预先提取的窗口特征，按窗口第一个样本的序号索引；特征提取器或窗口大小变化后作废
+type windowPrefetch struct {
+	extractor *FeatureExtractor
+	window    int
+	features  map[int64]map[string]float64
+}
+
+// prefetchedWindow 返回缓冲区当前窗口的特征：尚未预先提取时，并行提取缓冲区中从当前位置起的各完整窗口。
+// 只有一个完整窗口、工作协程数为1或开启逐帧流水线时返回 false，由调用方逐个提取
+func (e *Engine) prefetchedWindow(session *AudioStreamSession, window, hop int) (map[string]float64, bool) {
+	start := session.SamplesReceived - int64(len(session.Buffer))
+	prefetch := &session.prefetched
+	if prefetch.extractor != session.FeatureExtractor || prefetch.window != window {
+		*prefetch = windowPrefetch{}
+	}
+	if features, ok := prefetch.features[start]; ok {
+		delete(prefetch.features, start)
+		return features, true
+	}
+
+	e.settingsMu.RLock()
+	workers := e.windowWorkers
+	e.settingsMu.RUnlock()
+	count := 1 + (len(session.Buffer)-window)/hop
+	if workers == 1 || count < 2 || session.FeatureExtractor.options.FramePipeline {
+		return nil, false
+	}
+
+	// 静默或低于最小能量的窗口不参与匹配，不提取；当前窗口已通过检查
+	features := make([]map[string]float64, count)
+	parallelWindows(count, workers, func(index int) {
+		samples := session.Buffer[index*hop : index*hop+window]
+		if index > 0 && e.belowThresholds(samples) {
+			return
+		}
+		features[index] = session.FeatureExtractor.Extract(&AudioData{
+			Samples:    applyHammingWindow(samples),
+			SampleRate: e.Config.SampleRate,
+		})
+	})
+	*prefetch = windowPrefetch{
+		extractor: session.FeatureExtractor,
+		window:    window,
+		features:  make(map[int64]map[string]float64, count-1),
+	}
+	for index, f := range features[1:] {
+		if f != nil {
+			prefetch.features[start+int64((index+1)*hop)] = f
+		}
+	}
+	return features[0], true
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestEngineWindowWorkers 测试引擎窗口循环中的并行特征提取
+// 测试内容：
+// 1. 一次送入多个窗口的音频时，并行提取与逐个计算得到的各窗口特征、评分与识别结果相同，顺序不变
+// 2. 缓冲区中的静默窗口照常跳过
+// 3. 工作协程数为负数时报错
+func TestEngineWindowWorkers(t *testing.T) {
+	const rate = 8000
+	data := generateTestAudio(440, 3, rate)
+	for i := range data {
+		data[i] *= 0.2 + 0.8*float64(i%rate)/rate // 各窗口能量不同，顺序错乱时可以发现
+	}
+	data = append(data, make([]float64, rate)...)
+
+	run := func(workers int) []AudioStreamResult {
+		t.Helper()
+		engine := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 2048, Deterministic: true})
+		if err := engine.SetWindowWorkers(workers); err != nil {
+			t.Fatal(err)
+		}
+		session := engine.NewSession("cat1")
+		session.Debug = true
+		engine.Append(session, data)
+		var results []AudioStreamResult
+		err := engine.Drain(session, func(data []byte) {
+			var result AudioStreamResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, result)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+
+	want := run(1)
+	got := run(4)
+	if len(want) < 5 {
+		t.Fatalf("got %d results, want at least 5", len(want))
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parallel engine produced %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Emotion != want[i].Emotion || got[i].Confidence != want[i].Confidence {
+			t.Errorf("result %d = %s %.3f, want %s %.3f", i, got[i].Emotion, got[i].Confidence, want[i].Emotion, want[i].Confidence)
+		}
+		if !reflect.DeepEqual(got[i].Debug, want[i].Debug) {
+			t.Errorf("result %d: parallel window features differ from sequential", i)
+		}
+	}
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: rate, BufferSize: 2048})
+	if err := engine.SetWindowWorkers(-1); err == nil {
+		t.Error("negative window workers should fail")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"compress/flate"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket 压缩
+//
+// 仍以JSON浮点数组发送音频的客户端，每秒数据量是PCM原始数据的数倍，移动网络下流量可观。
+// 协商 permessage-deflate 后文本帧按消息压缩，数字文本压缩率很高；客户端不支持时自动退回不压缩。
+
+// DefaultCompressionLevel 默认压缩级别：实时流优先速度
+const DefaultCompressionLevel = flate.BestSpeed
+
+// SetCompression 设置WebSocket是否协商 permessage-deflate 以及压缩级别（-2~9，同 compress/flate）
+func (s *AudioServer) SetCompression(enabled bool, level int) error {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return fmt.Errorf("compression level %d out of range [%d, %d]", level, flate.HuffmanOnly, flate.BestCompression)
+	}
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+	s.compression = enabled
+	s.compressionLevel = level
+	return nil
+}
+
+// upgradeWebSocket 升级为WebSocket连接，按配置协商压缩
+func (s *AudioServer) upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	s.wsMu.Lock()
+	enabled, level := s.compression, s.compressionLevel
+	s.wsMu.Unlock()
+
+	up := upgrader
+	up.EnableCompression = enabled
+	conn, err := up.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		if err := conn.SetCompressionLevel(level); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
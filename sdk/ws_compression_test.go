@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestWebSocketCompression 测试WebSocket压缩协商
+// 测试内容：
+// 1. 客户端支持时协商 permessage-deflate，压缩后的消息可以正常收发
+// 2. 关闭压缩后不协商
+// 3. 压缩级别越界返回错误
+func TestWebSocketCompression(t *testing.T) {
+	server := NewAudioServer(newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096}))
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+	dialer := websocket.Dialer{EnableCompression: true}
+
+	conn, resp, err := dialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Errorf("extensions = %q, want permessage-deflate", ext)
+	}
+	var msg map[string]interface{}
+	conn.ReadJSON(&msg)
+	conn.WriteJSON(map[string]interface{}{"type": "config", "sampleRate": 44100})
+	conn.ReadJSON(&msg)
+	conn.WriteJSON(generateTestAudio(440, 0.1, 44100))
+	for msg["type"] != "result" {
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read compressed result: %v", err)
+		}
+	}
+	if result, _ := msg["result"].(map[string]interface{}); result["emotion"] == nil {
+		t.Errorf("result = %v, want emotion", msg)
+	}
+	conn.Close()
+
+	if err := server.SetCompression(false, DefaultCompressionLevel); err != nil {
+		t.Fatal(err)
+	}
+	conn, resp, err = dialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Errorf("extensions with compression disabled = %q, want none", ext)
+	}
+	conn.Close()
+
+	if err := server.SetCompression(true, 10); err == nil {
+		t.Error("level 10 should fail")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+)
+
+// WebSocket 连接配置：发送音频前可先发送一条字段与 /start 相同的配置消息，服务端回复 {"type":"config"} 确认
+// 或 {"type":"error"}，出错时可修正后重新发送；只含 format 字段的格式声明消息仍然兼容。
+
+// WebSocketConfig WebSocket 连接配置消息：{"type":"config", "sampleRate":16000, "catId":"...", ...}
+type WebSocketConfig struct {
+	Type            string             `json:"type"`                      // 固定为 config，旧格式声明消息可省略
+	SampleRate      int                `json:"sampleRate,omitempty"`      // 简写：未抽取时域数据的采样率，与 format 二选一
+	Format          *StreamFormat      `json:"format,omitempty"`          // 数据格式，同 /start
+	FrequencyPreset string             `json:"frequencyPreset,omitempty"` // kitten/adult/large-breed
+	Strategy        string             `json:"strategy,omitempty"`        // 处理策略
+	LowLatency      bool               `json:"lowLatency,omitempty"`      // 简写：使用 low-latency 策略
+	CatID           string             `json:"catId,omitempty"`
+	CatName         string             `json:"catName,omitempty"`
+	Context         string             `json:"context,omitempty"`
+	Lang            string             `json:"lang,omitempty"` // 结果语言，覆盖 ?lang= 参数
+	Priors          *ContextPriors     `json:"priors,omitempty"`
+	Hints           []string           `json:"hints,omitempty"`         // 情境提示，同 /start
+	EmotionPriors   map[string]float64 `json:"emotionPriors,omitempty"` // 直接注入的情感先验倍数，同 /start
+	DebounceMs      int                `json:"debounceMs,omitempty"`
+	Debug           bool               `json:"debug,omitempty"`         // 结果中附带逐窗口特征与评分，也可用 ?debug=1 开启
+	FeatureVector   bool               `json:"featureVector,omitempty"` // 结果中附带最终特征向量
+	Video           *VideoLink         `json:"video,omitempty"`         // 关联的视频片段，同 /start
+}
+
+// decodeWebSocketConfig 解析配置消息，type 为 config 或带 format 字段的消息视为配置
+func decodeWebSocketConfig(message []byte) (*WebSocketConfig, bool) {
+	var config WebSocketConfig
+	if err := json.Unmarshal(message, &config); err != nil {
+		return nil, false
+	}
+	if config.Type != "config" && config.Format == nil {
+		return nil, false
+	}
+	return &config, true
+}
+
+// decodeWebSocketFormat 解析WebSocket消息中声明的数据格式：{"format":{"sampleRate":44100,"decimation":10,"domain":"time"}}
+// 或配置消息的 sampleRate 简写
+func decodeWebSocketFormat(message []byte) (StreamFormat, bool) {
+	config, ok := decodeWebSocketConfig(message)
+	if !ok {
+		return StreamFormat{}, false
+	}
+	format := config.streamFormat()
+	return format, !format.IsZero()
+}
+
+// streamFormat 配置声明的数据格式，未声明时为零值
+func (c *WebSocketConfig) streamFormat() StreamFormat {
+	if c.Format != nil {
+		return *c.Format
+	}
+	if c.SampleRate > 0 {
+		return StreamFormat{SampleRate: c.SampleRate, Decimation: 1, Domain: DomainTime}
+	}
+	return StreamFormat{}
+}
+
+// settings 转换为流设置，lang 为连接参数中的语言，配置未指定语言时使用
+func (c *WebSocketConfig) settings(lang string) (StreamSettings, error) {
+	if c.SampleRate < 0 || c.DebounceMs < 0 {
+		return StreamSettings{}, fmt.Errorf("sampleRate and debounceMs must not be negative")
+	}
+	if c.Format != nil && c.SampleRate > 0 && c.Format.SampleRate != c.SampleRate {
+		return StreamSettings{}, fmt.Errorf("sampleRate %d conflicts with format.sampleRate %d", c.SampleRate, c.Format.SampleRate)
+	}
+	strategy := c.Strategy
+	if c.LowLatency {
+		if strategy != "" && strategy != StrategyLowLatency {
+			return StreamSettings{}, fmt.Errorf("lowLatency conflicts with strategy %q", strategy)
+		}
+		strategy = StrategyLowLatency
+	}
+	if c.Lang != "" {
+		lang = c.Lang
+	}
+
+	return StreamSettings{
+		Format:          c.streamFormat(),
+		FrequencyPreset: c.FrequencyPreset,
+		Strategy:        strategy,
+		Cat:             CatProfile{ID: c.CatID, Name: c.CatName, Priors: c.Priors},
+		Context:         c.Context,
+		Hints:           c.Hints,
+		EmotionPriors:   c.EmotionPriors,
+		Lang:            lang,
+		EventDebounce:   time.Duration(c.DebounceMs) * time.Millisecond,
+		Debug:           c.Debug,
+		FeatureVector:   c.FeatureVector,
+	}, nil
+}
+
+// configureWebSocket 校验并应用连接配置，返回发给客户端的确认或错误消息
+// 恢复的会话重复发送相同配置时不重新配置，保留断线前的缓冲区
+func (s *AudioServer) configureWebSocket(session *wsSession, config *WebSocketConfig, resumed bool) map[string]interface{} {
+	settings, err := config.settings(session.lang)
+	settings.Debug = settings.Debug || session.debug
+	if err == nil && config.Video != nil {
+		err = config.Video.Validate()
+	}
+	if err == nil && !(resumed && session.configured && reflect.DeepEqual(settings, session.settings)) {
+		err = s.processor.ConfigureStream(session.streamID, settings)
+	}
+	if err != nil {
+		log.Printf("WebSocket连接配置无效: StreamID=%s, %v", session.streamID, err)
+		return map[string]interface{}{"type": "error", "error": err.Error()}
+	}
+
+	session.settings = settings
+	session.configured = true
+	s.bindCat(session.streamID, settings.Cat.ID)
+	if config.Video != nil {
+		s.linkVideo(session.streamID, *config.Video, time.Now())
+	}
+	session.lang = settings.Lang
+
+	// 旧格式声明消息保持原来的确认格式
+	if config.Type != "config" {
+		return map[string]interface{}{"type": "format", "format": settings.Format}
+	}
+	return map[string]interface{}{
+		"type":     "config",
+		"streamId": session.streamID,
+		"format":   settings.Format,
+		"strategy": settings.Strategy,
+		"catId":    settings.Cat.ID,
+		"lang":     NormalizeLocale(settings.Lang),
+	}
+}
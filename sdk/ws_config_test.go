@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestWebSocketConfig 测试WebSocket连接配置消息
+// 测试内容：
+// 1. 配置消息与旧格式声明、音频消息的区分，sampleRate 与 lowLatency 简写
+// 2. 冲突的配置返回错误，修正后重新发送可以成功
+// 3. 配置应用到会话：格式、策略、猫咪档案与语言
+func TestWebSocketConfig(t *testing.T) {
+	if _, ok := decodeWebSocketConfig([]byte(`{"streamId":"a","data":[0.1]}`)); ok {
+		t.Error("audio message should not be a config")
+	}
+	if f, ok := decodeWebSocketFormat([]byte(`{"type":"config","sampleRate":16000}`)); !ok || f.SampleRate != 16000 || f.Decimation != 1 {
+		t.Errorf("decodeWebSocketFormat(sampleRate) = %+v, %v", f, ok)
+	}
+	if _, ok := decodeWebSocketFormat([]byte(`{"type":"config","catId":"mimi"}`)); ok {
+		t.Error("config without format should not declare a format")
+	}
+	for _, msg := range []string{
+		`{"type":"config","lowLatency":true,"strategy":"accurate"}`,
+		`{"type":"config","sampleRate":16000,"format":{"sampleRate":8000}}`,
+		`{"type":"config","debounceMs":-1}`,
+	} {
+		config, _ := decodeWebSocketConfig([]byte(msg))
+		if _, err := config.settings(""); err == nil {
+			t.Errorf("settings(%s) should fail", msg)
+		}
+	}
+
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	server := NewAudioServer(engine)
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"?lang=en", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var init struct {
+		StreamID string `json:"streamId"`
+	}
+	if err := conn.ReadJSON(&init); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(msg string) map[string]interface{} {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		var reply map[string]interface{}
+		if err := conn.ReadJSON(&reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+
+	if reply := send(`{"type":"config","sampleRate":8000}`); reply["type"] != "error" {
+		t.Errorf("unsupported sample rate reply = %v, want error", reply)
+	}
+	reply := send(`{"type":"config","sampleRate":44100,"catId":"mimi","lang":"zh","lowLatency":true}`)
+	if reply["type"] != "config" || reply["strategy"] != StrategyLowLatency || reply["catId"] != "mimi" || reply["lang"] != "zh" {
+		t.Fatalf("config reply = %v", reply)
+	}
+
+	engine.mu.Lock()
+	session := engine.sessions[init.StreamID]
+	engine.mu.Unlock()
+	if session == nil {
+		t.Fatal("config should create the engine session")
+	}
+	if session.Strategy.Name != StrategyLowLatency || session.Cat.ID != "mimi" || session.Lang != "zh" || session.Format.SampleRate != 44100 {
+		t.Errorf("session = strategy %s, cat %q, lang %s, format %+v", session.Strategy.Name, session.Cat.ID, session.Lang, session.Format)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket 心跳
+//
+// 客户端断电或网络中断时往往收不到关闭帧，读循环要等操作系统的TCP超时（可能长达数十分钟）
+// 才会出错，期间会话一直占用。服务端按固定间隔发送 ping，收到 pong 或任何消息即视为存活；
+// 超过 pongTimeout 没有任何回应时读取超时，连接被关闭并按会话恢复规则清理会话。
+
+// WebSocket 心跳默认参数
+const (
+	DefaultPingInterval = 20 * time.Second
+	DefaultPongTimeout  = 60 * time.Second
+)
+
+// heartbeatWriteTimeout 发送 ping 的写超时
+const heartbeatWriteTimeout = 5 * time.Second
+
+// SetHeartbeat 设置WebSocket心跳：每 interval 发送一次 ping，超过 timeout 无回应则断开
+// interval 为0时关闭心跳，连接只在读取出错时断开
+func (s *AudioServer) SetHeartbeat(interval, timeout time.Duration) error {
+	if interval < 0 || (interval > 0 && timeout <= interval) {
+		return fmt.Errorf("heartbeat timeout (%v) must be greater than ping interval (%v)", timeout, interval)
+	}
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+	s.pingInterval = interval
+	s.pongTimeout = timeout
+	return nil
+}
+
+// startHeartbeat 为连接开启心跳，返回的函数用于停止发送 ping
+// 收到 pong 或任何消息后需调用 alive 延长读取期限
+func (s *AudioServer) startHeartbeat(conn *websocket.Conn, streamID string) (alive func(), stop func()) {
+	s.wsMu.Lock()
+	interval, timeout := s.pingInterval, s.pongTimeout
+	s.wsMu.Unlock()
+	if interval <= 0 {
+		return func() {}, func() {}
+	}
+
+	alive = func() {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+	}
+	alive()
+	conn.SetPongHandler(func(string) error {
+		alive()
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeatWriteTimeout)); err != nil {
+					log.Printf("发送WebSocket心跳失败: StreamID=%s, %v", streamID, err)
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return alive, func() { close(done) }
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestWebSocketHeartbeat 测试WebSocket心跳与断线检测
+// 测试内容：
+// 1. 心跳参数校验：超时必须大于间隔，间隔为0时关闭心跳
+// 2. 持续读取（自动回复 pong）的客户端超过超时时长后仍保持连接
+// 3. 停止响应的客户端在超时后被断开，会话进入等待恢复状态
+func TestWebSocketHeartbeat(t *testing.T) {
+	server := NewAudioServer(NewMockAudioProcessor())
+	if err := server.SetHeartbeat(50*time.Millisecond, 20*time.Millisecond); err == nil {
+		t.Error("timeout below interval should fail")
+	}
+	if err := server.SetHeartbeat(0, 0); err != nil {
+		t.Errorf("disabled heartbeat error = %v", err)
+	}
+	if err := server.SetHeartbeat(20*time.Millisecond, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	attached := func(token string) bool {
+		server.wsMu.Lock()
+		defer server.wsMu.Unlock()
+		session, ok := server.wsSessions[token]
+		return ok && session.attached
+	}
+	dial := func() (*websocket.Conn, string) {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var init struct {
+			ResumeToken string `json:"resumeToken"`
+		}
+		if err := conn.ReadJSON(&init); err != nil {
+			t.Fatal(err)
+		}
+		return conn, init.ResumeToken
+	}
+
+	// 持续读取的客户端由 gorilla/websocket 自动回复 pong
+	live, liveToken := dial()
+	defer live.Close()
+	go func() {
+		for {
+			if _, _, err := live.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 不读取的客户端不会回复 pong
+	dead, deadToken := dial()
+	defer dead.Close()
+
+	for deadline := time.Now().Add(2 * time.Second); attached(deadToken); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("unresponsive connection was not closed")
+		}
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !attached(liveToken) {
+		t.Error("responsive connection should stay open")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+)
+
+// WebSocket 会话恢复：init 消息下发恢复令牌，断线后会话保留一段宽限期，客户端带 ?resume=<token> 重连即接回
+// 同一个会话，缓冲区与连接配置不变。
+
+// DefaultResumeGrace 断线后会话默认保留的时长
+const DefaultResumeGrace = 30 * time.Second
+
+// wsSession 一个可恢复的WebSocket会话
+type wsSession struct {
+	token      string
+	streamID   string
+	lang       string
+	debug      bool           // 连接参数 ?debug= 指定的调试开关
+	settings   StreamSettings // 已应用的连接配置，恢复后重复发送相同配置时不重新配置（避免清空缓冲区）
+	configured bool           // 是否已应用过连接配置
+	attached   bool           // 是否有连接正在使用
+	expiry     *time.Timer    // 断线后的过期计时器
+}
+
+// SetResumeGrace 设置WebSocket断线后会话保留的时长，0表示断线立即结束会话且不下发恢复令牌
+func (s *AudioServer) SetResumeGrace(grace time.Duration) {
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+	s.resumeGrace = grace
+}
+
+// newResumeToken 生成随机恢复令牌
+func newResumeToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("生成恢复令牌失败: %v", err)
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// attachWebSocket 按恢复令牌接回会话，令牌无效、已过期或会话正被其他连接使用时创建新会话
+// 返回的布尔值表示是否为恢复的会话
+func (s *AudioServer) attachWebSocket(token string) (*wsSession, bool) {
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+
+	if session, ok := s.wsSessions[token]; ok && token != "" && !session.attached {
+		session.attached = true
+		if session.expiry != nil {
+			session.expiry.Stop()
+			session.expiry = nil
+		}
+		return session, true
+	}
+
+	session := &wsSession{
+		streamID: fmt.Sprintf("ws-%d", time.Now().UnixNano()),
+		attached: true,
+	}
+	if s.resumeGrace > 0 {
+		session.token = newResumeToken()
+	}
+	if session.token != "" {
+		if s.wsSessions == nil {
+			s.wsSessions = make(map[string]*wsSession)
+		}
+		s.wsSessions[session.token] = session
+	}
+	return session, false
+}
+
+// detachWebSocket 连接断开：保留会话到宽限期结束，期间未恢复则结束会话
+func (s *AudioServer) detachWebSocket(session *wsSession) {
+	s.wsMu.Lock()
+	session.attached = false
+	if session.token == "" || s.resumeGrace <= 0 {
+		delete(s.wsSessions, session.token)
+		s.wsMu.Unlock()
+		s.endWebSocketSession(session.streamID)
+		return
+	}
+
+	grace := s.resumeGrace
+	session.expiry = time.AfterFunc(grace, func() {
+		s.wsMu.Lock()
+		expired := !session.attached && s.wsSessions[session.token] == session
+		if expired {
+			delete(s.wsSessions, session.token)
+		}
+		s.wsMu.Unlock()
+
+		if expired {
+			log.Printf("WebSocket会话恢复期已过: StreamID=%s", session.streamID)
+			s.endWebSocketSession(session.streamID)
+		}
+	})
+	s.wsMu.Unlock()
+	log.Printf("WebSocket会话等待恢复: StreamID=%s, 保留 %v", session.streamID, grace)
+}
+
+// endWebSocketSession 结束会话并关闭录制
+func (s *AudioServer) endWebSocketSession(streamID string) {
+	s.processor.StopStream(streamID)
+	if s.recorder != nil {
+		s.recorder.CloseSession(streamID)
+	}
+	s.forgetActivity(streamID)
+	s.bindCat(streamID, "")
+	s.setPaused(streamID, false)
+	s.resetChunks(streamID)
+	s.forgetVideo(streamID)
+	s.distress.forget(streamID)
+	s.forgetHistory(streamID)
+	s.forgetHooks(streamID)
+	s.saveBaselines()
+}
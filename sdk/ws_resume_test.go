@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestWebSocketResume 测试WebSocket会话恢复
+// 测试内容：
+// 1. init 消息携带恢复令牌，断线后凭令牌重连接回同一会话
+// 2. 恢复后重复声明相同格式不清空缓冲区，断线前的音频与新音频合并处理
+// 3. 宽限期过后会话结束，旧令牌重连得到新会话
+// 4. 宽限期为0时不下发恢复令牌
+func TestWebSocketResume(t *testing.T) {
+	engine := newTestEngine(t, AudioStreamConfig{SampleRate: 44100, BufferSize: 4096})
+	server := NewAudioServer(engine)
+	server.SetResumeGrace(time.Minute)
+	ts := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	type initMessage struct {
+		StreamID      string `json:"streamId"`
+		Resumed       bool   `json:"resumed"`
+		ResumeToken   string `json:"resumeToken"`
+		ResumeGraceMs int64  `json:"resumeGraceMs"`
+	}
+	dial := func(query string) (*websocket.Conn, initMessage) {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+query, nil)
+		if err != nil {
+			t.Fatalf("Dial(%s) error = %v", query, err)
+		}
+		var init initMessage
+		if err := conn.ReadJSON(&init); err != nil {
+			t.Fatal(err)
+		}
+		return conn, init
+	}
+	// readReply 读取指定类型的回复，跳过情感变化事件
+	readReply := func(conn *websocket.Conn, kind string) map[string]interface{} {
+		t.Helper()
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("read %s: %v", kind, err)
+			}
+			if msg["type"] == kind {
+				return msg
+			}
+		}
+	}
+	// waitFor 等待服务端完成断线处理
+	waitFor := func(what string, done func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); !done(); time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+		}
+	}
+	detached := func(token string) func() bool {
+		return func() bool {
+			server.wsMu.Lock()
+			defer server.wsMu.Unlock()
+			session, ok := server.wsSessions[token]
+			return !ok || !session.attached
+		}
+	}
+	format := `{"format": {"sampleRate": 44100, "decimation": 1}}`
+	samples := generateTestAudio(440, 0.15, 44100)
+
+	conn, first := dial("")
+	if first.ResumeToken == "" || first.Resumed || first.ResumeGraceMs != time.Minute.Milliseconds() {
+		t.Fatalf("init = %+v, want resume token and grace", first)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(format))
+	readReply(conn, "format")
+	conn.WriteJSON(samples[:2000])
+	if result := readReply(conn, "result")["result"].(map[string]interface{}); result["status"] != "waiting" {
+		t.Errorf("first chunk result = %v, want waiting", result)
+	}
+	conn.Close()
+	waitFor("detach", detached(first.ResumeToken))
+
+	conn, resumed := dial("?resume=" + first.ResumeToken)
+	if !resumed.Resumed || resumed.StreamID != first.StreamID || resumed.ResumeToken != first.ResumeToken {
+		t.Fatalf("resumed init = %+v, want same session %s", resumed, first.StreamID)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(format))
+	readReply(conn, "format")
+	conn.WriteJSON(samples[2000:])
+	result := readReply(conn, "result")["result"].(map[string]interface{})
+	if meta, _ := result["metadata"].(map[string]interface{}); meta == nil || meta["audioLength"] != float64(4096) {
+		t.Errorf("resumed result = %v, want analysis of buffered and new audio", result)
+	}
+
+	// 同一令牌不能被两个连接同时使用
+	other, duplicate := dial("?resume=" + first.ResumeToken)
+	if duplicate.Resumed || duplicate.StreamID == first.StreamID {
+		t.Errorf("duplicate resume = %+v, want new session while attached", duplicate)
+	}
+	other.Close()
+
+	// 宽限期过后会话结束
+	server.SetResumeGrace(20 * time.Millisecond)
+	conn.Close()
+	waitFor("session expiry", func() bool {
+		engine.mu.Lock()
+		defer engine.mu.Unlock()
+		_, ok := engine.sessions[first.StreamID]
+		return !ok
+	})
+	conn, expired := dial("?resume=" + first.ResumeToken)
+	if expired.Resumed || expired.StreamID == first.StreamID {
+		t.Errorf("init after expiry = %+v, want new session", expired)
+	}
+	conn.Close()
+
+	server.SetResumeGrace(0)
+	conn, plain := dial("")
+	if plain.ResumeToken != "" {
+		t.Errorf("init with zero grace = %+v, want no resume token", plain)
+	}
+	conn.Close()
+}